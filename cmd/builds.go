@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// buildChannelAll lists every build rather than a channel's
+const buildChannelAll = "all"
+
+var buildsCmd = &cobra.Command{
+	Use:   "builds",
+	Short: "Browse the FXServer builds on the artifacts page",
+}
+
+var buildsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available FXServer builds",
+	Long: `Lists the FXServer builds published on the artifacts page, newest first,
+marking the current recommended and optional builds. --channel narrows the list
+to the build a channel points to: recommended, optional or latest.
+
+Use --output json to pick builds in scripts, e.g. the recommended one:
+
+  inkwash builds list --channel recommended -o json | jq '.[0].number'
+
+If the artifacts page can't be reached, the last list fetched is shown.`,
+	Example: `  inkwash builds list
+  inkwash builds list --limit 5
+  inkwash builds list --channel optional --target-os linux`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runBuildsList,
+}
+
+func init() {
+	rootCmd.AddCommand(buildsCmd)
+	buildsCmd.AddCommand(buildsListCmd)
+
+	buildsListCmd.Flags().String("channel", buildChannelAll, "Builds to list: recommended, optional, latest or all")
+	buildsListCmd.Flags().Int("limit", 20, "Most builds to list, 0 for all of them")
+	buildsListCmd.Flags().String("target-os", "", "Platform to list builds for: windows or linux (default: this machine)")
+
+	buildsListCmd.RegisterFlagCompletionFunc("channel", cobra.FixedCompletions(
+		[]string{types.BuildChannelRecommended, types.BuildChannelOptional, types.BuildChannelLatest, buildChannelAll},
+		cobra.ShellCompDirectiveNoFileComp))
+	buildsListCmd.RegisterFlagCompletionFunc("target-os", cobra.FixedCompletions(
+		[]string{types.TargetWindows, types.TargetLinux}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// buildView is an artifacts page build as `builds list` shows it
+type buildView struct {
+	Number      int    `json:"number"`
+	Hash        string `json:"hash"`
+	Recommended bool   `json:"recommended"`
+	Optional    bool   `json:"optional"`
+	DownloadURL string `json:"download_url"`
+}
+
+func runBuildsList(cmd *cobra.Command, args []string) error {
+	channel, _ := cmd.Flags().GetString("channel")
+	channel = strings.ToLower(channel)
+	switch channel {
+	case types.BuildChannelRecommended, types.BuildChannelOptional, types.BuildChannelLatest, buildChannelAll:
+	default:
+		return &usageError{fmt.Errorf("unknown channel %q (expected recommended, optional, latest or all)", channel)}
+	}
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit < 0 {
+		return &usageError{fmt.Errorf("--limit can't be negative")}
+	}
+
+	targetOS, err := resolveTargetOS(cmd)
+	if err != nil {
+		return &usageError{err}
+	}
+
+	client := download.NewArtifactClient()
+	if err := client.SetTargetOS(targetOS); err != nil {
+		return &usageError{err}
+	}
+
+	builds, err := client.FetchBuilds()
+	if err != nil {
+		builds = client.CachedBuilds()
+		if builds == nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(fmt.Sprintf(
+			"%s Couldn't reach the artifacts page (%v); showing the last list fetched", ui.SymbolWarning, err)))
+	}
+
+	if channel != buildChannelAll {
+		build, err := download.ResolveBuild(builds, channel)
+		if err != nil {
+			return err
+		}
+		builds = []types.Build{*build}
+	}
+
+	sort.Slice(builds, func(i, j int) bool { return builds[i].Number > builds[j].Number })
+	total := len(builds)
+	if limit > 0 && len(builds) > limit {
+		builds = builds[:limit]
+	}
+
+	views := make([]buildView, 0, len(builds))
+	for _, b := range builds {
+		views = append(views, buildView{
+			Number:      b.Number,
+			Hash:        strings.TrimPrefix(b.Hash, strconv.Itoa(b.Number)+"-"),
+			Recommended: b.Recommended,
+			Optional:    b.Optional,
+			DownloadURL: client.GetDownloadURL(b),
+		})
+	}
+
+	return writeOutput(cmd, views, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader(fmt.Sprintf("FXSERVER BUILDS (%s)", strings.ToUpper(targetOS))))
+
+		for _, b := range views {
+			label := fmt.Sprintf("Build %d", b.Number)
+			if b.Recommended {
+				label += " (recommended)"
+			} else if b.Optional {
+				label += " (optional)"
+			}
+			fmt.Printf("  %s %s\n", ui.RenderAccent(fmt.Sprintf("%-26s", label)), ui.RenderMuted(b.Hash))
+		}
+
+		if len(views) < total {
+			fmt.Printf("\n%s\n", ui.RenderMuted(fmt.Sprintf("Showing %d of %d builds; --limit 0 lists them all", len(views), total)))
+		}
+		fmt.Println()
+	})
+}
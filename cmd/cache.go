@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Maintain the FXServer build cache",
+	Long: `Commands for listing, checking and pruning the FXServer builds InkWash keeps
+for new installs. The cache holds at most cache.max_builds builds and, if
+cache.max_size_gb is set, at most that many GB; the least recently used builds
+make way for new ones.`,
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify [build...]",
+	Short: "Check cached builds against their checksums",
+	Long: `Recomputes the SHA256 of each cached build's archive and extracted files and
+compares them with the checksums recorded when it was cached. Corrupt builds are
+removed from the cache so the next install downloads them again.
+
+Builds cached by older versions of InkWash have no checksums; their archive is
+extracted again and compared with the extracted files instead, and their
+checksums are recorded once they pass. --archive does that for every build,
+which also catches files that were already wrong when they were cached.
+
+Set cache.verify to true to check the checksums automatically before every
+install from the cache.`,
+	RunE: runCacheVerify,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the cached FXServer builds",
+	Long: `Lists the builds kept for new installs, newest first, with their size and
+when they were downloaded and last used. Once cache.max_builds or
+cache.max_size_gb is reached, the least recently used build makes way for the
+next one.`,
+	Args: cobra.NoArgs,
+	RunE: runCacheList,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove the least recently used builds over the cache limits",
+	Long: `Removes the least recently used builds until the cache is within
+cache.max_builds and cache.max_size_gb, or the limits given with --keep and
+--max-size. The most recently used build is always kept; 'inkwash cache clear'
+removes everything. Installed servers keep their own copy of their build.`,
+	Example: `  inkwash cache clean --keep 1
+  inkwash cache clean --max-size 5 --dry-run`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runCacheClean,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached build",
+	Long: `Removes every build from the cache, asking first unless --yes is given. The
+next install downloads its build again; installed servers keep their own copy.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheVerifyCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	cacheVerifyCmd.Flags().Bool("keep", false, "Report corrupt builds without removing them")
+	cacheVerifyCmd.Flags().Bool("archive", false, "Compare every build's extracted files with its archive, not just the checksums")
+	cacheCleanCmd.Flags().Int("keep", 0, "Builds to keep (default: cache.max_builds)")
+	cacheCleanCmd.Flags().Int("max-size", 0, "GB the builds may take up (default: cache.max_size_gb)")
+	cacheCleanCmd.Flags().Bool("dry-run", false, "List the builds that would be removed without removing them")
+	cacheClearCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+}
+
+// openBinaryCache opens the build cache with the configured limits
+func openBinaryCache() (*cache.BinaryCache, error) {
+	binaryCache, err := cache.NewBinaryCache(registry.GetDefaultCachePath(), viper.GetInt("cache.max_builds"))
+	if err != nil {
+		return nil, err
+	}
+	binaryCache.SetMaxSize(cacheMaxSize())
+	return binaryCache, nil
+}
+
+// cacheMaxSize returns cache.max_size_gb in bytes, 0 for no limit
+func cacheMaxSize() int64 {
+	return int64(viper.GetInt("cache.max_size_gb")) << 30
+}
+
+// formatSize formats a size in bytes as MB, or GB from 1 GB up
+func formatSize(bytes int64) string {
+	if bytes >= 1<<30 {
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	}
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+}
+
+func runCacheVerify(cmd *cobra.Command, args []string) error {
+	keep, _ := cmd.Flags().GetBool("keep")
+	againstArchive, _ := cmd.Flags().GetBool("archive")
+
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	var numbers []int
+	if len(args) > 0 {
+		for _, arg := range args {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid build number %q", arg)
+			}
+			numbers = append(numbers, n)
+		}
+	} else {
+		for _, build := range binaryCache.List() {
+			numbers = append(numbers, build.Number)
+		}
+	}
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("CACHE VERIFY"))
+	if len(numbers) == 0 {
+		fmt.Printf("  %s\n\n", ui.RenderMuted("The cache is empty"))
+		return nil
+	}
+
+	corrupt := 0
+	for _, n := range numbers {
+		label := fmt.Sprintf("Build %d", n)
+
+		err := binaryCache.VerifyBuild(n)
+		checkedArchive := false
+		if err == nil && againstArchive || errors.Is(err, cache.ErrNoChecksum) {
+			err = binaryCache.VerifyArchive(n)
+			checkedArchive = true
+		}
+		switch {
+		case err == nil && checkedArchive:
+			fmt.Printf("  %s\n", ui.RenderSuccess(label+" matches its archive"))
+
+		case err == nil:
+			fmt.Printf("  %s\n", ui.RenderSuccess(label))
+
+		case errors.Is(err, cache.ErrChecksumMismatch):
+			corrupt++
+			fmt.Printf("  %s\n", ui.RenderError(err.Error()))
+			if keep {
+				continue
+			}
+			if err := binaryCache.Remove(n); err != nil {
+				return fmt.Errorf("failed to remove build %d: %w", n, err)
+			}
+			fmt.Printf("    %s\n", ui.RenderMuted("Removed from the cache, it will be downloaded again when needed"))
+
+		default:
+			fmt.Printf("  %s\n", ui.RenderError(err.Error()))
+		}
+	}
+
+	fmt.Println()
+	if corrupt > 0 {
+		return fmt.Errorf("%d corrupt build(s) found", corrupt)
+	}
+	return nil
+}
+
+// cachedBuildView is a cached build as `cache list` shows it
+type cachedBuildView struct {
+	Number      int       `json:"number"`
+	Hash        string    `json:"hash"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Downloaded  time.Time `json:"downloaded"`
+	LastUsed    time.Time `json:"last_used"`
+	Recommended bool      `json:"recommended"`
+	Optional    bool      `json:"optional"`
+	Checksummed bool      `json:"checksummed"` // Whether cache verify can check it
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	builds := binaryCache.List()
+	views := make([]cachedBuildView, 0, len(builds))
+	for _, b := range builds {
+		views = append(views, cachedBuildView{
+			Number:      b.Number,
+			Hash:        b.Hash,
+			SizeBytes:   b.Size,
+			Downloaded:  b.Downloaded,
+			LastUsed:    b.LastUsed,
+			Recommended: b.Recommended,
+			Optional:    b.Optional,
+			Checksummed: b.SHA256 != "" && b.TreeSHA256 != "",
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Number > views[j].Number })
+
+	return writeOutput(cmd, views, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("CACHED BUILDS"))
+		if len(views) == 0 {
+			fmt.Printf("  %s\n\n", ui.RenderMuted("The cache is empty"))
+			return
+		}
+
+		var total int64
+		for _, b := range views {
+			total += b.SizeBytes
+			label := fmt.Sprintf("Build %d", b.Number)
+			if b.Recommended {
+				label += " (recommended)"
+			} else if b.Optional {
+				label += " (optional)"
+			}
+
+			fmt.Printf("  %s\n", ui.RenderAccent(label))
+			fmt.Printf("    %s\n", ui.RenderMuted(fmt.Sprintf("%s, downloaded %s, last used %s",
+				formatSize(b.SizeBytes), b.Downloaded.Format("2006-01-02"), formatRelativeTime(b.LastUsed))))
+		}
+
+		limit := fmt.Sprintf("%d build(s)", viper.GetInt("cache.max_builds"))
+		if maxSize := cacheMaxSize(); maxSize > 0 {
+			limit += " or " + formatSize(maxSize)
+		}
+		fmt.Printf("\nTotal: %d build(s), %s (limit %s)\n\n", len(views), formatSize(total), limit)
+	})
+}
+
+func runCacheClean(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	keep := viper.GetInt("cache.max_builds")
+	if flags.Changed("keep") {
+		keep, _ = flags.GetInt("keep")
+	}
+	maxSize := cacheMaxSize()
+	if flags.Changed("max-size") {
+		gb, _ := flags.GetInt("max-size")
+		maxSize = int64(gb) << 30
+	}
+	dryRun, _ := flags.GetBool("dry-run")
+	if keep < 1 || maxSize < 0 {
+		return &usageError{fmt.Errorf("--keep must be at least 1 and --max-size at least 0, use 'inkwash cache clear' to empty the cache")}
+	}
+
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	removed := binaryCache.PruneCandidates(keep, maxSize)
+	if !dryRun {
+		removed, err = binaryCache.Prune(keep, maxSize)
+	}
+
+	var freed int64
+	for _, b := range removed {
+		freed += b.Size
+		verb := "Removed"
+		if dryRun {
+			verb = "Would remove"
+		}
+		fmt.Printf("  %s build %d %s\n", verb, b.Number, ui.RenderMuted(fmt.Sprintf("(%s, last used %s)", formatSize(b.Size), formatRelativeTime(b.LastUsed))))
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(removed) == 0:
+		fmt.Printf("%s\n", ui.RenderSuccess("The cache is within its limits, nothing to remove"))
+	case dryRun:
+		fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("%s would be freed", formatSize(freed))))
+	default:
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Freed %s", formatSize(freed))))
+	}
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	stats := binaryCache.GetStats()
+	if stats.TotalBuilds == 0 {
+		fmt.Printf("%s\n", ui.RenderMuted("The cache is already empty"))
+		return nil
+	}
+	if !yes && !confirm(fmt.Sprintf("Remove %d cached build(s), %s? [y/N]: ", stats.TotalBuilds, formatSize(stats.TotalSize))) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	if err := binaryCache.Clear(); err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Removed %d build(s), freed %s", stats.TotalBuilds, formatSize(stats.TotalSize))))
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the one network call completion may make, so a
+// slow or offline artifacts server doesn't hang the shell
+const completionTimeout = 2 * time.Second
+
+// completeBuilds suggests build channels and numbers for --build. It reads the
+// cached build list and only fetches it (briefly) if nothing is cached yet.
+func completeBuilds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client := download.NewArtifactClient()
+	if targetOS, _ := cmd.Flags().GetString("target-os"); targetOS != "" {
+		if err := client.SetTargetOS(targetOS); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	builds := client.CachedBuilds()
+	if builds == nil {
+		client.SetTimeout(completionTimeout)
+		builds, _ = client.FetchBuilds()
+	}
+
+	completions := make([]string, 0, len(builds)+3)
+	completions = append(completions,
+		types.BuildChannelRecommended+"\tCurrent recommended build",
+		types.BuildChannelOptional+"\tCurrent optional build",
+		types.BuildChannelLatest+"\tNewest build",
+	)
+	for _, build := range builds {
+		completions = append(completions, fmt.Sprintf("%d\t%s", build.Number, buildDescription(build)))
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// buildDescription labels a build for completion menus
+func buildDescription(build types.Build) string {
+	switch {
+	case build.Recommended:
+		return "Recommended"
+	case build.Optional:
+		return "Optional"
+	case !build.Timestamp.IsZero():
+		return build.Timestamp.Format("2006-01-02")
+	}
+	return "FXServer build"
+}
+
+// completeKeyIDs suggests license key IDs from the vault, labelled with each key's label
+func completeKeyIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Never migrate or prompt while completing
+	vault, err := cache.NewKeyVaultWithOptions(keyVaultPath(), vaultOptions("", false))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	keys := vault.List()
+	completions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		completions = append(completions, key.ID+"\t"+key.Label)
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeServerNames suggests registered server names
+func completeServerNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	servers := reg.List()
+	names := make([]string, 0, len(servers))
+	for _, srv := range servers {
+		names = append(names, srv.Name)
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGameBuilds suggests the game builds sv_enforceGameBuild accepts, newest first
+func completeGameBuilds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions := make([]string, 0, len(types.GameBuilds)+1)
+	for i := len(types.GameBuilds) - 1; i >= 0; i-- {
+		build := types.GameBuilds[i]
+		completions = append(completions, strconv.Itoa(build.Number)+"\t"+build.DLC)
+	}
+	completions = append(completions, "none\tDon't enforce a game build")
+
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
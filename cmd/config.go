@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/VexoaXYZ/inkwash/internal/config"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect inkwash configuration and edit servers' server.cfg",
+	Long: `Inspect and validate the inkwash config file (show, validate, path), read
+or change a server's server.cfg (get, set, edit), and set the extra arguments
+and environment FXServer is started with (set-arg, set-env).`,
+	// Config commands report problems themselves
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for unknown or invalid values",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigValidate,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file location",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(configFilePath())
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration and where each value comes from",
+	Long: `Prints every resolved setting with its source: default, file, env or flag.
+Sensitive values are masked.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configShowCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	// Values set in the file itself, so they can be told apart from defaults
+	var fileCfg *viper.Viper
+	if viper.ConfigFileUsed() != "" {
+		fileCfg, _ = config.LoadFile(viper.ConfigFileUsed())
+	}
+
+	// Known keys plus anything extra in the file
+	seen := make(map[string]bool)
+	keys := config.Keys()
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range viper.AllKeys() {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("CONFIGURATION"))
+	if viper.ConfigFileUsed() != "" {
+		fmt.Printf("  %s %s\n\n", ui.RenderMuted("File:"), ui.RenderPath(viper.ConfigFileUsed()))
+	} else {
+		fmt.Printf("  %s %s\n\n", ui.RenderMuted("File:"), ui.RenderMuted("none (using defaults)"))
+	}
+
+	for _, key := range keys {
+		value := fmt.Sprint(viper.Get(key))
+		if config.IsSensitive(key) && value != "" {
+			value = "********"
+		}
+		fmt.Printf("  %-28s %s %s\n", key, ui.RenderAccent(fmt.Sprintf("%-40s", value)), ui.RenderMuted(configSource(cmd, key, fileCfg)))
+	}
+
+	// Derived values that aren't stored in the config
+	fmt.Printf("  %-28s %s %s\n\n", "ui.animation_tier", ui.RenderAccent(fmt.Sprintf("%-40s", ui.DetectAnimationTier().String())), ui.RenderMuted("detected"))
+
+	return nil
+}
+
+// configSource reports which layer a setting's effective value comes from
+func configSource(cmd *cobra.Command, key string, fileCfg *viper.Viper) string {
+	flagName := key
+	if name, ok := boundFlags[key]; ok {
+		flagName = name
+	}
+	if flag := cmd.Flags().Lookup(flagName); flag != nil && flag.Changed {
+		return "flag"
+	}
+	if _, ok := os.LookupEnv(configEnvVar(key)); ok {
+		return "env"
+	}
+	if fileCfg != nil && fileCfg.IsSet(key) {
+		return "file"
+	}
+	return "default"
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path := configFilePath()
+
+	if viper.ConfigFileUsed() == "" && configLoadErr == nil {
+		fmt.Printf("No config file found at %s (using defaults)\n", ui.RenderPath(path))
+		return nil
+	}
+
+	fileCfg, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	issues := config.Validate(fileCfg)
+	if len(issues) == 0 {
+		fmt.Printf("%s %s\n", ui.RenderSuccess("Config is valid:"), ui.RenderPath(path))
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s\n\n", ui.RenderWarning(fmt.Sprintf("Found %d problem(s) in", len(issues))), ui.RenderPath(path))
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "  %s  %s\n", ui.RenderAccent(issue.Key), issue.Message)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	return fmt.Errorf("config has %d problem(s)", len(issues))
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui/console"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console <server-name>",
+	Short: "Attach to a running server's console",
+	Long: `Attach to a running server's console: its log streams in live, and
+commands typed at the bottom are sent to the server over RCON.
+
+RCON needs rcon_password set in the server's server.cfg (it's commented out
+in the generated one). Without it the console opens read-only.
+
+Keys: Enter send, Up/Down command history, PgUp/PgDn scroll, Esc detach.
+Detaching leaves the server running.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+
+		if !isTerminal() {
+			fmt.Fprintf(os.Stderr, "Error: console needs an interactive terminal; use 'inkwash logs %s --follow' instead\n", serverName)
+			os.Exit(exitUsage)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		srv, err := client.Get(serverName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", serverName)
+			os.Exit(exitNotFound)
+		}
+		if !client.IsRunning(srv) {
+			err := fmt.Errorf("%w: '%s'; start it with 'inkwash start %s'", inkwash.ErrServerNotRunning, serverName, serverName)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		// Without RCON the log is still worth watching
+		noRCON := ""
+		rconClient, err := server.DialRCON(srv)
+		switch {
+		case errors.Is(err, server.ErrNoRCONPassword):
+			noRCON = "rcon_password is not set in server.cfg"
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		default:
+			defer rconClient.Close()
+		}
+
+		p := tea.NewProgram(console.New(srv, rconClient, noRCON), tea.WithAltScreen())
+		if _, err := p.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consoleCmd)
+}
@@ -1,25 +1,92 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/VexoaXYZ/inkwash/internal/convert"
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/network"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/internal/ui/wizard"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var convertCmd = &cobra.Command{
 	Use:   "convert",
 	Short: "Convert GTA5 mods to FiveM resources",
-	Long:  `Convert GTA5 mods from gta5-mods.com to FiveM resources using the convert.cfx.rs service.`,
+	Long: `Convert GTA5 mods from gta5-mods.com to FiveM resources using the convert.cfx.rs service.
+
+Each mod is installed into its own folder under resources/. --layout (or
+convert.layout) picks the grouping: category puts it in resources/[category]/<mod>,
+mod in resources/<mod>, and flat extracts the archive into resources/ as-is.
+
+With --ensure (or convert.auto_ensure), mods converted into a registered server
+get "ensure" lines in its server.cfg so they start with the server; otherwise
+the wizard offers to add them once the mods are installed. With --ensure-file
+resources.cfg (or convert.ensure_file), the lines go in a resources.cfg that
+server.cfg execs instead. Resources folders picked by path are left alone.
+
+With --url (repeatable) or --urls-file, mods are converted one after another
+without the wizard into --server's resources folder or --path, so scripts and
+shells without a terminal can run conversions:
+
+  inkwash convert --server myserver --url <gta5-mods-url> --url <another> --json
+
+Failed conversions are retried --retries times; a failed mod doesn't stop the
+batch unless --fail-fast is set. The run ends with a report, and failed URLs
+are written to --failed-file so they can be fed back in with --urls-file.
+--json prints one JSON object per line for each step instead.
+
+The wizard keeps its progress in a session file until every mod is installed.
+Failed mods can be retried from its summary, and a wizard that was closed or
+crashed picks up where it stopped with --resume: converted files are only
+downloaded and running conversions are followed again.
+
+  inkwash convert --resume
+
+Mods that were already downloaded are converted locally with 'convert file'.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		urls, _ := cmd.Flags().GetStringArray("url")
+		urlsFile, _ := cmd.Flags().GetString("urls-file")
+		resume, _ := cmd.Flags().GetBool("resume")
+		if len(urls) > 0 || urlsFile != "" {
+			if resume {
+				fmt.Fprintf(os.Stderr, "Error: --resume is for the wizard and can't be used with --url or --urls-file\n")
+				os.Exit(exitUsage)
+			}
+			if err := runConvertBatch(cmd, urls, urlsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			fmt.Fprintf(os.Stderr, "Error: --json needs --url or --urls-file\n")
+			os.Exit(exitUsage)
+		}
+		if !isTerminal() {
+			fmt.Fprintf(os.Stderr, "Error: the convert wizard needs an interactive terminal; pass --url or --urls-file instead\n")
+			os.Exit(exitUsage)
+		}
+
 		// Load registry
 		reg, err := registry.NewRegistry(registry.GetRegistryPath())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		// Check if any servers exist
@@ -28,17 +95,60 @@ var convertCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error: No servers found. Please create a server first.\n")
 			fmt.Println("\nCreate a server:")
 			fmt.Println("  inkwash create <server-name>")
-			os.Exit(1)
+			os.Exit(exitNotFound)
+		}
+
+		layout, err := resolveConvertLayout(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
 		// Create and run wizard
-		wizardModel := wizard.NewConvertWizard(reg)
+		wizardModel := wizard.NewConvertWizard(reg, newConvertClient())
+		if err := wizardModel.SetLayout(layout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		wizardModel.SetRateLimits(viper.GetInt("convert.max_concurrent"), convertPollInterval())
+		wizardModel.SetAutoEnsure(resolveConvertEnsure(cmd))
+		if err := wizardModel.SetEnsureFile(resolveConvertEnsureFile(cmd)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+
+		// Pick up a wizard that crashed or was closed, asking first unless --resume
+		session, err := wizard.LoadConvertSession()
+		switch {
+		case err != nil && resume:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		case resume && session == nil:
+			fmt.Fprintf(os.Stderr, "Error: no unfinished conversion to resume\n")
+			os.Exit(exitNotFound)
+		case resume:
+			if err := wizardModel.Continue(session); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		case session != nil:
+			prompt := fmt.Sprintf("Found %d URL(s) from an unfinished conversion on %s. Resume it? [y/N]: ",
+				len(session.Pending()), session.SavedAt.Format("2006-01-02 15:04"))
+			if confirm(prompt) {
+				wizardModel.Resume(session)
+			} else {
+				wizard.ClearConvertSession()
+			}
+		}
+
 		p := tea.NewProgram(wizardModel, tea.WithAltScreen())
 
 		finalModel, err := p.Run()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		// Check if wizard completed successfully
@@ -50,6 +160,744 @@ var convertCmd = &cobra.Command{
 	},
 }
 
+var convertHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List previously converted mods",
+	Long:  `List mods converted with inkwash, with the index used by 'convert redownload'.`,
+	Args:  cobra.NoArgs,
+	RunE:  runConvertHistory,
+}
+
+var convertRedownloadCmd = &cobra.Command{
+	Use:   "redownload <url-or-index>",
+	Short: "Re-download a previously converted mod",
+	Long: `Downloads and extracts a mod from the convert history again.
+
+If convert.cfx.rs no longer has the converted file, the mod is converted again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConvertRedownload,
+}
+
+var convertFileCmd = &cobra.Command{
+	Use:   "file <path>",
+	Short: "Convert a mod that was already downloaded",
+	Long: `Converts a mod downloaded from gta5-mods.com without convert.cfx.rs and
+installs it into --server's resources folder or --path.
+
+<path> is the .zip, .rar, .7z or .oiv file, the folder it was extracted to or
+a bare dlc.rpf. FiveM resources shipped in the mod are installed as they are.
+Otherwise the models, textures and meta files are taken out of its dlc.rpf
+(and the archives inside it) and loose files and laid out as a resource with
+stream/ and data/ folders and a generated fxmanifest.lua. Archives encrypted
+with the game's keys can't be read; convert those mods by URL instead.
+
+Without --category, mods with vehicles go in [vehicles] and others in [misc].
+
+  inkwash convert file ~/Downloads/lambo-urus.zip --server myserver`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runConvertFile,
+}
+
 func init() {
 	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.AddCommand(convertHistoryCmd)
+	convertCmd.AddCommand(convertRedownloadCmd)
+	convertCmd.AddCommand(convertFileCmd)
+
+	convertCmd.PersistentFlags().String("layout", "", "Where to put converted mods: category, mod or flat (default: convert.layout)")
+	convertCmd.RegisterFlagCompletionFunc("layout", cobra.FixedCompletions(convert.Layouts, cobra.ShellCompDirectiveNoFileComp))
+
+	convertCmd.Flags().Bool("resume", false, "Resume the last unfinished wizard conversion without asking")
+	convertCmd.Flags().Bool("ensure", false, "Add ensure lines for converted mods to the server's server.cfg (default: convert.auto_ensure)")
+	convertCmd.Flags().String("ensure-file", "", "Where ensure lines go: server.cfg or resources.cfg, exec'd from server.cfg (default: convert.ensure_file)")
+	convertCmd.RegisterFlagCompletionFunc("ensure-file", cobra.FixedCompletions(servercfg.EnsureFiles, cobra.ShellCompDirectiveNoFileComp))
+	convertCmd.Flags().StringArray("url", nil, "Convert this gta5-mods.com URL without the wizard (repeatable)")
+	convertCmd.Flags().String("urls-file", "", "Convert the gta5-mods.com URLs in this file (one per line) without the wizard")
+	convertCmd.Flags().String("server", "", "Without the wizard, the server to install mods into")
+	convertCmd.Flags().String("path", "", "Without the wizard, the resources folder to install mods into")
+	convertCmd.Flags().Int("retries", 2, "Without the wizard, how many times to retry a failed conversion")
+	convertCmd.Flags().Bool("fail-fast", false, "Without the wizard, stop at the first mod that fails instead of continuing")
+	convertCmd.Flags().String("failed-file", "failed.txt", "Without the wizard, where to write the URLs that failed")
+	convertCmd.Flags().Bool("json", false, "Without the wizard, print progress as one JSON object per line")
+	convertCmd.RegisterFlagCompletionFunc("server", completeServerNames)
+
+	convertRedownloadCmd.Flags().String("path", "", "Resources folder to extract into (default: the original location)")
+
+	convertFileCmd.Flags().String("server", "", "Server to install the mod into")
+	convertFileCmd.Flags().String("path", "", "Resources folder to install the mod into")
+	convertFileCmd.Flags().String("name", "", "Folder name for the mod (default: from the file name)")
+	convertFileCmd.Flags().String("category", "", "[category] folder for the category layout (default: vehicles or misc)")
+	convertFileCmd.RegisterFlagCompletionFunc("server", completeServerNames)
+}
+
+// resolveConvertEnsure reports whether converted mods should be ensured, from
+// --ensure or convert.auto_ensure
+func resolveConvertEnsure(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("ensure") {
+		ensure, _ := cmd.Flags().GetBool("ensure")
+		return ensure
+	}
+	return viper.GetBool("convert.auto_ensure")
+}
+
+// resolveConvertEnsureFile returns the file ensure lines are added to, from
+// --ensure-file or convert.ensure_file
+func resolveConvertEnsureFile(cmd *cobra.Command) string {
+	file, _ := cmd.Flags().GetString("ensure-file")
+	if file == "" {
+		file = viper.GetString("convert.ensure_file")
+	}
+	if file == "" {
+		return "server.cfg"
+	}
+	return file
+}
+
+// resolveConvertLayout returns the mod layout from --layout or convert.layout
+func resolveConvertLayout(cmd *cobra.Command) (string, error) {
+	layout, _ := cmd.Flags().GetString("layout")
+	if layout == "" {
+		layout = viper.GetString("convert.layout")
+	}
+	if layout == "" {
+		return convert.DefaultLayout, nil
+	}
+
+	if err := convert.ValidateLayout(layout); err != nil {
+		return "", err
+	}
+	return layout, nil
+}
+
+// newConvertClient creates a convert client using the configured request timeout
+func newConvertClient() *convert.Client {
+	return convert.NewClientWithTimeout(convertTimeout())
+}
+
+// convertTimeout returns the per-request timeout for convert.cfx.rs.
+// An explicit --timeout wins over convert.timeout.
+func convertTimeout() time.Duration {
+	if rootCmd.PersistentFlags().Changed("timeout") {
+		return network.APITimeout()
+	}
+	return time.Duration(viper.GetInt("convert.timeout")) * time.Second
+}
+
+// convertPollInterval returns how long to wait between progress checks of a
+// conversion (convert.poll_interval)
+func convertPollInterval() time.Duration {
+	return time.Duration(viper.GetInt("convert.poll_interval")) * time.Second
+}
+
+func runConvertHistory(cmd *cobra.Command, args []string) error {
+	history, err := convert.LoadHistory(convert.GetHistoryPath())
+	if err != nil {
+		return err
+	}
+
+	entries := history.List()
+	return writeOutput(cmd, entries, func() {
+		if len(entries) == 0 {
+			fmt.Println("No conversions recorded yet")
+			fmt.Println("\nConvert mods:")
+			fmt.Println("  inkwash convert")
+			return
+		}
+
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("CONVERT HISTORY"))
+
+		for i, entry := range entries {
+			fmt.Printf("  %s  %s\n", ui.RenderMuted(fmt.Sprintf("%3d", i+1)), ui.RenderAccent(entry.URL))
+			fmt.Printf("       %s\n", ui.RenderMuted(fmt.Sprintf("[%s] %s • %s",
+				entry.Category, filepath.Base(entry.File), entry.ConvertedAt.Format("2006-01-02 15:04"))))
+			fmt.Printf("       %s\n", ui.RenderPath(entry.ResourcesPath))
+		}
+
+		fmt.Printf("\nTotal: %d conversion(s)\n\n", len(entries))
+	})
+}
+
+func runConvertRedownload(cmd *cobra.Command, args []string) error {
+	pathOverride, _ := cmd.Flags().GetString("path")
+	layout, err := resolveConvertLayout(cmd)
+	if err != nil {
+		return err
+	}
+
+	history, err := convert.LoadHistory(convert.GetHistoryPath())
+	if err != nil {
+		return err
+	}
+
+	entry, err := history.Find(args[0])
+	if err != nil {
+		return err
+	}
+
+	resourcesPath := entry.ResourcesPath
+	if pathOverride != "" {
+		resourcesPath = filepath.Clean(pathOverride)
+	}
+	if err := os.MkdirAll(resourcesPath, 0755); err != nil {
+		return fmt.Errorf("failed to create resources directory: %w", err)
+	}
+
+	client := newConvertClient()
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	fmt.Printf("Downloading %s...\n", ui.RenderAccent(entry.URL))
+	destPath, err := downloadConverted(ctx, client, entry, resourcesPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("redownload cancelled")
+		}
+		return err
+	}
+
+	stagePath, err := os.MkdirTemp(resourcesPath, ".inkwash-extract-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging folder: %w", err)
+	}
+	defer os.RemoveAll(stagePath)
+
+	if err := download.NewExtractor().Extract(destPath, stagePath); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(entry.File), err)
+	}
+	os.Remove(destPath)
+
+	// Extraction can't be interrupted; stop before touching the resources folder
+	if ctx.Err() != nil {
+		return fmt.Errorf("redownload cancelled")
+	}
+
+	report, err := convert.Inspect(stagePath)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", filepath.Base(entry.File), err)
+	}
+
+	installPath, _, err := convert.Install(stagePath, resourcesPath, layout, entry.Category, convert.ModFolderName(entry.URL))
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %w", filepath.Base(entry.File), err)
+	}
+
+	entry.ResourcesPath = resourcesPath
+	entry.ConvertedAt = time.Now()
+	if err := history.Record(*entry); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess("Extracted to "+installPath))
+	printConvertReport(report, "  ")
+	return nil
+}
+
+// convertDownloader is the part of the convert.cfx.rs client redownloads use
+type convertDownloader interface {
+	StartConversionContext(ctx context.Context, modURL string) (string, error)
+	WaitForConversionContext(ctx context.Context, uuid string, interval, timeout time.Duration) (*convert.ConversionStatus, error)
+	GetDownloadURL(file string) string
+	DownloadFileContext(ctx context.Context, fileURL, destPath string) error
+}
+
+// downloadConverted downloads a history entry's converted file into
+// resourcesPath and returns where it went. Converted files expire on
+// convert.cfx.rs, so if it's gone the mod is converted again and entry is
+// updated with the new conversion.
+func downloadConverted(ctx context.Context, client convertDownloader, entry *convert.HistoryEntry, resourcesPath string) (string, error) {
+	destPath := filepath.Join(resourcesPath, filepath.Base(entry.File))
+	err := client.DownloadFileContext(ctx, client.GetDownloadURL(entry.File), destPath)
+
+	if errors.Is(err, convert.ErrFileExpired) {
+		os.Remove(destPath)
+		fmt.Println(ui.RenderMuted("Converted file has expired, converting again..."))
+
+		var uuid string
+		uuid, err = client.StartConversionContext(ctx, entry.URL)
+		if err != nil {
+			return "", err
+		}
+
+		var status *convert.ConversionStatus
+		status, err = client.WaitForConversionContext(ctx, uuid, convertPollInterval(), 10*time.Minute)
+		if err != nil {
+			return "", err
+		}
+
+		entry.UUID = uuid
+		entry.File = status.File
+		destPath = filepath.Join(resourcesPath, filepath.Base(entry.File))
+		err = client.DownloadFileContext(ctx, client.GetDownloadURL(entry.File), destPath)
+	}
+	if err != nil {
+		os.Remove(destPath)
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+// convertFileResult is what convert file prints with --output json
+type convertFileResult struct {
+	Path     string   `json:"path"`
+	Vehicles []string `json:"vehicles"`
+	Warnings []string `json:"warnings"`
+}
+
+func runConvertFile(cmd *cobra.Command, args []string) error {
+	serverName, _ := cmd.Flags().GetString("server")
+	pathFlag, _ := cmd.Flags().GetString("path")
+	name, _ := cmd.Flags().GetString("name")
+	category, _ := cmd.Flags().GetString("category")
+
+	layout, err := resolveConvertLayout(cmd)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		return fmt.Errorf("mod file not found: %s", args[0])
+	} else if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var resourcesPath string
+	switch {
+	case serverName != "" && pathFlag != "":
+		return &usageError{fmt.Errorf("--server and --path can't be used together")}
+	case serverName != "":
+		srv, err := client.Get(serverName)
+		if err != nil {
+			return err
+		}
+		resourcesPath = filepath.Join(srv.Path, "resources")
+	case pathFlag != "":
+		resourcesPath = filepath.Clean(pathFlag)
+	default:
+		return &usageError{fmt.Errorf("--server or --path is needed to install the mod into")}
+	}
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	var report *inkwash.ConvertReport
+	opts := inkwash.ConvertOptions{
+		ResourcesPath: resourcesPath,
+		Layout:        layout,
+		Name:          name,
+		Category:      category,
+		OnInspect:     func(r *inkwash.ConvertReport) { report = r },
+	}
+	if !jsonOutput(cmd) {
+		fmt.Printf("Converting %s...\n", ui.RenderAccent(filepath.Base(args[0])))
+	}
+
+	installPath, err := client.ConvertFile(ctx, args[0], opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("conversion cancelled")
+		}
+		return err
+	}
+
+	result := convertFileResult{Path: installPath, Vehicles: report.Vehicles, Warnings: report.Warnings()}
+	if result.Vehicles == nil {
+		result.Vehicles = []string{}
+	}
+	if result.Warnings == nil {
+		result.Warnings = []string{}
+	}
+	return writeOutput(cmd, result, func() {
+		fmt.Printf("%s\n", ui.RenderSuccess("Installed to "+installPath))
+		printConvertReport(report, "  ")
+	})
+}
+
+// printConvertReport lists what was fixed or found in a converted mod and the
+// vehicles it adds
+func printConvertReport(report *inkwash.ConvertReport, indent string) {
+	for _, warning := range report.Warnings() {
+		fmt.Printf("%s%s\n", indent, ui.RenderWarning(ui.SymbolWarning+" "+warning))
+	}
+	if len(report.Vehicles) > 0 {
+		fmt.Printf("%sVehicles to spawn: %s\n", indent, ui.RenderAccent(strings.Join(report.Vehicles, ", ")))
+	}
+}
+
+// Outcomes of a mod in a batch run
+const (
+	batchSucceeded = "succeeded"
+	batchFailed    = "failed"
+	batchSkipped   = "skipped"
+)
+
+// batchResult is what happened to one URL of a batch run
+type batchResult struct {
+	url      string
+	status   string // batchSucceeded, batchFailed or batchSkipped
+	detail   string // Install folder, error or reason for skipping
+	attempts int
+	retry    bool                   // Written to --failed-file
+	report   *inkwash.ConvertReport // Set for installed mods
+}
+
+// runConvertBatch converts the --url URLs and every URL in urlsFile without
+// the wizard and reports what succeeded, failed and was skipped
+func runConvertBatch(cmd *cobra.Command, urls []string, urlsFile string) error {
+	serverName, _ := cmd.Flags().GetString("server")
+	pathFlag, _ := cmd.Flags().GetString("path")
+	retries, _ := cmd.Flags().GetInt("retries")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	failedFile, _ := cmd.Flags().GetString("failed-file")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	if retries < 0 {
+		return &usageError{fmt.Errorf("--retries can't be negative")}
+	}
+	if cmd.Flags().Changed("ensure") {
+		return &usageError{fmt.Errorf("--ensure only works in the wizard, run 'inkwash resource scan <server> --fix' after the batch")}
+	}
+
+	layout, err := resolveConvertLayout(cmd)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var resourcesPath string
+	switch {
+	case serverName != "" && pathFlag != "":
+		return &usageError{fmt.Errorf("--server and --path can't be used together")}
+	case serverName != "":
+		srv, err := client.Get(serverName)
+		if err != nil {
+			return err
+		}
+		resourcesPath = filepath.Join(srv.Path, "resources")
+	case pathFlag != "":
+		resourcesPath = filepath.Clean(pathFlag)
+	default:
+		return &usageError{fmt.Errorf("converting without the wizard needs --server or --path to install into")}
+	}
+
+	if urlsFile != "" {
+		fromFile, err := readURLsFile(urlsFile)
+		if err != nil {
+			return err
+		}
+		if len(fromFile) == 0 && len(urls) == 0 {
+			return fmt.Errorf("no URLs found in %s", urlsFile)
+		}
+		urls = append(urls, fromFile...)
+	}
+
+	out := newBatchOutput(asJSON)
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	opts := inkwash.ConvertOptions{
+		ResourcesPath: resourcesPath,
+		Layout:        layout,
+		Timeout:       convertTimeout(),
+		PollInterval:  convertPollInterval(),
+	}
+
+	out.begin(len(urls), resourcesPath)
+
+	results := make([]batchResult, 0, len(urls))
+	seen := make(map[string]bool)
+	stopReason := ""
+
+	for i, url := range urls {
+		out.start(i+1, len(urls), url)
+
+		result := batchResult{url: url}
+		switch {
+		case seen[url]:
+			result.status, result.detail = batchSkipped, "listed more than once"
+
+		case !strings.Contains(url, "gta5-mods.com"):
+			result.status, result.detail = batchSkipped, "not a gta5-mods.com URL"
+
+		case stopReason != "":
+			result.status, result.detail, result.retry = batchSkipped, stopReason, true
+
+		default:
+			modOpts := opts
+			modOpts.OnStep = func(step string) { out.step(i+1, url, step) }
+			modOpts.OnRetry = func(step string, attempt int, err error, wait time.Duration) {
+				out.requestRetry(i+1, url, step, attempt, err, wait)
+			}
+			modOpts.OnInspect = func(report *inkwash.ConvertReport) { result.report = report }
+
+			installPath, attempts, err := convertWithRetries(ctx, client, url, modOpts, retries, out)
+			result.attempts = attempts
+
+			switch {
+			case err == nil:
+				result.status, result.detail = batchSucceeded, installPath
+			case ctx.Err() != nil:
+				result.status, result.detail, result.retry = batchSkipped, "cancelled", true
+				stopReason = "cancelled"
+			default:
+				result.status, result.detail, result.retry = batchFailed, err.Error(), true
+				if failFast {
+					stopReason = "not attempted, an earlier mod failed (--fail-fast)"
+				}
+			}
+		}
+
+		out.result(i+1, result)
+
+		seen[url] = true
+		results = append(results, result)
+	}
+
+	written, err := writeFailedURLs(failedFile, results)
+	if err != nil {
+		return err
+	}
+	failed := out.report(results, written)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("batch cancelled: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d mod(s) failed", failed, len(urls))
+	}
+	return nil
+}
+
+// convertWithRetries converts a mod, retrying up to retries times with a
+// growing pause. It returns the install folder and how many attempts it took.
+func convertWithRetries(ctx context.Context, client *inkwash.Client, url string, opts inkwash.ConvertOptions, retries int, out *batchOutput) (string, int, error) {
+	for attempt := 1; ; attempt++ {
+		installPath, err := client.Convert(ctx, url, opts)
+		if err == nil || attempt > retries || ctx.Err() != nil {
+			return installPath, attempt, err
+		}
+
+		wait := time.Duration(attempt) * 5 * time.Second
+		out.retry(url, attempt, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return "", attempt, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// batchOutput prints a batch run, as styled text or, with --json, as one
+// batchEvent per line
+type batchOutput struct {
+	enc *json.Encoder // nil for text
+}
+
+// batchEvent is one line of --json output. Event is begin, start, step,
+// retry, result or summary; only the fields that event has are set.
+type batchEvent struct {
+	Event    string   `json:"event"`
+	Index    int      `json:"index,omitempty"` // 1-based position of the URL in the batch
+	Total    int      `json:"total,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Path     string   `json:"path,omitempty"` // Resources folder (begin) or install folder (result)
+	Step     string   `json:"step,omitempty"`
+	Status   string   `json:"status,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Reason   string   `json:"reason,omitempty"` // Why a URL was skipped
+	Attempts int      `json:"attempts,omitempty"`
+	RetryIn  float64  `json:"retry_in_seconds,omitempty"`
+	Vehicles []string `json:"vehicles,omitempty"` // Spawn names of an installed mod's vehicles
+	Warnings []string `json:"warnings,omitempty"` // Generated manifests and unreferenced data files
+}
+
+// batchSummary is the last line of --json output
+type batchSummary struct {
+	Event      string `json:"event"`
+	Succeeded  int    `json:"succeeded"`
+	Failed     int    `json:"failed"`
+	Skipped    int    `json:"skipped"`
+	FailedFile string `json:"failed_file,omitempty"` // Set if failed URLs were written
+}
+
+func newBatchOutput(asJSON bool) *batchOutput {
+	if asJSON {
+		return &batchOutput{enc: json.NewEncoder(os.Stdout)}
+	}
+	return &batchOutput{}
+}
+
+func (o *batchOutput) begin(total int, resourcesPath string) {
+	if o.enc != nil {
+		o.enc.Encode(batchEvent{Event: "begin", Total: total, Path: resourcesPath})
+		return
+	}
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("CONVERT"))
+	fmt.Printf("  Converting %d mod(s) into %s\n\n", total, ui.RenderPath(resourcesPath))
+}
+
+func (o *batchOutput) start(index, total int, url string) {
+	if o.enc != nil {
+		o.enc.Encode(batchEvent{Event: "start", Index: index, Total: total, URL: url})
+		return
+	}
+	fmt.Printf("  %s %s\n", ui.RenderMuted(fmt.Sprintf("[%d/%d]", index, total)), url)
+}
+
+// step is only reported as JSON; the text output shows the outcome alone
+func (o *batchOutput) step(index int, url, step string) {
+	if o.enc != nil {
+		o.enc.Encode(batchEvent{Event: "step", Index: index, URL: url, Step: step})
+	}
+}
+
+func (o *batchOutput) retry(url string, attempt int, err error, wait time.Duration) {
+	if o.enc != nil {
+		o.enc.Encode(batchEvent{Event: "retry", URL: url, Attempts: attempt, Error: err.Error(), RetryIn: wait.Seconds()})
+		return
+	}
+	fmt.Printf("        %s\n", ui.RenderMuted(fmt.Sprintf("Attempt %d failed (%v), retrying in %s", attempt, err, wait)))
+}
+
+// requestRetry reports a single request being retried within step, as
+// opposed to retry, which starts the whole mod over
+func (o *batchOutput) requestRetry(index int, url, step string, attempt int, err error, wait time.Duration) {
+	if o.enc != nil {
+		o.enc.Encode(batchEvent{Event: "retry", Index: index, URL: url, Step: step, Attempts: attempt, Error: err.Error(), RetryIn: wait.Seconds()})
+		return
+	}
+	fmt.Printf("        %s\n", ui.RenderMuted(fmt.Sprintf("%s: attempt %d failed (%v), retrying in %s", step, attempt, err, wait.Round(100*time.Millisecond))))
+}
+
+func (o *batchOutput) result(index int, r batchResult) {
+	if o.enc != nil {
+		event := batchEvent{Event: "result", Index: index, URL: r.url, Status: r.status, Attempts: r.attempts}
+		switch r.status {
+		case batchSucceeded:
+			event.Path = r.detail
+			if r.report != nil {
+				event.Vehicles, event.Warnings = r.report.Vehicles, r.report.Warnings()
+			}
+		case batchFailed:
+			event.Error = r.detail
+		default:
+			event.Reason = r.detail
+		}
+		o.enc.Encode(event)
+		return
+	}
+
+	switch r.status {
+	case batchSucceeded:
+		fmt.Printf("        %s\n", ui.RenderSuccess("Installed to "+r.detail))
+		if r.report != nil {
+			printConvertReport(r.report, "        ")
+		}
+	case batchFailed:
+		fmt.Printf("        %s\n", ui.RenderError(r.detail))
+	default:
+		fmt.Printf("        %s\n", ui.RenderMuted("Skipped: "+r.detail))
+	}
+}
+
+// report prints the end-of-run summary and returns how many mods failed.
+// failedFile is where the URLs worth retrying were written, or "".
+func (o *batchOutput) report(results []batchResult, failedFile string) int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.status]++
+	}
+
+	if o.enc != nil {
+		o.enc.Encode(batchSummary{
+			Event:      "summary",
+			Succeeded:  counts[batchSucceeded],
+			Failed:     counts[batchFailed],
+			Skipped:    counts[batchSkipped],
+			FailedFile: failedFile,
+		})
+		return counts[batchFailed]
+	}
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("REPORT"))
+	fmt.Printf("  %s   %s   %s\n\n",
+		ui.RenderAccent(fmt.Sprintf("%d succeeded", counts[batchSucceeded])),
+		fmt.Sprintf("%d failed", counts[batchFailed]),
+		ui.RenderMuted(fmt.Sprintf("%d skipped", counts[batchSkipped])))
+
+	for _, status := range []string{batchFailed, batchSkipped} {
+		if counts[status] == 0 {
+			continue
+		}
+		fmt.Printf("  %s\n", strings.ToUpper(status[:1])+status[1:]+":")
+		for _, r := range results {
+			if r.status != status {
+				continue
+			}
+			detail := r.detail
+			if r.attempts > 1 {
+				detail = fmt.Sprintf("%s (%d attempts)", detail, r.attempts)
+			}
+			fmt.Printf("    %s %s\n      %s\n", ui.RenderMuted(ui.SymbolDot), r.url, ui.RenderMuted(detail))
+		}
+		fmt.Println()
+	}
+
+	if failedFile != "" {
+		fmt.Printf("  Failed URLs written to %s, retry them with %s\n\n",
+			ui.RenderPath(failedFile), ui.RenderCode("inkwash convert --urls-file "+failedFile))
+	}
+
+	return counts[batchFailed]
+}
+
+// writeFailedURLs writes the URLs worth retrying to path, one per line, so
+// they can be passed back with --urls-file. It returns path, or "" if all
+// went well and nothing was written.
+func writeFailedURLs(path string, results []batchResult) (string, error) {
+	var b strings.Builder
+	for _, r := range results {
+		if r.retry {
+			b.WriteString(r.url + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return "", nil
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// readURLsFile reads one URL per line, skipping blank lines and # comments
+func readURLsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URLs file: %w", err)
+	}
+
+	var urls []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, nil
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/convert"
+)
+
+// stubConvertClient serves files from a map, reporting the rest as expired,
+// and converts every mod to newFile
+type stubConvertClient struct {
+	files       map[string]string
+	newFile     string
+	conversions int
+}
+
+func (s *stubConvertClient) StartConversionContext(ctx context.Context, modURL string) (string, error) {
+	s.conversions++
+	return "new-uuid", nil
+}
+
+func (s *stubConvertClient) WaitForConversionContext(ctx context.Context, uuid string, interval, timeout time.Duration) (*convert.ConversionStatus, error) {
+	s.files[s.newFile] = "converted again"
+	return &convert.ConversionStatus{Progress: 100, File: s.newFile}, nil
+}
+
+func (s *stubConvertClient) GetDownloadURL(file string) string {
+	return file
+}
+
+func (s *stubConvertClient) DownloadFileContext(ctx context.Context, fileURL, destPath string) error {
+	content, ok := s.files[fileURL]
+	if !ok {
+		return convert.ErrFileExpired
+	}
+	return os.WriteFile(destPath, []byte(content), 0644)
+}
+
+func TestDownloadConverted(t *testing.T) {
+	t.Run("available", func(t *testing.T) {
+		dir := t.TempDir()
+		client := &stubConvertClient{files: map[string]string{"files/old.zip": "original"}}
+		entry := &convert.HistoryEntry{URL: "https://www.gta5-mods.com/vehicles/car", UUID: "old-uuid", File: "files/old.zip"}
+
+		path, err := downloadConverted(context.Background(), client, entry, dir)
+		if err != nil {
+			t.Fatalf("downloadConverted: %v", err)
+		}
+		if path != filepath.Join(dir, "old.zip") {
+			t.Errorf("path = %q, want old.zip in %s", path, dir)
+		}
+		if client.conversions != 0 {
+			t.Errorf("converted %d time(s), want 0", client.conversions)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		dir := t.TempDir()
+		client := &stubConvertClient{files: map[string]string{}, newFile: "files/new.zip"}
+		entry := &convert.HistoryEntry{URL: "https://www.gta5-mods.com/vehicles/car", UUID: "old-uuid", File: "files/old.zip"}
+
+		path, err := downloadConverted(context.Background(), client, entry, dir)
+		if err != nil {
+			t.Fatalf("downloadConverted: %v", err)
+		}
+		if client.conversions != 1 {
+			t.Errorf("converted %d time(s), want 1", client.conversions)
+		}
+		if path != filepath.Join(dir, "new.zip") {
+			t.Errorf("path = %q, want new.zip in %s", path, dir)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != "converted again" {
+			t.Errorf("downloaded file = %q, %v; want the new conversion", data, err)
+		}
+		if entry.UUID != "new-uuid" || entry.File != "files/new.zip" {
+			t.Errorf("entry = %+v, want the new conversion recorded", entry)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+)
+
+var crashesCmd = &cobra.Command{
+	Use:   "crashes <server-name> [crash-id]",
+	Short: "List a server's crashes, or show one",
+	Long: `When a server's process exits without 'inkwash stop', the daemon, 'inkwash
+watch' or the next 'inkwash start' counts the crash in metadata.json and saves
+the last 200 lines of the log, plus any CitizenFX crash dumps written since the
+server started, in crashes/<timestamp>/ in the server's folder. The latest 10
+crashes are kept.
+
+Without a crash ID, lists the kept crashes. With one (or "latest"), shows that
+crash's dumps and saved log.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runCrashes,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(crashesCmd)
+}
+
+// crashView is a collected crash as `crashes --output json` shows it
+type crashView struct {
+	inkwash.CrashReport
+	Path string `json:"path"`
+}
+
+func runCrashes(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	srv, err := client.Get(args[0])
+	if err != nil {
+		return err
+	}
+	reports, err := client.Crashes(srv.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		return showCrash(cmd, srv.Name, reports, args[1])
+	}
+
+	views := make([]crashView, 0, len(reports))
+	for _, r := range reports {
+		views = append(views, crashView{CrashReport: r, Path: r.Dir})
+	}
+
+	return writeOutput(cmd, views, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("CRASHES: "+srv.Name))
+
+		metadata, err := server.NewMetadataManager().Load(srv.Path)
+		if err == nil && metadata.Stats.LastCrash != nil {
+			fmt.Printf("  %s %d, last %s\n\n", ui.RenderMuted("Crashed:"), metadata.Stats.CrashCount, formatRelativeTime(*metadata.Stats.LastCrash))
+		}
+
+		if len(views) == 0 {
+			fmt.Printf("  %s\n\n", ui.RenderMuted("No crashes collected"))
+			return
+		}
+
+		for _, v := range views {
+			detail := fmt.Sprintf("PID %d", v.PID)
+			if !v.StartedAt.IsZero() {
+				detail += fmt.Sprintf(", noticed %s after it started", formatDuration(v.Time.Sub(v.StartedAt).Round(time.Second)))
+			}
+			detail += fmt.Sprintf(", %d log line(s), %d dump(s)", v.LogLines, len(v.Dumps))
+			fmt.Printf("  %s  %s  %s\n", ui.RenderAccent(fmt.Sprintf("%-18s", v.ID)), formatTime(v.Time), ui.RenderMuted(detail))
+		}
+		fmt.Printf("\n  %s %s\n", ui.RenderMuted("Folder:"), ui.RenderPath(server.CrashesDir(srv.Path)))
+		fmt.Printf("  %s\n\n", ui.RenderMuted(fmt.Sprintf("Show one with 'inkwash crashes %s <crash-id>'", srv.Name)))
+	})
+}
+
+// showCrash prints one crash's details and the log lines kept with it
+func showCrash(cmd *cobra.Command, serverName string, reports []inkwash.CrashReport, id string) error {
+	var report *inkwash.CrashReport
+	for i := range reports {
+		if reports[i].ID == id || (id == "latest" && i == 0) {
+			report = &reports[i]
+			break
+		}
+	}
+	if report == nil {
+		return fmt.Errorf("no crash '%s' for '%s'; list them with 'inkwash crashes %s'", id, serverName, serverName)
+	}
+
+	lines, logErr := server.CrashLog(report)
+	if lines == nil {
+		lines = []string{}
+	}
+
+	data := struct {
+		crashView
+		Log []string `json:"log"`
+	}{crashView{CrashReport: *report, Path: report.Dir}, lines}
+
+	return writeOutput(cmd, data, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("CRASH "+report.ID))
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Noticed:"), formatTime(report.Time))
+		fmt.Printf("  %s %d\n", ui.RenderMuted("PID:    "), report.PID)
+		if !report.StartedAt.IsZero() {
+			fmt.Printf("  %s %s\n", ui.RenderMuted("Started:"), formatTime(report.StartedAt))
+		}
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Folder: "), ui.RenderPath(report.Dir))
+		for _, dump := range report.Dumps {
+			fmt.Printf("  %s %s\n", ui.RenderMuted("Dump:   "), dump)
+		}
+		fmt.Println()
+
+		if logErr != nil {
+			fmt.Printf("  %s\n\n", ui.RenderMuted("No log was saved with this crash"))
+			return
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	})
+}
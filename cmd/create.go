@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/download"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/internal/ui/wizard"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,41 +28,95 @@ var createCmd = &cobra.Command{
 	Long: `Create a new FiveM server with interactive configuration.
 
 If server name is provided, uses defaults for other options.
-Otherwise, launches interactive wizard.`,
+Otherwise, launches interactive wizard.
+
+With --recipe, the server is set up from a txAdmin recipe (a file or an
+http(s) URL) instead of cfx-server-data: its download_github, unzip, move,
+replace_string and other tasks run in the new server folder. Database tasks
+can't be run by inkwash; they're listed so the database can be set up by hand.
+
+With --template, a template is applied once the server is set up, like
+'inkwash template apply'. The built-in esx and qbcore templates install ESX
+Legacy or QBCore with oxmysql; the wizard offers them as frameworks and can
+create their database too.`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		targetOS, err := resolveTargetOS(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		warnIfStaging(targetOS)
+
+		var serverRecipe *inkwash.Recipe
+		if source, _ := cmd.Flags().GetString("recipe"); source != "" {
+			serverRecipe, err = inkwash.LoadRecipe(cmd.Context(), source)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+
+		var serverTemplate *types.Template
+		if name, _ := cmd.Flags().GetString("template"); name != "" {
+			serverTemplate, err = inkwash.FindTemplate(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+
 		if len(args) == 0 {
 			// Launch interactive wizard
-			cachePath := registry.GetDefaultCachePath()
-			binaryCache, err := cache.NewBinaryCache(cachePath, viper.GetInt("cache.max_builds"))
+			binaryCache, err := openBinaryCache()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: Failed to initialize cache: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 
 			registryPath := registry.GetRegistryPath()
 			reg, err := registry.NewRegistry(registryPath)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: Failed to initialize registry: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 
-			vaultPath := registry.GetDefaultConfigPath() + "/keys.enc"
-			vault, err := cache.NewKeyVault(vaultPath)
+			vault, err := openKeyVault()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: Failed to load key vault: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 
 			installer := server.NewInstaller(binaryCache, reg)
+			installer.SetLongPaths(viper.GetBool("advanced.long_paths"))
+			installer.SetVerifyCache(viper.GetBool("cache.verify"))
 			wizardModel := wizard.NewCreateWizard(installer, vault, reg)
+			if err := wizardModel.SetTargetOS(targetOS); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			wizardModel.SetRecipe(serverRecipe)
+			wizardModel.SetTemplate(serverTemplate)
+
+			// Offer to pick up a wizard that crashed or was closed
+			if session, err := wizard.LoadCreateSession(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			} else if session != nil {
+				prompt := fmt.Sprintf("Found an unfinished setup of '%s' from %s. Resume it? [y/N]: ",
+					session.ServerName, session.SavedAt.Format("2006-01-02 15:04"))
+				if confirm(prompt) {
+					wizardModel.Resume(session)
+				} else {
+					wizard.ClearCreateSession()
+				}
+			}
 
 			p := tea.NewProgram(wizardModel, tea.WithAltScreen())
 			finalModel, err := p.Run()
 
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 
 			// Check completion
@@ -66,12 +129,34 @@ Otherwise, launches interactive wizard.`,
 		}
 
 		serverName := args[0]
+		if err := validation.ValidateServerName(serverName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
 
 		// Get flags
-		buildNumber, _ := cmd.Flags().GetInt("build")
+		buildFlag, _ := cmd.Flags().GetString("build")
 		keyID, _ := cmd.Flags().GetString("key")
 		port, _ := cmd.Flags().GetInt("port")
 		installPath, _ := cmd.Flags().GetString("path")
+		bindAddress, _ := cmd.Flags().GetString("bind")
+		noScaffold, _ := cmd.Flags().GetBool("no-scaffold")
+		gameBuildFlag, _ := cmd.Flags().GetString("game-build")
+		force, _ := cmd.Flags().GetBool("force")
+		artifactURL, _ := cmd.Flags().GetString("artifact-url")
+		forceRedownload, _ := cmd.Flags().GetBool("force-redownload")
+		launchCommand, _ := cmd.Flags().GetString("launch-command")
+		verify, _ := cmd.Flags().GetBool("verify")
+		sha256, _ := cmd.Flags().GetString("sha256")
+		progressMode, _ := cmd.Flags().GetString("progress")
+
+		onProgress, err := progressPrinter(progressMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// JSON output is for programs, so the human-readable lines stay out of it
+		quiet := progressMode == progressJSON
 
 		if installPath == "" {
 			installPath = viper.GetString("defaults.install_path")
@@ -81,72 +166,237 @@ Otherwise, launches interactive wizard.`,
 			port = viper.GetInt("defaults.port")
 		}
 
-		// Initialize systems
-		cachePath := registry.GetDefaultCachePath()
-		binaryCache, err := cache.NewBinaryCache(cachePath, viper.GetInt("cache.max_builds"))
+		if bindAddress == "" {
+			bindAddress = viper.GetString("defaults.bind_address")
+		}
+
+		if err := validation.ValidateBindAddress(bindAddress); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		if sum, err := hex.DecodeString(sha256); sha256 != "" && (err != nil || len(sum) != 32) {
+			fmt.Fprintf(os.Stderr, "Error: --sha256 must be 64 hex characters\n")
+			os.Exit(exitUsage)
+		}
+
+		var buildNumber int
+		var buildChannel string
+		if artifactURL != "" {
+			if cmd.Flags().Changed("build") {
+				fmt.Fprintf(os.Stderr, "Error: --build and --artifact-url can't be used together\n")
+				os.Exit(exitUsage)
+			}
+			if _, _, err := validation.ParseArtifactURL(artifactURL); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		} else {
+			buildNumber, buildChannel, err = validation.ParseBuild(buildFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+
+		gameBuild, err := validation.ParseGameBuild(gameBuildFlag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to initialize cache: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
-		registryPath := registry.GetRegistryPath()
-		reg, err := registry.NewRegistry(registryPath)
+		client, err := newClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to initialize registry: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
 		// Get license key
 		var licenseKey string
 		if keyID != "" {
-			vaultPath := registry.GetDefaultConfigPath() + "/keys.enc"
-			vault, err := cache.NewKeyVault(vaultPath)
+			vault, err := openKeyVault()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: Failed to load key vault: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 
 			key, err := vault.Get(keyID)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: License key not found: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitNotFound)
 			}
 
 			licenseKey = key.Key
+			warnKeyShared(client.ServersUsingKey(key.ID))
 		}
 
-		// Create installer
-		installer := server.NewInstaller(binaryCache, reg)
-
 		// Install with progress
-		fmt.Printf("Creating server '%s'...\n\n", serverName)
+		if !quiet {
+			fmt.Printf("Creating server '%s'...\n\n", serverName)
+		}
 
-		err = installer.Install(serverName, installPath, buildNumber, licenseKey, port, func(progress server.InstallProgress) {
-			fmt.Printf("[%d/%d] %s", progress.CompletedSteps, progress.TotalSteps, progress.Step)
+		opts := inkwash.CreateOptions{
+			Name:         serverName,
+			Path:         installPath,
+			Build:        buildNumber,
+			BuildChannel: buildChannel,
+			LicenseKey:   licenseKey,
+			KeyID:        keyID,
+			Port:         port,
+			BindAddress:  bindAddress,
+			NoScaffold:   noScaffold,
+			TargetOS:     targetOS,
+			GameBuild:    gameBuild,
+			Force:        force,
+			ArtifactURL:  artifactURL,
 
-			if progress.DownloadSpeed > 0 {
-				fmt.Printf(" (%.1f MB/s, ETA: %s)", progress.DownloadSpeed, progress.DownloadETA.Round(1))
-			}
+			ForceRedownload: forceRedownload,
+			LaunchCommand:   launchCommand,
 
-			fmt.Println()
-		})
+			Verify: verify,
+			SHA256: sha256,
+
+			Recipe:   serverRecipe,
+			Template: serverTemplate,
+		}
 
+		// Ctrl+C rolls the install back instead of leaving a half-written server
+		ctx, stop := interruptContext(cmd.Context())
+		_, err = client.Create(ctx, opts, onProgress)
+		cancelled := ctx.Err() != nil
+		stop()
+
+		if err != nil && cancelled {
+			fmt.Fprintf(os.Stderr, "Installation cancelled.\n")
+			os.Exit(exitInterrupted)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
+		if quiet {
+			return
+		}
 		fmt.Printf("\n✓ Server '%s' created successfully!\n", serverName)
+		if targetOS != types.HostTargetOS() {
+			fmt.Printf("\nCopy the server folder to a %s machine to run it.\n", targetOS)
+			return
+		}
 		fmt.Printf("\nStart your server:\n")
 		fmt.Printf("  inkwash start %s\n", serverName)
 	},
 }
 
+// Progress output formats for non-interactive installs
+const (
+	progressText = "text"
+	progressJSON = "json"
+)
+
+// progressEvent is one line of --progress json output
+type progressEvent struct {
+	Step       string  `json:"step"`
+	Completed  int     `json:"completed"`
+	Total      int     `json:"total"`
+	Percent    float64 `json:"percent"`
+	Speed      float64 `json:"speed_mbps,omitempty"`
+	ETASeconds float64 `json:"eta_seconds,omitempty"`
+	File       string  `json:"file,omitempty"`
+	Warning    bool    `json:"warning,omitempty"`
+}
+
+// progressPrinter returns the progress callback for a --progress format
+func progressPrinter(mode string) (inkwash.ProgressFunc, error) {
+	switch mode {
+	case progressText:
+		return func(progress inkwash.Progress) {
+			if progress.Warning {
+				fmt.Printf("[%d/%d] %s\n", progress.Completed, progress.Total, ui.RenderWarning(ui.SymbolWarning+" "+progress.Step))
+				return
+			}
+			fmt.Printf("[%d/%d] %s", progress.Completed, progress.Total, progress.Step)
+
+			if progress.DownloadSpeed > 0 {
+				fmt.Printf(" (%.1f MB/s, ETA: %s)", progress.DownloadSpeed, progress.DownloadETA.Round(1))
+			}
+
+			fmt.Println()
+		}, nil
+
+	case progressJSON:
+		// One object per line (NDJSON) so consumers can act on each update as it arrives
+		enc := json.NewEncoder(os.Stdout)
+		return func(progress inkwash.Progress) {
+			enc.Encode(progressEvent{
+				Step:       progress.Step,
+				Completed:  progress.Completed,
+				Total:      progress.Total,
+				Percent:    math.Round(progress.Percent*10) / 10,
+				Speed:      math.Round(progress.DownloadSpeed*100) / 100,
+				ETASeconds: progress.DownloadETA.Round(time.Second).Seconds(),
+				File:       progress.CurrentFile,
+				Warning:    progress.Warning,
+			})
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown progress format %q (expected %s or %s)", mode, progressText, progressJSON)
+}
+
+// resolveTargetOS returns the platform to install builds for from --target-os,
+// defaults.target_os or the host
+func resolveTargetOS(cmd *cobra.Command) (string, error) {
+	targetOS, _ := cmd.Flags().GetString("target-os")
+	if targetOS == "" {
+		targetOS = viper.GetString("defaults.target_os")
+	}
+	if targetOS == "" {
+		return types.HostTargetOS(), nil
+	}
+
+	targetOS = strings.ToLower(targetOS)
+	if err := download.ValidateTargetOS(targetOS); err != nil {
+		return "", err
+	}
+	return targetOS, nil
+}
+
+// warnIfStaging explains that a server built for another platform can only be staged here
+func warnIfStaging(targetOS string) {
+	if targetOS == types.HostTargetOS() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n\n", ui.RenderWarning(fmt.Sprintf(
+		"Staging a %s server on %s: it can be installed here but not started.", targetOS, types.HostTargetOS())))
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
 
-	createCmd.Flags().IntP("build", "b", 17000, "FXServer build number")
+	createCmd.Flags().StringP("build", "b", "17000", "FXServer build number, or recommended, optional or latest")
 	createCmd.Flags().StringP("key", "k", "", "License key ID from vault")
 	createCmd.Flags().IntP("port", "p", 0, "Server port (default: 30120)")
 	createCmd.Flags().String("path", "", "Installation path")
+	createCmd.Flags().String("bind", "", "IP address to bind to, IPv4 or IPv6 (default: 0.0.0.0)")
+	createCmd.Flags().Bool("no-scaffold", false, "Don't write a .gitignore and README.md into the server folder")
+	createCmd.Flags().String("game-build", "", "GTA V build to enforce (sv_enforceGameBuild), e.g. 2802, or none (default: 2802)")
+	createCmd.Flags().String("target-os", "", "Platform to install FXServer for: windows or linux (default: this machine)")
+	createCmd.Flags().Bool("force", false, "Install over an existing server folder that inkwash doesn't manage")
+	createCmd.Flags().String("progress", progressText, "Progress output for non-interactive installs: text, or json for one JSON object per line")
+	createCmd.Flags().Bool("force-redownload", false, "Download the FXServer build even if it's cached, replacing the cached copy, and update the cached cfx-server-data")
+	createCmd.Flags().String("artifact-url", "", "Download FXServer from this archive URL instead of the artifacts page")
+	createCmd.Flags().String("launch-command", "", "Command that starts the server from its folder, instead of the entrypoint detected in the build")
+	createCmd.Flags().Bool("verify", false, "Test the downloaded archive and download it again if it's damaged")
+	createCmd.Flags().String("sha256", "", "Expected SHA256 of the downloaded archive (implies --verify)")
+	createCmd.Flags().String("recipe", "", "Set the server up from a txAdmin recipe file or URL instead of cfx-server-data")
+	createCmd.Flags().String("template", "", "Apply a template once the server is set up, e.g. esx or qbcore")
+
+	createCmd.RegisterFlagCompletionFunc("build", completeBuilds)
+	createCmd.RegisterFlagCompletionFunc("key", completeKeyIDs)
+	createCmd.RegisterFlagCompletionFunc("game-build", completeGameBuilds)
+	createCmd.RegisterFlagCompletionFunc("target-os", cobra.FixedCompletions(
+		[]string{types.TargetWindows, types.TargetLinux}, cobra.ShellCompDirectiveNoFileComp))
+	createCmd.RegisterFlagCompletionFunc("progress", cobra.FixedCompletions(
+		[]string{progressText, progressJSON}, cobra.ShellCompDirectiveNoFileComp))
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/daemon"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Supervise servers and restart them when they crash",
+	Long: `Runs in the foreground and watches every registered server. A server that
+exits without 'inkwash stop' is started again; one that crashes more than
+daemon.max_restarts times within daemon.restart_window seconds is left stopped.
+
+Servers stopped with 'inkwash stop' stay stopped. Only one daemon runs at a time.
+Run it under systemd, a Windows service wrapper or tmux to keep it going after
+you log out, and check on it with 'inkwash daemon status'.`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what the running daemon sees",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	opts := daemon.Options{
+		Interval:      time.Duration(viper.GetInt("daemon.interval")) * time.Second,
+		MaxRestarts:   viper.GetInt("daemon.max_restarts"),
+		RestartWindow: time.Duration(viper.GetInt("daemon.restart_window")) * time.Second,
+	}
+	logf := func(format string, args ...interface{}) {
+		fmt.Printf("%s %s\n", ui.RenderMuted(time.Now().Format("2006-01-02 15:04:05")), fmt.Sprintf(format, args...))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := daemon.New(reg, opts, logf).Run(ctx); err != nil {
+		return err
+	}
+	logf("Daemon stopped; servers keep running")
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	status, err := daemon.Query()
+	if err != nil {
+		return err
+	}
+	if status.Servers == nil {
+		status.Servers = []daemon.ServerStatus{}
+	}
+
+	return writeOutput(cmd, status, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("DAEMON"))
+		fmt.Printf("  %s\n", ui.RenderSuccess(fmt.Sprintf("Running (PID %d), up %s", status.PID, time.Since(status.StartedAt).Round(time.Second))))
+		fmt.Println()
+
+		if len(status.Servers) == 0 {
+			fmt.Printf("  %s\n\n", ui.RenderMuted("No servers registered"))
+			return
+		}
+
+		for _, srv := range status.Servers {
+			state := ui.RenderStatusStopped(srv.State)
+			switch srv.State {
+			case daemon.StateRunning:
+				state = ui.RenderStatusRunning(srv.State)
+			case daemon.StateCrashed, daemon.StateFailed:
+				state = ui.RenderError(srv.State)
+			}
+
+			fmt.Printf("  %s  %s\n", state, ui.RenderAccent(srv.Name))
+			if srv.Restarts > 0 {
+				fmt.Printf("      %s\n", ui.RenderMuted(fmt.Sprintf("Restarted %d time(s), last crash %s", srv.Restarts, srv.LastCrash.Format("2006-01-02 15:04:05"))))
+			}
+			if srv.LastError != "" {
+				fmt.Printf("      %s\n", ui.RenderMuted("Last restart failed: "+srv.LastError))
+			}
+		}
+		fmt.Println()
+	})
+}
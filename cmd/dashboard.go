@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/ui/dashboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Open the interactive server dashboard",
+	Long: `Open an interactive dashboard listing your servers with live status,
+CPU and RAM usage.
+
+Keys: s start, x stop, r restart, d remove, l logs, i info,
+c clear the build cache, q quit. Stop, restart, remove and clearing
+the cache ask for confirmation first.
+Running inkwash with no command opens the dashboard too.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDashboard()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+}
+
+// runDashboard opens the dashboard until the user quits
+func runDashboard() {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	// Without a usable cache the dashboard still works, just can't clear it
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		binaryCache = nil
+	}
+
+	p := tea.NewProgram(dashboard.New(reg, binaryCache), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// isTerminal reports whether stdout is an interactive terminal
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Set up a server's MySQL/MariaDB database",
+}
+
+var dbSetupCmd = &cobra.Command{
+	Use:   "setup <server-name>",
+	Short: "Create a database and user for a server and write its connection string",
+	Long: `Creates a database and a user that may use it on a local or remote
+MySQL/MariaDB, runs the given .sql files against it and writes the connection
+string to the server's server.cfg as mysql_connection_string, which oxmysql
+reads. Running it again keeps the database and resets the user's password.
+
+The database and user are named after the server unless given. Without
+--password one is generated. The admin account (root on 127.0.0.1 by default)
+is only used here and isn't saved; without --admin-password it's asked for.
+Needs the mysql or mariadb command-line client.
+
+With --skip-create, an existing database and user (and their --password) are
+used as they are, only running the .sql files and writing the connection string.
+
+Examples:
+  inkwash db setup myserver --sql resources/[qb]/qb-core/qbcore.sql
+  inkwash db setup myserver --host db.example.com --admin-user admin --user rp`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDBSetup,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbSetupCmd)
+
+	dbSetupCmd.Flags().String("host", "127.0.0.1", "MySQL/MariaDB host")
+	dbSetupCmd.Flags().Int("port", database.DefaultPort, "MySQL/MariaDB port")
+	dbSetupCmd.Flags().String("admin-user", "root", "Account that creates the database and user")
+	dbSetupCmd.Flags().String("admin-password", "", "Password of the admin account (asked for if not given)")
+	dbSetupCmd.Flags().String("database", "", "Database name (default: derived from the server name)")
+	dbSetupCmd.Flags().String("user", "", "User the server connects as (default: the database name)")
+	dbSetupCmd.Flags().String("password", "", "Password for the user (default: generated)")
+	dbSetupCmd.Flags().String("user-host", "%", "Hosts the user may connect from")
+	dbSetupCmd.Flags().StringArray("sql", nil, "SQL file to run against the database, repeatable")
+	dbSetupCmd.Flags().Bool("skip-create", false, "Use an existing database and user instead of creating them")
+}
+
+// readAdminPassword asks for the password of the admin account in opts
+// without echoing it
+func readAdminPassword(opts inkwash.DatabaseOptions) (string, error) {
+	user := opts.AdminUser
+	if user == "" {
+		user = "root"
+	}
+	fmt.Printf("MySQL password for %s@%s (empty for none): ", user, opts.Host)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+	return string(password), nil
+}
+
+// dbSetupView is the result of `db setup --output json`
+type dbSetupView struct {
+	Server           string `json:"server"`
+	Host             string `json:"host"`
+	Port             int    `json:"port"`
+	Database         string `json:"database"`
+	User             string `json:"user"`
+	ConnectionString string `json:"connection_string"`
+}
+
+func runDBSetup(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	var opts inkwash.DatabaseOptions
+	opts.Host, _ = flags.GetString("host")
+	opts.Port, _ = flags.GetInt("port")
+	opts.AdminUser, _ = flags.GetString("admin-user")
+	opts.AdminPassword, _ = flags.GetString("admin-password")
+	opts.Database, _ = flags.GetString("database")
+	opts.User, _ = flags.GetString("user")
+	opts.Password, _ = flags.GetString("password")
+	opts.UserHost, _ = flags.GetString("user-host")
+	opts.SQLFiles, _ = flags.GetStringArray("sql")
+	opts.SkipCreate, _ = flags.GetBool("skip-create")
+
+	if opts.Port < 1 || opts.Port > 65535 {
+		return &usageError{fmt.Errorf("--port must be between 1 and 65535")}
+	}
+	for kind, name := range map[string]string{"database": opts.Database, "user": opts.User} {
+		if name != "" {
+			if err := database.ValidateIdentifier(kind, name); err != nil {
+				return &usageError{err}
+			}
+		}
+	}
+	if opts.SkipCreate && opts.Password == "" {
+		return &usageError{fmt.Errorf("--skip-create needs the existing user's --password")}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	srv, err := client.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := database.FindClient(); err != nil {
+		return err
+	}
+
+	// Local installs often let root in without a password, so an empty answer is fine
+	if !flags.Changed("admin-password") && term.IsTerminal(int(os.Stdin.Fd())) {
+		if opts.AdminPassword, err = readAdminPassword(opts); err != nil {
+			return err
+		}
+	}
+
+	quiet := jsonOutput(cmd)
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+	result, err := client.SetupDatabase(ctx, srv.Name, opts, func(step string) {
+		if !quiet {
+			fmt.Printf("%s\n", ui.RenderMuted(step+"..."))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	view := dbSetupView{
+		Server:           srv.Name,
+		Host:             opts.Host,
+		Port:             opts.Port,
+		Database:         result.Database,
+		User:             result.User,
+		ConnectionString: result.ConnectionString,
+	}
+
+	return writeOutput(cmd, view, func() {
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Database '%s' is ready for '%s' (user %s)", view.Database, srv.Name, view.User)))
+		fmt.Printf("Wrote %s to %s\n", database.ConnectionConvar, ui.RenderPath(filepath.Join(srv.Path, "server.cfg")))
+		if result.Generated {
+			fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("See the generated password with 'inkwash config get %s %s'", srv.Name, database.ConnectionConvar)))
+		}
+		printRestartHint(srv)
+	})
+}
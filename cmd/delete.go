@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <server-name>",
+	Short: "Delete a server and its folder",
+	Long: `Stops the server if it's running, removes it from the registry and deletes its
+folder, including server.cfg, metadata.json, resources and logs. Asks for
+confirmation first unless --force is given.
+
+With --keep-resources, resources/ is zipped into archives/ under the data
+directory (or --archive) before anything is deleted.
+
+Folders that don't look like a FiveM server, or that contain inkwash's own
+directories or another server, are never deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDelete,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().Bool("keep-resources", false, "Zip the resources folder before deleting the server")
+	deleteCmd.Flags().String("archive", "", "Where --keep-resources writes the zip (default: archives/ under the data directory)")
+	deleteCmd.Flags().BoolP("force", "f", false, "Don't ask for confirmation")
+}
+
+func runDelete(cmd *cobra.Command, args []string) error {
+	serverName := args[0]
+	keepResources, _ := cmd.Flags().GetBool("keep-resources")
+	archivePath, _ := cmd.Flags().GetString("archive")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if archivePath != "" && !keepResources {
+		return &usageError{fmt.Errorf("--archive only applies with --keep-resources")}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	srv, err := client.Get(serverName)
+	if err != nil {
+		return err
+	}
+
+	// Picked now so the confirmation shows the name the zip gets
+	if keepResources && archivePath == "" {
+		archivePath = server.ResourceArchivePath(srv)
+	}
+
+	if !force {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("DELETE SERVER"))
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Server:"), srv.Name)
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Folder:"), ui.RenderPath(srv.Path))
+		if client.IsRunning(srv) {
+			fmt.Printf("\n  %s\n", ui.RenderWarning(fmt.Sprintf("%s It's running (PID: %d) and will be stopped", ui.SymbolWarning, srv.PID)))
+		}
+		if keepResources {
+			fmt.Printf("\n  %s %s\n", ui.RenderMuted("resources/ will be kept in"), ui.RenderPath(archivePath))
+		} else {
+			fmt.Printf("\n  %s\n", ui.RenderMuted("Everything in the folder, resources included, will be deleted (use --keep-resources to keep them)"))
+		}
+		fmt.Println()
+
+		if !confirm(fmt.Sprintf("Delete '%s'? [y/N]: ", srv.Name)) {
+			fmt.Println("Aborted")
+			return nil
+		}
+	}
+
+	result, err := client.Delete(cmd.Context(), serverName, inkwash.DeleteOptions{
+		KeepResources: keepResources,
+		ArchivePath:   archivePath,
+	})
+	if result != nil && result.Stopped {
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Stopped '%s'", serverName)))
+	}
+	if result != nil && result.ArchivePath != "" {
+		fmt.Printf("%s %s\n", ui.RenderSuccess("Kept resources in"), ui.RenderPath(result.ArchivePath))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Deleted '%s'", serverName)))
+	return nil
+}
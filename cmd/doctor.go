@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/spf13/cobra"
+)
+
+// Free space below which doctor warns or fails. A server with its FXServer
+// build takes a few hundred MB before any resources are added.
+const (
+	doctorDiskWarn = 5 * 1024 * 1024 * 1024
+	doctorDiskFail = 1024 * 1024 * 1024
+)
+
+// checkStatus is the outcome of one doctor check
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+// checkResult is one line of the doctor report
+type checkResult struct {
+	status checkStatus
+	name   string
+	detail string
+	fixed  bool // The problem was fixed by --fix
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment InkWash runs in",
+	Long: `Runs a set of checks and reports each as pass, warn or fail:
+
+  • git, used to fetch cfx-server-data (a tarball download is used without it)
+  • archive support for FXServer builds and mods
+  • free disk space where servers are installed
+  • that the registry loads
+  • stale PIDs left by servers that stopped without InkWash noticing
+  • server folders in the default location that aren't registered
+  • the build cache: entries whose files are gone, and files without an entry
+
+With --fix, stale PIDs are cleared, missing log folders are created and the
+cache's bookkeeping is repaired. Nothing else is changed.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().Bool("fix", false, "Fix simple problems: stale PIDs, missing log folders and cache bookkeeping")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	sections := []struct {
+		title  string
+		checks func(fix bool) []checkResult
+	}{
+		{"TOOLS", doctorTools},
+		{"DISK", doctorDisk},
+		{"SERVERS", doctorServers},
+		{"CACHE", doctorCache},
+	}
+
+	failed, warned := 0, 0
+	for _, section := range sections {
+		fmt.Printf("\n%s\n", ui.RenderHeader(section.title))
+		for _, result := range section.checks(fix) {
+			printCheckResult(result)
+			switch {
+			case result.fixed:
+			case result.status == checkFail:
+				failed++
+			case result.status == checkWarn:
+				warned++
+			}
+		}
+	}
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("doctor found %d problem(s) and %d warning(s)", failed, warned)
+	}
+	if warned > 0 {
+		fmt.Printf("%s\n\n", ui.RenderWarning(fmt.Sprintf("%s %d warning(s), nothing is broken", ui.SymbolWarning, warned)))
+		return nil
+	}
+	fmt.Printf("%s\n\n", ui.RenderSuccess("Everything looks good"))
+	return nil
+}
+
+// printCheckResult prints one line of the report
+func printCheckResult(r checkResult) {
+	line := r.name
+	if r.detail != "" {
+		line += ": " + r.detail
+	}
+
+	switch {
+	case r.fixed:
+		fmt.Printf("  %s %s\n", ui.RenderSuccess(line), ui.RenderMuted("(fixed)"))
+	case r.status == checkPass:
+		fmt.Printf("  %s\n", ui.RenderSuccess(line))
+	case r.status == checkWarn:
+		fmt.Printf("  %s\n", ui.RenderWarning(ui.SymbolWarning+" "+line))
+	default:
+		fmt.Printf("  %s\n", ui.RenderError(line))
+	}
+}
+
+// doctorTools checks the external tools InkWash uses
+func doctorTools(bool) []checkResult {
+	var results []checkResult
+
+	if path, err := exec.LookPath("git"); err == nil {
+		results = append(results, checkResult{status: checkPass, name: "git", detail: path})
+	} else {
+		results = append(results, checkResult{status: checkWarn, name: "git",
+			detail: "not found, cfx-server-data will be downloaded as a tarball instead"})
+	}
+
+	if path, err := database.FindClient(); err == nil {
+		results = append(results, checkResult{status: checkPass, name: "mysql client", detail: path})
+	} else {
+		results = append(results, checkResult{status: checkWarn, name: "mysql client",
+			detail: "not found, only needed for 'inkwash db setup'"})
+	}
+
+	// Extraction is built in, so tar and 7z aren't needed
+	results = append(results, checkResult{status: checkPass, name: "archives",
+		detail: "7z, tar.xz, tar.gz, zip and rar are extracted without external tools"})
+
+	return results
+}
+
+// doctorDisk checks the free space where servers are installed by default
+func doctorDisk(bool) []checkResult {
+	path := filepath.Join(registry.GetDefaultConfigPath(), "servers")
+
+	// Walk up to the first folder that exists
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return []checkResult{{status: checkWarn, name: "free space", detail: fmt.Sprintf("can't check %s: %v", path, err)}}
+	}
+
+	detail := fmt.Sprintf("%.1f GB free at %s", float64(usage.Free)/(1024*1024*1024), path)
+	switch {
+	case usage.Free < doctorDiskFail:
+		return []checkResult{{status: checkFail, name: "free space", detail: detail}}
+	case usage.Free < doctorDiskWarn:
+		return []checkResult{{status: checkWarn, name: "free space", detail: detail}}
+	}
+	return []checkResult{{status: checkPass, name: "free space", detail: detail}}
+}
+
+// doctorServers checks the registry and the servers in it
+func doctorServers(fix bool) []checkResult {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if errors.Is(err, registry.ErrCorrupt) {
+		return []checkResult{{status: checkFail, name: "registry",
+			detail: fmt.Sprintf("%v; run 'inkwash registry scan <dir>' to rebuild it", err)}}
+	}
+	if err != nil {
+		return []checkResult{{status: checkFail, name: "registry", detail: err.Error()}}
+	}
+
+	servers := reg.List()
+	results := []checkResult{{status: checkPass, name: "registry",
+		detail: fmt.Sprintf("%d server(s) in %s", len(servers), registry.GetRegistryPath())}}
+
+	pm := server.NewProcessManager()
+	for _, srv := range servers {
+		// Servers whose folder is gone were already dropped by List
+		name := "'" + srv.Name + "'"
+
+		if srv.PID != 0 && !pm.IsRunning(&srv) {
+			result := checkResult{status: checkWarn, name: name,
+				detail: fmt.Sprintf("marked as running with PID %d, which has exited", srv.PID)}
+			if fix {
+				server.RecordCrash(&srv)
+				result.fixed = reg.UpdatePID(srv.Name, 0) == nil
+			}
+			results = append(results, result)
+		}
+
+		logsDir := filepath.Dir(server.LogPath(srv.Path))
+		if _, err := os.Stat(logsDir); os.IsNotExist(err) {
+			result := checkResult{status: checkWarn, name: name, detail: "logs folder is missing"}
+			if fix {
+				result.fixed = os.MkdirAll(logsDir, 0755) == nil
+			}
+			results = append(results, result)
+		}
+	}
+
+	results = append(results, doctorOrphans(servers)...)
+	return results
+}
+
+// doctorOrphans lists server folders in the default install location that
+// aren't registered
+func doctorOrphans(servers []types.Server) []checkResult {
+	root := filepath.Join(registry.GetDefaultConfigPath(), "servers")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+
+	var results []checkResult
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if !entry.IsDir() || registeredAt(servers, path) != "" || server.DetectServer(path) == nil {
+			continue
+		}
+		results = append(results, checkResult{status: checkWarn, name: "unregistered folder",
+			detail: fmt.Sprintf("%s; run 'inkwash registry scan %s' to add it", path, root)})
+	}
+	return results
+}
+
+// doctorCache checks that the build cache's bookkeeping matches its files
+func doctorCache(fix bool) []checkResult {
+	cachePath := registry.GetDefaultCachePath()
+	binaryCache, err := openBinaryCache()
+	if err != nil {
+		return []checkResult{{status: checkFail, name: "cache", detail: err.Error()}}
+	}
+
+	var results []checkResult
+	known := make(map[string]bool)
+	for _, build := range binaryCache.List() {
+		dir := strconv.Itoa(build.Number)
+		known[dir] = true
+		if _, err := os.Stat(filepath.Join(cachePath, dir, "extracted")); err == nil {
+			continue
+		}
+
+		result := checkResult{status: checkWarn, name: fmt.Sprintf("Build %d", build.Number),
+			detail: "listed in the cache but its files are gone"}
+		if fix {
+			result.fixed = binaryCache.Remove(build.Number) == nil
+		}
+		results = append(results, result)
+	}
+
+	entries, err := os.ReadDir(cachePath)
+	if err != nil {
+		return append(results, checkResult{status: checkFail, name: "cache", detail: err.Error()})
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || known[entry.Name()] {
+			continue
+		}
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+
+		path := filepath.Join(cachePath, entry.Name())
+		result := checkResult{status: checkWarn, name: "Build " + entry.Name(),
+			detail: "files in the cache that it has no record of, " + path}
+		if fix {
+			result.fixed = os.RemoveAll(path) == nil
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		stats := binaryCache.GetStats()
+		results = append(results, checkResult{status: checkPass, name: "cache",
+			detail: fmt.Sprintf("%d build(s), consistent", stats.TotalBuilds)})
+	}
+	return results
+}
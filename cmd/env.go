@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Show the environment InkWash detects, for bug reports",
+	Long: `Prints the platform, terminal, detected animation tier and the checks behind it,
+and where InkWash keeps its files. Paste the output into bug reports, especially
+for rendering problems.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		caps := ui.DetectCapabilities()
+		width, height := ui.GetTerminalSize()
+
+		fmt.Printf("\n%s\n", ui.RenderHeader("VERSIONS"))
+		printEnvRow("inkwash", version)
+		printEnvRow("go", runtime.Version())
+
+		fmt.Printf("\n%s\n", ui.RenderHeader("SYSTEM"))
+		printEnvRow("os/arch", runtime.GOOS+"/"+runtime.GOARCH)
+		printEnvRow("cpus", fmt.Sprint(caps.CPUs))
+		printEnvRow("ram", formatRAM(caps.AvailableRAM, caps.TotalRAM))
+
+		fmt.Printf("\n%s\n", ui.RenderHeader("TERMINAL"))
+		for _, name := range []string{"TERM", "COLORTERM", "TERM_PROGRAM", "WT_SESSION"} {
+			printEnvRow(name, os.Getenv(name))
+		}
+		printEnvRow("interactive", fmt.Sprint(isTerminal()))
+		printEnvRow("size", fmt.Sprintf("%dx%d", width, height))
+		printEnvRow("256 colors", fmt.Sprint(caps.ANSI256))
+		printEnvRow("modern terminal", fmt.Sprint(caps.ModernTerminal))
+
+		fmt.Printf("\n%s\n", ui.RenderHeader("ANIMATIONS"))
+		printEnvRow("detected tier", caps.Tier().String())
+		printEnvRow("ui.animations", viper.GetString("ui.animations"))
+
+		fmt.Printf("\n%s\n", ui.RenderHeader("PATHS"))
+		printEnvRow("config", configFilePath())
+		printEnvRow("registry", registry.GetRegistryPath())
+		printEnvRow("cache", registry.GetDefaultCachePath())
+		fmt.Println()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
+
+// printEnvRow prints one label/value line of the env report
+func printEnvRow(label, value string) {
+	if value == "" {
+		fmt.Printf("  %-16s %s\n", label, ui.RenderMuted("(not set)"))
+		return
+	}
+	fmt.Printf("  %-16s %s\n", label, value)
+}
+
+// formatRAM formats available and total RAM in GB
+func formatRAM(available, total uint64) string {
+	if total == 0 {
+		return "unknown"
+	}
+	const gb = 1024 * 1024 * 1024
+	return fmt.Sprintf("%.1f GB available of %.1f GB", float64(available)/gb, float64(total)/gb)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/convert"
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/lockfile"
+	"github.com/VexoaXYZ/inkwash/internal/rcon"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes, so scripts can tell failures apart without parsing messages.
+// They're listed in the README; don't renumber them.
+const (
+	exitError       = 1   // Anything not covered below
+	exitUsage       = 2   // Bad arguments, flags or values
+	exitNotFound    = 3   // No such server, key or file
+	exitNetwork     = 4   // A download or request failed
+	exitFilesystem  = 5   // Reading or writing files failed
+	exitConflict    = 6   // The name is taken, or the server is running, busy or in the way
+	exitInterrupted = 130 // Cancelled with Ctrl+C
+)
+
+// usageError marks an error caused by how the command was invoked
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// exitCode picks the exit code for an error a command failed with
+func exitCode(err error) int {
+	var usageErr *usageError
+	var validationErr *validation.ValidationError
+	var netErr net.Error
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+
+	switch {
+	case err == nil:
+		return 0
+
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+
+	case errors.As(err, &usageErr), errors.As(err, &validationErr),
+		errors.Is(err, inkwash.ErrPinned), strings.HasPrefix(err.Error(), "unknown command"):
+		return exitUsage
+
+	case errors.Is(err, inkwash.ErrServerNotFound), errors.Is(err, convert.ErrFileExpired),
+		errors.Is(err, inkwash.ErrTemplateNotFound), errors.Is(err, inkwash.ErrProfileNotFound),
+		errors.Is(err, inkwash.ErrDatabaseClientNotFound):
+		return exitNotFound
+
+	case errors.Is(err, inkwash.ErrNameTaken), errors.Is(err, inkwash.ErrServerExists),
+		errors.Is(err, inkwash.ErrServerBusy), errors.Is(err, inkwash.ErrServerRunning),
+		errors.Is(err, inkwash.ErrServerNotRunning), errors.Is(err, lockfile.ErrLocked),
+		errors.Is(err, inkwash.ErrTemplateExists), errors.Is(err, inkwash.ErrProfileExists):
+		return exitConflict
+
+	case errors.As(err, &netErr), errors.Is(err, download.ErrIncomplete), errors.Is(err, download.ErrCorrupt),
+		errors.Is(err, rcon.ErrNoResponse):
+		return exitNetwork
+
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.Is(err, download.ErrPathTooLong),
+		errors.Is(err, registry.ErrCorrupt), errors.Is(err, cache.ErrChecksumMismatch):
+		return exitFilesystem
+	}
+
+	return exitError
+}
+
+// markUsageErrors makes flag and argument errors from cmd and its
+// subcommands exit with exitUsage
+func markUsageErrors(cmd *cobra.Command) {
+	// Subcommands inherit the flag error func, argument validators are per command
+	if !cmd.HasParent() {
+		cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+			return &usageError{err}
+		})
+	}
+
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validate(c, args); err != nil {
+				return &usageError{err}
+			}
+			return nil
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
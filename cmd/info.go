@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/VexoaXYZ/inkwash/internal/cache"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 	"github.com/spf13/cobra"
 )
@@ -44,49 +47,132 @@ func runInfo(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load metadata: %w", err)
 	}
 
-	// Display server info
-	fmt.Printf("\n%s\n", bold("SERVER INFORMATION"))
-	fmt.Printf("  Name:     %s\n", srv.Name)
-	fmt.Printf("  Path:     %s\n", srv.Path)
-	fmt.Printf("  Port:     %d\n", srv.Port)
-	fmt.Printf("  Status:   %s\n", getStatusString(srv))
-
-	// Display build info
-	fmt.Printf("\n%s\n", bold("BUILD"))
-	fmt.Printf("  Number:      %d\n", metadata.Build.Number)
-	fmt.Printf("  Hash:        %s\n", metadata.Build.Hash)
-	fmt.Printf("  Installed:   %s\n", formatTime(metadata.Build.InstalledAt))
-	fmt.Printf("  Type:        %s\n", getBuildType(metadata.Build.Recommended, metadata.Build.Optional))
-
-	// Display lifecycle info
-	fmt.Printf("\n%s\n", bold("LIFECYCLE"))
-	fmt.Printf("  Created:      %s (%s)\n",
-		formatTime(metadata.Lifecycle.CreatedAt),
-		formatRelativeTime(metadata.Lifecycle.CreatedAt))
-
-	if metadata.Lifecycle.LastStarted != nil {
-		fmt.Printf("  Last Started: %s (%s)\n",
-			formatTime(*metadata.Lifecycle.LastStarted),
-			formatRelativeTime(*metadata.Lifecycle.LastStarted))
-	} else {
-		fmt.Printf("  Last Started: Never\n")
-	}
-
-	if metadata.Lifecycle.LastStopped != nil {
-		fmt.Printf("  Last Stopped: %s (%s)\n",
-			formatTime(*metadata.Lifecycle.LastStopped),
-			formatRelativeTime(*metadata.Lifecycle.LastStopped))
-	} else {
-		fmt.Printf("  Last Stopped: Never\n")
-	}
-
-	// Display usage stats
-	fmt.Printf("\n%s\n", bold("USAGE STATISTICS"))
-	fmt.Printf("  Restart Count: %d\n", metadata.Stats.RestartCount)
-	fmt.Printf("  Total Uptime:  %s\n", formatDuration(metadata.Stats.TotalUptime))
-
-	fmt.Println()
-	return nil
+	launch, launchErr := server.LaunchCommand(srv)
+	views := []serverView{newServerView(server.NewProcessManager(), srv)}
+	addPlayerCounts([]types.Server{*srv}, views)
+
+	view := infoView{
+		serverView: views[0],
+		Launch:     launch,
+		Env:        server.LaunchOptions(srv).Env,
+		Build:      metadata.Build,
+		Channel:    metadata.BuildChannel(),
+		Lifecycle:  metadata.Lifecycle,
+		Stats:      metadata.Stats,
+		Resources:  metadata.Resources,
+	}
+	if srv.KeyID != "" {
+		view.Key = &infoKeyView{ID: srv.KeyID}
+		// Never prompt for the vault passphrase just to show a label
+		if vault, err := cache.NewKeyVaultWithOptions(keyVaultPath(), vaultOptions("", false)); err == nil {
+			if key, err := vault.Get(srv.KeyID); err == nil {
+				view.Key.Label = key.Label
+			}
+		}
+		for _, other := range reg.List() {
+			if other.Name != srv.Name && other.KeyID == srv.KeyID {
+				view.Key.SharedWith = append(view.Key.SharedWith, other.Name)
+			}
+		}
+	}
+
+	return writeOutput(cmd, view, func() {
+		// Display server info
+		fmt.Printf("\n%s\n", bold("SERVER INFORMATION"))
+		fmt.Printf("  Name:     %s\n", srv.Name)
+		fmt.Printf("  Path:     %s\n", srv.Path)
+		fmt.Printf("  Port:     %d\n", srv.Port)
+		fmt.Printf("  Endpoint: %s\n", srv.Endpoint())
+		fmt.Printf("  Game:     %s\n", types.GameBuildLabel(srv.GetGameBuild()))
+		if !srv.CanRunOnHost() {
+			fmt.Printf("  Platform: %s (staged, can't run on this machine)\n", srv.GetTargetOS())
+		}
+		if launchErr == nil {
+			fmt.Printf("  Launch:   %s\n", strings.Join(launch, " "))
+		} else {
+			fmt.Printf("  Launch:   %v\n", launchErr)
+		}
+		if env := server.LaunchEnv(srv); len(env) > 0 {
+			fmt.Printf("  Env:      %s\n", strings.Join(env, " "))
+		}
+		fmt.Printf("  Status:   %s\n", getStatusString(srv))
+		if view.Players != nil {
+			fmt.Printf("  Players:  %s\n", playersLabel(view.serverView))
+		}
+		if key := view.Key; key != nil {
+			if key.Label != "" {
+				fmt.Printf("  Key:      %s %s\n", key.Label, ui.RenderMuted("("+key.ID+")"))
+			} else {
+				fmt.Printf("  Key:      %s\n", key.ID)
+			}
+			if len(key.SharedWith) > 0 {
+				fmt.Printf("            %s\n", ui.RenderWarning(fmt.Sprintf("%s Also used by %s; only one server per key can be online at a time",
+					ui.SymbolWarning, strings.Join(key.SharedWith, ", "))))
+			}
+		}
+
+		// Display build info
+		fmt.Printf("\n%s\n", bold("BUILD"))
+		fmt.Printf("  Number:      %d\n", metadata.Build.Number)
+		fmt.Printf("  Hash:        %s\n", metadata.Build.Hash)
+		fmt.Printf("  Installed:   %s\n", formatTime(metadata.Build.InstalledAt))
+		fmt.Printf("  Type:        %s\n", getBuildType(metadata.Build.Recommended, metadata.Build.Optional))
+		fmt.Printf("  Channel:     %s\n", view.Channel)
+
+		// Display lifecycle info
+		fmt.Printf("\n%s\n", bold("LIFECYCLE"))
+		fmt.Printf("  Created:      %s (%s)\n",
+			formatTime(metadata.Lifecycle.CreatedAt),
+			formatRelativeTime(metadata.Lifecycle.CreatedAt))
+
+		if metadata.Lifecycle.LastStarted != nil {
+			fmt.Printf("  Last Started: %s (%s)\n",
+				formatTime(*metadata.Lifecycle.LastStarted),
+				formatRelativeTime(*metadata.Lifecycle.LastStarted))
+		} else {
+			fmt.Printf("  Last Started: Never\n")
+		}
+
+		if metadata.Lifecycle.LastStopped != nil {
+			fmt.Printf("  Last Stopped: %s (%s)\n",
+				formatTime(*metadata.Lifecycle.LastStopped),
+				formatRelativeTime(*metadata.Lifecycle.LastStopped))
+		} else {
+			fmt.Printf("  Last Stopped: Never\n")
+		}
+
+		// Display usage stats
+		fmt.Printf("\n%s\n", bold("USAGE STATISTICS"))
+		fmt.Printf("  Restart Count: %d\n", metadata.Stats.RestartCount)
+		fmt.Printf("  Total Uptime:  %s\n", formatDuration(metadata.Stats.TotalUptime))
+		if metadata.Stats.LastCrash != nil {
+			fmt.Printf("  Crashes:       %d, last %s ('inkwash crashes %s' for details)\n",
+				metadata.Stats.CrashCount, formatRelativeTime(*metadata.Stats.LastCrash), srv.Name)
+		}
+
+		fmt.Println()
+	})
+}
+
+// infoView is what `info --output json` prints: the server as list shows
+// it plus its metadata.json
+type infoView struct {
+	serverView
+	Launch    []string                  `json:"launch_command,omitempty"`
+	Env       map[string]string         `json:"launch_env,omitempty"`
+	Build     types.BuildMetadata       `json:"build"`
+	Channel   string                    `json:"channel"` // Build channel update-server follows
+	Lifecycle types.LifecycleMetadata   `json:"lifecycle"`
+	Stats     types.UsageStats          `json:"stats"`
+	Resources []types.InstalledResource `json:"resources,omitempty"`
+	Key       *infoKeyView              `json:"license_key,omitempty"`
+}
+
+// infoKeyView is the vault key a server was assigned
+type infoKeyView struct {
+	ID         string   `json:"id"`
+	Label      string   `json:"label,omitempty"`       // "" if the key isn't in the vault, or it's locked
+	SharedWith []string `json:"shared_with,omitempty"` // Other servers assigned the same key
 }
 
 func getStatusString(srv *types.Server) string {
@@ -1,20 +1,32 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/VexoaXYZ/inkwash/internal/cache"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/internal/validation"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var keyCmd = &cobra.Command{
 	Use:   "key",
 	Short: "Manage license keys",
-	Long:  `Manage FiveM license keys in encrypted vault.`,
+	Long: `Manage FiveM license keys in encrypted vault.
+
+keys.backend sets how the vault's encryption key is protected: "machine" derives
+it from the hostname (the default, and it breaks if the machine is renamed),
+"keychain" keeps a random key in the OS keychain, and "passphrase" derives it
+from a passphrase, read from INKWASH_VAULT_PASSPHRASE or asked for. Changing it
+re-encrypts the vault the next time it's opened. Where there's no keychain,
+"keychain" falls back to a passphrase.`,
 }
 
 var keyAddCmd = &cobra.Command{
@@ -36,11 +48,10 @@ var keyAddCmd = &cobra.Command{
 		}
 
 		// Load vault
-		vaultPath := registry.GetDefaultConfigPath() + "/keys.enc"
-		vault, err := cache.NewKeyVault(vaultPath)
+		vault, err := openKeyVault()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to load vault: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		// Add key
@@ -54,7 +65,7 @@ var keyAddCmd = &cobra.Command{
 			} else {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		fmt.Printf("%s\n", ui.RenderSuccess("License key added"))
@@ -68,67 +79,368 @@ var keyListCmd = &cobra.Command{
 	Short: "List all license keys",
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load vault
-		vaultPath := registry.GetDefaultConfigPath() + "/keys.enc"
-		vault, err := cache.NewKeyVault(vaultPath)
+		vault, err := openKeyVault()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to load vault: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		keys := vault.List()
 
-		if len(keys) == 0 {
-			fmt.Println("No license keys found")
-			fmt.Println("\nAdd a key:")
-			fmt.Println("  inkwash key add")
-			return
+		// Which servers use each key, best-effort
+		usedBy := make(map[string][]string)
+		if reg, err := registry.NewRegistry(registry.GetRegistryPath()); err == nil {
+			for _, srv := range reg.List() {
+				if srv.KeyID != "" {
+					usedBy[srv.KeyID] = append(usedBy[srv.KeyID], srv.Name)
+				}
+			}
 		}
 
-		fmt.Printf("\n%s\n\n", ui.RenderHeader("LICENSE KEYS"))
-
+		// Keys are masked in every format
+		views := make([]keyView, 0, len(keys))
 		for _, key := range keys {
-			fmt.Printf("  %s\n", ui.RenderAccent(key.Label))
-			fmt.Printf("    ID:  %s\n", ui.RenderMuted(key.ID))
-			fmt.Printf("    Key: %s\n", ui.RenderMuted(validation.MaskKey(key.Key)))
-			fmt.Printf("    Created: %s\n\n", ui.RenderMuted(key.Created.Format("Jan 2, 2006")))
+			views = append(views, keyView{ID: key.ID, Label: key.Label, Key: validation.MaskKey(key.Key), Created: key.Created, Servers: usedBy[key.ID]})
 		}
 
-		fmt.Printf("Total: %d key(s)\n\n", len(keys))
+		err = writeOutput(cmd, views, func() {
+			if len(views) == 0 {
+				fmt.Println("No license keys found")
+				fmt.Println("\nAdd a key:")
+				fmt.Println("  inkwash key add")
+				return
+			}
+
+			fmt.Printf("\n%s\n\n", ui.RenderHeader("LICENSE KEYS"))
+
+			for _, key := range views {
+				fmt.Printf("  %s\n", ui.RenderAccent(key.Label))
+				fmt.Printf("    ID:  %s\n", ui.RenderMuted(key.ID))
+				fmt.Printf("    Key: %s\n", ui.RenderMuted(key.Key))
+				fmt.Printf("    Created: %s\n", ui.RenderMuted(key.Created.Format("Jan 2, 2006")))
+				switch len(key.Servers) {
+				case 0:
+					fmt.Printf("    Used by: %s\n", ui.RenderMuted("no server"))
+				case 1:
+					fmt.Printf("    Used by: %s\n", key.Servers[0])
+				default:
+					fmt.Printf("    Used by: %s\n", ui.RenderWarning(fmt.Sprintf("%s %s (only one can be online at a time)",
+						ui.SymbolWarning, strings.Join(key.Servers, ", "))))
+				}
+				fmt.Println()
+			}
+
+			fmt.Printf("Total: %d key(s)\n", len(views))
+			fmt.Printf("%s\n\n", ui.RenderMuted("Vault protected by: "+vaultBackendLabel(vault.Backend())))
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
 	},
 }
 
+// keyView is a vault entry as `key list` shows it
+type keyView struct {
+	ID      string    `json:"id"`
+	Label   string    `json:"label"`
+	Key     string    `json:"key"` // Masked
+	Created time.Time `json:"created"`
+	Servers []string  `json:"servers,omitempty"` // Assigned the key, see `key assign`
+}
+
 var keyRemoveCmd = &cobra.Command{
 	Use:   "remove <key-id>",
 	Short: "Remove a license key",
 	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeKeyIDs(cmd, args, toComplete)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		keyID := args[0]
 
 		// Load vault
-		vaultPath := registry.GetDefaultConfigPath() + "/keys.enc"
-		vault, err := cache.NewKeyVault(vaultPath)
+		vault, err := openKeyVault()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to load vault: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		// Remove key
 		if err := vault.Remove(keyID); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to remove key: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		fmt.Printf("%s\n", ui.RenderSuccess("License key removed"))
 	},
 }
 
+var keyAssignCmd = &cobra.Command{
+	Use:   "assign <server-name> <key-id>",
+	Short: "Give a server a license key from the vault",
+	Long: `Sets sv_licenseKey in the server's server.cfg to a key from the vault and
+remembers which key the server uses, for 'inkwash info' and 'inkwash key list'.
+A running server picks the key up when it's restarted.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return completeServerNames(cmd, args, toComplete)
+		case 1:
+			return completeKeyIDs(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverName, keyID := args[0], args[1]
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		srv, err := client.Get(serverName)
+		if err != nil {
+			return err
+		}
+
+		vault, err := openKeyVault()
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+		key, err := vault.Get(keyID)
+		if err != nil {
+			return &usageError{fmt.Errorf("license key '%s' not found, see 'inkwash key list'", keyID)}
+		}
+
+		var others []string
+		for _, name := range client.ServersUsingKey(key.ID) {
+			if name != srv.Name {
+				others = append(others, name)
+			}
+		}
+		warnKeyShared(others)
+
+		srv, err = client.AssignKey(cmd.Context(), srv.Name, key.ID, key.Key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("'%s' now uses %s", srv.Name, key.Label)))
+		if client.IsRunning(srv) {
+			fmt.Printf("  %s\n", ui.RenderMuted(fmt.Sprintf("It's running; restart it to use the new key: inkwash stop %s && inkwash start %s", srv.Name, srv.Name)))
+		}
+		return nil
+	},
+}
+
+// warnKeyShared warns that a license key is already assigned to other servers
+func warnKeyShared(servers []string) {
+	if len(servers) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(fmt.Sprintf(
+		"%s This key is already used by %s; only one server per key can be online at a time",
+		ui.SymbolWarning, strings.Join(servers, ", "))))
+}
+
+var keyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export license keys to a passphrase-protected file",
+	Long: `Writes every license key to a file encrypted with a passphrase you choose
+(Argon2id and AES-256-GCM), so the keys can be moved to another machine or
+restored after a reinstall with 'inkwash key import'. The vault itself only
+opens on this machine.
+
+The passphrase is asked for, or read from INKWASH_EXPORT_PASSPHRASE.`,
+	Example:      `  inkwash key export --file keys.backup`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if file == "" {
+			return &usageError{fmt.Errorf("--file is needed to export the keys to")}
+		}
+		if _, err := os.Stat(file); err == nil && !force {
+			return &usageError{fmt.Errorf("%s already exists, use --force to overwrite it", file)}
+		}
+
+		vault, err := openKeyVault()
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+		if vault.Count() == 0 {
+			return fmt.Errorf("no license keys to export")
+		}
+
+		passphrase, err := exportPassphrase(true)
+		if err != nil {
+			return err
+		}
+		n, err := vault.Export(file, passphrase)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Exported %d key(s) to %s", n, file)))
+		fmt.Printf("  %s\n", ui.RenderMuted("Keep the passphrase, the keys can't be imported without it"))
+		return nil
+	},
+}
+
+var keyImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import license keys from 'inkwash key export'",
+	Long: `Adds the license keys in a file written by 'inkwash key export' to the vault.
+Keys already in the vault are skipped.
+
+The passphrase is asked for, or read from INKWASH_EXPORT_PASSPHRASE.`,
+	Example:      `  inkwash key import --file keys.backup`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+
+		if file == "" {
+			return &usageError{fmt.Errorf("--file is needed to import the keys from")}
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return fmt.Errorf("export file not found: %s", file)
+		}
+
+		vault, err := openKeyVault()
+		if err != nil {
+			return fmt.Errorf("failed to load vault: %w", err)
+		}
+
+		passphrase, err := exportPassphrase(false)
+		if err != nil {
+			return err
+		}
+		imported, skipped, err := vault.Import(file, passphrase)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Imported %d key(s)", imported)))
+		if skipped > 0 {
+			fmt.Printf("  %s\n", ui.RenderMuted(fmt.Sprintf("Skipped %d already in the vault", skipped)))
+		}
+		return nil
+	},
+}
+
+// exportPassphraseEnv holds the passphrase for key export and import in scripts
+const exportPassphraseEnv = "INKWASH_EXPORT_PASSPHRASE"
+
+// exportPassphrase returns the passphrase protecting a key export, from
+// INKWASH_EXPORT_PASSPHRASE or asked for (twice with confirm)
+func exportPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(exportPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", &usageError{fmt.Errorf("no terminal to ask for the passphrase, set %s", exportPassphraseEnv)}
+	}
+	passphrase, err := readPassphrase("Export passphrase", confirm)
+	if err == nil && passphrase == "" {
+		err = errors.New("passphrase can't be empty")
+	}
+	return passphrase, err
+}
+
 func init() {
 	rootCmd.AddCommand(keyCmd)
 
 	keyCmd.AddCommand(keyAddCmd)
 	keyCmd.AddCommand(keyListCmd)
 	keyCmd.AddCommand(keyRemoveCmd)
+	keyCmd.AddCommand(keyAssignCmd)
+	keyCmd.AddCommand(keyExportCmd)
+	keyCmd.AddCommand(keyImportCmd)
 
 	keyAddCmd.Flags().StringP("label", "l", "", "Label for the key")
 	keyAddCmd.Flags().StringP("key", "k", "", "License key")
+
+	keyExportCmd.Flags().StringP("file", "f", "", "File to write the keys to")
+	keyExportCmd.Flags().Bool("force", false, "Overwrite the file if it exists")
+	keyImportCmd.Flags().StringP("file", "f", "", "File written by 'inkwash key export'")
+}
+
+// vaultPassphraseEnv holds the key vault passphrase for scripts and servers
+const vaultPassphraseEnv = "INKWASH_VAULT_PASSPHRASE"
+
+// keyVaultPath returns where the license key vault is kept
+func keyVaultPath() string {
+	return registry.GetDefaultConfigPath() + "/keys.enc"
+}
+
+// openKeyVault opens the license key vault protected as keys.backend says,
+// migrating it if it's protected differently
+func openKeyVault() (*cache.KeyVault, error) {
+	return cache.NewKeyVaultWithOptions(keyVaultPath(), vaultOptions(viper.GetString("keys.backend"), true))
+}
+
+// vaultOptions protects the key vault with backend. The passphrase comes from
+// INKWASH_VAULT_PASSPHRASE or, with prompt and a terminal, is asked for.
+func vaultOptions(backend string, prompt bool) cache.VaultOptions {
+	return cache.VaultOptions{
+		Backend: backend,
+		Passphrase: func(confirm bool) (string, error) {
+			if passphrase := os.Getenv(vaultPassphraseEnv); passphrase != "" {
+				return passphrase, nil
+			}
+			if !prompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+				return "", fmt.Errorf("key vault needs a passphrase, set %s", vaultPassphraseEnv)
+			}
+			if confirm {
+				return readPassphrase("New key vault passphrase", true)
+			}
+			return readPassphrase("Key vault passphrase", false)
+		},
+	}
+}
+
+// readPassphrase asks for a passphrase without echoing it, and with confirm
+// asks again to catch typos
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the passphrase: %w", err)
+		}
+		return string(passphrase), nil
+	}
+
+	passphrase, err := read(prompt + ": ")
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	again, err := read("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+// vaultBackendLabel describes a cache.Vault* protection for `key list`
+func vaultBackendLabel(backend string) string {
+	switch backend {
+	case cache.VaultKeychain:
+		return "OS keychain"
+	case cache.VaultPassphrase:
+		return "passphrase"
+	}
+	return "machine name (set keys.backend to keychain or passphrase for stronger protection)"
 }
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var configSetArgCmd = &cobra.Command{
+	Use:   "set-arg <server-name> [arg...]",
+	Short: "Set extra arguments FXServer is started with",
+	Long: `Sets arguments added to the end of the server's launch command, replacing
+any set before (--add appends to them instead, --clear removes them). They're
+kept in the server's metadata.json and used both by 'inkwash start' and by the
+run.sh/run.cmd in its folder, which is regenerated:
+
+  inkwash config set-arg myserver +set onesync on
+
+Arguments starting with "-" go after "--". A running server picks up the
+change when it's restarted.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runConfigSetArg,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var configSetEnvCmd = &cobra.Command{
+	Use:   "set-env <server-name> [KEY=value...]",
+	Short: "Set environment variables FXServer is started with",
+	Long: `Sets environment variables for the server's FXServer process, on top of the
+environment inkwash runs in, e.g. to preload a library:
+
+  inkwash config set-env myserver LD_PRELOAD=/usr/lib/libjemalloc.so
+
+--unset removes a variable. Like extra arguments, they're kept in the server's
+metadata.json and written into its run.sh/run.cmd.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runConfigSetEnv,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	configCmd.AddCommand(configSetArgCmd)
+	configCmd.AddCommand(configSetEnvCmd)
+
+	configSetArgCmd.Flags().Bool("add", false, "Append to the extra arguments instead of replacing them")
+	configSetArgCmd.Flags().Bool("clear", false, "Remove all extra arguments")
+	configSetEnvCmd.Flags().StringSlice("unset", nil, "Remove an environment variable (repeatable)")
+}
+
+// envNamePattern matches the variable names both bash and cmd.exe accept
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func runConfigSetArg(cmd *cobra.Command, args []string) error {
+	add, _ := cmd.Flags().GetBool("add")
+	clearArgs, _ := cmd.Flags().GetBool("clear")
+	extra := args[1:]
+
+	switch {
+	case clearArgs && (add || len(extra) > 0):
+		return &usageError{fmt.Errorf("--clear can't be combined with arguments or --add")}
+	case !clearArgs && len(extra) == 0:
+		return &usageError{fmt.Errorf("give the arguments to add, or --clear to remove them")}
+	}
+
+	srv, err := updateLaunchOptions(args[0], func(launch *types.LaunchOptions) {
+		if add {
+			launch.Args = append(launch.Args, extra...)
+		} else {
+			launch.Args = extra
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if clearArgs {
+		fmt.Printf("%s\n", ui.RenderSuccess("Removed the extra arguments of '"+srv.Name+"'"))
+	} else {
+		fmt.Printf("%s\n", ui.RenderSuccess("Set the extra arguments of '"+srv.Name+"'"))
+	}
+	if launch, err := server.LaunchCommand(srv); err == nil {
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Launch:"), strings.Join(launch, " "))
+	}
+	printRestartHint(srv)
+	return nil
+}
+
+func runConfigSetEnv(cmd *cobra.Command, args []string) error {
+	unset, _ := cmd.Flags().GetStringSlice("unset")
+	if len(args) == 1 && len(unset) == 0 {
+		return &usageError{fmt.Errorf("give KEY=value pairs to set, or --unset KEY")}
+	}
+
+	set := make(map[string]string, len(args)-1)
+	for _, pair := range args[1:] {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return &usageError{fmt.Errorf("'%s' isn't KEY=value", pair)}
+		}
+		if !envNamePattern.MatchString(key) {
+			return &usageError{fmt.Errorf("'%s' isn't a valid variable name", key)}
+		}
+		set[key] = value
+	}
+
+	var missing []string
+	srv, err := updateLaunchOptions(args[0], func(launch *types.LaunchOptions) {
+		if launch.Env == nil {
+			launch.Env = make(map[string]string)
+		}
+		for _, key := range unset {
+			if _, ok := launch.Env[key]; !ok {
+				missing = append(missing, key)
+			}
+			delete(launch.Env, key)
+		}
+		for key, value := range set {
+			launch.Env[key] = value
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess("Updated the environment of '"+srv.Name+"'"))
+	for _, pair := range server.LaunchEnv(srv) {
+		fmt.Printf("  %s\n", pair)
+	}
+	for _, key := range missing {
+		fmt.Printf("%s\n", ui.RenderWarning(ui.SymbolWarning+" "+key+" wasn't set"))
+	}
+	printRestartHint(srv)
+	return nil
+}
+
+// updateLaunchOptions changes a server's launch options in its metadata.json
+// and regenerates its launch script to match
+func updateLaunchOptions(serverName string, change func(*types.LaunchOptions)) (*types.Server, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	srv, err := client.Get(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	mm := server.NewMetadataManager()
+	metadata, err := mm.Load(srv.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	launch := types.LaunchOptions{}
+	if metadata.Launch != nil {
+		launch = *metadata.Launch
+	}
+	change(&launch)
+	if len(launch.Env) == 0 {
+		launch.Env = nil
+	}
+	metadata.Launch = &launch
+	if len(launch.Args) == 0 && launch.Env == nil {
+		metadata.Launch = nil
+	}
+
+	if err := mm.Save(srv.Path, metadata); err != nil {
+		return nil, err
+	}
+
+	if err := server.NewConfigGenerator().GenerateLaunchScript(srv); err != nil {
+		fmt.Printf("%s\n", ui.RenderWarning(fmt.Sprintf("%s Saved, but the launch script wasn't updated: %v", ui.SymbolWarning, err)))
+	}
+	return srv, nil
+}
+
+// printRestartHint tells the user a running server needs a restart for a change to apply
+func printRestartHint(srv *types.Server) {
+	if server.NewProcessManager().IsRunning(srv) {
+		fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("Restart the server for it to take effect: inkwash stop %s && inkwash start %s", srv.Name, srv.Name)))
+	}
+}
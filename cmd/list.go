@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/VexoaXYZ/inkwash/internal/daemon"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/internal/server"
 	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
 	"github.com/spf13/cobra"
 )
 
@@ -19,53 +25,171 @@ var listCmd = &cobra.Command{
 		reg, err := registry.NewRegistry(registry.GetRegistryPath())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 		servers := reg.List()
 
-		if len(servers) == 0 {
-			fmt.Println("No servers found")
-			fmt.Println("\nCreate a server:")
-			fmt.Println("  inkwash create <server-name>")
-			return
-		}
-
 		// Create process manager to check status
 		pm := server.NewProcessManager()
 
-		fmt.Printf("\n%s\n\n", ui.RenderHeader("SERVERS"))
-
-		for _, srv := range servers {
-			// Check actual running status
-			isRunning := pm.IsRunning(&srv)
+		// Crash restarts are only known to the daemon, if one is running
+		supervised := make(map[string]daemon.ServerStatus)
+		status, err := daemon.Query()
+		if err == nil {
+			for _, s := range status.Servers {
+				supervised[s.Name] = s
+			}
+		}
 
-			// Status indicator
-			var status string
-			if isRunning {
-				status = ui.RenderStatusRunning(srv.Status())
-			} else {
-				status = ui.RenderStatusStopped(srv.Status())
+		views := make([]serverView, 0, len(servers))
+		for i := range servers {
+			view := newServerView(pm, &servers[i])
+			if s, ok := supervised[view.Name]; ok {
+				view.Restarts = s.Restarts
+				view.GaveUp = s.State == daemon.StateFailed
 			}
+			views = append(views, view)
+		}
+		addPlayerCounts(servers, views)
+
+		err = writeOutput(cmd, views, func() { printServerList(views, status) })
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	},
+}
+
+// serverView is a server as `list` and `info` show it
+type serverView struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Port      int    `json:"port"`
+	Endpoint  string `json:"endpoint"`
+	TargetOS  string `json:"target_os"`
+	GameBuild int    `json:"game_build"`
+	Running   bool   `json:"running"`
+	PID       int    `json:"pid,omitempty"`
+
+	// Only for running servers whose process can be read
+	MemoryBytes *uint64 `json:"memory_bytes,omitempty"`
+	statsDenied bool
+
+	// Only for running servers that answered the players query
+	Players    *int `json:"players,omitempty"`
+	MaxPlayers int  `json:"max_players,omitempty"`
+
+	// From the daemon, if one is running
+	Restarts int  `json:"daemon_restarts,omitempty"`
+	GaveUp   bool `json:"daemon_gave_up,omitempty"`
+}
+
+// newServerView collects what list and info show about a server
+func newServerView(pm *server.ProcessManager, srv *types.Server) serverView {
+	view := serverView{
+		Name:      srv.Name,
+		Path:      srv.Path,
+		Port:      srv.Port,
+		Endpoint:  srv.Endpoint(),
+		TargetOS:  srv.GetTargetOS(),
+		GameBuild: srv.GetGameBuild(),
+		Running:   pm.IsRunning(srv),
+	}
+	if !view.Running {
+		return view
+	}
 
-			fmt.Printf("  %s  %s\n", status, ui.RenderAccent(srv.Name))
-			fmt.Printf("      %s\n", ui.RenderMuted("Port: "+fmt.Sprint(srv.Port)))
-			fmt.Printf("      %s\n", ui.RenderPath(srv.Path))
-
-			if isRunning {
-				// Get memory usage
-				mem, err := pm.GetMemoryUsage(&srv)
-				if err == nil {
-					memGB := float64(mem) / 1024 / 1024 / 1024
-					fmt.Printf("      %s\n", ui.RenderMuted(fmt.Sprintf("RAM: %.2f GB", memGB)))
-				}
+	view.PID = srv.PID
+	mem, err := pm.GetMemoryUsage(srv)
+	if err == nil {
+		view.MemoryBytes = &mem
+	} else if errors.Is(err, server.ErrStatsUnavailable) {
+		view.statsDenied = true
+	}
+	return view
+}
+
+// playerQueryTimeout bounds how long list and info wait for servers to
+// report their players
+const playerQueryTimeout = time.Second
+
+// addPlayerCounts asks the running servers how many players they have, all at
+// once so a server that doesn't answer only costs playerQueryTimeout
+func addPlayerCounts(servers []types.Server, views []serverView) {
+	ctx, cancel := context.WithTimeout(context.Background(), playerQueryTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range views {
+		if !views[i].Running {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			players, maxPlayers, err := server.QueryPlayers(ctx, &servers[i])
+			if err == nil {
+				views[i].Players = &players
+				views[i].MaxPlayers = maxPlayers
 			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// playersLabel formats a player count, with the limit if the server reported it
+func playersLabel(view serverView) string {
+	if view.MaxPlayers > 0 {
+		return fmt.Sprintf("%d/%d", *view.Players, view.MaxPlayers)
+	}
+	return fmt.Sprint(*view.Players)
+}
+
+// printServerList prints the text view of list
+func printServerList(views []serverView, status *daemon.Status) {
+	if len(views) == 0 {
+		fmt.Println("No servers found")
+		fmt.Println("\nCreate a server:")
+		fmt.Println("  inkwash create <server-name>")
+		return
+	}
 
-			fmt.Println()
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("SERVERS"))
+
+	for _, view := range views {
+		if view.Running {
+			fmt.Printf("  %s  %s\n", ui.RenderStatusRunning("Running"), ui.RenderAccent(view.Name))
+		} else {
+			fmt.Printf("  %s  %s\n", ui.RenderStatusStopped("Stopped"), ui.RenderAccent(view.Name))
 		}
+		fmt.Printf("      %s\n", ui.RenderMuted("Port: "+fmt.Sprint(view.Port)))
+		fmt.Printf("      %s\n", ui.RenderPath(view.Path))
 
-		fmt.Printf("Total: %d server(s)\n\n", len(servers))
-	},
+		if view.MemoryBytes != nil {
+			memGB := float64(*view.MemoryBytes) / 1024 / 1024 / 1024
+			fmt.Printf("      %s\n", ui.RenderMuted(fmt.Sprintf("RAM: %.2f GB", memGB)))
+		} else if view.statsDenied {
+			fmt.Printf("      %s\n", ui.RenderMuted("RAM: unavailable (not permitted to read process stats)"))
+		}
+		if view.Players != nil {
+			fmt.Printf("      %s\n", ui.RenderMuted("Players: "+playersLabel(view)))
+		}
+
+		if view.GaveUp {
+			fmt.Printf("      %s\n", ui.RenderWarning(ui.SymbolWarning+" The daemon gave up restarting it after repeated crashes"))
+		} else if view.Restarts > 0 {
+			fmt.Printf("      %s\n", ui.RenderMuted(fmt.Sprintf("Restarted %d time(s) by the daemon", view.Restarts)))
+		}
+
+		fmt.Println()
+	}
+
+	fmt.Printf("Total: %d server(s)\n", len(views))
+	if status != nil {
+		fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("Supervised by the daemon (PID %d)", status.PID)))
+	}
+	fmt.Println()
 }
 
 func init() {
@@ -1,78 +1,138 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
 
+// logPollInterval is how often followed logs are checked for new lines
+const logPollInterval = 500 * time.Millisecond
+
+// logTagColors tell servers apart in combined output
+var logTagColors = []lipgloss.Color{
+	ui.ColorPrimaryGlow,
+	ui.ColorSuccess,
+	ui.ColorWarning,
+	lipgloss.Color("#38BDF8"),
+	lipgloss.Color("#F472B6"),
+	lipgloss.Color("#A3E635"),
+}
+
 var logsCmd = &cobra.Command{
-	Use:   "logs <server-name>",
+	Use:   "logs [server-name]",
 	Short: "View server logs",
-	Long:  `View logs for a FiveM server.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `View logs for a FiveM server.
+
+When --tail reaches past the start of the current log, older lines are read
+from rotated logs (server.log.1, server.log.2.gz, ...). --follow keeps
+watching through a rotation, finishing the old log before the new one.
+
+--grep only shows lines matching a regular expression (case-insensitive with
+-i); with --tail that's the last N matching lines. Errors and warnings are
+colored when printing to a terminal.
+
+With --all, the logs of every registered server are shown, each line tagged
+with its server's name. Add --follow to watch all of them at once.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		serverName := args[0]
 		follow, _ := cmd.Flags().GetBool("follow")
 		lines, _ := cmd.Flags().GetInt("lines")
+		all, _ := cmd.Flags().GetBool("all")
+		pattern, _ := cmd.Flags().GetString("grep")
+		ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
 
-		// Load registry
-		reg, err := registry.NewRegistry(registry.GetRegistryPath())
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
-			os.Exit(1)
+		if cmd.Flags().Changed("tail") {
+			lines, _ = cmd.Flags().GetInt("tail")
+		}
+		if lines < 0 {
+			fmt.Fprintf(os.Stderr, "Error: the number of lines can't be negative\n")
+			os.Exit(exitUsage)
 		}
 
-		// Get server
-		srv, err := reg.Get(serverName)
+		match, err := logMatcher(pattern, ignoreCase)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", serverName)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
 		}
 
-		// Get log file path
-		logPath := filepath.Join(srv.Path, "logs", "server.log")
-
-		// Check if log exists
-		if _, err := os.Stat(logPath); os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: Log file not found: %s\n", logPath)
-			os.Exit(1)
+		if all == (len(args) == 1) {
+			fmt.Fprintf(os.Stderr, "Error: pass a server name or --all\n")
+			os.Exit(exitUsage)
 		}
 
-		// Open log file
-		file, err := os.Open(logPath)
+		// Load registry
+		reg, err := registry.NewRegistry(registry.GetRegistryPath())
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to open log: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
+			os.Exit(exitCode(err))
 		}
-		defer file.Close()
 
-		if follow {
-			// TODO: Implement tail -f functionality
-			fmt.Println("Follow mode not implemented yet")
-			fmt.Println("Showing last lines instead...")
+		var servers []types.Server
+		if all {
+			servers = reg.List()
+			if len(servers) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: No servers found\n")
+				os.Exit(exitNotFound)
+			}
+		} else {
+			srv, err := reg.Get(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", args[0])
+				os.Exit(exitNotFound)
+			}
+			servers = []types.Server{*srv}
 		}
 
-		// Show last N lines
-		scanner := bufio.NewScanner(file)
-		var allLines []string
+		tags := logTags(servers, all)
+
+		// Show last N lines, going back into rotated logs if needed
+		found := false
+		for i, srv := range servers {
+			history, err := server.TailLogMatching(srv.Path, lines, match)
+			if os.IsNotExist(err) {
+				if !all {
+					fmt.Fprintf(os.Stderr, "Error: Log file not found: %s\n", server.LogPath(srv.Path))
+					os.Exit(exitNotFound)
+				}
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
 
-		for scanner.Scan() {
-			allLines = append(allLines, scanner.Text())
+			found = true
+			for _, line := range history {
+				fmt.Println(tags[i] + ui.RenderLogLine(line))
+			}
 		}
 
-		// Print last N lines
-		start := len(allLines) - lines
-		if start < 0 {
-			start = 0
+		if !found && !follow {
+			fmt.Fprintf(os.Stderr, "Error: None of the servers have a log yet\n")
+			os.Exit(exitNotFound)
 		}
 
-		for i := start; i < len(allLines); i++ {
-			fmt.Println(allLines[i])
+		if follow {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := followLogs(ctx, servers, tags, match); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
 		}
 	},
 }
@@ -82,4 +142,79 @@ func init() {
 
 	logsCmd.Flags().BoolP("follow", "f", false, "Follow log output")
 	logsCmd.Flags().IntP("lines", "n", 50, "Number of lines to show")
+	logsCmd.Flags().Int("tail", 50, "Number of lines to show (same as --lines)")
+	logsCmd.Flags().String("grep", "", "Only show lines matching a regular expression")
+	logsCmd.Flags().BoolP("ignore-case", "i", false, "Match --grep case-insensitively")
+	logsCmd.Flags().BoolP("all", "a", false, "Show the logs of all servers, tagged by server name")
+}
+
+// logTags returns the prefix printed before each server's lines: its name,
+// colored and padded to line up, or nothing when only one log is shown
+func logTags(servers []types.Server, tagged bool) []string {
+	tags := make([]string, len(servers))
+	if !tagged {
+		return tags
+	}
+
+	width := 0
+	for _, srv := range servers {
+		if len(srv.Name) > width {
+			width = len(srv.Name)
+		}
+	}
+
+	for i, srv := range servers {
+		style := lipgloss.NewStyle().Foreground(logTagColors[i%len(logTagColors)]).Bold(true)
+		tags[i] = style.Render(srv.Name) + strings.Repeat(" ", width-len(srv.Name)) + ui.RenderMuted(" | ")
+	}
+	return tags
+}
+
+// logMatcher compiles --grep into a line filter, nil when there's no pattern
+func logMatcher(pattern string, ignoreCase bool) (func(string) bool, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --grep pattern: %w", err)
+	}
+	return re.MatchString, nil
+}
+
+// followLogs prints lines as they're appended to the servers' logs, until ctx
+// is cancelled. Lines from different servers are interleaved as they arrive;
+// match, if set, filters them.
+func followLogs(ctx context.Context, servers []types.Server, tags []string, match func(string) bool) error {
+	followers := make([]*server.LogFollower, len(servers))
+	for i, srv := range servers {
+		followers[i] = server.NewLogFollower(srv.Path)
+	}
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for i, follower := range followers {
+			newLines, err := follower.Poll()
+			if err != nil {
+				return err
+			}
+			for _, line := range newLines {
+				if match != nil && !match(line) {
+					continue
+				}
+				fmt.Println(tags[i] + ui.RenderLogLine(line))
+			}
+		}
+	}
 }
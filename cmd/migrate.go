@@ -119,6 +119,12 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 }
 
 func migrateServer(srv *types.Server, metadataManager *server.MetadataManager, configGen *server.ConfigGenerator) error {
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	// Create bin/ directory
 	binPath := filepath.Join(srv.Path, "bin")
 	if err := os.MkdirAll(binPath, 0755); err != nil {
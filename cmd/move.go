@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move <server-name> <new-path>",
+	Short: "Move a server's folder",
+	Long: `Moves a stopped server's folder to new-path, or into it if new-path is an
+existing folder, and updates the registry and the launch script (run.sh or
+run.cmd), which hold the absolute path. Moving to another drive copies the
+folder and removes the original once the copy is registered.
+
+Examples:
+  inkwash move myserver /srv/fivem/myserver
+  inkwash move myserver D:\servers`,
+	Args: cobra.ExactArgs(2),
+	RunE: runMove,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+}
+
+func runMove(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	result, err := client.Move(cmd.Context(), args[0], args[1])
+	if result == nil {
+		return err
+	}
+
+	how := "Moved"
+	if result.Copied {
+		how = "Copied"
+	}
+	fmt.Printf("%s %s\n", ui.RenderSuccess(fmt.Sprintf("%s '%s' to", how, args[0])), ui.RenderPath(result.Server.Path))
+	return err
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated [server-name...]",
+	Short: "Show servers and resources with updates available",
+	Long: `Checks every registered server (or the ones named) for a newer FXServer build
+on its channel, and its resources for newer upstream versions, then lists what's
+outdated with the command that updates it. Nothing is changed.
+
+Pinned servers are compared with the recommended build. Resources installed
+with 'resource add' or a template are compared with their repository's latest
+release or branch commit; other resource folders that are git checkouts are
+fetched and compared with the branch they track. --no-resources checks builds
+only.`,
+	Example: `  inkwash outdated
+  inkwash outdated myserver --no-resources
+  inkwash outdated -o json`,
+	ValidArgsFunction: completeServerNames,
+	SilenceUsage:      true,
+	RunE:              runOutdated,
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+
+	outdatedCmd.Flags().Bool("no-resources", false, "Only check FXServer builds")
+}
+
+// Kinds of outdated items
+const (
+	outdatedBuild    = "build"
+	outdatedResource = "resource"
+)
+
+// outdatedView is something `outdated` found a newer version of
+type outdatedView struct {
+	Server    string `json:"server"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Current   string `json:"current"`
+	Available string `json:"available"`
+	Command   string `json:"command"`
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	noResources, _ := cmd.Flags().GetBool("no-resources")
+
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	var servers []types.Server
+	if len(args) == 0 {
+		servers = reg.List()
+	} else {
+		for _, name := range args {
+			srv, err := reg.Get(name)
+			if err != nil {
+				return fmt.Errorf("server '%s' not found", name)
+			}
+			servers = append(servers, *srv)
+		}
+	}
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	checker := &outdatedChecker{builds: make(map[string][]types.Build)}
+	_, gitErr := exec.LookPath("git")
+	checker.git = gitErr == nil
+
+	views := []outdatedView{}
+	for i := range servers {
+		srv := &servers[i]
+
+		metadata, err := server.NewMetadataManager().Load(srv.Path)
+		if err != nil {
+			checker.warn("%s: couldn't load metadata (%v), run 'inkwash migrate'", srv.Name, err)
+		}
+
+		if metadata != nil {
+			if view := checker.checkBuild(srv, metadata); view != nil {
+				views = append(views, *view)
+			}
+		}
+		if !noResources {
+			views = append(views, checker.checkResources(ctx, srv, metadata)...)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return writeOutput(cmd, views, func() {
+		if len(views) == 0 {
+			fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Everything is up to date (%d server(s) checked)", len(servers))))
+			return
+		}
+
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("OUTDATED"))
+		for _, v := range views {
+			name := v.Server
+			if v.Kind == outdatedResource {
+				name += "/" + v.Name
+			}
+			fmt.Printf("  %s %s %s %s\n",
+				ui.RenderAccent(fmt.Sprintf("%-30s", name)),
+				fmt.Sprintf("%-22s", v.Current),
+				ui.RenderMuted("→"),
+				v.Available)
+			fmt.Printf("      %s\n", ui.RenderMuted(v.Command))
+		}
+		fmt.Printf("\n%d update(s) available\n", len(views))
+	})
+}
+
+// outdatedChecker looks up newer versions, fetching each platform's build
+// list once
+type outdatedChecker struct {
+	builds map[string][]types.Build // By target OS; nil when it couldn't be fetched
+	git    bool                     // Whether git is installed, for checking checkouts
+}
+
+// warn reports a check that couldn't be made
+func (oc *outdatedChecker) warn(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(ui.SymbolWarning+" "+fmt.Sprintf(format, args...)))
+}
+
+// buildsFor returns the builds published for targetOS, or the last list
+// fetched if the artifacts page can't be reached
+func (oc *outdatedChecker) buildsFor(targetOS string) []types.Build {
+	if builds, ok := oc.builds[targetOS]; ok {
+		return builds
+	}
+
+	client := download.NewArtifactClient()
+	if err := client.SetTargetOS(targetOS); err != nil {
+		oc.warn("Couldn't check %s builds: %v", targetOS, err)
+		oc.builds[targetOS] = nil
+		return nil
+	}
+	builds, err := client.FetchBuilds()
+	if err != nil {
+		builds = client.CachedBuilds()
+		if builds == nil {
+			oc.warn("Couldn't check %s builds: %v", targetOS, err)
+		} else {
+			oc.warn("Couldn't reach the artifacts page (%v); comparing with the last list fetched", err)
+		}
+	}
+	oc.builds[targetOS] = builds
+	return builds
+}
+
+// checkBuild compares a server's build with the newest on its channel, or
+// with the recommended build if it's pinned
+func (oc *outdatedChecker) checkBuild(srv *types.Server, metadata *types.ServerMetadata) *outdatedView {
+	builds := oc.buildsFor(srv.GetTargetOS())
+	if builds == nil {
+		return nil
+	}
+
+	channel := metadata.BuildChannel()
+	pinned := channel == types.BuildChannelPinned
+	if pinned {
+		channel = types.BuildChannelRecommended
+	}
+	latest, err := download.ResolveBuild(builds, channel)
+	if err != nil {
+		oc.warn("%s: %v", srv.Name, err)
+		return nil
+	}
+	if latest.Number <= metadata.Build.Number {
+		return nil
+	}
+
+	view := &outdatedView{
+		Server:    srv.Name,
+		Kind:      outdatedBuild,
+		Name:      "FXServer",
+		Current:   buildName(metadata.Build.Number),
+		Available: fmt.Sprintf("build %d (%s)", latest.Number, channel),
+		Command:   "inkwash update-server " + srv.Name,
+	}
+	if pinned {
+		view.Current += " (pinned)"
+		view.Command += fmt.Sprintf(" --build %d", latest.Number)
+	}
+	return view
+}
+
+// checkResources looks for newer versions of a server's resources: the ones
+// recorded in its metadata, and folders that are git checkouts
+func (oc *outdatedChecker) checkResources(ctx context.Context, srv *types.Server, metadata *types.ServerMetadata) []outdatedView {
+	var views []outdatedView
+
+	tracked := make(map[string]bool)
+	if metadata != nil {
+		for _, res := range metadata.Resources {
+			tracked[res.Name] = true
+
+			update, err := resource.CheckInstalled(res)
+			if err != nil {
+				oc.warn("%s/%s: %v", srv.Name, res.Name, err)
+				continue
+			}
+			if update == nil {
+				continue
+			}
+			views = append(views, outdatedView{
+				Server:    srv.Name,
+				Kind:      outdatedResource,
+				Name:      res.Name,
+				Current:   update.Current,
+				Available: update.Available,
+				Command:   fmt.Sprintf("inkwash resource update %s %s", srv.Name, res.Name),
+			})
+		}
+	}
+
+	found, err := resource.Scan(filepath.Join(srv.Path, "resources"))
+	if err != nil {
+		oc.warn("%s: %v", srv.Name, err)
+		return views
+	}
+	for _, res := range found {
+		if tracked[res.Name] || !resource.IsGitCheckout(res.Path) {
+			continue
+		}
+		if !oc.git {
+			oc.warn("%s/%s is a git checkout but git isn't installed, skipping it", srv.Name, res.Name)
+			continue
+		}
+
+		behind, head, err := resource.CheckCheckout(ctx, res.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return views
+			}
+			oc.warn("%s/%s: %v", srv.Name, res.Name, err)
+			continue
+		}
+		if behind == 0 {
+			continue
+		}
+		views = append(views, outdatedView{
+			Server:    srv.Name,
+			Kind:      outdatedResource,
+			Name:      res.Name,
+			Current:   head,
+			Available: fmt.Sprintf("%d new commit(s)", behind),
+			Command:   fmt.Sprintf("git -C %s pull", quoteArg(res.Path)),
+		})
+	}
+
+	return views
+}
+
+// quoteArg quotes a path for a suggested command if it has spaces
+func quoteArg(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// checkOutputFlag rejects an unknown --output format before the command runs
+func checkOutputFlag(cmd *cobra.Command) error {
+	switch format, _ := cmd.Flags().GetString("output"); format {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return &usageError{fmt.Errorf("unknown output format %q (expected %s or %s)", format, outputText, outputJSON)}
+	}
+}
+
+// jsonOutput reports whether the command was run with --output json
+func jsonOutput(cmd *cobra.Command) bool {
+	format, _ := cmd.Flags().GetString("output")
+	return format == outputJSON
+}
+
+// writeOutput is how read commands print their results: with --output json,
+// data is written to stdout as indented JSON; otherwise text prints the
+// human-readable view. Give data a non-nil slice for empty lists so they
+// come out as [] rather than null.
+func writeOutput(cmd *cobra.Command, data interface{}, text func()) error {
+	if !jsonOutput(cmd) {
+		text()
+		return nil
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/config"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage a server's server.cfg profiles (dev, staging, prod...)",
+	Long: `A profile is a server.<profile>.cfg next to server.cfg, e.g. server.dev.cfg.
+It execs server.cfg and then sets the convars that differ, so endpoints,
+resources and later changes to server.cfg carry over. Start a server with one
+with 'inkwash start <server-name> --profile <profile>'; it keeps starting with
+that profile (also when the daemon restarts it) until started with
+--profile default.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list <server-name>",
+	Short: "List a server's profiles and the convars they override",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileList,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <server-name> <profile> [convar=value...]",
+	Short: "Create a profile from server.cfg with some convars overridden",
+	Long: `Writes server.<profile>.cfg, which execs server.cfg and then sets the given
+convars. Known convars are checked first, as with 'inkwash config set'. Change
+them later with 'inkwash config set <server-name> <convar> <value> --profile
+<profile>'.
+
+Example:
+  inkwash profile create myserver dev sv_hostname="My Server [DEV]" sv_maxclients=8`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runProfileCreate,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <server-name> <profile>",
+	Short: "Delete a profile's server.<profile>.cfg",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runProfileDelete,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+}
+
+// profileView is a profile as `profile list --output json` shows it
+type profileView struct {
+	Name    string          `json:"name"`
+	File    string          `json:"file"`
+	Active  bool            `json:"active"` // The server starts with it
+	Convars []profileConvar `json:"convars,omitempty"`
+}
+
+// profileConvar is a convar a profile overrides
+type profileConvar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	srv, err := client.Get(args[0])
+	if err != nil {
+		return err
+	}
+	profiles, err := client.Profiles(srv.Name)
+	if err != nil {
+		return err
+	}
+
+	views := []profileView{{Name: server.DefaultProfile, File: server.ProfileConfigName(""), Active: srv.Profile == ""}}
+	for _, profile := range profiles {
+		view := profileView{Name: profile, File: server.ProfileConfigName(profile), Active: srv.Profile == profile}
+		if cfg, err := servercfg.Load(server.ProfileConfigPath(srv.Path, profile)); err == nil {
+			for _, setting := range cfg.Settings() {
+				value := setting.Value
+				if config.IsSensitive(setting.Name) && value != "" {
+					value = "********"
+				}
+				view.Convars = append(view.Convars, profileConvar{Name: setting.Name, Value: value})
+			}
+		}
+		views = append(views, view)
+	}
+
+	return writeOutput(cmd, views, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("PROFILES: "+srv.Name))
+		for _, view := range views {
+			marker := "  "
+			if view.Active {
+				marker = ui.StyleSuccess.Render("*") + " "
+			}
+			fmt.Printf("  %s%s  %s\n", marker, ui.RenderAccent(fmt.Sprintf("%-12s", view.Name)), ui.RenderMuted(view.File))
+
+			var overrides []string
+			for _, convar := range view.Convars {
+				overrides = append(overrides, convar.Name+"="+convar.Value)
+			}
+			if len(overrides) > 0 {
+				fmt.Printf("      %s\n", strings.Join(overrides, ", "))
+			}
+		}
+		if len(profiles) == 0 {
+			fmt.Printf("\n  %s\n", ui.RenderMuted(fmt.Sprintf("No profiles yet; create one with 'inkwash profile create %s <profile>'", srv.Name)))
+		}
+		fmt.Println()
+	})
+}
+
+func runProfileCreate(cmd *cobra.Command, args []string) error {
+	serverName, profile := args[0], args[1]
+	if err := server.ValidateProfileName(profile); err != nil {
+		return &usageError{err}
+	}
+
+	var overrides []inkwash.ConvarOverride
+	for _, pair := range args[2:] {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return &usageError{fmt.Errorf("'%s' isn't convar=value", pair)}
+		}
+		if err := servercfg.ValidateConvar(name, value); err != nil {
+			return &usageError{err}
+		}
+		overrides = append(overrides, inkwash.ConvarOverride{Name: name, Value: value})
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	path, err := client.CreateProfile(cmd.Context(), serverName, profile, overrides)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess("Created the "+profile+" profile: "+path))
+	fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("Start with it: inkwash start %s --profile %s", serverName, profile)))
+	return nil
+}
+
+func runProfileDelete(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteProfile(cmd.Context(), args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess("Deleted the "+args[1]+" profile of '"+args[0]+"'"))
+	return nil
+}
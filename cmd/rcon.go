@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/rcon"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+)
+
+var rconCmd = &cobra.Command{
+	Use:   "rcon <server-name> [command]",
+	Short: "Run a console command on a running server",
+	Long: `Sends a console command to a running server over RCON and prints what the
+server answered. Everything after the server name is the command, so quoting
+it is optional.
+
+RCON needs rcon_password set in the server's server.cfg (it's commented out
+in the generated one), and the server restarted after setting it.
+
+With --interactive, commands are read one per line until "exit" or Ctrl+D.
+
+Examples:
+  inkwash rcon myserver refresh
+  inkwash rcon myserver "restart esx_core"
+  inkwash rcon myserver --interactive`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRCON,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(rconCmd)
+
+	rconCmd.Flags().BoolP("interactive", "i", false, "Read commands from the terminal until \"exit\"")
+}
+
+// rconView is a command's result as `rcon --output json` shows it
+type rconView struct {
+	Server   string `json:"server"`
+	Command  string `json:"command"`
+	Response string `json:"response"`
+}
+
+func runRCON(cmd *cobra.Command, args []string) error {
+	serverName := args[0]
+	command := strings.TrimSpace(strings.Join(args[1:], " "))
+	interactive, _ := cmd.Flags().GetBool("interactive")
+
+	switch {
+	case interactive && command != "":
+		return &usageError{fmt.Errorf("give either a command or --interactive, not both")}
+	case !interactive && command == "":
+		return &usageError{fmt.Errorf("no command given; pass one after the server name or use --interactive")}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	srv, err := client.Get(serverName)
+	if err != nil {
+		return err
+	}
+	if !client.IsRunning(srv) {
+		return fmt.Errorf("%w: '%s'; start it with 'inkwash start %s'", inkwash.ErrServerNotRunning, serverName, serverName)
+	}
+
+	rconClient, err := server.DialRCON(srv)
+	if err != nil {
+		return err
+	}
+	defer rconClient.Close()
+
+	if interactive {
+		return rconREPL(srv.Name, rconClient)
+	}
+
+	response, err := rconClient.Exec(command)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(cmd, rconView{Server: srv.Name, Command: command, Response: response}, func() {
+		printRCONResponse(response)
+	})
+}
+
+// rconREPL sends commands read from stdin until "exit", "quit" or end of
+// input. A command that fails is reported and the next one is read, except
+// for a rejected password, which no later command would get past.
+func rconREPL(serverName string, client *rcon.Client) error {
+	// Prompts only make sense when someone is typing the commands
+	prompt := ""
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		prompt = ui.RenderAccent(serverName+">") + " "
+		fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("Connected to %s over RCON. Type \"exit\" or press Ctrl+D to leave.", client.Address())))
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(prompt)
+		if !scanner.Scan() {
+			if prompt != "" {
+				fmt.Println()
+			}
+			return scanner.Err()
+		}
+
+		command := strings.TrimSpace(scanner.Text())
+		switch command {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+
+		response, err := client.Exec(command)
+		if errors.Is(err, rcon.ErrBadPassword) {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(fmt.Sprintf("%s %v", ui.SymbolWarning, err)))
+			continue
+		}
+		printRCONResponse(response)
+	}
+}
+
+// printRCONResponse prints a server's answer, ending it with a newline
+// if it didn't have one (commands like "refresh" print nothing at all)
+func printRCONResponse(response string) {
+	if response == "" {
+		return
+	}
+	fmt.Print(response)
+	if !strings.HasSuffix(response, "\n") {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Maintain the server registry",
+	Long:  `Commands for repairing the list of servers InkWash manages.`,
+}
+
+var registryScanCmd = &cobra.Command{
+	Use:   "scan <root>",
+	Short: "Find servers on disk and re-add them to the registry",
+	Long: `Walks <root> for InkWash servers (folders containing both metadata.json and
+server.cfg) and offers to add any that aren't registered. Use this to recover
+after the registry was deleted or corrupted.
+
+Names are taken from the folder name, the port, bind address and game build
+from server.cfg. A name that's already taken gets a numeric suffix. If the
+registry file can't be parsed, it's backed up and rebuilt.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegistryScan,
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.AddCommand(registryScanCmd)
+
+	registryScanCmd.Flags().BoolP("yes", "y", false, "Add every server found without asking")
+	registryScanCmd.Flags().Bool("dry-run", false, "Only list what would be added")
+}
+
+func runRegistryScan(cmd *cobra.Command, args []string) error {
+	root := args[0]
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	reg, err := openRegistryForRepair(yes, dryRun)
+	if err != nil {
+		return err
+	}
+
+	found, err := server.Discover(root)
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("REGISTRY SCAN"))
+	if len(found) == 0 {
+		fmt.Printf("  No InkWash servers found under %s\n\n", ui.RenderPath(root))
+		return nil
+	}
+	fmt.Printf("  Found %d server(s) under %s\n\n", len(found), ui.RenderPath(root))
+
+	var registered []types.Server
+	if reg != nil {
+		registered = reg.List()
+	}
+
+	added := 0
+	for _, d := range found {
+		srv := d.Server
+
+		if owner := registeredAt(registered, srv.Path); owner != "" {
+			fmt.Printf("  %s %s %s\n", ui.RenderMuted(ui.SymbolCheck), ui.RenderAccent(srv.Name), ui.RenderMuted("already registered as '"+owner+"'"))
+			continue
+		}
+
+		if name := uniqueServerName(registered, srv.Name); name != srv.Name {
+			fmt.Printf("  %s\n", ui.RenderWarning(fmt.Sprintf("%s Name '%s' is taken, using '%s'", ui.SymbolWarning, srv.Name, name)))
+			srv.Name = name
+		}
+
+		fmt.Printf("  %s %s\n", ui.SymbolDot, ui.RenderAccent(srv.Name))
+		fmt.Printf("      %s\n", ui.RenderPath(srv.Path))
+		fmt.Printf("      %s\n", ui.RenderMuted(fmt.Sprintf("Endpoint: %s  Build: %d  Game: %s",
+			srv.Endpoint(), d.Metadata.Build.Number, types.GameBuildLabel(srv.GetGameBuild()))))
+		for _, other := range registered {
+			if other.Port == srv.Port {
+				fmt.Printf("      %s\n", ui.RenderWarning(fmt.Sprintf("%s Port %d is also used by '%s'", ui.SymbolWarning, srv.Port, other.Name)))
+				break
+			}
+		}
+
+		if dryRun {
+			continue
+		}
+		if !yes && !confirm(fmt.Sprintf("      Add '%s'? [y/N]: ", srv.Name)) {
+			continue
+		}
+
+		if err := reg.Add(srv); err != nil {
+			return fmt.Errorf("failed to add '%s': %w", srv.Name, err)
+		}
+		registered = append(registered, srv)
+		added++
+	}
+
+	fmt.Println()
+	if dryRun {
+		fmt.Printf("%s\n\n", ui.RenderMuted("Dry run: nothing was added"))
+	} else {
+		fmt.Printf("%s\n\n", ui.RenderSuccess(fmt.Sprintf("Added %d server(s) to the registry", added)))
+	}
+
+	return nil
+}
+
+// openRegistryForRepair loads the registry, offering to back up and replace
+// it if it's corrupt. In a dry run a corrupt registry is treated as empty (nil).
+func openRegistryForRepair(yes, dryRun bool) (*registry.Registry, error) {
+	registryPath := registry.GetRegistryPath()
+
+	reg, err := registry.NewRegistry(registryPath)
+	if err == nil {
+		return reg, nil
+	}
+	if !errors.Is(err, registry.ErrCorrupt) {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	fmt.Printf("%s\n", ui.RenderWarning(fmt.Sprintf("%s The registry at %s is corrupt: %v", ui.SymbolWarning, registryPath, err)))
+	if dryRun {
+		return nil, nil
+	}
+	if !yes && !confirm("Back it up and rebuild it from the servers found? [y/N]: ") {
+		return nil, fmt.Errorf("registry is corrupt; rerun with --yes to back it up and rebuild it")
+	}
+
+	backupPath, err := registry.BackupCorrupt(registryPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%s\n", ui.RenderMuted("Old registry saved to "+backupPath))
+
+	reg, err = registry.NewRegistry(registryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry: %w", err)
+	}
+	return reg, nil
+}
+
+// registeredAt returns the name of the server registered at path, or ""
+func registeredAt(servers []types.Server, path string) string {
+	for _, srv := range servers {
+		if filepath.Clean(srv.Path) == filepath.Clean(path) {
+			return srv.Name
+		}
+	}
+	return ""
+}
+
+// uniqueServerName returns name, or name-2, name-3... if it's already registered
+func uniqueServerName(servers []types.Server, name string) string {
+	taken := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		taken[srv.Name] = true
+	}
+
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <server-name> <new-name>",
+	Short: "Rename a server",
+	Long: `Registers a stopped server under a new name. Its folder keeps its name (use
+'inkwash move' to change that), and the generated README.md is updated with
+the new name unless it's been edited.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRename,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	srv, err := client.Rename(cmd.Context(), args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Renamed '%s' to '%s'", args[0], srv.Name)))
+	return nil
+}
@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var resourceCmd = &cobra.Command{
+	Use:   "resource",
+	Short: "Manage server resources",
+	Long:  `Inspect and manage the resources installed in a FiveM server.`,
+}
+
+var resourceScanCmd = &cobra.Command{
+	Use:   "scan <server-name>",
+	Short: "Compare resources on disk with server.cfg",
+	Long: `Lists resources that exist in the server's resources/ folder but are never
+started, and resources started in server.cfg that don't exist on disk.
+
+Use --fix to append ensure lines for resources that aren't started yet.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResourceScan,
+}
+
+var resourceAddCmd = &cobra.Command{
+	Use:     "add <server-name> <github-url>[@ref]",
+	Aliases: []string{"install"},
+	Short:   "Install a resource from a GitHub repository",
+	Long: `Downloads a GitHub repository (at an optional branch, tag or commit) into the
+server's resources/ folder, appends an ensure line to server.cfg and records
+the source so it can be updated later with 'inkwash resource update'.
+
+With --release, the latest GitHub release (or the release tagged @ref) is
+installed instead: its first .zip asset if it has one, otherwise the source of
+the tagged commit. Use it for resources whose releases are built, e.g. with a
+bundled web UI.
+
+The resource's root must contain an fxmanifest.lua or __resource.lua.
+
+Examples:
+  inkwash resource add myserver https://github.com/owner/my-resource
+  inkwash resource add myserver owner/my-resource@v1.2.0
+  inkwash resource install myserver overextended/ox_lib --release`,
+	Args: cobra.ExactArgs(2),
+	RunE: runResourceAdd,
+}
+
+var resourceUpdateCmd = &cobra.Command{
+	Use:   "update <server-name> <resource-name>",
+	Short: "Re-download a resource installed with 'resource add'",
+	Long: `Downloads the resource again from its recorded repository and ref. Branch refs
+pick up the latest commit, and resources installed with --release the latest
+release; use --ref to switch to another branch, tag or commit (or release tag).`,
+	Args: cobra.ExactArgs(2),
+	RunE: runResourceUpdate,
+}
+
+func init() {
+	rootCmd.AddCommand(resourceCmd)
+
+	resourceCmd.AddCommand(resourceScanCmd)
+	resourceCmd.AddCommand(resourceAddCmd)
+	resourceCmd.AddCommand(resourceUpdateCmd)
+
+	resourceScanCmd.Flags().Bool("fix", false, "Append ensure lines for resources that aren't started")
+
+	resourceAddCmd.Flags().String("name", "", "Folder name under resources/ (default: repository name)")
+	resourceAddCmd.Flags().Bool("no-ensure", false, "Don't add an ensure line to server.cfg")
+	resourceAddCmd.Flags().Bool("force", false, "Replace an existing resource folder with the same name")
+	resourceAddCmd.Flags().Bool("release", false, "Install the latest release (or the release tagged @ref) instead of the source")
+
+	resourceUpdateCmd.Flags().String("ref", "", "Switch to this branch, tag or commit")
+}
+
+func runResourceAdd(cmd *cobra.Command, args []string) error {
+	serverName := args[0]
+	name, _ := cmd.Flags().GetString("name")
+	noEnsure, _ := cmd.Flags().GetBool("no-ensure")
+	force, _ := cmd.Flags().GetBool("force")
+	release, _ := cmd.Flags().GetBool("release")
+
+	src, err := resource.ParseGitHubSource(args[1])
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = src.Repo
+	}
+
+	srv, err := getServer(serverName)
+	if err != nil {
+		return err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	resourcesPath := filepath.Join(srv.Path, "resources")
+	if _, err := os.Stat(filepath.Join(resourcesPath, name)); err == nil && !force {
+		return fmt.Errorf("resources/%s already exists (use --force to replace it, or --name to pick another name)", name)
+	}
+
+	if err := installResource(srv, src, name, release, "", resourcesPath); err != nil {
+		return err
+	}
+
+	if noEnsure {
+		return nil
+	}
+
+	added, err := servercfg.AppendEnsures(filepath.Join(srv.Path, "server.cfg"), []string{name})
+	if err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		fmt.Printf("%s\n", ui.RenderSuccess("Added 'ensure "+name+"' to server.cfg"))
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func runResourceUpdate(cmd *cobra.Command, args []string) error {
+	serverName, name := args[0], args[1]
+
+	srv, err := getServer(serverName)
+	if err != nil {
+		return err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	metadata, err := server.NewMetadataManager().Load(srv.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	installed := metadata.FindResource(name)
+	if installed == nil {
+		return fmt.Errorf("resource '%s' wasn't installed with 'inkwash resource add'", name)
+	}
+
+	src, err := resource.ParseGitHubSource(installed.Repository)
+	if err != nil {
+		return err
+	}
+	src.Ref = installed.Ref
+	if cmd.Flags().Changed("ref") {
+		src.Ref, _ = cmd.Flags().GetString("ref")
+	}
+
+	// Updated where it is, e.g. in the [category] a template put it in
+	resourcesPath := filepath.Join(srv.Path, "resources")
+	found, _ := resource.Scan(resourcesPath)
+	for _, r := range found {
+		if r.Name == name {
+			resourcesPath = filepath.Dir(r.Path)
+			break
+		}
+	}
+
+	return installResource(srv, src, name, installed.Release, installed.Path, resourcesPath)
+}
+
+// getServer looks up a server in the registry
+func getServer(serverName string) (*types.Server, error) {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	srv, err := reg.Get(serverName)
+	if err != nil {
+		return nil, fmt.Errorf("server '%s' not found", serverName)
+	}
+
+	return srv, nil
+}
+
+// installResource downloads src, or its release, into resourcesPath and
+// records it in metadata.json. path is the resource's folder in the
+// repository, "" for its root.
+func installResource(srv *types.Server, src *resource.GitHubSource, name string, release bool, path, resourcesPath string) error {
+	var manifest *resource.Manifest
+	var tag string
+	if path != "" {
+		fmt.Printf("Downloading %s...\n", ui.RenderAccent(src.String()+" ("+path+")"))
+
+		archiveURL, label := src.ArchiveURL(), src.String()
+		if release {
+			found, err := resource.FindRelease(src)
+			if err != nil {
+				return err
+			}
+			archiveURL, label = found.ArchiveURL, fmt.Sprintf("%s release %s", src.URL(), found.Tag)
+			tag = found.Tag
+		}
+
+		archives := resource.NewArchives()
+		defer archives.Close()
+		var err error
+		manifest, err = archives.Install(archiveURL, label, path, resourcesPath, name, nil)
+		if err != nil {
+			return err
+		}
+	} else if release {
+		found, err := resource.FindRelease(src)
+		if err != nil {
+			return err
+		}
+
+		label := fmt.Sprintf("%s release %s", src.URL(), found.Tag)
+		tag = found.Tag
+		if found.Asset != "" {
+			label += " (" + found.Asset + ")"
+		}
+		fmt.Printf("Downloading %s...\n", ui.RenderAccent(label))
+
+		manifest, err = resource.InstallArchive(found.ArchiveURL, label, resourcesPath, name, nil)
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Downloading %s...\n", ui.RenderAccent(src.String()))
+
+		var err error
+		manifest, err = resource.InstallFromGitHub(src, resourcesPath, name, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	version := manifest.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	installedAt, _ := filepath.Rel(srv.Path, filepath.Join(resourcesPath, name))
+	fmt.Printf("%s %s\n", ui.RenderSuccess("Installed "+filepath.ToSlash(installedAt)), ui.RenderMuted("("+version+")"))
+
+	// Record the source so 'resource update' knows where to fetch from
+	metadataManager := server.NewMetadataManager()
+	metadata, err := metadataManager.Load(srv.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning("Couldn't record the resource source (no metadata.json, run 'inkwash migrate')"))
+		return nil
+	}
+
+	metadata.RecordResource(types.InstalledResource{
+		Name:        name,
+		Repository:  src.URL(),
+		Ref:         src.Ref,
+		Release:     release,
+		Tag:         tag,
+		Path:        path,
+		Version:     manifest.Version,
+		InstalledAt: time.Now(),
+	})
+
+	if err := metadataManager.Save(srv.Path, metadata); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	return nil
+}
+
+func runResourceScan(cmd *cobra.Command, args []string) error {
+	serverName := args[0]
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	// Load registry
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	// Get server
+	srv, err := reg.Get(serverName)
+	if err != nil {
+		return fmt.Errorf("server '%s' not found", serverName)
+	}
+
+	cfgPath := filepath.Join(srv.Path, "server.cfg")
+	cfg, err := servercfg.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	// Resources ensured in an exec'd resources.cfg are started too. cfg is
+	// only read from here on; --fix loads server.cfg again.
+	if cfg.Execs(servercfg.ResourcesFile) {
+		if included, err := servercfg.Load(filepath.Join(srv.Path, servercfg.ResourcesFile)); err == nil {
+			cfg.Lines = append(cfg.Lines, included.Lines...)
+		}
+	}
+
+	resources, err := resource.Scan(filepath.Join(srv.Path, "resources"))
+	if err != nil {
+		return err
+	}
+
+	// Resources on disk that are never started
+	onDisk := make(map[string]bool)
+	var notStarted []resource.Resource
+	for _, res := range resources {
+		onDisk[res.Name] = true
+		if !cfg.IsStarted(res.Name, res.Category) {
+			notStarted = append(notStarted, res)
+		}
+	}
+
+	// Categories on disk, so "ensure [category]" lines aren't reported as missing
+	categories := make(map[string]bool)
+	for _, res := range resources {
+		if res.Category != "" {
+			categories["["+res.Category+"]"] = true
+		}
+	}
+
+	// Resources started in server.cfg that aren't on disk
+	var missing []string
+	for _, name := range cfg.StartedResources() {
+		if !onDisk[name] && !categories[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("RESOURCE SCAN"))
+	fmt.Printf("  %s %d resource(s) on disk, %d started in server.cfg\n\n",
+		ui.RenderMuted("Found"), len(resources), len(cfg.StartedResources()))
+
+	if len(notStarted) == 0 && len(missing) == 0 {
+		fmt.Printf("%s\n\n", ui.RenderSuccess("server.cfg and resources/ are in sync"))
+		return nil
+	}
+
+	if len(notStarted) > 0 {
+		fmt.Printf("  %s\n", ui.RenderAccent("Present but not started:"))
+		for _, res := range notStarted {
+			rel, _ := filepath.Rel(srv.Path, res.Path)
+			fmt.Printf("    %s  %s\n", res.Name, ui.RenderPath(rel))
+		}
+		fmt.Println()
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf("  %s\n", ui.RenderAccent("Started but missing:"))
+		for _, name := range missing {
+			fmt.Printf("    %s\n", ui.RenderWarning(name))
+		}
+		fmt.Println()
+	}
+
+	if !fix {
+		if len(notStarted) > 0 {
+			fmt.Printf("Run %s to ensure the new resources.\n\n",
+				ui.RenderCode(fmt.Sprintf("inkwash resource scan %s --fix", serverName)))
+		}
+		return nil
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	names := make([]string, len(notStarted))
+	for i, res := range notStarted {
+		names[i] = res.Name
+	}
+
+	added, err := servercfg.AppendEnsures(cfgPath, names)
+	if err != nil {
+		return err
+	}
+
+	if len(added) > 0 {
+		fmt.Printf("%s\n\n", ui.RenderSuccess(fmt.Sprintf("Added %d ensure line(s) to server.cfg", len(added))))
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "%s\n\n", ui.RenderMuted("Missing resources must be removed from server.cfg manually."))
+	}
+
+	return nil
+}
@@ -1,15 +1,50 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/VexoaXYZ/inkwash/internal/config"
+	"github.com/VexoaXYZ/inkwash/internal/network"
+	"github.com/VexoaXYZ/inkwash/internal/web"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+// version is the inkwash release, set from main's build-time version
+var version = "dev"
+
+// boundFlags maps config keys to the global flags that override them
+var boundFlags = map[string]string{
+	"debug":           "debug",
+	"network.timeout": "timeout",
+}
+
+// envPrefix namespaces the environment variables settings are read from, so
+// other tools' variables (e.g. API_TOKEN) aren't picked up
+const envPrefix = "INKWASH"
+
+// envKeyReplacer maps nested config keys to environment variable names
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
+// configEnvVar returns the environment variable a setting is read from,
+// e.g. INKWASH_CACHE_MAX_BUILDS for cache.max_builds
+func configEnvVar(key string) string {
+	return envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// configLoadErr is set when a config file was found but couldn't be read
+var configLoadErr error
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "inkwash",
@@ -24,35 +59,119 @@ Features:
   • Automated FiveM downloads and installation
 
 Commands:
+  dashboard Interactive dashboard (default when run without a command)
   create    Create a new FiveM server (interactive wizard)
   start     Start a server
   stop      Stop a server
   list      List all servers
+  update-server Upgrade a server's FXServer build in place
+  rename    Rename a server
+  move      Move a server's folder
+  delete    Delete a server and its folder
   logs      View server logs
+  console   Attach to a running server (live log and RCON)
+  rcon      Run a console command on a running server
+  daemon    Supervise servers and restart them when they crash
+  watch     Check a server's health and restart it when it stops responding
+  crashes   List a server's crashes with their log and crash dumps
+  web       Serve a web panel for managing servers
+  serve-api Serve the REST API for remote management
   info      Show server information
   convert   Convert GTA5 mods to FiveM resources
   key       Manage FiveM license keys (add/list/remove)
+  resource  Manage server resources (scan, add, update)
+  template  Save server setups as templates and apply them (export/apply/list)
+  config    Inspect configuration, edit server.cfg (show/get/set/edit)
+  profile   Manage server.cfg profiles like dev and prod (list/create/delete)
+  db        Create a server's MySQL/MariaDB database (setup)
+  registry  Repair the server registry (scan)
+  cache     List and check the FXServer build cache (list/verify)
+  env       Show detected platform and terminal details (for bug reports)
+  doctor    Check the environment and fix simple problems
   migrate   Migrate from older versions
+  uninstall Remove InkWash data from this machine
 
 Get started:
+  inkwash                     Open the server dashboard
   inkwash create              Create your first server
   inkwash key add             Add a FiveM license key
   inkwash convert             Convert GTA5 mods
 
 Documentation: https://github.com/VexoaXYZ/InkWash/wiki
 Get License Key: https://portal.cfx.re/servers/registration-keys`,
+	// Warn about config problems before any command runs
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		warnConfigIssues()
+		return checkOutputFlag(cmd)
+	},
 	// If no subcommand is provided, launch the interactive dashboard
 	Run: func(cmd *cobra.Command, args []string) {
-		cmd.Help()
+		if !isTerminal() {
+			// Scripts and pipes get the help text instead of a TUI
+			cmd.Help()
+			return
+		}
+		runDashboard()
 	},
 }
 
+// SetVersion records the release version reported by `inkwash env`
+func SetVersion(v string) {
+	version = v
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	markUsageErrors(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// newClient opens the inkwash API client with the configured cache settings
+func newClient() (*inkwash.Client, error) {
+	return inkwash.New(inkwash.Options{
+		MaxCachedBuilds: viper.GetInt("cache.max_builds"),
+		MaxCacheSize:    cacheMaxSize(),
+		LongPaths:       viper.GetBool("advanced.long_paths"),
+		VerifyCache:     viper.GetBool("cache.verify"),
+	})
+}
+
+// interruptContext returns a context that's cancelled on the first SIGINT or
+// SIGTERM, so long-running commands can clean up instead of dying mid-write.
+// A second signal exits immediately. Call stop once the work is done to
+// restore the default signal handling.
+func interruptContext(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigs:
+		case <-done:
+			return
+		}
+
+		fmt.Fprintln(os.Stderr, "\ncancelled, cleaning up...")
+		cancel()
+
+		select {
+		case <-sigs:
+			os.Exit(exitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel()
 	}
 }
 
@@ -62,7 +181,16 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/inkwash/config.yaml)")
 	rootCmd.PersistentFlags().Bool("no-animations", false, "disable all animations")
-	rootCmd.PersistentFlags().Bool("debug", false, "enable debug mode")
+	rootCmd.PersistentFlags().Bool("debug", false, "enable debug mode (logs HTTP requests to stderr)")
+	rootCmd.PersistentFlags().Bool("config-init", false, "write a default config file if none exists")
+	rootCmd.PersistentFlags().Int("timeout", 30, "timeout in seconds for network API requests (downloads use network.download_timeout)")
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "output format for list, info and other read commands: text or json")
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{outputText, outputJSON}, cobra.ShellCompDirectiveNoFileComp))
+
+	for key, flag := range boundFlags {
+		viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(flag))
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -84,20 +212,29 @@ func initConfig() {
 		viper.SetConfigName("config")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer) // cache.max_builds -> INKWASH_CACHE_MAX_BUILDS
+	viper.AutomaticEnv()                    // read in environment variables that match
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		if viper.GetBool("debug") {
 			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		}
+	} else if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound || cfgFile != "" {
+		// A file exists but is malformed (or --config points nowhere)
+		configLoadErr = err
 	}
 
 	// Set defaults
 	viper.SetDefault("defaults.install_path", getDefaultInstallPath())
 	viper.SetDefault("defaults.port", 30120)
+	viper.SetDefault("defaults.bind_address", "0.0.0.0")
+	viper.SetDefault("defaults.target_os", "") // empty = this machine's platform
 	viper.SetDefault("cache.enabled", true)
 	viper.SetDefault("cache.max_builds", 3)
+	viper.SetDefault("cache.max_size_gb", 0) // total size of cached builds, 0 = no limit
+	viper.SetDefault("cache.verify", false)  // checksum cached builds before installing from them
 	viper.SetDefault("ui.theme", "purple")
 	viper.SetDefault("ui.animations", "auto")
 	viper.SetDefault("ui.refresh_interval", 2)
@@ -105,6 +242,101 @@ func initConfig() {
 	viper.SetDefault("advanced.parallel_downloads", true)
 	viper.SetDefault("advanced.download_chunks", 3)
 	viper.SetDefault("advanced.log_level", "info")
+	// \\?\ paths on Windows, for installs under deep folders
+	viper.SetDefault("advanced.long_paths", false)
+	viper.SetDefault("convert.timeout", 30)           // seconds per convert.cfx.rs request
+	viper.SetDefault("convert.layout", "category")    // category, mod or flat
+	viper.SetDefault("convert.auto_ensure", false)    // add ensure lines for mods converted into a registered server
+	viper.SetDefault("convert.max_concurrent", 2)     // conversions the wizard runs at once on convert.cfx.rs
+	viper.SetDefault("convert.poll_interval", 2)      // seconds between progress checks of a conversion
+	viper.SetDefault("network.timeout", 30)           // seconds per API/metadata request
+	viper.SetDefault("network.download_timeout", 600) // seconds per file download, 0 = no limit
+	viper.SetDefault("network.retries", 3)            // retries of a failed request or download chunk, 0 = none
+	viper.SetDefault("network.retry_backoff", 1)      // seconds before the first retry, doubling after each
+	viper.SetDefault("network.retry_max_backoff", 30) // seconds, longest wait between retries
+	viper.SetDefault("network.retry_jitter", 20)      // percent each wait is randomised by
+	viper.SetDefault("start.wait_timeout", 120)       // seconds inkwash start waits for a server to be ready
+	viper.SetDefault("daemon.interval", 5)            // seconds between checks
+	viper.SetDefault("daemon.max_restarts", 5)        // crashes within daemon.restart_window before giving up
+	viper.SetDefault("daemon.restart_window", 600)    // seconds
+	viper.SetDefault("web.listen", web.DefaultListen) // inkwash web, loopback only
+	viper.SetDefault("web.token", "")                 // empty = random token per run
+	viper.SetDefault("api.listen", "127.0.0.1:8080")  // inkwash serve-api, loopback only
+	viper.SetDefault("api.token", "")                 // empty = random token per run
+	viper.SetDefault("keys.backend", "machine")       // machine, keychain or passphrase protection for the key vault
+	// Where converted mods are ensured: server.cfg, or resources.cfg exec'd from it
+	viper.SetDefault("convert.ensure_file", "server.cfg")
+
+	// Log outbound HTTP requests when debugging
+	network.SetDebug(viper.GetBool("debug"))
+	network.SetTimeouts(
+		time.Duration(viper.GetInt("network.timeout"))*time.Second,
+		time.Duration(viper.GetInt("network.download_timeout"))*time.Second,
+	)
+	network.SetRetryPolicy(network.RetryPolicy{
+		Attempts:   viper.GetInt("network.retries") + 1,
+		Backoff:    time.Duration(viper.GetInt("network.retry_backoff")) * time.Second,
+		MaxBackoff: time.Duration(viper.GetInt("network.retry_max_backoff")) * time.Second,
+		Jitter:     float64(viper.GetInt("network.retry_jitter")) / 100,
+	})
+
+	if initFlag := rootCmd.PersistentFlags().Lookup("config-init"); initFlag != nil && initFlag.Changed {
+		writeDefaultConfig()
+	}
+}
+
+// configFilePath returns the config file in use, or where one would be read from
+func configFilePath() string {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used
+	}
+	if cfgFile != "" {
+		return cfgFile
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "inkwash", "config.yaml")
+}
+
+// writeDefaultConfig writes the current defaults to the config path if no file exists yet
+func writeDefaultConfig() {
+	path := configFilePath()
+	if _, err := os.Stat(path); err == nil {
+		fmt.Fprintf(os.Stderr, "Config file already exists: %s\n", path)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to create config directory: %v\n", err)
+		return
+	}
+
+	if err := viper.SafeWriteConfigAs(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write config file: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote default config to %s\n", path)
+}
+
+// warnConfigIssues prints config problems to stderr without stopping the command
+func warnConfigIssues() {
+	if configLoadErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config file: %v\n", configLoadErr)
+		return
+	}
+
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+
+	fileCfg, err := config.LoadFile(viper.ConfigFileUsed())
+	if err != nil {
+		return
+	}
+
+	for _, issue := range config.Validate(fileCfg) {
+		fmt.Fprintf(os.Stderr, "Warning: config %s\n", issue)
+	}
 }
 
 func getDefaultInstallPath() string {
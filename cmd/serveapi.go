@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/api"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var serveAPICmd = &cobra.Command{
+	Use:   "serve-api",
+	Short: "Serve the REST API for remote management",
+	Long: `Serves InkWash's JSON REST API under /api/v1/, for your own frontends or
+hosting panels: create, list and remove servers, start, stop, restart and
+update them, read their logs, convert mods into them and manage the build
+cache. Installs, updates and conversions run as jobs you can poll or cancel.
+See docs/API.md for every endpoint.
+
+It listens on api.listen (127.0.0.1:8080 by default). Every request needs the
+token from api.token or --token, sent as "Authorization: Bearer <token>";
+without one, a random token is generated and printed at startup. Put it
+behind a TLS reverse proxy before listening on a public address.`,
+	Example: `  inkwash serve-api
+  inkwash serve-api --listen :8080 --token "$INKWASH_API_TOKEN"`,
+	Args: cobra.NoArgs,
+	RunE: runServeAPI,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(serveAPICmd)
+
+	serveAPICmd.Flags().String("listen", "", "Address to listen on (default: api.listen)")
+	serveAPICmd.Flags().String("token", "", "Token requests must carry (default: api.token, or a random one)")
+}
+
+func runServeAPI(cmd *cobra.Command, args []string) error {
+	listen, token, generated, err := listenAndToken(cmd, "api")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	printServing("REST API on http://"+listen+api.Prefix, listen, token, generated, "api.token")
+	a := api.New(client, apiOptions(token))
+	return a.ListenAndServe(ctx, listen, a.Handler())
+}
+
+// listenAndToken reads --listen and --token, falling back to <section>.listen
+// and <section>.token in the config and generating a token if neither is set
+func listenAndToken(cmd *cobra.Command, section string) (listen, token string, generated bool, err error) {
+	listen, _ = cmd.Flags().GetString("listen")
+	if listen == "" {
+		listen = viper.GetString(section + ".listen")
+	}
+	if _, _, err := net.SplitHostPort(listen); err != nil {
+		return "", "", false, &usageError{fmt.Errorf("--listen must be host:port, got %q", listen)}
+	}
+
+	token, _ = cmd.Flags().GetString("token")
+	if token == "" {
+		token = viper.GetString(section + ".token")
+	}
+	if token == "" {
+		if token, err = api.GenerateToken(); err != nil {
+			return "", "", false, err
+		}
+		generated = true
+	}
+	return listen, token, generated, nil
+}
+
+// apiOptions fills the API's defaults for new servers and conversions from the config
+func apiOptions(token string) api.Options {
+	return api.Options{
+		Token:               token,
+		InstallPath:         viper.GetString("defaults.install_path"),
+		Port:                viper.GetInt("defaults.port"),
+		BindAddress:         viper.GetString("defaults.bind_address"),
+		TargetOS:            viper.GetString("defaults.target_os"),
+		ConvertLayout:       viper.GetString("convert.layout"),
+		ConvertTimeout:      time.Duration(viper.GetInt("convert.timeout")) * time.Second,
+		ConvertPollInterval: convertPollInterval(),
+		KeyVault:            vaultOptions("", false), // Migrating is left to the key commands
+	}
+}
+
+// printServing announces where a server listens and, if it was generated, its token
+func printServing(what, listen, token string, generated bool, tokenKey string) {
+	fmt.Printf("%s\n", ui.RenderSuccess(what))
+	if generated {
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Token:"), token)
+		fmt.Printf("  %s\n", ui.RenderMuted("Set "+tokenKey+" to keep the same token across restarts"))
+	}
+	host, _, _ := net.SplitHostPort(listen)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && !ip.IsLoopback()) {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(ui.SymbolWarning+" Listening beyond this machine over plain HTTP; the token can be read by anyone on the network path"))
+	}
+	fmt.Printf("  %s\n", ui.RenderMuted("Press Ctrl+C to stop"))
+}
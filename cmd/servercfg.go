@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/config"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <server-name> [convar]",
+	Short: "Show a server's server.cfg, or one convar's value",
+	Long: `Without a convar, lists the server's endpoints, the convars its server.cfg
+sets (secrets masked) and the resources it ensures. With one, prints just its
+value, unmasked, for use in scripts.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, cfg := loadServerConfig(args[0])
+
+		if len(args) == 2 {
+			value, ok := cfg.Convar(args[1])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: %s is not set in %s\n", args[1], cfg.Path)
+				os.Exit(exitNotFound)
+			}
+			fmt.Println(value)
+			return
+		}
+
+		printServerConfig(cfg)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <server-name> <convar> <value>",
+	Short: "Change a convar in a server's server.cfg",
+	Long: `Sets a convar in the server's server.cfg, rewriting the line that sets it
+or uncommenting a commented-out one (e.g. rcon_password) before adding a new
+line. Known convars are checked first, e.g. sv_maxclients must be 1-2048 and
+onesync must be on, off or legacy. Quote values with spaces:
+
+  inkwash config set myserver sv_hostname "My Server"
+
+--profile changes it in server.<profile>.cfg instead (see 'inkwash profile').
+A running server picks up the change when it's restarted.`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, value := args[1], args[2]
+		profile, _ := cmd.Flags().GetString("profile")
+		srv, cfg := loadServerConfig(args[0])
+		if profile != "" && profile != server.DefaultProfile {
+			err := server.CheckProfile(srv.Path, profile)
+			if err == nil {
+				cfg, err = servercfg.Load(server.ProfileConfigPath(srv.Path, profile))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitNotFound)
+			}
+		}
+
+		lock, err := server.LockServer(srv.Path, srv.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		defer lock.Release()
+
+		if err := cfg.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		// The registry keeps its own copy of the enforced game build for info and the dashboard
+		if strings.EqualFold(name, "sv_enforceGameBuild") && cfg.Path == filepath.Join(srv.Path, "server.cfg") {
+			if build, err := strconv.Atoi(value); err == nil {
+				srv.GameBuild = build
+				if reg, err := registry.NewRegistry(registry.GetRegistryPath()); err == nil {
+					reg.Update(*srv)
+				}
+			}
+		}
+
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Set %s in %s", name, cfg.Path)))
+		if !servercfg.IsKnownConvar(name) {
+			fmt.Printf("%s\n", ui.RenderWarning(ui.SymbolWarning+" "+name+" isn't a convar InkWash knows, so its value wasn't checked"))
+		}
+		if server.NewProcessManager().IsRunning(srv) {
+			fmt.Printf("%s\n", ui.RenderMuted(fmt.Sprintf("Restart the server for it to take effect: inkwash stop %s && inkwash start %s", srv.Name, srv.Name)))
+		}
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit <server-name>",
+	Short: "Edit a server's server.cfg in your editor",
+	Long: `Opens a copy of the server's server.cfg in $VISUAL or $EDITOR (notepad on
+Windows, nano or vi elsewhere). When the editor closes, the copy is checked:
+known convars must have valid values, endpoints must be valid addresses and a
+license key must be set. Only a copy that passes replaces server.cfg; if it
+doesn't, you can edit it again or leave server.cfg as it was.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !isTerminal() {
+			fmt.Fprintf(os.Stderr, "Error: edit needs an interactive terminal; use 'inkwash config set' instead\n")
+			os.Exit(exitUsage)
+		}
+
+		srv, cfg := loadServerConfig(args[0])
+		if err := editServerConfig(srv.Path, srv.Name, cfg.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configEditCmd)
+
+	configSetCmd.Flags().String("profile", "", "Set it in server.<profile>.cfg instead of server.cfg")
+}
+
+// loadServerConfig looks up a server and parses its server.cfg, exiting on failure
+func loadServerConfig(serverName string) (*types.Server, *servercfg.Config) {
+	srv, err := getServer(serverName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitNotFound)
+	}
+
+	cfg, err := servercfg.Load(filepath.Join(srv.Path, "server.cfg"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitNotFound)
+	}
+	return srv, cfg
+}
+
+// printServerConfig prints the structured view of a server.cfg
+func printServerConfig(cfg *servercfg.Config) {
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("SERVER.CFG"))
+	fmt.Printf("  %s %s\n", ui.RenderMuted("File:"), ui.RenderPath(cfg.Path))
+
+	fmt.Printf("\n  %s\n", ui.RenderAccent("Endpoints"))
+	endpoints := cfg.Endpoints()
+	if len(endpoints) == 0 {
+		fmt.Printf("    %s\n", ui.RenderMuted("none"))
+	}
+	for _, endpoint := range endpoints {
+		fmt.Printf("    %-4s %s\n", endpoint.Protocol, endpoint.Address)
+	}
+
+	fmt.Printf("\n  %s\n", ui.RenderAccent("Convars"))
+	for _, setting := range cfg.Settings() {
+		value := setting.Value
+		if config.IsSensitive(setting.Name) && value != "" {
+			value = "********"
+		}
+		fmt.Printf("    %-24s %s\n", setting.Name, value)
+	}
+
+	fmt.Printf("\n  %s\n", ui.RenderAccent("Resources"))
+	resources := cfg.StartedResources()
+	if len(resources) == 0 {
+		fmt.Printf("    %s\n", ui.RenderMuted("none"))
+	} else {
+		fmt.Printf("    %s\n", strings.Join(resources, ", "))
+	}
+
+	if issues := cfg.Validate(); len(issues) > 0 {
+		fmt.Printf("\n  %s\n", ui.RenderWarning(fmt.Sprintf("%s %d problem(s)", ui.SymbolWarning, len(issues))))
+		for _, issue := range issues {
+			fmt.Printf("    %s\n", issue)
+		}
+	}
+	fmt.Println()
+}
+
+// editServerConfig lets the user edit a copy of cfgPath and writes it back
+// once it validates
+func editServerConfig(serverPath, serverName, cfgPath string) error {
+	original, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read server.cfg: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", serverName+"-server-*.cfg")
+	if err != nil {
+		return fmt.Errorf("failed to create a copy to edit: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(original)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to create a copy to edit: %w", err)
+	}
+
+	var edited *servercfg.Config
+	for {
+		if err := runEditor(tmpPath); err != nil {
+			return fmt.Errorf("%w (your edits are in %s)", err, tmpPath)
+		}
+
+		if data, err := os.ReadFile(tmpPath); err == nil && bytes.Equal(data, original) {
+			os.Remove(tmpPath)
+			fmt.Println("No changes")
+			return nil
+		}
+
+		edited, err = servercfg.Load(tmpPath)
+		if err != nil {
+			return err
+		}
+
+		issues := edited.Validate()
+		if len(issues) == 0 {
+			break
+		}
+
+		fmt.Printf("\n%s\n", ui.RenderWarning(fmt.Sprintf("%s Found %d problem(s):", ui.SymbolWarning, len(issues))))
+		for _, issue := range issues {
+			fmt.Printf("  %s\n", issue)
+		}
+		fmt.Println()
+		if !confirm("Edit again? [y/N] ") {
+			fmt.Printf("server.cfg was left unchanged. Your edits are in %s\n", ui.RenderPath(tmpPath))
+			return nil
+		}
+	}
+
+	// The editor may have been open a while, so only lock for the write back
+	lock, err := server.LockServer(serverPath, serverName)
+	if err != nil {
+		return fmt.Errorf("%w (your edits are in %s)", err, tmpPath)
+	}
+	defer lock.Release()
+
+	if current, err := os.ReadFile(cfgPath); err != nil || !bytes.Equal(current, original) {
+		return fmt.Errorf("server.cfg changed while you were editing it; your edits are in %s", tmpPath)
+	}
+
+	edited.Path = cfgPath
+	if err := edited.Save(); err != nil {
+		return fmt.Errorf("%w (your edits are in %s)", err, tmpPath)
+	}
+	os.Remove(tmpPath)
+
+	fmt.Printf("%s\n", ui.RenderSuccess("Saved "+cfgPath))
+	return nil
+}
+
+// runEditor opens path in the user's editor and waits for it to close
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		switch {
+		case runtime.GOOS == "windows":
+			editor = "notepad"
+		case hasCommand("nano"):
+			editor = "nano"
+		default:
+			editor = "vi"
+		}
+	}
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// hasCommand reports whether name is on the PATH
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
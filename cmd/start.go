@@ -1,59 +1,122 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/VexoaXYZ/inkwash/internal/registry"
-	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var startCmd = &cobra.Command{
 	Use:   "start <server-name>",
 	Short: "Start a FiveM server",
-	Long:  `Start a FiveM server by name.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Start a FiveM server by name and wait until it's ready: until its log says
+it's listening or its players.json endpoint answers. If it exits or logs a
+known problem first (a rejected license key, a port already in use), the
+reason and the log lines leading up to it are shown and start fails.
+
+The wait is up to start.wait_timeout seconds (120 by default, or
+--wait-timeout); Ctrl+C stops waiting but leaves the server starting. Use
+--wait=false to return as soon as the process is launched.
+
+--profile execs server.<profile>.cfg instead of server.cfg (see 'inkwash
+profile'). The server keeps starting with that profile, also when the daemon
+or 'inkwash watch' restarts it, until it's started with --profile default.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
+		wait, _ := cmd.Flags().GetBool("wait")
 
-		// Load registry
-		reg, err := registry.NewRegistry(registry.GetRegistryPath())
+		client, err := newClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
-		// Get server
-		srv, err := reg.Get(serverName)
+		srv, err := client.Get(serverName)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", serverName)
-			os.Exit(1)
+			os.Exit(exitNotFound)
 		}
-
-		// Create process manager
-		pm := server.NewProcessManager()
-
-		// Check if already running
-		if pm.IsRunning(srv) {
+		if client.IsRunning(srv) {
 			fmt.Printf("Server '%s' is already running (PID: %d)\n", serverName, srv.PID)
 			return
 		}
 
-		// Start server
-		fmt.Printf("Starting server '%s'...\n", serverName)
+		if cmd.Flags().Changed("profile") {
+			profile, _ := cmd.Flags().GetString("profile")
+			srv, err = client.SetProfile(cmd.Context(), serverName, profile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+
+		if srv.Profile != "" {
+			fmt.Printf("Starting server '%s' with the %s profile...\n", serverName, srv.Profile)
+		} else {
+			fmt.Printf("Starting server '%s'...\n", serverName)
+		}
+
+		if wait {
+			timeout, _ := cmd.Flags().GetInt("wait-timeout")
+			if timeout <= 0 {
+				timeout = viper.GetInt("start.wait_timeout")
+			}
 
-		if err := pm.Start(srv); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to start server: %v\n", err)
-			os.Exit(1)
+			// Ctrl+C ends the wait; the server carries on starting
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			srv, err = client.StartAndWait(ctx, serverName, time.Duration(timeout)*time.Second)
+			stop()
+		} else {
+			srv, err = client.Start(cmd.Context(), serverName)
 		}
 
-		// Update registry
-		if err := reg.Update(*srv); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to update registry: %v\n", err)
+		var startupErr *inkwash.StartupError
+		switch {
+		case errors.Is(err, inkwash.ErrServerRunning):
+			// Started by someone else in the meantime
+			fmt.Printf("Server '%s' is already running (PID: %d)\n", serverName, srv.PID)
+			return
+		case errors.As(err, &startupErr):
+			fmt.Fprintf(os.Stderr, "✗ Server '%s' failed to start: %s\n", serverName, startupErr.Reason)
+			if len(startupErr.Excerpt) > 0 {
+				fmt.Fprintf(os.Stderr, "\nLast lines of the log:\n")
+				for _, line := range startupErr.Excerpt {
+					fmt.Fprintf(os.Stderr, "  %s\n", line)
+				}
+			}
+			if !startupErr.Exited {
+				fmt.Fprintf(os.Stderr, "\nIt's still running (PID: %d); stop it with 'inkwash stop %s'\n", srv.PID, serverName)
+			}
+			os.Exit(exitCode(err))
+		case errors.Is(err, inkwash.ErrStartupTimeout):
+			fmt.Fprintf(os.Stderr, "Warning: server '%s' (PID: %d) didn't report ready in time; it may still come up\n", serverName, srv.PID)
+			fmt.Fprintf(os.Stderr, "Check on it with 'inkwash logs %s --follow'\n", serverName)
+			os.Exit(exitCode(err))
+		case errors.Is(err, context.Canceled) && srv != nil:
+			fmt.Printf("\nStopped waiting; server '%s' is still starting (PID: %d)\n", serverName, srv.PID)
+			os.Exit(exitInterrupted)
+		case err != nil && srv == nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		case err != nil:
+			// Started, but the PID wasn't saved
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
 
-		fmt.Printf("✓ Server '%s' started successfully (PID: %d)\n", serverName, srv.PID)
+		if wait {
+			fmt.Printf("✓ Server '%s' is up and ready (PID: %d)\n", serverName, srv.PID)
+		} else {
+			fmt.Printf("✓ Server '%s' started successfully (PID: %d)\n", serverName, srv.PID)
+		}
 		fmt.Printf("\nView logs:\n")
 		fmt.Printf("  inkwash logs %s\n", serverName)
 	},
@@ -61,4 +124,8 @@ var startCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(startCmd)
+
+	startCmd.Flags().Bool("wait", true, "Wait until the server is ready, reporting why if it fails to start")
+	startCmd.Flags().Int("wait-timeout", 0, "Seconds to wait for the server to be ready (default start.wait_timeout)")
+	startCmd.Flags().String("profile", "", "Start with server.<profile>.cfg from now on (\"default\" for server.cfg)")
 }
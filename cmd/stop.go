@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
-	"github.com/VexoaXYZ/inkwash/internal/registry"
-	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
 	"github.com/spf13/cobra"
 )
 
@@ -17,40 +17,33 @@ var stopCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
-		// Load registry
-		reg, err := registry.NewRegistry(registry.GetRegistryPath())
+		client, err := newClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to load registry: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
-		// Get server
-		srv, err := reg.Get(serverName)
+		srv, err := client.Get(serverName)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", serverName)
-			os.Exit(1)
+			os.Exit(exitNotFound)
 		}
-
-		// Create process manager
-		pm := server.NewProcessManager()
-
-		// Check if running
-		if !pm.IsRunning(srv) {
+		if !client.IsRunning(srv) {
 			fmt.Printf("Server '%s' is not running\n", serverName)
 			return
 		}
 
-		// Stop server
 		fmt.Printf("Stopping server '%s' (PID: %d)...\n", serverName, srv.PID)
 
-		if err := pm.Stop(srv); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to stop server: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Update registry
-		if err := reg.Update(*srv); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to update registry: %v\n", err)
+		srv, err = client.Stop(cmd.Context(), serverName)
+		switch {
+		case errors.Is(err, inkwash.ErrServerNotRunning):
+			// Exited on its own in the meantime
+		case err != nil && srv == nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
 
 		fmt.Printf("✓ Server '%s' stopped successfully\n", serverName)
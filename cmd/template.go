@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/template"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Save a server's setup as a template and apply it to others",
+	Long: `Templates are JSON files describing a server setup: the resources it runs
+(and where to download them), the convars it sets and what the host needs.
+They're kept in the templates/ folder under the config directory.
+
+Two templates are built in: esx installs ESX Legacy and qbcore installs
+QBCore, each with oxmysql, their core resources and the SQL their database
+needs. A saved template with the same name takes their place.`,
+}
+
+var templateExportCmd = &cobra.Command{
+	Use:   "export <server-name> <template-name>",
+	Short: "Save a server's resources and convars as a template",
+	Long: `Reads the server's server.cfg and resources/ folder and saves them as a
+template. Resources keep the order server.cfg starts them in; those it doesn't
+start are exported with "no_ensure". Resources installed with 'inkwash resource
+add' keep their repository, so applying the template downloads them; others
+must already be on the server it's applied to (like the default cfx-server-data
+resources every server has).
+
+Secrets such as sv_licenseKey and rcon_password are never exported.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTemplateExport,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var templateApplyCmd = &cobra.Command{
+	Use:   "apply <server-name> <template-name>",
+	Short: "Install a template's resources and convars on a server",
+	Long: `Checks the template's requirements (RAM, CPUs, free ports) against this
+machine, downloads the resources the server doesn't have yet, sets the
+template's convars in server.cfg and adds ensure lines so each resource starts
+after the ones its manifest depends on. Nothing is changed if a requirement
+isn't met or a resource has nowhere to be downloaded from, and server.cfg is
+only touched once every dependency has been found.
+
+If the template has a database, its SQL files are gathered into the server
+folder. With --db-admin the database is also created and the SQL run, as
+'inkwash db setup' does, and the connection string is written to server.cfg;
+without it, the 'inkwash db setup' command to run is printed.
+
+The template can also be given as a path to a .json file. A running server
+picks the changes up when it's restarted.
+
+Examples:
+  inkwash template apply myserver qbcore --db-admin root@127.0.0.1
+  inkwash template apply myserver roleplay
+  inkwash template apply myserver ./templates/roleplay.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTemplateApply,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved templates",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplateList,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+
+	templateCmd.AddCommand(templateExportCmd)
+	templateCmd.AddCommand(templateApplyCmd)
+	templateCmd.AddCommand(templateListCmd)
+
+	templateExportCmd.Flags().Bool("force", false, "Replace a saved template with the same name")
+	templateExportCmd.Flags().String("description", "", "Description saved with the template")
+
+	templateApplyCmd.Flags().Bool("force", false, "Download resources again even if the server already has them")
+	templateApplyCmd.Flags().String("db-admin", "", "Also set up the template's database with this MySQL/MariaDB admin, as user[:password]@host[:port]")
+}
+
+func runTemplateExport(cmd *cobra.Command, args []string) error {
+	serverName, name := args[0], args[1]
+	force, _ := cmd.Flags().GetBool("force")
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	t, omitted, err := client.ExportTemplate(cmd.Context(), serverName, name)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("description") {
+		t.Description, _ = cmd.Flags().GetString("description")
+	}
+
+	path, err := inkwash.SaveTemplate(t, force)
+	if err != nil {
+		return err
+	}
+
+	var withoutSource []string
+	for _, r := range t.Resources {
+		if r.Repository == "" {
+			withoutSource = append(withoutSource, r.Name)
+		}
+	}
+
+	fmt.Printf("%s %s\n", ui.RenderSuccess("Saved template '"+name+"'"), ui.RenderMuted("("+path+")"))
+	fmt.Printf("  %s %d resource(s), %d convar(s)\n", ui.RenderMuted("Contains"), len(t.Resources), len(t.Convars))
+	if len(omitted) > 0 {
+		fmt.Printf("  %s %s\n", ui.RenderMuted("Left out:"), strings.Join(omitted, ", "))
+	}
+	if len(withoutSource) > 0 {
+		fmt.Printf("  %s\n", ui.RenderWarning(fmt.Sprintf("%s %d resource(s) have no recorded repository, so servers it's applied to must already have them:", ui.SymbolWarning, len(withoutSource))))
+		fmt.Printf("    %s\n", strings.Join(withoutSource, ", "))
+	}
+	return nil
+}
+
+func runTemplateApply(cmd *cobra.Command, args []string) error {
+	serverName := args[0]
+	force, _ := cmd.Flags().GetBool("force")
+
+	t, err := inkwash.FindTemplate(args[1])
+	if err != nil {
+		return err
+	}
+
+	var dbOpts *inkwash.DatabaseOptions
+	if admin, _ := cmd.Flags().GetString("db-admin"); admin != "" {
+		opts, err := database.ParseAdmin(admin)
+		if err != nil {
+			return &usageError{err}
+		}
+		if _, err := database.FindClient(); err != nil {
+			return err
+		}
+		// Local installs often let root in without a password, so an empty answer is fine
+		if opts.AdminPassword == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+			if opts.AdminPassword, err = readAdminPassword(opts); err != nil {
+				return err
+			}
+		}
+		dbOpts = &opts
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+	result, err := client.ApplyTemplate(ctx, serverName, t, inkwash.TemplateOptions{
+		Force: force,
+		OnStep: func(step string) {
+			fmt.Printf("%s...\n", step)
+		},
+		Database: dbOpts,
+	})
+	if result != nil && len(result.Installed) > 0 {
+		fmt.Printf("%s %s\n", ui.RenderSuccess("Installed"), strings.Join(result.Installed, ", "))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(result.Convars) > 0 {
+		fmt.Printf("%s %s\n", ui.RenderSuccess("Set"), strings.Join(result.Convars, ", "))
+	}
+	if len(result.Ensured) > 0 {
+		fmt.Printf("%s %s\n", ui.RenderSuccess("Added ensure lines for"), strings.Join(result.Ensured, ", "))
+	}
+	if result.Database != nil {
+		fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Set up database '%s' (user %s) and wrote %s", result.Database.Database, result.Database.User, database.ConnectionConvar)))
+	}
+	fmt.Printf("%s\n", ui.RenderSuccess(fmt.Sprintf("Applied template '%s' to %s", t.Name, serverName)))
+	if t.Database != nil && result.Database == nil {
+		fmt.Printf("%s\n", ui.RenderWarning(fmt.Sprintf("%s The template needs a database; set it up with:", ui.SymbolWarning)))
+		fmt.Printf("  %s\n", database.SetupCommand(serverName, result.SQLFiles))
+	}
+
+	srv, err := client.Get(serverName)
+	if err == nil && client.IsRunning(srv) {
+		fmt.Printf("%s\n", ui.RenderMuted("Restart the server to pick up the changes"))
+	}
+	return nil
+}
+
+// templateView is a saved template as `template list` shows it
+type templateView struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Resources   int    `json:"resources"`
+	Convars     int    `json:"convars"`
+	Builtin     bool   `json:"builtin,omitempty"`
+}
+
+func runTemplateList(cmd *cobra.Command, args []string) error {
+	templates, problems := inkwash.Templates()
+	for _, err := range problems {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.RenderWarning(fmt.Sprintf("%s %v", ui.SymbolWarning, err)))
+	}
+
+	views := make([]templateView, 0, len(templates))
+	for _, t := range templates {
+		views = append(views, templateView{
+			Name:        t.Name,
+			Description: t.Description,
+			Resources:   len(t.Resources),
+			Convars:     len(t.Convars),
+			Builtin:     inkwash.IsBuiltinTemplate(t.Name),
+		})
+	}
+
+	return writeOutput(cmd, views, func() {
+		fmt.Printf("\n%s\n\n", ui.RenderHeader("TEMPLATES"))
+		if len(views) == 0 {
+			fmt.Printf("  %s\n\n", ui.RenderMuted("No templates yet. Save one with 'inkwash template export <server-name> <template-name>'."))
+			return
+		}
+		for _, v := range views {
+			summary := fmt.Sprintf("%d resource(s), %d convar(s)", v.Resources, v.Convars)
+			if v.Builtin {
+				summary += ", built in"
+			}
+			fmt.Printf("  %s %s\n", ui.RenderAccent(fmt.Sprintf("%-20s", v.Name)), ui.RenderMuted(summary))
+			if v.Description != "" {
+				fmt.Printf("  %-20s %s\n", "", v.Description)
+			}
+		}
+		fmt.Printf("\n  %s %s\n\n", ui.RenderMuted("Folder:"), ui.RenderPath(template.Dir()))
+	})
+}
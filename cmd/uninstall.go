@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove InkWash data from this machine",
+	Long: `Removes InkWash's config, registry, key vault, build cache and data directories.
+
+Managed servers are kept unless --purge-servers is given. Refuses to run while
+any server is running. The inkwash binary itself must be deleted manually.`,
+	Args: cobra.NoArgs,
+	RunE: runUninstall,
+}
+
+func init() {
+	rootCmd.AddCommand(uninstallCmd)
+
+	uninstallCmd.Flags().Bool("purge-servers", false, "Also delete every managed server folder")
+	uninstallCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	purgeServers, _ := cmd.Flags().GetBool("purge-servers")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	// Load registry
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	servers := reg.List()
+
+	// Refuse while anything is running
+	pm := server.NewProcessManager()
+	for _, srv := range servers {
+		if pm.IsRunning(&srv) {
+			return fmt.Errorf("server '%s' is running; stop it first with: inkwash stop %s", srv.Name, srv.Name)
+		}
+		if server.IsLocked(srv.Path) {
+			return fmt.Errorf("server '%s' is being modified by another inkwash command; try again when it finishes", srv.Name)
+		}
+	}
+
+	dataDirs := []struct {
+		label string
+		path  string
+	}{
+		{"Config, registry and key vault", registry.GetDefaultConfigPath()},
+		{"Build cache", registry.GetDefaultCachePath()},
+		{"Data", registry.GetDefaultDataPath()},
+	}
+
+	// Server folders inside the data directories must survive unless purging
+	var keep []string
+	if !purgeServers {
+		for _, srv := range servers {
+			keep = append(keep, filepath.Clean(srv.Path))
+		}
+	}
+
+	fmt.Printf("\n%s\n\n", ui.RenderHeader("UNINSTALL"))
+	fmt.Println("  The following will be removed:")
+	for _, dir := range dataDirs {
+		if _, err := os.Stat(dir.path); os.IsNotExist(err) {
+			continue
+		}
+		fmt.Printf("    %-32s %s\n", dir.label, ui.RenderPath(dir.path))
+	}
+	if purgeServers {
+		for _, srv := range servers {
+			fmt.Printf("    %-32s %s\n", "Server '"+srv.Name+"'", ui.RenderPath(srv.Path))
+		}
+	} else if len(servers) > 0 {
+		fmt.Printf("\n  %s\n", ui.RenderMuted(fmt.Sprintf("%d server folder(s) will be kept (use --purge-servers to delete them)", len(servers))))
+	}
+	fmt.Println()
+
+	if !yes && !confirm("Continue? [y/N]: ") {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	if purgeServers {
+		for _, srv := range servers {
+			if err := os.RemoveAll(srv.Path); err != nil {
+				return fmt.Errorf("failed to remove server '%s': %w", srv.Name, err)
+			}
+		}
+	}
+
+	for _, dir := range dataDirs {
+		if err := removeAllExcept(dir.path, keep); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir.path, err)
+		}
+	}
+
+	fmt.Printf("%s\n", ui.RenderSuccess("InkWash data removed"))
+	if exe, err := os.Executable(); err == nil {
+		fmt.Printf("\nTo finish, delete the binary:\n  %s\n\n", exe)
+	}
+
+	return nil
+}
+
+// stdinReader is shared so consecutive prompts don't lose buffered input
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := stdinReader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// removeAllExcept removes root, but preserves any path in keep (and the
+// directories leading to it)
+func removeAllExcept(root string, keep []string) error {
+	root = filepath.Clean(root)
+
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+
+	var kept bool
+	for _, k := range keep {
+		// root is (or is inside) a server folder
+		if k == root || isWithin(k, root) {
+			return nil
+		}
+		if isWithin(root, k) {
+			kept = true
+		}
+	}
+
+	if !kept {
+		return os.RemoveAll(root)
+	}
+
+	// Something to keep lives below root: only remove the other entries
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := removeAllExcept(filepath.Join(root, entry.Name()), keep); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isWithin reports whether path is inside dir
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var updateServerCmd = &cobra.Command{
+	Use:   "update-server <server-name>",
+	Short: "Upgrade a server's FXServer build in place",
+	Long: `Installs another FXServer build into a stopped server's bin/ folder, using the
+build cache like 'inkwash create'. The build in metadata.json is compared first,
+so nothing is downloaded if the server already has it.
+
+--build takes a build number or recommended, optional or latest. Without it,
+the server moves to the newest build on its channel: the one it was created
+from, or recommended. --channel switches the channel (recommended, optional,
+latest, or pinned to stay on one build) and updates along it. Updating to a
+build number pins the server; a pinned server is only updated when given a
+build or channel.
+
+The replaced binaries are kept next to bin/ as bin.<build>; only the most recent
+backup is kept. To go back, update to the old build again:
+
+  inkwash update-server myserver --build 16000`,
+	Example: `  inkwash update-server myserver
+  inkwash update-server myserver --channel optional
+  inkwash update-server myserver --channel pinned`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeServerNames,
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+
+		buildFlag, _ := cmd.Flags().GetString("build")
+		channelFlag, _ := cmd.Flags().GetString("channel")
+		artifactURL, _ := cmd.Flags().GetString("artifact-url")
+		force, _ := cmd.Flags().GetBool("force")
+		forceRedownload, _ := cmd.Flags().GetBool("force-redownload")
+		verify, _ := cmd.Flags().GetBool("verify")
+		sha256, _ := cmd.Flags().GetString("sha256")
+		progressMode, _ := cmd.Flags().GetString("progress")
+
+		onProgress, err := progressPrinter(progressMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		quiet := progressMode == progressJSON
+
+		if sum, err := hex.DecodeString(sha256); sha256 != "" && (err != nil || len(sum) != 32) {
+			fmt.Fprintf(os.Stderr, "Error: --sha256 must be 64 hex characters\n")
+			os.Exit(exitUsage)
+		}
+
+		opts := inkwash.UpdateOptions{
+			ArtifactURL: artifactURL,
+			Force:       force,
+
+			ForceRedownload: forceRedownload,
+			Verify:          verify,
+			SHA256:          sha256,
+		}
+		if artifactURL != "" {
+			if cmd.Flags().Changed("build") {
+				fmt.Fprintf(os.Stderr, "Error: --build and --artifact-url can't be used together\n")
+				os.Exit(exitUsage)
+			}
+			if _, _, err := validation.ParseArtifactURL(artifactURL); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		} else if buildFlag != "" {
+			opts.Build, opts.BuildChannel, err = validation.ParseBuild(buildFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+		if channelFlag != "" {
+			opts.Channel, err = validation.ParseChannel(channelFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			if opts.Channel != types.BuildChannelPinned && (buildFlag != "" || artifactURL != "") {
+				fmt.Fprintf(os.Stderr, "Error: --channel %s updates to that channel's build, so it can't be used with --build or --artifact-url\n", opts.Channel)
+				os.Exit(exitUsage)
+			}
+		}
+
+		client, err := newClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		srv, err := client.Get(serverName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Server '%s' not found\n", serverName)
+			os.Exit(exitNotFound)
+		}
+		if client.IsRunning(srv) {
+			fmt.Fprintf(os.Stderr, "Error: Server '%s' is running, stop it first: inkwash stop %s\n", serverName, serverName)
+			os.Exit(exitConflict)
+		}
+
+		if !quiet {
+			fmt.Printf("Updating server '%s'...\n\n", serverName)
+		}
+
+		// Ctrl+C leaves the current build in place
+		ctx, stop := interruptContext(cmd.Context())
+		result, err := client.UpdateBuild(ctx, serverName, opts, onProgress)
+		cancelled := ctx.Err() != nil
+		stop()
+
+		switch {
+		case errors.Is(err, inkwash.ErrUpToDate):
+			if !quiet {
+				fmt.Printf("\nServer '%s' is already on build %d (use --force to reinstall it)\n", serverName, result.From)
+				if opts.Channel != "" {
+					fmt.Printf("%s\n", ui.RenderMuted(channelNote(result)))
+				}
+			}
+			return
+		case err != nil && cancelled:
+			fmt.Fprintf(os.Stderr, "Update cancelled, the server's build is unchanged.\n")
+			os.Exit(exitInterrupted)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		if quiet {
+			return
+		}
+		fmt.Printf("\n%s\n", ui.RenderSuccess(fmt.Sprintf("Server '%s' updated from %s to %s", serverName, buildName(result.From), buildName(result.To))))
+		if result.BackupPath != "" {
+			fmt.Printf("  %s %s\n", ui.RenderMuted("Previous binaries:"), ui.RenderPath(result.BackupPath))
+		}
+		fmt.Printf("  %s\n", ui.RenderMuted(channelNote(result)))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateServerCmd)
+
+	updateServerCmd.Flags().StringP("build", "b", "", "FXServer build number, or recommended, optional or latest (default: the server's channel)")
+	updateServerCmd.Flags().String("channel", "", "Build channel the server follows from now on: recommended, optional, latest or pinned")
+	updateServerCmd.Flags().String("artifact-url", "", "Download FXServer from this archive URL instead of the artifacts page")
+	updateServerCmd.Flags().Bool("force", false, "Reinstall the build even if the server already has it")
+	updateServerCmd.Flags().Bool("force-redownload", false, "Download the FXServer build even if it's cached, replacing the cached copy")
+	updateServerCmd.Flags().Bool("verify", false, "Test the downloaded archive and download it again if it's damaged")
+	updateServerCmd.Flags().String("sha256", "", "Expected SHA256 of the downloaded archive (implies --verify)")
+	updateServerCmd.Flags().String("progress", progressText, "Progress output: text, or json for one JSON object per line")
+
+	updateServerCmd.RegisterFlagCompletionFunc("build", completeBuilds)
+	updateServerCmd.RegisterFlagCompletionFunc("channel", cobra.FixedCompletions(
+		[]string{types.BuildChannelRecommended, types.BuildChannelOptional, types.BuildChannelLatest, types.BuildChannelPinned},
+		cobra.ShellCompDirectiveNoFileComp))
+	updateServerCmd.RegisterFlagCompletionFunc("progress", cobra.FixedCompletions(
+		[]string{progressText, progressJSON}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// buildName describes a build number for messages, where 0 means unknown
+func buildName(build int) string {
+	if build == 0 {
+		return "an unknown build"
+	}
+	return fmt.Sprintf("build %d", build)
+}
+
+// channelNote says which channel an updated server follows
+func channelNote(result *inkwash.UpdateResult) string {
+	if result.Channel == types.BuildChannelPinned {
+		return fmt.Sprintf("Pinned to %s", buildName(result.To))
+	}
+	return fmt.Sprintf("Following the %s channel", result.Channel)
+}
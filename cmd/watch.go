@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/daemon"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <server-name>",
+	Short: "Check a server's health and restart it when it stops responding",
+	Long: `Runs in the foreground and checks a running server every 30 seconds: it must
+accept a TCP connection on its port and answer a players.json request. After
+3 failed checks in a row the server is restarted and the incident is recorded
+in its metadata.json. Checks only start counting 120 seconds after a start,
+so resources have time to load.
+
+Each server can change this in the "health" block of its metadata.json, with
+"interval", "timeout", "failures" and "startup_grace" in seconds or counts,
+"no_tcp" or "no_players" to skip a check, and "log_timeout" to also fail when
+the log hasn't been written to for that many seconds.
+
+A stopped server is waited for, not started. Like the daemon, watch gives up
+after daemon.max_restarts restarts within daemon.restart_window seconds.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	reg, err := registry.NewRegistry(registry.GetRegistryPath())
+	if err != nil {
+		return fmt.Errorf("failed to load registry: %w", err)
+	}
+	if !reg.Exists(args[0]) {
+		return fmt.Errorf("%w: '%s'", inkwash.ErrServerNotFound, args[0])
+	}
+
+	opts := daemon.WatchOptions{
+		MaxRestarts:   viper.GetInt("daemon.max_restarts"),
+		RestartWindow: time.Duration(viper.GetInt("daemon.restart_window")) * time.Second,
+	}
+	logf := func(format string, args ...interface{}) {
+		fmt.Printf("%s %s\n", ui.RenderMuted(time.Now().Format("2006-01-02 15:04:05")), fmt.Sprintf(format, args...))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := daemon.Watch(ctx, reg, args[0], opts, logf); err != nil {
+		return err
+	}
+	logf("Stopped watching; the server keeps running")
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/VexoaXYZ/inkwash/internal/api"
+	"github.com/VexoaXYZ/inkwash/internal/web"
+	"github.com/spf13/cobra"
+)
+
+var webCmd = &cobra.Command{
+	Use:   "web",
+	Short: "Serve a web panel for managing servers",
+	Long: `Serves a small web panel for listing servers, starting, stopping and
+restarting them, reading their logs and watching CPU, RAM and player counts.
+The REST API it runs on is served alongside it under /api/v1/, as with
+'inkwash serve-api'.
+
+It listens on web.listen (127.0.0.1:8484 by default), so only this machine can
+reach it. Sign in with the token from web.token or --token; without one, a
+random token is generated and printed at startup. Put the panel behind a TLS
+reverse proxy before listening on a public address.`,
+	Args: cobra.NoArgs,
+	RunE: runWeb,
+	// Problems are already listed, usage would bury them
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(webCmd)
+
+	webCmd.Flags().String("listen", "", "Address to listen on (default: web.listen)")
+	webCmd.Flags().String("token", "", "Token to sign in with (default: web.token, or a random one)")
+}
+
+func runWeb(cmd *cobra.Command, args []string) error {
+	listen, token, generated, err := listenAndToken(cmd, "web")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext(cmd.Context())
+	defer stop()
+
+	printServing("Web panel on http://"+listen, listen, token, generated, "web.token")
+	return web.New(api.New(client, apiOptions(token))).ListenAndServe(ctx, listen)
+}
@@ -0,0 +1,241 @@
+// Package api implements InkWash's JSON REST API: servers (create, list,
+// remove, start, stop, update, logs), convert and install jobs, and the
+// FXServer build cache. `inkwash serve-api` serves it on its own and
+// `inkwash web` serves it behind the web panel; docs/API.md describes it.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+)
+
+// Prefix is the path every route lives under
+const Prefix = "/api/v1"
+
+// metricsInterval is how often running servers are sampled
+const metricsInterval = 2 * time.Second
+
+// maxBodySize caps request bodies; none of them need more than a few fields
+const maxBodySize = 64 * 1024
+
+// shutdownTimeout is how long requests in flight get to finish on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Options configures an API. Requests that leave a setting out get these,
+// which the CLI fills from its config.
+type Options struct {
+	Token string // Required as "Authorization: Bearer <token>" on every request
+
+	InstallPath string // Folder new servers are created in
+	Port        int
+	BindAddress string
+	TargetOS    string
+
+	ConvertLayout       string
+	ConvertTimeout      time.Duration
+	ConvertPollInterval time.Duration
+
+	KeyVault cache.VaultOptions // How the license key vault is unlocked for key_id
+}
+
+// API serves the REST API for the servers a Client manages
+type API struct {
+	client  *inkwash.Client
+	opts    Options
+	metrics *server.MetricsCollector
+	jobs    *jobQueue
+
+	// Installs, updates and cache changes share the build cache, which
+	// isn't safe for concurrent use, so they take turns
+	buildMu sync.Mutex
+}
+
+// New returns an API that accepts requests carrying opts.Token
+func New(client *inkwash.Client, opts Options) *API {
+	return &API{
+		client:  client,
+		opts:    opts,
+		metrics: server.NewMetricsCollector(metricsInterval),
+		jobs:    newJobQueue(),
+	}
+}
+
+// GenerateToken returns a random token for when none is configured
+func GenerateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Handler returns the API's routes, all under Prefix and all requiring the token
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+Prefix+"/servers", a.handleListServers)
+	mux.HandleFunc("POST "+Prefix+"/servers", a.handleCreateServer)
+	mux.HandleFunc("GET "+Prefix+"/servers/{name}", a.handleGetServer)
+	mux.HandleFunc("DELETE "+Prefix+"/servers/{name}", a.handleRemoveServer)
+	mux.HandleFunc("POST "+Prefix+"/servers/{name}/start", a.handleStart)
+	mux.HandleFunc("POST "+Prefix+"/servers/{name}/stop", a.handleStop)
+	mux.HandleFunc("POST "+Prefix+"/servers/{name}/restart", a.handleRestart)
+	mux.HandleFunc("POST "+Prefix+"/servers/{name}/update", a.handleUpdateServer)
+	mux.HandleFunc("POST "+Prefix+"/servers/{name}/convert", a.handleConvert)
+	mux.HandleFunc("GET "+Prefix+"/servers/{name}/logs", a.handleLogs)
+
+	mux.HandleFunc("GET "+Prefix+"/jobs", a.handleListJobs)
+	mux.HandleFunc("GET "+Prefix+"/jobs/{id}", a.handleGetJob)
+	mux.HandleFunc("DELETE "+Prefix+"/jobs/{id}", a.handleCancelJob)
+
+	mux.HandleFunc("GET "+Prefix+"/cache", a.handleListCache)
+	mux.HandleFunc("DELETE "+Prefix+"/cache", a.handleClearCache)
+	mux.HandleFunc("DELETE "+Prefix+"/cache/{build}", a.handleRemoveCachedBuild)
+
+	mux.HandleFunc(Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint: %s %s", r.Method, r.URL.Path))
+	})
+
+	return a.authenticate(mux)
+}
+
+// ListenAndServe serves handler on address until ctx is cancelled, collecting
+// metrics for running servers meanwhile. Jobs still running are cancelled.
+// handler is usually Handler(), or a mux that includes it.
+func (a *API) ListenAndServe(ctx context.Context, address string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	a.metrics.Start()
+	defer a.metrics.Stop()
+
+	runCtx, stopRunning := context.WithCancel(ctx)
+	defer stopRunning()
+	go a.trackRunning(runCtx)
+	a.jobs.setContext(runCtx)
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// authenticate rejects requests without the bearer token. Accepted requests
+// see the registry as it is on disk, so servers created, started or stopped
+// from the CLI meanwhile aren't acted on with stale PIDs.
+func (a *API) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="inkwash"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+			return
+		}
+		a.client.Reload()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trackRunning keeps the metrics collector following the running servers,
+// including ones started outside the API
+func (a *API) trackRunning(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		a.client.Reload()
+		for _, srv := range a.client.List() {
+			if !a.client.IsRunning(&srv) {
+				a.metrics.Pause(srv.Name)
+				continue
+			}
+			if snapshot, ok := a.metrics.Snapshot(srv.Name); !ok || snapshot.Paused || snapshot.PID != srv.PID {
+				a.metrics.Track(&srv)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// badRequest marks an error caused by what the client sent
+type badRequest struct{ err error }
+
+func (e *badRequest) Error() string { return e.err.Error() }
+func (e *badRequest) Unwrap() error { return e.err }
+
+// decodeBody reads a JSON request body into v, rejecting unknown fields so
+// typos don't silently fall back to defaults
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return &badRequest{fmt.Errorf("invalid request body: %w", err)}
+	}
+	return nil
+}
+
+// statusFor maps an error to an HTTP status
+func statusFor(err error) int {
+	var bad *badRequest
+	switch {
+	case errors.As(err, &bad):
+		return http.StatusBadRequest
+	case errors.Is(err, inkwash.ErrServerNotFound), errors.Is(err, inkwash.ErrNotCached), errors.Is(err, errJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, inkwash.ErrNameTaken), errors.Is(err, inkwash.ErrServerExists),
+		errors.Is(err, inkwash.ErrServerRunning), errors.Is(err, inkwash.ErrServerNotRunning),
+		errors.Is(err, inkwash.ErrServerBusy), errors.Is(err, errJobFinished):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes {"error": "..."}, with the status from statusFor when status is 0
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status == 0 {
+		status = statusFor(err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
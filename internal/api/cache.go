@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CachedBuild is a cached FXServer build as the API returns it
+type CachedBuild struct {
+	Number     int       `json:"number"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Downloaded time.Time `json:"downloaded"`
+	LastUsed   time.Time `json:"last_used"`
+}
+
+func (a *API) handleListCache(w http.ResponseWriter, r *http.Request) {
+	a.buildMu.Lock()
+	builds, err := a.client.CachedBuilds()
+	a.buildMu.Unlock()
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	views := make([]CachedBuild, 0, len(builds))
+	for _, b := range builds {
+		views = append(views, CachedBuild{Number: b.Number, SizeBytes: b.Size, Downloaded: b.Downloaded, LastUsed: b.LastUsed})
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (a *API) handleClearCache(w http.ResponseWriter, r *http.Request) {
+	// Waits for installs and updates in progress, which may be reading from it
+	a.buildMu.Lock()
+	defer a.buildMu.Unlock()
+
+	if err := a.client.ClearCache(); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleRemoveCachedBuild(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(r.PathValue("build"))
+	if err != nil || number <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid build number %q", r.PathValue("build")))
+		return
+	}
+
+	a.buildMu.Lock()
+	defer a.buildMu.Unlock()
+
+	if err := a.client.RemoveCachedBuild(number); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
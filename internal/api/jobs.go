@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxFinishedJobs is how many finished jobs are kept for clients to look up
+const maxFinishedJobs = 100
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+var (
+	errJobNotFound = errors.New("job not found")
+	errJobFinished = errors.New("job already finished")
+)
+
+// Job is a long-running operation started by a request: creating or updating
+// a server, or converting a mod into one
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"` // create, update or convert
+	Server     string      `json:"server"`
+	State      string      `json:"state"`
+	Step       string      `json:"step,omitempty"`
+	Percent    float64     `json:"percent"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// jobUpdate lets a running job report its progress
+type jobUpdate func(step string, percent float64)
+
+// jobQueue runs jobs in the background and remembers the recent ones
+type jobQueue struct {
+	mu     sync.Mutex
+	ctx    context.Context // Parent of every job's context; cancelled on shutdown
+	jobs   map[string]*Job
+	nextID int
+}
+
+func newJobQueue() *jobQueue {
+	return &jobQueue{ctx: context.Background(), jobs: make(map[string]*Job)}
+}
+
+// setContext makes jobs started from now on stop when ctx is cancelled
+func (q *jobQueue) setContext(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ctx = ctx
+}
+
+// start runs fn in the background and returns a snapshot of its job. fn's
+// result becomes the job's Result when it succeeds.
+func (q *jobQueue) start(kind, serverName string, fn func(ctx context.Context, update jobUpdate) (interface{}, error)) Job {
+	q.mu.Lock()
+	q.nextID++
+	ctx, cancel := context.WithCancel(q.ctx)
+	job := &Job{
+		ID:        strconv.Itoa(q.nextID),
+		Kind:      kind,
+		Server:    serverName,
+		State:     JobRunning,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+	q.jobs[job.ID] = job
+	snapshot := *job
+	q.mu.Unlock()
+
+	update := func(step string, percent float64) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		job.Step, job.Percent = step, percent
+	}
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, update)
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		now := time.Now()
+		job.FinishedAt = &now
+		switch {
+		case err != nil && ctx.Err() != nil:
+			job.State, job.Error = JobCancelled, err.Error()
+		case err != nil:
+			job.State, job.Error = JobFailed, err.Error()
+		default:
+			job.State, job.Result, job.Percent = JobSucceeded, result, 100
+		}
+		q.prune()
+	}()
+
+	return snapshot
+}
+
+// prune forgets the oldest finished jobs beyond maxFinishedJobs (caller must hold the lock)
+func (q *jobQueue) prune() {
+	var finished []*Job
+	for _, job := range q.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(q.jobs, job.ID)
+	}
+}
+
+// list returns every job, newest first
+func (q *jobQueue) list() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	return jobs
+}
+
+// get returns a snapshot of a job
+func (q *jobQueue) get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	return *job, nil
+}
+
+// cancelJob asks a running job to stop; it finishes as cancelled once it has cleaned up
+func (q *jobQueue) cancelJob(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	if job.FinishedAt != nil {
+		return *job, fmt.Errorf("%w: %s is %s", errJobFinished, id, job.State)
+	}
+	job.cancel()
+	return *job, nil
+}
+
+func (a *API) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.jobs.list())
+}
+
+func (a *API) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.jobs.get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (a *API) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.jobs.cancelJob(r.PathValue("id"))
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
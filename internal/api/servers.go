@@ -0,0 +1,514 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/inkwash"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Log requests are capped so one request can't read a whole log history
+const (
+	defaultLogLines = 200
+	maxLogLines     = 5000
+)
+
+// Server is a server as the API returns it
+type Server struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Endpoint  string `json:"endpoint"`
+	TargetOS  string `json:"target_os"`
+	GameBuild int    `json:"game_build"`
+	Running   bool   `json:"running"`
+	PID       int    `json:"pid,omitempty"`
+
+	// Only while running, once the first samples are in
+	Metrics *Metrics `json:"metrics,omitempty"`
+}
+
+// Metrics is a running server's latest usage; the history is only included
+// when a single server is requested
+type Metrics struct {
+	CPUPercent       float64   `json:"cpu_percent"`
+	RAMGB            float64   `json:"ram_gb"`
+	Players          *int      `json:"players,omitempty"`
+	MaxPlayers       int       `json:"max_players,omitempty"`
+	StatsUnavailable bool      `json:"stats_unavailable,omitempty"`
+	CPUHistory       []float64 `json:"cpu_history,omitempty"`
+	RAMHistory       []float64 `json:"ram_history,omitempty"`
+}
+
+// view builds the API's view of a server
+func (a *API) view(srv *types.Server, history bool) Server {
+	view := Server{
+		Name:      srv.Name,
+		Path:      srv.Path,
+		Endpoint:  srv.Endpoint(),
+		TargetOS:  srv.GetTargetOS(),
+		GameBuild: srv.GetGameBuild(),
+		Running:   a.client.IsRunning(srv),
+	}
+	if !view.Running {
+		return view
+	}
+	view.PID = srv.PID
+
+	snapshot, ok := a.metrics.Snapshot(srv.Name)
+	if !ok || snapshot.Paused || snapshot.PID != srv.PID {
+		return view
+	}
+
+	metrics := &Metrics{
+		CPUPercent:       snapshot.CurrentCPU(),
+		RAMGB:            snapshot.CurrentRAM(),
+		StatsUnavailable: snapshot.StatsUnavailable,
+	}
+	if snapshot.PlayersKnown {
+		players := snapshot.PlayerCount
+		metrics.Players = &players
+		metrics.MaxPlayers = snapshot.MaxPlayers
+	}
+	if history {
+		metrics.CPUHistory = snapshot.CPU
+		metrics.RAMHistory = snapshot.RAM
+	}
+	view.Metrics = metrics
+	return view
+}
+
+func (a *API) handleListServers(w http.ResponseWriter, r *http.Request) {
+	servers := a.client.List()
+	views := make([]Server, 0, len(servers))
+	for i := range servers {
+		views = append(views, a.view(&servers[i], false))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (a *API) handleGetServer(w http.ResponseWriter, r *http.Request) {
+	srv, err := a.client.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.view(srv, true))
+}
+
+// buildValue accepts a build as a JSON string ("recommended", "17000") or number
+type buildValue string
+
+func (b *buildValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = buildValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("build must be a string or a number")
+	}
+	*b = buildValue(n.String())
+	return nil
+}
+
+// createRequest is the body of POST /servers
+type createRequest struct {
+	Name            string     `json:"name"`
+	Path            string     `json:"path"`
+	Build           buildValue `json:"build"`
+	ArtifactURL     string     `json:"artifact_url"`
+	LicenseKey      string     `json:"license_key"`
+	KeyID           string     `json:"key_id"` // A key stored with `inkwash key add`
+	Port            int        `json:"port"`
+	BindAddress     string     `json:"bind_address"`
+	TargetOS        string     `json:"target_os"`
+	GameBuild       buildValue `json:"game_build"`
+	NoScaffold      bool       `json:"no_scaffold"`
+	Force           bool       `json:"force"`
+	ForceRedownload bool       `json:"force_redownload"`
+	LaunchCommand   string     `json:"launch_command"`
+	Verify          bool       `json:"verify"`
+	SHA256          string     `json:"sha256"`
+}
+
+func (a *API) handleCreateServer(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	opts, err := a.createOptions(req)
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	if _, err := a.client.Get(opts.Name); err == nil {
+		writeError(w, 0, fmt.Errorf("%w: '%s'", inkwash.ErrNameTaken, opts.Name))
+		return
+	}
+
+	job := a.jobs.start("create", opts.Name, func(ctx context.Context, update jobUpdate) (interface{}, error) {
+		a.buildMu.Lock()
+		defer a.buildMu.Unlock()
+
+		srv, err := a.client.Create(ctx, opts, func(p inkwash.Progress) {
+			update(p.Step, p.Percent)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return a.view(srv, false), nil
+	})
+	writeJob(w, job)
+}
+
+// createOptions checks a create request and fills in the defaults
+func (a *API) createOptions(req createRequest) (inkwash.CreateOptions, error) {
+	opts := inkwash.CreateOptions{
+		Name:            req.Name,
+		Path:            req.Path,
+		ArtifactURL:     req.ArtifactURL,
+		LicenseKey:      req.LicenseKey,
+		Port:            req.Port,
+		BindAddress:     req.BindAddress,
+		TargetOS:        req.TargetOS,
+		NoScaffold:      req.NoScaffold,
+		Force:           req.Force,
+		ForceRedownload: req.ForceRedownload,
+		LaunchCommand:   req.LaunchCommand,
+		Verify:          req.Verify,
+		SHA256:          req.SHA256,
+	}
+
+	if err := validation.ValidateServerName(opts.Name); err != nil {
+		return opts, &badRequest{err}
+	}
+	if opts.Path == "" {
+		opts.Path = a.opts.InstallPath
+	}
+	if opts.Port == 0 {
+		opts.Port = a.opts.Port
+	}
+	if opts.BindAddress == "" {
+		opts.BindAddress = a.opts.BindAddress
+	}
+	if opts.TargetOS == "" {
+		opts.TargetOS = a.opts.TargetOS
+	}
+	if err := validation.ValidateBindAddress(opts.BindAddress); err != nil {
+		return opts, &badRequest{err}
+	}
+
+	var err error
+	if opts.ArtifactURL != "" {
+		if req.Build != "" {
+			return opts, &badRequest{errors.New("build and artifact_url can't be used together")}
+		}
+		if _, _, err := validation.ParseArtifactURL(opts.ArtifactURL); err != nil {
+			return opts, &badRequest{err}
+		}
+	} else {
+		build := string(req.Build)
+		if build == "" {
+			build = types.BuildChannelRecommended
+		}
+		if opts.Build, opts.BuildChannel, err = validation.ParseBuild(build); err != nil {
+			return opts, &badRequest{err}
+		}
+	}
+
+	if opts.GameBuild, err = validation.ParseGameBuild(string(req.GameBuild)); err != nil {
+		return opts, &badRequest{err}
+	}
+	if err := checkSHA256(opts.SHA256); err != nil {
+		return opts, err
+	}
+
+	if req.KeyID != "" {
+		if opts.LicenseKey != "" {
+			return opts, &badRequest{errors.New("license_key and key_id can't be used together")}
+		}
+		vault, err := cache.NewKeyVaultWithOptions(filepath.Join(registry.GetDefaultConfigPath(), "keys.enc"), a.opts.KeyVault)
+		if err != nil {
+			return opts, fmt.Errorf("failed to load key vault: %w", err)
+		}
+		key, err := vault.Get(req.KeyID)
+		if err != nil {
+			return opts, &badRequest{fmt.Errorf("license key not found: %w", err)}
+		}
+		opts.LicenseKey = key.Key
+		opts.KeyID = key.ID
+	}
+
+	return opts, nil
+}
+
+// checkSHA256 checks an expected archive checksum, if one was given
+func checkSHA256(sum string) error {
+	if decoded, err := hex.DecodeString(sum); sum != "" && (err != nil || len(decoded) != 32) {
+		return &badRequest{errors.New("sha256 must be 64 hex characters")}
+	}
+	return nil
+}
+
+func (a *API) handleRemoveServer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.client.Remove(r.Context(), name); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	a.metrics.Untrack(name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleStart(w http.ResponseWriter, r *http.Request) {
+	srv, err := a.client.Start(r.Context(), r.PathValue("name"))
+	a.respondAction(w, srv, err)
+}
+
+func (a *API) handleStop(w http.ResponseWriter, r *http.Request) {
+	srv, err := a.client.Stop(r.Context(), r.PathValue("name"))
+	if err == nil {
+		a.metrics.Pause(srv.Name)
+	}
+	a.respondAction(w, srv, err)
+}
+
+func (a *API) handleRestart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := a.client.Stop(r.Context(), name); err != nil && !errors.Is(err, inkwash.ErrServerNotRunning) {
+		a.respondAction(w, nil, err)
+		return
+	}
+	a.metrics.Pause(name)
+
+	srv, err := a.client.Start(r.Context(), name)
+	a.respondAction(w, srv, err)
+}
+
+// respondAction answers a start, stop or restart. A server that changed state
+// but couldn't be saved to the registry is reported as a success.
+func (a *API) respondAction(w http.ResponseWriter, srv *types.Server, err error) {
+	if err != nil && (srv == nil || errors.Is(err, inkwash.ErrServerRunning) || errors.Is(err, inkwash.ErrServerNotRunning)) {
+		writeError(w, 0, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.view(srv, false))
+}
+
+// updateRequest is the body of POST /servers/{name}/update
+type updateRequest struct {
+	Build           buildValue `json:"build"`
+	Channel         string     `json:"channel"`
+	ArtifactURL     string     `json:"artifact_url"`
+	Force           bool       `json:"force"`
+	ForceRedownload bool       `json:"force_redownload"`
+	Verify          bool       `json:"verify"`
+	SHA256          string     `json:"sha256"`
+}
+
+// updateResult is the result of a finished update job
+type updateResult struct {
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	BackupPath string `json:"backup_path,omitempty"`
+	UpToDate   bool   `json:"up_to_date,omitempty"`
+	Channel    string `json:"channel"`
+}
+
+func (a *API) handleUpdateServer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req updateRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	opts := inkwash.UpdateOptions{
+		ArtifactURL:     req.ArtifactURL,
+		Force:           req.Force,
+		ForceRedownload: req.ForceRedownload,
+		Verify:          req.Verify,
+		SHA256:          req.SHA256,
+	}
+	if opts.ArtifactURL != "" {
+		if req.Build != "" {
+			writeError(w, http.StatusBadRequest, errors.New("build and artifact_url can't be used together"))
+			return
+		}
+		if _, _, err := validation.ParseArtifactURL(opts.ArtifactURL); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	} else if req.Build != "" {
+		var err error
+		if opts.Build, opts.BuildChannel, err = validation.ParseBuild(string(req.Build)); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if req.Channel != "" {
+		var err error
+		if opts.Channel, err = validation.ParseChannel(req.Channel); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if opts.Channel != types.BuildChannelPinned && (req.Build != "" || req.ArtifactURL != "") {
+			writeError(w, http.StatusBadRequest, errors.New("channel can't be used with build or artifact_url unless it's pinned"))
+			return
+		}
+	}
+	if err := checkSHA256(opts.SHA256); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	// Refuse up front rather than in a job that fails straight away
+	srv, err := a.client.Get(name)
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	if a.client.IsRunning(srv) {
+		writeError(w, 0, fmt.Errorf("%w: stop '%s' before updating it", inkwash.ErrServerRunning, name))
+		return
+	}
+
+	job := a.jobs.start("update", name, func(ctx context.Context, update jobUpdate) (interface{}, error) {
+		a.buildMu.Lock()
+		defer a.buildMu.Unlock()
+
+		result, err := a.client.UpdateBuild(ctx, name, opts, func(p inkwash.Progress) {
+			update(p.Step, p.Percent)
+		})
+		if errors.Is(err, inkwash.ErrUpToDate) && result != nil {
+			return updateResult{From: result.From, To: result.To, UpToDate: true, Channel: result.Channel}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return updateResult{From: result.From, To: result.To, BackupPath: result.BackupPath, Channel: result.Channel}, nil
+	})
+	writeJob(w, job)
+}
+
+// convertRequest is the body of POST /servers/{name}/convert
+type convertRequest struct {
+	URL    string `json:"url"`
+	Layout string `json:"layout"`
+}
+
+// convertResult is the result of a finished convert job
+type convertResult struct {
+	Path     string   `json:"path"`
+	Vehicles []string `json:"vehicles,omitempty"` // Spawn names of the mod's vehicles
+	Warnings []string `json:"warnings,omitempty"` // Generated manifests and unreferenced data files
+}
+
+func (a *API) handleConvert(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req convertRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	if !strings.HasPrefix(req.URL, "https://") && !strings.HasPrefix(req.URL, "http://") {
+		writeError(w, http.StatusBadRequest, errors.New("url must be a gta5-mods.com link"))
+		return
+	}
+
+	srv, err := a.client.Get(name)
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	opts := inkwash.ConvertOptions{
+		ResourcesPath: filepath.Join(srv.Path, "resources"),
+		Layout:        req.Layout,
+		Timeout:       a.opts.ConvertTimeout,
+		PollInterval:  a.opts.ConvertPollInterval,
+	}
+	if opts.Layout == "" {
+		opts.Layout = a.opts.ConvertLayout
+	}
+
+	job := a.jobs.start("convert", name, func(ctx context.Context, update jobUpdate) (interface{}, error) {
+		var report *inkwash.ConvertReport
+		opts.OnStep = func(step string) { update(step, 0) }
+		opts.OnInspect = func(r *inkwash.ConvertReport) { report = r }
+		path, err := a.client.Convert(ctx, req.URL, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		result := convertResult{Path: path}
+		if report != nil {
+			result.Vehicles, result.Warnings = report.Vehicles, report.Warnings()
+		}
+		return result, nil
+	})
+	writeJob(w, job)
+}
+
+// writeJob answers a request that started a job
+func writeJob(w http.ResponseWriter, job Job) {
+	w.Header().Set("Location", Prefix+"/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (a *API) handleLogs(w http.ResponseWriter, r *http.Request) {
+	srv, err := a.client.Get(r.PathValue("name"))
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+
+	lines := defaultLogLines
+	if value := r.URL.Query().Get("lines"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("lines must be a positive number, got %q", value))
+			return
+		}
+		lines = min(n, maxLogLines)
+	}
+
+	var match func(string) bool
+	if pattern := r.URL.Query().Get("grep"); pattern != "" {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid grep pattern: %w", err))
+			return
+		}
+		match = re.MatchString
+	}
+
+	logLines, err := server.TailLogMatching(srv.Path, lines, match)
+	if os.IsNotExist(err) {
+		logLines, err = []string{}, nil
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"lines": logLines})
+}
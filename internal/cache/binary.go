@@ -13,13 +13,20 @@ import (
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
-// BinaryCache manages cached FXServer builds
+// BinaryCache manages cached FXServer builds. Once it holds more than
+// maxBuilds builds, or more than maxSize bytes, the least recently used
+// builds are evicted.
 type BinaryCache struct {
 	basePath  string
 	metadata  *Metadata
 	maxBuilds int
+	maxSize   int64 // 0 for no limit
 }
 
+// metadataVersion is the current metadata.json version. Version 2 counts the
+// extracted files in CachedBuild.Size, not just the archive.
+const metadataVersion = 2
+
 // NewBinaryCache creates a new binary cache
 func NewBinaryCache(basePath string, maxBuilds int) (*BinaryCache, error) {
 	if maxBuilds <= 0 {
@@ -72,6 +79,12 @@ func (bc *BinaryCache) Get(buildNumber int) (string, error) {
 	return buildPath, nil
 }
 
+// SetMaxSize limits the total size of the cache in bytes, 0 for no limit.
+// It's applied when a build is added and by Prune.
+func (bc *BinaryCache) SetMaxSize(bytes int64) {
+	bc.maxSize = max(bytes, 0)
+}
+
 // Add adds a build to the cache
 func (bc *BinaryCache) Add(build types.Build, archivePath, extractedPath string) error {
 	buildDir := filepath.Join(bc.basePath, strconv.Itoa(build.Number))
@@ -94,6 +107,10 @@ func (bc *BinaryCache) Add(build types.Build, archivePath, extractedPath string)
 	// Move/copy extracted files
 	destExtracted := filepath.Join(buildDir, "extracted")
 	if extractedPath != destExtracted {
+		// An earlier copy of the build is replaced, not merged into
+		if err := os.RemoveAll(destExtracted); err != nil {
+			return fmt.Errorf("failed to replace extracted files: %w", err)
+		}
 		if err := os.Rename(extractedPath, destExtracted); err != nil {
 			// If rename fails (cross-device), try copy
 			if err := copyDir(extractedPath, destExtracted); err != nil {
@@ -103,10 +120,20 @@ func (bc *BinaryCache) Add(build types.Build, archivePath, extractedPath string)
 		}
 	}
 
-	// Get archive size
-	archiveInfo, err := os.Stat(destArchive)
+	// Size on disk, archive and extracted files
+	size, err := dirSize(buildDir)
+	if err != nil {
+		return fmt.Errorf("failed to measure build: %w", err)
+	}
+
+	// Checksums let VerifyBuild catch bit-rot and partial writes later
+	archiveSum, err := fileSHA256(destArchive)
 	if err != nil {
-		return fmt.Errorf("failed to stat archive: %w", err)
+		return fmt.Errorf("failed to checksum archive: %w", err)
+	}
+	treeSum, err := treeSHA256(destExtracted)
+	if err != nil {
+		return fmt.Errorf("failed to checksum extracted files: %w", err)
 	}
 
 	// Add to metadata
@@ -114,17 +141,33 @@ func (bc *BinaryCache) Add(build types.Build, archivePath, extractedPath string)
 		Number:      build.Number,
 		Hash:        build.Hash,
 		Downloaded:  time.Now(),
-		Size:        archiveInfo.Size(),
+		Size:        size,
 		Recommended: build.Recommended,
 		Optional:    build.Optional,
 		LastUsed:    time.Now(),
+		Archive:     archiveName,
+		SHA256:      archiveSum,
+		TreeSHA256:  treeSum,
 	}
 
-	bc.metadata.Builds = append(bc.metadata.Builds, cacheBuild)
-	bc.metadata.TotalSize += archiveInfo.Size()
+	// A build added again (e.g. re-downloaded after failing verification)
+	// replaces its entry, so it's neither listed nor counted twice
+	replaced := false
+	for i, existing := range bc.metadata.Builds {
+		if existing.Number == build.Number {
+			bc.metadata.TotalSize -= existing.Size
+			bc.metadata.Builds[i] = cacheBuild
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		bc.metadata.Builds = append(bc.metadata.Builds, cacheBuild)
+	}
+	bc.metadata.TotalSize += size
 
 	// Enforce cache limits
-	if err := bc.enforceLimits(); err != nil {
+	if _, err := bc.enforceLimits(bc.maxBuilds, bc.maxSize); err != nil {
 		return err
 	}
 
@@ -183,30 +226,61 @@ func (bc *BinaryCache) GetStats() CacheStats {
 		TotalBuilds: len(bc.metadata.Builds),
 		TotalSize:   bc.metadata.TotalSize,
 		MaxBuilds:   bc.maxBuilds,
+		MaxSize:     bc.maxSize,
 	}
 }
 
-// enforceLimits enforces cache size limits using LRU eviction
-func (bc *BinaryCache) enforceLimits() error {
-	if len(bc.metadata.Builds) <= bc.maxBuilds {
-		return nil
+// Prune evicts the least recently used builds until at most maxBuilds are
+// left and they take up at most maxSize bytes (0 for no size limit), and
+// returns the evicted builds. The most recently used build is always kept.
+func (bc *BinaryCache) Prune(maxBuilds int, maxSize int64) ([]CachedBuild, error) {
+	return bc.enforceLimits(max(maxBuilds, 1), maxSize)
+}
+
+// PruneCandidates returns the builds Prune would evict, without removing them
+func (bc *BinaryCache) PruneCandidates(maxBuilds int, maxSize int64) []CachedBuild {
+	builds := bc.byLastUsed()
+	maxBuilds = max(maxBuilds, 1)
+
+	var total int64
+	for _, build := range builds {
+		total += build.Size
 	}
 
-	// Sort by last used (oldest first)
-	sort.Slice(bc.metadata.Builds, func(i, j int) bool {
-		return bc.metadata.Builds[i].LastUsed.Before(bc.metadata.Builds[j].LastUsed)
-	})
+	var evict []CachedBuild
+	for len(builds)-len(evict) > 1 {
+		overCount := len(builds)-len(evict) > maxBuilds
+		overSize := maxSize > 0 && total > maxSize
+		if !overCount && !overSize {
+			break
+		}
+		build := builds[len(evict)]
+		evict = append(evict, build)
+		total -= build.Size
+	}
+	return evict
+}
 
-	// Remove oldest builds
-	toRemove := len(bc.metadata.Builds) - bc.maxBuilds
-	for i := 0; i < toRemove; i++ {
-		build := bc.metadata.Builds[0]
+// enforceLimits evicts builds using LRU until the cache is within
+// maxBuilds and maxSize, and returns them
+func (bc *BinaryCache) enforceLimits(maxBuilds int, maxSize int64) ([]CachedBuild, error) {
+	evict := bc.PruneCandidates(maxBuilds, maxSize)
+	for i, build := range evict {
 		if err := bc.Remove(build.Number); err != nil {
-			return err
+			return evict[:i], err
 		}
 	}
+	return evict, nil
+}
 
-	return nil
+// byLastUsed returns the cached builds, least recently used first
+func (bc *BinaryCache) byLastUsed() []CachedBuild {
+	builds := make([]CachedBuild, len(bc.metadata.Builds))
+	copy(builds, bc.metadata.Builds)
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].LastUsed.Before(builds[j].LastUsed)
+	})
+	return builds
 }
 
 // updateLastUsed updates the last used timestamp for a build
@@ -227,7 +301,7 @@ func (bc *BinaryCache) loadMetadata() error {
 	// If metadata doesn't exist, create empty
 	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
 		bc.metadata = &Metadata{
-			Version:   1,
+			Version:   metadataVersion,
 			Builds:    []CachedBuild{},
 			MaxBuilds: bc.maxBuilds,
 			TotalSize: 0,
@@ -247,6 +321,19 @@ func (bc *BinaryCache) loadMetadata() error {
 	}
 
 	bc.metadata = &metadata
+
+	// Older caches only counted the archive, measure the whole build once
+	if metadata.Version < metadataVersion {
+		bc.metadata.TotalSize = 0
+		for i, build := range bc.metadata.Builds {
+			if size, err := dirSize(filepath.Join(bc.basePath, strconv.Itoa(build.Number))); err == nil {
+				bc.metadata.Builds[i].Size = size
+			}
+			bc.metadata.TotalSize += bc.metadata.Builds[i].Size
+		}
+		bc.metadata.Version = metadataVersion
+		return bc.saveMetadata()
+	}
 	return nil
 }
 
@@ -268,6 +355,21 @@ func (bc *BinaryCache) saveMetadata() error {
 
 // Helper functions
 
+// dirSize returns the total size of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// stageBuild writes a fake downloaded build: an archive and extracted files
+func stageBuild(t *testing.T, dir string, extractedSize int) (archive, extracted string) {
+	t.Helper()
+	archive = filepath.Join(dir, "server.7z")
+	extracted = filepath.Join(dir, "extracted")
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "FXServer"), make([]byte, extractedSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archive, extracted
+}
+
+func TestBinaryCacheAddReplacesBuild(t *testing.T) {
+	bc, err := NewBinaryCache(filepath.Join(t.TempDir(), "cache"), 3)
+	if err != nil {
+		t.Fatalf("NewBinaryCache: %v", err)
+	}
+
+	build := types.Build{Number: 7290, Hash: "abc"}
+	archive, extracted := stageBuild(t, t.TempDir(), 100)
+	if err := bc.Add(build, archive, extracted); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Added again without being removed first
+	archive, extracted = stageBuild(t, t.TempDir(), 300)
+	if err := bc.Add(build, archive, extracted); err != nil {
+		t.Fatalf("second Add: %v", err)
+	}
+
+	builds := bc.List()
+	if len(builds) != 1 {
+		t.Fatalf("cache lists %d builds, want 1", len(builds))
+	}
+	if stats := bc.GetStats(); stats.TotalSize != builds[0].Size {
+		t.Fatalf("total size %d, want the one build's %d", stats.TotalSize, builds[0].Size)
+	}
+	if builds[0].Size < 300 || builds[0].Size >= 400 {
+		t.Fatalf("build size %d, want the second copy's (over 300 bytes, without the first's 100)", builds[0].Size)
+	}
+	if err := bc.VerifyBuild(build.Number); err != nil {
+		t.Fatalf("VerifyBuild after replacing: %v", err)
+	}
+
+	// Still consistent after reloading
+	reopened, err := NewBinaryCache(bc.basePath, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reopened.List()); n != 1 {
+		t.Fatalf("reloaded cache lists %d builds, want 1", n)
+	}
+}
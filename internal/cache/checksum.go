@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+)
+
+// ErrChecksumMismatch is returned when a cached build no longer matches its recorded checksum
+var ErrChecksumMismatch = errors.New("cached build is corrupt")
+
+// ErrNoChecksum is returned when verifying a build cached before checksums were recorded
+var ErrNoChecksum = errors.New("no checksum recorded for cached build")
+
+// ChecksumError describes which part of a cached build failed verification
+type ChecksumError struct {
+	Build    int
+	Part     string // "archive" or "extracted files"
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("build %d: %s missing", e.Build, e.Part)
+	}
+	return fmt.Sprintf("build %d: %s checksum mismatch (expected %.12s, got %.12s)", e.Build, e.Part, e.Expected, e.Actual)
+}
+
+// Unwrap lets callers match with errors.Is(err, ErrChecksumMismatch)
+func (e *ChecksumError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// VerifyBuild recomputes a cached build's checksums and compares them with the
+// ones recorded when it was added. Builds cached before checksums were
+// recorded return ErrNoChecksum.
+func (bc *BinaryCache) VerifyBuild(buildNumber int) error {
+	var cached *CachedBuild
+	for i := range bc.metadata.Builds {
+		if bc.metadata.Builds[i].Number == buildNumber {
+			cached = &bc.metadata.Builds[i]
+			break
+		}
+	}
+	if cached == nil {
+		return fmt.Errorf("build %d not in cache", buildNumber)
+	}
+	if cached.SHA256 == "" || cached.TreeSHA256 == "" {
+		return fmt.Errorf("%w: build %d", ErrNoChecksum, buildNumber)
+	}
+
+	buildDir := filepath.Join(bc.basePath, strconv.Itoa(buildNumber))
+
+	sum, _ := fileSHA256(filepath.Join(buildDir, cached.Archive))
+	if sum != cached.SHA256 {
+		return &ChecksumError{Build: buildNumber, Part: "archive", Expected: cached.SHA256, Actual: sum}
+	}
+
+	sum, _ = treeSHA256(filepath.Join(buildDir, "extracted"))
+	if sum != cached.TreeSHA256 {
+		return &ChecksumError{Build: buildNumber, Part: "extracted files", Expected: cached.TreeSHA256, Actual: sum}
+	}
+
+	return nil
+}
+
+// VerifyArchive extracts a cached build's archive again and checks that its
+// extracted files still match it, which also works for builds cached before
+// checksums were recorded. Those get their checksums recorded once they pass,
+// so VerifyBuild can check them from then on.
+func (bc *BinaryCache) VerifyArchive(buildNumber int) error {
+	var cached *CachedBuild
+	for i := range bc.metadata.Builds {
+		if bc.metadata.Builds[i].Number == buildNumber {
+			cached = &bc.metadata.Builds[i]
+			break
+		}
+	}
+	if cached == nil {
+		return fmt.Errorf("build %d not in cache", buildNumber)
+	}
+
+	buildDir := filepath.Join(bc.basePath, strconv.Itoa(buildNumber))
+	archive := cached.Archive
+	if archive == "" {
+		// Older entries didn't record the name, it's the only file next to extracted/
+		entries, err := os.ReadDir(buildDir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				archive = entry.Name()
+			}
+		}
+		if archive == "" {
+			return &ChecksumError{Build: buildNumber, Part: "archive"}
+		}
+	}
+	archivePath := filepath.Join(buildDir, archive)
+
+	tmp, err := os.MkdirTemp(bc.basePath, ".verify-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := download.NewExtractor().Extract(archivePath, tmp); err != nil {
+		return fmt.Errorf("%w: build %d: archive can't be extracted: %v", ErrChecksumMismatch, buildNumber, err)
+	}
+	want, err := treeSHA256(tmp)
+	if err != nil {
+		return err
+	}
+	got, _ := treeSHA256(filepath.Join(buildDir, "extracted"))
+	if got != want {
+		return &ChecksumError{Build: buildNumber, Part: "extracted files", Expected: want, Actual: got}
+	}
+
+	if cached.SHA256 == "" || cached.TreeSHA256 == "" {
+		sum, err := fileSHA256(archivePath)
+		if err != nil {
+			return err
+		}
+		cached.Archive, cached.SHA256, cached.TreeSHA256 = archive, sum, want
+		return bc.saveMetadata()
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// treeSHA256 returns a hex SHA256 over every file's relative path and
+// contents (and every symlink's target) under dir, in walk order
+func treeSHA256(dir string) (string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "L %s\x00%s\x00", filepath.ToSlash(rel), target)
+
+		case info.Mode().IsRegular():
+			sum, err := fileSHA256(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "F %s\x00%s\x00", filepath.ToSlash(rel), sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
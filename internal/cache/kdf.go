@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for new passphrase-protected files (the vault and key
+// exports). They're stored in the file, so they can be raised without
+// breaking older files.
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024 // KiB
+	kdfThreads = 4
+	kdfSalt    = 16
+)
+
+// kdfHeaderSize is the size of a passphraseKDF in a vault header
+const kdfHeaderSize = 4 + 4 + 1 + kdfSalt
+
+// passphraseKDF is how a file's encryption key is derived from a passphrase
+type passphraseKDF struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+}
+
+// newPassphraseKDF returns the current parameters with a new random salt
+func newPassphraseKDF() (*passphraseKDF, error) {
+	kdf := &passphraseKDF{
+		Time:    kdfTime,
+		Memory:  kdfMemory,
+		Threads: kdfThreads,
+		Salt:    make([]byte, kdfSalt),
+	}
+	if _, err := rand.Read(kdf.Salt); err != nil {
+		return nil, err
+	}
+	return kdf, nil
+}
+
+// key derives the 32-byte encryption key for passphrase
+func (kdf *passphraseKDF) key(passphrase string) ([]byte, error) {
+	// Don't let a crafted file ask for more than 1 GiB of memory
+	if kdf.Time == 0 || kdf.Threads == 0 || kdf.Memory > 1024*1024 || len(kdf.Salt) == 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	return argon2.IDKey([]byte(passphrase), kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, 32), nil
+}
+
+// cipher derives the AES-256-GCM cipher for passphrase
+func (kdf *passphraseKDF) cipher(passphrase string) (cipher.AEAD, error) {
+	key, err := kdf.key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// marshalBinary encodes the parameters for a vault header
+func (kdf *passphraseKDF) marshalBinary() []byte {
+	buf := make([]byte, 0, kdfHeaderSize)
+	buf = binary.BigEndian.AppendUint32(buf, kdf.Time)
+	buf = binary.BigEndian.AppendUint32(buf, kdf.Memory)
+	buf = append(buf, kdf.Threads)
+	return append(buf, kdf.Salt...)
+}
+
+// parseKDF decodes parameters written by marshalBinary
+func parseKDF(data []byte) (*passphraseKDF, error) {
+	if len(data) < kdfHeaderSize {
+		return nil, fmt.Errorf("key derivation parameters are truncated")
+	}
+	return &passphraseKDF{
+		Time:    binary.BigEndian.Uint32(data[0:4]),
+		Memory:  binary.BigEndian.Uint32(data[4:8]),
+		Threads: data[8],
+		Salt:    bytes.Clone(data[9:kdfHeaderSize]),
+	}, nil
+}
+
+// equal reports whether two sets of parameters derive the same keys
+func (kdf *passphraseKDF) equal(other *passphraseKDF) bool {
+	if kdf == nil || other == nil {
+		return kdf == other
+	}
+	return bytes.Equal(kdf.marshalBinary(), other.marshalBinary())
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// ErrWrongPassphrase is returned when a key export can't be decrypted with
+// the passphrase given
+var ErrWrongPassphrase = errors.New("wrong passphrase, or the export is damaged")
+
+// exportFormat identifies a key export file
+const exportFormat = "inkwash-keys"
+
+// keyExport is a passphrase-protected copy of the vault's keys, readable on
+// any machine
+type keyExport struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	passphraseKDF
+	Data []byte `json:"data"` // AES-256-GCM nonce and sealed keys
+}
+
+// Export writes the vault's keys to path encrypted with passphrase, so they
+// can be imported on another machine or after a reinstall. It returns how
+// many keys were written.
+func (kv *KeyVault) Export(path, passphrase string) (int, error) {
+	if passphrase == "" {
+		return 0, fmt.Errorf("passphrase can't be empty")
+	}
+
+	keys := kv.List()
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal keys: %w", err)
+	}
+
+	kdf, err := newPassphraseKDF()
+	if err != nil {
+		return 0, err
+	}
+	exp := keyExport{
+		Format:        exportFormat,
+		Version:       1,
+		KDF:           "argon2id",
+		passphraseKDF: *kdf,
+	}
+
+	gcm, err := exp.cipher(passphrase)
+	if err != nil {
+		return 0, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return 0, err
+	}
+	exp.Data = gcm.Seal(nonce, nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(exp, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal export: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return len(keys), nil
+}
+
+// Import adds the keys in an export written by Export, decrypting it with
+// passphrase. Keys already in the vault are skipped; the others keep their
+// ID, label and creation date.
+func (kv *KeyVault) Import(path, passphrase string) (imported, skipped int, err error) {
+	keys, err := ReadExport(path, passphrase)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = kv.withLock(func() error {
+		if err := kv.load(); err != nil {
+			return err
+		}
+
+		have := make(map[string]bool, len(kv.keys))
+		ids := make(map[string]bool, len(kv.keys))
+		for _, key := range kv.keys {
+			have[key.Key] = true
+			ids[key.ID] = true
+		}
+
+		imported, skipped = 0, 0
+		for _, key := range keys {
+			if have[key.Key] {
+				skipped++
+				continue
+			}
+			if key.ID == "" || ids[key.ID] {
+				key.ID = uuid.New().String()
+			}
+			have[key.Key] = true
+			ids[key.ID] = true
+			kv.keys = append(kv.keys, key)
+			imported++
+		}
+
+		if imported == 0 {
+			return nil
+		}
+		return kv.save()
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return imported, skipped, nil
+}
+
+// ReadExport decrypts the keys in an export written by KeyVault.Export
+func ReadExport(path, passphrase string) ([]LicenseKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	var exp keyExport
+	if err := json.Unmarshal(data, &exp); err != nil || exp.Format != exportFormat {
+		return nil, fmt.Errorf("%s isn't an inkwash key export", path)
+	}
+	if exp.Version != 1 || exp.KDF != "argon2id" {
+		return nil, fmt.Errorf("export was written by a newer inkwash")
+	}
+
+	gcm, err := exp.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(exp.Data) < nonceSize {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := gcm.Open(nil, exp.Data[:nonceSize], exp.Data[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var keys []LicenseKey
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+	return keys, nil
+}
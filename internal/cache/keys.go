@@ -6,12 +6,15 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/VexoaXYZ/inkwash/internal/lockfile"
 	"github.com/VexoaXYZ/inkwash/internal/validation"
 	"github.com/google/uuid"
 )
@@ -24,14 +27,41 @@ type LicenseKey struct {
 	Created time.Time `json:"created"`
 }
 
-// KeyVault manages encrypted license keys
+// vaultLockTimeout is how long to wait for another inkwash process to finish writing the vault
+const vaultLockTimeout = 5 * time.Second
+
+// KeyVault manages encrypted license keys.
+// Writes hold both an in-process mutex and a lock file next to the vault, and
+// re-read the vault first, so concurrent writers merge instead of overwriting.
+// The encryption key is protected as VaultOptions.Backend says.
 type KeyVault struct {
 	filePath string
 	keys     []LicenseKey
+	mu       sync.Mutex
+
+	opts     VaultOptions
+	mode     string         // Vault* protection the file uses
+	keychain []byte         // Key read from the OS keychain
+	passKey  []byte         // Key derived from the passphrase with kdf
+	kdf      *passphraseKDF // Passphrase parameters the file uses
 }
 
-// NewKeyVault creates a new key vault
+// NewKeyVault creates a new key vault, keeping the protection an existing
+// vault uses
 func NewKeyVault(filePath string) (*KeyVault, error) {
+	return NewKeyVaultWithOptions(filePath, VaultOptions{})
+}
+
+// NewKeyVaultWithOptions creates a new key vault protected as opts says. An
+// existing vault protected differently is re-encrypted, so changing
+// keys.backend migrates it.
+func NewKeyVaultWithOptions(filePath string, opts VaultOptions) (*KeyVault, error) {
+	if opts.Backend != "" {
+		if err := ValidateVaultBackend(opts.Backend); err != nil {
+			return nil, err
+		}
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
@@ -40,16 +70,31 @@ func NewKeyVault(filePath string) (*KeyVault, error) {
 
 	kv := &KeyVault{
 		filePath: filePath,
+		opts:     opts,
 	}
 
 	// Load or create vault
-	if err := kv.load(); err != nil {
+	if err := kv.withLock(kv.load); err != nil {
 		return nil, err
 	}
 
 	return kv, nil
 }
 
+// withLock runs fn while holding the vault's in-process and cross-process locks
+func (kv *KeyVault) withLock(fn func() error) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	lock, err := lockfile.Acquire(kv.filePath+".lock", vaultLockTimeout)
+	if err != nil {
+		return fmt.Errorf("key vault is busy: %w", err)
+	}
+	defer lock.Release()
+
+	return fn()
+}
+
 // Add adds a new license key
 func (kv *KeyVault) Add(label, key string) (string, error) {
 	// Use new validation
@@ -57,25 +102,32 @@ func (kv *KeyVault) Add(label, key string) (string, error) {
 		return "", err
 	}
 
-	// Check if key already exists
-	for _, existingKey := range kv.keys {
-		if existingKey.Key == key {
-			return "", fmt.Errorf("key already exists")
+	var id string
+	err := kv.withLock(func() error {
+		// Pick up keys added by other processes since we loaded
+		if err := kv.load(); err != nil {
+			return err
 		}
-	}
 
-	// Create new key entry
-	id := uuid.New().String()
-	licenseKey := LicenseKey{
-		ID:      id,
-		Label:   label,
-		Key:     key,
-		Created: time.Now(),
-	}
+		// Check if key already exists
+		for _, existingKey := range kv.keys {
+			if existingKey.Key == key {
+				return fmt.Errorf("key already exists")
+			}
+		}
 
-	kv.keys = append(kv.keys, licenseKey)
+		// Create new key entry
+		id = uuid.New().String()
+		kv.keys = append(kv.keys, LicenseKey{
+			ID:      id,
+			Label:   label,
+			Key:     key,
+			Created: time.Now(),
+		})
 
-	if err := kv.save(); err != nil {
+		return kv.save()
+	})
+	if err != nil {
 		return "", err
 	}
 
@@ -84,21 +136,31 @@ func (kv *KeyVault) Add(label, key string) (string, error) {
 
 // Remove removes a license key by ID
 func (kv *KeyVault) Remove(id string) error {
-	for i, key := range kv.keys {
-		if key.ID == id {
-			kv.keys = append(kv.keys[:i], kv.keys[i+1:]...)
-			return kv.save()
+	return kv.withLock(func() error {
+		if err := kv.load(); err != nil {
+			return err
+		}
+
+		for i, key := range kv.keys {
+			if key.ID == id {
+				kv.keys = append(kv.keys[:i], kv.keys[i+1:]...)
+				return kv.save()
+			}
 		}
-	}
 
-	return fmt.Errorf("key not found")
+		return fmt.Errorf("key not found")
+	})
 }
 
 // Get retrieves a license key by ID
 func (kv *KeyVault) Get(id string) (*LicenseKey, error) {
-	for i, key := range kv.keys {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	for _, key := range kv.keys {
 		if key.ID == id {
-			return &kv.keys[i], nil
+			k := key
+			return &k, nil
 		}
 	}
 
@@ -107,19 +169,39 @@ func (kv *KeyVault) Get(id string) (*LicenseKey, error) {
 
 // List returns all license keys (with masked keys for display)
 func (kv *KeyVault) List() []LicenseKey {
-	return kv.keys
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	keys := make([]LicenseKey, len(kv.keys))
+	copy(keys, kv.keys)
+	return keys
+}
+
+// Backend returns how the vault's encryption key is protected, one of VaultBackends
+func (kv *KeyVault) Backend() string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return kv.mode
 }
 
 // Count returns the number of stored keys
 func (kv *KeyVault) Count() int {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
 	return len(kv.keys)
 }
 
-// load loads the vault from disk (encrypted)
+// load loads the vault from disk (encrypted). Caller must hold the locks.
 func (kv *KeyVault) load() error {
 	// If vault doesn't exist, create empty
 	if _, err := os.Stat(kv.filePath); os.IsNotExist(err) {
 		kv.keys = []LicenseKey{}
+		kv.mode = kv.opts.Backend
+		if kv.mode == "" {
+			kv.mode = VaultMachine
+		}
 		return kv.save()
 	}
 
@@ -129,9 +211,27 @@ func (kv *KeyVault) load() error {
 		return fmt.Errorf("failed to read vault: %w", err)
 	}
 
+	mode, kdf, body, err := splitVault(encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+	if mode != kv.mode || !kdf.equal(kv.kdf) {
+		kv.mode, kv.kdf, kv.passKey = mode, kdf, nil
+	}
+
 	// Decrypt
-	data, err := kv.decrypt(encrypted)
+	key, err := kv.encryptionKey(false)
 	if err != nil {
+		return fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	data, err := kv.decrypt(key, body)
+	switch {
+	case err != nil && mode == VaultPassphrase:
+		kv.passKey = nil
+		return fmt.Errorf("failed to decrypt vault: wrong passphrase")
+	case err != nil && mode == VaultMachine:
+		return fmt.Errorf("failed to decrypt vault (its key comes from the machine's name, was it renamed?): %w", err)
+	case err != nil:
 		return fmt.Errorf("failed to decrypt vault: %w", err)
 	}
 
@@ -142,10 +242,43 @@ func (kv *KeyVault) load() error {
 	}
 
 	kv.keys = keys
+
+	if backend := kv.opts.Backend; backend != "" && backend != mode {
+		if err := kv.migrate(backend); err != nil {
+			return fmt.Errorf("failed to move vault to %s protection: %w", backend, err)
+		}
+	}
+	return nil
+}
+
+// migrate re-encrypts the vault with backend's protection. Caller must hold
+// the locks.
+func (kv *KeyVault) migrate(backend string) error {
+	prev, prevKDF, prevPassKey := kv.mode, kv.kdf, kv.passKey
+
+	// A vault that fell back to a passphrase stays on it until there's a keychain
+	if backend == VaultKeychain && prev == VaultPassphrase {
+		if _, err := kv.keychainKey(true); errors.Is(err, ErrKeychainUnavailable) {
+			return nil
+		}
+	}
+
+	kv.mode = backend
+	if backend == VaultPassphrase {
+		kv.kdf, kv.passKey = nil, nil
+	}
+	if err := kv.save(); err != nil {
+		kv.mode, kv.kdf, kv.passKey = prev, prevKDF, prevPassKey
+		return err
+	}
+
+	if prev == VaultKeychain && kv.mode != VaultKeychain {
+		kv.forgetKeychainKey()
+	}
 	return nil
 }
 
-// save saves the vault to disk (encrypted)
+// save saves the vault to disk (encrypted). Caller must hold the locks.
 func (kv *KeyVault) save() error {
 	// Marshal to JSON
 	data, err := json.MarshalIndent(kv.keys, "", "  ")
@@ -153,14 +286,24 @@ func (kv *KeyVault) save() error {
 		return fmt.Errorf("failed to marshal vault: %w", err)
 	}
 
-	// Encrypt
-	encrypted, err := kv.encrypt(data)
+	// Encrypt, making a key first if the vault doesn't have one yet
+	key, err := kv.encryptionKey(true)
+	if err != nil {
+		return fmt.Errorf("failed to lock vault: %w", err)
+	}
+	body, err := kv.encrypt(key, data)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt vault: %w", err)
 	}
+	encrypted := joinVault(kv.mode, kv.kdf, body)
 
-	// Write to file
-	if err := os.WriteFile(kv.filePath, encrypted, 0600); err != nil {
+	// Write to a temp file and rename so readers never see a partial vault
+	tmpPath := kv.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, encrypted, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(tmpPath, kv.filePath); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write vault: %w", err)
 	}
 
@@ -168,9 +311,7 @@ func (kv *KeyVault) save() error {
 }
 
 // encrypt encrypts data using AES-256-GCM
-func (kv *KeyVault) encrypt(plaintext []byte) ([]byte, error) {
-	key := kv.getMachineKey()
-
+func (kv *KeyVault) encrypt(key, plaintext []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -191,9 +332,7 @@ func (kv *KeyVault) encrypt(plaintext []byte) ([]byte, error) {
 }
 
 // decrypt decrypts data using AES-256-GCM
-func (kv *KeyVault) decrypt(ciphertext []byte) ([]byte, error) {
-	key := kv.getMachineKey()
-
+func (kv *KeyVault) decrypt(key, ciphertext []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -218,7 +357,8 @@ func (kv *KeyVault) decrypt(ciphertext []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
-// getMachineKey derives a machine-specific encryption key
+// getMachineKey derives a machine-specific encryption key (VaultMachine).
+// It's weak and changes with the hostname; keys.backend offers better.
 func (kv *KeyVault) getMachineKey() []byte {
 	// Get machine ID (hostname for simplicity)
 	hostname, _ := os.Hostname()
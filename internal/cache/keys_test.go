@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestKeyVaultConcurrentAdd adds keys from several goroutines, half through a
+// shared vault and half through vaults of their own on the same file, as
+// separate processes would, and checks none are lost
+func TestKeyVaultConcurrentAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	shared, err := NewKeyVault(path)
+	if err != nil {
+		t.Fatalf("NewKeyVault: %v", err)
+	}
+
+	const writers, perWriter = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			vault := shared
+			if w%2 == 1 {
+				own, err := NewKeyVault(path)
+				if err != nil {
+					errs <- err
+					return
+				}
+				vault = own
+			}
+			for i := 0; i < perWriter; i++ {
+				key := fmt.Sprintf("cfxk_concurrenttest%02d%02d", w, i)
+				if _, err := vault.Add(fmt.Sprintf("writer %d key %d", w, i), key); err != nil {
+					errs <- fmt.Errorf("add %s: %w", key, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	reopened, err := NewKeyVault(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	keys := reopened.List()
+	if len(keys) != writers*perWriter {
+		t.Fatalf("vault has %d keys, want %d", len(keys), writers*perWriter)
+	}
+
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if seen[k.Key] {
+			t.Errorf("key %s stored twice", k.Key)
+		}
+		seen[k.Key] = true
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < perWriter; i++ {
+			if key := fmt.Sprintf("cfxk_concurrenttest%02d%02d", w, i); !seen[key] {
+				t.Errorf("key %s was lost", key)
+			}
+		}
+	}
+}
+
+func TestKeyVaultPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	opts := VaultOptions{
+		Backend:    VaultPassphrase,
+		Passphrase: func(bool) (string, error) { return "correct horse", nil },
+	}
+
+	vault, err := NewKeyVaultWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("NewKeyVaultWithOptions: %v", err)
+	}
+	if _, err := vault.Add("main", "cfxk_passphrasetest01"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode, kdf, _, err := splitVault(data)
+	if err != nil || mode != VaultPassphrase || kdf == nil || kdf.Time != kdfTime || kdf.Memory != kdfMemory {
+		t.Fatalf("vault header = %s %+v (%v), want passphrase mode with the Argon2id parameters", mode, kdf, err)
+	}
+
+	reopened, err := NewKeyVaultWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if keys := reopened.List(); len(keys) != 1 || keys[0].Key != "cfxk_passphrasetest01" {
+		t.Fatalf("reopened vault has %+v", keys)
+	}
+
+	wrong := VaultOptions{Passphrase: func(bool) (string, error) { return "wrong", nil }}
+	if _, err := NewKeyVaultWithOptions(path, wrong); err == nil {
+		t.Fatal("vault opened with the wrong passphrase")
+	}
+}
+
+func TestKeyExportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	vault, err := NewKeyVault(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("NewKeyVault: %v", err)
+	}
+	if _, err := vault.Add("main", "cfxk_exportroundtrip01"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	exportPath := filepath.Join(dir, "keys.export")
+	if n, err := vault.Export(exportPath, "correct horse"); err != nil || n != 1 {
+		t.Fatalf("Export = %d, %v", n, err)
+	}
+
+	// The parameters sit at the top level of the file, as before
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header map[string]any
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"kdf", "time", "memory", "threads", "salt", "data"} {
+		if _, ok := header[field]; !ok {
+			t.Errorf("export has no %q field", field)
+		}
+	}
+
+	keys, err := ReadExport(exportPath, "correct horse")
+	if err != nil || len(keys) != 1 || keys[0].Key != "cfxk_exportroundtrip01" {
+		t.Fatalf("ReadExport = %+v, %v", keys, err)
+	}
+	if _, err := ReadExport(exportPath, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("ReadExport with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+}
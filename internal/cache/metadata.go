@@ -15,10 +15,15 @@ type CachedBuild struct {
 	Number      int       `json:"number"`
 	Hash        string    `json:"hash"`
 	Downloaded  time.Time `json:"downloaded"`
-	Size        int64     `json:"size"`
+	Size        int64     `json:"size"` // Bytes on disk, archive and extracted files
 	Recommended bool      `json:"recommended"`
 	Optional    bool      `json:"optional"`
 	LastUsed    time.Time `json:"last_used"`
+
+	// Checksums taken when the build was added, empty for older cache entries
+	Archive    string `json:"archive,omitempty"`     // Archive file name in the build directory
+	SHA256     string `json:"sha256,omitempty"`      // Of the archive
+	TreeSHA256 string `json:"tree_sha256,omitempty"` // Of the extracted files
 }
 
 // CacheStats represents cache statistics
@@ -26,4 +31,5 @@ type CacheStats struct {
 	TotalBuilds int
 	TotalSize   int64
 	MaxBuilds   int
+	MaxSize     int64 // Bytes, 0 for no limit
 }
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Ways the vault's encryption key is protected (keys.backend)
+const (
+	VaultMachine    = "machine"    // Derived from the hostname and vault path, the original scheme
+	VaultKeychain   = "keychain"   // Random key kept in the OS keychain (Credential Manager, Keychain, Secret Service)
+	VaultPassphrase = "passphrase" // Derived from a passphrase
+)
+
+// VaultBackends lists the accepted keys.backend values
+var VaultBackends = []string{VaultMachine, VaultKeychain, VaultPassphrase}
+
+// ErrKeychainUnavailable is returned when the OS keychain can't be reached,
+// e.g. on a Linux server without a Secret Service
+var ErrKeychainUnavailable = errors.New("OS keychain unavailable")
+
+// VaultOptions controls how a KeyVault protects its encryption key
+type VaultOptions struct {
+	// Backend is the protection the vault is saved with, one of
+	// VaultBackends. A vault protected differently is re-encrypted when it's
+	// opened. "" keeps what the vault uses (VaultMachine for a new one).
+	Backend string
+
+	// Passphrase, if set, is asked for the passphrase of a VaultPassphrase
+	// vault; confirm is true when a new one is being chosen. It's also how
+	// VaultKeychain falls back to a passphrase where there's no keychain.
+	Passphrase func(confirm bool) (string, error)
+}
+
+// keychainService is the service name the vault key is stored under in the
+// OS keychain; the account is the vault's path
+const keychainService = "inkwash"
+
+// Vault file header for the keychain and passphrase modes. Machine mode
+// vaults have no header, as before. Passphrase mode is followed by the
+// Argon2id parameters, as key exports store them.
+const (
+	vaultMagic        = "IWV2"
+	vaultModeKeychain = 'k'
+	vaultModePass     = 'p'
+)
+
+// ValidateVaultBackend checks that backend is one of VaultBackends
+func ValidateVaultBackend(backend string) error {
+	for _, b := range VaultBackends {
+		if backend == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key vault backend %q (expected machine, keychain or passphrase)", backend)
+}
+
+// splitVault returns the protection, passphrase parameters and encrypted body of a vault file
+func splitVault(data []byte) (mode string, kdf *passphraseKDF, body []byte, err error) {
+	if !bytes.HasPrefix(data, []byte(vaultMagic)) || len(data) < len(vaultMagic)+1 {
+		return VaultMachine, nil, data, nil
+	}
+
+	rest := data[len(vaultMagic)+1:]
+	switch data[len(vaultMagic)] {
+	case vaultModeKeychain:
+		return VaultKeychain, nil, rest, nil
+	case vaultModePass:
+		kdf, err := parseKDF(rest)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("vault is truncated")
+		}
+		return VaultPassphrase, kdf, rest[kdfHeaderSize:], nil
+	}
+	return "", nil, nil, fmt.Errorf("vault was written by a newer inkwash")
+}
+
+// joinVault prepends the header for mode to an encrypted body
+func joinVault(mode string, kdf *passphraseKDF, body []byte) []byte {
+	switch mode {
+	case VaultKeychain:
+		return append([]byte(vaultMagic+string(rune(vaultModeKeychain))), body...)
+	case VaultPassphrase:
+		header := append([]byte(vaultMagic+string(rune(vaultModePass))), kdf.marshalBinary()...)
+		return append(header, body...)
+	}
+	return body
+}
+
+// encryptionKey returns the AES key for the vault's current mode. With
+// create, a key that doesn't exist yet (a new keychain entry or passphrase)
+// is made; a keychain that can't be reached then falls back to a passphrase
+// if one can be asked for.
+func (kv *KeyVault) encryptionKey(create bool) ([]byte, error) {
+	switch kv.mode {
+	case VaultKeychain:
+		key, err := kv.keychainKey(create)
+		if errors.Is(err, ErrKeychainUnavailable) && create && kv.opts.Passphrase != nil {
+			kv.mode, kv.kdf = VaultPassphrase, nil
+			return kv.passphraseKey(true)
+		}
+		return key, err
+	case VaultPassphrase:
+		return kv.passphraseKey(create && kv.kdf == nil)
+	default:
+		return kv.getMachineKey(), nil
+	}
+}
+
+// keychainKey reads the vault key from the OS keychain, storing a new random
+// one with create if there's none
+func (kv *KeyVault) keychainKey(create bool) ([]byte, error) {
+	if kv.keychain != nil {
+		return kv.keychain, nil
+	}
+
+	secret, err := keyring.Get(keychainService, kv.filePath)
+	switch {
+	case err == nil:
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("vault key in the OS keychain is corrupt")
+		}
+		kv.keychain = key
+		return key, nil
+
+	case errors.Is(err, keyring.ErrNotFound) && create:
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := keyring.Set(keychainService, kv.filePath, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeychainUnavailable, err)
+		}
+		kv.keychain = key
+		return key, nil
+
+	case errors.Is(err, keyring.ErrNotFound):
+		return nil, fmt.Errorf("vault key not found in the OS keychain")
+	}
+	return nil, fmt.Errorf("%w: %v", ErrKeychainUnavailable, err)
+}
+
+// passphraseKey derives the vault key from the passphrase with kv.kdf. With
+// create, new parameters are made and the passphrase is asked for with
+// confirmation.
+func (kv *KeyVault) passphraseKey(create bool) ([]byte, error) {
+	if kv.passKey != nil && !create {
+		return kv.passKey, nil
+	}
+	if kv.opts.Passphrase == nil {
+		return nil, fmt.Errorf("key vault is protected by a passphrase, but none was given")
+	}
+
+	passphrase, err := kv.opts.Passphrase(create)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase can't be empty")
+	}
+
+	if create {
+		if kv.kdf, err = newPassphraseKDF(); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := kv.kdf.key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	kv.passKey = key
+	return key, nil
+}
+
+// forgetKeychainKey removes the vault key from the OS keychain once the vault
+// no longer uses it. It's best-effort: a leftover entry can't decrypt anything.
+func (kv *KeyVault) forgetKeychainKey() {
+	keyring.Delete(keychainService, kv.filePath)
+	kv.keychain = nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// Issue describes a problem with a config value
+type Issue struct {
+	Key     string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Key, i.Message)
+}
+
+// keyKind is the expected type of a config value
+type keyKind int
+
+const (
+	kindString keyKind = iota
+	kindInt
+	kindBool
+)
+
+// keySpec describes a known config key
+type keySpec struct {
+	kind    keyKind
+	min     int      // Inclusive lower bound for ints
+	max     int      // Inclusive upper bound for ints (0 = no limit)
+	allowed []string // Allowed values for strings (nil = any)
+}
+
+// knownKeys lists every key inkwash reads from config.yaml
+var knownKeys = map[string]keySpec{
+	"defaults.install_path":       {kind: kindString},
+	"defaults.port":               {kind: kindInt, min: 1, max: 65535},
+	"defaults.bind_address":       {kind: kindString},
+	"defaults.target_os":          {kind: kindString, allowed: []string{"windows", "linux"}},
+	"cache.enabled":               {kind: kindBool},
+	"cache.max_builds":            {kind: kindInt, min: 0},
+	"cache.max_size_gb":           {kind: kindInt, min: 0},
+	"cache.verify":                {kind: kindBool},
+	"ui.theme":                    {kind: kindString, allowed: []string{"purple"}},
+	"ui.animations":               {kind: kindString, allowed: []string{"auto", "full", "balanced", "minimal"}},
+	"ui.refresh_interval":         {kind: kindInt, min: 1},
+	"telemetry.enabled":           {kind: kindBool},
+	"advanced.parallel_downloads": {kind: kindBool},
+	"advanced.download_chunks":    {kind: kindInt, min: 1, max: 16},
+	"advanced.long_paths":         {kind: kindBool},
+	"advanced.log_level":          {kind: kindString, allowed: []string{"debug", "info", "warn", "error"}},
+	"convert.timeout":             {kind: kindInt, min: 1},
+	"convert.layout":              {kind: kindString, allowed: []string{"category", "mod", "flat"}},
+	"convert.auto_ensure":         {kind: kindBool},
+	"convert.ensure_file":         {kind: kindString, allowed: []string{"server.cfg", "resources.cfg"}},
+	"convert.max_concurrent":      {kind: kindInt, min: 1, max: 10},
+	"convert.poll_interval":       {kind: kindInt, min: 1},
+	"network.timeout":             {kind: kindInt, min: 1},
+	"network.download_timeout":    {kind: kindInt, min: 0},
+	"network.retries":             {kind: kindInt, min: 0, max: 10},
+	"network.retry_backoff":       {kind: kindInt, min: 1},
+	"network.retry_max_backoff":   {kind: kindInt, min: 1},
+	"network.retry_jitter":        {kind: kindInt, min: 0, max: 100},
+	"start.wait_timeout":          {kind: kindInt, min: 1},
+	"daemon.interval":             {kind: kindInt, min: 1},
+	"daemon.max_restarts":         {kind: kindInt, min: 0},
+	"daemon.restart_window":       {kind: kindInt, min: 1},
+	"web.listen":                  {kind: kindString},
+	"web.token":                   {kind: kindString},
+	"api.listen":                  {kind: kindString},
+	"api.token":                   {kind: kindString},
+	"keys.backend":                {kind: kindString, allowed: []string{"machine", "keychain", "passphrase"}},
+	"debug":                       {kind: kindBool},
+}
+
+// Keys returns every known config key, sorted
+func Keys() []string {
+	keys := make([]string, 0, len(knownKeys))
+	for key := range knownKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sensitiveWords mark a config key or convar whose value is a secret when
+// one of its words is one of them, e.g. web.token, rcon_password or
+// sv_licenseKey
+var sensitiveWords = map[string]bool{"key": true, "token": true, "secret": true, "password": true, "passphrase": true}
+
+// IsSensitive reports whether a key's value should be masked when displayed.
+// Whole words are compared, so keys.backend isn't masked for containing "key".
+func IsSensitive(key string) bool {
+	words := splitWords(key)
+	for i, word := range words {
+		if sensitiveWords[word] || word == "connection" && i+1 < len(words) && words[i+1] == "string" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitWords splits a config key or convar name into lowercase words at
+// dots, underscores, dashes and camelCase humps
+func splitWords(name string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '.' || r == '_' || r == '-':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		word.WriteRune(r)
+	}
+	flush()
+	return words
+}
+
+// LoadFile reads a config file on its own, without defaults, env or flags
+func LoadFile(path string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// Validate checks every value set in v against the known keys.
+// Issues are sorted by key.
+func Validate(v *viper.Viper) []Issue {
+	var issues []Issue
+
+	for _, key := range v.AllKeys() {
+		spec, ok := knownKeys[key]
+		if !ok {
+			issues = append(issues, Issue{Key: key, Message: "unknown key"})
+			continue
+		}
+
+		if msg := checkValue(spec, v.Get(key)); msg != "" {
+			issues = append(issues, Issue{Key: key, Message: msg})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Key < issues[j].Key
+	})
+
+	return issues
+}
+
+// checkValue returns a description of what's wrong with value, or "" if it's valid
+func checkValue(spec keySpec, value interface{}) string {
+	switch spec.kind {
+	case kindInt:
+		n, ok := toInt(value)
+		if !ok {
+			return fmt.Sprintf("expected a number, got %q", fmt.Sprint(value))
+		}
+		if n < spec.min {
+			return fmt.Sprintf("must be at least %d (got %d)", spec.min, n)
+		}
+		if spec.max > 0 && n > spec.max {
+			return fmt.Sprintf("must be at most %d (got %d)", spec.max, n)
+		}
+
+	case kindBool:
+		if _, ok := value.(bool); !ok {
+			if _, err := strconv.ParseBool(fmt.Sprint(value)); err != nil {
+				return fmt.Sprintf("expected true or false, got %q", fmt.Sprint(value))
+			}
+		}
+
+	case kindString:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string, got %q", fmt.Sprint(value))
+		}
+		if spec.allowed != nil && !contains(spec.allowed, strings.ToLower(s)) {
+			return fmt.Sprintf("must be one of %s (got %q)", strings.Join(spec.allowed, ", "), s)
+		}
+	}
+
+	return ""
+}
+
+// toInt converts YAML/env numeric values to an int
+func toInt(value interface{}) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n != float64(int(n)) {
+			return 0, false
+		}
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(n))
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestIsSensitive(t *testing.T) {
+	tests := map[string]bool{
+		"web.token":               true,
+		"api.token":               true,
+		"sv_licenseKey":           true,
+		"steam_webApiKey":         true,
+		"rcon_password":           true,
+		"sv_tebexSecret":          true,
+		"mysql_connection_string": true,
+		"keys.backend":            false,
+		"cache.max_builds":        false,
+		"sv_hostname":             false,
+		"sv_enforceGameBuild":     false,
+	}
+	for key, want := range tests {
+		if got := IsSensitive(key); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package convert
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,8 +11,14 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/network"
 )
 
+// ErrFileExpired is returned when a converted file is no longer available for download
+var ErrFileExpired = errors.New("converted file has expired")
+
 // ConversionStatus represents the status of a mod conversion
 type ConversionStatus struct {
 	Progress int    `json:"progress"`
@@ -28,22 +36,93 @@ type ConvertResponse struct {
 
 // Client handles GTA5 mod conversion via convert.cfx.rs
 type Client struct {
-	httpClient *http.Client
+	httpClient *http.Client  // Bounded by network.download_timeout; API calls also by timeout
+	timeout    time.Duration // Per-request timeout of API calls
 	baseURL    string
+	retry      network.RetryPolicy
+	onRetry    RetryFunc
 }
 
-// NewClient creates a new conversion client
+// Requests reported to a RetryFunc
+const (
+	RequestStart    = "start"
+	RequestQuery    = "query"
+	RequestDownload = "download"
+)
+
+// RetryFunc is told about a failed request before it's tried again
+type RetryFunc func(request string, attempt int, err error, wait time.Duration)
+
+// DefaultTimeout is the per-request timeout used by NewClient
+const DefaultTimeout = 30 * time.Second
+
+// NewClient creates a new conversion client using the network API timeout
 func NewClient() *Client {
+	return NewClientWithTimeout(network.APITimeout())
+}
+
+// NewClientWithTimeout creates a conversion client with a custom per-request
+// timeout for API calls. Converted files are downloaded under the larger
+// network.download_timeout instead. Failed requests are retried under
+// network.Retry().
+func NewClientWithTimeout(timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL: "https://convert.cfx.rs",
+		httpClient: network.NewDownloadClient(),
+		timeout:    timeout,
+		baseURL:    "https://convert.cfx.rs",
+		retry:      network.Retry(),
+	}
+}
+
+// SetRetryPolicy replaces the policy failed requests are retried under
+func (c *Client) SetRetryPolicy(p network.RetryPolicy) {
+	c.retry = p
+}
+
+// OnRetry sets a function told about each failed request before it's tried again
+func (c *Client) OnRetry(fn RetryFunc) {
+	c.onRetry = fn
+}
+
+// do runs one of the client's requests, retrying transient failures
+func (c *Client) do(ctx context.Context, request string, retryable func(error) bool, fn func() error) error {
+	var onRetry network.RetryFunc
+	if c.onRetry != nil {
+		onRetry = func(attempt int, err error, wait time.Duration) {
+			c.onRetry(request, attempt, err, wait)
+		}
+	}
+	return c.retry.Do(ctx, retryable, onRetry, func(int) error { return fn() })
+}
+
+// requestContext derives the context of one API call, expiring after the
+// client's timeout the way network.RequestContext does
+func (c *Client) requestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, c.timeout)
+}
+
+// postForm sends a form POST that is aborted when ctx is cancelled
+func (c *Client) postForm(ctx context.Context, endpoint string, data url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.httpClient.Do(req)
 }
 
 // StartConversion initiates a mod conversion
 func (c *Client) StartConversion(modURL string) (string, error) {
+	return c.StartConversionContext(context.Background(), modURL)
+}
+
+// StartConversionContext initiates a mod conversion, honouring ctx cancellation
+func (c *Client) StartConversionContext(ctx context.Context, modURL string) (string, error) {
 	// Validate URL is from gta5-mods.com
 	if !strings.Contains(modURL, "gta5-mods.com") {
 		return "", fmt.Errorf("URL must be from gta5-mods.com")
@@ -54,21 +133,29 @@ func (c *Client) StartConversion(modURL string) (string, error) {
 	data.Set("url", modURL)
 	data.Set("lang", "en")
 
-	// Make POST request
-	resp, err := c.httpClient.PostForm(c.baseURL+"/api/convert", data)
-	if err != nil {
-		return "", fmt.Errorf("failed to start conversion: %w", err)
-	}
-	defer resp.Body.Close()
+	var result ConvertResponse
+	err := c.do(ctx, RequestStart, nil, func() error {
+		// Make POST request
+		reqCtx, cancel := c.requestContext(ctx)
+		defer cancel()
+		resp, err := c.postForm(reqCtx, "/api/convert", data)
+		if err != nil {
+			return fmt.Errorf("failed to start conversion: %w", err)
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+		if resp.StatusCode != http.StatusOK {
+			return network.NewStatusError(resp)
+		}
 
-	// Parse response
-	var result ConvertResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		// Parse response
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	if result.Status != 200 {
@@ -80,31 +167,78 @@ func (c *Client) StartConversion(modURL string) (string, error) {
 
 // QueryProgress checks the progress of a conversion
 func (c *Client) QueryProgress(uuid string) (*ConversionStatus, error) {
+	return c.QueryProgressContext(context.Background(), uuid)
+}
+
+// QueryProgressContext checks the progress of a conversion, honouring ctx cancellation
+func (c *Client) QueryProgressContext(ctx context.Context, uuid string) (*ConversionStatus, error) {
 	// Prepare form data
 	data := url.Values{}
 	data.Set("uuid", uuid)
 	data.Set("lang", "en")
 
-	// Make POST request
-	resp, err := c.httpClient.PostForm(c.baseURL+"/api/query", data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query progress: %w", err)
-	}
-	defer resp.Body.Close()
+	var status ConversionStatus
+	err := c.do(ctx, RequestQuery, nil, func() error {
+		// Make POST request
+		reqCtx, cancel := c.requestContext(ctx)
+		defer cancel()
+		resp, err := c.postForm(reqCtx, "/api/query", data)
+		if err != nil {
+			return fmt.Errorf("failed to query progress: %w", err)
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+		if resp.StatusCode != http.StatusOK {
+			return network.NewStatusError(resp)
+		}
 
-	// Parse response
-	var status ConversionStatus
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		// Parse response
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &status, nil
 }
 
+// WaitForConversion polls a conversion until it completes or the timeout elapses
+func (c *Client) WaitForConversion(uuid string, interval, timeout time.Duration) (*ConversionStatus, error) {
+	return c.WaitForConversionContext(context.Background(), uuid, interval, timeout)
+}
+
+// WaitForConversionContext polls a conversion until it completes, the timeout
+// elapses, or ctx is cancelled
+func (c *Client) WaitForConversionContext(ctx context.Context, uuid string, interval, timeout time.Duration) (*ConversionStatus, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		status, err := c.QueryProgressContext(ctx, uuid)
+		if err == nil && status.Progress >= 100 {
+			if status.File == "" {
+				return nil, fmt.Errorf("conversion finished without a file: %s", status.Message)
+			}
+			return status, nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return nil, fmt.Errorf("conversion timed out: %w", err)
+			}
+			return nil, fmt.Errorf("conversion timed out at %d%%", status.Progress)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 // GetDownloadURL returns the download URL for a converted file
 func (c *Client) GetDownloadURL(file string) string {
 	return c.baseURL + "/" + file
@@ -112,14 +246,38 @@ func (c *Client) GetDownloadURL(file string) string {
 
 // DownloadFile downloads a converted file to the specified path
 func (c *Client) DownloadFile(fileURL, destPath string) error {
-	resp, err := c.httpClient.Get(fileURL)
+	return c.DownloadFileContext(context.Background(), fileURL, destPath)
+}
+
+// DownloadFileContext downloads a converted file, honouring ctx cancellation.
+// Failed and short downloads are retried from the start.
+func (c *Client) DownloadFileContext(ctx context.Context, fileURL, destPath string) error {
+	retryable := func(err error) bool {
+		return errors.Is(err, download.ErrIncomplete) || network.Temporary(err)
+	}
+	return c.do(ctx, RequestDownload, retryable, func() error {
+		return c.downloadFile(ctx, fileURL, destPath)
+	})
+}
+
+// downloadFile makes a single attempt at DownloadFileContext
+func (c *Client) downloadFile(ctx context.Context, fileURL, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrFileExpired
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return network.NewStatusError(resp)
 	}
 
 	// Create destination file
@@ -130,9 +288,15 @@ func (c *Client) DownloadFile(fileURL, destPath string) error {
 	defer out.Close()
 
 	// Copy content
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	written, err := io.Copy(out, resp.Body)
+	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
+	// Verify against Content-Length so a truncated transfer isn't extracted
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("%w: received %d of %d bytes", download.ErrIncomplete, written, resp.ContentLength)
+	}
+
 	return nil
 }
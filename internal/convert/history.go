@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+)
+
+// HistoryEntry records a completed conversion
+type HistoryEntry struct {
+	URL           string    `json:"url"`
+	UUID          string    `json:"uuid"`
+	File          string    `json:"file"`
+	Category      string    `json:"category"`
+	ResourcesPath string    `json:"resources_path"`
+	ConvertedAt   time.Time `json:"converted_at"`
+}
+
+// History is a JSON-backed log of completed conversions
+type History struct {
+	path    string
+	entries []HistoryEntry
+	mu      sync.Mutex
+}
+
+// GetHistoryPath returns the path to the convert history file
+func GetHistoryPath() string {
+	return filepath.Join(registry.GetDefaultDataPath(), "convert_history.json")
+}
+
+// LoadHistory loads the history file, returning an empty history if it doesn't exist
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read convert history: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse convert history: %w", err)
+	}
+
+	return h, nil
+}
+
+// Record adds or replaces the entry for a URL and saves the history
+func (h *History) Record(entry HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry.ConvertedAt.IsZero() {
+		entry.ConvertedAt = time.Now()
+	}
+
+	// Keep one entry per URL so redownload indices stay stable
+	for i, existing := range h.entries {
+		if existing.URL == entry.URL {
+			h.entries[i] = entry
+			return h.save()
+		}
+	}
+
+	h.entries = append(h.entries, entry)
+	return h.save()
+}
+
+// List returns a copy of all entries, oldest first
+func (h *History) List() []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]HistoryEntry, len(h.entries))
+	copy(entries, h.entries)
+	return entries
+}
+
+// Find looks up an entry by 1-based index (as shown by `convert history`) or URL
+func (h *History) Find(ref string) (*HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 1 || index > len(h.entries) {
+			return nil, fmt.Errorf("no history entry #%d", index)
+		}
+		entry := h.entries[index-1]
+		return &entry, nil
+	}
+
+	for _, entry := range h.entries {
+		if entry.URL == ref {
+			e := entry
+			return &e, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no history entry for %s", ref)
+}
+
+// MovePaths points entries installed under oldRoot at the same place under
+// newRoot, after a server folder is moved. Returns how many were changed.
+func (h *History) MovePaths(oldRoot, newRoot string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	changed := 0
+	for i, entry := range h.entries {
+		rel, err := filepath.Rel(oldRoot, entry.ResourcesPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		h.entries[i].ResourcesPath = filepath.Join(newRoot, rel)
+		changed++
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, h.save()
+}
+
+// save writes the history to disk (caller must hold the lock)
+func (h *History) save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal convert history: %w", err)
+	}
+
+	if err := os.WriteFile(h.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write convert history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package convert
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+)
+
+// Report is what Inspect found in an extracted mod
+type Report struct {
+	Vehicles  []string // Spawn names from vehicles.meta files, e.g. for /car
+	Generated []string // Folders given an fxmanifest.lua, relative to the mod ("" for its root)
+	Problems  []string // Data files a manifest doesn't reference
+}
+
+// Warnings returns the generated manifests and problems as sentences
+func (r *Report) Warnings() []string {
+	var warnings []string
+	for _, dir := range r.Generated {
+		if dir == "" {
+			warnings = append(warnings, "Generated a missing fxmanifest.lua")
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Generated a missing fxmanifest.lua in %s/", dir))
+		}
+	}
+	return append(warnings, r.Problems...)
+}
+
+// dataFileTypes maps meta file names (by prefix) to the data_file type
+// FXServer loads them as. More specific prefixes come first.
+var dataFileTypes = []struct {
+	prefix   string
+	fileType string
+}{
+	{"handling", "HANDLING_FILE"},
+	{"vehiclelayouts", "VEHICLE_LAYOUTS_FILE"},
+	{"vehicles", "VEHICLE_METADATA_FILE"},
+	{"carcols", "CARCOLS_FILE"},
+	{"carvariations", "VEHICLE_VARIATION_FILE"},
+	{"dlctext", "DLCTEXT_FILE"},
+	{"weaponarchetypes", "WEAPON_METADATA_FILE"},
+	{"weaponanimations", "WEAPON_ANIMATIONS_FILE"},
+	{"weapon", "WEAPONINFO_FILE"},
+	{"pedpersonality", "PED_PERSONALITY_FILE"},
+	{"peds", "PED_METADATA_FILE"},
+}
+
+var (
+	modelNamePattern    = regexp.MustCompile(`(?i)<modelName>\s*([^<\s]+)\s*</modelName>`)
+	manifestPathPattern = regexp.MustCompile(`['"]([^'"]+)['"]`)
+)
+
+// Inspect checks a mod extracted to dir before it's installed. Folders that
+// stream assets or hold meta files but have no manifest get a generated
+// fxmanifest.lua, meta files a manifest doesn't reference are reported, and
+// the spawn names of the vehicles are collected.
+func Inspect(dir string) (*Report, error) {
+	report := &Report{}
+	if err := inspectDir(dir, dir, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// inspectDir inspects dir if it's a resource or looks like one, otherwise
+// the folders inside it
+func inspectDir(root, dir string, report *Report) error {
+	if manifestPath := resource.FindManifest(dir); manifestPath != "" {
+		return inspectResource(root, dir, manifestPath, report)
+	}
+
+	if looksLikeResource(dir) {
+		manifestPath := filepath.Join(dir, "fxmanifest.lua")
+		if err := os.WriteFile(manifestPath, []byte(generateManifest(dir)), 0644); err != nil {
+			return fmt.Errorf("failed to write fxmanifest.lua: %w", err)
+		}
+		rel, _ := filepath.Rel(root, dir)
+		if rel == "." {
+			rel = ""
+		}
+		report.Generated = append(report.Generated, filepath.ToSlash(rel))
+		return inspectResource(root, dir, manifestPath, report)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			if err := inspectDir(root, filepath.Join(dir, entry.Name()), report); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// looksLikeResource reports whether dir has a stream folder or meta files,
+// directly or in a data folder, as converted vehicles and weapons do
+func looksLikeResource(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		switch {
+		case entry.IsDir() && name == "stream":
+			return true
+		case entry.IsDir() && name == "data":
+			if len(metaFiles(filepath.Join(dir, entry.Name()))) > 0 {
+				return true
+			}
+		case !entry.IsDir() && filepath.Ext(name) == ".meta":
+			return true
+		}
+	}
+	return false
+}
+
+// inspectResource reports the meta files of a resource its manifest doesn't
+// reference and collects its vehicles
+func inspectResource(root, dir, manifestPath string, report *Report) error {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var patterns []*regexp.Regexp
+	for _, match := range manifestPathPattern.FindAllStringSubmatch(string(data), -1) {
+		patterns = append(patterns, globPattern(match[1]))
+	}
+
+	for _, rel := range metaFiles(dir) {
+		if dataFileType(rel) == "" {
+			continue
+		}
+
+		referenced := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(rel) {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			shown, _ := filepath.Rel(root, filepath.Join(dir, filepath.FromSlash(rel)))
+			report.Problems = append(report.Problems, fmt.Sprintf("%s isn't referenced in %s, so it won't load",
+				filepath.ToSlash(shown), filepath.Base(manifestPath)))
+		}
+
+		if dataFileType(rel) == "VEHICLE_METADATA_FILE" {
+			meta, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+			if err != nil {
+				continue
+			}
+			for _, match := range modelNamePattern.FindAllSubmatch(meta, -1) {
+				report.addVehicle(string(match[1]))
+			}
+		}
+	}
+	return nil
+}
+
+// addVehicle adds a spawn name unless it's already listed
+func (r *Report) addVehicle(name string) {
+	for _, existing := range r.Vehicles {
+		if strings.EqualFold(existing, name) {
+			return
+		}
+	}
+	r.Vehicles = append(r.Vehicles, name)
+}
+
+// metaFiles returns the .meta files under dir, relative to it with forward
+// slashes, sorted
+func metaFiles(dir string) []string {
+	var files []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".meta") {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// dataFileType returns the data_file type of a meta file, or "" if it's not
+// one FXServer needs declared
+func dataFileType(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	for _, t := range dataFileTypes {
+		if strings.HasPrefix(name, t.prefix) {
+			return t.fileType
+		}
+	}
+	return ""
+}
+
+// globPattern turns a manifest path, which may use * and ** globs, into a
+// case-insensitive regular expression matching whole relative paths
+func globPattern(glob string) *regexp.Regexp {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "./")
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// generateManifest writes an fxmanifest.lua for a converted mod that came
+// without one. The stream folder is loaded by FXServer on its own; meta files
+// need to be listed and declared as data files.
+func generateManifest(dir string) string {
+	var b strings.Builder
+	b.WriteString("-- Generated by inkwash for a converted mod that had no manifest\n")
+	b.WriteString("fx_version 'cerulean'\n")
+	b.WriteString("game 'gta5'\n")
+
+	// Names that would need escaping in Lua are left out
+	var files []string
+	for _, file := range metaFiles(dir) {
+		if !strings.ContainsAny(file, `'\`) {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\nfiles {\n")
+	for _, file := range files {
+		fmt.Fprintf(&b, "    '%s',\n", file)
+	}
+	b.WriteString("}\n\n")
+
+	for _, file := range files {
+		if fileType := dataFileType(file); fileType != "" {
+			fmt.Fprintf(&b, "data_file '%s' '%s'\n", fileType, file)
+		}
+	}
+	return b.String()
+}
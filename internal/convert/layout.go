@@ -0,0 +1,179 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/staging"
+)
+
+// Layouts for where converted mods are installed (convert.layout)
+const (
+	LayoutCategory = "category" // resources/[category]/<mod>/
+	LayoutMod      = "mod"      // resources/<mod>/
+	LayoutFlat     = "flat"     // resources/, as the archive is laid out
+)
+
+// DefaultLayout is used when convert.layout isn't set
+const DefaultLayout = LayoutCategory
+
+// Layouts lists the accepted convert.layout values
+var Layouts = []string{LayoutCategory, LayoutMod, LayoutFlat}
+
+// unsafeFolderChars matches anything that shouldn't end up in a resource folder name
+var unsafeFolderChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ValidateLayout checks that layout is one of Layouts
+func ValidateLayout(layout string) error {
+	for _, l := range Layouts {
+		if layout == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown layout %q (expected %s)", layout, strings.Join(Layouts, ", "))
+}
+
+// ModFolderName returns a folder name for a mod from its gta5-mods.com URL,
+// e.g. ".../vehicles/1995-mclaren-f1-lm-addon" -> "1995-mclaren-f1-lm-addon"
+func ModFolderName(modURL string) string {
+	slug := modURL
+	if u, err := url.Parse(modURL); err == nil && u.Path != "" {
+		slug = path.Base(strings.TrimSuffix(u.Path, "/"))
+	}
+	return folderName(slug)
+}
+
+// folderName turns a mod's name into a safe folder name, "mod" if nothing's left
+func folderName(name string) string {
+	name = strings.Trim(unsafeFolderChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" || name == "." {
+		return "mod"
+	}
+	return name
+}
+
+// ModCategory returns the gta5-mods.com category of a mod URL,
+// e.g. "https://www.gta5-mods.com/vehicles/..." -> "vehicles"
+func ModCategory(modURL string) string {
+	parts := strings.Split(modURL, "/")
+	for i, part := range parts {
+		if part == "www.gta5-mods.com" || part == "gta5-mods.com" {
+			if i+1 < len(parts) && parts[i+1] != "" {
+				return parts[i+1]
+			}
+		}
+	}
+	return "misc"
+}
+
+// InstallDir returns the folder a mod is installed to under resourcesPath.
+// The flat layout has no folder of its own, so it returns resourcesPath.
+func InstallDir(resourcesPath, layout, category, modName string) string {
+	switch layout {
+	case LayoutMod:
+		return filepath.Join(resourcesPath, modName)
+	case LayoutFlat:
+		return resourcesPath
+	default:
+		if category == "" {
+			category = "misc"
+		}
+		return filepath.Join(resourcesPath, "["+category+"]", modName)
+	}
+}
+
+// Install moves a mod extracted to stagePath into resourcesPath following
+// layout and returns where it ended up and the names of the resources it
+// installed. An archive holding a single resource (possibly wrapped in one
+// folder) becomes the mod's folder; one holding several resources gets a
+// [mod] category folder so FXServer still finds them. Anything already at
+// the destination is replaced.
+func Install(stagePath, resourcesPath, layout, category, modName string) (string, []string, error) {
+	if layout == LayoutFlat {
+		// Only a bare resource needs a folder to live in
+		if resource.FindManifest(stagePath) != "" {
+			dest := filepath.Join(resourcesPath, modName)
+			return dest, []string{modName}, replaceDir(stagePath, dest)
+		}
+
+		// The entries are merged into resourcesPath, so list them while they're still apart
+		names := resourceNames(stagePath)
+		return resourcesPath, names, moveEntries(stagePath, resourcesPath)
+	}
+
+	content := unwrapSingleFolder(stagePath)
+	isResource := resource.FindManifest(content) != ""
+
+	dest := InstallDir(resourcesPath, layout, category, modName)
+	if !isResource {
+		dest = filepath.Join(filepath.Dir(dest), "["+modName+"]")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", nil, err
+	}
+	if err := replaceDir(content, dest); err != nil {
+		return "", nil, err
+	}
+	return dest, resourceNames(dest), nil
+}
+
+// resourceNames returns dir's name if it's a resource, otherwise the names of
+// the resources inside it
+func resourceNames(dir string) []string {
+	if resource.FindManifest(dir) != "" {
+		return []string{filepath.Base(dir)}
+	}
+
+	found, err := resource.Scan(dir)
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, len(found))
+	for i, res := range found {
+		names[i] = res.Name
+	}
+	return names
+}
+
+// unwrapSingleFolder returns the only entry of dir if it's a folder and dir
+// isn't a resource itself, otherwise dir
+func unwrapSingleFolder(dir string) string {
+	if resource.FindManifest(dir) != "" {
+		return dir
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
+
+// replaceDir moves src to dst, replacing whatever was at dst only once src is in place
+func replaceDir(src, dst string) error {
+	return staging.Swap(src, dst)
+}
+
+// moveEntries moves every entry of src into dst, replacing existing entries
+func moveEntries(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := replaceDir(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTree creates the given files (slash-separated, relative to root)
+func writeTree(t *testing.T, root string, files []string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("-- "+f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInstallLayouts(t *testing.T) {
+	// Archives as convert.cfx.rs produces them, extracted
+	wrapped := []string{"car_v2/fxmanifest.lua", "car_v2/stream/car.yft"}
+	bare := []string{"fxmanifest.lua", "stream/car.yft"}
+	several := []string{"pack/car_a/fxmanifest.lua", "pack/car_b/fxmanifest.lua"}
+
+	tests := []struct {
+		name      string
+		layout    string
+		archive   []string
+		wantDest  string // Relative to resources/
+		wantNames []string
+		wantFiles []string // Relative to resources/
+	}{
+		{
+			name: "category, single resource", layout: LayoutCategory, archive: wrapped,
+			wantDest:  "[vehicles]/car",
+			wantNames: []string{"car"},
+			wantFiles: []string{"[vehicles]/car/fxmanifest.lua", "[vehicles]/car/stream/car.yft"},
+		},
+		{
+			name: "category, several resources", layout: LayoutCategory, archive: several,
+			wantDest:  "[vehicles]/[car]",
+			wantNames: []string{"car_a", "car_b"},
+			wantFiles: []string{"[vehicles]/[car]/car_a/fxmanifest.lua", "[vehicles]/[car]/car_b/fxmanifest.lua"},
+		},
+		{
+			name: "mod, single resource", layout: LayoutMod, archive: wrapped,
+			wantDest:  "car",
+			wantNames: []string{"car"},
+			wantFiles: []string{"car/fxmanifest.lua", "car/stream/car.yft"},
+		},
+		{
+			name: "mod, several resources", layout: LayoutMod, archive: several,
+			wantDest:  "[car]",
+			wantNames: []string{"car_a", "car_b"},
+			wantFiles: []string{"[car]/car_a/fxmanifest.lua", "[car]/car_b/fxmanifest.lua"},
+		},
+		{
+			name: "flat, wrapped resource", layout: LayoutFlat, archive: wrapped,
+			wantDest:  "",
+			wantNames: []string{"car_v2"},
+			wantFiles: []string{"car_v2/fxmanifest.lua", "car_v2/stream/car.yft"},
+		},
+		{
+			name: "flat, bare resource", layout: LayoutFlat, archive: bare,
+			wantDest:  "car",
+			wantNames: []string{"car"},
+			wantFiles: []string{"car/fxmanifest.lua", "car/stream/car.yft"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			stage := filepath.Join(root, "stage")
+			resources := filepath.Join(root, "resources")
+			writeTree(t, stage, tt.archive)
+			// Something already installed must survive
+			writeTree(t, resources, []string{"other/fxmanifest.lua"})
+
+			dest, names, err := Install(stage, resources, tt.layout, "vehicles", "car")
+			if err != nil {
+				t.Fatalf("Install: %v", err)
+			}
+
+			if want := filepath.Join(resources, filepath.FromSlash(tt.wantDest)); dest != want {
+				t.Errorf("dest = %s, want %s", dest, want)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+			for _, f := range append(tt.wantFiles, "other/fxmanifest.lua") {
+				if _, err := os.Stat(filepath.Join(resources, filepath.FromSlash(f))); err != nil {
+					t.Errorf("missing resources/%s", f)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallDir(t *testing.T) {
+	resources := filepath.Join("srv", "resources")
+	tests := []struct {
+		layout, category string
+		want             string
+	}{
+		{LayoutCategory, "weapons", filepath.Join(resources, "[weapons]", "mod")},
+		{LayoutCategory, "", filepath.Join(resources, "[misc]", "mod")},
+		{LayoutMod, "weapons", filepath.Join(resources, "mod")},
+		{LayoutFlat, "weapons", resources},
+	}
+	for _, tt := range tests {
+		if got := InstallDir(resources, tt.layout, tt.category, "mod"); got != tt.want {
+			t.Errorf("InstallDir(%s, %q) = %s, want %s", tt.layout, tt.category, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+)
+
+// streamExtensions are the GTA V asset files FXServer streams from a
+// resource's stream folder
+var streamExtensions = map[string]bool{
+	".yft": true, ".ytd": true, ".ydr": true, ".ydd": true, ".ycd": true,
+	".ybn": true, ".ymap": true, ".ytyp": true, ".ynd": true, ".ynv": true,
+	".ypt": true, ".yld": true, ".ymt": true,
+}
+
+// LocalModName returns a folder name for a mod from the file or folder it
+// was downloaded as, e.g. "Lambo Urus [Add-On].zip" -> "lambo-urus-add-on".
+// A bare dlc.rpf is named after the folder it's in.
+func LocalModName(path string) string {
+	base := filepath.Base(filepath.Clean(path))
+	if strings.EqualFold(base, "dlc.rpf") {
+		base = filepath.Base(filepath.Dir(filepath.Clean(path)))
+	}
+
+	lower := strings.ToLower(base)
+	for _, ext := range []string{".tar.gz", ".tar.xz", ".zip", ".rar", ".7z", ".oiv", ".rpf"} {
+		if strings.HasSuffix(lower, ext) {
+			base = base[:len(base)-len(ext)]
+			break
+		}
+	}
+	return folderName(base)
+}
+
+// ConvertLocal turns a mod that was downloaded from gta5-mods.com and
+// extracted to src (or a bare dlc.rpf) into a FiveM resource in stagePath,
+// without convert.cfx.rs. FiveM resources shipped in the mod are used as
+// they are. Otherwise the streamed assets and meta files are taken out of
+// its .rpf archives (dlc.rpf and the ones nested in it) and loose files,
+// and laid out as stream/ and data/ with a generated fxmanifest.lua.
+// src is only read from.
+func ConvertLocal(src, stagePath string) (*Report, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		if dirs := findResources(src); len(dirs) > 0 {
+			for _, dir := range dirs {
+				if err := copyDir(dir, filepath.Join(stagePath, filepath.Base(dir))); err != nil {
+					return nil, fmt.Errorf("failed to copy %s: %w", filepath.Base(dir), err)
+				}
+			}
+			return Inspect(stagePath)
+		}
+	}
+
+	b := &resourceBuilder{dir: stagePath, placed: make(map[string]bool)}
+	if info.IsDir() {
+		err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !d.Type().IsRegular() {
+				return err
+			}
+			rel, _ := filepath.Rel(src, path)
+			return b.addFile(path, filepath.ToSlash(rel))
+		})
+	} else {
+		err = b.addFile(src, filepath.Base(src))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if b.files == 0 {
+		if b.encrypted != "" {
+			return nil, fmt.Errorf("%s is encrypted with the game's keys, convert the mod's gta5-mods.com URL instead", b.encrypted)
+		}
+		return nil, fmt.Errorf("no FiveM resource or GTA V assets (.rpf, .yft, .ytd, .meta) found in %s", filepath.Base(src))
+	}
+
+	report, err := Inspect(stagePath)
+	if err != nil {
+		return nil, err
+	}
+	report.Problems = append(b.problems, report.Problems...)
+	return report, nil
+}
+
+// resourceBuilder lays out the files of a mod as a single resource
+type resourceBuilder struct {
+	dir       string
+	placed    map[string]bool // Lower-cased paths written so far, relative to dir
+	files     int
+	encrypted string // First archive that couldn't be read
+	audio     bool   // Whether audio files were left out
+	problems  []string
+}
+
+// addFile adds a file of the mod, name being where it is in the mod
+func (b *resourceBuilder) addFile(path, name string) error {
+	if !strings.EqualFold(filepath.Ext(name), ".rpf") {
+		return b.add(name, func() ([]byte, error) { return os.ReadFile(path) })
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return b.addArchive(f, name, 0)
+}
+
+// addArchive adds the files of an RPF7 archive and the archives inside it
+func (b *resourceBuilder) addArchive(r io.ReaderAt, name string, depth int) error {
+	archive, err := openRPF(r)
+	if errors.Is(err, ErrEncryptedRPF) {
+		if b.encrypted == "" {
+			b.encrypted = name
+		}
+		b.problems = append(b.problems, fmt.Sprintf("%s is encrypted with the game's keys and was skipped", name))
+		return nil
+	}
+	if err != nil {
+		b.problems = append(b.problems, fmt.Sprintf("%s couldn't be read and was skipped: %v", name, err))
+		return nil
+	}
+
+	return archive.walk(func(entryName string, e *rpfEntry) error {
+		fullName := name + "/" + entryName
+		if strings.EqualFold(path.Ext(entryName), ".rpf") {
+			if depth >= rpfMaxDepth {
+				return nil
+			}
+			nested, err := archive.open(e)
+			if err != nil {
+				b.problems = append(b.problems, fmt.Sprintf("%s couldn't be read and was skipped: %v", fullName, err))
+				return nil
+			}
+			return b.addArchive(nested, fullName, depth+1)
+		}
+		return b.add(fullName, func() ([]byte, error) { return archive.read(e) })
+	})
+}
+
+// add places a file of the mod in the resource: streamed assets go in
+// stream/, meta files in data/ and everything else is left out. Where two
+// files share a name, the first one wins.
+func (b *resourceBuilder) add(name string, read func() ([]byte, error)) error {
+	base := path.Base(name)
+	ext := strings.ToLower(path.Ext(base))
+
+	var rel string
+	switch {
+	case streamExtensions[ext]:
+		rel = "stream/" + base
+	case ext == ".meta":
+		rel = "data/" + base
+	case ext == ".awc":
+		if !b.audio {
+			b.audio = true
+			b.problems = append(b.problems, "Audio files (.awc) were left out, the mod's own sounds won't play")
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if b.placed[strings.ToLower(rel)] {
+		b.problems = append(b.problems, fmt.Sprintf("%s is in the mod more than once, only the first one was kept", base))
+		return nil
+	}
+
+	data, err := read()
+	if err != nil {
+		b.problems = append(b.problems, fmt.Sprintf("%s couldn't be read and was skipped: %v", name, err))
+		return nil
+	}
+
+	target := filepath.Join(b.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	b.placed[strings.ToLower(rel)] = true
+	b.files++
+	return nil
+}
+
+// findResources returns the folders under dir that have a manifest, without
+// looking inside them
+func findResources(dir string) []string {
+	var dirs []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if resource.FindManifest(path) != "" {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs
+}
+
+// copyDir copies a folder's files and folders to dest. Links are skipped, as
+// they could point outside the mod.
+func copyDir(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
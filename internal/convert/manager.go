@@ -0,0 +1,316 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+)
+
+// Defaults for ManagerOptions, overridden by convert.max_concurrent and
+// convert.poll_interval
+const (
+	DefaultMaxConcurrent = 2
+	DefaultPollInterval  = 2 * time.Second
+)
+
+const (
+	maxPollBatch    = 4                      // Progress queries per poll, round-robin over the running conversions
+	maxPollFailures = 5                      // Failed queries in a row before giving up, e.g. on one convert.cfx.rs forgot
+	startStagger    = 200 * time.Millisecond // Pause between conversions started together
+	maxRateLimit    = 2 * time.Minute        // Longest pause after convert.cfx.rs answers 429
+)
+
+// ManagerOptions limits how hard a Manager works convert.cfx.rs
+type ManagerOptions struct {
+	MaxConcurrent int           // Conversions running at once, defaults to DefaultMaxConcurrent
+	PollInterval  time.Duration // Time between progress polls, defaults to DefaultPollInterval
+}
+
+// Job is a conversion as seen by a Manager
+type Job struct {
+	URL    string
+	UUID   string            // Set once convert.cfx.rs accepted the conversion
+	Status *ConversionStatus // Last progress reported
+	File   string            // Converted file, set when the conversion finished
+	Err    error             // Why the conversion failed
+
+	pollFailures int
+}
+
+// Done reports whether the job finished, successfully or not
+func (j *Job) Done() bool {
+	return j.File != "" || j.Err != nil
+}
+
+// Manager runs a queue of conversions on convert.cfx.rs: at most
+// MaxConcurrent at a time, started a little apart and polled every
+// PollInterval a few at a time. When the service answers 429 Too Many
+// Requests, every request is paused for a growing backoff (or what its
+// Retry-After says) instead of failing the conversions.
+type Manager struct {
+	client        *Client
+	maxConcurrent int
+	pollInterval  time.Duration
+	wake          chan struct{}
+
+	mu          sync.Mutex
+	jobs        map[string]*Job // URL -> job
+	order       []string        // URLs in the order they were added
+	queue       []string        // URLs waiting to be started
+	active      int             // Conversions started and not done
+	pollCursor  int
+	backoff     time.Duration // Current rate limit pause, 0 when not limited
+	pausedUntil time.Time
+}
+
+// NewManager creates a Manager using client for its requests. Nothing is
+// sent until Run is called.
+func NewManager(client *Client, opts ManagerOptions) *Manager {
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	return &Manager{
+		client:        client,
+		maxConcurrent: opts.MaxConcurrent,
+		pollInterval:  opts.PollInterval,
+		wake:          make(chan struct{}, 1),
+		jobs:          make(map[string]*Job),
+	}
+}
+
+// MaxConcurrent returns how many conversions run at once
+func (m *Manager) MaxConcurrent() int {
+	return m.maxConcurrent
+}
+
+// Add queues a conversion of modURL. A URL that's already known is started
+// over, which is how failed conversions are retried.
+func (m *Manager) Add(modURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job := m.jobs[modURL]; job != nil && !job.Done() {
+		return
+	}
+	m.put(&Job{URL: modURL})
+	m.queue = append(m.queue, modURL)
+	m.notify()
+}
+
+// Follow polls a conversion that was started earlier, e.g. by a session
+// being resumed
+func (m *Manager) Follow(modURL, uuid string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job := m.jobs[modURL]; job != nil && !job.Done() {
+		return
+	}
+	m.put(&Job{URL: modURL, UUID: uuid})
+	m.active++
+	m.notify()
+}
+
+// put adds or replaces a job. Call with m.mu held.
+func (m *Manager) put(job *Job) {
+	if m.jobs[job.URL] == nil {
+		m.order = append(m.order, job.URL)
+	}
+	m.jobs[job.URL] = job
+}
+
+// notify wakes Run up without waiting for its next poll
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Job returns a copy of the job for modURL
+func (m *Manager) Job(modURL string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.jobs[modURL]
+	if job == nil {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Counts returns how many conversions are waiting to start and running
+func (m *Manager) Counts() (queued, active int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue), m.active
+}
+
+// Idle reports whether every conversion is done
+func (m *Manager) Idle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue) == 0 && m.active == 0
+}
+
+// PausedUntil returns when requests resume after convert.cfx.rs rate
+// limited them, or the zero time if they aren't paused
+func (m *Manager) PausedUntil() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Now().After(m.pausedUntil) {
+		return time.Time{}
+	}
+	return m.pausedUntil
+}
+
+// Run starts and polls conversions until ctx is cancelled. Call it once, in
+// its own goroutine.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.startQueued(ctx)
+		m.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+	}
+}
+
+// paused reports whether requests are held back by a rate limit
+func (m *Manager) paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Now().Before(m.pausedUntil)
+}
+
+// startQueued starts queued conversions while there's room for them
+func (m *Manager) startQueued(ctx context.Context) {
+	for started := 0; ctx.Err() == nil && !m.paused(); started++ {
+		m.mu.Lock()
+		if len(m.queue) == 0 || m.active >= m.maxConcurrent {
+			m.mu.Unlock()
+			return
+		}
+		modURL := m.queue[0]
+		m.queue = m.queue[1:]
+		m.active++
+		m.mu.Unlock()
+
+		if started > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(startStagger):
+			}
+		}
+
+		uuid, err := m.client.StartConversionContext(ctx, modURL)
+
+		m.mu.Lock()
+		job := m.jobs[modURL]
+		switch {
+		case ctx.Err() != nil, m.rateLimited(err):
+			// Not the conversion's fault, start it again later
+			m.queue = append([]string{modURL}, m.queue...)
+			m.active--
+		case err != nil:
+			job.Err = err
+			m.active--
+		default:
+			job.UUID = uuid
+		}
+		m.mu.Unlock()
+	}
+}
+
+// poll queries the progress of the next few running conversions
+func (m *Manager) poll(ctx context.Context) {
+	if m.paused() {
+		return
+	}
+
+	m.mu.Lock()
+	var running []*Job
+	for _, modURL := range m.order {
+		if job := m.jobs[modURL]; job.UUID != "" && !job.Done() {
+			running = append(running, job)
+		}
+	}
+	var batch []Job
+	if len(running) > 0 {
+		for i := 0; i < min(maxPollBatch, len(running)); i++ {
+			batch = append(batch, *running[(m.pollCursor+i)%len(running)])
+		}
+		m.pollCursor = (m.pollCursor + len(batch)) % len(running)
+	}
+	m.mu.Unlock()
+
+	for _, target := range batch {
+		if ctx.Err() != nil || m.paused() {
+			return
+		}
+		status, err := m.client.QueryProgressContext(ctx, target.UUID)
+
+		m.mu.Lock()
+		job := m.jobs[target.URL]
+		switch {
+		case job == nil || job.UUID != target.UUID || job.Done():
+			// Retried while the query was out
+		case ctx.Err() != nil, m.rateLimited(err):
+		case err != nil:
+			// Transient query failures are retried on the next poll
+			job.pollFailures++
+			if job.pollFailures >= maxPollFailures {
+				job.Err = fmt.Errorf("lost track of the conversion: %w", err)
+				m.active--
+			}
+		default:
+			job.pollFailures = 0
+			job.Status = status
+			if status.Progress >= 100 {
+				if status.File == "" {
+					job.Err = fmt.Errorf("conversion finished without a file: %s", status.Message)
+				} else {
+					job.File = status.File
+				}
+				m.active--
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// rateLimited reports whether err is convert.cfx.rs answering 429 and, if
+// so, pauses all requests for twice as long as last time or what its
+// Retry-After asks. A request that got through ends the backoff. Call with
+// m.mu held.
+func (m *Manager) rateLimited(err error) bool {
+	var status *network.StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusTooManyRequests {
+		if err == nil {
+			m.backoff = 0
+		}
+		return false
+	}
+
+	m.backoff = min(max(2*m.backoff, m.pollInterval), maxRateLimit)
+	wait := max(m.backoff, min(status.RetryAfter, maxRateLimit))
+	m.pausedUntil = time.Now().Add(wait)
+	network.Debugf("convert.cfx.rs is rate limiting, pausing for %s", wait)
+	return true
+}
@@ -0,0 +1,213 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+)
+
+// fakeConverter stands in for convert.cfx.rs: conversions finish after
+// queriesToFinish progress queries, mods with "broken" in their URL are
+// refused, and the first rateLimit start requests are answered with 429
+type fakeConverter struct {
+	queriesToFinish int
+	rateLimit       int
+
+	mu         sync.Mutex
+	started    []string       // Mod URLs in the order conversions started
+	queries    map[string]int // UUID -> progress queries so far
+	running    int
+	maxRunning int
+}
+
+func (f *fakeConverter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/convert":
+		if f.rateLimit > 0 {
+			f.rateLimit--
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		modURL := r.Form.Get("url")
+		if strings.Contains(modURL, "broken") {
+			json.NewEncoder(w).Encode(ConvertResponse{Status: 500})
+			return
+		}
+		f.started = append(f.started, modURL)
+		f.running++
+		f.maxRunning = max(f.maxRunning, f.running)
+		json.NewEncoder(w).Encode(ConvertResponse{Message: fmt.Sprintf("uuid-%d", len(f.started)), Status: 200})
+
+	case "/api/query":
+		uuid := r.Form.Get("uuid")
+		f.queries[uuid]++
+		status := ConversionStatus{Progress: 100 * f.queries[uuid] / f.queriesToFinish}
+		if f.queries[uuid] == f.queriesToFinish {
+			status.File = uuid + ".zip"
+			f.running--
+		}
+		json.NewEncoder(w).Encode(status)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestManager runs a Manager against a fakeConverter until the test ends
+func newTestManager(t *testing.T, fake *fakeConverter, opts ManagerOptions) (*Manager, func()) {
+	t.Helper()
+	fake.queries = make(map[string]int)
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	client := NewClientWithTimeout(5 * time.Second)
+	client.baseURL = ts.URL
+	client.SetRetryPolicy(network.RetryPolicy{Attempts: 1})
+
+	m := NewManager(client, opts)
+	run := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go m.Run(ctx)
+	}
+	return m, run
+}
+
+// waitIdle waits for every conversion the manager has to finish
+func waitIdle(t *testing.T, m *Manager) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !m.Idle() {
+		if time.Now().After(deadline) {
+			queued, active := m.Counts()
+			t.Fatalf("conversions didn't finish: %d queued, %d active", queued, active)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func modURLs(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://www.gta5-mods.com/vehicles/car-%d", i)
+	}
+	return urls
+}
+
+func TestManagerQueue(t *testing.T) {
+	fake := &fakeConverter{queriesToFinish: 3}
+	m, run := newTestManager(t, fake, ManagerOptions{MaxConcurrent: 2, PollInterval: 5 * time.Millisecond})
+
+	urls := modURLs(5)
+	for _, u := range urls {
+		m.Add(u)
+	}
+	m.Add(urls[0]) // Already queued, so not added twice
+
+	if queued, active := m.Counts(); queued != 5 || active != 0 {
+		t.Fatalf("before Run: %d queued, %d active; want 5 queued, 0 active", queued, active)
+	}
+
+	run()
+	waitIdle(t, m)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if strings.Join(fake.started, " ") != strings.Join(urls, " ") {
+		t.Errorf("conversions started in order %v, want %v", fake.started, urls)
+	}
+	if fake.maxRunning > 2 {
+		t.Errorf("%d conversions ran at once, max_concurrent is 2", fake.maxRunning)
+	}
+	if fake.maxRunning < 2 {
+		t.Errorf("at most %d conversion ran at once, want 2", fake.maxRunning)
+	}
+	for _, u := range urls {
+		job, ok := m.Job(u)
+		if !ok || job.File == "" || job.Err != nil {
+			t.Errorf("job %s = %+v, want a converted file", u, job)
+		}
+	}
+}
+
+func TestManagerMaxConcurrent(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			fake := &fakeConverter{queriesToFinish: 4}
+			m, run := newTestManager(t, fake, ManagerOptions{MaxConcurrent: limit, PollInterval: 5 * time.Millisecond})
+			if m.MaxConcurrent() != limit {
+				t.Fatalf("MaxConcurrent() = %d, want %d", m.MaxConcurrent(), limit)
+			}
+
+			for _, u := range modURLs(6) {
+				m.Add(u)
+			}
+			run()
+
+			// Only the limit may be running while others wait
+			deadline := time.Now().Add(10 * time.Second)
+			for !m.Idle() && time.Now().Before(deadline) {
+				if queued, active := m.Counts(); active > limit {
+					t.Fatalf("%d active with %d queued, limit is %d", active, queued, limit)
+				}
+				time.Sleep(2 * time.Millisecond)
+			}
+			waitIdle(t, m)
+
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			if fake.maxRunning > limit {
+				t.Errorf("%d conversions ran at once, limit is %d", fake.maxRunning, limit)
+			}
+			if len(fake.started) != 6 {
+				t.Errorf("%d conversions started, want 6", len(fake.started))
+			}
+		})
+	}
+}
+
+func TestManagerFailedStartFreesSlot(t *testing.T) {
+	fake := &fakeConverter{queriesToFinish: 2}
+	m, run := newTestManager(t, fake, ManagerOptions{MaxConcurrent: 1, PollInterval: 5 * time.Millisecond})
+
+	broken := "https://www.gta5-mods.com/vehicles/broken"
+	good := "https://www.gta5-mods.com/vehicles/car"
+	m.Add(broken)
+	m.Add(good)
+	run()
+	waitIdle(t, m)
+
+	if job, _ := m.Job(broken); job.Err == nil {
+		t.Errorf("refused conversion has no error: %+v", job)
+	}
+	if job, _ := m.Job(good); job.File == "" {
+		t.Errorf("conversion queued behind a refused one didn't finish: %+v", job)
+	}
+}
+
+func TestManagerRateLimitRequeues(t *testing.T) {
+	fake := &fakeConverter{queriesToFinish: 2, rateLimit: 2}
+	m, run := newTestManager(t, fake, ManagerOptions{MaxConcurrent: 1, PollInterval: 5 * time.Millisecond})
+
+	url := modURLs(1)[0]
+	m.Add(url)
+	run()
+	waitIdle(t, m)
+
+	job, _ := m.Job(url)
+	if job.Err != nil || job.File == "" {
+		t.Errorf("rate limited conversion = %+v, want it started again and finished", job)
+	}
+}
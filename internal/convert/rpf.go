@@ -0,0 +1,225 @@
+package convert
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// RPF7 is the archive format of GTA V, used for a mod's dlc.rpf and the
+// archives nested in it. OpenIV writes them unencrypted ("OPEN"); archives
+// encrypted with the game's keys can't be read without them.
+const (
+	rpfMagic          = 0x52504637 // "RPF7"
+	rpfEncryptionNone = 0
+	rpfEncryptionOpen = 0x4E45504F // "OPEN"
+	rpfDirectory      = 0x7FFFFF00
+	rpfBlockSize      = 512
+	rpfMaxDepth       = 8 // Archives nested deeper than this are ignored
+)
+
+// ErrEncryptedRPF is returned for archives encrypted with the game's keys
+var ErrEncryptedRPF = errors.New("archive is encrypted with the game's keys")
+
+// rpfEntry is a folder or file in an RPF7 archive
+type rpfEntry struct {
+	name string
+	dir  bool
+
+	first, count uint32 // Folder: index of its first entry and how many it has
+
+	offset    int64  // File: where its data starts
+	size      uint32 // File: stored size, 0 for uncompressed binary files
+	rawSize   uint32 // Binary file: size once decompressed
+	encrypted bool   // Binary file: encrypted with the game's keys
+	resource  bool   // Resource file (.yft, .ytd...) rather than a binary one
+	sysFlags  uint32 // Resource file: system flags, which hold half the version
+	gfxFlags  uint32 // Resource file: graphics flags, which hold the other half
+}
+
+// rpfArchive is an opened RPF7 archive
+type rpfArchive struct {
+	r       io.ReaderAt
+	entries []rpfEntry
+}
+
+// openRPF reads the entry table of an RPF7 archive
+func openRPF(r io.ReaderAt) (*rpfArchive, error) {
+	header := make([]byte, 16)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(header) != rpfMagic {
+		return nil, fmt.Errorf("not an RPF7 archive")
+	}
+
+	count := binary.LittleEndian.Uint32(header[4:])
+	namesLength := binary.LittleEndian.Uint32(header[8:])
+	switch binary.LittleEndian.Uint32(header[12:]) {
+	case rpfEncryptionNone, rpfEncryptionOpen:
+	default:
+		return nil, ErrEncryptedRPF
+	}
+	if count == 0 || count > 1<<20 || namesLength > 1<<24 {
+		return nil, fmt.Errorf("corrupt archive header")
+	}
+
+	table := make([]byte, int(count)*16+int(namesLength))
+	if _, err := r.ReadAt(table, 16); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
+	names := table[count*16:]
+
+	archive := &rpfArchive{r: r, entries: make([]rpfEntry, count)}
+	for i := range archive.entries {
+		b := table[i*16 : i*16+16]
+		e := &archive.entries[i]
+
+		var nameOffset uint32
+		if binary.LittleEndian.Uint32(b[4:]) == rpfDirectory {
+			nameOffset = binary.LittleEndian.Uint32(b)
+			e.dir = true
+			e.first = binary.LittleEndian.Uint32(b[8:])
+			e.count = binary.LittleEndian.Uint32(b[12:])
+		} else {
+			v := binary.LittleEndian.Uint64(b)
+			nameOffset = uint32(v & 0xFFFF)
+			e.size = uint32(v>>16) & 0xFFFFFF
+			e.offset = int64(v>>40&0x7FFFFF) * rpfBlockSize
+			e.resource = v>>63 == 1
+			if e.resource {
+				e.sysFlags = binary.LittleEndian.Uint32(b[8:])
+				e.gfxFlags = binary.LittleEndian.Uint32(b[12:])
+			} else {
+				e.rawSize = binary.LittleEndian.Uint32(b[8:])
+				e.encrypted = binary.LittleEndian.Uint32(b[12:]) == 1
+			}
+		}
+
+		if nameOffset >= uint32(len(names)) {
+			return nil, fmt.Errorf("corrupt entry name")
+		}
+		name := names[nameOffset:]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		e.name = string(name)
+	}
+
+	if !archive.entries[0].dir {
+		return nil, fmt.Errorf("archive has no root folder")
+	}
+	return archive, nil
+}
+
+// walk calls fn for every file in the archive with its path inside it
+func (a *rpfArchive) walk(fn func(name string, e *rpfEntry) error) error {
+	return a.walkDir(&a.entries[0], "", 0, fn)
+}
+
+func (a *rpfArchive) walkDir(dir *rpfEntry, prefix string, depth int, fn func(name string, e *rpfEntry) error) error {
+	if depth > 32 || uint64(dir.first)+uint64(dir.count) > uint64(len(a.entries)) {
+		return fmt.Errorf("corrupt folder %s", prefix)
+	}
+
+	for i := dir.first; i < dir.first+dir.count; i++ {
+		e := &a.entries[i]
+		// Entry names are plain file names; anything else would escape the archive
+		if e.name == "" || e.name == "." || e.name == ".." || strings.ContainsAny(e.name, `/\`) {
+			continue
+		}
+
+		name := path.Join(prefix, e.name)
+		if e.dir {
+			if e == dir {
+				continue
+			}
+			if err := a.walkDir(e, name, depth+1, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(name, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedSize returns how many bytes a file takes up in the archive. Resource
+// files too big for the entry table keep their size in their header.
+func (a *rpfArchive) storedSize(e *rpfEntry) (int64, error) {
+	if !e.resource && e.size == 0 {
+		return int64(e.rawSize), nil
+	}
+	if !e.resource || e.size != 0xFFFFFF {
+		return int64(e.size), nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := a.r.ReadAt(b, e.offset); err != nil {
+		return 0, err
+	}
+	return int64(b[7]) | int64(b[14])<<8 | int64(b[5])<<16 | int64(b[2])<<24, nil
+}
+
+// open returns a reader for a nested archive's data, without loading it
+func (a *rpfArchive) open(e *rpfEntry) (io.ReaderAt, error) {
+	if e.resource || e.encrypted {
+		return nil, fmt.Errorf("%s isn't a plain file", e.name)
+	}
+	if e.size != 0 {
+		data, err := a.read(e)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+	return io.NewSectionReader(a.r, e.offset, int64(e.rawSize)), nil
+}
+
+// read returns a file's contents as they'd be on disk: binary files are
+// decompressed, resource files get their RSC7 header back
+func (a *rpfArchive) read(e *rpfEntry) ([]byte, error) {
+	if e.encrypted {
+		return nil, ErrEncryptedRPF
+	}
+
+	size, err := a.storedSize(e)
+	if err != nil {
+		return nil, err
+	}
+	if size > 1<<30 {
+		return nil, fmt.Errorf("%s is too large", e.name)
+	}
+	data := make([]byte, size)
+	if _, err := a.r.ReadAt(data, e.offset); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", e.name, err)
+	}
+
+	if e.resource {
+		if len(data) < 16 {
+			return nil, fmt.Errorf("%s is truncated", e.name)
+		}
+		version := (e.sysFlags>>28&0xF)<<4 | e.gfxFlags>>28&0xF
+		binary.LittleEndian.PutUint32(data[0:], 0x37435352) // "RSC7"
+		binary.LittleEndian.PutUint32(data[4:], version)
+		binary.LittleEndian.PutUint32(data[8:], e.sysFlags)
+		binary.LittleEndian.PutUint32(data[12:], e.gfxFlags)
+		return data, nil
+	}
+
+	if e.size == 0 {
+		return data, nil
+	}
+	raw, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), int64(e.rawSize)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", e.name, err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,343 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/lockfile"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrNotRunning is returned when no daemon answers on the socket
+var ErrNotRunning = errors.New("the inkwash daemon is not running")
+
+// DefaultInterval is how often servers are checked when Options doesn't say
+const DefaultInterval = 5 * time.Second
+
+// Server states reported by the daemon
+const (
+	StateStopped = "stopped" // Not running and not meant to be
+	StateRunning = "running" // Running normally
+	StateCrashed = "crashed" // Exited on its own; a restart is pending or failed
+	StateFailed  = "gave up" // Crashed too often, left stopped
+	StateBusy    = "busy"    // Being modified by another inkwash command
+)
+
+// Options tune how the daemon supervises servers
+type Options struct {
+	Interval      time.Duration // How often servers are checked
+	MaxRestarts   int           // Restarts allowed within RestartWindow before giving up
+	RestartWindow time.Duration
+}
+
+// ServerStatus is what the daemon knows about one server
+type ServerStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	PID       int       `json:"pid,omitempty"`
+	Restarts  int       `json:"restarts"`
+	LastCrash time.Time `json:"last_crash,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Status is the daemon's answer to a status request
+type Status struct {
+	PID       int            `json:"pid"`
+	StartedAt time.Time      `json:"started_at"`
+	Servers   []ServerStatus `json:"servers"`
+}
+
+// supervised tracks a server between checks
+type supervised struct {
+	status     ServerStatus
+	suspectPID int         // PID seen dead once; a crash is confirmed on the next check
+	crashedPID int         // PID whose crash was recorded, so failed restarts don't record it again
+	crashes    []time.Time // Restart attempts within the window
+}
+
+// Daemon supervises the registered servers, restarting those that crash
+type Daemon struct {
+	reg  *registry.Registry
+	pm   *server.ProcessManager
+	opts Options
+	logf func(format string, args ...interface{})
+
+	mu        sync.Mutex
+	servers   map[string]*supervised
+	startedAt time.Time
+}
+
+// New creates a daemon for the servers in reg. logf receives a line for
+// each crash and restart.
+func New(reg *registry.Registry, opts Options, logf func(format string, args ...interface{})) *Daemon {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	return &Daemon{
+		reg:     reg,
+		pm:      server.NewProcessManager(),
+		opts:    opts,
+		logf:    logf,
+		servers: make(map[string]*supervised),
+	}
+}
+
+// SocketPath returns the unix socket the daemon answers status requests on
+func SocketPath() string {
+	return filepath.Join(registry.GetDefaultConfigPath(), "daemon.sock")
+}
+
+// lockPath is held while a daemon runs, so only one supervises the servers
+func lockPath() string {
+	return filepath.Join(registry.GetDefaultConfigPath(), "daemon.lock")
+}
+
+// Run supervises servers and answers status requests until ctx is cancelled
+func (d *Daemon) Run(ctx context.Context) error {
+	lock, err := lockfile.TryAcquire(lockPath())
+	if err != nil {
+		var locked *lockfile.LockedError
+		if errors.As(err, &locked) && locked.PID > 0 {
+			return fmt.Errorf("another daemon is already running (PID %d): %w", locked.PID, err)
+		}
+		return err
+	}
+	defer lock.Release()
+
+	// We hold the lock, so a socket left behind is from a daemon that died
+	socketPath := SocketPath()
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+	defer listener.Close()
+
+	d.startedAt = time.Now()
+	go d.serve(listener)
+	d.logf("Supervising servers, checking every %s", d.opts.Interval)
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.check()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check looks at every registered server once, restarting crashed ones
+func (d *Daemon) check() {
+	if err := d.reg.Reload(); err != nil {
+		d.logf("Failed to reload the registry: %v", err)
+		return
+	}
+	servers := d.reg.List()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for i := range servers {
+		srv := &servers[i]
+		seen[srv.Name] = true
+
+		s, ok := d.servers[srv.Name]
+		if !ok {
+			s = &supervised{status: ServerStatus{Name: srv.Name}}
+			d.servers[srv.Name] = s
+		}
+		s.status.PID = srv.PID
+
+		switch {
+		case server.IsLocked(srv.Path):
+			s.status.State = StateBusy
+			continue
+
+		case srv.PID == 0:
+			// Stopped on purpose, or given up on (which stays visible until it's started again)
+			s.suspectPID = 0
+			s.crashedPID = 0
+			if s.status.State != StateFailed {
+				s.status.State = StateStopped
+			}
+			continue
+
+		case d.pm.IsRunning(srv):
+			s.suspectPID = 0
+			s.crashedPID = 0
+			s.status.State = StateRunning
+			continue
+		}
+
+		// inkwash stop clears the PID right after the process exits. Waiting
+		// for a second look avoids taking that moment for a crash.
+		if s.suspectPID != srv.PID {
+			s.suspectPID = srv.PID
+			continue
+		}
+
+		d.restart(srv, s)
+	}
+
+	for name := range d.servers {
+		if !seen[name] {
+			delete(d.servers, name)
+		}
+	}
+}
+
+// restart starts a crashed server again, unless it has crashed too often
+// lately. The crash is recorded once; if starting fails, later checks only
+// try to start it again.
+func (d *Daemon) restart(srv *types.Server, s *supervised) {
+	now := time.Now()
+	s.status.State = StateCrashed
+	retry := s.crashedPID == srv.PID
+	if !retry {
+		s.crashedPID = srv.PID
+		s.status.LastCrash = now
+		d.recordCrash(srv)
+	}
+
+	recent := s.crashes[:0]
+	for _, t := range s.crashes {
+		if now.Sub(t) < d.opts.RestartWindow {
+			recent = append(recent, t)
+		}
+	}
+	s.crashes = recent
+
+	if len(s.crashes) >= d.opts.MaxRestarts {
+		d.logf("'%s' crashed again after %d restart(s) within %s, giving up; start it again with 'inkwash start %s'",
+			srv.Name, len(s.crashes), d.opts.RestartWindow, srv.Name)
+		s.status.State = StateFailed
+		s.suspectPID = 0
+		if err := d.reg.UpdatePID(srv.Name, 0); err != nil {
+			d.logf("Failed to update the registry: %v", err)
+		}
+		return
+	}
+	s.crashes = append(s.crashes, now)
+
+	if retry {
+		d.logf("Trying to restart '%s' again", srv.Name)
+	} else {
+		d.logf("'%s' (PID %d) exited unexpectedly, restarting", srv.Name, srv.PID)
+	}
+	srv.PID = 0
+	if err := d.pm.Start(srv); err != nil {
+		// The registry still has the dead PID, so the next check tries again
+		s.status.LastError = err.Error()
+		d.logf("Failed to restart '%s': %v", srv.Name, err)
+		return
+	}
+
+	s.suspectPID = 0
+	s.status.State = StateRunning
+	s.status.PID = srv.PID
+	s.status.Restarts++
+	s.status.LastError = ""
+
+	// Pick up changes other inkwash commands made since the check started
+	err := d.reg.Reload()
+	if err == nil {
+		err = d.reg.Update(*srv)
+	}
+	if err != nil {
+		d.logf("Restarted '%s' but the registry couldn't be updated: %v", srv.Name, err)
+		return
+	}
+	d.logf("Restarted '%s' (PID %d)", srv.Name, srv.PID)
+}
+
+// recordCrash collects a crashed server's log and dumps, logging where they went
+func (d *Daemon) recordCrash(srv *types.Server) {
+	report, err := server.RecordCrash(srv)
+	if report != nil {
+		d.logf("Saved the end of the log and %d crash dump(s) of '%s' in %s", len(report.Dumps), srv.Name, report.Dir)
+	}
+	if err != nil {
+		d.logf("Failed to collect the crash of '%s': %v", srv.Name, err)
+	}
+}
+
+// Status returns what the daemon knows about each server, sorted by name
+func (d *Daemon) Status() *Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := &Status{PID: os.Getpid(), StartedAt: d.startedAt}
+	for _, s := range d.servers {
+		status.Servers = append(status.Servers, s.status)
+	}
+	sort.Slice(status.Servers, func(i, j int) bool {
+		return status.Servers[i].Name < status.Servers[j].Name
+	})
+	return status
+}
+
+// serve answers requests until the listener is closed. Each connection
+// sends one line with a request ("status") and gets one JSON reply.
+func (d *Daemon) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			request, _ := bufio.NewReader(conn).ReadString('\n')
+			switch strings.TrimSpace(request) {
+			case "status":
+				json.NewEncoder(conn).Encode(d.Status())
+			default:
+				fmt.Fprintf(conn, "{\"error\":%q}\n", "unknown request")
+			}
+		}()
+	}
+}
+
+// Query asks the running daemon for its status
+func Query() (*Status, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w; start it with 'inkwash daemon'", ErrNotRunning)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, "status"); err != nil {
+		return nil, fmt.Errorf("failed to query the daemon: %w", err)
+	}
+
+	var status Status
+	if err := json.NewDecoder(conn).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to read the daemon's status: %w", err)
+	}
+	return &status, nil
+}
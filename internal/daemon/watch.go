@@ -0,0 +1,253 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// WatchOptions limit how often Watch restarts an unhealthy server
+type WatchOptions struct {
+	MaxRestarts   int // Restarts allowed within RestartWindow before giving up
+	RestartWindow time.Duration
+}
+
+// watcher tracks one server between health checks
+type watcher struct {
+	reg  *registry.Registry
+	pm   *server.ProcessManager
+	mm   *server.MetadataManager
+	name string
+	opts WatchOptions
+	logf func(format string, args ...interface{})
+
+	failures int         // Failed checks in a row
+	restarts []time.Time // Restarts within the window
+	waiting  bool        // Already logged that the server isn't running
+}
+
+// Watch checks a server's health with the settings in its metadata.json,
+// rereading them before every check, and restarts it once it fails
+// HealthConfig.Failures checks in a row. Each such restart is recorded as an
+// incident in the server's metadata. A stopped server is waited for rather
+// than started. Watch returns when ctx is cancelled, the server is removed
+// from the registry, or it stays unhealthy after MaxRestarts restarts within
+// RestartWindow.
+func Watch(ctx context.Context, reg *registry.Registry, name string, opts WatchOptions, logf func(format string, args ...interface{})) error {
+	w := &watcher{
+		reg:  reg,
+		pm:   server.NewProcessManager(),
+		mm:   server.NewMetadataManager(),
+		name: name,
+		opts: opts,
+		logf: logf,
+	}
+
+	srv, err := reg.Get(name)
+	if err != nil {
+		return err
+	}
+	cfg := w.healthConfig(srv)
+	var probes []string
+	if !cfg.NoTCP {
+		probes = append(probes, server.ProbeTCP)
+	}
+	if !cfg.NoPlayers {
+		probes = append(probes, server.ProbePlayers)
+	}
+	if cfg.LogTimeout > 0 {
+		probes = append(probes, server.ProbeLog)
+	}
+	if len(probes) == 0 {
+		return fmt.Errorf("every health check is turned off in the metadata.json of '%s'", name)
+	}
+	logf("Watching '%s' (%s) every %ds, restarting after %d failed checks in a row",
+		name, strings.Join(probes, ", "), cfg.Interval, cfg.Failures)
+
+	for {
+		interval, err := w.check(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// healthConfig reads a server's health check settings, falling back to the
+// defaults if its metadata.json can't be read
+func (w *watcher) healthConfig(srv *types.Server) types.HealthConfig {
+	metadata, err := w.mm.Load(srv.Path)
+	if err != nil {
+		metadata = &types.ServerMetadata{}
+	}
+	return metadata.HealthChecks()
+}
+
+// check runs one round of health checks, restarting the server if it has
+// now failed too many in a row, and returns how long to wait for the next
+func (w *watcher) check(ctx context.Context) (time.Duration, error) {
+	if err := w.reg.Reload(); err != nil {
+		w.logf("Failed to reload the registry: %v", err)
+		return time.Duration((&types.ServerMetadata{}).HealthChecks().Interval) * time.Second, nil
+	}
+	found, err := w.reg.Get(w.name)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is no longer registered, stopped watching", w.name)
+	}
+	srv := *found
+
+	cfg := w.healthConfig(&srv)
+	interval := time.Duration(cfg.Interval) * time.Second
+
+	switch {
+	case server.IsLocked(srv.Path):
+		// Being updated or moved; its health says nothing until that's done
+		w.failures = 0
+		return interval, nil
+
+	case srv.PID == 0:
+		if !w.waiting {
+			w.logf("'%s' isn't running, waiting for it to be started", w.name)
+			w.waiting = true
+		}
+		w.failures = 0
+		return interval, nil
+
+	case time.Since(srv.LastStarted) < time.Duration(cfg.StartupGrace)*time.Second:
+		// Still loading resources; failures now would restart it in a loop
+		w.waiting = false
+		w.failures = 0
+		return interval, nil
+	}
+	w.waiting = false
+
+	report := &server.HealthReport{Time: time.Now()}
+	if w.pm.IsRunning(&srv) {
+		report = server.CheckHealth(ctx, &srv, cfg)
+	} else {
+		report.Probes = []server.HealthProbe{{Name: "process", Err: fmt.Errorf("PID %d has exited", srv.PID)}}
+		w.recordCrash(&srv)
+	}
+	if ctx.Err() != nil {
+		return interval, nil
+	}
+
+	if report.Healthy() {
+		if w.failures > 0 {
+			w.logf("'%s' is healthy again", w.name)
+		}
+		w.failures = 0
+		return interval, nil
+	}
+
+	w.failures++
+	w.logf("'%s' failed its health check (%d/%d): %s", w.name, w.failures, cfg.Failures, strings.Join(report.Failures(), "; "))
+	if w.failures < cfg.Failures {
+		return interval, nil
+	}
+	w.failures = 0
+
+	incident := types.HealthIncident{Time: report.Time, Failures: report.Failures()}
+	if !w.allowRestart() {
+		incident.Error = fmt.Sprintf("gave up after %d restart(s) within %s", len(w.restarts), w.opts.RestartWindow)
+		w.recordIncident(&srv, incident)
+		return 0, fmt.Errorf("'%s' is still unhealthy after %d restart(s) within %s, giving up",
+			w.name, len(w.restarts), w.opts.RestartWindow)
+	}
+
+	if err := w.restart(&srv); err != nil {
+		// Left stopped; the next checks wait for it to be started by hand
+		w.logf("Failed to restart '%s': %v", w.name, err)
+		incident.Error = err.Error()
+	} else {
+		incident.Restarted = true
+	}
+	w.recordIncident(&srv, incident)
+	return interval, nil
+}
+
+// allowRestart reports whether the server may be restarted again, counting
+// the restart if so
+func (w *watcher) allowRestart() bool {
+	now := time.Now()
+	recent := w.restarts[:0]
+	for _, t := range w.restarts {
+		if now.Sub(t) < w.opts.RestartWindow {
+			recent = append(recent, t)
+		}
+	}
+	w.restarts = recent
+
+	if len(w.restarts) >= w.opts.MaxRestarts {
+		return false
+	}
+	w.restarts = append(w.restarts, now)
+	return true
+}
+
+// restart stops an unhealthy server if it's still running and starts it again
+func (w *watcher) restart(srv *types.Server) error {
+	w.logf("Restarting '%s'", w.name)
+	if w.pm.IsRunning(srv) {
+		if err := w.pm.Stop(srv); err != nil {
+			return fmt.Errorf("failed to stop: %w", err)
+		}
+	}
+
+	// A daemon finding the old PID dead would start the server as well;
+	// with no PID it takes the server for stopped on purpose
+	srv.PID = 0
+	if err := w.reg.Reload(); err == nil {
+		w.reg.UpdatePID(w.name, 0)
+	}
+
+	if err := w.pm.Start(srv); err != nil {
+		return err
+	}
+
+	// Pick up changes other inkwash commands made since the check started
+	err := w.reg.Reload()
+	if err == nil {
+		err = w.reg.Update(*srv)
+	}
+	if err != nil {
+		w.logf("Restarted '%s' but the registry couldn't be updated: %v", w.name, err)
+		return nil
+	}
+	w.logf("Restarted '%s' (PID %d)", w.name, srv.PID)
+	return nil
+}
+
+// recordCrash collects the log and dumps of a server whose process exited.
+// Crashes the daemon already recorded are skipped.
+func (w *watcher) recordCrash(srv *types.Server) {
+	report, err := server.RecordCrash(srv)
+	if report != nil {
+		w.logf("'%s' crashed; saved the end of the log and %d crash dump(s) in %s", w.name, len(report.Dumps), report.Dir)
+	}
+	if err != nil {
+		w.logf("Failed to collect the crash of '%s': %v", w.name, err)
+	}
+}
+
+// recordIncident adds an incident to the server's metadata.json
+func (w *watcher) recordIncident(srv *types.Server, incident types.HealthIncident) {
+	metadata, err := w.mm.Load(srv.Path)
+	if err == nil {
+		metadata.RecordIncident(incident)
+		err = w.mm.Save(srv.Path, metadata)
+	}
+	if err != nil {
+		w.logf("Failed to record the incident in metadata.json: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+// Package database creates the MySQL/MariaDB databases servers use through
+// oxmysql. It runs the mysql (or mariadb) command-line client, the way the
+// installer runs git, so no database driver is built in.
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+)
+
+// ErrClientNotFound is returned when neither mysql nor mariadb is installed
+var ErrClientNotFound = errors.New("mysql client not found")
+
+// ConnectionConvar is the server.cfg convar oxmysql reads its connection string from
+const ConnectionConvar = "mysql_connection_string"
+
+// DefaultPort is MySQL's and MariaDB's default port
+const DefaultPort = 3306
+
+// identifierPattern keeps database and user names free of anything that
+// would need quoting in SQL or a connection string
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,32}$`)
+
+// Options describes the database to set up
+type Options struct {
+	Host          string // Defaults to 127.0.0.1
+	Port          int    // Defaults to DefaultPort
+	AdminUser     string // Account that creates the database and user, defaults to root
+	AdminPassword string
+
+	Database string // Created if missing
+	User     string // Account the server connects with, created if missing
+	Password string // Generated when empty
+	UserHost string // Hosts User may connect from, defaults to % (any)
+
+	SkipCreate bool     // Use an existing database and user, only running SQLFiles
+	SQLFiles   []string // Run in order against Database with the admin account
+}
+
+// Result is what Setup did
+type Result struct {
+	Database         string
+	User             string
+	ConnectionString string
+	Password         string // The user's password, generated or given
+	Generated        bool   // Password was generated
+}
+
+// StepFunc is told what Setup is doing, e.g. to show progress
+type StepFunc func(step string)
+
+// FindClient returns the path of the mysql or mariadb command-line client
+func FindClient() (string, error) {
+	for _, name := range []string{"mariadb", "mysql"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: install the mysql or mariadb client and try again", ErrClientNotFound)
+}
+
+// ValidateIdentifier checks a database or user name
+func ValidateIdentifier(kind, name string) error {
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("invalid %s name '%s': use up to 32 letters, digits and '_'", kind, name)
+	}
+	return nil
+}
+
+// DefaultName derives a database and user name from a server name
+func DefaultName(serverName string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(serverName) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	name := strings.Trim(b.String(), "_")
+	if name == "" {
+		name = "fivem"
+	}
+	if len(name) > 32 {
+		name = strings.TrimRight(name[:32], "_")
+	}
+	return name
+}
+
+// ParseAdmin reads an admin account given as user[:password]@host[:port],
+// as the create wizard asks for it
+func ParseAdmin(s string) (Options, error) {
+	var opts Options
+	// The last @, since the password may have one
+	account, address := "", strings.TrimSpace(s)
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		account, address = address[:at], address[at+1:]
+	}
+	opts.AdminUser, opts.AdminPassword, _ = strings.Cut(account, ":")
+
+	opts.Host = address
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return opts, fmt.Errorf("invalid MySQL port '%s'", port)
+		}
+		opts.Host, opts.Port = host, p
+	}
+	if opts.Host == "" {
+		return opts, fmt.Errorf("no MySQL host in '%s': use user[:password]@host[:port]", s)
+	}
+	return opts, nil
+}
+
+// ForServer names the database and user after a server unless they're set
+func (o Options) ForServer(serverName string) Options {
+	if o.Database == "" {
+		o.Database = DefaultName(serverName)
+	}
+	if o.User == "" {
+		o.User = o.Database
+	}
+	return o
+}
+
+// withDefaults fills in the options left empty
+func (o Options) withDefaults() Options {
+	if o.Host == "" {
+		o.Host = "127.0.0.1"
+	}
+	if o.Port == 0 {
+		o.Port = DefaultPort
+	}
+	if o.AdminUser == "" {
+		o.AdminUser = "root"
+	}
+	if o.UserHost == "" {
+		o.UserHost = "%"
+	}
+	return o
+}
+
+// Setup creates the database and user, grants the user the database and runs
+// the SQL files. Creating is idempotent: an existing database is kept, and an
+// existing user gets the given password.
+func Setup(ctx context.Context, opts Options, onStep StepFunc) (*Result, error) {
+	opts = opts.withDefaults()
+	if err := ValidateIdentifier("database", opts.Database); err != nil {
+		return nil, err
+	}
+	if err := ValidateIdentifier("user", opts.User); err != nil {
+		return nil, err
+	}
+	if opts.SkipCreate && opts.Password == "" {
+		return nil, fmt.Errorf("the password of the existing user '%s' is needed for the connection string", opts.User)
+	}
+	for _, file := range opts.SQLFiles {
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("SQL file %s: %w", file, err)
+		}
+	}
+
+	client, err := FindClient()
+	if err != nil {
+		return nil, err
+	}
+	if onStep == nil {
+		onStep = func(string) {}
+	}
+
+	result := &Result{Database: opts.Database, User: opts.User, Password: opts.Password}
+	if result.Password == "" {
+		if result.Password, err = generatePassword(); err != nil {
+			return nil, err
+		}
+		result.Generated = true
+	}
+
+	// The admin password goes in an option file rather than on the command
+	// line, where other users could see it
+	optionFile, err := writeOptionFile(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(optionFile)
+
+	if !opts.SkipCreate {
+		onStep(fmt.Sprintf("Creating database %s on %s:%d", opts.Database, opts.Host, opts.Port))
+		account := fmt.Sprintf("'%s'@'%s'", opts.User, escape(opts.UserHost))
+		statements := strings.Join([]string{
+			fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", opts.Database),
+			fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY '%s'", account, escape(result.Password)),
+			fmt.Sprintf("ALTER USER %s IDENTIFIED BY '%s'", account, escape(result.Password)),
+			fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO %s", opts.Database, account),
+			"FLUSH PRIVILEGES",
+		}, ";\n") + ";\n"
+		if err := run(ctx, client, optionFile, "", strings.NewReader(statements)); err != nil {
+			return nil, fmt.Errorf("failed to create the database: %w", err)
+		}
+	}
+
+	for _, file := range opts.SQLFiles {
+		onStep("Running " + filepath.Base(file))
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		err = run(ctx, client, optionFile, opts.Database, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", file, err)
+		}
+	}
+
+	result.ConnectionString = ConnectionString(opts.Host, opts.Port, opts.Database, opts.User, result.Password)
+	return result, nil
+}
+
+// ConnectionString returns an oxmysql connection string
+func ConnectionString(host string, port int, database, user, password string) string {
+	u := url.URL{
+		Scheme:   "mysql",
+		User:     url.UserPassword(user, password),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     "/" + database,
+		RawQuery: "charset=utf8mb4",
+	}
+	return u.String()
+}
+
+// WriteConnectionString sets the connection string in a server.cfg,
+// replacing what a recipe's placeholder or an earlier setup left there
+func WriteConnectionString(cfgPath, connection string) error {
+	cfg, err := servercfg.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(ConnectionConvar, connection); err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(cfgPath), err)
+	}
+	return nil
+}
+
+// SetupCommand returns the 'inkwash db setup' command that sets up a server's
+// database and runs the given SQL files
+func SetupCommand(serverName string, sqlFiles []string) string {
+	command := "inkwash db setup " + serverName
+	for _, file := range sqlFiles {
+		// Quoted, as shells would expand the brackets of resource categories
+		command += ` --sql "` + file + `"`
+	}
+	return command
+}
+
+// run feeds SQL to the client, returning its error output on failure
+func run(ctx context.Context, client, optionFile, database string, sql io.Reader) error {
+	args := []string{"--defaults-extra-file=" + optionFile, "--batch"}
+	if database != "" {
+		args = append(args, database)
+	}
+	cmd := exec.CommandContext(ctx, client, args...)
+	cmd.Stdin = sql
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeOptionFile writes the admin account to a file only this user can read
+func writeOptionFile(opts Options) (string, error) {
+	f, err := os.CreateTemp("", "inkwash-mysql-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to write the MySQL option file: %w", err)
+	}
+	defer f.Close()
+
+	content := fmt.Sprintf("[client]\nhost=%s\nport=%d\nuser=%s\npassword=\"%s\"\n",
+		opts.Host, opts.Port, opts.AdminUser, optionEscape(opts.AdminPassword))
+	if _, err := f.WriteString(content); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write the MySQL option file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// escape escapes a string for a single-quoted SQL literal
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// optionEscape escapes a string for a double-quoted option file value
+func optionEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// generatePassword returns a random password that needs no escaping anywhere
+func generatePassword() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a password: %w", err)
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b), nil
+}
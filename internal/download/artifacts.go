@@ -1,14 +1,16 @@
 package download
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/VexoaXYZ/inkwash/internal/network"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
@@ -20,27 +22,143 @@ const (
 // ArtifactClient handles fetching FiveM server builds
 type ArtifactClient struct {
 	httpClient *http.Client
+	targetOS   string
+	cacheDir   string // Where build lists are cached ("" disables caching)
 }
 
-// NewArtifactClient creates a new artifact client
+// NewArtifactClient creates a new artifact client for the host platform
 func NewArtifactClient() *ArtifactClient {
 	return &ArtifactClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient: network.NewAPIClient(),
+		targetOS:   types.HostTargetOS(),
+		cacheDir:   registry.GetDefaultCachePath(),
 	}
 }
 
-// FetchBuilds fetches available builds from the FiveM artifacts page
+// ValidateTargetOS checks that builds are published for a platform
+func ValidateTargetOS(targetOS string) error {
+	switch targetOS {
+	case types.TargetWindows, types.TargetLinux:
+		return nil
+	}
+	return fmt.Errorf("unsupported target OS %q (must be %s or %s)", targetOS, types.TargetWindows, types.TargetLinux)
+}
+
+// SetTargetOS selects which platform's builds to fetch. An empty value means the host platform.
+func (ac *ArtifactClient) SetTargetOS(targetOS string) error {
+	if targetOS == "" {
+		targetOS = types.HostTargetOS()
+	}
+	if err := ValidateTargetOS(targetOS); err != nil {
+		return err
+	}
+	ac.targetOS = targetOS
+	return nil
+}
+
+// TargetOS returns the platform builds are fetched for
+func (ac *ArtifactClient) TargetOS() string {
+	return ac.targetOS
+}
+
+// SetTimeout replaces the API timeout for this client's requests
+func (ac *ArtifactClient) SetTimeout(timeout time.Duration) {
+	ac.httpClient = network.NewClient(timeout)
+}
+
+// CachedBuilds returns the last build list saved for the target platform,
+// however old, without touching the network. It returns nil if nothing is cached.
+func (ac *ArtifactClient) CachedBuilds() []types.Build {
+	if ac.cacheDir == "" {
+		return nil
+	}
+	if cached := loadBuildList(buildListPath(ac.cacheDir, ac.targetOS)); cached != nil {
+		return cached.Builds
+	}
+	return nil
+}
+
+// FetchBuilds fetches available builds. Which builds are recommended and
+// optional comes from the changelog API; the full list comes from the
+// artifacts page, whose last listing is cached on disk and revalidated with a
+// conditional GET, so an unchanged page costs a 304 instead of a full
+// download. If one of the two can't be reached the other is used alone: the
+// page's own recommended and optional markers, or the channel builds on top
+// of the last listing cached.
 func (ac *ArtifactClient) FetchBuilds() ([]types.Build, error) {
-	url := ac.getArtifactURL()
+	var cached *buildList
+	var cachePath string
+	if ac.cacheDir != "" {
+		cachePath = buildListPath(ac.cacheDir, ac.targetOS)
+		cached = loadBuildList(cachePath)
+	}
+
+	if cached != nil && cached.fresh() {
+		network.Debugf("build list cache hit for %s (fetched %s ago)", ac.targetOS, time.Since(cached.FetchedAt).Round(time.Second))
+		return cached.Builds, nil
+	}
+
+	ctx, cancel := network.RequestContext(context.Background())
+	defer cancel()
+
+	channels, apiErr := ac.fetchChannels(ctx)
+	if apiErr != nil {
+		network.Debugf("changelog API unavailable for %s, using the artifacts page alone: %v", ac.targetOS, apiErr)
+	}
+
+	listing, err := ac.fetchListing(ctx, cached)
+	if err != nil {
+		if channels == nil {
+			return nil, err
+		}
+		network.Debugf("artifacts page unavailable for %s, using the changelog API: %v", ac.targetOS, err)
+		var known []types.Build
+		if cached != nil {
+			known = cached.Builds
+		}
+		return channels.apply(known), nil
+	}
+
+	if channels != nil {
+		listing.Builds = channels.apply(listing.Builds)
+	}
+
+	if cachePath != "" {
+		listing.FetchedAt = time.Now()
+		saveBuildList(cachePath, listing)
+	}
+
+	return listing.Builds, nil
+}
 
-	resp, err := ac.httpClient.Get(url)
+// fetchListing fetches and parses the artifacts page, returning cached
+// (with its builds) if the page hasn't changed since it was fetched
+func (ac *ArtifactClient) fetchListing(ctx context.Context, cached *buildList) (*buildList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ac.getArtifactURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := ac.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch artifacts: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		network.Debugf("build list for %s not modified, using cached copy", ac.targetOS)
+		return cached, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -51,12 +169,52 @@ func (ac *ArtifactClient) FetchBuilds() ([]types.Build, error) {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	return ac.parseBuilds(doc)
+	builds, err := ac.parseBuilds(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &buildList{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Builds:       builds,
+	}, nil
 }
 
-// getArtifactURL returns the appropriate artifact URL for the current platform
+// ResolveBuild picks the build a channel ("recommended", "optional" or "latest") currently points to
+func ResolveBuild(builds []types.Build, channel string) (*types.Build, error) {
+	var resolved *types.Build
+
+	for i := range builds {
+		build := &builds[i]
+		switch channel {
+		case types.BuildChannelRecommended:
+			if build.Recommended {
+				return build, nil
+			}
+		case types.BuildChannelOptional:
+			if build.Optional {
+				return build, nil
+			}
+		case types.BuildChannelLatest:
+			if resolved == nil || build.Number > resolved.Number {
+				resolved = build
+			}
+		default:
+			return nil, fmt.Errorf("unknown build channel %q", channel)
+		}
+	}
+
+	if resolved == nil {
+		return nil, fmt.Errorf("no %s build is currently listed", channel)
+	}
+
+	return resolved, nil
+}
+
+// getArtifactURL returns the appropriate artifact URL for the target platform
 func (ac *ArtifactClient) getArtifactURL() string {
-	if runtime.GOOS == "windows" {
+	if ac.targetOS == types.TargetWindows {
 		return WindowsArtifactURL
 	}
 	return LinuxArtifactURL
@@ -176,7 +334,7 @@ func (ac *ArtifactClient) GetDownloadURL(build types.Build) string {
 	var baseURL string
 	var filename string
 
-	if runtime.GOOS == "windows" {
+	if ac.targetOS == types.TargetWindows {
 		baseURL = WindowsArtifactURL
 		filename = "server.7z"
 	} else {
@@ -187,27 +345,8 @@ func (ac *ArtifactClient) GetDownloadURL(build types.Build) string {
 	return fmt.Sprintf("%s%s/%s", baseURL, build.Hash, filename)
 }
 
-// GetFileSize gets the size of a file from a URL using HEAD request
+// GetFileSize gets the size of a file from a URL, falling back to a ranged
+// GET when the server rejects HEAD. Returns 0 if the size can't be determined.
 func (ac *ArtifactClient) GetFileSize(url string) (int64, error) {
-	resp, err := ac.httpClient.Head(url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get file size: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	contentLength := resp.Header.Get("Content-Length")
-	if contentLength == "" {
-		return 0, fmt.Errorf("no content-length header")
-	}
-
-	size, err := strconv.ParseInt(contentLength, 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse content-length: %w", err)
-	}
-
-	return size, nil
+	return probeFileSize(context.Background(), ac.httpClient, url)
 }
@@ -0,0 +1,60 @@
+package download
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// buildListTTL is how long a cached build list is used without asking the server
+const buildListTTL = 5 * time.Minute
+
+// buildList is the on-disk copy of the last artifacts listing for one platform
+type buildList struct {
+	ETag         string        `json:"etag,omitempty"`
+	LastModified string        `json:"last_modified,omitempty"`
+	FetchedAt    time.Time     `json:"fetched_at"`
+	Builds       []types.Build `json:"builds"`
+}
+
+// fresh reports whether the list is recent enough to skip revalidation
+func (bl *buildList) fresh() bool {
+	return time.Since(bl.FetchedAt) < buildListTTL
+}
+
+// buildListPath returns where the build list for a platform is cached
+func buildListPath(cacheDir, targetOS string) string {
+	return filepath.Join(cacheDir, "artifacts_"+targetOS+".json")
+}
+
+// loadBuildList reads a cached build list, returning nil if there isn't a usable one
+func loadBuildList(path string) *buildList {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var bl buildList
+	if err := json.Unmarshal(data, &bl); err != nil || len(bl.Builds) == 0 {
+		return nil
+	}
+
+	return &bl
+}
+
+// saveBuildList writes a build list to the cache
+func saveBuildList(path string, bl *buildList) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(bl, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,152 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Cfx's changelog API, which names the builds each channel points to
+const (
+	WindowsChangelogURL = "https://changelogs-live.fivem.net/api/changelog/versions/win32/server"
+	LinuxChangelogURL   = "https://changelogs-live.fivem.net/api/changelog/versions/linux/server"
+)
+
+// changelogVersions is the changelog API's answer: each channel's build
+// number and the archive to download it from
+type changelogVersions struct {
+	Recommended         string `json:"recommended"`
+	RecommendedDownload string `json:"recommended_download"`
+	Optional            string `json:"optional"`
+	OptionalDownload    string `json:"optional_download"`
+	Latest              string `json:"latest"`
+	LatestDownload      string `json:"latest_download"`
+}
+
+// channelBuilds are the builds the changelog API lists, by channel
+type channelBuilds struct {
+	recommended *types.Build
+	optional    *types.Build
+	latest      *types.Build
+}
+
+// fetchChannels asks the changelog API which builds the channels point to
+func (ac *ArtifactClient) fetchChannels(ctx context.Context) (*channelBuilds, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ac.getChangelogURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ac.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch changelog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("changelog API returned status code %d", resp.StatusCode)
+	}
+
+	var versions changelogVersions
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to parse changelog: %w", err)
+	}
+
+	channels := &channelBuilds{
+		recommended: changelogBuild(versions.Recommended, versions.RecommendedDownload),
+		optional:    changelogBuild(versions.Optional, versions.OptionalDownload),
+		latest:      changelogBuild(versions.Latest, versions.LatestDownload),
+	}
+	if channels.recommended == nil && channels.optional == nil && channels.latest == nil {
+		return nil, fmt.Errorf("changelog API listed no builds")
+	}
+	if channels.recommended != nil {
+		channels.recommended.Recommended = true
+	}
+	if channels.optional != nil {
+		channels.optional.Optional = true
+	}
+
+	return channels, nil
+}
+
+// changelogBuild turns a channel's version and download URL into a build, or
+// nil if the URL isn't in the artifacts server's "<number>-<hash>/" layout
+func changelogBuild(version, downloadURL string) *types.Build {
+	if downloadURL == "" {
+		return nil
+	}
+	parsed, _, err := validation.ParseArtifactURL(downloadURL)
+	if err != nil || parsed.Number == 0 {
+		return nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(version)); err == nil && n != parsed.Number {
+		return nil
+	}
+
+	return &types.Build{
+		Number: parsed.Number,
+		Hash:   fmt.Sprintf("%d-%s", parsed.Number, parsed.Hash),
+	}
+}
+
+// apply marks the channels' builds in builds, clearing flags the artifacts
+// page had on other builds, and adds any the list is missing. The result is
+// newest first when builds was.
+func (cb *channelBuilds) apply(builds []types.Build) []types.Build {
+	result := make([]types.Build, len(builds))
+	copy(result, builds)
+
+	for i := range result {
+		result[i].Recommended = cb.recommended != nil && result[i].Number == cb.recommended.Number
+		result[i].Optional = cb.optional != nil && result[i].Number == cb.optional.Number
+	}
+
+	for _, build := range []*types.Build{cb.latest, cb.optional, cb.recommended} {
+		if build == nil || containsBuild(result, build.Number) {
+			continue
+		}
+		b := *build
+		b.Recommended = cb.recommended != nil && b.Number == cb.recommended.Number
+		b.Optional = cb.optional != nil && b.Number == cb.optional.Number
+		result = insertBuild(result, b)
+	}
+
+	return result
+}
+
+// containsBuild reports whether builds has the build numbered number
+func containsBuild(builds []types.Build, number int) bool {
+	for _, b := range builds {
+		if b.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// insertBuild adds build before the first older build, keeping a newest-first
+// list in order
+func insertBuild(builds []types.Build, build types.Build) []types.Build {
+	for i, b := range builds {
+		if b.Number < build.Number {
+			return append(builds[:i], append([]types.Build{build}, builds[i:]...)...)
+		}
+	}
+	return append(builds, build)
+}
+
+// getChangelogURL returns the changelog API URL for the target platform
+func (ac *ArtifactClient) getChangelogURL() string {
+	if ac.targetOS == types.TargetWindows {
+		return WindowsChangelogURL
+	}
+	return LinuxChangelogURL
+}
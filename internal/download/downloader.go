@@ -1,13 +1,18 @@
 package download
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
 )
 
 // Progress represents download progress
@@ -17,8 +22,14 @@ type Progress struct {
 	Speed           float64 // MB/s
 	ETA             time.Duration
 	ChunkProgress   []int64 // Bytes downloaded per chunk
+	Resumed         int64   // Bytes kept from an earlier, interrupted attempt
+	ChunkAttempts   []int   // Attempt each chunk is on, from 1
+	Retries         int     // Failed attempts retried so far, across all chunks
 }
 
+// ErrIncomplete is returned when fewer bytes were written than the server advertised
+var ErrIncomplete = errors.New("download incomplete")
+
 // ProgressCallback is called periodically with download progress
 type ProgressCallback func(Progress)
 
@@ -26,24 +37,43 @@ type ProgressCallback func(Progress)
 type Downloader struct {
 	httpClient *http.Client
 	numChunks  int
+	retry      network.RetryPolicy
 }
 
-// NewDownloader creates a new downloader
+// NewDownloader creates a new downloader that retries failed requests under
+// network.Retry()
 func NewDownloader(numChunks int) *Downloader {
 	if numChunks <= 0 {
 		numChunks = 3
 	}
 
 	return &Downloader{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Minute,
-		},
-		numChunks: numChunks,
+		httpClient: network.NewDownloadClient(),
+		numChunks:  numChunks,
+		retry:      network.Retry(),
 	}
 }
 
+// SetRetryPolicy replaces the policy failed requests are retried under
+func (d *Downloader) SetRetryPolicy(p network.RetryPolicy) {
+	d.retry = p
+}
+
+// retryable reports whether a failed download is worth trying again. Short
+// transfers are, since every retry continues from the bytes already written.
+func retryable(err error) bool {
+	return errors.Is(err, ErrIncomplete) || network.Temporary(err)
+}
+
 // Download downloads a file with parallel chunks
 func (d *Downloader) Download(url, destPath string, onProgress ProgressCallback) error {
+	return d.DownloadContext(context.Background(), url, destPath, onProgress)
+}
+
+// DownloadContext is Download, stopping early with ctx.Err() when ctx is cancelled.
+// Partial files are left behind, and downloading the same URL to the same
+// destPath again continues from them; they're removed once it completes.
+func (d *Downloader) DownloadContext(ctx context.Context, url, destPath string, onProgress ProgressCallback) error {
 	// Create destination directory if it doesn't exist
 	destDir := filepath.Dir(destPath)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
@@ -51,39 +81,99 @@ func (d *Downloader) Download(url, destPath string, onProgress ProgressCallback)
 	}
 
 	// Get file size
-	totalSize, err := d.getFileSize(url)
+	totalSize, err := d.getFileSize(ctx, url)
 	if err != nil {
 		return err
 	}
 
 	// If size is unknown, use streaming download
 	if totalSize == 0 {
-		return d.downloadStreaming(url, destPath, onProgress)
+		return d.retry.Do(ctx, retryable, nil, func(attempt int) error {
+			return d.downloadStreaming(ctx, url, destPath, attempt, onProgress)
+		})
 	}
 
 	// Check if server supports range requests
-	supportsRanges, err := d.supportsRangeRequests(url)
+	supportsRanges, err := d.supportsRangeRequests(ctx, url)
 	if err != nil {
 		return err
 	}
 
 	if !supportsRanges {
 		// Fallback to single download
-		return d.downloadSingle(url, destPath, totalSize, onProgress)
+		return d.retry.Do(ctx, retryable, nil, func(attempt int) error {
+			return d.downloadSingle(ctx, url, destPath, totalSize, attempt, onProgress)
+		})
 	}
 
 	// Download in parallel chunks
-	return d.downloadParallel(url, destPath, totalSize, onProgress)
+	return d.downloadParallel(ctx, url, destPath, totalSize, onProgress)
+}
+
+// byteRange is the inclusive byte range of one chunk
+type byteRange struct {
+	start, end int64
+}
+
+// size returns the number of bytes in the range
+func (r byteRange) size() int64 {
+	return r.end - r.start + 1
+}
+
+// chunkRanges splits totalSize bytes into numChunks ranges; the last one gets any remainder
+func chunkRanges(totalSize int64, numChunks int) []byteRange {
+	chunkSize := totalSize / int64(numChunks)
+
+	ranges := make([]byteRange, numChunks)
+	for i := range ranges {
+		start := int64(i) * chunkSize
+		end := start + chunkSize - 1
+		if i == numChunks-1 {
+			end = totalSize - 1
+		}
+		ranges[i] = byteRange{start, end}
+	}
+	return ranges
 }
 
-// downloadParallel downloads a file in parallel chunks
-func (d *Downloader) downloadParallel(url, destPath string, totalSize int64, onProgress ProgressCallback) error {
-	chunkSize := totalSize / int64(d.numChunks)
+// chunkPath returns where chunk i of destPath is downloaded to
+func chunkPath(destPath string, i int) string {
+	return fmt.Sprintf("%s.part%d", destPath, i)
+}
+
+// chunkSize returns the size of a chunk file on disk, or -1 if it doesn't exist
+func chunkSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
 
-	// Create progress tracker
+// downloadParallel downloads a file in parallel chunks. Chunks left by an
+// earlier failed run of the same download are resumed, and each chunk is
+// retried on its own, continuing from what it already has.
+func (d *Downloader) downloadParallel(ctx context.Context, url, destPath string, totalSize int64, onProgress ProgressCallback) error {
+	ranges := chunkRanges(totalSize, d.numChunks)
+	if err := prepareParts(destPath, partManifest{URL: url, TotalSize: totalSize, Chunks: d.numChunks}); err != nil {
+		return fmt.Errorf("failed to prepare download: %w", err)
+	}
+
+	// Create progress tracker, counting what earlier runs already fetched
 	progress := Progress{
 		TotalBytes:    totalSize,
 		ChunkProgress: make([]int64, d.numChunks),
+		ChunkAttempts: make([]int, d.numChunks),
+	}
+	for i, r := range ranges {
+		progress.ChunkAttempts[i] = 1
+		if size := chunkSize(chunkPath(destPath, i)); size > 0 && size <= r.size() {
+			progress.ChunkProgress[i] = size
+			progress.Resumed += size
+		}
+	}
+	if progress.Resumed > 0 {
+		network.Debugf("resuming %s with %d of %d bytes", filepath.Base(destPath), progress.Resumed, totalSize)
 	}
 
 	var wg sync.WaitGroup
@@ -96,49 +186,89 @@ func (d *Downloader) downloadParallel(url, destPath string, totalSize int64, onP
 	go d.reportProgress(&progress, &mu, onProgress, progressChan, stopProgress)
 
 	// Download chunks
-	for i := 0; i < d.numChunks; i++ {
-		wg.Add(1)
-		go func(chunkID int) {
-			defer wg.Done()
-
-			start := int64(chunkID) * chunkSize
-			end := start + chunkSize - 1
+	for i, r := range ranges {
+		path := chunkPath(destPath, i)
 
-			// Last chunk gets any remainder
-			if chunkID == d.numChunks-1 {
-				end = totalSize - 1
-			}
+		// A complete chunk from an earlier run doesn't need downloading again
+		if chunkSize(path) == r.size() {
+			continue
+		}
 
-			chunkPath := fmt.Sprintf("%s.part%d", destPath, chunkID)
+		wg.Add(1)
+		go func(chunkID int, r byteRange, path string) {
+			defer wg.Done()
 
-			if err := d.downloadChunk(url, start, end, chunkPath, chunkID, &progress, &mu, progressChan); err != nil {
+			if err := d.fetchChunk(ctx, url, r, path, chunkID, &progress, &mu, progressChan); err != nil {
 				errChan <- fmt.Errorf("chunk %d failed: %w", chunkID, err)
 			}
-		}(i)
+		}(i, r, path)
 	}
 
 	wg.Wait()
-	close(stopProgress)
 	close(errChan)
 
+	// Report the cancellation rather than whichever chunk noticed it first
+	if err := ctx.Err(); err != nil {
+		close(stopProgress)
+		return err
+	}
+
+	close(stopProgress)
+
 	// Check for errors
 	if len(errChan) > 0 {
 		return <-errChan
 	}
 
 	// Merge chunks
-	return d.mergeChunks(destPath, d.numChunks)
+	if err := d.mergeChunks(destPath, ranges); err != nil {
+		return err
+	}
+
+	return verifySize(destPath, totalSize)
 }
 
-// downloadChunk downloads a single chunk
-func (d *Downloader) downloadChunk(url string, start, end int64, destPath string, chunkID int, progress *Progress, mu *sync.Mutex, progressChan chan struct{}) error {
-	req, err := http.NewRequest("GET", url, nil)
+// fetchChunk downloads a chunk, retrying failures the retry policy allows.
+// Every retry continues from the bytes the chunk already has.
+func (d *Downloader) fetchChunk(ctx context.Context, url string, r byteRange, path string, chunkID int, progress *Progress, mu *sync.Mutex, progressChan chan struct{}) error {
+	onRetry := func(attempt int, err error, wait time.Duration) {
+		mu.Lock()
+		progress.ChunkAttempts[chunkID] = attempt + 1
+		progress.Retries++
+		mu.Unlock()
+	}
+
+	return d.retry.Do(ctx, retryable, onRetry, func(attempt int) error {
+		return d.downloadChunk(ctx, url, r, path, chunkID, progress, mu, progressChan)
+	})
+}
+
+// downloadChunk downloads a single chunk into destPath, continuing from the
+// bytes an earlier attempt left there. A chunk file that has outgrown its
+// range is started over. A connection can end cleanly before the range is
+// complete, which is reported as ErrIncomplete.
+func (d *Downloader) downloadChunk(ctx context.Context, url string, r byteRange, destPath string, chunkID int, progress *Progress, mu *sync.Mutex, progressChan chan struct{}) error {
+	have := chunkSize(destPath)
+	if have < 0 || have > r.size() {
+		have = 0
+	}
+
+	mu.Lock()
+	progress.ChunkProgress[chunkID] = have
+	mu.Unlock()
+
+	if have == r.size() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
 	// Set range header
-	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	start := r.start + have
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, r.end))
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
@@ -146,12 +276,20 @@ func (d *Downloader) downloadChunk(url string, start, end int64, destPath string
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	// Anything but the requested range would be appended in the wrong place
+	if resp.StatusCode != http.StatusPartialContent {
+		return network.NewStatusError(resp)
+	}
+	if got := contentRangeStart(resp); got >= 0 && got != start {
+		return fmt.Errorf("server sent bytes from %d, asked for %d", got, start)
 	}
 
-	// Create chunk file
-	file, err := os.Create(destPath)
+	// Open chunk file, keeping what's already there
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if have > 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(destPath, flags, 0644)
 	if err != nil {
 		return err
 	}
@@ -186,6 +324,9 @@ func (d *Downloader) downloadChunk(url string, start, end int64, destPath string
 		}
 	}
 
+	if size := chunkSize(destPath); size != r.size() {
+		return fmt.Errorf("%w: chunk %d received %d of %d bytes", ErrIncomplete, chunkID, max(size, 0), r.size())
+	}
 	return nil
 }
 
@@ -198,9 +339,11 @@ func (d *Downloader) reportProgress(progress *Progress, mu *sync.Mutex, callback
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
-	startTime := time.Now()
-	lastBytes := int64(0)
-	lastTime := startTime
+	// Bytes resumed from an earlier run don't count towards the speed
+	mu.Lock()
+	lastBytes := progress.Resumed
+	mu.Unlock()
+	lastTime := time.Now()
 
 	for {
 		select {
@@ -243,53 +386,117 @@ func (d *Downloader) reportProgress(progress *Progress, mu *sync.Mutex, callback
 	}
 }
 
-// mergeChunks merges chunk files into the final file
-func (d *Downloader) mergeChunks(destPath string, numChunks int) error {
+// mergeChunks merges chunk files into the final file. Every chunk must match
+// its range; the chunk files are only removed once the merge has succeeded,
+// so a failed merge can be retried without downloading them again.
+func (d *Downloader) mergeChunks(destPath string, ranges []byteRange) error {
+	for i, r := range ranges {
+		if size := chunkSize(chunkPath(destPath, i)); size != r.size() {
+			return fmt.Errorf("%w: chunk %d has %d of %d bytes", ErrIncomplete, i, max(size, 0), r.size())
+		}
+	}
+
 	// Create final file
 	outFile, err := os.Create(destPath)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer outFile.Close()
 
 	// Merge chunks in order
-	for i := 0; i < numChunks; i++ {
-		chunkPath := fmt.Sprintf("%s.part%d", destPath, i)
-
+	for i := range ranges {
 		// Open chunk file
-		chunkFile, err := os.Open(chunkPath)
+		chunkFile, err := os.Open(chunkPath(destPath, i))
 		if err != nil {
+			outFile.Close()
 			return fmt.Errorf("failed to open chunk %d: %w", i, err)
 		}
 
 		// Copy chunk to output
 		if _, err := io.Copy(outFile, chunkFile); err != nil {
 			chunkFile.Close()
+			outFile.Close()
 			return fmt.Errorf("failed to copy chunk %d: %w", i, err)
 		}
 
 		chunkFile.Close()
+	}
+
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
 
-		// Delete chunk file
-		os.Remove(chunkPath)
+	// Delete chunk files
+	for i := range ranges {
+		os.Remove(chunkPath(destPath, i))
 	}
+	removeManifest(destPath)
 
 	return nil
 }
 
-// downloadSingle downloads a file without chunking
-func (d *Downloader) downloadSingle(url, destPath string, totalSize int64, onProgress ProgressCallback) error {
-	resp, err := d.httpClient.Get(url)
+// verifySize checks that the file on disk matches the expected size
+func verifySize(path string, expected int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat downloaded file: %w", err)
+	}
+	if info.Size() != expected {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrIncomplete, info.Size(), expected)
+	}
+	return nil
+}
+
+// downloadSingle downloads a file without chunking.
+// A partial file left by an interrupted attempt at the same download is
+// resumed with a Range request when the server honours it; otherwise the
+// download starts over.
+func (d *Downloader) downloadSingle(ctx context.Context, url, destPath string, totalSize int64, attempt int, onProgress ProgressCallback) error {
+	manifest := partManifest{URL: url, TotalSize: totalSize}
+
+	var offset int64
+	if info, err := os.Stat(destPath); err == nil && info.Size() > 0 && info.Size() < totalSize && loadManifest(destPath) == manifest {
+		offset = info.Size()
+	}
+	if err := saveManifest(destPath, manifest); err != nil {
+		return fmt.Errorf("failed to prepare download: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	file, err := os.Create(destPath)
+	var file *os.File
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp) == offset:
+		network.Debugf("resuming %s at byte %d", filepath.Base(destPath), offset)
+		file, err = os.OpenFile(destPath, os.O_WRONLY|os.O_APPEND, 0644)
+
+	case offset > 0 && (resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent):
+		// Range rejected or answered with the wrong bytes: start over
+		resp.Body.Close()
+		if err := os.Remove(destPath); err != nil {
+			return err
+		}
+		return d.downloadSingle(ctx, url, destPath, totalSize, attempt, onProgress)
+
+	case resp.StatusCode == http.StatusOK:
+		// Full body (the server may have ignored the range)
+		offset = 0
+		file, err = os.Create(destPath)
+
+	default:
+		return network.NewStatusError(resp)
+	}
 	if err != nil {
 		return err
 	}
@@ -297,8 +504,12 @@ func (d *Downloader) downloadSingle(url, destPath string, totalSize int64, onPro
 
 	// Download with progress tracking
 	progress := Progress{
-		TotalBytes:    totalSize,
-		ChunkProgress: []int64{0},
+		TotalBytes:      totalSize,
+		DownloadedBytes: offset,
+		ChunkProgress:   []int64{offset},
+		Resumed:         offset,
+		ChunkAttempts:   []int{attempt},
+		Retries:         attempt - 1,
 	}
 
 	buffer := make([]byte, 32*1024)
@@ -317,9 +528,10 @@ func (d *Downloader) downloadSingle(url, destPath string, totalSize int64, onPro
 
 			// Report progress every 100ms
 			if time.Since(lastUpdate) >= 100*time.Millisecond {
+				// Speed only counts bytes fetched in this attempt
 				elapsed := time.Since(startTime).Seconds()
 				if elapsed > 0 {
-					progress.Speed = float64(progress.DownloadedBytes) / elapsed / 1024 / 1024
+					progress.Speed = float64(progress.DownloadedBytes-offset) / elapsed / 1024 / 1024
 				}
 
 				if progress.Speed > 0 {
@@ -344,20 +556,44 @@ func (d *Downloader) downloadSingle(url, destPath string, totalSize int64, onPro
 		}
 	}
 
+	// Prefer the GET response's length, it describes exactly what was sent
+	expected := totalSize
+	if resp.ContentLength > 0 {
+		expected = offset + resp.ContentLength
+	}
+	if progress.DownloadedBytes != expected {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrIncomplete, progress.DownloadedBytes, expected)
+	}
+
+	removeManifest(destPath)
 	return nil
 }
 
+// contentRangeStart returns the first byte offset of a 206 response, or -1 if unknown
+func contentRangeStart(resp *http.Response) int64 {
+	var start int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil {
+		return -1
+	}
+	return start
+}
+
 // downloadStreaming downloads a file without knowing the total size
 // This is used when the server doesn't provide Content-Length headers
-func (d *Downloader) downloadStreaming(url, destPath string, onProgress ProgressCallback) error {
-	resp, err := d.httpClient.Get(url)
+func (d *Downloader) downloadStreaming(ctx context.Context, url, destPath string, attempt int, onProgress ProgressCallback) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return network.NewStatusError(resp)
 	}
 
 	file, err := os.Create(destPath)
@@ -377,6 +613,8 @@ func (d *Downloader) downloadStreaming(url, destPath string, onProgress Progress
 	progress := Progress{
 		TotalBytes:    totalSize, // May be 0 if unknown
 		ChunkProgress: []int64{0},
+		ChunkAttempts: []int{attempt},
+		Retries:       attempt - 1,
 	}
 
 	buffer := make([]byte, 32*1024)
@@ -428,6 +666,10 @@ func (d *Downloader) downloadStreaming(url, destPath string, onProgress Progress
 		}
 	}
 
+	if totalSize > 0 && progress.DownloadedBytes != totalSize {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrIncomplete, progress.DownloadedBytes, totalSize)
+	}
+
 	// Send final progress update
 	if onProgress != nil {
 		elapsed := time.Since(startTime).Seconds()
@@ -444,82 +686,113 @@ func (d *Downloader) downloadStreaming(url, destPath string, onProgress Progress
 
 // getFileSize gets the file size from a URL
 // Returns (size, nil) on success, (0, nil) if size cannot be determined (caller should use streaming),
-// or (0, error) on actual errors
-func (d *Downloader) getFileSize(url string) (int64, error) {
+// or (0, error) if ctx was cancelled
+func (d *Downloader) getFileSize(parent context.Context, url string) (int64, error) {
+	return probeFileSize(parent, d.httpClient, url)
+}
+
+// probeFileSize asks the server for a file's size with a HEAD request, then
+// with a one-byte ranged GET for servers that reject HEAD or leave out
+// Content-Length. It returns 0 if neither tells the size; only a cancelled
+// ctx is an error, since the download itself may still work.
+func probeFileSize(parent context.Context, client *http.Client, url string) (int64, error) {
+	ctx, cancel := network.RequestContext(parent)
+	defer cancel()
+
 	// First try HEAD request
-	resp, err := d.httpClient.Head(url)
+	headReq, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get file size: %w", err)
 	}
-	resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		contentLength := resp.Header.Get("Content-Length")
-		if contentLength != "" {
-			var size int64
-			if _, err := fmt.Sscanf(contentLength, "%d", &size); err == nil && size > 0 {
+	if resp, err := client.Do(headReq); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if size := parseContentLength(resp.Header); size > 0 {
 				return size, nil
 			}
 		}
+	} else if parent.Err() != nil {
+		return 0, parent.Err()
 	}
 
 	// HEAD didn't work, try a GET request with Range header to get Content-Range
 	// This works on some servers that don't support HEAD properly
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return 0, nil // Cannot determine size, use streaming
 	}
 	req.Header.Set("Range", "bytes=0-0")
 
-	resp, err = d.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
+		if parent.Err() != nil {
+			return 0, parent.Err()
+		}
 		return 0, nil // Cannot determine size, use streaming
 	}
+	// The body isn't read: one byte for a range, or the whole file if the range was ignored
 	defer resp.Body.Close()
 
-	// Check Content-Range header (format: "bytes 0-0/TOTAL_SIZE")
-	if resp.StatusCode == http.StatusPartialContent {
-		contentRange := resp.Header.Get("Content-Range")
-		if contentRange != "" {
-			// Parse "bytes 0-0/12345678"
-			var start, end, total int64
-			if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &total); err == nil && total > 0 {
-				return total, nil
-			}
-		}
-	}
-
-	// Also check Content-Length on the response (some servers return it on GET but not HEAD)
-	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
-		contentLength := resp.Header.Get("Content-Length")
-		if contentLength != "" {
-			var size int64
-			if _, err := fmt.Sscanf(contentLength, "%d", &size); err == nil && size > 0 {
-				// If we got a partial response, we need to get the real size differently
-				// Check if there's a Content-Range header
-				contentRange := resp.Header.Get("Content-Range")
-				if contentRange != "" {
-					var start, end, total int64
-					if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &total); err == nil && total > 0 {
-						return total, nil
-					}
-				}
-			}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Content-Range format: "bytes 0-0/TOTAL_SIZE"
+		var start, end, total int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err == nil && total > 0 {
+			return total, nil
 		}
+	case http.StatusOK:
+		// Range ignored, so Content-Length is the whole file
+		return parseContentLength(resp.Header), nil
 	}
 
 	// Cannot determine size, return 0 to indicate streaming download should be used
 	return 0, nil
 }
 
-// supportsRangeRequests checks if the server supports range requests
-func (d *Downloader) supportsRangeRequests(url string) (bool, error) {
-	resp, err := d.httpClient.Head(url)
+// parseContentLength returns the Content-Length header, or 0 if it's missing or invalid
+func parseContentLength(header http.Header) int64 {
+	size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// supportsRangeRequests checks if the server supports range requests. Servers
+// that reject HEAD are asked with a one-byte ranged GET instead; if that fails
+// too the file is downloaded in one piece.
+func (d *Downloader) supportsRangeRequests(parent context.Context, url string) (bool, error) {
+	ctx, cancel := network.RequestContext(parent)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
 
-	acceptRanges := resp.Header.Get("Accept-Ranges")
-	return acceptRanges == "bytes", nil
+	if resp, err := d.httpClient.Do(req); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return resp.Header.Get("Accept-Ranges") == "bytes", nil
+		}
+	} else if parent.Err() != nil {
+		return false, parent.Err()
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		if parent.Err() != nil {
+			return false, parent.Err()
+		}
+		return false, nil
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusPartialContent, nil
 }
@@ -0,0 +1,233 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+)
+
+// truncatingServer serves data with range support, cutting the first
+// response for the range starting at cutStart short after half its bytes
+type truncatingServer struct {
+	data     []byte
+	cutStart int64
+
+	mu       sync.Mutex
+	cut      bool
+	requests []string // Range headers of the chunk requests
+}
+
+func (s *truncatingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	rangeHeader := r.Header.Get("Range")
+	if r.Method == http.MethodHead || rangeHeader == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		if r.Method == http.MethodGet {
+			w.Write(s.data)
+		}
+		return
+	}
+
+	var start, end int64
+	if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+		http.Error(w, "bad range", http.StatusBadRequest)
+		return
+	}
+	body := s.data[start : end+1]
+
+	s.mu.Lock()
+	if rangeHeader != "bytes=0-0" {
+		s.requests = append(s.requests, rangeHeader)
+	}
+	if start == s.cutStart && !s.cut {
+		// End the response cleanly before the range is complete
+		s.cut = true
+		body = body[:len(body)/2]
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.data)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(body)
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestDownloadTruncatedChunkIsRetried(t *testing.T) {
+	data := testData(3000)
+	srv := &truncatingServer{data: data, cutStart: 1000}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	d := NewDownloader(3)
+	d.SetRetryPolicy(network.RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+
+	if err := d.Download(ts.URL, dest, nil); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("downloaded file differs from the served one (%d bytes, want %d)", len(got), len(data))
+	}
+
+	// The cut chunk was asked for again, from where it stopped
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resumed := false
+	for _, r := range srv.requests {
+		if r == "bytes=1500-1999" {
+			resumed = true
+		}
+	}
+	if !resumed {
+		t.Errorf("truncated chunk wasn't resumed; chunk requests: %v", srv.requests)
+	}
+	if _, err := os.Stat(chunkPath(dest, 1)); !os.IsNotExist(err) {
+		t.Errorf("chunk file left behind after merging")
+	}
+}
+
+func TestDownloadTruncatedChunkIsNotMerged(t *testing.T) {
+	data := testData(3000)
+	srv := &truncatingServer{data: data, cutStart: 1000}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	d := NewDownloader(3)
+	d.SetRetryPolicy(network.RetryPolicy{Attempts: 1})
+
+	err := d.DownloadContext(context.Background(), ts.URL, dest, nil)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("Download error = %v, want ErrIncomplete", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("truncated chunk was merged into %s", dest)
+	}
+	if size := chunkSize(chunkPath(dest, 1)); size != 500 {
+		t.Fatalf("truncated chunk has %d bytes on disk, want the 500 received", size)
+	}
+
+	// Downloading again fetches the rest of the chunk
+	if err := d.Download(ts.URL, dest, nil); err != nil {
+		t.Fatalf("second Download: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("downloaded file differs from the served one (%d bytes, want %d)", len(got), len(data))
+	}
+}
+
+// flakyServer serves data without advertising range support, cutting the
+// first response off halfway and failing the first resume with a 503
+type flakyServer struct {
+	data []byte
+
+	mu       sync.Mutex
+	gets     int
+	ranges   []string // Range headers of the downloads
+	failOnce bool
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		return
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader == "bytes=0-0" {
+		// Range probe: not supported
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.mu.Lock()
+	s.gets++
+	s.ranges = append(s.ranges, rangeHeader)
+	gets := s.gets
+	fail := rangeHeader != "" && !s.failOnce
+	if fail {
+		s.failOnce = true
+	}
+	s.mu.Unlock()
+
+	switch {
+	case fail:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case rangeHeader != "":
+		var start int64
+		fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.data)-1, len(s.data)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)-int(start)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(s.data[start:])
+	case gets == 1:
+		// Promise the whole file, then drop the connection halfway
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(s.data[:len(s.data)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	default:
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.Write(s.data)
+	}
+}
+
+func TestDownloadSingleResumesAfterServerError(t *testing.T) {
+	data := testData(64 * 1024)
+	srv := &flakyServer{data: data}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	d := NewDownloader(3)
+	d.SetRetryPolicy(network.RetryPolicy{Attempts: 4, Backoff: time.Millisecond})
+
+	if err := d.Download(ts.URL, dest, nil); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("downloaded file differs from the served one (%d bytes, want %d)", len(got), len(data))
+	}
+
+	// The 503 didn't throw the first half away: both tries after it asked
+	// for the rest only
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	want := fmt.Sprintf("bytes=%d-", len(data)/2)
+	if len(srv.ranges) != 3 || srv.ranges[1] != want || srv.ranges[2] != want {
+		t.Errorf("download requests had ranges %q, want \"\", %q, %q", srv.ranges, want, want)
+	}
+}
@@ -3,19 +3,24 @@ package download
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 
+	"github.com/VexoaXYZ/inkwash/pkg/types"
 	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
 	"github.com/ulikunitz/xz"
 )
 
 // Extractor handles archive extraction
-type Extractor struct{}
+type Extractor struct {
+	longPaths bool // Use \\?\ paths on Windows (see SetLongPaths)
+}
 
 // NewExtractor creates a new extractor
 func NewExtractor() *Extractor {
@@ -24,25 +29,100 @@ func NewExtractor() *Extractor {
 
 // Extract extracts an archive to the destination directory
 func (e *Extractor) Extract(archivePath, destPath string) error {
+	if err := e.CheckDestination(archivePath, destPath); err != nil {
+		return err
+	}
+	if e.longPaths {
+		destPath = longPathName(destPath)
+	}
+
 	// Ensure destination directory exists
 	if err := os.MkdirAll(destPath, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Determine archive type from extension
-	if strings.HasSuffix(archivePath, ".7z") {
+	// Determine archive type from extension, falling back to the file's magic bytes
+	format := formatFromExtension(archivePath)
+	if format == "" {
+		detected, err := DetectFormat(archivePath)
+		if err != nil {
+			return err
+		}
+		format = detected
+	}
+
+	switch format {
+	case ".7z":
 		return e.extract7z(archivePath, destPath)
-	} else if strings.HasSuffix(archivePath, ".tar.xz") {
+	case ".tar.xz":
 		return e.extractTarXz(archivePath, destPath)
-	} else if strings.HasSuffix(archivePath, ".tar.gz") {
+	case ".tar.gz":
 		return e.extractTarGz(archivePath, destPath)
-	} else if strings.HasSuffix(archivePath, ".zip") {
+	case ".zip":
 		return e.extractZip(archivePath, destPath)
+	case ".rar":
+		return e.extractRar(archivePath, destPath)
 	}
 
 	return fmt.Errorf("unsupported archive format: %s", archivePath)
 }
 
+// Archive signatures used to identify files without a usable extension
+var archiveSignatures = []struct {
+	format string
+	magic  []byte
+}{
+	{".zip", []byte("PK\x03\x04")},
+	{".zip", []byte("PK\x05\x06")}, // Empty zip
+	{".7z", []byte("7z\xBC\xAF\x27\x1C")},
+	{".tar.xz", []byte("\xFD7zXZ\x00")},
+	{".tar.gz", []byte("\x1F\x8B")},
+	{".rar", []byte("Rar!\x1A\x07")},
+}
+
+// formatFromExtension returns the archive format implied by a file name, or "" if unknown
+func formatFromExtension(path string) string {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".7z", ".tar.xz", ".tar.gz", ".tgz", ".zip", ".oiv", ".rar"} {
+		if strings.HasSuffix(lower, ext) {
+			switch ext {
+			case ".tgz":
+				return ".tar.gz"
+			case ".oiv":
+				// OpenIV packages are zip files
+				return ".zip"
+			}
+			return ext
+		}
+	}
+	return ""
+}
+
+// DetectFormat identifies an archive's format by sniffing its first bytes.
+// Returns the canonical extension (e.g. ".zip", ".7z", ".tar.xz").
+func DetectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read archive header: %w", err)
+	}
+	header = header[:n]
+
+	for _, sig := range archiveSignatures {
+		if bytes.HasPrefix(header, sig.magic) {
+			return sig.format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unrecognised archive format: %s", filepath.Base(path))
+}
+
 // extract7z extracts a 7z archive (Windows)
 func (e *Extractor) extract7z(src, dest string) error {
 	r, err := sevenzip.OpenReader(src)
@@ -109,9 +189,28 @@ func (e *Extractor) extractTarXz(src, dest string) error {
 		return fmt.Errorf("failed to create xz reader: %w", err)
 	}
 
-	// Create tar reader
-	tarReader := tar.NewReader(xzReader)
+	return e.extractTar(tar.NewReader(xzReader), dest)
+}
+
+// extractTarGz extracts a tar.gz archive (community resources and tools)
+func (e *Extractor) extractTarGz(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gzReader, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzReader.Close()
 
+	return e.extractTar(tar.NewReader(gzReader), dest)
+}
+
+// extractTar writes the entries of a decompressed tar stream to dest
+func (e *Extractor) extractTar(tarReader *tar.Reader, dest string) error {
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -170,13 +269,6 @@ func (e *Extractor) extractTarXz(src, dest string) error {
 	return nil
 }
 
-// extractTarGz extracts a tar.gz archive (fallback/utility)
-func (e *Extractor) extractTarGz(src, dest string) error {
-	// Similar to extractTarXz but with gzip instead of xz
-	// Not needed for FiveM but useful for future
-	return fmt.Errorf("tar.gz extraction not implemented yet")
-}
-
 // extractZip extracts a zip archive
 func (e *Extractor) extractZip(src, dest string) error {
 	r, err := zip.OpenReader(src)
@@ -229,6 +321,58 @@ func (e *Extractor) extractZip(src, dest string) error {
 	return nil
 }
 
+// extractRar extracts a rar archive (converted mods are sometimes packed as rar)
+func (e *Extractor) extractRar(src, dest string) error {
+	r, err := rardecode.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("failed to open rar archive: %w", err)
+	}
+	defer r.Close()
+
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read rar entry: %w", err)
+		}
+
+		path := filepath.Join(dest, header.Name)
+
+		// Security check: prevent path traversal
+		if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(dest)) {
+			return fmt.Errorf("illegal file path: %s", header.Name)
+		}
+
+		if header.IsDir {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", path, err)
+			}
+			continue
+		}
+
+		// Create parent directory
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create parent directory: %w", err)
+		}
+
+		outFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", path, err)
+		}
+
+		_, err = io.Copy(outFile, r)
+		outFile.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to extract file %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
 // ExtractWithProgress extracts an archive with progress callback
 func (e *Extractor) ExtractWithProgress(archivePath, destPath string, onProgress func(current, total int)) error {
 	// For now, just extract without progress
@@ -247,19 +391,25 @@ func (e *Extractor) GetArchiveFileCount(archivePath string) (int, error) {
 		return len(r.File), nil
 	}
 
-	if strings.HasSuffix(archivePath, ".tar.xz") {
+	format := formatFromExtension(archivePath)
+	if format == ".tar.xz" || format == ".tar.gz" {
 		f, err := os.Open(archivePath)
 		if err != nil {
 			return 0, err
 		}
 		defer f.Close()
 
-		xzReader, err := xz.NewReader(f)
+		var decompressed io.Reader
+		if format == ".tar.xz" {
+			decompressed, err = xz.NewReader(f)
+		} else {
+			decompressed, err = gzip.NewReader(f)
+		}
 		if err != nil {
 			return 0, err
 		}
 
-		tarReader := tar.NewReader(xzReader)
+		tarReader := tar.NewReader(decompressed)
 		count := 0
 
 		for {
@@ -281,7 +431,12 @@ func (e *Extractor) GetArchiveFileCount(archivePath string) (int, error) {
 
 // GetPlatformArchiveExtension returns the archive extension for the current platform
 func GetPlatformArchiveExtension() string {
-	if runtime.GOOS == "windows" {
+	return ArchiveExtensionFor(types.HostTargetOS())
+}
+
+// ArchiveExtensionFor returns the archive extension builds use on a target platform
+func ArchiveExtensionFor(targetOS string) string {
+	if targetOS == types.TargetWindows {
 		return ".7z"
 	}
 	return ".tar.xz"
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// maxWindowsPath is MAX_PATH (260) minus the terminating NUL
+const maxWindowsPath = 259
+
+// ErrPathTooLong is returned when extracting would create paths Windows can't handle
+var ErrPathTooLong = errors.New("path too long")
+
+// PathTooLongError describes which archive entry would exceed MAX_PATH
+type PathTooLongError struct {
+	Dest   string
+	Entry  string
+	Length int
+}
+
+func (e *PathTooLongError) Error() string {
+	return fmt.Sprintf("extracting to %s would create paths of %d characters (Windows allows %d), e.g. %s; "+
+		"choose a shorter install path such as C:\\FXServer, or set advanced.long_paths: true",
+		e.Dest, e.Length, maxWindowsPath, e.Entry)
+}
+
+// Unwrap lets callers match with errors.Is(err, ErrPathTooLong)
+func (e *PathTooLongError) Unwrap() error {
+	return ErrPathTooLong
+}
+
+// SetLongPaths enables the \\?\ prefix on Windows so extraction isn't limited to MAX_PATH
+func (e *Extractor) SetLongPaths(enabled bool) {
+	e.longPaths = enabled
+}
+
+// CheckDestination reports whether an archive's entries fit under dest without
+// exceeding MAX_PATH. It only checks on Windows, and only formats whose entry
+// list can be read without decompressing (zip and 7z).
+func (e *Extractor) CheckDestination(archivePath, dest string) error {
+	if runtime.GOOS != "windows" || e.longPaths {
+		return nil
+	}
+
+	format := formatFromExtension(archivePath)
+	if format == "" {
+		format, _ = DetectFormat(archivePath)
+	}
+
+	longest, err := longestEntry(archivePath, format)
+	if err != nil || longest == "" {
+		// Unreadable archives are reported by Extract itself
+		return nil
+	}
+
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		absDest = dest
+	}
+
+	full := filepath.Join(absDest, longest)
+	if len(full) > maxWindowsPath {
+		return &PathTooLongError{Dest: absDest, Entry: longest, Length: len(full)}
+	}
+
+	return nil
+}
+
+// longestEntry returns the longest entry name in a zip or 7z archive
+func longestEntry(archivePath, format string) (string, error) {
+	var names []string
+
+	switch format {
+	case ".zip":
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			names = append(names, f.Name)
+		}
+
+	case ".7z":
+		r, err := sevenzip.OpenReader(archivePath)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			names = append(names, f.Name)
+		}
+	}
+
+	var longest string
+	for _, name := range names {
+		name = filepath.FromSlash(name)
+		if len(name) > len(longest) {
+			longest = name
+		}
+	}
+
+	return longest, nil
+}
+
+// longPathName adds the \\?\ prefix to an absolute Windows path so the
+// filesystem APIs skip the MAX_PATH limit
+func longPathName(path string) string {
+	if runtime.GOOS != "windows" || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	// UNC shares use \\?\UNC\server\share
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + strings.TrimPrefix(abs, `\\`)
+	}
+	return `\\?\` + abs
+}
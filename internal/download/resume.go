@@ -0,0 +1,65 @@
+package download
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partManifest records what the partial files next to a download belong to,
+// so a later run only resumes from bytes of the same file
+type partManifest struct {
+	URL       string `json:"url"`
+	TotalSize int64  `json:"total_size"`
+	Chunks    int    `json:"chunks"` // 0 when written straight to the destination
+}
+
+// manifestPath returns where the manifest for destPath is kept
+func manifestPath(destPath string) string {
+	return destPath + ".manifest"
+}
+
+// loadManifest reads the manifest for destPath; a missing or unreadable one
+// reads as the zero manifest, which matches no download
+func loadManifest(destPath string) partManifest {
+	var m partManifest
+	data, err := os.ReadFile(manifestPath(destPath))
+	if err == nil {
+		json.Unmarshal(data, &m)
+	}
+	return m
+}
+
+func saveManifest(destPath string, m partManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(destPath), data, 0644)
+}
+
+// removeManifest forgets the partial state of destPath once it's complete
+func removeManifest(destPath string) {
+	os.Remove(manifestPath(destPath))
+}
+
+// prepareParts keeps the chunk files of destPath when they were written for
+// m, and otherwise removes them (along with any left by a different chunk
+// count) and records m for the download about to start
+func prepareParts(destPath string, m partManifest) error {
+	if loadManifest(destPath) == m {
+		return nil
+	}
+
+	prefix := filepath.Base(destPath) + ".part"
+	entries, _ := os.ReadDir(filepath.Dir(destPath))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) {
+			if err := os.Remove(filepath.Join(filepath.Dir(destPath), entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return saveManifest(destPath, m)
+}
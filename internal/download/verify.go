@@ -0,0 +1,180 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/ulikunitz/xz"
+)
+
+// ErrCorrupt is returned when a downloaded file fails verification
+var ErrCorrupt = errors.New("download is corrupt")
+
+// VerifySHA256 checks a file against an expected hex-encoded SHA256
+func VerifySHA256(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("%w: %s has SHA256 %s, expected %s", ErrCorrupt, filepath.Base(path), actual, expected)
+	}
+	return nil
+}
+
+// VerifyArchive reads every entry of an archive without writing anything,
+// so the checks built into each format (CRC32 in zip and gzip, the xz and 7z
+// checksums, rar's CRC) catch a download that's the right size but damaged
+func VerifyArchive(path string) error {
+	format := formatFromExtension(path)
+	if format == "" {
+		detected, err := DetectFormat(path)
+		if err != nil {
+			return err
+		}
+		format = detected
+	}
+
+	var err error
+	switch format {
+	case ".7z":
+		err = verify7z(path)
+	case ".tar.xz", ".tar.gz":
+		err = verifyTar(path, format)
+	case ".zip":
+		err = verifyZip(path)
+	case ".rar":
+		err = verifyRar(path)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrCorrupt, filepath.Base(path), err)
+	}
+	return nil
+}
+
+func verify7z(path string) error {
+	r, err := sevenzip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := discardEntry(f.Open); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func verifyZip(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := discardEntry(f.Open); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func verifyTar(path, format string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var decompressed io.Reader
+	if format == ".tar.xz" {
+		decompressed, err = xz.NewReader(f)
+	} else {
+		decompressed, err = gzip.NewReader(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	tarReader := tar.NewReader(decompressed)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, tarReader); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+
+	// The compression's own checksum comes after the last tar entry
+	_, err = io.Copy(io.Discard, decompressed)
+	return err
+}
+
+func verifyRar(path string) error {
+	r, err := rardecode.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+}
+
+// discardEntry reads an archive entry to the end, which is when its checksum is tested
+func discardEntry(open func() (io.ReadCloser, error)) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
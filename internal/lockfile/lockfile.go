@@ -0,0 +1,173 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ErrLocked is returned when another live process holds the lock
+var ErrLocked = errors.New("locked by another process")
+
+// retryInterval is how often Acquire retries a held lock
+const retryInterval = 50 * time.Millisecond
+
+// unwrittenGrace is how long an empty lock file (the owner hasn't written
+// its PID yet) is treated as held
+const unwrittenGrace = 5 * time.Second
+
+// Lock is a cross-process lock backed by a file containing the owner's PID
+type Lock struct {
+	path string
+}
+
+// LockedError reports who holds a lock
+type LockedError struct {
+	Path string
+	PID  int // 0 if unknown
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is held by process %d", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is held by another process", e.Path)
+}
+
+// Unwrap lets callers match with errors.Is(err, ErrLocked)
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// TryAcquire takes the lock without waiting. Locks left behind by processes
+// that are no longer running are reclaimed.
+func TryAcquire(path string) (*Lock, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			if werr != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", werr)
+			}
+			return &Lock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Released in the meantime
+			continue
+		}
+		pid, stale := inspect(path)
+		if !stale {
+			return nil, &LockedError{Path: path, PID: pid}
+		}
+
+		// Owner is gone: reclaim its lock and try once more
+		if err := reclaim(path, info); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, &LockedError{Path: path}
+}
+
+// reclaim removes the stale lock file at path, which was stale when it looked
+// like info. Other processes may be reclaiming it at the same time, and one
+// of them may already hold a fresh lock at path, so the file is first moved
+// aside (atomically, under a name no one else uses) and checked again; a
+// fresh lock moved by mistake is put back.
+func reclaim(path string, info os.FileInfo) error {
+	aside := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			// Another process reclaimed it first
+			return nil
+		}
+		return fmt.Errorf("failed to remove stale lock: %w", err)
+	}
+
+	moved, err := os.Stat(aside)
+	if _, stale := inspect(aside); err == nil && stale && os.SameFile(info, moved) {
+		os.Remove(aside)
+		return nil
+	}
+
+	// Another process took the lock since it was checked: give it back
+	// without replacing a lock created in the meantime
+	if err := os.Link(aside, path); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to restore lock: %w", err)
+	}
+	os.Remove(aside)
+	return nil
+}
+
+// Acquire waits up to timeout for the lock
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		lock, err := TryAcquire(path)
+		if err == nil || !errors.Is(err, ErrLocked) || time.Now().After(deadline) {
+			return lock, err
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// Release removes the lock file
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// Owner returns the PID recorded in a lock file, or 0 if there is no valid lock
+func Owner(path string) int {
+	pid, stale := inspect(path)
+	if stale {
+		return 0
+	}
+	return pid
+}
+
+// inspect reads a lock file and reports its owner and whether it can be reclaimed
+func inspect(path string) (int, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Released in the meantime
+		return 0, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		// Either just created or left half-written by a crash
+		return 0, time.Since(info.ModTime()) > unwrittenGrace
+	}
+
+	if pid == os.Getpid() {
+		return pid, false
+	}
+
+	alive, err := process.PidExists(int32(pid))
+	if err != nil {
+		return pid, false
+	}
+
+	return pid, !alive
+}
@@ -0,0 +1,122 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// deadPID returns a PID no process is running as
+func deadPID(t *testing.T) int {
+	t.Helper()
+	for pid := 4194000; pid > 1000; pid-- {
+		if alive, err := process.PidExists(int32(pid)); err == nil && !alive {
+			return pid
+		}
+	}
+	t.Skip("no unused PID found")
+	return 0
+}
+
+// TestTryAcquireReclaimsStaleLockOnce has several goroutines find the same
+// stale lock at once; exactly one of them may end up holding it
+func TestTryAcquireReclaimsStaleLockOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	stale := fmt.Sprintf("%d\n", deadPID(t))
+
+	for round := 0; round < 50; round++ {
+		if err := os.WriteFile(path, []byte(stale), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		const contenders = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var held []*Lock
+		start := make(chan struct{})
+		for i := 0; i < contenders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				lock, err := TryAcquire(path)
+				if err != nil {
+					if !errors.Is(err, ErrLocked) {
+						t.Errorf("TryAcquire: %v", err)
+					}
+					return
+				}
+				mu.Lock()
+				held = append(held, lock)
+				mu.Unlock()
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if len(held) != 1 {
+			t.Fatalf("round %d: %d goroutines hold the lock, want 1", round, len(held))
+		}
+		if Owner(path) != os.Getpid() {
+			t.Fatalf("round %d: lock file owned by %d, want this process", round, Owner(path))
+		}
+		held[0].Release()
+
+		leftovers, _ := filepath.Glob(path + ".stale-*")
+		if len(leftovers) > 0 {
+			t.Fatalf("round %d: stale locks left behind: %v", round, leftovers)
+		}
+	}
+}
+
+func TestTryAcquireHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	lock, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	defer lock.Release()
+
+	if _, err := TryAcquire(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second TryAcquire = %v, want ErrLocked", err)
+	}
+}
+
+// TestReclaimKeepsFreshLock is the race two processes reclaiming the same
+// stale lock run into: one has already replaced it with its own lock by the
+// time the other gets to reclaiming what it checked
+func TestReclaimKeepsFreshLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", deadPID(t))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checked, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The other process reclaims it first and takes the lock
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	defer lock.Release()
+
+	if err := reclaim(path, checked); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if owner := Owner(path); owner != os.Getpid() {
+		t.Fatalf("lock owned by %d after reclaiming the stale one, want it kept for %d", owner, os.Getpid())
+	}
+	if leftovers, _ := filepath.Glob(path + ".stale-*"); len(leftovers) > 0 {
+		t.Fatalf("stale locks left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,16 @@
+package network
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewClient creates an HTTP client with the given timeout.
+// All InkWash HTTP traffic should go through clients created here so that
+// debug logging (and other shared transport behaviour) applies uniformly.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &debugTransport{base: http.DefaultTransport},
+	}
+}
@@ -0,0 +1,109 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	debugMu      sync.RWMutex
+	debugEnabled bool
+	debugOutput  io.Writer = os.Stderr
+)
+
+// licenseKeyPattern matches FiveM license keys wherever they appear in a URL
+var licenseKeyPattern = regexp.MustCompile(`cfxk_[A-Za-z0-9_]+`)
+
+// SetDebug enables or disables request/response logging
+func SetDebug(enabled bool) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugEnabled = enabled
+}
+
+// SetDebugOutput sets where debug logs are written (default: stderr)
+func SetDebugOutput(w io.Writer) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugOutput = w
+}
+
+// DebugEnabled reports whether debug logging is enabled
+func DebugEnabled() bool {
+	debugMu.RLock()
+	defer debugMu.RUnlock()
+	return debugEnabled
+}
+
+// Debugf writes a debug line if debug mode is enabled
+func Debugf(format string, args ...interface{}) {
+	debugMu.RLock()
+	defer debugMu.RUnlock()
+
+	if !debugEnabled {
+		return
+	}
+	fmt.Fprintf(debugOutput, "[debug] "+format+"\n", args...)
+}
+
+// debugTransport logs a summary of every request when debug mode is enabled
+type debugTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !DebugEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	target := RedactURL(req.URL)
+	if rng := req.Header.Get("Range"); rng != "" {
+		target += " [Range: " + rng + "]"
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		Debugf("%s %s -> error: %v (%s)", req.Method, target, err, elapsed)
+		return resp, err
+	}
+
+	size := "unknown size"
+	if resp.ContentLength >= 0 {
+		size = fmt.Sprintf("%d bytes", resp.ContentLength)
+	}
+	Debugf("%s %s -> %s, %s (%s)", req.Method, target, resp.Status, size, elapsed)
+
+	return resp, nil
+}
+
+// RedactURL returns the URL as a string with any license key removed
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		for key := range query {
+			lower := strings.ToLower(key)
+			if strings.Contains(lower, "key") || strings.Contains(lower, "token") {
+				query.Set(key, "REDACTED")
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+
+	return licenseKeyPattern.ReplaceAllString(redacted.String(), "cfxk_REDACTED")
+}
@@ -0,0 +1,165 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy says how often a failed request is tried again and how long to
+// wait in between. Each wait doubles the one before, up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int           // Tries in total, including the first; 1 never retries
+	Backoff    time.Duration // Wait before the first retry
+	MaxBackoff time.Duration // Longest wait between tries
+	Jitter     float64       // Fraction of each wait randomised either way, 0 to 1
+}
+
+// DefaultRetryPolicy is used until SetRetryPolicy is called, overridden by
+// network.retries, network.retry_backoff, network.retry_max_backoff and
+// network.retry_jitter
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   4,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+	Jitter:     0.2,
+}
+
+var (
+	retryMu     sync.RWMutex
+	retryPolicy = DefaultRetryPolicy
+)
+
+// SetRetryPolicy sets the policy returned by Retry
+func SetRetryPolicy(p RetryPolicy) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryPolicy = p
+}
+
+// Retry returns the configured retry policy
+func Retry() RetryPolicy {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	return retryPolicy
+}
+
+// Delay returns how long to wait after the attempt-th try (from 1) failed
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	wait := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+
+	if p.Jitter > 0 {
+		spread := float64(wait) * min(p.Jitter, 1)
+		wait += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return max(wait, 0)
+}
+
+// RetryFunc is told about each failed try before waiting to make the next one
+type RetryFunc func(attempt int, err error, wait time.Duration)
+
+// Do calls fn until it succeeds, fails with an error retryable doesn't accept
+// (nil means Temporary), runs out of attempts or ctx is cancelled. fn is
+// passed the attempt number, from 1. A StatusError's RetryAfter is waited
+// out if it's longer than the backoff. The last error is returned, or
+// ctx.Err() if ctx is cancelled while waiting.
+func (p RetryPolicy) Do(ctx context.Context, retryable func(error) bool, onRetry RetryFunc, fn func(attempt int) error) error {
+	if retryable == nil {
+		retryable = Temporary
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		wait := p.Delay(attempt)
+		var status *StatusError
+		if errors.As(err, &status) && status.RetryAfter > wait {
+			wait = min(status.RetryAfter, maxRetryAfter)
+		}
+		Debugf("attempt %d failed (%v), retrying in %s", attempt, err, wait.Round(time.Millisecond))
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// maxRetryAfter caps how long a Retry-After header can make Do wait
+const maxRetryAfter = 5 * time.Minute
+
+// StatusError is an HTTP response with a status the caller didn't expect
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // From a Retry-After header (sent with 429 and 503), 0 if none
+}
+
+// NewStatusError returns the StatusError for an unexpected response
+func NewStatusError(resp *http.Response) *StatusError {
+	return &StatusError{Code: resp.StatusCode, RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as a date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// Temporary reports whether err looks like a hiccup worth retrying: a
+// connection that failed or dropped, a timeout, or a 408, 429 or 5xx
+// response. Cancellation and other responses are not.
+func Temporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+
+	// url.Error counts as a net.Error whatever went wrong, so look underneath
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
@@ -0,0 +1,64 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default timeouts, overridden by network.timeout and network.download_timeout
+const (
+	DefaultAPITimeout      = 30 * time.Second
+	DefaultDownloadTimeout = 10 * time.Minute
+)
+
+var (
+	timeoutMu       sync.RWMutex
+	apiTimeout      = DefaultAPITimeout
+	downloadTimeout = DefaultDownloadTimeout
+)
+
+// SetTimeouts sets the timeouts used by NewAPIClient, NewDownloadClient and
+// RequestContext. A zero api timeout keeps the default; a zero download
+// timeout means downloads never time out.
+func SetTimeouts(api, download time.Duration) {
+	timeoutMu.Lock()
+	defer timeoutMu.Unlock()
+
+	if api > 0 {
+		apiTimeout = api
+	}
+	if download >= 0 {
+		downloadTimeout = download
+	}
+}
+
+// APITimeout returns the timeout for metadata and API requests
+func APITimeout() time.Duration {
+	timeoutMu.RLock()
+	defer timeoutMu.RUnlock()
+	return apiTimeout
+}
+
+// DownloadTimeout returns the timeout for whole-file downloads (0 = none)
+func DownloadTimeout() time.Duration {
+	timeoutMu.RLock()
+	defer timeoutMu.RUnlock()
+	return downloadTimeout
+}
+
+// NewAPIClient creates a client for metadata and API calls using the configured timeout
+func NewAPIClient() *http.Client {
+	return NewClient(APITimeout())
+}
+
+// NewDownloadClient creates a client for large downloads using the configured download timeout
+func NewDownloadClient() *http.Client {
+	return NewClient(DownloadTimeout())
+}
+
+// RequestContext derives a context that expires after the configured API timeout
+func RequestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, APITimeout())
+}
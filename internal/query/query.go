@@ -0,0 +1,92 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+)
+
+// DefaultTimeout bounds a single request when the caller's context has no deadline
+const DefaultTimeout = 2 * time.Second
+
+// maxResponseSize caps how much of a response is read; players.json on a full
+// 2048-slot server with identifiers stays well under this
+const maxResponseSize = 4 * 1024 * 1024
+
+// Player is an entry in FXServer's players.json
+type Player struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Ping        int      `json:"ping"`
+	Identifiers []string `json:"identifiers"`
+}
+
+// Info is the part of FXServer's info.json InkWash uses
+type Info struct {
+	Version    string            `json:"version"`
+	Resources  []string          `json:"resources"`
+	Vars       map[string]string `json:"vars"`
+	MaxPlayers int               `json:"-"` // From sv_maxClients, 0 if the server doesn't say
+}
+
+// Players fetches the players connected to the server listening on address (host:port)
+func Players(ctx context.Context, address string) ([]Player, error) {
+	var players []Player
+	if err := get(ctx, address, "players.json", &players); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+// GetInfo fetches the server's version, resources and public convars
+func GetInfo(ctx context.Context, address string) (*Info, error) {
+	var info Info
+	if err := get(ctx, address, "info.json", &info); err != nil {
+		return nil, err
+	}
+
+	// Convar names keep the case they were set with
+	for name, value := range info.Vars {
+		if strings.EqualFold(name, "sv_maxClients") {
+			info.MaxPlayers, _ = strconv.Atoi(strings.TrimSpace(value))
+			break
+		}
+	}
+	return &info, nil
+}
+
+// get requests http://address/path and decodes the JSON response into v
+func get(ctx context.Context, address, path string, v interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/"+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := network.NewAPIClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to query %s: %s returned %s", address, path, resp.Status)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("failed to read %s from %s: %w", path, address, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package rcon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout is how long Exec waits for the server to start answering
+const DefaultTimeout = 3 * time.Second
+
+// continuationTimeout is how long Exec waits for more packets once the first
+// one arrived; long output (e.g. status on a full server) spans several
+const continuationTimeout = 150 * time.Millisecond
+
+// maxPacketSize is the largest UDP payload FXServer sends
+const maxPacketSize = 64 * 1024
+
+// header starts every out-of-band packet of the Quake-style protocol FXServer speaks
+var header = []byte{0xff, 0xff, 0xff, 0xff}
+
+var (
+	// ErrBadPassword is returned when the server rejects the RCON password
+	ErrBadPassword = errors.New("the server rejected the RCON password")
+
+	// ErrNoResponse is returned when the server doesn't answer a command in time
+	ErrNoResponse = errors.New("no response from the server")
+)
+
+// Client sends console commands to a running FXServer over RCON. It keeps
+// one UDP socket open for its lifetime; commands are sent one at a time.
+type Client struct {
+	conn     net.Conn
+	address  string
+	password string
+	Timeout  time.Duration
+
+	mu sync.Mutex
+}
+
+// Dial opens an RCON connection to the server at address (host:port, the
+// server's game endpoint). UDP is connectionless, so a wrong address or a
+// server that isn't running only shows up when the first command times out.
+func Dial(address, password string) (*Client, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	return &Client{
+		conn:     conn,
+		address:  address,
+		password: password,
+		Timeout:  DefaultTimeout,
+	}, nil
+}
+
+// Address returns the host:port the client sends commands to
+func (c *Client) Address() string {
+	return c.address
+}
+
+// Exec runs a console command and returns what the server printed in response
+func (c *Client) Exec(command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Late answers to a command that timed out would be taken for this one's
+	c.drain()
+
+	packet := append(append([]byte{}, header...), []byte("rcon "+c.password+" "+command)...)
+	if _, err := c.conn.Write(packet); err != nil {
+		return "", fmt.Errorf("failed to send command to %s: %w", c.address, err)
+	}
+
+	// Commands that print nothing still get an empty print packet back
+	var output strings.Builder
+	var received bool
+	buf := make([]byte, maxPacketSize)
+	deadline := c.Timeout
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(deadline))
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && received {
+				break
+			}
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return "", fmt.Errorf("%w at %s within %s", ErrNoResponse, c.address, c.Timeout)
+			}
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				return "", fmt.Errorf("%w: nothing is listening at %s", ErrNoResponse, c.address)
+			}
+			return "", fmt.Errorf("failed to read from %s: %w", c.address, err)
+		}
+
+		text, ok := parsePrint(buf[:n])
+		if !ok {
+			continue
+		}
+		output.WriteString(text)
+		received = true
+		deadline = continuationTimeout
+	}
+
+	response := output.String()
+	if strings.TrimSpace(response) == "Invalid password." {
+		return "", ErrBadPassword
+	}
+	return response, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// drain discards packets that are already waiting to be read
+func (c *Client) drain() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		c.conn.SetReadDeadline(time.Now())
+		if _, err := c.conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+// parsePrint returns the text of a "print" response packet, "" for a
+// command that printed nothing
+func parsePrint(packet []byte) (string, bool) {
+	prefix := append(append([]byte{}, header...), []byte("print")...)
+	if !bytes.HasPrefix(packet, prefix) {
+		return "", false
+	}
+	text := packet[len(prefix):]
+	if len(text) > 0 && text[0] != ' ' && text[0] != '\n' {
+		return "", false
+	}
+	if len(text) > 0 {
+		text = text[1:]
+	}
+	return string(text), true
+}
@@ -0,0 +1,219 @@
+// Package recipe reads txAdmin recipes, the YAML files community server
+// bases are shipped as, and runs their tasks against a server folder.
+package recipe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+	"gopkg.in/yaml.v3"
+)
+
+// Engine is the newest txAdmin recipe engine version recipes may ask for
+const Engine = 3
+
+// maxRecipeSize caps how much of a recipe URL is read
+const maxRecipeSize = 1 << 20
+
+// Recipe is a parsed txAdmin recipe
+type Recipe struct {
+	Engine        int               `yaml:"$engine"`
+	MinFXVersion  int               `yaml:"$minFxVersion"`
+	OneSync       string            `yaml:"$onesync"` // on, legacy or off, passed to FXServer with +set
+	SteamRequired bool              `yaml:"$steamRequired"`
+	Name          string            `yaml:"name"`
+	Version       string            `yaml:"version"`
+	Author        string            `yaml:"author"`
+	Description   string            `yaml:"description"`
+	Variables     map[string]string `yaml:"variables"`
+	Tasks         []Task            `yaml:"tasks"`
+}
+
+// Task is one step of a recipe. Which fields are used depends on Action.
+type Task struct {
+	Action    string `yaml:"action"`
+	Src       string `yaml:"src"`
+	Dest      string `yaml:"dest"`
+	Ref       string `yaml:"ref"`
+	Subpath   string `yaml:"subpath"`
+	URL       string `yaml:"url"`
+	Path      string `yaml:"path"`
+	File      paths  `yaml:"file"`
+	Data      string `yaml:"data"`
+	Append    bool   `yaml:"append"`
+	Overwrite bool   `yaml:"overwrite"`
+	Mode      string `yaml:"mode"` // replace_string: literal (default), template or all_vars
+	Search    string `yaml:"search"`
+	Replace   string `yaml:"replace"`
+	Seconds   int    `yaml:"seconds"`
+}
+
+// paths is a field recipes give as either one path or a list of them
+type paths []string
+
+func (p *paths) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = paths{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// Describe returns a short summary of the task for progress output
+func (t *Task) Describe() string {
+	switch t.Action {
+	case "download_github":
+		if t.Ref != "" {
+			return fmt.Sprintf("%s %s@%s", t.Action, t.Src, t.Ref)
+		}
+		return t.Action + " " + t.Src
+	case "download_file":
+		return t.Action + " " + t.URL
+	case "unzip", "move_path", "copy_path":
+		return fmt.Sprintf("%s %s -> %s", t.Action, t.Src, t.Dest)
+	case "remove_path", "ensure_dir":
+		return t.Action + " " + t.Path
+	case "write_file", "replace_string", "query_database":
+		return t.Action + " " + strings.Join(t.File, ", ")
+	}
+	return t.Action
+}
+
+// Parse reads a recipe and checks every task can be run
+func Parse(data []byte) (*Recipe, error) {
+	var r Recipe
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid recipe: %w", err)
+	}
+
+	if r.Engine > Engine {
+		return nil, fmt.Errorf("recipe needs recipe engine %d, inkwash supports up to %d", r.Engine, Engine)
+	}
+	switch r.OneSync {
+	case "", "on", "legacy", "off":
+	default:
+		return nil, fmt.Errorf("invalid recipe: $onesync must be on, legacy or off, not %q", r.OneSync)
+	}
+	if len(r.Tasks) == 0 {
+		return nil, fmt.Errorf("invalid recipe: no tasks")
+	}
+
+	for i := range r.Tasks {
+		if err := r.Tasks[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid recipe: task %d: %w", i+1, err)
+		}
+	}
+	return &r, nil
+}
+
+// validate checks a task's action is known and has the fields it needs
+func (t *Task) validate() error {
+	var missing []string
+	need := func(name, value string) {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	switch t.Action {
+	case "download_github":
+		need("src", t.Src)
+		need("dest", t.Dest)
+	case "download_file":
+		need("url", t.URL)
+		need("path", t.Path)
+	case "unzip", "move_path", "copy_path":
+		need("src", t.Src)
+		need("dest", t.Dest)
+	case "remove_path", "ensure_dir":
+		need("path", t.Path)
+	case "write_file":
+		if len(t.File) != 1 {
+			return fmt.Errorf("write_file needs one file")
+		}
+	case "replace_string":
+		if len(t.File) == 0 {
+			missing = append(missing, "file")
+		}
+		switch t.Mode {
+		case "", "literal", "template":
+			need("search", t.Search)
+		case "all_vars":
+		default:
+			return fmt.Errorf("unknown replace_string mode %q", t.Mode)
+		}
+	case "waste_time", "connect_database", "query_database", "load_vars":
+	case "":
+		return fmt.Errorf("no action")
+	default:
+		return fmt.Errorf("unknown action %q", t.Action)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s needs %s", t.Action, strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// Load reads a recipe from a file or an http(s) URL
+func Load(ctx context.Context, source string) (*Recipe, error) {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		fetched, err := fetch(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		data = fetched
+	} else {
+		read, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipe: %w", err)
+		}
+		data = read
+	}
+
+	r, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+	return r, nil
+}
+
+// fetch downloads a recipe
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipe URL: %w", err)
+	}
+	resp, err := network.NewAPIClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download recipe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download recipe: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRecipeSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download recipe: %w", err)
+	}
+	if len(data) > maxRecipeSize {
+		return nil, fmt.Errorf("recipe at %s is larger than %d bytes", url, maxRecipeSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,372 @@
+package recipe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+)
+
+// placeholderPattern matches the {{variable}} placeholders recipes fill in
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// runner holds what the tasks of one run share
+type runner struct {
+	recipe     *Recipe
+	serverPath string
+	tmpDir     string // Downloads and extractions, removed when the run ends
+	vars       map[string]string
+	notes      []string
+	downloads  int
+}
+
+// Run runs the recipe's tasks in order in serverPath. vars fills the
+// {{placeholders}} that replace_string and write_file use, on top of the
+// recipe's own variables and recipeName, recipeAuthor, recipeDescription
+// and deployPath. Every path a task names must stay inside serverPath.
+// Database tasks and load_vars can't be run here; they're skipped and
+// described in the returned notes. onTask is called before each task.
+func (r *Recipe) Run(ctx context.Context, serverPath string, vars map[string]string, onTask func(index int, task *Task)) ([]string, error) {
+	run := &runner{
+		recipe:     r,
+		serverPath: serverPath,
+		vars: map[string]string{
+			"recipeName":        r.Name,
+			"recipeAuthor":      r.Author,
+			"recipeDescription": r.Description,
+			"deployPath":        serverPath,
+			"serverPath":        serverPath,
+		},
+	}
+	for name, value := range r.Variables {
+		run.vars[name] = value
+	}
+	for name, value := range vars {
+		run.vars[name] = value
+	}
+
+	tmpDir, err := os.MkdirTemp(serverPath, ".inkwash-recipe-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recipe work folder: %w", err)
+	}
+	run.tmpDir = tmpDir
+	defer os.RemoveAll(tmpDir)
+
+	for i := range r.Tasks {
+		if err := ctx.Err(); err != nil {
+			return run.notes, err
+		}
+		task := &r.Tasks[i]
+		if onTask != nil {
+			onTask(i, task)
+		}
+		if err := run.task(ctx, task); err != nil {
+			return run.notes, fmt.Errorf("recipe task %d (%s) failed: %w", i+1, task.Action, err)
+		}
+	}
+	return run.notes, nil
+}
+
+// task runs one task
+func (run *runner) task(ctx context.Context, t *Task) error {
+	switch t.Action {
+	case "download_github":
+		return run.downloadGitHub(ctx, t)
+	case "download_file":
+		dest, err := run.path(t.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return download.NewDownloader(1).DownloadContext(ctx, t.URL, dest, nil)
+	case "unzip":
+		src, dest, err := run.paths(t.Src, t.Dest)
+		if err != nil {
+			return err
+		}
+		return download.NewExtractor().Extract(src, dest)
+	case "move_path":
+		return run.move(t)
+	case "copy_path":
+		src, dest, err := run.paths(t.Src, t.Dest)
+		if err != nil {
+			return err
+		}
+		return copyTree(src, dest, t.Overwrite)
+	case "remove_path":
+		path, err := run.path(t.Path)
+		if err != nil {
+			return err
+		}
+		if path == run.serverPath {
+			return fmt.Errorf("won't remove the server folder itself")
+		}
+		return os.RemoveAll(path)
+	case "ensure_dir":
+		path, err := run.path(t.Path)
+		if err != nil {
+			return err
+		}
+		return os.MkdirAll(path, 0755)
+	case "write_file":
+		return run.writeFile(t)
+	case "replace_string":
+		return run.replaceString(t)
+	case "waste_time":
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(t.Seconds) * time.Second):
+		}
+		return nil
+	case "connect_database":
+		run.notes = append(run.notes, "skipped connect_database: create the recipe's database yourself and set its connection string in server.cfg")
+		return nil
+	case "query_database":
+		what := "its query"
+		if len(t.File) > 0 {
+			what = strings.Join(t.File, ", ")
+		}
+		run.notes = append(run.notes, fmt.Sprintf("skipped query_database: import %s into the database yourself", what))
+		return nil
+	case "load_vars":
+		run.notes = append(run.notes, "skipped load_vars: set the variables it loads by hand")
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", t.Action)
+}
+
+// path resolves a recipe path against the server folder, refusing any that
+// would leave it
+func (run *runner) path(rel string) (string, error) {
+	path := filepath.Join(run.serverPath, filepath.FromSlash(rel))
+	inside, err := filepath.Rel(run.serverPath, path)
+	if err != nil || inside == ".." || strings.HasPrefix(inside, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the server folder", rel)
+	}
+	return path, nil
+}
+
+// paths resolves a task's source and destination
+func (run *runner) paths(src, dest string) (string, string, error) {
+	srcPath, err := run.path(src)
+	if err != nil {
+		return "", "", err
+	}
+	destPath, err := run.path(dest)
+	if err != nil {
+		return "", "", err
+	}
+	return srcPath, destPath, nil
+}
+
+// downloadGitHub downloads a repository, or one folder of it, into dest,
+// merging it with what's already there
+func (run *runner) downloadGitHub(ctx context.Context, t *Task) error {
+	dest, err := run.path(t.Dest)
+	if err != nil {
+		return err
+	}
+	src, err := resource.ParseGitHubSource(t.Src)
+	if err != nil {
+		return err
+	}
+	if t.Ref != "" {
+		src.Ref = t.Ref
+	}
+
+	run.downloads++
+	archive := filepath.Join(run.tmpDir, fmt.Sprintf("github-%d.zip", run.downloads))
+	if err := download.NewDownloader(1).DownloadContext(ctx, src.ArchiveURL(), archive, nil); err != nil {
+		return fmt.Errorf("failed to download %s: %w", src, err)
+	}
+	extracted := filepath.Join(run.tmpDir, fmt.Sprintf("github-%d", run.downloads))
+	if err := download.NewExtractor().Extract(archive, extracted); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", src, err)
+	}
+	os.Remove(archive)
+
+	// GitHub archives wrap everything in a single "<repo>-<ref>" folder
+	root := extracted
+	if entries, err := os.ReadDir(extracted); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(extracted, entries[0].Name())
+	}
+	if t.Subpath != "" {
+		sub := filepath.Join(root, filepath.FromSlash(t.Subpath))
+		if rel, err := filepath.Rel(root, sub); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("subpath %q is outside the repository", t.Subpath)
+		}
+		if _, err := os.Stat(sub); err != nil {
+			return fmt.Errorf("%s has no %s", src, t.Subpath)
+		}
+		root = sub
+	}
+
+	return copyTree(root, dest, true)
+}
+
+// move moves a file or folder, replacing the destination only if the task allows it
+func (run *runner) move(t *Task) error {
+	src, dest, err := run.paths(t.Src, t.Dest)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		if !t.Overwrite {
+			return fmt.Errorf("%s already exists", t.Dest)
+		}
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dest)
+}
+
+// writeFile writes or appends a task's data
+func (run *runner) writeFile(t *Task) error {
+	path, err := run.path(t.File[0])
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if t.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(t.Data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replaceString edits the task's files: literal replaces search with
+// replace, template fills replace's placeholders first, and all_vars fills
+// every placeholder in the files
+func (run *runner) replaceString(t *Task) error {
+	for _, file := range t.File {
+		path, err := run.path(file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		content := string(data)
+		switch t.Mode {
+		case "all_vars":
+			content = run.expand(content)
+		case "template":
+			content = strings.ReplaceAll(content, t.Search, run.expand(t.Replace))
+		default:
+			content = strings.ReplaceAll(content, t.Search, t.Replace)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand fills the placeholders it has variables for, leaving the rest
+func (run *runner) expand(s string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := run.vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// Placeholders returns the {{placeholders}} still in a file, e.g. the
+// database settings a recipe expects txAdmin to fill in
+func Placeholders(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(string(data), -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyTree copies a file or folder to dest, merging folders. Existing files
+// are replaced only with overwrite.
+func copyTree(src, dest string, overwrite bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			// Links could point outside the server folder
+			return nil
+		}
+
+		if _, err := os.Stat(target); err == nil && !overwrite {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies one file
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -2,14 +2,19 @@ package registry
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
+// ErrCorrupt is returned when the registry file exists but can't be parsed
+var ErrCorrupt = errors.New("failed to parse registry")
+
 // Registry manages server instances
 type Registry struct {
 	configPath string
@@ -132,6 +137,28 @@ func (r *Registry) Update(server types.Server) error {
 	return fmt.Errorf("server '%s' not found", server.Name)
 }
 
+// Rename changes the name a server is registered under
+func (r *Registry) Rename(oldName, newName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := -1
+	for i, server := range r.data.Servers {
+		switch server.Name {
+		case newName:
+			return fmt.Errorf("server '%s' already exists", newName)
+		case oldName:
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("server '%s' not found", oldName)
+	}
+
+	r.data.Servers[index].Name = newName
+	return r.save()
+}
+
 // UpdatePID updates a server's PID
 func (r *Registry) UpdatePID(name string, pid int) error {
 	r.mu.Lock()
@@ -218,7 +245,7 @@ func (r *Registry) load() error {
 
 	var registryData RegistryData
 	if err := json.Unmarshal(data, &registryData); err != nil {
-		return fmt.Errorf("failed to parse registry: %w", err)
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
 	}
 
 	r.data = &registryData
@@ -239,6 +266,16 @@ func (r *Registry) save() error {
 	return nil
 }
 
+// BackupCorrupt moves an unreadable registry file aside so a fresh one can be
+// created, and returns where it was moved to
+func BackupCorrupt(configPath string) (string, error) {
+	backupPath := fmt.Sprintf("%s.corrupt-%d", configPath, time.Now().Unix())
+	if err := os.Rename(configPath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up registry: %w", err)
+	}
+	return backupPath, nil
+}
+
 // Reload reloads the registry from disk
 func (r *Registry) Reload() error {
 	r.mu.Lock()
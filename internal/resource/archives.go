@@ -0,0 +1,160 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/staging"
+)
+
+// Archives downloads and extracts each archive once, so several resources
+// can be installed from one repository, like ESX's esx_core. Close removes
+// what was downloaded.
+type Archives struct {
+	dir       string
+	extracted map[string]string // Archive URL to its unwrapped root
+}
+
+// NewArchives returns an empty set of downloaded archives
+func NewArchives() *Archives {
+	return &Archives{extracted: make(map[string]string)}
+}
+
+// Close removes the downloaded archives
+func (a *Archives) Close() error {
+	if a.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(a.dir)
+}
+
+// Install installs the resource in the archive's folder path ("" for its
+// root) into resourcesPath/name, replacing any existing folder of that name.
+// The archive is downloaded the first time it's asked for. label names the
+// source in errors.
+func (a *Archives) Install(archiveURL, label, path, resourcesPath, name string, onProgress download.ProgressCallback) (*Manifest, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || IsCategory(name) {
+		return nil, fmt.Errorf("invalid resource name: %q", name)
+	}
+
+	root, err := a.fetch(archiveURL, label, onProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	src := root
+	if path != "" {
+		src = filepath.Join(root, filepath.FromSlash(path))
+		if rel, err := filepath.Rel(root, src); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s: path %q is outside the archive", label, path)
+		}
+		label = fmt.Sprintf("%s (%s)", label, path)
+	}
+
+	manifestPath := FindManifest(src)
+	if manifestPath == "" {
+		return nil, fmt.Errorf("%s has no fxmanifest.lua or __resource.lua", label)
+	}
+	manifest, err := ParseManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copied, since other resources may come from the same archive
+	dest := filepath.Join(resourcesPath, name)
+	stageDir, err := staging.Dir(dest)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	if err := copyTree(src, stageDir); err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %w", label, err)
+	}
+	if err := staging.CheckTree(stageDir, filepath.Base(manifestPath)); err != nil {
+		return nil, fmt.Errorf("%s: %w", label, err)
+	}
+	if err := staging.Swap(stageDir, dest); err != nil {
+		return nil, fmt.Errorf("failed to install resource: %w", err)
+	}
+
+	manifest.Path = filepath.Join(dest, filepath.Base(manifestPath))
+	return manifest, nil
+}
+
+// fetch downloads and extracts an archive unless that was already done
+func (a *Archives) fetch(archiveURL, label string, onProgress download.ProgressCallback) (string, error) {
+	if root, ok := a.extracted[archiveURL]; ok {
+		return root, nil
+	}
+
+	if a.dir == "" {
+		dir, err := os.MkdirTemp("", "inkwash-archives-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create download directory: %w", err)
+		}
+		a.dir = dir
+	}
+
+	n := len(a.extracted) + 1
+	archivePath := filepath.Join(a.dir, fmt.Sprintf("%d.zip", n))
+	if err := download.NewDownloader(1).Download(archiveURL, archivePath, onProgress); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", label, err)
+	}
+	defer os.Remove(archivePath)
+
+	extractPath := filepath.Join(a.dir, fmt.Sprintf("%d", n))
+	if err := download.NewExtractor().Extract(archivePath, extractPath); err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", label, err)
+	}
+
+	// GitHub archives wrap everything in a single "<repo>-<ref>" folder
+	root := extractPath
+	if entries, err := os.ReadDir(extractPath); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(extractPath, entries[0].Name())
+	}
+	a.extracted[archiveURL] = root
+	return root, nil
+}
+
+// copyTree copies a folder's files and folders into dest. Links are skipped,
+// as they could point outside the resource.
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
@@ -0,0 +1,114 @@
+package resource
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveZip serves a zip of files (slash-separated path to content) and
+// counts the downloads
+func serveZip(t *testing.T, files map[string]string) (string, *int) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	downloads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+			downloads++
+		}
+		http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(buf.Bytes()))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/archive.zip", &downloads
+}
+
+func TestInstallArchiveReplacesResource(t *testing.T) {
+	archiveURL, _ := serveZip(t, map[string]string{
+		"repo-main/fxmanifest.lua": "fx_version 'cerulean'\nversion '2.0'\n",
+		"repo-main/client.lua":     "print('new')",
+	})
+	resources := filepath.Join(t.TempDir(), "resources")
+	writeFile(t, filepath.Join(resources, "thing", "old.lua"), "print('old')")
+
+	manifest, err := InstallArchive(archiveURL, "test archive", resources, "thing", nil)
+	if err != nil {
+		t.Fatalf("InstallArchive: %v", err)
+	}
+	if want := filepath.Join(resources, "thing", "fxmanifest.lua"); manifest.Path != want {
+		t.Errorf("manifest.Path = %s, want %s", manifest.Path, want)
+	}
+	if _, err := os.Stat(filepath.Join(resources, "thing", "client.lua")); err != nil {
+		t.Errorf("new version not installed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(resources, "thing", "old.lua")); !os.IsNotExist(err) {
+		t.Errorf("old version not replaced")
+	}
+	assertNoStaging(t, resources)
+}
+
+func TestArchivesInstallsSeveralFromOneDownload(t *testing.T) {
+	archiveURL, downloads := serveZip(t, map[string]string{
+		"core-main/[core]/es_extended/fxmanifest.lua": "fx_version 'cerulean'\n",
+		"core-main/[core]/es_extended/server.lua":     "print('esx')",
+		"core-main/[core]/esx_menu/fxmanifest.lua":    "fx_version 'cerulean'\n",
+	})
+	resources := filepath.Join(t.TempDir(), "resources")
+
+	archives := NewArchives()
+	defer archives.Close()
+	for _, name := range []string{"es_extended", "esx_menu"} {
+		if _, err := archives.Install(archiveURL, "core", "[core]/"+name, resources, name, nil); err != nil {
+			t.Fatalf("Install %s: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(resources, name, "fxmanifest.lua")); err != nil {
+			t.Errorf("%s not installed: %v", name, err)
+		}
+	}
+	if *downloads != 1 {
+		t.Errorf("archive downloaded %d times, want once", *downloads)
+	}
+
+	if _, err := archives.Install(archiveURL, "core", "../outside", resources, "outside", nil); err == nil || !strings.Contains(err.Error(), "outside the archive") {
+		t.Errorf("path outside the archive = %v, want it refused", err)
+	}
+	assertNoStaging(t, resources)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertNoStaging fails if a staging directory was left next to resources
+func assertNoStaging(t *testing.T, resources string) {
+	t.Helper()
+	entries, _ := os.ReadDir(resources)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".inkwash-") {
+			t.Errorf("staging directory %s left behind", e.Name())
+		}
+	}
+}
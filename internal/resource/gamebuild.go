@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dlcPacks maps GTA V DLC pack names to the game build that introduced them.
+// Content referencing a pack only loads when the server enforces at least that build.
+var dlcPacks = []struct {
+	pack  string
+	build int
+}{
+	{"mpchristmas2018", 1604},
+	{"mpsum", 2060},
+	{"mpheist4", 2189},
+	{"mptuner", 2372},
+	{"mpsecurity", 2545},
+	{"mpsum2", 2699},
+	{"mpchristmas3", 2802},
+	{"mp2023_01", 2944},
+	{"mp2023_02", 3095},
+	{"mp2024_01", 3258},
+	{"mp2024_02", 3407},
+}
+
+// dlcPackPatterns match a pack name that isn't part of a longer name (mpsum vs mpsum2)
+var dlcPackPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(dlcPacks))
+	for i, p := range dlcPacks {
+		patterns[i] = regexp.MustCompile(`(?i)(^|[^a-z0-9])` + regexp.QuoteMeta(p.pack) + `([^a-z0-9]|$)`)
+	}
+	return patterns
+}()
+
+// Text files that reference DLC packs (vehicle metas, manifests, data files)
+var scannedExtensions = map[string]bool{
+	".meta": true,
+	".xml":  true,
+	".lua":  true,
+	".json": true,
+	".txt":  true,
+	".dat":  true,
+}
+
+// maxScannedFileSize skips large files, which are never the metadata we're after
+const maxScannedFileSize = 4 << 20
+
+// RequiredGameBuild guesses the minimum sv_enforceGameBuild a resource needs by
+// looking for DLC pack names in its data files. It returns 0 if nothing
+// build-specific was found, along with the pack that set the requirement.
+// This is a heuristic: it can't see references inside binary stream files.
+func RequiredGameBuild(dir string) (int, string) {
+	required, pack := 0, ""
+
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if !scannedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxScannedFileSize {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		for i, pattern := range dlcPackPatterns {
+			if dlcPacks[i].build > required && pattern.Match(data) {
+				required, pack = dlcPacks[i].build, dlcPacks[i].pack
+			}
+		}
+		return nil
+	})
+
+	return required, pack
+}
@@ -0,0 +1,105 @@
+package resource
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/download"
+)
+
+// GitHubSource identifies a GitHub repository and an optional branch, tag or commit
+type GitHubSource struct {
+	Owner string
+	Repo  string
+	Ref   string // "" means the default branch
+}
+
+// ParseGitHubSource parses "https://github.com/owner/repo[@ref]", also accepting
+// ".git" suffixes, "/tree/<ref>" paths, SSH clone URLs and the short "owner/repo" form
+func ParseGitHubSource(raw string) (*GitHubSource, error) {
+	s := strings.TrimSpace(raw)
+
+	// SSH clone URLs: git@github.com:owner/repo.git
+	if rest, ok := strings.CutPrefix(s, "git@github.com:"); ok {
+		s = "https://github.com/" + rest
+	}
+
+	var ref string
+	// Only an "@" after the owner/repo path starts a ref (not "git@github.com:...")
+	if at := strings.LastIndex(s, "@"); at != -1 && strings.Contains(s[:at], "/") {
+		s, ref = s[:at], s[at+1:]
+	}
+
+	if !strings.Contains(s, "://") && !strings.HasPrefix(s, "github.com/") {
+		s = "github.com/" + s
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || !strings.EqualFold(strings.TrimPrefix(u.Host, "www."), "github.com") {
+		return nil, fmt.Errorf("not a GitHub repository URL: %s", raw)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("not a GitHub repository URL: %s", raw)
+	}
+
+	src := &GitHubSource{
+		Owner: parts[0],
+		Repo:  strings.TrimSuffix(parts[1], ".git"),
+		Ref:   ref,
+	}
+
+	// https://github.com/owner/repo/tree/<ref>
+	if src.Ref == "" && len(parts) > 3 && parts[2] == "tree" {
+		src.Ref = strings.Join(parts[3:], "/")
+	}
+
+	return src, nil
+}
+
+// URL returns the repository's web URL
+func (s *GitHubSource) URL() string {
+	return fmt.Sprintf("https://github.com/%s/%s", s.Owner, s.Repo)
+}
+
+// String returns the URL with the ref appended, in the form ParseGitHubSource accepts
+func (s *GitHubSource) String() string {
+	if s.Ref == "" {
+		return s.URL()
+	}
+	return s.URL() + "@" + s.Ref
+}
+
+// ArchiveURL returns the zip download URL for the source's ref
+func (s *GitHubSource) ArchiveURL() string {
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	segments := strings.Split(ref, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("%s/archive/%s.zip", s.URL(), strings.Join(segments, "/"))
+}
+
+// InstallFromGitHub downloads src into resourcesPath/name, replacing any
+// existing folder of that name, and returns the installed resource's manifest.
+// The repository root must contain an fxmanifest.lua or __resource.lua.
+func InstallFromGitHub(src *GitHubSource, resourcesPath, name string, onProgress download.ProgressCallback) (*Manifest, error) {
+	return InstallArchive(src.ArchiveURL(), src.String(), resourcesPath, name, onProgress)
+}
+
+// InstallArchive downloads a zipped resource into resourcesPath/name,
+// replacing any existing folder of that name. A single top-level folder in
+// the archive is unwrapped. label names the source in errors.
+func InstallArchive(archiveURL, label, resourcesPath, name string, onProgress download.ProgressCallback) (*Manifest, error) {
+	archives := NewArchives()
+	defer archives.Close()
+	return archives.Install(archiveURL, label, "", resourcesPath, name, onProgress)
+}
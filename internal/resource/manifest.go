@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Manifest filenames recognised by FXServer, in order of preference
+var manifestNames = []string{"fxmanifest.lua", "__resource.lua"}
+
+// Manifest holds the fields InkWash cares about from a resource manifest
+type Manifest struct {
+	Path         string
+	FxVersion    string
+	Games        []string
+	Version      string
+	Author       string
+	Description  string
+	Dependencies []string
+}
+
+var (
+	manifestFieldPattern = regexp.MustCompile(`(?m)^\s*(fx_version|game|version|author|description)\s+['"]([^'"]*)['"]`)
+	gamesPattern         = regexp.MustCompile(`(?m)^\s*games\s*\{([^}]*)\}`)
+	dependencyPattern    = regexp.MustCompile(`(?m)^\s*dependency\s+['"]([^'"]+)['"]`)
+	dependenciesPattern  = regexp.MustCompile(`(?ms)^\s*dependencies\s*\{(.*?)\}`)
+	quotedPattern        = regexp.MustCompile(`['"]([^'"]+)['"]`)
+)
+
+// FindManifest returns the manifest path inside a resource directory, or "" if none exists
+func FindManifest(dir string) string {
+	for _, name := range manifestNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// ParseManifest parses an fxmanifest.lua or __resource.lua file
+func ParseManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	content := string(data)
+	manifest := &Manifest{Path: path}
+
+	for _, match := range manifestFieldPattern.FindAllStringSubmatch(content, -1) {
+		switch match[1] {
+		case "fx_version":
+			manifest.FxVersion = match[2]
+		case "game":
+			manifest.Games = append(manifest.Games, match[2])
+		case "version":
+			manifest.Version = match[2]
+		case "author":
+			manifest.Author = match[2]
+		case "description":
+			manifest.Description = match[2]
+		}
+	}
+
+	if match := gamesPattern.FindStringSubmatch(content); match != nil {
+		for _, game := range quotedPattern.FindAllStringSubmatch(match[1], -1) {
+			manifest.Games = append(manifest.Games, game[1])
+		}
+	}
+
+	for _, match := range dependencyPattern.FindAllStringSubmatch(content, -1) {
+		manifest.Dependencies = append(manifest.Dependencies, match[1])
+	}
+	if match := dependenciesPattern.FindStringSubmatch(content); match != nil {
+		for _, dep := range quotedPattern.FindAllStringSubmatch(match[1], -1) {
+			manifest.Dependencies = append(manifest.Dependencies, strings.TrimPrefix(dep[1], "/"))
+		}
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+)
+
+// githubAPI is the base URL of the GitHub REST API
+const githubAPI = "https://api.github.com"
+
+// Release is the archive picked from a GitHub release
+type Release struct {
+	Tag        string
+	ArchiveURL string
+	Asset      string    // Name of the release asset, "" for GitHub's source archive
+	Published  time.Time // When the release was published
+}
+
+// FindRelease looks up the release tagged src.Ref, or the latest release if
+// src.Ref is empty. Many resources publish a built zip that differs from the
+// repository (e.g. bundled web UIs), so the first .zip asset is preferred
+// over GitHub's source archive of the tag.
+func FindRelease(src *GitHubSource) (*Release, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/releases/latest", githubAPI, url.PathEscape(src.Owner), url.PathEscape(src.Repo))
+	if src.Ref != "" {
+		endpoint = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", githubAPI, url.PathEscape(src.Owner), url.PathEscape(src.Repo), url.PathEscape(src.Ref))
+	}
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := network.NewAPIClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up releases of %s: %w", src.URL(), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && src.Ref != "":
+		return nil, fmt.Errorf("%s has no release tagged %s", src.URL(), src.Ref)
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s has no releases; install it without --release", src.URL())
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to look up releases of %s: HTTP %d", src.URL(), resp.StatusCode)
+	}
+
+	var body struct {
+		TagName     string    `json:"tag_name"`
+		PublishedAt time.Time `json:"published_at"`
+		Assets      []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse releases of %s: %w", src.URL(), err)
+	}
+
+	release := &Release{Tag: body.TagName, Published: body.PublishedAt}
+	for _, asset := range body.Assets {
+		if strings.HasSuffix(strings.ToLower(asset.Name), ".zip") {
+			release.ArchiveURL, release.Asset = asset.URL, asset.Name
+			return release, nil
+		}
+	}
+
+	tagged := *src
+	tagged.Ref = body.TagName
+	release.ArchiveURL = tagged.ArchiveURL()
+	return release, nil
+}
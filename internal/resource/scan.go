@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Resource is a resource folder found on disk
+type Resource struct {
+	Name     string
+	Path     string
+	Category string // Enclosing [category] folder name without brackets, "" if none
+	Manifest string // Path to the manifest file
+}
+
+// Scan walks a resources directory and returns every resource it contains.
+// Folders wrapped in brackets (e.g. "[vehicles]") are treated as categories and searched recursively.
+func Scan(resourcesPath string) ([]Resource, error) {
+	if _, err := os.Stat(resourcesPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("resources directory not found: %s", resourcesPath)
+		}
+		return nil, err
+	}
+
+	var resources []Resource
+	if err := scanDir(resourcesPath, "", &resources); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+
+	return resources, nil
+}
+
+func scanDir(dir, category string, resources *[]Resource) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		if IsCategory(name) {
+			if err := scanDir(path, strings.Trim(name, "[]"), resources); err != nil {
+				return err
+			}
+			continue
+		}
+
+		manifest := FindManifest(path)
+		if manifest == "" {
+			continue
+		}
+
+		*resources = append(*resources, Resource{
+			Name:     name,
+			Path:     path,
+			Category: category,
+			Manifest: manifest,
+		})
+	}
+
+	return nil
+}
+
+// IsCategory reports whether a folder name is a [category] folder
+func IsCategory(name string) bool {
+	return strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]")
+}
@@ -0,0 +1,173 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/network"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Update describes a newer upstream version of a resource
+type Update struct {
+	Current   string // What's installed: a release tag, commit or install date
+	Available string // The newer release tag or commit
+}
+
+// CheckInstalled looks for a newer upstream version of a resource installed
+// with 'resource add' or a template. Resources installed from their latest
+// release are compared with the latest release, and ones installed from a
+// branch with the branch's latest commit. Resources pinned to a release tag,
+// tag or commit have nothing newer. It returns nil when the resource is up to
+// date.
+func CheckInstalled(res types.InstalledResource) (*Update, error) {
+	src, err := ParseGitHubSource(res.Repository)
+	if err != nil {
+		return nil, err
+	}
+	src.Ref = res.Ref
+
+	if res.Release {
+		if res.Ref != "" {
+			return nil, nil
+		}
+		release, err := FindRelease(src)
+		if err != nil {
+			return nil, err
+		}
+		// Older installs didn't record the tag, only when they happened
+		if res.Tag == release.Tag || res.Tag == "" && !release.Published.After(res.InstalledAt) {
+			return nil, nil
+		}
+		current := res.Tag
+		if current == "" {
+			current = "installed " + res.InstalledAt.Format("2006-01-02")
+		}
+		return &Update{Current: current, Available: release.Tag}, nil
+	}
+
+	sha, date, err := latestCommit(src)
+	if err != nil {
+		return nil, err
+	}
+	// A tag or commit ref resolves to the commit that was installed
+	if !date.After(res.InstalledAt) {
+		return nil, nil
+	}
+	return &Update{
+		Current:   "installed " + res.InstalledAt.Format("2006-01-02"),
+		Available: fmt.Sprintf("%s (%s)", shortSHA(sha), date.Format("2006-01-02")),
+	}, nil
+}
+
+// latestCommit returns the commit src.Ref (or the default branch) points to
+// and when it was committed
+func latestCommit(src *GitHubSource) (string, time.Time, error) {
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/commits/%s", githubAPI, url.PathEscape(src.Owner), url.PathEscape(src.Repo), url.PathEscape(ref))
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := network.NewAPIClient().Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to look up commits of %s: %w", src.URL(), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity:
+		return "", time.Time{}, fmt.Errorf("%s has no branch, tag or commit %s", src.URL(), ref)
+	case resp.StatusCode != http.StatusOK:
+		return "", time.Time{}, fmt.Errorf("failed to look up commits of %s: HTTP %d", src.URL(), resp.StatusCode)
+	}
+
+	var body struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse commits of %s: %w", src.URL(), err)
+	}
+
+	return body.SHA, body.Commit.Committer.Date, nil
+}
+
+// IsGitCheckout reports whether a resource folder is a git working copy of its own
+func IsGitCheckout(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// CheckCheckout fetches a resource folder that's a git checkout and returns
+// how many commits its branch is behind the branch it tracks, and the
+// commit it's on. A branch that doesn't track one is never behind.
+func CheckCheckout(ctx context.Context, dir string) (behind int, head string, err error) {
+	head, err = gitOutput(ctx, dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := gitOutput(ctx, dir, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		return 0, head, nil
+	}
+
+	if _, err := gitOutput(ctx, dir, "fetch", "--quiet"); err != nil {
+		return 0, head, err
+	}
+	count, err := gitOutput(ctx, dir, "rev-list", "--count", "HEAD..@{upstream}")
+	if err != nil {
+		return 0, head, err
+	}
+	behind, _ = strconv.Atoi(count)
+	return behind, head, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output, or its first line
+// of error output on failure
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Keep git from asking for credentials for private remotes
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if line, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); line != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], line)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// shortSHA abbreviates a commit hash the way git does
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/VexoaXYZ/inkwash/pkg/types"
@@ -28,8 +29,8 @@ sv_licenseKey "{{.LicenseKey}}"
 sv_maxclients {{.MaxPlayers}}
 
 ## Server Endpoints
-endpoint_add_tcp "0.0.0.0:{{.Port}}"
-endpoint_add_udp "0.0.0.0:{{.Port}}"
+endpoint_add_tcp "{{.Endpoint}}"
+endpoint_add_udp "{{.Endpoint}}"
 
 ## ═══════════════════════════════════════════════════════════════
 ##  Core Resources
@@ -88,8 +89,9 @@ set sv_endpointprivacy true
 ##  Server Configuration
 ## ═══════════════════════════════════════════════════════════════
 
-set sv_enforceGameBuild 2802
-sv_scriptHookAllowed 0
+{{if .GameBuild}}set sv_enforceGameBuild {{.GameBuild}}
+{{else}}# set sv_enforceGameBuild 2802
+{{end}}sv_scriptHookAllowed 0
 
 ## ═══════════════════════════════════════════════════════════════
 ##  Add your custom resources below this line
@@ -98,6 +100,38 @@ sv_scriptHookAllowed 0
 # ensure your-resource-name
 `
 
+const gitignoreTemplate = `# Generated by Inkwash
+# Runtime data and downloaded binaries don't belong in version control
+
+cache/
+logs/
+crashes/
+bin/
+bin.*/
+*.log
+.inkwash.lock
+`
+
+const readmeTemplate = `# {{.ServerName}}
+
+FiveM server managed by [Inkwash](https://github.com/VexoaXYZ/InkWash).
+
+## Running
+
+    inkwash start {{.NameArg}}
+    inkwash logs {{.NameArg}}
+    inkwash stop {{.NameArg}}
+
+## Layout
+
+- ` + "`server.cfg`" + ` - server configuration (listens on {{.Endpoint}})
+- ` + "`resources/`" + ` - server resources; add ` + "`ensure <name>`" + ` lines to server.cfg to start them
+- ` + "`bin/`" + ` - FXServer binaries (installed by Inkwash, not committed)
+- ` + "`cache/`" + `, ` + "`logs/`" + ` - runtime data (not committed)
+
+Keep your license key out of public repositories: server.cfg contains it.
+`
+
 // ConfigGenerator generates server configuration files
 type ConfigGenerator struct{}
 
@@ -125,11 +159,18 @@ func (cg *ConfigGenerator) GenerateServerConfig(server *types.Server, licenseKey
 		LicenseKey  string
 		MaxPlayers  int
 		Port        int
+		Endpoint    string
+		GameBuild   int // 0 leaves the line commented out
 	}{
 		ServerName: server.Name,
 		LicenseKey: licenseKey,
 		MaxPlayers: 32,
 		Port:       server.Port,
+		Endpoint:   server.Endpoint(),
+	}
+
+	if gameBuild := server.GetGameBuild(); gameBuild != types.GameBuildNone {
+		data.GameBuild = gameBuild
 	}
 
 	if err := tmpl.Execute(file, data); err != nil {
@@ -139,6 +180,98 @@ func (cg *ConfigGenerator) GenerateServerConfig(server *types.Server, licenseKey
 	return nil
 }
 
+// scaffoldData is what the scaffold templates are filled in with
+type scaffoldData struct {
+	ServerName string
+	NameArg    string
+	Endpoint   string
+}
+
+func newScaffoldData(server *types.Server) scaffoldData {
+	// Quote names with spaces so the commands can be copied as-is
+	nameArg := server.Name
+	if strings.ContainsAny(nameArg, " \t") {
+		nameArg = fmt.Sprintf("%q", nameArg)
+	}
+
+	return scaffoldData{
+		ServerName: server.Name,
+		NameArg:    nameArg,
+		Endpoint:   server.Endpoint(),
+	}
+}
+
+// GenerateScaffoldFiles writes a .gitignore and README.md into the server directory.
+// Existing files are left untouched.
+func (cg *ConfigGenerator) GenerateScaffoldFiles(server *types.Server) error {
+	data := newScaffoldData(server)
+
+	files := []struct {
+		name     string
+		template string
+	}{
+		{".gitignore", gitignoreTemplate},
+		{"README.md", readmeTemplate},
+	}
+
+	for _, f := range files {
+		path := filepath.Join(server.Path, f.name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		tmpl, err := template.New(f.name).Parse(f.template)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s template: %w", f.name, err)
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.name, err)
+		}
+
+		err = tmpl.Execute(file, data)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// RenameScaffoldFiles rewrites the generated README.md, whose commands name
+// the server, after a rename. A README that's been edited since it was
+// generated is left alone; the bool reports whether it was rewritten.
+func (cg *ConfigGenerator) RenameScaffoldFiles(old, renamed *types.Server) (bool, error) {
+	path := filepath.Join(renamed.Path, "README.md")
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil
+	}
+
+	tmpl, err := template.New("README.md").Parse(readmeTemplate)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse README.md template: %w", err)
+	}
+
+	var generated, updated strings.Builder
+	if err := tmpl.Execute(&generated, newScaffoldData(old)); err != nil {
+		return false, fmt.Errorf("failed to render README.md: %w", err)
+	}
+	if generated.String() != string(current) {
+		return false, nil
+	}
+
+	if err := tmpl.Execute(&updated, newScaffoldData(renamed)); err != nil {
+		return false, fmt.Errorf("failed to render README.md: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(updated.String()), 0644); err != nil {
+		return false, fmt.Errorf("failed to write README.md: %w", err)
+	}
+	return true, nil
+}
+
 // GenerateLaunchScript generates platform-specific launch script
 func (cg *ConfigGenerator) GenerateLaunchScript(server *types.Server) error {
 	scriptPath, scriptContent := cg.getScriptTemplate(server)
@@ -150,26 +283,69 @@ func (cg *ConfigGenerator) GenerateLaunchScript(server *types.Server) error {
 	return nil
 }
 
-// getScriptTemplate returns the script path and content for the platform
+// getScriptTemplate returns the script path and content for the server's target platform
 func (cg *ConfigGenerator) getScriptTemplate(server *types.Server) (string, string) {
-	if isWindows() {
+	env := LaunchEnv(server)
+
+	if server.GetTargetOS() == types.TargetWindows {
+		launch := []string{`bin\FXServer.exe`, "+exec", ProfileConfigName(server.Profile)}
+		for _, arg := range LaunchOptions(server).Args {
+			launch = append(launch, cmdQuote(arg))
+		}
+		var sets strings.Builder
+		for _, pair := range env {
+			sets.WriteString(`set "` + cmdEscape(pair) + "\"\n")
+		}
+
 		scriptPath := filepath.Join(server.Path, "run.cmd")
 		content := fmt.Sprintf(`@echo off
 cd /d "%s"
-bin\FXServer.exe +exec server.cfg
-`, server.Path)
+%s%s
+`, server.Path, sets.String(), strings.Join(launch, " "))
 		return scriptPath, content
 	}
 
-	// Linux
+	// Linux: start the server the same way inkwash does
+	launch := "bash bin/run.sh +exec " + ProfileConfigName(server.Profile)
+	if argv, err := LaunchCommand(server); err == nil {
+		quoted := make([]string, len(argv))
+		for i, arg := range argv {
+			quoted[i] = shellQuote(arg)
+		}
+		launch = strings.Join(quoted, " ")
+	}
+	var exports strings.Builder
+	for _, pair := range env {
+		key, value, _ := strings.Cut(pair, "=")
+		exports.WriteString("export " + key + "=" + shellQuote(value) + "\n")
+	}
+
 	scriptPath := filepath.Join(server.Path, "run.sh")
 	content := fmt.Sprintf(`#!/bin/bash
 cd "%s"
-bash bin/run.sh +exec server.cfg
-`, server.Path)
+%s%s
+`, server.Path, exports.String(), launch)
 	return scriptPath, content
 }
 
-func isWindows() bool {
-	return os.PathSeparator == '\\'
+// shellQuote quotes s for bash if it contains anything but plain path characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:+=") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cmdQuote quotes s for cmd.exe if it contains spaces or characters cmd.exe
+// treats specially
+func cmdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t&|<>^()\"%") {
+		return s
+	}
+	return `"` + cmdEscape(strings.ReplaceAll(s, `"`, `""`)) + `"`
+}
+
+// cmdEscape keeps cmd.exe from expanding %VARIABLES% in s
+func cmdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
 }
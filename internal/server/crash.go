@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+const (
+	// crashesDirName is the folder in a server's folder crash reports are
+	// collected in, one subfolder per crash. FXServer also drops its own
+	// dumps there.
+	crashesDirName = "crashes"
+
+	// crashReportFile describes a crash inside its folder
+	crashReportFile = "crash.json"
+
+	// crashLogFile holds the end of the log in a crash's folder
+	crashLogFile = "server.log"
+
+	// CrashLogLines is how many lines of the log are kept with a crash
+	CrashLogLines = 200
+
+	// MaxCrashReports is how many crash folders are kept; dumps can be
+	// hundreds of MB, so older ones are deleted
+	MaxCrashReports = 10
+)
+
+// CrashReport describes a crash collected by RecordCrash
+type CrashReport struct {
+	ID        string    `json:"id"`                   // Folder name under crashes/, from the time
+	Time      time.Time `json:"time"`                 // When the crash was noticed
+	PID       int       `json:"pid"`                  // The process that exited
+	StartedAt time.Time `json:"started_at,omitempty"` // When that process was started
+	LogLines  int       `json:"log_lines"`            // Lines of the log kept in server.log
+	Dumps     []string  `json:"dumps,omitempty"`      // CitizenFX crash dumps moved into the folder
+	Dir       string    `json:"-"`
+}
+
+// CrashesDir returns the folder a server's crash reports are kept in
+func CrashesDir(serverPath string) string {
+	return filepath.Join(serverPath, crashesDirName)
+}
+
+// RecordCrash records that a server's process exited without being stopped:
+// it counts the crash in metadata.json and collects the last CrashLogLines
+// lines of the log and any crash dumps written since the process started
+// into crashes/<timestamp>/. server.PID must still be the process that
+// exited. A crash already recorded for that PID returns nil and no error.
+// The report is returned even if part of the collection failed.
+func RecordCrash(server *types.Server) (*CrashReport, error) {
+	mm := NewMetadataManager()
+	metadata, err := mm.Load(server.Path)
+	if err == nil && metadata.Stats.LastCrashPID == server.PID && metadata.Stats.LastCrash != nil &&
+		!metadata.Stats.LastCrash.Before(server.LastStarted) {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if err == nil {
+		metadata.Stats.CrashCount++
+		metadata.Stats.LastCrash = &now
+		metadata.Stats.LastCrashPID = server.PID
+		err = mm.Save(server.Path, metadata)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to record crash in metadata: %w", err)
+	}
+
+	report, collectErr := collectCrash(server, now)
+	if err == nil {
+		err = collectErr
+	}
+	pruneCrashReports(server.Path)
+	return report, err
+}
+
+// collectCrash creates a crash's folder and fills it
+func collectCrash(server *types.Server, now time.Time) (*CrashReport, error) {
+	crashesDir := CrashesDir(server.Path)
+	if err := os.MkdirAll(crashesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create crashes folder: %w", err)
+	}
+
+	// Two crashes in the same second get a suffix rather than sharing a folder
+	id := now.Format("20060102-150405")
+	dir := filepath.Join(crashesDir, id)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create crash folder: %w", err)
+		}
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), i)
+		dir = filepath.Join(crashesDir, id)
+	}
+
+	report := &CrashReport{ID: id, Time: now, PID: server.PID, StartedAt: server.LastStarted, Dir: dir}
+	var problems []string
+
+	if lines, err := TailLog(server.Path, CrashLogLines); err == nil {
+		data := strings.Join(lines, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, crashLogFile), []byte(data), 0644); err != nil {
+			problems = append(problems, fmt.Sprintf("failed to save the log: %v", err))
+		} else {
+			report.LogLines = len(lines)
+		}
+	} else if !os.IsNotExist(err) {
+		problems = append(problems, fmt.Sprintf("failed to read the log: %v", err))
+	}
+
+	dumps, err := moveCrashDumps(server, dir)
+	report.Dumps = dumps
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, crashReportFile), data, 0644)
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to write %s: %v", crashReportFile, err))
+	}
+
+	if len(problems) > 0 {
+		return report, fmt.Errorf("crash collected in %s with problems: %s", dir, strings.Join(problems, "; "))
+	}
+	return report, nil
+}
+
+// moveCrashDumps moves the dumps CitizenFX wrote since the server started
+// into dir. FXServer writes them to crashes/ in its working directory (the
+// server folder) or next to its binaries, depending on the platform.
+func moveCrashDumps(server *types.Server, dir string) ([]string, error) {
+	var dumps []string
+	var failed []string
+	for _, dumpDir := range []string{CrashesDir(server.Path), filepath.Join(server.Path, "bin", crashesDirName)} {
+		entries, err := os.ReadDir(dumpDir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			info, err := entry.Info()
+			// Earlier crashes' folders are directories, dumps are files
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if !server.LastStarted.IsZero() && info.ModTime().Before(server.LastStarted) {
+				continue
+			}
+
+			if err := os.Rename(filepath.Join(dumpDir, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+				failed = append(failed, entry.Name())
+				continue
+			}
+			dumps = append(dumps, entry.Name())
+		}
+	}
+
+	if len(failed) > 0 {
+		return dumps, fmt.Errorf("failed to move crash dumps %s", strings.Join(failed, ", "))
+	}
+	return dumps, nil
+}
+
+// pruneCrashReports deletes all but the newest MaxCrashReports crash folders
+func pruneCrashReports(serverPath string) {
+	reports, err := ListCrashes(serverPath)
+	if err != nil {
+		return
+	}
+	for _, report := range reports[min(len(reports), MaxCrashReports):] {
+		os.RemoveAll(report.Dir)
+	}
+}
+
+// ListCrashes returns a server's collected crashes, newest first
+func ListCrashes(serverPath string) ([]CrashReport, error) {
+	entries, err := os.ReadDir(CrashesDir(serverPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crashes folder: %w", err)
+	}
+
+	var reports []CrashReport
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(CrashesDir(serverPath), entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, crashReportFile))
+		if err != nil {
+			// Not one of ours
+			continue
+		}
+		var report CrashReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			continue
+		}
+		report.ID = entry.Name()
+		report.Dir = dir
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Time.After(reports[j].Time)
+	})
+	return reports, nil
+}
+
+// CrashLog returns the log lines kept with a crash
+func CrashLog(report *CrashReport) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(report.Dir, crashLogFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crash log: %w", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// DeleteOptions controls Delete
+type DeleteOptions struct {
+	KeepResources bool   // Zip resources/ before the folder is removed
+	ArchivePath   string // Where the zip goes, defaults to ResourceArchivePath
+}
+
+// DeleteResult describes a finished Delete
+type DeleteResult struct {
+	ArchivePath string // The zip of resources/, "" if it wasn't kept
+}
+
+// ResourceArchivePath returns where Delete keeps a server's resources by
+// default: archives/ under the data directory, named after the folder and time
+func ResourceArchivePath(srv *types.Server) string {
+	name := fmt.Sprintf("%s-resources-%s.zip", filepath.Base(srv.Path), time.Now().Format("20060102-150405"))
+	return filepath.Join(registry.GetDefaultDataPath(), "archives", name)
+}
+
+// Delete unregisters a stopped server and removes its folder, including
+// metadata.json. Folders that don't look like a server, or that hold
+// inkwash's own data or another server, are refused before anything changes.
+// The caller checks the server isn't running.
+func Delete(reg *registry.Registry, srv *types.Server, opts DeleteOptions) (*DeleteResult, error) {
+	path, err := filepath.Abs(srv.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeletable(reg, srv, path); err != nil {
+		return nil, err
+	}
+
+	result, err := unregisterForDelete(reg, srv, path, opts)
+	if err != nil {
+		return result, err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return result, fmt.Errorf("server unregistered but its folder couldn't be fully removed: %w", err)
+	}
+	return result, nil
+}
+
+// unregisterForDelete archives the resources if asked to and removes the
+// registry entry, holding the server's lock. The lock is released before the
+// folder is removed, as Windows can't remove a file that's open.
+func unregisterForDelete(reg *registry.Registry, srv *types.Server, path string, opts DeleteOptions) (*DeleteResult, error) {
+	lock, err := LockServer(path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	result := &DeleteResult{}
+	if opts.KeepResources {
+		archive := opts.ArchivePath
+		if archive == "" {
+			archive = ResourceArchivePath(srv)
+		}
+		archive, err = filepath.Abs(archive)
+		if err != nil {
+			return nil, err
+		}
+		if isWithin(path, archive) {
+			return nil, fmt.Errorf("the resources archive can't go inside the server folder being deleted")
+		}
+		if err := ArchiveResources(path, archive); err != nil {
+			return nil, err
+		}
+		result.ArchivePath = archive
+	}
+
+	if err := reg.Remove(srv.Name); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// checkDeletable refuses to delete folders a mistaken registry entry could
+// point at: ones without any server files, filesystem roots, the home
+// directory, inkwash's own directories and folders holding another server
+func checkDeletable(reg *registry.Registry, srv *types.Server, path string) error {
+	if len(DetectServer(path)) == 0 {
+		return fmt.Errorf("%s doesn't look like a FiveM server folder (no server.cfg, metadata.json or bin/); remove it by hand", path)
+	}
+
+	if filepath.Dir(path) == path {
+		return fmt.Errorf("refusing to delete %s, a filesystem root", path)
+	}
+	protected := []string{
+		registry.GetDefaultConfigPath(),
+		registry.GetDefaultCachePath(),
+		registry.GetDefaultDataPath(),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		protected = append(protected, home)
+	}
+	for _, dir := range protected {
+		if dir != "" && isWithin(path, dir) {
+			return fmt.Errorf("refusing to delete %s, it contains %s", path, dir)
+		}
+	}
+
+	for _, other := range reg.List() {
+		if other.Name != srv.Name && isWithin(path, other.Path) {
+			return fmt.Errorf("refusing to delete %s, it contains server '%s'", path, other.Name)
+		}
+	}
+	return nil
+}
+
+// ArchiveResources zips a server's resources/ folder into dest, with paths
+// starting at resources/ so the zip can be extracted into a server folder.
+// Symlinks are skipped.
+func ArchiveResources(serverPath, dest string) error {
+	resourcesPath := filepath.Join(serverPath, "resources")
+	if _, err := os.Stat(resourcesPath); err != nil {
+		return fmt.Errorf("failed to archive resources: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create archive folder: %w", err)
+	}
+
+	// Written aside and renamed, so a failed archive never looks complete
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".inkwash-archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := zip.NewWriter(tmp)
+	err = filepath.Walk(resourcesPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(serverPath, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zw.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(w, file)
+		return err
+	})
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to archive resources: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+	return nil
+}
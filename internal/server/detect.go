@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrServerExists is returned when an install target already contains a server
+var ErrServerExists = errors.New("a server already exists at this path")
+
+// serverMarkers are files that only a FiveM server folder contains
+var serverMarkers = []string{
+	"server.cfg",
+	"metadata.json",
+	filepath.Join("bin", "FXServer.exe"),
+	filepath.Join("bin", "run.sh"),
+}
+
+// ExistingServerError describes a server found where a new one would be installed
+type ExistingServerError struct {
+	Path       string
+	Markers    []string // Server files found in Path
+	Registered string   // Name of the registered server using Path, "" if unregistered
+}
+
+func (e *ExistingServerError) Error() string {
+	if e.Registered != "" {
+		return fmt.Sprintf("%s is already used by server '%s'; choose a different name or --path", e.Path, e.Registered)
+	}
+	return fmt.Sprintf("%s already contains a FiveM server that inkwash doesn't manage (found %s); "+
+		"choose a different name or --path, or pass --force to install over it",
+		e.Path, strings.Join(e.Markers, ", "))
+}
+
+// Unwrap lets callers match with errors.Is(err, ErrServerExists)
+func (e *ExistingServerError) Unwrap() error {
+	return ErrServerExists
+}
+
+// DetectServer returns the server files present in path, or nil if it doesn't
+// look like a server folder (missing, empty, or unrelated content)
+func DetectServer(path string) []string {
+	var found []string
+	for _, marker := range serverMarkers {
+		if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+			found = append(found, filepath.ToSlash(marker))
+		}
+	}
+	return found
+}
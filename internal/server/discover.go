@@ -0,0 +1,132 @@
+package server
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// maxDiscoverDepth bounds how far below the root Discover looks for servers
+const maxDiscoverDepth = 4
+
+// Folders that never contain a server of their own
+var skipDiscoverDirs = map[string]bool{
+	"bin":          true,
+	"cache":        true,
+	"logs":         true,
+	"resources":    true,
+	"node_modules": true,
+}
+
+// DiscoveredServer is an InkWash server folder found on disk
+type DiscoveredServer struct {
+	Server   types.Server          // Registry entry rebuilt from the folder
+	Metadata *types.ServerMetadata // Parsed metadata.json
+}
+
+// Discover walks root for InkWash servers (folders with both metadata.json and
+// server.cfg) and rebuilds their registry entries. Names come from the folder,
+// network settings and game build from server.cfg. Unreadable folders are skipped.
+func Discover(root string) ([]DiscoveredServer, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	mm := NewMetadataManager()
+	var found []DiscoveredServer
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		if path != root {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || skipDiscoverDirs[strings.ToLower(name)] {
+				return filepath.SkipDir
+			}
+			if rel, err := filepath.Rel(root, path); err == nil && strings.Count(rel, string(filepath.Separator)) >= maxDiscoverDepth {
+				return filepath.SkipDir
+			}
+		}
+
+		if !mm.Exists(path) {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, "server.cfg")); err != nil {
+			return nil
+		}
+
+		metadata, err := mm.Load(path)
+		if err != nil {
+			return nil
+		}
+
+		found = append(found, DiscoveredServer{
+			Server:   rebuildServer(path, metadata),
+			Metadata: metadata,
+		})
+
+		// A server's own subfolders can't hold another server
+		return filepath.SkipDir
+	})
+
+	return found, err
+}
+
+// rebuildServer reconstructs a registry entry from a server folder
+func rebuildServer(path string, metadata *types.ServerMetadata) types.Server {
+	srv := types.Server{
+		Name:     filepath.Base(path),
+		Path:     path,
+		Port:     30120,
+		TargetOS: detectTargetOS(path),
+		Created:  metadata.Lifecycle.CreatedAt,
+	}
+	if srv.Created.IsZero() {
+		srv.Created = time.Now()
+	}
+
+	cfg, err := servercfg.Load(filepath.Join(path, "server.cfg"))
+	if err != nil {
+		return srv
+	}
+
+	if host, port, ok := cfg.Endpoint(); ok {
+		srv.Port = port
+		if host != types.DefaultBindAddress {
+			srv.BindAddress = host
+		}
+	}
+
+	if value, ok := cfg.Convar("sv_enforceGameBuild"); ok {
+		if build, err := strconv.Atoi(value); err == nil && build > 0 {
+			srv.GameBuild = build
+		}
+	} else {
+		srv.GameBuild = types.GameBuildNone
+	}
+
+	return srv
+}
+
+// detectTargetOS guesses a server's platform from the binaries in bin/
+func detectTargetOS(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "bin", "FXServer.exe")); err == nil {
+		return types.TargetWindows
+	}
+	if _, err := DetectEntrypoint(filepath.Join(path, "bin"), types.TargetLinux); err == nil {
+		return types.TargetLinux
+	}
+	return ""
+}
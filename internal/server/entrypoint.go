@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrNoEntrypoint is returned when a server's FXServer build has no known way to start it
+var ErrNoEntrypoint = errors.New("no FXServer entrypoint found")
+
+// alpineRoots are where Linux builds keep their Alpine userland: under alpine/,
+// or at the top when the archive's alpine/ folder was unwrapped on install
+var alpineRoots = []string{"alpine", ""}
+
+// LaunchCommand returns the command that starts a server from its folder: the
+// server's own launch command if one is set, otherwise the entrypoint detected
+// in its bin folder, followed by the extra arguments in its metadata.json.
+// Either way, the server's profile is exec'd in place of server.cfg.
+func LaunchCommand(server *types.Server) ([]string, error) {
+	extra := LaunchOptions(server).Args
+	if custom := strings.Fields(server.LaunchCommand); len(custom) > 0 {
+		for i := 1; i < len(custom); i++ {
+			if custom[i-1] == "+exec" && custom[i] == "server.cfg" {
+				custom[i] = ProfileConfigName(server.Profile)
+			}
+		}
+		return append(custom, extra...), nil
+	}
+
+	entrypoint, err := DetectEntrypoint(server.GetBinaryPath(), server.GetTargetOS())
+	if err != nil {
+		return nil, fmt.Errorf("can't start '%s': %w", server.Name, err)
+	}
+	return append(append(entrypoint, "+exec", ProfileConfigName(server.Profile)), extra...), nil
+}
+
+// LaunchOptions returns the extra arguments and environment variables set for
+// a server in its metadata.json; none if it can't be read
+func LaunchOptions(server *types.Server) types.LaunchOptions {
+	metadata, err := NewMetadataManager().Load(server.Path)
+	if err != nil || metadata.Launch == nil {
+		return types.LaunchOptions{}
+	}
+	return *metadata.Launch
+}
+
+// LaunchEnv returns the extra environment variables set for a server as
+// sorted KEY=value pairs
+func LaunchEnv(server *types.Server) []string {
+	env := LaunchOptions(server).Env
+	pairs := make([]string, 0, len(env))
+	for key, value := range env {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// DetectEntrypoint finds how to start the FXServer build in binPath. Linux
+// builds are started through their run.sh, a plain FXServer binary, or
+// Alpine's FXServer under its bundled musl loader, in that order of preference.
+func DetectEntrypoint(binPath, targetOS string) ([]string, error) {
+	if targetOS == types.TargetWindows {
+		exePath := filepath.Join(binPath, "FXServer.exe")
+		if isFile(exePath) {
+			return []string{exePath}, nil
+		}
+		return nil, fmt.Errorf("%w: FXServer.exe is missing from %s", ErrNoEntrypoint, binPath)
+	}
+
+	if script := filepath.Join(binPath, "run.sh"); isFile(script) {
+		return []string{"bash", script}, nil
+	}
+
+	if exe := filepath.Join(binPath, "FXServer"); isFile(exe) {
+		return []string{exe}, nil
+	}
+
+	for _, root := range alpineRoots {
+		rootPath := filepath.Join(binPath, root)
+		serverDir := filepath.Join(rootPath, "opt", "cfx-server")
+		exe := filepath.Join(serverDir, "FXServer")
+		if !isFile(exe) {
+			continue
+		}
+
+		citizen := []string{"+set", "citizen_dir", filepath.Join(serverDir, "citizen") + string(filepath.Separator)}
+
+		// FXServer is linked against musl, which the host may not have
+		loaders, _ := filepath.Glob(filepath.Join(serverDir, "ld-musl-*.so.1"))
+		if len(loaders) == 0 {
+			loaders, _ = filepath.Glob(filepath.Join(rootPath, "lib", "ld-musl-*.so.1"))
+		}
+		if len(loaders) == 0 {
+			return append([]string{exe}, citizen...), nil
+		}
+
+		libraryPath := strings.Join([]string{
+			filepath.Join(rootPath, "usr", "lib", "v8") + "/",
+			filepath.Join(rootPath, "lib") + "/",
+			filepath.Join(rootPath, "usr", "lib") + "/",
+		}, ":")
+		return append([]string{loaders[0], "--library-path", libraryPath, "--", exe}, citizen...), nil
+	}
+
+	return nil, fmt.Errorf("%w in %s (looked for run.sh, FXServer and alpine/opt/cfx-server/FXServer); "+
+		"set the server's launch_command if its build starts another way", ErrNoEntrypoint, binPath)
+}
+
+// isFile reports whether path exists and is a regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
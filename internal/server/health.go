@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/query"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Health probe names, as they appear in reports and incidents
+const (
+	ProbeTCP     = "tcp"
+	ProbePlayers = "players.json"
+	ProbeLog     = "log"
+)
+
+// HealthProbe is the outcome of one probe
+type HealthProbe struct {
+	Name string
+	Err  error // nil if the probe passed
+}
+
+// HealthReport is the outcome of one round of health checks
+type HealthReport struct {
+	Time   time.Time
+	Probes []HealthProbe
+}
+
+// Healthy reports whether every probe passed
+func (r *HealthReport) Healthy() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures describes the probes that failed, as "name: reason"
+func (r *HealthReport) Failures() []string {
+	var failures []string
+	for _, p := range r.Probes {
+		if p.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", p.Name, p.Err))
+		}
+	}
+	return failures
+}
+
+// CheckHealth runs the probes cfg enables against a running server: a TCP
+// connect to its game port, a players.json request and how long ago its log
+// was written to. Each probe gets cfg.Timeout seconds.
+func CheckHealth(ctx context.Context, server *types.Server, cfg types.HealthConfig) *HealthReport {
+	report := &HealthReport{Time: time.Now()}
+	address := QueryAddress(server)
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	if !cfg.NoTCP {
+		report.Probes = append(report.Probes, HealthProbe{Name: ProbeTCP, Err: probeTCP(ctx, address, timeout)})
+	}
+	if !cfg.NoPlayers {
+		report.Probes = append(report.Probes, HealthProbe{Name: ProbePlayers, Err: probePlayers(ctx, address, timeout)})
+	}
+	if cfg.LogTimeout > 0 {
+		report.Probes = append(report.Probes, HealthProbe{Name: ProbeLog, Err: probeLog(server, time.Duration(cfg.LogTimeout)*time.Second)})
+	}
+	return report
+}
+
+// probeTCP checks the server accepts connections on its game port
+func probeTCP(ctx context.Context, address string, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("no connection to %s: %w", address, err)
+	}
+	return conn.Close()
+}
+
+// probePlayers checks the server answers HTTP requests, which stop when its
+// main thread hangs even though the port stays open
+func probePlayers(ctx context.Context, address string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := query.Players(ctx, address)
+	return err
+}
+
+// probeLog checks the server has written to its log within maxAge
+func probeLog(server *types.Server, maxAge time.Duration) error {
+	info, err := os.Stat(LogPath(server.Path))
+	if err != nil {
+		return fmt.Errorf("can't read the log: %w", err)
+	}
+
+	if age := time.Since(info.ModTime()); age > maxAge {
+		return fmt.Errorf("no output for %s (allowed %s)", age.Round(time.Second), maxAge)
+	}
+	return nil
+}
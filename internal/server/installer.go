@@ -1,9 +1,14 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -11,8 +16,12 @@ import (
 	"time"
 
 	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/database"
 	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/recipe"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/staging"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
@@ -25,8 +34,51 @@ type InstallProgress struct {
 	CurrentFile     string
 	TotalSteps      int
 	CompletedSteps  int
+	Warning         bool // Step is something to follow up on, e.g. a skipped recipe task
 }
 
+// InstallOptions describes the server to install
+type InstallOptions struct {
+	ServerName   string
+	InstallPath  string
+	BuildNumber  int
+	BuildChannel string // "recommended", "optional" or "latest"; resolved at install time and overrides BuildNumber
+	LicenseKey   string
+	KeyID        string // Vault ID of LicenseKey, recorded on the server; "" for a key typed in
+	Port         int
+	BindAddress  string // Defaults to types.DefaultBindAddress
+	NoScaffold   bool   // Skip writing .gitignore and README.md
+	TargetOS     string // Platform to install binaries for, defaults to the host
+	GameBuild    int    // sv_enforceGameBuild, 0 for types.DefaultGameBuild, types.GameBuildNone to disable
+	Force        bool   // Install over an unregistered server already in the target folder
+	ArtifactURL  string // Download FXServer from this archive instead of the artifacts page; overrides BuildNumber and BuildChannel
+
+	ForceRedownload bool   // Ignore the cached copy of the build and replace it with a fresh download; also updates the cached cfx-server-data
+	LaunchCommand   string // Start the server with this command instead of the detected entrypoint
+
+	Verify bool   // Test the downloaded archive's integrity, downloading again if it's damaged
+	SHA256 string // Expected SHA256 of the downloaded archive; implies Verify
+
+	Recipe   *recipe.Recipe    // txAdmin recipe run in the server folder instead of cloning cfx-server-data
+	Database *database.Options // MySQL/MariaDB database created for the server, named after it unless set
+
+	// Template is applied with ApplyTemplate once the server is set up, also
+	// setting up its database when Database is set
+	Template      *types.Template
+	ApplyTemplate TemplateFunc
+
+	keepBinary string // Where an update moves the bin/ it replaces, "" to delete it
+}
+
+// TemplateFunc applies a template to a server being installed, setting up its
+// database with db unless that's nil, in which case the template's SQL files
+// are returned to be run later. template.ApplyNew is one; that package
+// depends on this one, so the installer can't call it directly.
+type TemplateFunc func(ctx context.Context, server *types.Server, t *types.Template, db *database.Options, onStep func(step string)) (sqlFiles []string, err error)
+
+// downloadAttempts is how often a download that fails verification is tried
+const downloadAttempts = 3
+
 // ProgressCallback is called during installation
 type ProgressCallback func(InstallProgress)
 
@@ -38,6 +90,8 @@ type Installer struct {
 	cache          *cache.BinaryCache
 	registry       *registry.Registry
 	configGen      *ConfigGenerator
+	verifyCache    bool   // Check cached builds' checksums before copying them
+	serverDataDir  string // Shared copy of cfx-server-data new servers start from
 }
 
 // NewInstaller creates a new installer
@@ -49,9 +103,27 @@ func NewInstaller(cache *cache.BinaryCache, registry *registry.Registry) *Instal
 		cache:          cache,
 		registry:       registry,
 		configGen:      NewConfigGenerator(),
+		serverDataDir:  DefaultServerDataPath(),
 	}
 }
 
+// SetLongPaths allows extracting paths longer than MAX_PATH on Windows
+func (inst *Installer) SetLongPaths(enabled bool) {
+	inst.extractor.SetLongPaths(enabled)
+}
+
+// SetVerifyCache checks a cached build's checksums before it's used; a
+// corrupt build is evicted and downloaded again
+func (inst *Installer) SetVerifyCache(enabled bool) {
+	inst.verifyCache = enabled
+}
+
+// SetServerDataPath sets where the shared copy of cfx-server-data is kept,
+// DefaultServerDataPath unless set
+func (inst *Installer) SetServerDataPath(path string) {
+	inst.serverDataDir = path
+}
+
 // slugifyServerName converts a server name to a safe folder name
 // Example: "Vexoa Test Server" -> "vexoa-test-server"
 func slugifyServerName(name string) string {
@@ -105,14 +177,29 @@ func ensureUniqueFolderName(basePath, folderName string) string {
 }
 
 // Install installs a new FiveM server
-func (inst *Installer) Install(
-	serverName string,
-	installPath string,
-	buildNumber int,
-	licenseKey string,
-	port int,
-	onProgress ProgressCallback,
-) error {
+func (inst *Installer) Install(opts InstallOptions, onProgress ProgressCallback) error {
+	return inst.InstallContext(context.Background(), opts, onProgress)
+}
+
+// InstallContext is Install, stopping when ctx is cancelled. A cancelled
+// install removes the server folder it created and returns ctx.Err().
+func (inst *Installer) InstallContext(ctx context.Context, opts InstallOptions, onProgress ProgressCallback) (err error) {
+	serverName := opts.ServerName
+	installPath := opts.InstallPath
+	buildNumber := opts.BuildNumber
+	licenseKey := opts.LicenseKey
+	port := opts.Port
+
+	bindAddress := opts.BindAddress
+	if bindAddress == "" {
+		bindAddress = types.DefaultBindAddress
+	}
+
+	targetOS := opts.TargetOS
+	if targetOS == "" {
+		targetOS = types.HostTargetOS()
+	}
+
 	totalSteps := 8
 
 	// Step 1: Validate inputs
@@ -127,6 +214,22 @@ func (inst *Installer) Install(
 		return err
 	}
 
+	if err := validation.ValidateBindAddress(bindAddress); err != nil {
+		return err
+	}
+
+	gameBuild := opts.GameBuild
+	if gameBuild == 0 {
+		gameBuild = types.DefaultGameBuild
+	}
+	if err := validation.ValidateGameBuild(gameBuild); err != nil {
+		return err
+	}
+
+	if err := inst.artifactClient.SetTargetOS(targetOS); err != nil {
+		return err
+	}
+
 	// Convert server name to slug for folder name
 	// This ensures filesystem safety: "Vexoa Test Server" -> "vexoa-test-server"
 	folderSlug := slugifyServerName(serverName)
@@ -134,8 +237,36 @@ func (inst *Installer) Install(
 		folderSlug = "fivem-server" // Fallback for invalid names
 	}
 
-	// Ensure the folder name is unique
-	folderSlug = ensureUniqueFolderName(installPath, folderSlug)
+	// Never install over another server unless asked to
+	reuseFolder, err := inst.checkExistingServer(filepath.Join(installPath, folderSlug), opts.Force)
+	if err != nil {
+		return err
+	}
+
+	// Otherwise pick a fresh folder name so unrelated folders are left alone
+	if !reuseFolder {
+		folderSlug = ensureUniqueFolderName(installPath, folderSlug)
+	}
+
+	if opts.ArtifactURL != "" {
+		// Checked before anything is written; the build comes from the URL
+		if _, _, err := validation.ParseArtifactURL(opts.ArtifactURL); err != nil {
+			return err
+		}
+	} else if opts.BuildChannel != "" {
+		build, err := inst.resolveBuildChannel(opts.BuildChannel)
+		if err != nil {
+			return err
+		}
+		buildNumber = build.Number
+
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           fmt.Sprintf("Using %s build %d", opts.BuildChannel, buildNumber),
+			Progress:       0,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 0,
+		})
+	}
 
 	// Step 2: Create directory structure
 	inst.reportProgress(onProgress, InstallProgress{
@@ -148,10 +279,26 @@ func (inst *Installer) Install(
 	serverPath := filepath.Join(installPath, folderSlug)
 	binaryPath := filepath.Join(serverPath, "bin")
 
+	// Only a folder this install created is removed when it's cancelled
+	_, statErr := os.Stat(serverPath)
+	createdFolder := os.IsNotExist(statErr)
+
 	if err := inst.createDirectories(serverPath, binaryPath); err != nil {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	defer func() {
+		if err != nil && ctx.Err() != nil && createdFolder {
+			os.RemoveAll(serverPath)
+		}
+	}()
+
+	lock, err := LockServer(serverPath, serverName)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	// Step 3: Get or download FXServer build
 	inst.reportProgress(onProgress, InstallProgress{
 		Step:           "Checking cache for FXServer build",
@@ -160,21 +307,57 @@ func (inst *Installer) Install(
 		CompletedSteps: 2,
 	})
 
-	targetBuild, err := inst.installBinary(buildNumber, binaryPath, onProgress)
+	targetBuild, err := inst.installBinary(ctx, opts, buildNumber, binaryPath, targetOS, onProgress)
 	if err != nil {
 		return fmt.Errorf("failed to install FXServer: %w", err)
 	}
+	if opts.Recipe != nil && targetBuild.Number > 0 && targetBuild.Number < opts.Recipe.MinFXVersion {
+		return fmt.Errorf("recipe '%s' needs FXServer build %d or newer, not %d", opts.Recipe.Name, opts.Recipe.MinFXVersion, targetBuild.Number)
+	}
 
-	// Step 4: Clone server-data repository
-	inst.reportProgress(onProgress, InstallProgress{
-		Step:           "Cloning cfx-server-data",
-		Progress:       0.57,
-		TotalSteps:     totalSteps,
-		CompletedSteps: 4,
-	})
+	server := &types.Server{
+		Name:      serverName,
+		Path:      serverPath,
+		KeyID:     opts.KeyID,
+		Port:      port,
+		TargetOS:  targetOS,
+		GameBuild: gameBuild,
+		Created:   time.Now(),
+
+		LaunchCommand: strings.TrimSpace(opts.LaunchCommand),
+	}
+
+	// Only store non-default addresses so existing entries stay unchanged
+	if bindAddress != types.DefaultBindAddress {
+		server.BindAddress = bindAddress
+	}
+
+	// Step 4: Clone server-data repository, or run the recipe, which brings its own
+	if opts.Recipe != nil {
+		if err := inst.runRecipe(ctx, opts.Recipe, server, licenseKey, totalSteps, onProgress); err != nil {
+			return err
+		}
+	} else {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           "Copying cfx-server-data",
+			Progress:       0.57,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 4,
+		})
 
-	if err := inst.cloneServerData(serverPath); err != nil {
-		return fmt.Errorf("failed to clone server-data: %w", err)
+		note, err := inst.cloneServerData(ctx, serverPath, opts.ForceRedownload)
+		if err != nil {
+			return fmt.Errorf("failed to clone server-data: %w", err)
+		}
+		if note != "" {
+			inst.reportProgress(onProgress, InstallProgress{
+				Step:           note,
+				Progress:       0.62,
+				TotalSteps:     totalSteps,
+				CompletedSteps: 4,
+				Warning:        true,
+			})
+		}
 	}
 
 	// Step 5: Create metadata.json
@@ -187,6 +370,16 @@ func (inst *Installer) Install(
 
 	metadataManager := NewMetadataManager()
 	metadata := types.NewServerMetadata(*targetBuild)
+	// A server created from a channel keeps following it; one created from a
+	// specific build stays on it
+	metadata.Channel = opts.BuildChannel
+	if metadata.Channel == "" || opts.ArtifactURL != "" {
+		metadata.Channel = types.BuildChannelPinned
+	}
+	if opts.Recipe != nil && opts.Recipe.OneSync != "" {
+		// txAdmin passes the recipe's OneSync mode on the command line too
+		metadata.Launch = &types.LaunchOptions{Args: []string{"+set", "onesync", opts.Recipe.OneSync}}
+	}
 	if err := metadataManager.Save(serverPath, metadata); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
 	}
@@ -199,15 +392,11 @@ func (inst *Installer) Install(
 		CompletedSteps: 6,
 	})
 
-	server := &types.Server{
-		Name:    serverName,
-		Path:    serverPath,
-		Port:    port,
-		Created: time.Now(),
-	}
-
-	if err := inst.configGen.GenerateServerConfig(server, licenseKey); err != nil {
-		return fmt.Errorf("failed to generate config: %w", err)
+	// A recipe's own server.cfg is kept
+	if opts.Recipe == nil || !isFile(filepath.Join(serverPath, "server.cfg")) {
+		if err := inst.configGen.GenerateServerConfig(server, licenseKey); err != nil {
+			return fmt.Errorf("failed to generate config: %w", err)
+		}
 	}
 
 	// Step 7: Create launch script
@@ -222,6 +411,24 @@ func (inst *Installer) Install(
 		return fmt.Errorf("failed to create launch script: %w", err)
 	}
 
+	if !opts.NoScaffold {
+		if err := inst.configGen.GenerateScaffoldFiles(server); err != nil {
+			return fmt.Errorf("failed to create scaffold files: %w", err)
+		}
+	}
+
+	switch {
+	case opts.Template != nil && opts.ApplyTemplate != nil:
+		inst.applyTemplate(ctx, opts, server, totalSteps, onProgress)
+	case opts.Database != nil:
+		inst.setupDatabase(ctx, *opts.Database, server, totalSteps, onProgress)
+	}
+
+	// Last chance to back out before the server becomes visible
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Step 8: Register server
 	inst.reportProgress(onProgress, InstallProgress{
 		Step:           "Registering server",
@@ -237,77 +444,133 @@ func (inst *Installer) Install(
 	return nil
 }
 
-// installBinary installs the FXServer binary and returns the Build info
-func (inst *Installer) installBinary(buildNumber int, binaryPath string, onProgress ProgressCallback) (*types.Build, error) {
-	// Fetch available builds first (needed for metadata even if cached)
-	inst.reportProgress(onProgress, InstallProgress{
-		Step:           "Fetching build information",
-		Progress:       0.30,
-		TotalSteps:     7,
-		CompletedSteps: 2,
-	})
-
+// resolveBuildChannel looks up the build a channel currently points to
+func (inst *Installer) resolveBuildChannel(channel string) (*types.Build, error) {
 	builds, err := inst.artifactClient.FetchBuilds()
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch builds: %w", err)
 	}
 
-	// Find the requested build
+	return download.ResolveBuild(builds, channel)
+}
+
+// installBinary installs the FXServer binary and returns the Build info
+func (inst *Installer) installBinary(ctx context.Context, opts InstallOptions, buildNumber int, binaryPath, targetOS string, onProgress ProgressCallback) (*types.Build, error) {
 	var targetBuild *types.Build
-	for _, build := range builds {
-		if build.Number == buildNumber {
-			targetBuild = &build
-			break
+	var downloadURL, archiveExt string
+
+	if artifactURL := opts.ArtifactURL; artifactURL != "" {
+		// A direct URL skips the artifacts page; the build number comes from the URL if it has one
+		build, ext, err := validation.ParseArtifactURL(artifactURL)
+		if err != nil {
+			return nil, err
 		}
+		targetBuild, downloadURL, archiveExt = &build, artifactURL, ext
+		buildNumber = build.Number
+	} else {
+		// Fetch available builds first (needed for metadata even if cached)
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           "Fetching build information",
+			Progress:       0.30,
+			TotalSteps:     7,
+			CompletedSteps: 2,
+		})
+
+		builds, err := inst.artifactClient.FetchBuilds()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch builds: %w", err)
+		}
+
+		// Find the requested build
+		for _, build := range builds {
+			if build.Number == buildNumber {
+				targetBuild = &build
+				break
+			}
+		}
+
+		if targetBuild == nil {
+			return nil, fmt.Errorf("build %d not found", buildNumber)
+		}
+
+		downloadURL = inst.artifactClient.GetDownloadURL(*targetBuild)
+		archiveExt = download.ArchiveExtensionFor(targetOS)
 	}
 
-	if targetBuild == nil {
-		return nil, fmt.Errorf("build %d not found", buildNumber)
+	buildLabel := fmt.Sprintf("Build %d", buildNumber)
+	if buildNumber == 0 {
+		buildLabel = path.Base(downloadURL)
 	}
 
-	// Check cache after getting build info
-	cachedPath, err := inst.cache.Get(buildNumber)
-	if err == nil {
-		// Copy from cache
+	// The cache only holds host builds, so staging for another platform always
+	// downloads. A URL without a build number has nothing to cache it under.
+	useCache := targetOS == types.HostTargetOS() && buildNumber > 0
+
+	if useCache && !opts.ForceRedownload && (inst.verifyCache || opts.Verify) && inst.cache.Has(buildNumber) {
 		inst.reportProgress(onProgress, InstallProgress{
-			Step:           "Copying from cache",
-			Progress:       0.35,
+			Step:           "Verifying cached build",
+			Progress:       0.32,
 			CurrentFile:    fmt.Sprintf("Build %d (cached)", buildNumber),
 			TotalSteps:     7,
 			CompletedSteps: 2,
 		})
 
-		if err := copyDir(cachedPath, binaryPath); err != nil {
-			return nil, err
+		// Builds cached before checksums were recorded are used as they are
+		if err := inst.cache.VerifyBuild(buildNumber); errors.Is(err, cache.ErrChecksumMismatch) {
+			inst.reportProgress(onProgress, InstallProgress{
+				Step:           "Cached build is corrupt, downloading it again",
+				Progress:       0.32,
+				TotalSteps:     7,
+				CompletedSteps: 2,
+			})
+			if err := inst.cache.Remove(buildNumber); err != nil {
+				return nil, fmt.Errorf("failed to evict corrupt build %d: %w", buildNumber, err)
+			}
 		}
-		return targetBuild, nil
 	}
 
-	// Download
-	downloadURL := inst.artifactClient.GetDownloadURL(*targetBuild)
-	tmpDir := filepath.Join(os.TempDir(), "inkwash-download")
-	os.MkdirAll(tmpDir, 0755)
-	defer os.RemoveAll(tmpDir)
-
-	archivePath := filepath.Join(tmpDir, "server"+download.GetPlatformArchiveExtension())
+	// Files are assembled next to bin/ and swapped in once complete, so an
+	// interrupted install never leaves a half-copied bin/ behind
+	stageDir, err := staging.Dir(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
 
-	err = inst.downloader.Download(downloadURL, archivePath, func(p download.Progress) {
-		downloadProgress := float64(p.DownloadedBytes) / float64(p.TotalBytes) * 0.15
-		inst.reportProgress(onProgress, InstallProgress{
-			Step:           "Downloading FXServer",
-			Progress:       0.30 + downloadProgress,
-			DownloadSpeed:  p.Speed,
-			DownloadETA:    p.ETA,
-			CurrentFile:    fmt.Sprintf("Build %d", buildNumber),
-			TotalSteps:     7,
-			CompletedSteps: 3,
-		})
-	})
+	// Check cache after getting build info
+	if useCache && !opts.ForceRedownload {
+		if cachedPath, err := inst.cache.Get(buildNumber); err == nil {
+			// Copy from cache
+			inst.reportProgress(onProgress, InstallProgress{
+				Step:           "Copying from cache",
+				Progress:       0.35,
+				CurrentFile:    fmt.Sprintf("Build %d (cached)", buildNumber),
+				TotalSteps:     7,
+				CompletedSteps: 2,
+			})
+
+			if err := copyDir(cachedPath, stageDir); err != nil {
+				return nil, err
+			}
+			if err := swapBinary(stageDir, binaryPath, targetOS, opts.LaunchCommand == "", opts.keepBinary); err != nil {
+				return nil, err
+			}
+			return targetBuild, nil
+		}
+	}
 
+	// Download. What was fetched is kept if the install fails, so running it
+	// again resumes instead of starting a 1GB+ download over.
+	archivePath, err := downloadPath(downloadURL, archiveExt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download: %w", err)
+		return nil, err
 	}
 
+	if err := inst.downloadVerified(ctx, opts, downloadURL, archivePath, buildLabel, onProgress); err != nil {
+		return nil, err
+	}
+	defer os.Remove(archivePath)
+
 	// Extract
 	inst.reportProgress(onProgress, InstallProgress{
 		Step:           "Extracting archive",
@@ -316,123 +579,282 @@ func (inst *Installer) installBinary(buildNumber int, binaryPath string, onProgr
 		CompletedSteps: 3,
 	})
 
-	extractPath := filepath.Join(tmpDir, "extracted")
+	// The files end up under the staging folder, so check it before extracting anything
+	if err := inst.extractor.CheckDestination(archivePath, stageDir); err != nil {
+		return nil, err
+	}
+
+	extractDir, err := os.MkdirTemp("", "inkwash-extract")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	defer os.RemoveAll(extractDir)
+
+	extractPath := filepath.Join(extractDir, "extracted")
 	if err := inst.extractor.Extract(archivePath, extractPath); err != nil {
 		return nil, fmt.Errorf("failed to extract: %w", err)
 	}
 
+	// Extraction can't be interrupted, so check before copying its output
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Find the actual binary directory (may be nested like alpine/)
 	sourcePath := findBinaryDir(extractPath)
 
 	// Copy to destination
-	if err := copyDirSkipBrokenSymlinks(sourcePath, binaryPath); err != nil {
+	if err := copyDirSkipBrokenSymlinks(sourcePath, stageDir); err != nil {
 		return nil, fmt.Errorf("failed to copy files: %w", err)
 	}
+	if err := swapBinary(stageDir, binaryPath, targetOS, opts.LaunchCommand == "", opts.keepBinary); err != nil {
+		return nil, err
+	}
 
 	// Add to cache
-	inst.cache.Add(*targetBuild, archivePath, extractPath)
+	if useCache {
+		if opts.ForceRedownload && inst.cache.Has(buildNumber) {
+			// The suspect copy is only dropped once a fresh one is in hand
+			if err := inst.cache.Remove(buildNumber); err != nil {
+				return nil, fmt.Errorf("failed to replace cached build %d: %w", buildNumber, err)
+			}
+		}
+
+		if err := inst.cache.Add(*targetBuild, archivePath, extractPath); err == nil && opts.ForceRedownload {
+			// The server already has its files, so a bad cache write only costs the cache entry
+			if err := inst.cache.VerifyBuild(buildNumber); err != nil {
+				inst.cache.Remove(buildNumber)
+			}
+		}
+	}
 
 	return targetBuild, nil
 }
 
-// cloneServerData clones the cfx-server-data repository or downloads it as ZIP if git is unavailable
-func (inst *Installer) cloneServerData(serverPath string) error {
-	// Clone to temporary directory
-	tmpDir := filepath.Join(os.TempDir(), "inkwash-server-data")
-	os.RemoveAll(tmpDir) // Clean up any previous clone
-	defer os.RemoveAll(tmpDir)
-
-	// Check if git is available and try to clone
-	if inst.isGitAvailable() {
-		// Clone using git (suppress progress output for clean TUI)
-		cmd := exec.Command("git", "clone", "--quiet", "--depth", "1", "https://github.com/citizenfx/cfx-server-data.git", tmpDir)
-		// Suppress output to avoid breaking TUI
-		cmd.Stdout = nil
-		cmd.Stderr = nil
-
-		if err := cmd.Run(); err == nil {
-			// Git clone succeeded, copy resources
-			srcResources := filepath.Join(tmpDir, "resources")
-			dstResources := filepath.Join(serverPath, "resources")
-
-			if err := copyDir(srcResources, dstResources); err != nil {
-				return fmt.Errorf("failed to copy resources: %w", err)
+// downloadVerified downloads the FXServer archive. With opts.Verify or
+// opts.SHA256, the archive is checked afterwards and downloaded again (up to
+// downloadAttempts times) if it's incomplete or damaged.
+func (inst *Installer) downloadVerified(ctx context.Context, opts InstallOptions, url, archivePath, label string, onProgress ProgressCallback) error {
+	verify := opts.Verify || opts.SHA256 != ""
+
+	for attempt := 1; ; attempt++ {
+		step := "Downloading FXServer"
+		if attempt > 1 {
+			step = fmt.Sprintf("Downloading FXServer (attempt %d of %d)", attempt, downloadAttempts)
+		}
+
+		err := inst.downloader.DownloadContext(ctx, url, archivePath, func(p download.Progress) {
+			downloadProgress := float64(p.DownloadedBytes) / float64(p.TotalBytes) * 0.15
+			label := step
+			if attempt == 1 && p.Resumed > 0 {
+				label = "Resuming FXServer download"
+			}
+			if p.Retries > 0 {
+				label += fmt.Sprintf(" (retried %d time(s))", p.Retries)
+			}
+			inst.reportProgress(onProgress, InstallProgress{
+				Step:           label,
+				Progress:       0.30 + downloadProgress,
+				DownloadSpeed:  p.Speed,
+				DownloadETA:    p.ETA,
+				CurrentFile:    label,
+				TotalSteps:     7,
+				CompletedSteps: 3,
+			})
+		})
+
+		if err == nil && verify {
+			inst.reportProgress(onProgress, InstallProgress{
+				Step:           "Verifying download",
+				Progress:       0.45,
+				CurrentFile:    label,
+				TotalSteps:     7,
+				CompletedSteps: 3,
+			})
+			if opts.SHA256 != "" {
+				err = download.VerifySHA256(archivePath, opts.SHA256)
 			}
+			if err == nil {
+				err = download.VerifyArchive(archivePath)
+			}
+		}
+		if err == nil {
 			return nil
 		}
-		// Git clone failed, fall through to ZIP download
+
+		// Only a damaged download is worth fetching again
+		retry := verify && attempt < downloadAttempts && ctx.Err() == nil &&
+			(errors.Is(err, download.ErrCorrupt) || errors.Is(err, download.ErrIncomplete))
+		if !retry {
+			if errors.Is(err, download.ErrCorrupt) {
+				// Complete but damaged, so there's nothing to resume
+				os.Remove(archivePath)
+			}
+			return fmt.Errorf("failed to download: %w", err)
+		}
+		os.Remove(archivePath)
 	}
+}
+
+// staleDownloadAge is how long a partial download of another build is kept
+// for resuming. Downloads in progress keep their files fresh, so concurrent
+// installs of other builds aren't disturbed.
+const staleDownloadAge = 7 * 24 * time.Hour
+
+// downloadPath returns where the archive at url is downloaded to. The name
+// follows the URL, so a later install of the same build finds and resumes
+// it; partial downloads of other builds untouched for staleDownloadAge are
+// removed.
+func downloadPath(url, ext string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "inkwash-download")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	name := "fxserver-" + hex.EncodeToString(sum[:6])
 
-	// Git not available or clone failed - download as ZIP from GitHub
-	return inst.downloadServerDataZip(serverPath, tmpDir)
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), name) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > staleDownloadAge {
+			os.RemoveAll(filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	return filepath.Join(dir, name+ext), nil
 }
 
-// isGitAvailable checks if git is installed and accessible
-func (inst *Installer) isGitAvailable() bool {
-	cmd := exec.Command("git", "--version")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+// swapBinary checks that a staged FXServer build is complete and moves it
+// into binaryPath. Unless the server has its own launch command, the build
+// must contain an entrypoint inkwash knows how to start. The replaced
+// binaries are moved to keep, or deleted if keep is "".
+func swapBinary(stageDir, binaryPath, targetOS string, needEntrypoint bool, keep string) error {
+	if err := staging.CheckTree(stageDir); err != nil {
+		return fmt.Errorf("FXServer build is incomplete: %w", err)
+	}
+	if needEntrypoint {
+		if _, err := DetectEntrypoint(stageDir, targetOS); err != nil {
+			return fmt.Errorf("FXServer build is incomplete: %w", err)
+		}
+	}
+	return staging.SwapKeep(stageDir, binaryPath, keep)
 }
 
-// downloadServerDataZip downloads cfx-server-data as a ZIP archive from GitHub
-func (inst *Installer) downloadServerDataZip(serverPath, tmpDir string) error {
-	// GitHub provides ZIP archives at this URL pattern
-	zipURL := "https://github.com/citizenfx/cfx-server-data/archive/refs/heads/master.zip"
-	zipPath := filepath.Join(tmpDir, "server-data.zip")
+// runRecipe runs a txAdmin recipe in the new server's folder, reporting each
+// task as part of step 4. Tasks it can't run, like database imports, and
+// placeholders left in server.cfg are reported as steps of their own.
+func (inst *Installer) runRecipe(ctx context.Context, r *recipe.Recipe, server *types.Server, licenseKey string, totalSteps int, onProgress ProgressCallback) error {
+	inst.reportProgress(onProgress, InstallProgress{
+		Step:           fmt.Sprintf("Running recipe %s", r.Name),
+		Progress:       0.57,
+		TotalSteps:     totalSteps,
+		CompletedSteps: 4,
+	})
 
-	// Ensure temp directory exists
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+	endpoint := server.Endpoint()
+	vars := map[string]string{
+		"serverName":      server.Name,
+		"svLicense":       licenseKey,
+		"maxClients":      "32",
+		"serverEndpoints": fmt.Sprintf("endpoint_add_tcp \"%s\"\nendpoint_add_udp \"%s\"", endpoint, endpoint),
 	}
 
-	// Download the ZIP file
-	if err := inst.downloader.Download(zipURL, zipPath, nil); err != nil {
-		// If download fails, fall back to basic structure
-		return inst.createBasicStructure(serverPath)
+	notes, err := r.Run(ctx, server.Path, vars, func(index int, task *recipe.Task) {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           fmt.Sprintf("Running recipe %s (task %d of %d)", r.Name, index+1, len(r.Tasks)),
+			Progress:       0.57 + 0.05*float64(index)/float64(len(r.Tasks)),
+			CurrentFile:    task.Describe(),
+			TotalSteps:     totalSteps,
+			CompletedSteps: 4,
+		})
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
 	}
 
-	// Extract the ZIP file
-	extractPath := filepath.Join(tmpDir, "extracted")
-	if err := inst.extractor.Extract(zipPath, extractPath); err != nil {
-		return inst.createBasicStructure(serverPath)
+	for _, name := range recipe.Placeholders(filepath.Join(server.Path, "server.cfg")) {
+		notes = append(notes, fmt.Sprintf("server.cfg still has {{%s}}; fill it in by hand", name))
 	}
+	for _, note := range notes {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           "Recipe: " + note,
+			Progress:       0.62,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 4,
+			Warning:        true,
+		})
+	}
+	return nil
+}
 
-	// GitHub ZIP archives extract to a folder named "{repo}-{branch}"
-	// e.g., "cfx-server-data-master"
-	srcResources := filepath.Join(extractPath, "cfx-server-data-master", "resources")
+// setupDatabase creates the server's database and writes its connection
+// string to server.cfg. The server is usable without it, so a failure is
+// reported as a step rather than failing the install.
+func (inst *Installer) setupDatabase(ctx context.Context, opts database.Options, server *types.Server, totalSteps int, onProgress ProgressCallback) {
+	opts = opts.ForServer(server.Name)
 
-	// Check if the expected path exists, if not try to find it
-	if _, err := os.Stat(srcResources); os.IsNotExist(err) {
-		// Try to find the resources directory
-		entries, readErr := os.ReadDir(extractPath)
-		if readErr != nil {
-			return inst.createBasicStructure(serverPath)
-		}
+	report := func(step string) {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           step,
+			Progress:       0.94,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 7,
+		})
+	}
 
-		// Look for a directory that contains "resources"
-		for _, entry := range entries {
-			if entry.IsDir() {
-				possibleResources := filepath.Join(extractPath, entry.Name(), "resources")
-				if _, statErr := os.Stat(possibleResources); statErr == nil {
-					srcResources = possibleResources
-					break
-				}
-			}
-		}
+	result, err := database.Setup(ctx, opts, report)
+	if err == nil {
+		err = database.WriteConnectionString(filepath.Join(server.Path, "server.cfg"), result.ConnectionString)
 	}
+	if err != nil {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           fmt.Sprintf("Database setup failed: %v; retry with 'inkwash db setup %s'", err, server.Name),
+			Progress:       0.94,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 7,
+			Warning:        true,
+		})
+	}
+}
 
-	// If we still can't find resources, create basic structure
-	if _, err := os.Stat(srcResources); os.IsNotExist(err) {
-		return inst.createBasicStructure(serverPath)
+// applyTemplate applies the server's template and sets up its database. As
+// with setupDatabase, the server is usable without them, so failures are
+// reported as steps.
+func (inst *Installer) applyTemplate(ctx context.Context, opts InstallOptions, server *types.Server, totalSteps int, onProgress ProgressCallback) {
+	report := func(step string, warning bool) {
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           step,
+			Progress:       0.94,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 7,
+			Warning:        warning,
+		})
 	}
 
-	dstResources := filepath.Join(serverPath, "resources")
-	if err := copyDir(srcResources, dstResources); err != nil {
-		return fmt.Errorf("failed to copy resources: %w", err)
+	report(fmt.Sprintf("Applying template '%s'", opts.Template.Name), false)
+	sqlFiles, err := opts.ApplyTemplate(ctx, server, opts.Template, opts.Database, func(step string) {
+		report(step, false)
+	})
+	switch {
+	case err != nil:
+		report(fmt.Sprintf("Template '%s' failed: %v", opts.Template.Name, err), true)
+	case len(sqlFiles) > 0:
+		report(fmt.Sprintf("Template '%s' needs a database: %s", opts.Template.Name, database.SetupCommand(server.Name, sqlFiles)), true)
 	}
+}
 
-	return nil
+// isGitAvailable checks if git is installed and accessible
+func (inst *Installer) isGitAvailable() bool {
+	cmd := exec.Command("git", "--version")
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	return cmd.Run() == nil
 }
 
 // createBasicStructure creates a basic server structure without git
@@ -456,8 +878,8 @@ func (inst *Installer) createBasicStructure(serverPath string) error {
 // validateInputs validates installation inputs
 func (inst *Installer) validateInputs(serverName, installPath string) error {
 	// Check if server name is valid
-	if serverName == "" {
-		return fmt.Errorf("server name cannot be empty")
+	if err := validation.ValidateServerName(serverName); err != nil {
+		return err
 	}
 
 	// Check if server already exists
@@ -466,17 +888,32 @@ func (inst *Installer) validateInputs(serverName, installPath string) error {
 	}
 
 	// Check if install path is writable
-	testFile := filepath.Join(installPath, ".inkwash-test")
-	if err := os.MkdirAll(installPath, 0755); err != nil {
-		return fmt.Errorf("cannot create install directory: %w", err)
+	if err := validation.ValidatePathWritable(installPath); err != nil {
+		return fmt.Errorf("invalid install path: %w", err)
 	}
 
-	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
-		return fmt.Errorf("install path not writable: %w", err)
+	return nil
+}
+
+// checkExistingServer refuses to install into a folder that already holds a
+// server. With force, an unregistered server's folder is reused (reported by
+// the bool); a folder registered to another server is never reused.
+func (inst *Installer) checkExistingServer(serverPath string, force bool) (bool, error) {
+	for _, existing := range inst.registry.List() {
+		if filepath.Clean(existing.Path) == filepath.Clean(serverPath) {
+			return false, &ExistingServerError{Path: serverPath, Registered: existing.Name}
+		}
 	}
-	os.Remove(testFile)
 
-	return nil
+	markers := DetectServer(serverPath)
+	if len(markers) == 0 {
+		return false, nil
+	}
+	if !force {
+		return false, &ExistingServerError{Path: serverPath, Markers: markers}
+	}
+
+	return true, nil
 }
 
 // createDirectories creates the directory structure
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/VexoaXYZ/inkwash/internal/lockfile"
+)
+
+// LockFileName is the file held in a server directory while it's being modified
+const LockFileName = ".inkwash.lock"
+
+// ErrServerBusy is returned when another inkwash command is modifying a server
+var ErrServerBusy = errors.New("server is busy")
+
+// LockServer takes a server's lock for a destructive operation (install,
+// migrate, resource changes). Locks left by dead processes are reclaimed.
+// Release the returned lock when the operation finishes.
+func LockServer(serverPath, name string) (*lockfile.Lock, error) {
+	lock, err := lockfile.TryAcquire(filepath.Join(serverPath, LockFileName))
+	if err == nil {
+		return lock, nil
+	}
+
+	var locked *lockfile.LockedError
+	if errors.As(err, &locked) {
+		if locked.PID > 0 {
+			return nil, fmt.Errorf("%w: '%s' is being modified by another inkwash command (PID %d), try again when it finishes", ErrServerBusy, name, locked.PID)
+		}
+		return nil, fmt.Errorf("%w: '%s' is being modified by another inkwash command, try again when it finishes", ErrServerBusy, name)
+	}
+
+	return nil, err
+}
+
+// IsLocked reports whether a destructive operation is in progress on a server
+func IsLocked(serverPath string) bool {
+	return lockfile.Owner(filepath.Join(serverPath, LockFileName)) != 0
+}
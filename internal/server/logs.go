@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// logFileName is the log FXServer output is written to; rotated copies are
+// named server.log.1 (newest) to server.log.N, optionally gzipped
+const logFileName = "server.log"
+
+// maxLogLineSize is the longest log line read; longer lines are cut off
+const maxLogLineSize = 1024 * 1024
+
+// LogPath returns the path of a server's current log
+func LogPath(serverPath string) string {
+	return filepath.Join(serverPath, "logs", logFileName)
+}
+
+// LogSegments returns a server's log files, oldest first: rotated segments
+// (server.log.N or server.log.N.gz, higher N is older) followed by the current log
+func LogSegments(serverPath string) ([]string, error) {
+	logsDir := filepath.Dir(LogPath(serverPath))
+	entries, err := os.ReadDir(logsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	type segment struct {
+		path string
+		n    int
+	}
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, logFileName) {
+			continue
+		}
+
+		if name == logFileName {
+			segments = append(segments, segment{path: filepath.Join(logsDir, name)})
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, logFileName+"."), ".gz"))
+		if err != nil || n < 1 {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(logsDir, name), n: n})
+	}
+
+	// Oldest first; the current log (n == 0) ends up last
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].n > segments[j].n
+	})
+
+	paths := make([]string, len(segments))
+	for i, s := range segments {
+		paths[i] = s.path
+	}
+	return paths, nil
+}
+
+// TailLog returns the last n lines of a server's log. Rotated segments are
+// read (and gunzipped) only when the current log is shorter than n lines.
+func TailLog(serverPath string, n int) ([]string, error) {
+	return TailLogMatching(serverPath, n, nil)
+}
+
+// TailLogMatching returns the last n lines of a server's log for which keep
+// returns true (every line if keep is nil), reading back through rotated
+// segments until it has n of them
+func TailLogMatching(serverPath string, n int, keep func(line string) bool) ([]string, error) {
+	segments, err := LogSegments(serverPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, &os.PathError{Op: "open", Path: LogPath(serverPath), Err: os.ErrNotExist}
+	}
+
+	var lines []string
+	for i := len(segments) - 1; i >= 0 && len(lines) < n; i-- {
+		segmentLines, err := readLogTail(segments[i], n-len(lines), keep)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(segmentLines, lines...)
+	}
+	return lines, nil
+}
+
+// readLogTail returns the last n lines of one log segment that keep accepts
+func readLogTail(path string, n int, keep func(line string) bool) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		if keep != nil && !keep(scanner.Text()) {
+			continue
+		}
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return lines, nil
+}
+
+// LogFollower reads the lines appended to a server's log, like tail -f. The
+// log is reopened on every poll rather than held open, so it can still be
+// renamed by a log rotator on Windows.
+type LogFollower struct {
+	path    string
+	offset  int64
+	file    os.FileInfo // The log last read from, to notice when it's replaced
+	partial string      // Start of a line that hasn't been finished yet
+}
+
+// NewLogFollower returns a follower that starts at the current end of the log
+func NewLogFollower(serverPath string) *LogFollower {
+	f := &LogFollower{path: LogPath(serverPath)}
+
+	// Stat through a handle: on Windows, a FileInfo from os.Stat only looks up
+	// the file's identity when it's compared, by which time the path may be a new log
+	file, err := os.Open(f.path)
+	if err != nil {
+		return f
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil {
+		f.offset, f.file = info.Size(), info
+	}
+	return f
+}
+
+// Poll returns the complete lines written since the last call. A log that
+// doesn't exist yet isn't an error. When the log was rotated, the rest of the
+// old one is read from server.log.1 before the new one is read from the
+// start; one that shrank in place was truncated and is also read from the start.
+func (f *LogFollower) Poll() ([]string, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+
+	var lines []string
+	if f.file != nil && !os.SameFile(f.file, info) {
+		lines = f.finishRotated()
+		f.offset, f.partial = 0, ""
+	} else if info.Size() < f.offset {
+		f.offset, f.partial = 0, ""
+	}
+	f.file = info
+
+	newLines, err := f.readFrom(file, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	return append(lines, newLines...), nil
+}
+
+// finishRotated returns the lines written to the previous log after the last
+// poll, if it's still around uncompressed as server.log.1
+func (f *LogFollower) finishRotated() []string {
+	rotated, err := os.Open(f.path + ".1")
+	if err != nil {
+		return nil
+	}
+	defer rotated.Close()
+
+	info, err := rotated.Stat()
+	if err != nil || !os.SameFile(f.file, info) || info.Size() < f.offset {
+		return nil
+	}
+
+	lines, err := f.readFrom(rotated, info.Size())
+	if err != nil {
+		return nil
+	}
+	// The old log won't be written to again, so its last line is complete
+	if f.partial != "" {
+		lines = append(lines, strings.TrimSuffix(f.partial, "\r"))
+	}
+	return lines
+}
+
+// readFrom reads the complete lines between the follower's offset and size
+func (f *LogFollower) readFrom(file *os.File, size int64) ([]string, error) {
+	if size == f.offset {
+		return nil, nil
+	}
+
+	data := make([]byte, size-f.offset)
+	read, err := file.ReadAt(data, f.offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+	f.offset += int64(read)
+
+	text := f.partial + string(data[:read])
+	lines := strings.Split(text, "\n")
+	f.partial = lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
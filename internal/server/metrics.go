@@ -1,18 +1,29 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
+	"github.com/VexoaXYZ/inkwash/internal/query"
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
+// cpuPrimeInterval is the short sample taken when a server is first tracked.
+// CPU usage is measured as the change in process CPU time between two readings,
+// so a reading is only meaningful once a previous one exists; priming gives the
+// first reported value a real baseline instead of 0% or a since-start average.
+const cpuPrimeInterval = 200 * time.Millisecond
+
 // MetricsCollector collects server metrics in background
 type MetricsCollector struct {
 	servers  map[string]*types.ServerMetrics
+	procs    map[string]*process.Process // Kept across ticks so CPU is measured between samples
+	addrs    map[string]string           // Where each server answers player queries
 	interval time.Duration
+	timeout  time.Duration // Upper bound for a single collection pass
 	stopChan chan struct{}
 	mu       sync.RWMutex
 	pm       *ProcessManager
@@ -26,7 +37,10 @@ func NewMetricsCollector(interval time.Duration) *MetricsCollector {
 
 	return &MetricsCollector{
 		servers:  make(map[string]*types.ServerMetrics),
+		procs:    make(map[string]*process.Process),
+		addrs:    make(map[string]string),
 		interval: interval,
+		timeout:  interval,
 		stopChan: make(chan struct{}),
 		pm:       NewProcessManager(),
 	}
@@ -42,14 +56,65 @@ func (mc *MetricsCollector) Stop() {
 	close(mc.stopChan)
 }
 
-// Track adds a server to track
+// Track adds a server to track. A paused server (or one that restarted with a
+// new PID) resumes with its earlier samples intact.
 func (mc *MetricsCollector) Track(server *types.Server) {
+	if !server.IsRunning() {
+		return
+	}
+
+	// Prime the CPU baseline outside the lock (it sleeps for cpuPrimeInterval)
+	var primedCPU float64
+	primed := false
+	proc, err := process.NewProcess(int32(server.PID))
+	if err == nil {
+		if cpu, err := proc.Percent(cpuPrimeInterval); err == nil {
+			primedCPU, primed = cpu, true
+		}
+	}
+
+	// Reads server.cfg, so also outside the lock
+	address := QueryAddress(server)
+
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
-	if server.IsRunning() {
-		mc.servers[server.Name] = types.NewServerMetrics(server.PID)
+	mc.addrs[server.Name] = address
+
+	metrics, ok := mc.servers[server.Name]
+	if ok {
+		metrics.Resume(server.PID)
+	} else {
+		metrics = types.NewServerMetrics(server.PID)
+		mc.servers[server.Name] = metrics
+	}
+	if primed {
+		metrics.AddCPUSample(primedCPU)
+	}
+
+	if proc != nil {
+		mc.procs[server.Name] = proc
+	} else {
+		delete(mc.procs, server.Name)
+	}
+}
+
+// Pause stops sampling a server without forgetting it. Its last samples stay
+// available until Track resumes it.
+func (mc *MetricsCollector) Pause(serverName string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.pauseLocked(serverName)
+}
+
+// pauseLocked marks a server as stopped (caller must hold the lock)
+func (mc *MetricsCollector) pauseLocked(serverName string) {
+	if metrics, ok := mc.servers[serverName]; ok {
+		metrics.Paused = true
 	}
+	// The handle belongs to the old process
+	delete(mc.procs, serverName)
 }
 
 // Untrack removes a server from tracking
@@ -58,6 +123,8 @@ func (mc *MetricsCollector) Untrack(serverName string) {
 	defer mc.mu.Unlock()
 
 	delete(mc.servers, serverName)
+	delete(mc.procs, serverName)
+	delete(mc.addrs, serverName)
 }
 
 // Get returns metrics for a server
@@ -68,6 +135,23 @@ func (mc *MetricsCollector) Get(serverName string) *types.ServerMetrics {
 	return mc.servers[serverName]
 }
 
+// Snapshot returns a copy of a server's metrics that is safe to read while
+// collection continues in the background
+func (mc *MetricsCollector) Snapshot(serverName string) (types.ServerMetrics, bool) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	metrics, ok := mc.servers[serverName]
+	if !ok {
+		return types.ServerMetrics{}, false
+	}
+
+	snapshot := *metrics
+	snapshot.RAM = append([]float64(nil), metrics.RAM...)
+	snapshot.CPU = append([]float64(nil), metrics.CPU...)
+	return snapshot, true
+}
+
 // GetAll returns all tracked metrics
 func (mc *MetricsCollector) GetAll() map[string]*types.ServerMetrics {
 	mc.mu.RLock()
@@ -97,66 +181,201 @@ func (mc *MetricsCollector) collectLoop() {
 	}
 }
 
-// collect collects metrics for all tracked servers
+// processSample holds the raw readings for one server from a collection pass
+type processSample struct {
+	name       string
+	pid        int
+	proc       *process.Process
+	err        error
+	cpu        float64
+	cpuOK      bool
+	ramGB      float64
+	ramOK      bool
+	readBytes  uint64
+	writeBytes uint64
+	ioOK       bool
+	at         time.Time // When the I/O counters were read
+	denied     bool      // A reading failed because we aren't allowed to read the process
+	players    int
+	maxPlayers int // 0 if info.json wasn't queried or didn't say
+	playersOK  bool
+}
+
+// collect collects metrics for all tracked servers.
+// Processes are sampled concurrently outside the lock so a slow or stuck
+// process can't block Get/GetAll or delay the other servers.
 func (mc *MetricsCollector) collect() {
+	// Snapshot what to sample under a brief read lock
+	type target struct {
+		pid      int
+		proc     *process.Process
+		address  string
+		needInfo bool // The player limit isn't known yet
+	}
+
+	mc.mu.RLock()
+	targets := make(map[string]target, len(mc.servers))
+	for name, metrics := range mc.servers {
+		if metrics.Paused {
+			continue
+		}
+		targets[name] = target{pid: metrics.PID, proc: mc.procs[name], address: mc.addrs[name], needInfo: metrics.MaxPlayers == 0}
+	}
+	mc.mu.RUnlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mc.timeout)
+	defer cancel()
+
+	// Buffered so late samplers never block after a timeout
+	results := make(chan processSample, len(targets))
+	for name, t := range targets {
+		go func(name string, t target) {
+			if !mc.pm.IsRunning(&types.Server{PID: t.pid}) {
+				results <- processSample{name: name, pid: t.pid, err: fmt.Errorf("process %d is no longer running", t.pid)}
+				return
+			}
+			sample := sampleProcess(ctx, name, t.pid, t.proc)
+			if sample.err == nil && t.address != "" {
+				queryPlayers(ctx, &sample, t.address, t.needInfo)
+			}
+			results <- sample
+		}(name, t)
+	}
+
+	var samples []processSample
+	for range targets {
+		select {
+		case sample := <-results:
+			samples = append(samples, sample)
+		case <-ctx.Done():
+			// Servers that didn't answer in time are retried next tick
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Merge results under the write lock
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
-	for name, metrics := range mc.servers {
-		if err := mc.collectOne(metrics); err != nil {
-			// If collection fails, the process may have stopped
-			// Remove from tracking
-			delete(mc.servers, name)
+	for _, sample := range samples {
+		metrics, ok := mc.servers[sample.name]
+		if !ok || metrics.PID != sample.pid {
+			// Untracked or restarted while sampling
+			continue
+		}
+
+		if sample.err != nil {
+			// The process is gone (permission failures don't set err).
+			// Keep the entry so a restart picks up where it left off.
+			mc.pauseLocked(sample.name)
+			continue
+		}
+
+		if _, ok := mc.procs[sample.name]; !ok && sample.proc != nil {
+			mc.procs[sample.name] = sample.proc
 		}
+
+		applySample(metrics, sample)
 	}
 }
 
-// collectOne collects metrics for a single server
-func (mc *MetricsCollector) collectOne(metrics *types.ServerMetrics) error {
-	proc, err := process.NewProcess(int32(metrics.PID))
-	if err != nil {
-		return fmt.Errorf("process not found: %w", err)
+// sampleProcess reads CPU, memory and I/O counters for a single process.
+// proc is the handle from previous ticks (nil if there isn't one yet).
+func sampleProcess(ctx context.Context, name string, pid int, proc *process.Process) processSample {
+	sample := processSample{name: name, pid: pid}
+
+	if proc == nil {
+		p, err := process.NewProcessWithContext(ctx, int32(pid))
+		if err != nil {
+			if isPermissionError(err) {
+				// Running as far as we can tell, just not readable: keep tracking it
+				sample.denied = true
+				return sample
+			}
+			sample.err = fmt.Errorf("process not found: %w", err)
+			return sample
+		}
+		proc = p
+	} else if running, err := proc.IsRunningWithContext(ctx); err == nil && !running {
+		sample.err = fmt.Errorf("process %d is no longer running", pid)
+		return sample
 	}
+	sample.proc = proc
 
-	// Collect CPU percentage
-	cpu, err := proc.CPUPercent()
-	if err == nil {
-		metrics.AddCPUSample(cpu)
+	// Collect CPU percentage relative to the previous reading on this handle
+	// (the first reading without a primed baseline reports 0 and sets it)
+	if cpu, err := proc.PercentWithContext(ctx, 0); err == nil {
+		sample.cpu, sample.cpuOK = cpu, true
+	} else {
+		sample.denied = sample.denied || isPermissionError(err)
 	}
 
 	// Collect memory usage
-	memInfo, err := proc.MemoryInfo()
-	if err == nil {
-		ramGB := float64(memInfo.RSS) / 1024 / 1024 / 1024
-		metrics.AddRAMSample(ramGB)
+	if memInfo, err := proc.MemoryInfoWithContext(ctx); err == nil {
+		sample.ramGB, sample.ramOK = float64(memInfo.RSS)/1024/1024/1024, true
+	} else {
+		sample.denied = sample.denied || isPermissionError(err)
 	}
 
 	// Collect network I/O
-	ioCounters, err := proc.IOCounters()
-	if err == nil {
-		// Calculate delta from last measurement
-		if metrics.LastUpdate.IsZero() {
-			metrics.NetworkTX = 0
-			metrics.NetworkRX = 0
-		} else {
-			elapsed := time.Since(metrics.LastUpdate).Seconds()
-			if elapsed > 0 {
-				txDelta := ioCounters.WriteBytes
-				rxDelta := ioCounters.ReadBytes
-
-				metrics.NetworkTX = uint64(float64(txDelta) / elapsed)
-				metrics.NetworkRX = uint64(float64(rxDelta) / elapsed)
-			}
+	if ioCounters, err := proc.IOCountersWithContext(ctx); err == nil {
+		sample.readBytes, sample.writeBytes, sample.ioOK = ioCounters.ReadBytes, ioCounters.WriteBytes, true
+		sample.at = time.Now()
+	} else {
+		sample.denied = sample.denied || isPermissionError(err)
+	}
+
+	return sample
+}
+
+// queryPlayers asks the server how many players are connected and, if
+// needInfo is set, what its player limit is
+func queryPlayers(ctx context.Context, sample *processSample, address string, needInfo bool) {
+	players, err := query.Players(ctx, address)
+	if err != nil {
+		return
+	}
+	sample.players, sample.playersOK = len(players), true
+
+	if needInfo {
+		if info, err := query.GetInfo(ctx, address); err == nil {
+			sample.maxPlayers = info.MaxPlayers
 		}
 	}
+}
+
+// applySample records a sample on the server's metrics (caller must hold the lock)
+func applySample(metrics *types.ServerMetrics, sample processSample) {
+	metrics.StatsUnavailable = sample.denied
+
+	if sample.cpuOK {
+		metrics.AddCPUSample(sample.cpu)
+	}
 
-	// TODO: Get player count from server logs or query endpoint
-	// For now, set to 0
-	metrics.PlayerCount = 0
+	if sample.ramOK {
+		metrics.AddRAMSample(sample.ramGB)
+	}
 
-	metrics.LastUpdate = time.Now()
+	if sample.ioOK {
+		// Counters are cumulative; the rate comes from the delta since the last sample
+		metrics.UpdateNetworkIO(sample.readBytes, sample.writeBytes, sample.at)
+	}
 
-	return nil
+	metrics.PlayersKnown = sample.playersOK
+	if sample.playersOK {
+		metrics.PlayerCount = sample.players
+	}
+	if sample.maxPlayers > 0 {
+		metrics.MaxPlayers = sample.maxPlayers
+	}
+
+	metrics.LastUpdate = time.Now()
 }
 
 // UpdatePlayerCount manually updates player count for a server
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,6 +16,10 @@ import (
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 )
 
+// ErrStatsUnavailable is returned when a server is running but the OS won't let
+// us read its statistics (containers, hardened /proc, restricted Windows accounts)
+var ErrStatsUnavailable = errors.New("process statistics unavailable (permission denied)")
+
 // ProcessManager handles server process lifecycle
 type ProcessManager struct {
 	metadataManager *MetadataManager
@@ -32,26 +38,33 @@ func (pm *ProcessManager) Start(server *types.Server) error {
 		return fmt.Errorf("server '%s' is already running (PID: %d)", server.Name, server.PID)
 	}
 
-	// Create command
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		// Launch FXServer.exe directly instead of through run.cmd
-		// This allows proper process lifecycle tracking
-		exePath := filepath.Join(server.Path, "bin", "FXServer.exe")
-		if _, err := os.Stat(exePath); os.IsNotExist(err) {
-			return fmt.Errorf("FXServer.exe not found: %s", exePath)
-		}
-		cmd = exec.Command(exePath, "+exec", "server.cfg")
-	} else {
-		// On Linux, use the run.sh script
-		scriptPath := pm.getScriptPath(server)
-		if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-			return fmt.Errorf("launch script not found: %s", scriptPath)
-		}
-		cmd = exec.Command("bash", scriptPath)
+	if IsLocked(server.Path) {
+		return fmt.Errorf("%w: '%s' is being modified by another inkwash command", ErrServerBusy, server.Name)
+	}
+
+	if !server.CanRunOnHost() {
+		return fmt.Errorf("server '%s' was installed for %s and can't run on this machine", server.Name, server.GetTargetOS())
+	}
+
+	if err := CheckProfile(server.Path, server.Profile); err != nil {
+		return fmt.Errorf("can't start '%s': %w", server.Name, err)
 	}
 
+	// Launch FXServer directly instead of through run.cmd/run.sh in the
+	// server folder. This allows proper process lifecycle tracking.
+	argv, err := LaunchCommand(server)
+	if err != nil {
+		return err
+	}
+	name := argv[0]
+	if strings.ContainsRune(name, filepath.Separator) && !filepath.IsAbs(name) {
+		name = filepath.Join(server.Path, name)
+	}
+	cmd := exec.Command(name, argv[1:]...)
 	cmd.Dir = server.Path
+	if env := LaunchEnv(server); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	// Create logs directory
 	logsDir := filepath.Join(server.Path, "logs")
@@ -60,7 +73,7 @@ func (pm *ProcessManager) Start(server *types.Server) error {
 	}
 
 	// Redirect output to log file
-	logPath := filepath.Join(logsDir, "server.log")
+	logPath := LogPath(server.Path)
 	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create log file: %w", err)
@@ -75,6 +88,13 @@ func (pm *ProcessManager) Start(server *types.Server) error {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
+	// Reap the process when it exits, so a long-running caller like the
+	// dashboard isn't left with a zombie that still looks like it's running
+	go func() {
+		cmd.Wait()
+		logFile.Close()
+	}()
+
 	server.PID = cmd.Process.Pid
 	server.LastStarted = time.Now()
 
@@ -98,9 +118,13 @@ func (pm *ProcessManager) Stop(server *types.Server) error {
 
 	proc, err := process.NewProcess(int32(server.PID))
 	if err != nil {
-		// Process doesn't exist, update PID
-		server.PID = 0
-		return nil
+		if !isPermissionError(err) {
+			// Process doesn't exist, update PID
+			server.PID = 0
+			return nil
+		}
+		// We can't inspect the process, but may still be allowed to signal it
+		proc = &process.Process{Pid: int32(server.PID)}
 	}
 
 	// Graceful shutdown
@@ -136,8 +160,7 @@ func (pm *ProcessManager) Stop(server *types.Server) error {
 			return nil
 
 		case <-ticker.C:
-			exists, _ := process.PidExists(int32(server.PID))
-			if !exists {
+			if !pidRunning(server.PID) {
 				server.PID = 0
 				// Record stop in metadata
 				if err := pm.metadataManager.RecordStop(server.Path, startTime); err != nil {
@@ -155,12 +178,44 @@ func (pm *ProcessManager) IsRunning(server *types.Server) bool {
 		return false
 	}
 
-	exists, err := process.PidExists(int32(server.PID))
-	if err != nil || !exists {
+	return pidRunning(server.PID)
+}
+
+// pidRunning reports whether a process exists. If the OS refuses to say
+// (permission denied), the process is assumed to still be running so a server
+// we can't inspect isn't mistaken for a stopped one.
+func pidRunning(pid int) bool {
+	exists, err := process.PidExists(int32(pid))
+	if err != nil {
+		return isPermissionError(err)
+	}
+	return exists
+}
+
+// isPermissionError reports whether err means we lack the rights to read a
+// process, as opposed to the process not existing
+func isPermissionError(err error) bool {
+	if err == nil {
 		return false
 	}
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+		return true
+	}
 
-	return true
+	// gopsutil doesn't always wrap the underlying error
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "permission denied") ||
+		strings.Contains(msg, "operation not permitted") ||
+		strings.Contains(msg, "access is denied")
+}
+
+// statsError marks permission failures with ErrStatsUnavailable so callers
+// can tell "can't read it" apart from "not running"
+func statsError(err error) error {
+	if isPermissionError(err) {
+		return fmt.Errorf("%w: %v", ErrStatsUnavailable, err)
+	}
+	return err
 }
 
 // GetStatus returns detailed process status
@@ -171,6 +226,10 @@ func (pm *ProcessManager) GetStatus(server *types.Server) string {
 
 	proc, err := process.NewProcess(int32(server.PID))
 	if err != nil {
+		if isPermissionError(err) {
+			// Running (IsRunning said so) but we aren't allowed to look closer
+			return "Running"
+		}
 		return "Unknown"
 	}
 
@@ -201,14 +260,6 @@ func (pm *ProcessManager) Restart(server *types.Server) error {
 	return pm.Start(server)
 }
 
-// getScriptPath returns the launch script path for a server
-func (pm *ProcessManager) getScriptPath(server *types.Server) string {
-	if runtime.GOOS == "windows" {
-		return filepath.Join(server.Path, "run.cmd")
-	}
-	return filepath.Join(server.Path, "run.sh")
-}
-
 // GetMemoryUsage returns memory usage in bytes
 func (pm *ProcessManager) GetMemoryUsage(server *types.Server) (uint64, error) {
 	if !pm.IsRunning(server) {
@@ -217,18 +268,19 @@ func (pm *ProcessManager) GetMemoryUsage(server *types.Server) (uint64, error) {
 
 	proc, err := process.NewProcess(int32(server.PID))
 	if err != nil {
-		return 0, err
+		return 0, statsError(err)
 	}
 
 	memInfo, err := proc.MemoryInfo()
 	if err != nil {
-		return 0, err
+		return 0, statsError(err)
 	}
 
 	return memInfo.RSS, nil
 }
 
-// GetCPUPercent returns CPU usage percentage
+// GetCPUPercent returns CPU usage percentage, measured over a short
+// cpuPrimeInterval sample rather than averaged over the process lifetime
 func (pm *ProcessManager) GetCPUPercent(server *types.Server) (float64, error) {
 	if !pm.IsRunning(server) {
 		return 0, fmt.Errorf("server is not running")
@@ -236,12 +288,12 @@ func (pm *ProcessManager) GetCPUPercent(server *types.Server) (float64, error) {
 
 	proc, err := process.NewProcess(int32(server.PID))
 	if err != nil {
-		return 0, err
+		return 0, statsError(err)
 	}
 
-	cpuPercent, err := proc.CPUPercent()
+	cpuPercent, err := proc.Percent(cpuPrimeInterval)
 	if err != nil {
-		return 0, err
+		return 0, statsError(err)
 	}
 
 	return cpuPercent, nil
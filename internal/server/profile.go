@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+var (
+	// ErrProfileNotFound is returned when a server has no server.<profile>.cfg
+	ErrProfileNotFound = errors.New("profile not found")
+
+	// ErrProfileExists is returned when creating a profile whose file is already there
+	ErrProfileExists = errors.New("profile already exists")
+)
+
+// DefaultProfile names the server's own server.cfg where a profile is expected
+const DefaultProfile = "default"
+
+// profileNamePattern keeps profile names usable in file names on every platform
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ConvarOverride is a convar a profile sets differently from server.cfg
+type ConvarOverride struct {
+	Name  string
+	Value string
+}
+
+// ValidateProfileName checks a profile name can be used for a server.<profile>.cfg
+func ValidateProfileName(profile string) error {
+	if !profileNamePattern.MatchString(profile) {
+		return fmt.Errorf("invalid profile name '%s': use letters, digits, '-' and '_'", profile)
+	}
+	if strings.EqualFold(profile, DefaultProfile) {
+		return fmt.Errorf("'%s' is the server's own server.cfg", DefaultProfile)
+	}
+	return nil
+}
+
+// ProfileConfigName returns the file FXServer execs for a profile:
+// server.<profile>.cfg, or server.cfg for "" and DefaultProfile
+func ProfileConfigName(profile string) string {
+	if profile == "" || profile == DefaultProfile {
+		return "server.cfg"
+	}
+	return "server." + profile + ".cfg"
+}
+
+// ProfileConfigPath returns where a server's profile file is
+func ProfileConfigPath(serverPath, profile string) string {
+	return filepath.Join(serverPath, ProfileConfigName(profile))
+}
+
+// CheckProfile returns an error if a server has no file for profile
+func CheckProfile(serverPath, profile string) error {
+	if profile == "" || profile == DefaultProfile {
+		return nil
+	}
+	if err := ValidateProfileName(profile); err != nil {
+		return err
+	}
+	if !isFile(ProfileConfigPath(serverPath, profile)) {
+		return fmt.Errorf("%w: '%s' has no %s", ErrProfileNotFound, profile, ProfileConfigName(profile))
+	}
+	return nil
+}
+
+// ListProfiles returns the profiles in a server's folder, sorted by name
+func ListProfiles(serverPath string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(serverPath, "server.*.cfg"))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []string
+	for _, match := range matches {
+		profile := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "server."), ".cfg")
+		if ValidateProfileName(profile) == nil {
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// GenerateProfile writes server.<profile>.cfg for a server: it execs the
+// server's server.cfg, so endpoints, resources and later changes carry over,
+// and then sets the overridden convars. Known convars are checked first.
+func (cg *ConfigGenerator) GenerateProfile(server *types.Server, profile string, overrides []ConvarOverride) (string, error) {
+	if err := ValidateProfileName(profile); err != nil {
+		return "", err
+	}
+	path := ProfileConfigPath(server.Path, profile)
+	if isFile(path) {
+		return "", fmt.Errorf("%w: %s", ErrProfileExists, path)
+	}
+
+	cfg := &servercfg.Config{
+		Path: path,
+		Lines: []string{
+			fmt.Sprintf("# The %s profile of %s: server.cfg with the convars below changed.", profile, server.Name),
+			fmt.Sprintf("# Start with it: inkwash start %s --profile %s", server.Name, profile),
+			"exec server.cfg",
+			"",
+		},
+	}
+	for _, override := range overrides {
+		if err := cfg.Set(override.Name, override.Value); err != nil {
+			return "", err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", ProfileConfigName(profile), err)
+	}
+	return path, nil
+}
+
+// DeleteProfile removes a server's profile file
+func DeleteProfile(serverPath, profile string) error {
+	// Also keeps server.cfg itself from being deleted as the default profile
+	if err := ValidateProfileName(profile); err != nil {
+		return err
+	}
+	if err := CheckProfile(serverPath, profile); err != nil {
+		return err
+	}
+	if err := os.Remove(ProfileConfigPath(serverPath, profile)); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ProfileConfigName(profile), err)
+	}
+	return nil
+}
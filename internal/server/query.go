@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/VexoaXYZ/inkwash/internal/query"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// QueryAddress returns the host:port to reach a server's HTTP and RCON
+// endpoints on from this machine, preferring the endpoint in its server.cfg
+func QueryAddress(server *types.Server) string {
+	cfg, _ := servercfg.Load(filepath.Join(server.Path, "server.cfg"))
+	return localAddress(server, cfg)
+}
+
+// QueryPlayers returns the number of players on a running server and its
+// player limit (0 if the server doesn't report one)
+func QueryPlayers(ctx context.Context, server *types.Server) (int, int, error) {
+	address := QueryAddress(server)
+
+	players, err := query.Players(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	maxPlayers := 0
+	if info, err := query.GetInfo(ctx, address); err == nil {
+		maxPlayers = info.MaxPlayers
+	}
+	return len(players), maxPlayers, nil
+}
+
+// localAddress resolves the address a server listens on; cfg may be nil
+func localAddress(server *types.Server, cfg *servercfg.Config) string {
+	host, port := server.GetBindAddress(), server.Port
+	if cfg != nil {
+		if cfgHost, cfgPort, ok := cfg.Endpoint(); ok {
+			host, port = cfgHost, cfgPort
+		}
+	}
+
+	// A wildcard bind address is reachable on loopback
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+		if ip.To4() == nil {
+			host = "::1"
+		}
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/VexoaXYZ/inkwash/internal/rcon"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrNoRCONPassword is returned when a server's server.cfg doesn't set rcon_password
+var ErrNoRCONPassword = errors.New("rcon_password is not set in server.cfg")
+
+// DialRCON opens an RCON connection to a server, using the password and
+// endpoint from its server.cfg and profile
+func DialRCON(server *types.Server) (*rcon.Client, error) {
+	cfg, err := servercfg.Load(filepath.Join(server.Path, "server.cfg"))
+	if err != nil {
+		return nil, err
+	}
+
+	// A profile is exec'd after server.cfg, so a password it sets wins
+	password, ok := cfg.Convar("rcon_password")
+	if server.Profile != "" {
+		if profile, err := servercfg.Load(ProfileConfigPath(server.Path, server.Profile)); err == nil {
+			if value, set := profile.Convar("rcon_password"); set {
+				password, ok = value, true
+			}
+		}
+	}
+	if !ok || password == "" {
+		return nil, fmt.Errorf("%w; uncomment the rcon_password line in %s, set a password and restart the server",
+			ErrNoRCONPassword, filepath.Join(server.Path, "server.cfg"))
+	}
+
+	return rcon.Dial(localAddress(server, cfg), password)
+}
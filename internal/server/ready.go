@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/query"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+var (
+	// ErrStartupFailed is returned when a server exits or reports a fatal
+	// problem before it's ready; the error is a *StartupError
+	ErrStartupFailed = errors.New("server failed to start")
+
+	// ErrStartupTimeout is returned when a server is still running but hasn't
+	// become ready in time; it may yet
+	ErrStartupTimeout = errors.New("server didn't report ready in time")
+)
+
+// readyPollInterval is how often the log and endpoint are checked while waiting
+const readyPollInterval = 500 * time.Millisecond
+
+// excerptLines is how many log lines a StartupError shows, ending at the problem
+const excerptLines = 15
+
+// readyPattern matches the log lines FXServer prints once it takes connections
+var readyPattern = regexp.MustCompile(`(?i)listening on|server license key authentication succeeded`)
+
+// startupProblems are log lines that mean the server won't come up, with
+// what to tell the user. The first match wins.
+var startupProblems = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`(?i)license key authentication failed|invalid license key|no license key|sv_licenseKey.*(invalid|not set|missing)`),
+		"the license key was rejected; check sv_licenseKey in server.cfg and the key with 'inkwash key list'"},
+	{regexp.MustCompile(`(?i)could not bind|failed to bind|address already in use`),
+		"the port is already in use; stop whatever else listens on it or change the endpoints in server.cfg"},
+	{regexp.MustCompile(`(?i)\bfatal error\b`),
+		"FXServer reported a fatal error"},
+}
+
+// StartupError describes a server that failed while starting
+type StartupError struct {
+	Reason  string
+	Excerpt []string // The log lines leading up to the problem
+	Exited  bool     // The process has exited
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrStartupFailed, e.Reason)
+}
+
+func (e *StartupError) Unwrap() error {
+	return ErrStartupFailed
+}
+
+// StartupWatcher waits for a server to become ready. Create it before the
+// server is started, so it only reads what the new process logs.
+type StartupWatcher struct {
+	pm       *ProcessManager
+	follower *LogFollower
+	recent   []string // The last excerptLines lines logged
+}
+
+// NewStartupWatcher prepares to watch the server at serverPath start
+func NewStartupWatcher(serverPath string) *StartupWatcher {
+	return &StartupWatcher{
+		pm:       NewProcessManager(),
+		follower: NewLogFollower(serverPath),
+	}
+}
+
+// Wait returns once the server is ready: it logged that it's listening or
+// its players.json endpoint answered. It fails with a *StartupError as soon
+// as the process exits or logs a known fatal problem, and with
+// ErrStartupTimeout if neither happened within timeout. Cancelling ctx stops
+// waiting without touching the server.
+func (w *StartupWatcher) Wait(ctx context.Context, server *types.Server, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	address := QueryAddress(server)
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Checked before reading, so the lines it printed on its way out are read too
+		exited := !w.pm.IsRunning(server)
+		lines, _ := w.follower.Poll()
+		if ready, err := w.scan(lines, exited); ready || err != nil {
+			return err
+		}
+		if exited {
+			return &StartupError{Reason: "the process exited", Excerpt: w.excerpt(), Exited: true}
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, readyPollInterval)
+		_, err := query.Players(probeCtx, address)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: still starting after %s", ErrStartupTimeout, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan looks through newly logged lines for a known problem or, if the
+// process is still running, the line saying it's ready
+func (w *StartupWatcher) scan(lines []string, exited bool) (bool, error) {
+	for _, line := range lines {
+		w.remember(line)
+		for _, problem := range startupProblems {
+			if problem.pattern.MatchString(line) {
+				return false, &StartupError{Reason: problem.reason, Excerpt: w.excerpt(), Exited: exited}
+			}
+		}
+		if !exited && readyPattern.MatchString(line) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// remember keeps a log line for the excerpt
+func (w *StartupWatcher) remember(line string) {
+	w.recent = append(w.recent, line)
+	if len(w.recent) > excerptLines {
+		w.recent = w.recent[len(w.recent)-excerptLines:]
+	}
+}
+
+// excerpt returns the remembered lines, without trailing blank ones
+func (w *StartupWatcher) excerpt() []string {
+	lines := append([]string{}, w.recent...)
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
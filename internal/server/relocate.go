@@ -0,0 +1,170 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, what Windows returns for a
+// rename across drives
+const errorNotSameDevice = syscall.Errno(17)
+
+// RenameResult describes a finished Rename
+type RenameResult struct {
+	Server          *types.Server
+	ReadmeRewritten bool // The generated README.md was updated with the new name
+}
+
+// Rename registers a stopped server under a new name. The folder keeps its
+// name; only the generated README.md, whose commands name the server, is
+// rewritten if it hasn't been edited. The caller checks the server isn't running.
+func Rename(reg *registry.Registry, srv *types.Server, newName string) (*RenameResult, error) {
+	if err := validation.ValidateServerName(newName); err != nil {
+		return nil, err
+	}
+	if newName == srv.Name {
+		return nil, fmt.Errorf("server is already called '%s'", newName)
+	}
+	if reg.Exists(newName) {
+		return nil, fmt.Errorf("server '%s' already exists", newName)
+	}
+
+	lock, err := LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	if err := reg.Rename(srv.Name, newName); err != nil {
+		return nil, err
+	}
+
+	renamed := *srv
+	renamed.Name = newName
+	result := &RenameResult{Server: &renamed}
+
+	rewritten, err := NewConfigGenerator().RenameScaffoldFiles(srv, &renamed)
+	if err != nil {
+		return result, fmt.Errorf("server renamed but %w", err)
+	}
+	result.ReadmeRewritten = rewritten
+	return result, nil
+}
+
+// MoveResult describes a finished Move
+type MoveResult struct {
+	Server *types.Server
+	From   string // Folder the server was in
+	Copied bool   // The destination is on another drive, so files were copied rather than renamed
+}
+
+// Move moves a stopped server's folder to dest and updates the registry and
+// launch script, which hold the absolute path. If dest is an existing folder
+// the server is moved into it, keeping its folder name. metadata.json and
+// server.cfg only hold relative paths and move as they are. The caller
+// checks the server isn't running.
+func Move(reg *registry.Registry, srv *types.Server, dest string) (*MoveResult, error) {
+	from, err := filepath.Abs(srv.Path)
+	if err != nil {
+		return nil, err
+	}
+	to, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	if to == from {
+		return nil, fmt.Errorf("server '%s' is already in %s", srv.Name, from)
+	}
+	if info, err := os.Stat(to); err == nil && info.IsDir() {
+		to = filepath.Join(to, filepath.Base(from))
+	}
+
+	if isWithin(from, to) {
+		return nil, fmt.Errorf("can't move server '%s' into its own folder", srv.Name)
+	}
+	for _, existing := range reg.List() {
+		if existing.Name != srv.Name && isWithin(existing.Path, to) {
+			return nil, fmt.Errorf("%s is inside the folder of server '%s'", to, existing.Name)
+		}
+	}
+	if _, err := os.Lstat(to); err == nil {
+		return nil, fmt.Errorf("%s already exists; give a folder that doesn't exist yet, or one to move the server into", to)
+	}
+
+	if err := validation.ValidatePathWritable(filepath.Dir(to)); err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+
+	// The lock file lives in the folder being moved, and Windows won't rename a
+	// folder with an open file in it, so it's only held to check nobody else is
+	// modifying the server
+	lock, err := LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	lock.Release()
+
+	result := &MoveResult{From: from}
+	if err := os.Rename(from, to); err != nil {
+		if !isCrossDevice(err) {
+			return nil, fmt.Errorf("failed to move server folder: %w", err)
+		}
+
+		if err := copyDirSkipBrokenSymlinks(from, to); err != nil {
+			os.RemoveAll(to)
+			return nil, fmt.Errorf("failed to copy server folder: %w", err)
+		}
+		result.Copied = true
+	}
+
+	moved := *srv
+	moved.Path = to
+	result.Server = &moved
+
+	if err := reg.Update(moved); err != nil {
+		// Put it back, so the registry still points at the server
+		if result.Copied {
+			os.RemoveAll(to)
+		} else if os.Rename(to, from) != nil {
+			return result, fmt.Errorf("server moved to %s but the registry couldn't be updated (%v); run 'inkwash registry scan %s' to register it again", to, err, filepath.Dir(to))
+		}
+		return nil, fmt.Errorf("failed to update registry: %w", err)
+	}
+
+	// Only removed once the registry points at the copy
+	if result.Copied {
+		if err := os.RemoveAll(from); err != nil {
+			return result, fmt.Errorf("server copied to %s but the old folder couldn't be removed: %w", to, err)
+		}
+	}
+
+	if err := NewConfigGenerator().GenerateLaunchScript(&moved); err != nil {
+		return result, fmt.Errorf("server moved but %w", err)
+	}
+	return result, nil
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isCrossDevice reports whether a rename failed because the destination is
+// on another filesystem or drive
+func isCrossDevice(err error) bool {
+	if errors.Is(err, syscall.EXDEV) {
+		return true
+	}
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && errno == errorNotSameDevice
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/lockfile"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/staging"
+)
+
+// Where cfx-server-data is fetched from
+const (
+	serverDataRepo       = "https://github.com/citizenfx/cfx-server-data.git"
+	serverDataTarballURL = "https://github.com/citizenfx/cfx-server-data/archive/refs/heads/master.tar.gz"
+)
+
+// serverDataMaxAge is how long the cached copy of cfx-server-data is used
+// before it's updated
+const serverDataMaxAge = 24 * time.Hour
+
+// serverDataLockTimeout is how long to wait for another inkwash process to
+// finish updating the cached copy
+const serverDataLockTimeout = 2 * time.Minute
+
+// DefaultServerDataPath returns where the shared copy of cfx-server-data new
+// servers' resources are copied from
+func DefaultServerDataPath() string {
+	return filepath.Join(registry.GetDefaultCachePath(), "server-data")
+}
+
+// cloneServerData copies cfx-server-data's resources into a new server from
+// the shared copy, updating that first if it's missing, older than
+// serverDataMaxAge or refresh is set. If it can't be updated the copy already
+// cached is used, so servers can be created offline; the returned note says
+// so. Without any copy, the server gets empty folders.
+func (inst *Installer) cloneServerData(ctx context.Context, serverPath string, refresh bool) (string, error) {
+	if err := os.MkdirAll(inst.serverDataDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create server-data cache: %w", err)
+	}
+
+	lock, err := lockfile.Acquire(filepath.Join(inst.serverDataDir, "update.lock"), serverDataLockTimeout)
+	if err != nil {
+		return "", fmt.Errorf("server-data cache is busy: %w", err)
+	}
+	defer lock.Release()
+
+	repoPath := filepath.Join(inst.serverDataDir, "repo")
+	if err := staging.Recover(repoPath); err != nil {
+		return "", err
+	}
+
+	srcResources := filepath.Join(repoPath, "resources")
+	updated, cached := inst.serverDataUpdated()
+	if _, err := os.Stat(srcResources); err != nil {
+		cached = false
+	}
+
+	var note string
+	if !cached || refresh || time.Since(updated) > serverDataMaxAge {
+		if err := inst.updateServerData(ctx, repoPath); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if !cached {
+				return "", inst.createBasicStructure(serverPath)
+			}
+			note = fmt.Sprintf("Couldn't update cfx-server-data (%v); used the copy from %s", err, updated.Format("2006-01-02"))
+		}
+	}
+
+	dstResources := filepath.Join(serverPath, "resources")
+	if err := copyDir(srcResources, dstResources); err != nil {
+		return "", fmt.Errorf("failed to copy resources: %w", err)
+	}
+
+	return note, nil
+}
+
+// serverDataUpdated returns when the cached copy was last updated, and false
+// if it never was
+func (inst *Installer) serverDataUpdated() (time.Time, bool) {
+	info, err := os.Stat(filepath.Join(inst.serverDataDir, "updated"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// updateServerData brings the cached copy at repoPath up to date. A git
+// checkout is updated in place; otherwise it's cloned again, or downloaded as
+// a tarball when git isn't installed.
+func (inst *Installer) updateServerData(ctx context.Context, repoPath string) error {
+	err := fmt.Errorf("git not found")
+	if inst.isGitAvailable() {
+		err = inst.pullServerData(ctx, repoPath)
+	}
+	if err != nil && ctx.Err() == nil {
+		// Git not available or fetching failed - download the tarball from GitHub
+		err = inst.downloadServerData(ctx, repoPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	stamp := filepath.Join(inst.serverDataDir, "updated")
+	return os.WriteFile(stamp, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// pullServerData updates the git checkout at repoPath, cloning it if it isn't
+// one yet
+func (inst *Installer) pullServerData(ctx context.Context, repoPath string) error {
+	if _, err := os.Stat(filepath.Join(repoPath, ".git")); err == nil {
+		// This is a shallow git pull: with --depth 1 git can't tell a
+		// fast-forward from unrelated history, so the fetched commit is
+		// checked out directly
+		err := runGit(ctx, repoPath, "fetch", "--quiet", "--depth", "1", "origin", "HEAD")
+		if err == nil {
+			err = runGit(ctx, repoPath, "reset", "--quiet", "--hard", "FETCH_HEAD")
+		}
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		// A broken checkout is replaced with a fresh clone
+	}
+
+	stageDir, err := staging.Dir(repoPath)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	if err := runGit(ctx, "", "clone", "--quiet", "--depth", "1", serverDataRepo, stageDir); err != nil {
+		return err
+	}
+	return staging.Swap(stageDir, repoPath)
+}
+
+// downloadServerData replaces the cached copy at repoPath with cfx-server-data's
+// tarball from GitHub
+func (inst *Installer) downloadServerData(ctx context.Context, repoPath string) error {
+	tmpDir, err := staging.Dir(repoPath)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Download the tarball
+	tarballPath := filepath.Join(tmpDir, "server-data.tar.gz")
+	if err := inst.downloader.DownloadContext(ctx, serverDataTarballURL, tarballPath, nil); err != nil {
+		return fmt.Errorf("failed to download cfx-server-data: %w", err)
+	}
+
+	// Extract the tarball
+	extractPath := filepath.Join(tmpDir, "extracted")
+	if err := inst.extractor.Extract(tarballPath, extractPath); err != nil {
+		return fmt.Errorf("failed to extract cfx-server-data: %w", err)
+	}
+
+	// GitHub tarballs extract to a folder named "{repo}-{branch}"
+	// e.g., "cfx-server-data-master"; look for the one holding resources
+	entries, err := os.ReadDir(extractPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		srcPath := filepath.Join(extractPath, entry.Name())
+		if _, err := os.Stat(filepath.Join(srcPath, "resources")); err == nil {
+			return staging.Swap(srcPath, repoPath)
+		}
+	}
+
+	return fmt.Errorf("cfx-server-data download has no resources folder")
+}
+
+// runGit runs git in dir ("" for the current directory), returning its
+// error output on failure
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Keep git from asking for credentials if GitHub can't be reached
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(output) > 0 {
+			line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+			return fmt.Errorf("git %s: %s", args[0], line)
+		}
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return nil
+}
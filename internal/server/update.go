@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/staging"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrUpToDate is returned when a server already has the build an update asks for
+var ErrUpToDate = errors.New("server is already on this build")
+
+// ErrPinned is returned when an update of a server pinned to its build
+// doesn't say which build to move it to
+var ErrPinned = errors.New("server is pinned")
+
+// UpdateOptions describes the FXServer build to move a server to
+//
+// With none of BuildNumber, BuildChannel and ArtifactURL set, the server is
+// moved to the build its channel (see types.ServerMetadata.BuildChannel)
+// points to.
+type UpdateOptions struct {
+	BuildNumber  int    // Ignored when BuildChannel or ArtifactURL is set
+	BuildChannel string // types.BuildChannel*: resolved to a build when the update runs
+	ArtifactURL  string // Direct FXServer archive URL, used instead of BuildNumber and BuildChannel
+	Force        bool   // Reinstall the build even if the server already has it
+
+	// Channel is the channel the server follows from now on, a
+	// types.BuildChannel* or types.BuildChannelPinned; it's also the build to
+	// move to if none is given. "" keeps the server's channel, except that a
+	// build number or ArtifactURL pins it.
+	Channel string
+
+	ForceRedownload bool   // Ignore the cached copy of the build and replace it with a fresh download
+	Verify          bool   // Test the downloaded archive's integrity, downloading again if it's damaged
+	SHA256          string // Expected SHA256 of the downloaded archive; implies Verify
+}
+
+// UpdateResult describes a finished update
+type UpdateResult struct {
+	From       int    // Build the server had, 0 if metadata.json didn't say
+	To         int    // Build the server has now, 0 for an artifact URL without a build number
+	BackupPath string // Where the replaced bin/ was kept
+	Channel    string // Channel the server follows now
+}
+
+// BinaryBackupPath returns where an update keeps the binaries of the build it
+// replaces. Build 0 (unknown) is kept as bin.previous.
+func BinaryBackupPath(serverPath string, build int) string {
+	if build == 0 {
+		return filepath.Join(serverPath, "bin.previous")
+	}
+	return filepath.Join(serverPath, fmt.Sprintf("bin.%d", build))
+}
+
+// UpdateContext installs another FXServer build into an existing server's
+// bin/, using the build cache like a new install. The old bin/ is kept as a
+// backup and replaces the backup from any earlier update. The server must
+// not be running; its folder is locked while the update runs.
+func (inst *Installer) UpdateContext(ctx context.Context, srv *types.Server, opts UpdateOptions, onProgress ProgressCallback) (*UpdateResult, error) {
+	totalSteps := 4
+	targetOS := srv.GetTargetOS()
+
+	inst.reportProgress(onProgress, InstallProgress{
+		Step:           "Checking available builds",
+		Progress:       0,
+		TotalSteps:     totalSteps,
+		CompletedSteps: 0,
+	})
+
+	if err := inst.artifactClient.SetTargetOS(targetOS); err != nil {
+		return nil, err
+	}
+
+	lock, err := LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	// Servers adopted from elsewhere may not have metadata.json yet
+	metadataManager := NewMetadataManager()
+	metadata, err := metadataManager.Load(srv.Path)
+	if err != nil && metadataManager.Exists(srv.Path) {
+		return nil, err
+	}
+
+	result := &UpdateResult{Channel: types.BuildChannelRecommended}
+	if metadata != nil {
+		result.From = metadata.Build.Number
+		result.Channel = metadata.BuildChannel()
+	}
+
+	channel := opts.Channel
+	if channel == "" && (opts.ArtifactURL != "" || opts.BuildChannel == "" && opts.BuildNumber != 0) {
+		// A specific build keeps the server on it
+		channel = types.BuildChannelPinned
+	}
+
+	buildNumber := opts.BuildNumber
+	buildChannel := opts.BuildChannel
+	if opts.ArtifactURL == "" && buildChannel == "" && buildNumber == 0 {
+		// Nothing asked for: move the server along its channel
+		buildChannel = result.Channel
+		if channel != "" {
+			buildChannel = channel
+		}
+		if buildChannel == types.BuildChannelPinned {
+			if channel == "" {
+				return nil, fmt.Errorf("%w to build %d; give a build or channel to move it to", ErrPinned, result.From)
+			}
+			if result.From == 0 {
+				return nil, fmt.Errorf("the server's build is unknown; give a build to pin it to")
+			}
+			buildChannel, buildNumber = "", result.From
+		}
+	}
+
+	if opts.ArtifactURL != "" {
+		build, _, err := validation.ParseArtifactURL(opts.ArtifactURL)
+		if err != nil {
+			return nil, err
+		}
+		buildNumber = build.Number
+	} else if buildChannel != "" {
+		build, err := inst.resolveBuildChannel(buildChannel)
+		if err != nil {
+			return nil, err
+		}
+		buildNumber = build.Number
+	}
+
+	result.To = buildNumber
+	if buildNumber != 0 && buildNumber == result.From && !opts.Force {
+		if channel != "" && metadata != nil && metadata.Channel != channel {
+			metadata.Channel = channel
+			if err := metadataManager.Save(srv.Path, metadata); err != nil {
+				return nil, fmt.Errorf("failed to save metadata: %w", err)
+			}
+		}
+		if channel != "" {
+			result.Channel = channel
+		}
+		return result, fmt.Errorf("%w (%d)", ErrUpToDate, buildNumber)
+	}
+
+	inst.reportProgress(onProgress, InstallProgress{
+		Step:           "Preparing backup of the current build",
+		Progress:       0.1,
+		TotalSteps:     totalSteps,
+		CompletedSteps: 1,
+	})
+
+	result.BackupPath = BinaryBackupPath(srv.Path, result.From)
+	if err := os.RemoveAll(result.BackupPath); err != nil {
+		return nil, fmt.Errorf("failed to clear old backup %s: %w", result.BackupPath, err)
+	}
+
+	var installed types.BuildMetadata
+	if restore := restorableBackup(srv.Path, metadata, buildNumber); restore != "" && !opts.Force && !opts.ForceRedownload {
+		// Going back to the build the last update replaced just swaps its backup in
+		inst.reportProgress(onProgress, InstallProgress{
+			Step:           fmt.Sprintf("Restoring build %d from its backup", buildNumber),
+			Progress:       0.5,
+			TotalSteps:     totalSteps,
+			CompletedSteps: 2,
+		})
+
+		if err := staging.SwapKeep(restore, srv.GetBinaryPath(), result.BackupPath); err != nil {
+			return nil, fmt.Errorf("failed to restore build %d: %w", buildNumber, err)
+		}
+		installed = *metadata.Build.Previous
+	} else {
+		// installBinary reports its progress as steps 2-3 of a new install;
+		// fold them into step 3 of this update
+		onInstallProgress := func(p InstallProgress) {
+			p.Progress = 0.2 + clampProgress((p.Progress-0.28)/(0.45-0.28))*0.7
+			p.TotalSteps, p.CompletedSteps = totalSteps, 2
+			inst.reportProgress(onProgress, p)
+		}
+
+		targetBuild, err := inst.installBinary(ctx, InstallOptions{
+			ArtifactURL:     opts.ArtifactURL,
+			ForceRedownload: opts.ForceRedownload,
+			LaunchCommand:   srv.LaunchCommand,
+			Verify:          opts.Verify,
+			SHA256:          opts.SHA256,
+			keepBinary:      result.BackupPath,
+		}, buildNumber, srv.GetBinaryPath(), targetOS, onInstallProgress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install FXServer: %w", err)
+		}
+		installed = types.NewServerMetadata(*targetBuild).Build
+	}
+
+	result.To = installed.Number
+	if _, err := os.Stat(result.BackupPath); err != nil {
+		result.BackupPath = "" // There was no bin/ to keep
+	}
+
+	inst.reportProgress(onProgress, InstallProgress{
+		Step:           "Updating server metadata",
+		Progress:       0.95,
+		TotalSteps:     totalSteps,
+		CompletedSteps: 3,
+	})
+
+	installed.InstalledAt = time.Now()
+	installed.Previous = nil
+	if metadata != nil {
+		// Only the backup of the build just replaced is kept
+		previous := metadata.Build
+		if older := previous.Previous; older != nil && older.Number != installed.Number {
+			os.RemoveAll(BinaryBackupPath(srv.Path, older.Number))
+		}
+		previous.Previous = nil
+		installed.Previous = &previous
+	} else {
+		metadata = types.NewServerMetadata(types.Build{})
+		if !srv.Created.IsZero() {
+			metadata.Lifecycle.CreatedAt = srv.Created
+		}
+		if result.BackupPath != "" {
+			installed.Previous = &types.BuildMetadata{}
+		}
+	}
+	metadata.Build = installed
+	if channel != "" {
+		metadata.Channel = channel
+	}
+	result.Channel = metadata.BuildChannel()
+
+	if err := metadataManager.Save(srv.Path, metadata); err != nil {
+		return result, fmt.Errorf("FXServer was updated but metadata.json couldn't be saved: %w", err)
+	}
+
+	inst.reportProgress(onProgress, InstallProgress{
+		Step:           "Update complete",
+		Progress:       1.0,
+		TotalSteps:     totalSteps,
+		CompletedSteps: 4,
+	})
+
+	return result, nil
+}
+
+// restorableBackup returns the backup folder of the build the last update
+// replaced if that's the build asked for, or "" if there isn't one
+func restorableBackup(serverPath string, metadata *types.ServerMetadata, buildNumber int) string {
+	if metadata == nil || metadata.Build.Previous == nil || buildNumber == 0 || metadata.Build.Previous.Number != buildNumber {
+		return ""
+	}
+	path := BinaryBackupPath(serverPath, buildNumber)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return ""
+	}
+	return path
+}
+
+// clampProgress limits a progress fraction to 0-1
+func clampProgress(p float64) float64 {
+	if p < 0 {
+		return 0
+	}
+	if p > 1 {
+		return 1
+	}
+	return p
+}
@@ -0,0 +1,231 @@
+package servercfg
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// convarKind is the expected type of a convar's value
+type convarKind int
+
+const (
+	convarString convarKind = iota
+	convarInt
+	convarBool
+)
+
+// convarSpec describes a convar InkWash knows how to check
+type convarSpec struct {
+	kind     convarKind
+	min      int      // Inclusive lower bound for ints
+	max      int      // Inclusive upper bound for ints (0 = no limit)
+	allowed  []string // Allowed values for strings (nil = any)
+	nonEmpty bool
+	check    func(value string) string // Extra check, returns what's wrong or ""
+
+	// How a new assignment is written: "set", "sets" (also shown in the
+	// server browser) or "" for a bare "<name> <value>" line
+	command string
+}
+
+// knownConvars are the convars validated before server.cfg is written,
+// keyed by lowercase name
+var knownConvars = map[string]convarSpec{
+	"sv_hostname":             {kind: convarString, nonEmpty: true},
+	"sv_licensekey":           {kind: convarString, check: checkLicenseKey},
+	"sv_maxclients":           {kind: convarInt, min: 1, max: 2048},
+	"rcon_password":           {kind: convarString},
+	"sv_endpointprivacy":      {kind: convarBool, command: "set"},
+	"sv_scripthookallowed":    {kind: convarBool},
+	"sv_enforcegamebuild":     {kind: convarInt, check: checkGameBuild, command: "set"},
+	"onesync":                 {kind: convarString, allowed: []string{"on", "off", "legacy"}, command: "set"},
+	"sv_lan":                  {kind: convarBool, command: "set"},
+	"sv_logfile":              {kind: convarString, command: "set"},
+	"steam_webapikey":         {kind: convarString, command: "set"},
+	"sv_projectname":          {kind: convarString, command: "sets"},
+	"sv_projectdesc":          {kind: convarString, command: "sets"},
+	"locale":                  {kind: convarString, check: checkLocale, command: "sets"},
+	"tags":                    {kind: convarString, command: "sets"},
+	"gametype":                {kind: convarString, command: "sets"},
+	"mapname":                 {kind: convarString, command: "sets"},
+	"mysql_connection_string": {kind: convarString, command: "set"},
+}
+
+// Issue describes a problem found in server.cfg
+type Issue struct {
+	Line    int // 1-based, 0 if the problem is something missing
+	Name    string
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.Name, i.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Name, i.Message)
+}
+
+// IsKnownConvar reports whether name is a convar InkWash validates
+func IsKnownConvar(name string) bool {
+	_, ok := knownConvars[strings.ToLower(name)]
+	return ok
+}
+
+// ValidateConvar checks a value for a known convar. Unknown convars are
+// accepted as they are.
+func ValidateConvar(name, value string) error {
+	if strings.Contains(value, `"`) {
+		return fmt.Errorf("%s: values can't contain double quotes", name)
+	}
+
+	spec, ok := knownConvars[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	if msg := checkConvar(spec, value); msg != "" {
+		return fmt.Errorf("%s: %s", name, msg)
+	}
+	return nil
+}
+
+// checkConvar returns a description of what's wrong with value, or "" if it's valid
+func checkConvar(spec convarSpec, value string) string {
+	switch spec.kind {
+	case convarInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Sprintf("expected a number, got %q", value)
+		}
+		if n < spec.min {
+			return fmt.Sprintf("must be at least %d (got %d)", spec.min, n)
+		}
+		if spec.max > 0 && n > spec.max {
+			return fmt.Sprintf("must be at most %d (got %d)", spec.max, n)
+		}
+
+	case convarBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("expected true, false, 1 or 0, got %q", value)
+		}
+
+	case convarString:
+		if spec.nonEmpty && strings.TrimSpace(value) == "" {
+			return "can't be empty"
+		}
+		if spec.allowed != nil && !contains(spec.allowed, strings.ToLower(value)) {
+			return fmt.Sprintf("must be one of %s (got %q)", strings.Join(spec.allowed, ", "), value)
+		}
+	}
+
+	if spec.check != nil {
+		return spec.check(value)
+	}
+	return ""
+}
+
+// Validate checks the known convars and endpoints in the file
+func (c *Config) Validate() []Issue {
+	var issues []Issue
+
+	for i, line := range c.Lines {
+		command, args := splitCommand(line)
+
+		if command == "endpoint_add_tcp" || command == "endpoint_add_udp" {
+			if len(args) == 0 {
+				issues = append(issues, Issue{Line: i + 1, Name: command, Message: "missing address"})
+				continue
+			}
+			if msg := checkEndpoint(args[0]); msg != "" {
+				issues = append(issues, Issue{Line: i + 1, Name: command, Message: msg})
+			}
+			continue
+		}
+
+		for name, spec := range knownConvars {
+			value, _, ok := assignment(command, args, name)
+			if !ok {
+				continue
+			}
+			if msg := checkConvar(spec, value); msg != "" {
+				issues = append(issues, Issue{Line: i + 1, Name: name, Message: msg})
+			}
+		}
+	}
+
+	if key, ok := c.Convar("sv_licensekey"); !ok || key == "" {
+		issues = append(issues, Issue{Name: "sv_licenseKey", Message: "not set, FXServer won't start without a license key"})
+	}
+	if len(c.Endpoints()) == 0 {
+		issues = append(issues, Issue{Name: "endpoint_add_tcp", Message: "no endpoints, players can't connect"})
+	}
+
+	return issues
+}
+
+// checkEndpoint checks an endpoint_add_tcp/udp address
+func checkEndpoint(address string) string {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Sprintf("expected host:port, got %q", address)
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Sprintf("%q is not an IP address", host)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Sprintf("port must be between 1 and 65535 (got %q)", portStr)
+	}
+	return ""
+}
+
+// checkLicenseKey checks the format of sv_licenseKey
+func checkLicenseKey(value string) string {
+	err := validation.ValidateLicenseKey(value)
+	var vErr *validation.ValidationError
+	if errors.As(err, &vErr) {
+		return strings.ToLower(vErr.Message[:1]) + vErr.Message[1:]
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkGameBuild checks sv_enforceGameBuild against the builds FXServer knows.
+// Builds newer than InkWash's list are let through.
+func checkGameBuild(value string) string {
+	n, _ := strconv.Atoi(value)
+	if _, ok := types.FindGameBuild(n); ok || n > types.GameBuilds[len(types.GameBuilds)-1].Number {
+		return ""
+	}
+
+	known := make([]string, len(types.GameBuilds))
+	for i, build := range types.GameBuilds {
+		known[i] = strconv.Itoa(build.Number)
+	}
+	return fmt.Sprintf("unknown game build %d (known: %s)", n, strings.Join(known, ", "))
+}
+
+// checkLocale checks that locale looks like a language tag, e.g. en-US
+func checkLocale(value string) string {
+	parts := strings.Split(value, "-")
+	if len(parts) < 2 || len(parts[0]) < 2 || len(parts[0]) > 3 {
+		return fmt.Sprintf("expected a language tag like en-US, got %q", value)
+	}
+	return ""
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
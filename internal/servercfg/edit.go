@@ -0,0 +1,185 @@
+package servercfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Setting is the effective assignment of a convar in server.cfg
+type Setting struct {
+	Name    string // As written in the file
+	Value   string
+	Command string // set, sets, setr, or "" for a bare "<name> <value>" line
+	Line    int    // Index into Lines of the assignment that wins
+}
+
+// Endpoint is an endpoint_add_tcp or endpoint_add_udp line
+type Endpoint struct {
+	Protocol string // tcp or udp
+	Address  string
+}
+
+// Settings returns the convars the file sets, in the order they first
+// appear, each with its last assigned value. Bare "<name> <value>" lines are
+// only recognised for known convars, since other commands look the same.
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	index := make(map[string]int)
+
+	for i, line := range c.Lines {
+		command, args := splitCommand(line)
+
+		var name string
+		switch {
+		case (command == "set" || command == "sets" || command == "setr") && len(args) >= 2:
+			name = args[0]
+		case IsKnownConvar(command) && len(args) >= 1:
+			fields, _ := tokenize(line)
+			name = fields[0]
+		default:
+			continue
+		}
+
+		value, form, _ := assignment(command, args, strings.ToLower(name))
+		setting := Setting{Name: name, Value: value, Command: form, Line: i}
+		if j, ok := index[strings.ToLower(name)]; ok {
+			settings[j] = setting
+			continue
+		}
+		index[strings.ToLower(name)] = len(settings)
+		settings = append(settings, setting)
+	}
+
+	return settings
+}
+
+// Endpoints returns the endpoints the server listens on
+func (c *Config) Endpoints() []Endpoint {
+	var endpoints []Endpoint
+	for _, line := range c.Lines {
+		command, args := splitCommand(line)
+		if (command == "endpoint_add_tcp" || command == "endpoint_add_udp") && len(args) > 0 {
+			endpoints = append(endpoints, Endpoint{Protocol: strings.TrimPrefix(command, "endpoint_add_"), Address: args[0]})
+		}
+	}
+	return endpoints
+}
+
+// Set assigns a convar. The line that currently sets it is rewritten in
+// place; otherwise a commented-out assignment (like the generated
+// "# rcon_password ...") is uncommented, and failing that a line is appended.
+func (c *Config) Set(name, value string) error {
+	if err := ValidateConvar(name, value); err != nil {
+		return err
+	}
+	lower := strings.ToLower(name)
+
+	// The last assignment is the one that takes effect
+	for i := len(c.Lines) - 1; i >= 0; i-- {
+		command, args := splitCommand(c.Lines[i])
+		if _, form, ok := assignment(command, args, lower); ok {
+			c.Lines[i] = rewriteAssignment(c.Lines[i], form, name, value)
+			return nil
+		}
+	}
+
+	for i := len(c.Lines) - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(c.Lines[i])
+		if !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Only exact assignments, so prose comments mentioning the name are left alone
+		command, args := splitCommand(strings.TrimLeft(trimmed, "# "))
+		_, form, ok := assignment(command, args, lower)
+		if ok && ((form == "" && len(args) == 1) || (form != "" && len(args) == 2)) {
+			c.Lines[i] = formatAssignment(form, name, value)
+			return nil
+		}
+	}
+
+	form := "set"
+	if spec, ok := knownConvars[lower]; ok {
+		form = spec.command
+	}
+	c.Lines = append(c.Lines, formatAssignment(form, name, value))
+	return nil
+}
+
+// rewriteAssignment replaces the value in an assignment line, keeping its
+// indentation, command and trailing comment
+func rewriteAssignment(line, form, name, value string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	// Keep the name as it was written
+	fields, comment := tokenize(line)
+	if form == "" {
+		name = fields[0]
+	} else if len(fields) > 1 {
+		name = fields[1]
+	}
+
+	rewritten := indent + formatAssignment(form, name, value)
+	if comment >= 0 {
+		rewritten += " " + line[comment:]
+	}
+	return rewritten
+}
+
+// formatAssignment writes a convar assignment, quoting the value if needed
+func formatAssignment(form, name, value string) string {
+	if needsQuotes(name, value) {
+		value = `"` + value + `"`
+	}
+	if form == "" {
+		return name + " " + value
+	}
+	return form + " " + name + " " + value
+}
+
+// needsQuotes reports whether a value is written in quotes. Known free-text
+// convars always are, to match the generated server.cfg.
+func needsQuotes(name, value string) bool {
+	if spec, ok := knownConvars[strings.ToLower(name)]; ok {
+		return spec.kind == convarString && spec.allowed == nil
+	}
+	return value == "" || strings.ContainsAny(value, " \t#/;")
+}
+
+// Save writes the config back to Path, replacing the file in one step so a
+// crash can't leave it half-written
+func (c *Config) Save() error {
+	newline := "\n"
+	if c.CRLF {
+		newline = "\r\n"
+	}
+	data := strings.Join(c.Lines, newline) + newline
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(c.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), ".server.cfg-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(c.Path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(c.Path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(c.Path), err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(c.Path), err)
+	}
+	if err := os.Rename(tmp.Name(), c.Path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(c.Path), err)
+	}
+	return nil
+}
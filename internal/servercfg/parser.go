@@ -0,0 +1,280 @@
+package servercfg
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config is a parsed view of a FiveM server.cfg file
+type Config struct {
+	Path  string
+	Lines []string
+	CRLF  bool // Lines end in \r\n (kept when saving)
+}
+
+// Load reads and parses a server.cfg file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("server.cfg not found at %s", path)
+		}
+		return nil, fmt.Errorf("failed to read server.cfg: %w", err)
+	}
+
+	text := string(data)
+	cfg := &Config{Path: path, CRLF: strings.Contains(text, "\r\n")}
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text != "" {
+		cfg.Lines = strings.Split(text, "\n")
+	}
+
+	return cfg, nil
+}
+
+// StartedResources returns the resource names started via ensure/start lines,
+// in the order they appear. Category entries (e.g. "[vehicles]") are included as-is.
+func (c *Config) StartedResources() []string {
+	var resources []string
+	seen := make(map[string]bool)
+
+	for _, line := range c.Lines {
+		command, args := splitCommand(line)
+		if command != "ensure" && command != "start" {
+			continue
+		}
+		if len(args) == 0 || seen[args[0]] {
+			continue
+		}
+
+		seen[args[0]] = true
+		resources = append(resources, args[0])
+	}
+
+	return resources
+}
+
+// IsStarted reports whether a resource is started, either directly or via its category
+func (c *Config) IsStarted(name, category string) bool {
+	for _, started := range c.StartedResources() {
+		if started == name {
+			return true
+		}
+		if category != "" && started == "["+category+"]" {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoint returns the host and port from the first endpoint_add_tcp line
+func (c *Config) Endpoint() (string, int, bool) {
+	for _, line := range c.Lines {
+		command, args := splitCommand(line)
+		if command != "endpoint_add_tcp" || len(args) == 0 {
+			continue
+		}
+
+		host, portStr, err := net.SplitHostPort(args[0])
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		return host, port, true
+	}
+
+	return "", 0, false
+}
+
+// Convar returns the last value assigned to a console variable, either with
+// set/sets/setr or as a bare "<name> <value>" line. Names are case-insensitive.
+func (c *Config) Convar(name string) (string, bool) {
+	name = strings.ToLower(name)
+	value, found := "", false
+
+	for _, line := range c.Lines {
+		command, args := splitCommand(line)
+		if v, _, ok := assignment(command, args, name); ok {
+			value, found = v, true
+		}
+	}
+
+	return value, found
+}
+
+// assignment returns the value a line assigns to the convar name (lowercase),
+// and the command it uses: set, sets, setr, or "" for a bare "<name> <value>"
+func assignment(command string, args []string, name string) (string, string, bool) {
+	switch {
+	case (command == "set" || command == "sets" || command == "setr") && len(args) >= 2 && strings.ToLower(args[0]) == name:
+		return args[1], command, true
+	case command == name && len(args) >= 1:
+		return args[0], "", true
+	}
+	return "", "", false
+}
+
+// AppendEnsures appends "ensure <name>" lines for resources that aren't already started.
+// Returns the names that were actually added.
+func AppendEnsures(path string, names []string) ([]string, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	added := cfg.AddEnsures(names)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := cfg.Save(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ResourcesFile is a cfg next to server.cfg that ensure lines can be kept in
+// instead, exec'd from server.cfg
+const ResourcesFile = "resources.cfg"
+
+// EnsureFiles lists the files ensure lines can be added to
+var EnsureFiles = []string{"server.cfg", ResourcesFile}
+
+// AppendEnsuresIn adds "ensure <name>" lines for a server's resources that
+// aren't started yet to file, one of EnsureFiles. ResourcesFile is created
+// if it's missing and exec'd from server.cfg unless it already is. Returns
+// the names that were actually added.
+func AppendEnsuresIn(serverPath, file string, names []string) ([]string, error) {
+	cfgPath := filepath.Join(serverPath, "server.cfg")
+	if file != ResourcesFile {
+		return AppendEnsures(cfgPath, names)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resourcesPath := filepath.Join(serverPath, ResourcesFile)
+	resources := &Config{
+		Path:  resourcesPath,
+		Lines: []string{"# Resources started by server.cfg through 'exec " + ResourcesFile + "'"},
+		CRLF:  cfg.CRLF,
+	}
+	if _, err := os.Stat(resourcesPath); err == nil {
+		if resources, err = Load(resourcesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resources server.cfg already starts aren't started twice
+	var pending []string
+	for _, name := range names {
+		if !cfg.IsStarted(name, "") {
+			pending = append(pending, name)
+		}
+	}
+
+	added := resources.AddEnsures(pending)
+	if len(added) > 0 {
+		if err := resources.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !cfg.Execs(ResourcesFile) {
+		cfg.Lines = append(cfg.Lines, "exec "+ResourcesFile)
+		if err := cfg.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// Execs reports whether the config runs file, a path relative to the server
+// folder, with an exec line
+func (c *Config) Execs(file string) bool {
+	for _, line := range c.Lines {
+		command, args := splitCommand(line)
+		if command == "exec" && len(args) > 0 && strings.EqualFold(path.Clean(strings.ReplaceAll(args[0], `\`, "/")), file) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddEnsures adds "ensure <name>" lines, in order, for resources that aren't
+// already started. Returns the names that were actually added.
+func (c *Config) AddEnsures(names []string) []string {
+	started := make(map[string]bool)
+	for _, name := range c.StartedResources() {
+		started[name] = true
+	}
+
+	var added []string
+	for _, name := range names {
+		if name == "" || started[name] {
+			continue
+		}
+		started[name] = true
+		added = append(added, name)
+		c.Lines = append(c.Lines, "ensure "+name)
+	}
+	return added
+}
+
+// splitCommand splits a cfg line into its command and arguments,
+// ignoring comments and surrounding quotes
+func splitCommand(line string) (string, []string) {
+	fields, _ := tokenize(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return strings.ToLower(fields[0]), fields[1:]
+}
+
+// tokenize splits a cfg line into fields the way FXServer does: quoted
+// strings are one field, and # or // outside quotes starts a comment. It
+// also returns where the comment starts, or -1 if there is none.
+func tokenize(line string) ([]string, int) {
+	var fields []string
+	var field strings.Builder
+	inField, inQuotes := false, false
+
+	flush := func() {
+		if inField {
+			fields = append(fields, field.String())
+			field.Reset()
+			inField = false
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+			inField = true
+		case inQuotes:
+			field.WriteByte(ch)
+		case ch == ' ' || ch == '\t':
+			flush()
+		case !inField && (ch == '#' || strings.HasPrefix(line[i:], "//")):
+			return fields, i
+		default:
+			field.WriteByte(ch)
+			inField = true
+		}
+	}
+	flush()
+
+	return fields, -1
+}
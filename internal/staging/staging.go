@@ -0,0 +1,127 @@
+// Package staging replaces folders crash-safely. The new version is prepared
+// in a directory next to the destination and swapped in with renames, so an
+// interrupted install leaves the old version or the new one, never half of each.
+package staging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// backupPrefix names the copy of the old version kept while a swap is in progress
+const backupPrefix = ".inkwash-old-"
+
+// Dir creates an empty staging directory next to dest. Staging on the same
+// filesystem keeps Swap's renames atomic. Remove it when done; after a
+// successful Swap it no longer exists.
+func Dir(dest string) (string, error) {
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", parent, err)
+	}
+
+	dir, err := os.MkdirTemp(parent, ".inkwash-stage-"+filepath.Base(dest)+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Swap replaces dest with src. An existing dest is renamed aside first and
+// only deleted once src is in place; if src can't be moved in, dest is put back.
+func Swap(src, dest string) error {
+	return SwapKeep(src, dest, "")
+}
+
+// SwapKeep is Swap, except dest's old version is moved to keep instead of
+// being deleted. Nothing is kept when keep is "" or dest didn't exist. An
+// error moving the old version to keep is returned with src already in place.
+func SwapKeep(src, dest, keep string) error {
+	if err := Recover(dest); err != nil {
+		return err
+	}
+
+	backup := backupPath(dest)
+	hadOld := false
+	if _, err := os.Lstat(dest); err == nil {
+		if err := os.Rename(dest, backup); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", dest, err)
+		}
+		hadOld = true
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		if hadOld {
+			os.Rename(backup, dest)
+		}
+		return fmt.Errorf("failed to move new version into %s: %w", dest, err)
+	}
+
+	if hadOld && keep != "" {
+		if err := os.Rename(backup, keep); err != nil {
+			return fmt.Errorf("failed to keep the old version of %s: %w", dest, err)
+		}
+		return nil
+	}
+
+	// The new version is in place; a leftover backup is cleaned up by the next Recover
+	os.RemoveAll(backup)
+	return nil
+}
+
+// Recover finishes a Swap that was interrupted: if dest is missing but its
+// backup exists, the backup is restored; if both exist, the stale backup is removed.
+func Recover(dest string) error {
+	backup := backupPath(dest)
+	if _, err := os.Lstat(backup); err != nil {
+		return nil
+	}
+
+	if _, err := os.Lstat(dest); err == nil {
+		return os.RemoveAll(backup)
+	}
+
+	if err := os.Rename(backup, dest); err != nil {
+		return fmt.Errorf("failed to restore %s from an interrupted update: %w", dest, err)
+	}
+	return nil
+}
+
+// CheckTree checks that a staged folder looks like a complete install: it
+// must contain at least one non-empty file, plus every path in required
+func CheckTree(dir string, required ...string) error {
+	for _, rel := range required {
+		info, err := os.Stat(filepath.Join(dir, rel))
+		if err != nil {
+			return fmt.Errorf("staged files are missing %s", rel)
+		}
+		if info.Mode().IsRegular() && info.Size() == 0 {
+			return fmt.Errorf("staged file %s is empty", rel)
+		}
+	}
+
+	var total int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check staged files: %w", err)
+	}
+	if total == 0 {
+		return fmt.Errorf("staged files are empty")
+	}
+
+	return nil
+}
+
+// backupPath returns where Swap keeps dest's old version
+func backupPath(dest string) string {
+	return filepath.Join(filepath.Dir(dest), backupPrefix+filepath.Base(dest))
+}
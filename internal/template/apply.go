@@ -0,0 +1,412 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// builtinDependencies are manifest dependencies FXServer provides itself
+// rather than resources ("/server:5181", "/onesync", ...)
+var builtinDependencies = map[string]bool{
+	"server":     true,
+	"onesync":    true,
+	"policy":     true,
+	"gamebuild":  true,
+	"native":     true,
+	"assetpacks": true,
+}
+
+// Options controls how a template is applied
+type Options struct {
+	Force  bool              // Reinstall resources the server already has
+	OnStep func(step string) // Told what's happening, e.g. "Installing ox_lib"
+
+	// Database sets up the template's database with these options, named
+	// after the server unless given. When nil, its SQL files are only
+	// gathered, and listed in Result.SQLFiles for 'inkwash db setup'.
+	Database *database.Options
+}
+
+// Result describes what applying a template changed
+type Result struct {
+	Installed []string         // Resources downloaded into resources/
+	Existing  []string         // Resources the server already had, left as they were
+	Convars   []string         // Convars set in server.cfg
+	Ensured   []string         // ensure lines added to server.cfg, in start order
+	SQLFiles  []string         // The template's SQL files in the server folder, in order
+	Database  *database.Result // The database set up, nil without Options.Database
+}
+
+// MissingDependency is a resource another one needs that isn't available
+type MissingDependency struct {
+	Resource   string
+	Dependency string
+}
+
+// DependencyError lists dependencies that are neither on the server nor in the template
+type DependencyError struct {
+	Template string
+	Missing  []MissingDependency
+}
+
+func (e *DependencyError) Error() string {
+	lines := make([]string, len(e.Missing))
+	for i, m := range e.Missing {
+		lines[i] = fmt.Sprintf("'%s' depends on '%s'", m.Resource, m.Dependency)
+	}
+	return fmt.Sprintf("template '%s' has missing dependencies:\n  - %s\nAdd them to the template's resources, or install them with 'inkwash resource add'",
+		e.Template, strings.Join(lines, "\n  - "))
+}
+
+// Apply applies a template to a server. Requirements and resources without a
+// repository are checked before anything is downloaded, and dependencies
+// before server.cfg is touched, so a failed apply leaves at most some newly
+// installed resources behind. The database comes last, once server.cfg is
+// written; a failure there is returned as a DatabaseError. The
+// caller should hold the server's lock.
+func Apply(ctx context.Context, srv *types.Server, t *types.Template, opts Options) (*Result, error) {
+	step := func(name string) {
+		if opts.OnStep != nil {
+			opts.OnStep(name)
+		}
+	}
+
+	if err := Validate(t); err != nil {
+		return nil, err
+	}
+
+	step("Checking requirements")
+	if err := CheckRequirements(t, srv.Port); err != nil {
+		return nil, err
+	}
+
+	cfgPath := filepath.Join(srv.Path, "server.cfg")
+	cfg, err := servercfg.Load(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resourcesPath := filepath.Join(srv.Path, "resources")
+	onDisk := scanResources(resourcesPath)
+
+	var unavailable []string
+	for _, r := range t.Resources {
+		if _, ok := onDisk[r.Name]; !ok && r.Repository == "" {
+			unavailable = append(unavailable, r.Name)
+		}
+	}
+	if len(unavailable) > 0 {
+		return nil, fmt.Errorf("template '%s' needs %s, which the server doesn't have and the template has no repository for; give them a \"repository\" in the template or install them with 'inkwash resource add'",
+			t.Name, quoteList(unavailable))
+	}
+
+	// Resources from the same repository share one download
+	archives := resource.NewArchives()
+	defer archives.Close()
+
+	result := &Result{}
+	for _, r := range t.Resources {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if _, ok := onDisk[r.Name]; ok && !opts.Force {
+			result.Existing = append(result.Existing, r.Name)
+			continue
+		}
+		if r.Repository == "" {
+			// Forced, but there's nowhere to get it from again
+			result.Existing = append(result.Existing, r.Name)
+			continue
+		}
+
+		step("Installing " + r.Name)
+		if err := install(srv, r, resourcesPath, archives); err != nil {
+			return result, err
+		}
+		result.Installed = append(result.Installed, r.Name)
+	}
+
+	// Installs may have brought new manifests, and moved resources into categories
+	onDisk = scanResources(resourcesPath)
+
+	step("Resolving dependencies")
+	order, err := startOrder(t, onDisk)
+	if err != nil {
+		return result, err
+	}
+
+	step("Updating server.cfg")
+	for _, c := range t.Convars {
+		if err := cfg.Set(c.Name, c.Value); err != nil {
+			return result, err
+		}
+		result.Convars = append(result.Convars, c.Name)
+	}
+	result.Ensured = cfg.AddEnsures(order)
+
+	if err := cfg.Save(); err != nil {
+		return result, err
+	}
+
+	if t.Database == nil && opts.Database == nil {
+		return result, nil
+	}
+	if err := setupDatabase(ctx, srv, t, opts, result, step); err != nil {
+		return result, &DatabaseError{Template: t.Name, Server: srv.Name, SQLFiles: result.SQLFiles, Err: err}
+	}
+	return result, nil
+}
+
+// ApplyNew applies a template to a server the installer is setting up, as
+// server.InstallOptions.ApplyTemplate. Errors say how to retry.
+func ApplyNew(ctx context.Context, srv *types.Server, t *types.Template, db *database.Options, onStep func(step string)) ([]string, error) {
+	result, err := Apply(ctx, srv, t, Options{OnStep: onStep, Database: db})
+	var dbErr *DatabaseError
+	switch {
+	case errors.As(err, &dbErr):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("%w\nRetry with 'inkwash template apply %s %s'", err, srv.Name, t.Name)
+	case db != nil:
+		return nil, nil
+	}
+	return result.SQLFiles, nil
+}
+
+// DatabaseError is returned by Apply when everything but the template's
+// database was applied
+type DatabaseError struct {
+	Template string
+	Server   string
+	SQLFiles []string // The SQL files gathered before it failed
+	Err      error
+}
+
+func (e *DatabaseError) Error() string {
+	return fmt.Sprintf("template '%s' was applied, but its database wasn't set up: %v\nRetry with: %s",
+		e.Template, e.Err, database.SetupCommand(e.Server, e.SQLFiles))
+}
+
+func (e *DatabaseError) Unwrap() error {
+	return e.Err
+}
+
+// setupDatabase gathers the template's SQL files into the server folder and,
+// with Options.Database, creates the database, runs them and writes the
+// connection string to server.cfg. A database is created even for a
+// template that has none when Options.Database asks for one.
+func setupDatabase(ctx context.Context, srv *types.Server, t *types.Template, opts Options, result *Result, step func(string)) error {
+	var sqls []string
+	if t.Database != nil {
+		sqls = t.Database.SQL
+	}
+	for _, sql := range sqls {
+		file, err := sqlFile(srv, sql, step)
+		if err != nil {
+			return err
+		}
+		result.SQLFiles = append(result.SQLFiles, file)
+	}
+	if opts.Database == nil {
+		return nil
+	}
+
+	dbOpts := opts.Database.ForServer(srv.Name)
+	dbOpts.SQLFiles = append(dbOpts.SQLFiles, result.SQLFiles...)
+	db, err := database.Setup(ctx, dbOpts, step)
+	if err != nil {
+		return err
+	}
+	if err := database.WriteConnectionString(filepath.Join(srv.Path, "server.cfg"), db.ConnectionString); err != nil {
+		return err
+	}
+	result.Database = db
+	return nil
+}
+
+// sqlFile returns where one of a template's SQL files is in the server
+// folder, downloading it into sql/ first if it's a URL
+func sqlFile(srv *types.Server, sql string, step func(string)) (string, error) {
+	if !isURL(sql) {
+		file := filepath.Join(srv.Path, filepath.FromSlash(sql))
+		if _, err := os.Stat(file); err != nil {
+			return "", fmt.Errorf("SQL file %s isn't on the server; was the resource it comes with installed?", sql)
+		}
+		return file, nil
+	}
+
+	u, err := url.Parse(sql)
+	if err != nil {
+		return "", fmt.Errorf("invalid SQL URL %s: %w", sql, err)
+	}
+	name := path.Base(u.Path)
+	if name == "" || name == "/" || name == "." || strings.HasPrefix(name, ".") {
+		name = "template.sql"
+	}
+	file := filepath.Join(srv.Path, "sql", name)
+
+	step("Downloading " + name)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", fmt.Errorf("failed to create sql folder: %w", err)
+	}
+	if err := download.NewDownloader(1).Download(sql, file, nil); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", sql, err)
+	}
+	return file, nil
+}
+
+// scanResources returns the resources in resourcesPath by name; a missing
+// folder has none
+func scanResources(resourcesPath string) map[string]resource.Resource {
+	found := make(map[string]resource.Resource)
+	resources, _ := resource.Scan(resourcesPath)
+	for _, r := range resources {
+		found[r.Name] = r
+	}
+	return found
+}
+
+// install downloads a template resource and records where it came from, so
+// 'inkwash resource update' can fetch it again
+func install(srv *types.Server, r types.TemplateResource, resourcesPath string, archives *resource.Archives) error {
+	src, err := resource.ParseGitHubSource(r.Repository)
+	if err != nil {
+		return err
+	}
+	if r.Ref != "" {
+		src.Ref = r.Ref
+	}
+
+	dest := resourcesPath
+	if r.Category != "" {
+		dest = filepath.Join(resourcesPath, "["+r.Category+"]")
+	}
+
+	archiveURL, label, tag := src.ArchiveURL(), src.String(), ""
+	if r.Release {
+		found, err := resource.FindRelease(src)
+		if err != nil {
+			return fmt.Errorf("resource '%s': %w", r.Name, err)
+		}
+		archiveURL, label, tag = found.ArchiveURL, fmt.Sprintf("%s release %s", src.URL(), found.Tag), found.Tag
+	}
+	manifest, err := archives.Install(archiveURL, label, r.Path, dest, r.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	// Like 'inkwash resource add', a server without metadata.json just isn't tracked
+	metadataManager := server.NewMetadataManager()
+	metadata, err := metadataManager.Load(srv.Path)
+	if err != nil {
+		return nil
+	}
+	metadata.RecordResource(types.InstalledResource{
+		Name:        r.Name,
+		Repository:  src.URL(),
+		Ref:         src.Ref,
+		Release:     r.Release,
+		Tag:         tag,
+		Path:        r.Path,
+		Version:     manifest.Version,
+		InstalledAt: time.Now(),
+	})
+	if err := metadataManager.Save(srv.Path, metadata); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return nil
+}
+
+// startOrder returns the template's resources to ensure, each after the
+// resources it depends on. Dependencies that are neither on disk nor in the
+// template are reported together as a DependencyError; cycles are left in
+// template order, as FXServer resolves those itself.
+func startOrder(t *types.Template, onDisk map[string]resource.Resource) ([]string, error) {
+	inTemplate := make(map[string]types.TemplateResource)
+	for _, r := range t.Resources {
+		inTemplate[r.Name] = r
+	}
+
+	deps := make(map[string][]string)
+	var missing []MissingDependency
+	for _, r := range t.Resources {
+		found, ok := onDisk[r.Name]
+		if !ok || found.Manifest == "" {
+			continue
+		}
+		manifest, err := resource.ParseManifest(found.Manifest)
+		if err != nil {
+			continue
+		}
+
+		for _, dep := range manifest.Dependencies {
+			if isBuiltinDependency(dep) {
+				continue
+			}
+			if _, ok := onDisk[dep]; !ok {
+				missing = append(missing, MissingDependency{Resource: r.Name, Dependency: dep})
+				continue
+			}
+			if _, ok := inTemplate[dep]; ok {
+				deps[r.Name] = append(deps[r.Name], dep)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &DependencyError{Template: t.Name, Missing: missing}
+	}
+
+	var order []string
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+	var visit func(name string)
+	visit = func(name string) {
+		if state[name] != 0 {
+			return
+		}
+		state[name] = 1
+		for _, dep := range deps[name] {
+			visit(dep)
+		}
+		state[name] = 2
+		if !inTemplate[name].NoEnsure {
+			order = append(order, name)
+		}
+	}
+	for _, r := range t.Resources {
+		visit(r.Name)
+	}
+	return order, nil
+}
+
+// isBuiltinDependency reports whether a manifest dependency is provided by
+// FXServer or is a version constraint rather than a resource
+func isBuiltinDependency(dep string) bool {
+	name, _, constrained := strings.Cut(strings.TrimPrefix(dep, "/"), ":")
+	return constrained || builtinDependencies[strings.ToLower(name)]
+}
+
+// quoteList formats names as 'a', 'b' and 'c'
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
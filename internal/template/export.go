@@ -0,0 +1,98 @@
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/VexoaXYZ/inkwash/internal/config"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Export builds a template from a server's server.cfg and resources/ folder.
+// Resources keep the order server.cfg starts them in, followed by the ones it
+// doesn't start (exported with no_ensure); those installed with 'inkwash
+// resource add' keep their repository, so applying the template elsewhere
+// downloads them. omitted lists the convars left out: secrets such as
+// sv_licenseKey and rcon_password, and values that don't validate.
+func Export(srv *types.Server, name string) (t *types.Template, omitted []string, err error) {
+	cfg, err := servercfg.Load(filepath.Join(srv.Path, "server.cfg"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resources, err := resource.Scan(filepath.Join(srv.Path, "resources"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Where each resource is started, by name or by its [category]
+	position := make(map[string]int)
+	for i, started := range cfg.StartedResources() {
+		if _, ok := position[started]; !ok {
+			position[started] = i
+		}
+	}
+	startedAt := func(r resource.Resource) (int, bool) {
+		if i, ok := position[r.Name]; ok {
+			return i, true
+		}
+		if r.Category == "" {
+			return 0, false
+		}
+		i, ok := position["["+r.Category+"]"]
+		return i, ok
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, aStarted := startedAt(resources[i])
+		b, bStarted := startedAt(resources[j])
+		if aStarted != bStarted {
+			return aStarted
+		}
+		return aStarted && a < b
+	})
+
+	// Without metadata.json nothing's known about where resources came from
+	var metadata *types.ServerMetadata
+	if m, err := server.NewMetadataManager().Load(srv.Path); err == nil {
+		metadata = m
+	}
+
+	t = &types.Template{
+		Name:        name,
+		Description: fmt.Sprintf("Exported from server '%s'", srv.Name),
+	}
+	for _, r := range resources {
+		_, started := startedAt(r)
+		exported := types.TemplateResource{
+			Name:     r.Name,
+			Category: r.Category,
+			NoEnsure: !started,
+		}
+		if metadata != nil {
+			if installed := metadata.FindResource(r.Name); installed != nil {
+				exported.Repository = installed.Repository
+				exported.Ref = installed.Ref
+				exported.Release = installed.Release
+				exported.Path = installed.Path
+			}
+		}
+		t.Resources = append(t.Resources, exported)
+	}
+
+	for _, setting := range cfg.Settings() {
+		if config.IsSensitive(setting.Name) || servercfg.ValidateConvar(setting.Name, setting.Value) != nil {
+			omitted = append(omitted, setting.Name)
+			continue
+		}
+		t.Convars = append(t.Convars, types.TemplateConvar{Name: setting.Name, Value: setting.Value})
+	}
+
+	if err := Validate(t); err != nil {
+		return nil, nil, err
+	}
+	return t, omitted, nil
+}
@@ -0,0 +1,56 @@
+package template
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// presetFiles are the built-in framework templates. A saved template with
+// the same name takes their place.
+//
+//go:embed presets/*.json
+var presetFiles embed.FS
+
+// Preset returns the built-in template called name
+func Preset(name string) (*types.Template, bool) {
+	data, err := presetFiles.ReadFile("presets/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+
+	var t types.Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		panic(fmt.Sprintf("built-in template %s: %v", name, err))
+	}
+	return &t, true
+}
+
+// Presets returns the built-in templates sorted by name
+func Presets() []types.Template {
+	entries, _ := presetFiles.ReadDir("presets")
+
+	var templates []types.Template
+	for _, entry := range entries {
+		if t, ok := Preset(strings.TrimSuffix(entry.Name(), ".json")); ok {
+			templates = append(templates, *t)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// IsPreset reports whether name finds a built-in template rather than a
+// saved one
+func IsPreset(name string) bool {
+	if _, ok := Preset(name); !ok {
+		return false
+	}
+	_, err := os.Stat(Path(name))
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,68 @@
+package template
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// RequirementsError lists what the host is missing to run a template
+type RequirementsError struct {
+	Template string
+	Problems []string
+}
+
+func (e *RequirementsError) Error() string {
+	return fmt.Sprintf("this host doesn't meet the requirements of template '%s':\n  - %s",
+		e.Template, strings.Join(e.Problems, "\n  - "))
+}
+
+// CheckRequirements checks a template's requirements against this host.
+// ownPorts are ports the server itself uses, which don't need to be free.
+func CheckRequirements(t *types.Template, ownPorts ...int) error {
+	req := t.Requirements
+	var problems []string
+
+	if req.MinRAMGB > 0 {
+		if v, err := mem.VirtualMemory(); err == nil {
+			total := float64(v.Total) / (1024 * 1024 * 1024)
+			if total < req.MinRAMGB {
+				problems = append(problems, fmt.Sprintf("at least %.1f GB of RAM (this host has %.1f GB)", req.MinRAMGB, total))
+			}
+		}
+	}
+
+	if req.MinCPUs > 0 && runtime.NumCPU() < req.MinCPUs {
+		problems = append(problems, fmt.Sprintf("at least %d CPUs (this host has %d)", req.MinCPUs, runtime.NumCPU()))
+	}
+
+	for _, port := range req.Ports {
+		if slices.Contains(ownPorts, port) {
+			continue
+		}
+		if !portFree(port) {
+			problems = append(problems, fmt.Sprintf("port %d free, but something is already listening on it (stop it, or change the port the template's resources use)", port))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &RequirementsError{Template: t.Name, Problems: problems}
+	}
+	return nil
+}
+
+// portFree reports whether nothing is listening on a TCP port
+func portFree(port int) bool {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
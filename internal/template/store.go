@@ -0,0 +1,132 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrTemplateNotFound is returned when no template is saved under a name
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrTemplateExists is returned when saving over a template without force
+var ErrTemplateExists = errors.New("template already exists")
+
+// Dir returns the folder saved templates live in, one <name>.json each
+func Dir() string {
+	return filepath.Join(registry.GetDefaultConfigPath(), "templates")
+}
+
+// Path returns where the template called name is saved
+func Path(name string) string {
+	return filepath.Join(Dir(), name+".json")
+}
+
+// Save writes a template to the template folder under its name, replacing
+// one that's already there only if force is set
+func Save(t *types.Template, force bool) (string, error) {
+	if err := validation.ValidateTemplateName(t.Name); err != nil {
+		return "", err
+	}
+	if err := Validate(t); err != nil {
+		return "", err
+	}
+
+	path := Path(t.Name)
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%w: '%s' (use --force to replace it)", ErrTemplateExists, t.Name)
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode template: %w", err)
+	}
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create template folder: %w", err)
+	}
+
+	// Written aside and renamed, so a crash can't leave half a template
+	tmp, err := os.CreateTemp(Dir(), "."+t.Name+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save template: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to save template: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to save template: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save template: %w", err)
+	}
+	return path, nil
+}
+
+// Find returns the saved template called name, or the built-in one (esx,
+// qbcore) if none is saved under it. A name ending in .json, or containing a
+// path separator, is read as a file instead.
+func Find(name string) (*types.Template, error) {
+	if strings.HasSuffix(name, ".json") || strings.ContainsAny(name, `/\`) {
+		return Load(name)
+	}
+
+	if err := validation.ValidateTemplateName(name); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(Path(name)); os.IsNotExist(err) {
+		if t, ok := Preset(name); ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("%w: '%s' (see 'inkwash template list')", ErrTemplateNotFound, name)
+	}
+	return Load(Path(name))
+}
+
+// List returns the saved templates and the built-in ones no saved template
+// replaces, sorted by name. Files that can't be read are returned as errors
+// alongside the ones that could.
+func List() ([]types.Template, []error) {
+	var templates []types.Template
+	for _, t := range Presets() {
+		if IsPreset(t.Name) {
+			templates = append(templates, t)
+		}
+	}
+
+	entries, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return templates, nil
+	}
+	if err != nil {
+		return templates, []error{fmt.Errorf("failed to read template folder: %w", err)}
+	}
+
+	var problems []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		t, err := Load(filepath.Join(Dir(), name))
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		templates = append(templates, *t)
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, problems
+}
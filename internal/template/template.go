@@ -0,0 +1,105 @@
+// Package template applies server templates: it checks a template's
+// requirements against the host, installs its resources, works out the order
+// they must start in from their manifests, and writes its convars and ensure
+// lines into server.cfg.
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// Load reads a template from a JSON file and checks it
+func Load(path string) (*types.Template, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	var t types.Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", filepath.Base(path), err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if err := Validate(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks a template's resources and convars without touching the
+// network or any server
+func Validate(t *types.Template) error {
+	seen := make(map[string]bool)
+	for _, r := range t.Resources {
+		if r.Name == "" || r.Name != filepath.Base(r.Name) || strings.HasPrefix(r.Name, ".") || resource.IsCategory(r.Name) {
+			return fmt.Errorf("template '%s': invalid resource name %q", t.Name, r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("template '%s': resource '%s' is listed twice", t.Name, r.Name)
+		}
+		seen[r.Name] = true
+
+		if r.Category != "" && (r.Category != filepath.Base(r.Category) || strings.ContainsAny(r.Category, "[]")) {
+			return fmt.Errorf("template '%s': invalid category %q for '%s' (give it without brackets)", t.Name, r.Category, r.Name)
+		}
+		if r.Repository != "" {
+			if _, err := resource.ParseGitHubSource(r.Repository); err != nil {
+				return fmt.Errorf("template '%s': resource '%s': %w", t.Name, r.Name, err)
+			}
+		}
+		if r.Path != "" && (r.Repository == "" || !isRelative(r.Path)) {
+			return fmt.Errorf("template '%s': invalid path %q for '%s' (give a folder in its repository)", t.Name, r.Path, r.Name)
+		}
+	}
+
+	for _, c := range t.Convars {
+		if c.Name == "" || strings.ContainsAny(c.Name, " \t\"") {
+			return fmt.Errorf("template '%s': invalid convar name %q", t.Name, c.Name)
+		}
+		if err := servercfg.ValidateConvar(c.Name, c.Value); err != nil {
+			return fmt.Errorf("template '%s': %w", t.Name, err)
+		}
+	}
+
+	if t.Database != nil {
+		for _, sql := range t.Database.SQL {
+			if !isURL(sql) && !isRelative(sql) {
+				return fmt.Errorf("template '%s': invalid SQL file %q (give a path in the server folder or an http(s) URL)", t.Name, sql)
+			}
+		}
+	}
+
+	for _, port := range t.Requirements.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("template '%s': port must be between 1 and 65535 (got %d)", t.Name, port)
+		}
+	}
+	return nil
+}
+
+// isRelative reports whether path stays inside the folder it's relative to
+func isRelative(path string) bool {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) {
+		return false
+	}
+	clean := filepath.Clean(filepath.FromSlash(path))
+	return clean != "." && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// isURL reports whether s is an http(s) URL
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
@@ -0,0 +1,115 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Confirm is a yes/no modal shown before destructive actions.
+// It defaults to "No" so an accidental Enter never confirms.
+type Confirm struct {
+	Title     string
+	Message   string
+	YesLabel  string
+	NoLabel   string
+	Yes       bool // Whether "Yes" is highlighted
+	Answered  bool
+	Accepted  bool
+	Focused   bool
+	Dangerous bool // Highlight "Yes" in the error color
+}
+
+// NewConfirm creates a focused confirmation modal
+func NewConfirm(title, message string) *Confirm {
+	return &Confirm{
+		Title:    title,
+		Message:  message,
+		YesLabel: "Yes",
+		NoLabel:  "No",
+		Focused:  true,
+	}
+}
+
+// Update handles keyboard input
+func (c *Confirm) Update(msg tea.Msg) tea.Cmd {
+	if !c.Focused || c.Answered {
+		return nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "left", "right", "h", "l", "tab", "shift+tab":
+			c.Yes = !c.Yes
+
+		case "y", "Y":
+			c.answer(true)
+
+		case "n", "N", "esc", "q":
+			c.answer(false)
+
+		case "enter":
+			c.answer(c.Yes)
+		}
+	}
+
+	return nil
+}
+
+// answer records the user's choice
+func (c *Confirm) answer(accepted bool) {
+	c.Answered = true
+	c.Accepted = accepted
+}
+
+// View renders the modal
+func (c *Confirm) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorPureWhite).
+		Bold(true)
+
+	b.WriteString(titleStyle.Render(c.Title))
+	b.WriteString("\n\n")
+
+	if c.Message != "" {
+		b.WriteString(ui.StyleText.Render(c.Message))
+		b.WriteString("\n\n")
+	}
+
+	yesColor := ui.ColorPrimary
+	if c.Dangerous {
+		yesColor = ui.ColorError
+	}
+
+	button := func(label string, active bool, color lipgloss.Color) string {
+		style := lipgloss.NewStyle().Padding(0, 2)
+		if active {
+			return style.Foreground(ui.ColorPureWhite).Background(color).Bold(true).Render(label)
+		}
+		return style.Foreground(ui.ColorMediumGray).Render(label)
+	}
+
+	b.WriteString(button(c.NoLabel, !c.Yes, ui.ColorPrimary))
+	b.WriteString("  ")
+	b.WriteString(button(c.YesLabel, c.Yes, yesColor))
+	b.WriteString("\n\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorMediumGray).
+		Italic(true)
+	b.WriteString(helpStyle.Render("←/→: Choose  •  Enter: Confirm  •  y/n: Answer"))
+
+	return ui.StyleBoxAccent.Render(b.String())
+}
+
+// Reset clears the answer so the modal can be shown again
+func (c *Confirm) Reset() {
+	c.Yes = false
+	c.Answered = false
+	c.Accepted = false
+}
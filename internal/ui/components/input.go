@@ -18,6 +18,7 @@ type TextInput struct {
 	Focused      bool
 	Error        string
 	Validator    func(string) error
+	Masked       bool // Show the value as asterisks, for passwords
 	cursor       int
 	showCursor   bool
 	clearOnFocus bool // Clear value on first keypress after focus
@@ -75,6 +76,13 @@ func (t *TextInput) Clear() {
 	t.Error = ""
 }
 
+// SetValue replaces the value and moves the cursor to its end
+func (t *TextInput) SetValue(value string) {
+	t.Value = value
+	t.cursor = len(value)
+	t.clearOnFocus = false
+}
+
 // Update handles key input and cursor blinking
 func (t *TextInput) Update(msg tea.Msg) tea.Cmd {
 	if !t.Focused {
@@ -173,6 +181,9 @@ func (t *TextInput) View() string {
 
 	// Prepare input text
 	displayText := t.Value
+	if t.Masked {
+		displayText = strings.Repeat("*", len(t.Value))
+	}
 	if displayText == "" && !t.Focused {
 		displayText = t.Placeholder
 	}
@@ -0,0 +1,299 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/rcon"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/internal/ui/components"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pollInterval is how often the log is checked for new lines
+const pollInterval = 250 * time.Millisecond
+
+// maxScrollback is how many lines the console keeps
+const maxScrollback = 2000
+
+// initialLines is how much of the log is shown when the console opens
+const initialLines = 200
+
+// maxHistory is how many sent commands can be recalled with Up
+const maxHistory = 100
+
+// Model is an attached server console: the live log with a command line
+// that sends commands over RCON
+type Model struct {
+	server *types.Server
+	client *rcon.Client // nil when RCON isn't available
+	noRCON string       // Why commands can't be sent, if client is nil
+
+	follower *server.LogFollower
+	lines    []string
+	scroll   int // Lines scrolled up from the bottom
+
+	input      *components.TextInput
+	history    []string
+	historyPos int // Index into history while recalling, len(history) otherwise
+	sending    bool
+
+	width  int
+	height int
+}
+
+// pollMsg triggers a check for new log lines
+type pollMsg time.Time
+
+// responseMsg carries the server's answer to a command
+type responseMsg struct {
+	output string
+	err    error
+}
+
+// New creates a console for srv. client may be nil, in which case the log is
+// still streamed and noRCON explains why commands can't be sent.
+func New(srv *types.Server, client *rcon.Client, noRCON string) *Model {
+	input := components.NewTextInput("", "Type a command, e.g. status", 0)
+	if client != nil {
+		input.Focus()
+	}
+
+	m := &Model{
+		server:   srv,
+		client:   client,
+		noRCON:   noRCON,
+		follower: server.NewLogFollower(srv.Path),
+		input:    input,
+	}
+
+	// The follower starts at the end of the log, so the history is read separately
+	if history, err := server.TailLog(srv.Path, initialLines); err == nil {
+		m.lines = history
+	}
+	return m
+}
+
+// Init starts polling the log
+func (m *Model) Init() tea.Cmd {
+	if m.client != nil {
+		return tea.Batch(pollCmd(), m.input.BlinkCmd())
+	}
+	return pollCmd()
+}
+
+// Update handles messages
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case pollMsg:
+		if lines, err := m.follower.Poll(); err != nil {
+			m.appendLines(ui.RenderError(err.Error()))
+		} else {
+			m.appendLines(lines...)
+		}
+		return m, pollCmd()
+
+	case responseMsg:
+		m.sending = false
+		if msg.err != nil {
+			m.appendLines(ui.RenderError(msg.err.Error()))
+			return m, nil
+		}
+		output := strings.TrimRight(msg.output, "\n")
+		if output != "" {
+			m.appendLines(strings.Split(output, "\n")...)
+		}
+		return m, nil
+
+	case components.CursorBlinkMsg:
+		return m, m.input.Update(msg)
+	}
+
+	return m, nil
+}
+
+// handleKey handles keyboard input
+func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m, tea.Quit
+	case "pgup":
+		m.scrollBy(m.logHeight())
+		return m, nil
+	case "pgdown":
+		m.scrollBy(-m.logHeight())
+		return m, nil
+	case "ctrl+home":
+		m.scrollBy(len(m.lines))
+		return m, nil
+	case "ctrl+end":
+		m.scroll = 0
+		return m, nil
+	}
+
+	if m.client == nil {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up":
+		if m.historyPos > 0 {
+			m.historyPos--
+			m.input.SetValue(m.history[m.historyPos])
+		}
+		return m, nil
+	case "down":
+		if m.historyPos < len(m.history) {
+			m.historyPos++
+		}
+		if m.historyPos < len(m.history) {
+			m.input.SetValue(m.history[m.historyPos])
+		} else {
+			m.input.SetValue("")
+		}
+		return m, nil
+	case "enter":
+		return m, m.send()
+	}
+
+	return m, m.input.Update(msg)
+}
+
+// send runs the typed command over RCON
+func (m *Model) send() tea.Cmd {
+	command := strings.TrimSpace(m.input.Value)
+	if command == "" || m.sending {
+		return nil
+	}
+
+	m.input.Clear()
+	if len(m.history) == 0 || m.history[len(m.history)-1] != command {
+		m.history = append(m.history, command)
+		if len(m.history) > maxHistory {
+			m.history = m.history[1:]
+		}
+	}
+	m.historyPos = len(m.history)
+
+	m.appendLines(ui.RenderAccent("> " + command))
+	m.scroll = 0
+	m.sending = true
+
+	client := m.client
+	return func() tea.Msg {
+		output, err := client.Exec(command)
+		return responseMsg{output: output, err: err}
+	}
+}
+
+// appendLines adds lines to the scrollback, dropping the oldest past maxScrollback
+func (m *Model) appendLines(lines ...string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	m.lines = append(m.lines, lines...)
+	if len(m.lines) > maxScrollback {
+		m.lines = m.lines[len(m.lines)-maxScrollback:]
+	}
+
+	// Keep the view still while scrolled up
+	if m.scroll > 0 {
+		m.scrollBy(len(lines))
+	}
+}
+
+// scrollBy moves the view up (positive) or down (negative) by n lines
+func (m *Model) scrollBy(n int) {
+	m.scroll += n
+	if limit := len(m.lines) - m.logHeight(); m.scroll > limit {
+		m.scroll = limit
+	}
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+}
+
+// logHeight is how many log lines fit above the command line
+func (m *Model) logHeight() int {
+	// Leave room for the title, the command line box and help
+	height := m.height - 8
+	if height < 5 {
+		height = 5
+	}
+	return height
+}
+
+// View renders the console
+func (m *Model) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorPureWhite).
+		Background(ui.ColorPrimary).
+		Bold(true).
+		Padding(0, 2).
+		Width(m.width)
+
+	title := "Console: " + m.server.Name
+	if m.client != nil {
+		title += "  (RCON " + m.client.Address() + ")"
+	}
+	b.WriteString(titleStyle.Render(title))
+	b.WriteString("\n\n")
+
+	// Long lines are cut off so the command line stays put
+	lineStyle := lipgloss.NewStyle()
+	if m.width > 0 {
+		lineStyle = lineStyle.MaxWidth(m.width)
+	}
+
+	height := m.logHeight()
+	end := len(m.lines) - m.scroll
+	start := end - height
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < end; i++ {
+		b.WriteString(lineStyle.Render(m.lines[i]))
+		b.WriteString("\n")
+	}
+	for i := end - start; i < height; i++ {
+		b.WriteString("\n")
+	}
+
+	if m.client == nil {
+		b.WriteString(ui.RenderWarning(ui.SymbolWarning + " Read-only: " + m.noRCON))
+		b.WriteString("\n\n")
+		b.WriteString(ui.RenderHelp("PgUp/PgDn: Scroll  •  Esc: Detach"))
+		return b.String()
+	}
+
+	b.WriteString(m.input.View())
+	b.WriteString("\n")
+
+	help := "Enter: Send  •  ↑/↓: History  •  PgUp/PgDn: Scroll  •  Esc: Detach"
+	if m.scroll > 0 {
+		help = fmt.Sprintf("Scrolled up %d lines  •  Ctrl+End: Back to live  •  ", m.scroll) + help
+	}
+	b.WriteString(ui.RenderHelp(help))
+	return b.String()
+}
+
+func pollCmd() tea.Cmd {
+	return tea.Tick(pollInterval, func(t time.Time) tea.Msg {
+		return pollMsg(t)
+	})
+}
@@ -0,0 +1,736 @@
+package dashboard
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/ui"
+	"github.com/VexoaXYZ/inkwash/internal/ui/components"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// refreshInterval is how often server status and metrics are redrawn
+const refreshInterval = time.Second
+
+// maxLogLines is how much of the log is kept for the log view
+const maxLogLines = 500
+
+// View is the screen the dashboard is showing
+type View int
+
+const (
+	ViewServers View = iota
+	ViewLogs
+	ViewInfo
+)
+
+// Model is the interactive dashboard listing servers with live status
+type Model struct {
+	registry *registry.Registry
+	cache    *cache.BinaryCache
+	pm       *server.ProcessManager
+	metrics  *server.MetricsCollector
+
+	selector *components.Selector
+	spinner  *components.Spinner
+	servers  []types.Server
+
+	view     View
+	logLines []string
+	logErr   string
+	metadata *types.ServerMetadata
+
+	confirm       *components.Confirm // Open confirmation modal, nil if none
+	pendingAction string              // Action to run once the modal is accepted
+	pendingServer string
+
+	busy      map[string]string // Server name -> action in progress ("" for the build cache)
+	message   string            // Result of the last action
+	messageOK bool
+
+	width    int
+	height   int
+	quitting bool
+}
+
+// tickMsg triggers a status refresh
+type tickMsg time.Time
+
+// actionDoneMsg reports the result of an action on a server (or the build cache, with no name)
+type actionDoneMsg struct {
+	name   string
+	action string
+	err    error
+}
+
+// trackedMsg reports that a server's metrics are being collected
+type trackedMsg struct{}
+
+// New creates a dashboard for the servers in reg. binaryCache may be nil,
+// which disables clearing the build cache.
+func New(reg *registry.Registry, binaryCache *cache.BinaryCache) *Model {
+	selector := components.NewSelector("Servers", nil)
+	selector.MaxHeight = 10
+	selector.Focus()
+
+	m := &Model{
+		registry: reg,
+		cache:    binaryCache,
+		pm:       server.NewProcessManager(),
+		metrics:  server.NewMetricsCollector(2 * time.Second),
+		selector: selector,
+		spinner:  components.NewSpinner(ui.DetectAnimationTier()),
+		busy:     make(map[string]string),
+	}
+	m.refresh()
+	return m
+}
+
+// Init starts metrics collection and the refresh loop
+func (m *Model) Init() tea.Cmd {
+	m.metrics.Start()
+	return tea.Batch(tickCmd(), m.trackRunningCmd(), m.spinner.TickCmd())
+}
+
+// Update handles messages
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tickMsg:
+		m.refresh()
+		if m.view == ViewLogs {
+			m.loadLogs()
+		}
+		return m, tea.Batch(tickCmd(), m.trackRunningCmd())
+
+	case actionDoneMsg:
+		delete(m.busy, msg.name)
+		target := "the build cache"
+		if msg.name != "" {
+			target = "'" + msg.name + "'"
+		}
+		if msg.err != nil {
+			m.message = fmt.Sprintf("Failed to %s %s: %v", msg.action, target, msg.err)
+			m.messageOK = false
+		} else {
+			m.message = fmt.Sprintf("%s %s", pastTense(msg.action), target)
+			m.messageOK = true
+		}
+		m.refresh()
+		return m, m.trackRunningCmd()
+
+	case trackedMsg:
+		return m, nil
+
+	case components.SpinnerTickMsg:
+		m.spinner.Tick()
+		return m, m.spinner.TickCmd()
+	}
+
+	return m, nil
+}
+
+// handleKey handles keyboard input for the current view
+func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if key == "ctrl+c" {
+		return m.quit()
+	}
+
+	// An open modal takes all input until it's answered
+	if m.confirm != nil {
+		m.confirm.Update(msg)
+		if !m.confirm.Answered {
+			return m, nil
+		}
+
+		accepted, action, name := m.confirm.Accepted, m.pendingAction, m.pendingServer
+		m.confirm, m.pendingAction, m.pendingServer = nil, "", ""
+		if !accepted {
+			return m, nil
+		}
+		return m, m.runAction(action, name)
+	}
+
+	// Log and info views only need a way back
+	if m.view != ViewServers {
+		switch key {
+		case "esc", "q", "backspace":
+			m.view = ViewServers
+		case "l":
+			if m.view == ViewLogs {
+				m.view = ViewServers
+			}
+		case "i":
+			if m.view == ViewInfo {
+				m.view = ViewServers
+			}
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "q", "esc":
+		return m.quit()
+	case "s":
+		if srv := m.selectedServer(); srv != nil {
+			return m, m.runAction("start", srv.Name)
+		}
+		return m, nil
+	case "x", "r", "d":
+		if srv := m.selectedServer(); srv != nil {
+			m.askConfirm(map[string]string{"x": "stop", "r": "restart", "d": "remove"}[key], srv.Name)
+		}
+		return m, nil
+	case "c":
+		if m.cache != nil {
+			m.askConfirm("clear", "")
+		}
+		return m, nil
+	case "l":
+		if srv := m.selectedServer(); srv != nil {
+			m.view = ViewLogs
+			m.loadLogs()
+		}
+		return m, nil
+	case "i", "enter":
+		if srv := m.selectedServer(); srv != nil {
+			m.view = ViewInfo
+			m.loadMetadata()
+		}
+		return m, nil
+	}
+
+	return m, m.selector.Update(msg)
+}
+
+// quit stops metrics collection and exits
+func (m *Model) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	m.metrics.Stop()
+	return m, tea.Quit
+}
+
+// refresh reloads the registry and rebuilds the server list, keeping the selection
+func (m *Model) refresh() {
+	var selectedName string
+	if srv := m.selectedServer(); srv != nil {
+		selectedName = srv.Name
+	}
+
+	m.registry.Reload()
+	m.servers = m.registry.List()
+
+	items := make([]components.SelectorItem, len(m.servers))
+	selected := 0
+	for i := range m.servers {
+		srv := &m.servers[i]
+		if srv.Name == selectedName {
+			selected = i
+		}
+
+		items[i] = components.SelectorItem{
+			Label:       m.serverLabel(srv),
+			Description: fmt.Sprintf("%s  •  %s", srv.Endpoint(), srv.Path),
+			Value:       srv.Name,
+		}
+	}
+
+	m.selector.Items = items
+	m.selector.Selected = selected
+}
+
+// serverLabel renders one row of the server list
+func (m *Model) serverLabel(srv *types.Server) string {
+	status := "Stopped"
+	symbol := ui.SymbolStopped
+
+	if action, ok := m.busy[srv.Name]; ok {
+		status = progressive(action) + "..."
+		symbol = ui.SymbolDot
+	} else if m.pm.IsRunning(srv) {
+		status = "Running"
+		symbol = ui.SymbolRunning
+
+		if snapshot, ok := m.metrics.Snapshot(srv.Name); ok {
+			if snapshot.StatsUnavailable {
+				status += "  stats unavailable"
+			} else {
+				status += fmt.Sprintf("  CPU %5.1f%%  RAM %.2f GB", snapshot.CurrentCPU(), snapshot.CurrentRAM())
+			}
+			if snapshot.PlayersKnown {
+				status += "  Players " + playersLabel(snapshot)
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s %-24s %s", symbol, srv.Name, status)
+}
+
+// playersLabel formats a server's player count, with the limit if it's known
+func playersLabel(metrics types.ServerMetrics) string {
+	if metrics.MaxPlayers > 0 {
+		return fmt.Sprintf("%d/%d", metrics.PlayerCount, metrics.MaxPlayers)
+	}
+	return fmt.Sprint(metrics.PlayerCount)
+}
+
+// selectedServer returns the highlighted server, or nil if there are none
+func (m *Model) selectedServer() *types.Server {
+	name, ok := m.selector.SelectedValue().(string)
+	if !ok {
+		return nil
+	}
+
+	for i := range m.servers {
+		if m.servers[i].Name == name {
+			return &m.servers[i]
+		}
+	}
+	return nil
+}
+
+// askConfirm opens the confirmation modal for a destructive action
+func (m *Model) askConfirm(action, name string) {
+	var title, message string
+	switch action {
+	case "stop":
+		title = fmt.Sprintf("Stop '%s'?", name)
+		message = "Connected players will be disconnected."
+	case "restart":
+		title = fmt.Sprintf("Restart '%s'?", name)
+		message = "Connected players will be disconnected."
+	case "remove":
+		title = fmt.Sprintf("Remove '%s' from InkWash?", name)
+		message = "The server is unregistered; its folder is kept on disk."
+	case "clear":
+		title = "Clear the build cache?"
+		message = fmt.Sprintf("%d cached FXServer build(s) will be deleted and downloaded again when needed.", len(m.cache.List()))
+	}
+
+	m.confirm = components.NewConfirm(title, message)
+	m.confirm.Dangerous = action == "remove" || action == "clear"
+	m.pendingAction = action
+	m.pendingServer = name
+}
+
+// runAction runs an action on a server (or the build cache, for "clear")
+// in the background; the result arrives as an actionDoneMsg
+func (m *Model) runAction(action, name string) tea.Cmd {
+	if _, ok := m.busy[name]; ok {
+		return nil
+	}
+
+	if action == "clear" {
+		m.busy[""] = action
+		m.message = ""
+		binaryCache := m.cache
+		return func() tea.Msg {
+			return actionDoneMsg{action: action, err: binaryCache.Clear()}
+		}
+	}
+
+	var srv types.Server
+	found := false
+	for _, candidate := range m.servers {
+		if candidate.Name == name {
+			srv, found = candidate, true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	m.busy[srv.Name] = action
+	m.message = ""
+	m.refresh()
+
+	reg, pm, metrics := m.registry, m.pm, m.metrics
+	return func() tea.Msg {
+		var err error
+		switch action {
+		case "start":
+			if pm.IsRunning(&srv) {
+				err = fmt.Errorf("already running (PID: %d)", srv.PID)
+			} else {
+				if srv.PID != 0 {
+					// Left behind by a process that exited on its own
+					server.RecordCrash(&srv)
+					srv.PID = 0
+				}
+				err = pm.Start(&srv)
+			}
+		case "stop":
+			if !pm.IsRunning(&srv) {
+				err = fmt.Errorf("not running")
+			} else {
+				err = pm.Stop(&srv)
+			}
+		case "restart":
+			err = pm.Restart(&srv)
+		case "remove":
+			switch {
+			case pm.IsRunning(&srv):
+				err = fmt.Errorf("it's running, stop it first")
+			case server.IsLocked(srv.Path):
+				err = fmt.Errorf("%w: another inkwash command is modifying it", server.ErrServerBusy)
+			default:
+				metrics.Untrack(srv.Name)
+				err = reg.Remove(srv.Name)
+			}
+			return actionDoneMsg{name: srv.Name, action: action, err: err}
+		}
+
+		if err == nil {
+			// The next tick resumes tracking with the new PID
+			metrics.Pause(srv.Name)
+			err = reg.Update(srv)
+		}
+
+		return actionDoneMsg{name: srv.Name, action: action, err: err}
+	}
+}
+
+// trackRunningCmd starts collecting metrics for running servers that aren't tracked yet.
+// Tracking primes a CPU baseline (a short sleep), so it runs off the UI goroutine.
+func (m *Model) trackRunningCmd() tea.Cmd {
+	var untracked []types.Server
+	for _, srv := range m.servers {
+		if !m.pm.IsRunning(&srv) {
+			m.metrics.Pause(srv.Name)
+			continue
+		}
+		if snapshot, ok := m.metrics.Snapshot(srv.Name); !ok || snapshot.Paused || snapshot.PID != srv.PID {
+			untracked = append(untracked, srv)
+		}
+	}
+
+	if len(untracked) == 0 {
+		return nil
+	}
+
+	metrics := m.metrics
+	return func() tea.Msg {
+		for i := range untracked {
+			metrics.Track(&untracked[i])
+		}
+		return trackedMsg{}
+	}
+}
+
+// loadLogs reads the tail of the selected server's log
+func (m *Model) loadLogs() {
+	m.logLines, m.logErr = nil, ""
+
+	srv := m.selectedServer()
+	if srv == nil {
+		return
+	}
+
+	lines, err := server.TailLog(srv.Path, maxLogLines)
+	if err != nil {
+		if os.IsNotExist(err) {
+			m.logErr = "No log file yet: " + server.LogPath(srv.Path)
+		} else {
+			m.logErr = fmt.Sprintf("Failed to read log: %v", err)
+		}
+		return
+	}
+	m.logLines = lines
+}
+
+// loadMetadata reads the selected server's metadata for the info view
+func (m *Model) loadMetadata() {
+	m.metadata = nil
+	if srv := m.selectedServer(); srv != nil {
+		if metadata, err := server.NewMetadataManager().Load(srv.Path); err == nil {
+			m.metadata = metadata
+		}
+	}
+}
+
+// View renders the dashboard
+func (m *Model) View() string {
+	if m.quitting {
+		return ""
+	}
+	if m.width == 0 {
+		return "Loading..."
+	}
+
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorPureWhite).
+		Background(ui.ColorPrimary).
+		Bold(true).
+		Padding(0, 2).
+		Width(m.width)
+
+	b.WriteString(titleStyle.Render("InkWash Dashboard"))
+	b.WriteString("\n\n")
+
+	switch m.view {
+	case ViewServers:
+		b.WriteString(m.renderServers())
+	case ViewLogs:
+		b.WriteString(m.renderLogs())
+	case ViewInfo:
+		b.WriteString(m.renderInfo())
+	}
+
+	return b.String()
+}
+
+// renderServers renders the server list, the selected server's graphs and the status line
+func (m *Model) renderServers() string {
+	var b strings.Builder
+
+	if len(m.servers) == 0 {
+		b.WriteString(ui.RenderMuted("No servers yet. Create one with:"))
+		b.WriteString("\n  ")
+		b.WriteString(ui.RenderCode("inkwash create"))
+		b.WriteString("\n\n")
+		if m.confirm != nil {
+			b.WriteString(m.confirm.View())
+			b.WriteString("\n")
+			return b.String()
+		}
+		b.WriteString(m.renderStatus())
+		b.WriteString("\n")
+		b.WriteString(ui.RenderHelp("c: Clear cache  •  q: Quit"))
+		return b.String()
+	}
+
+	b.WriteString(m.selector.View())
+	b.WriteString("\n\n")
+
+	if srv := m.selectedServer(); srv != nil {
+		if snapshot, ok := m.metrics.Snapshot(srv.Name); ok && !snapshot.StatsUnavailable {
+			cpu := components.NewSparkline(len(snapshot.CPU))
+			cpu.Data = snapshot.CPU
+			cpu.SetMax(maxOf(snapshot.CPU, 100))
+
+			ram := components.NewSparkline(len(snapshot.RAM))
+			ram.Data = snapshot.RAM
+			ram.SetMax(maxOf(snapshot.RAM, 1))
+
+			b.WriteString(ui.RenderMuted("CPU "))
+			b.WriteString(cpu.Render())
+			b.WriteString(ui.RenderMuted(fmt.Sprintf("  %.1f%%", snapshot.CurrentCPU())))
+			b.WriteString("\n")
+			b.WriteString(ui.RenderMuted("RAM "))
+			b.WriteString(ram.Render())
+			b.WriteString(ui.RenderMuted(fmt.Sprintf("  %.2f GB", snapshot.CurrentRAM())))
+			b.WriteString("\n")
+			if snapshot.PlayersKnown && !snapshot.Paused {
+				b.WriteString(ui.RenderMuted("Players " + playersLabel(snapshot)))
+				b.WriteString("\n")
+			}
+			if snapshot.Paused {
+				b.WriteString(ui.RenderMuted("Stopped, showing the last known usage"))
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		} else if ok && !snapshot.Paused {
+			b.WriteString(ui.RenderWarning(ui.SymbolWarning + " Running, but this account isn't allowed to read the process's CPU/RAM usage"))
+			b.WriteString("\n")
+			// The player count comes from the server itself, so it's still known
+			if snapshot.PlayersKnown {
+				b.WriteString(ui.RenderMuted("Players " + playersLabel(snapshot)))
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if m.confirm != nil {
+		b.WriteString(m.confirm.View())
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	b.WriteString(m.renderStatus())
+	b.WriteString("\n")
+	b.WriteString(ui.RenderHelp("s: Start  •  x: Stop  •  r: Restart  •  d: Remove  •  l: Logs  •  i/Enter: Info  •  c: Clear cache  •  q: Quit"))
+	return b.String()
+}
+
+// renderStatus renders the spinner while actions run and the last action's result
+func (m *Model) renderStatus() string {
+	var b strings.Builder
+
+	if len(m.busy) > 0 {
+		b.WriteString(m.spinner.View())
+		b.WriteString(" ")
+		b.WriteString(ui.RenderMuted("Working..."))
+		b.WriteString("\n")
+	}
+	if m.message != "" {
+		if m.messageOK {
+			b.WriteString(ui.RenderSuccess(m.message))
+		} else {
+			b.WriteString(ui.RenderError(m.message))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// renderLogs renders the tail of the selected server's log
+func (m *Model) renderLogs() string {
+	var b strings.Builder
+
+	srv := m.selectedServer()
+	if srv == nil {
+		return ""
+	}
+
+	b.WriteString(ui.RenderSubheader("Logs: " + srv.Name))
+	b.WriteString("\n\n")
+
+	if m.logErr != "" {
+		b.WriteString(ui.RenderMuted(m.logErr))
+		b.WriteString("\n")
+	} else {
+		// Leave room for the title, header and help lines
+		visible := m.height - 7
+		if visible < 5 {
+			visible = 5
+		}
+		lines := m.logLines
+		if len(lines) > visible {
+			lines = lines[len(lines)-visible:]
+		}
+		for _, line := range lines {
+			b.WriteString(ui.RenderLogLine(line))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(ui.RenderHelp("Esc: Back  •  updates live"))
+	return b.String()
+}
+
+// renderInfo renders details about the selected server
+func (m *Model) renderInfo() string {
+	var b strings.Builder
+
+	srv := m.selectedServer()
+	if srv == nil {
+		return ""
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(ui.ColorMediumGray)
+	row := func(label, value string) {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %-14s", label)))
+		b.WriteString(value)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(ui.RenderSubheader("Server: " + srv.Name))
+	b.WriteString("\n\n")
+
+	row("Path", ui.RenderPath(srv.Path))
+	row("Endpoint", srv.Endpoint())
+	row("Game", types.GameBuildLabel(srv.GetGameBuild()))
+	if !srv.CanRunOnHost() {
+		row("Platform", srv.GetTargetOS()+" (staged, can't run on this machine)")
+	}
+	if m.pm.IsRunning(srv) {
+		row("Status", fmt.Sprintf("%s (PID: %d)", m.pm.GetStatus(srv), srv.PID))
+		if snapshot, ok := m.metrics.Snapshot(srv.Name); ok && snapshot.PlayersKnown && !snapshot.Paused {
+			row("Players", playersLabel(snapshot))
+		} else {
+			row("Players", ui.RenderMuted("not answering yet"))
+		}
+	} else {
+		row("Status", "Stopped")
+	}
+
+	if m.metadata != nil {
+		build := m.metadata.Build
+		row("Build", fmt.Sprintf("%d", build.Number))
+		row("Created", m.metadata.Lifecycle.CreatedAt.Format("2006-01-02 15:04"))
+		if started := m.metadata.Lifecycle.LastStarted; started != nil {
+			row("Last Started", started.Format("2006-01-02 15:04"))
+		}
+		row("Restarts", fmt.Sprintf("%d", m.metadata.Stats.RestartCount))
+		row("Total Uptime", m.metadata.Stats.TotalUptime.Round(time.Second).String())
+		if len(m.metadata.Resources) > 0 {
+			row("Resources", fmt.Sprintf("%d installed by inkwash", len(m.metadata.Resources)))
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(ui.RenderHelp("Esc: Back"))
+	return b.String()
+}
+
+// tickCmd schedules the next refresh
+func tickCmd() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// progressive describes an action in progress
+func progressive(action string) string {
+	switch action {
+	case "start":
+		return "Starting"
+	case "stop":
+		return "Stopping"
+	case "restart":
+		return "Restarting"
+	case "remove":
+		return "Removing"
+	}
+	return action
+}
+
+// pastTense turns an action into a status message prefix
+func pastTense(action string) string {
+	switch action {
+	case "start":
+		return "Started"
+	case "stop":
+		return "Stopped"
+	case "restart":
+		return "Restarted"
+	case "remove":
+		return "Removed"
+	case "clear":
+		return "Cleared"
+	}
+	return action
+}
+
+// maxOf returns the largest sample, but at least floor, for sparkline scaling
+func maxOf(samples []float64, floor float64) float64 {
+	max := floor
+	for _, sample := range samples {
+		if sample > max {
+			max = sample
+		}
+	}
+	return max
+}
@@ -21,26 +21,64 @@ const (
 	TierFull
 )
 
-// DetectAnimationTier determines the optimal animation tier based on system capabilities
-func DetectAnimationTier() AnimationTier {
+// Capabilities holds the results of the checks the animation tier is based on
+type Capabilities struct {
+	ANSI256        bool   // Terminal supports 256 colors
+	CPUs           int    // Logical CPU cores
+	AvailableRAM   uint64 // Bytes, 0 if it couldn't be detected
+	TotalRAM       uint64 // Bytes, 0 if it couldn't be detected
+	ModernTerminal bool   // Known terminal emulator with good rendering
+}
+
+// DetectCapabilities runs the terminal and system checks
+func DetectCapabilities() Capabilities {
+	caps := Capabilities{
+		ANSI256:        supportsANSI256(),
+		CPUs:           runtime.NumCPU(),
+		ModernTerminal: isModernTerminal(),
+	}
+
+	if v, err := mem.VirtualMemory(); err == nil {
+		caps.AvailableRAM = v.Available
+		caps.TotalRAM = v.Total
+	}
+
+	return caps
+}
+
+// EnoughRAM reports whether at least 2GB of RAM is available. Undetected RAM
+// is assumed to be sufficient.
+func (c Capabilities) EnoughRAM() bool {
+	// 2GB in bytes
+	const minRAM = 2 * 1024 * 1024 * 1024
+	return c.AvailableRAM == 0 || c.AvailableRAM >= minRAM
+}
+
+// Tier returns the animation tier these capabilities call for
+func (c Capabilities) Tier() AnimationTier {
 	// Check 1: Terminal capabilities
-	if !supportsANSI256() {
+	if !c.ANSI256 {
 		return TierMinimal
 	}
 
 	// Check 2: System performance (CPU cores, available RAM)
-	if runtime.NumCPU() < 4 || !hasEnoughRAM() {
+	if c.CPUs < 4 || !c.EnoughRAM() {
 		return TierBalanced
 	}
 
 	// Check 3: Terminal emulator detection
-	if !isModernTerminal() {
+	if !c.ModernTerminal {
 		return TierBalanced
 	}
 
 	return TierFull
 }
 
+// DetectAnimationTier determines the optimal animation tier based on system capabilities
+func DetectAnimationTier() AnimationTier {
+	return DetectCapabilities().Tier()
+}
+
 // supportsANSI256 checks if the terminal supports 256-color ANSI
 func supportsANSI256() bool {
 	term := os.Getenv("TERM")
@@ -60,19 +98,6 @@ func supportsANSI256() bool {
 	return term != "" && term != "dumb"
 }
 
-// hasEnoughRAM checks if system has at least 2GB available RAM
-func hasEnoughRAM() bool {
-	v, err := mem.VirtualMemory()
-	if err != nil {
-		// Assume sufficient RAM if we can't detect
-		return true
-	}
-
-	// 2GB in bytes
-	const minRAM = 2 * 1024 * 1024 * 1024
-	return v.Available >= minRAM
-}
-
 // isModernTerminal detects if we're running in a modern terminal emulator
 func isModernTerminal() bool {
 	termProgram := os.Getenv("TERM_PROGRAM")
@@ -0,0 +1,52 @@
+package ui
+
+import "strings"
+
+// LogSeverity is how serious a line of server output looks
+type LogSeverity int
+
+const (
+	LogInfo LogSeverity = iota
+	LogWarning
+	LogError
+)
+
+// Markers are matched against the lowercased line. FXServer has no fixed
+// log format; these cover its own messages, resource script errors and the
+// ^1 (red) and ^3 (yellow) color codes scripts print with.
+var (
+	logErrorMarkers   = []string{"script error", "error:", "[error]", "error loading", "failed to", "couldn't", "could not", "exception", "panic", "^1"}
+	logWarningMarkers = []string{"warning", "[warn]", "warn:", "deprecated", "^3"}
+)
+
+// ClassifyLogLine guesses the severity of a line of FXServer output
+func ClassifyLogLine(line string) LogSeverity {
+	lower := strings.ToLower(line)
+	for _, marker := range logErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return LogError
+		}
+	}
+	for _, marker := range logWarningMarkers {
+		if strings.Contains(lower, marker) {
+			return LogWarning
+		}
+	}
+	return LogInfo
+}
+
+// RenderLogLine colors a line of server output by its severity. Lines that
+// already carry terminal colors are left as they are.
+func RenderLogLine(line string) string {
+	if strings.Contains(line, "\x1b[") {
+		return line
+	}
+
+	switch ClassifyLogLine(line) {
+	case LogError:
+		return StyleError.Render(line)
+	case LogWarning:
+		return StyleWarning.Render(line)
+	}
+	return line
+}
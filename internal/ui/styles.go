@@ -147,6 +147,7 @@ const (
 	SymbolPointer  = "▸"
 	SymbolCheck    = "✓"
 	SymbolCross    = "✗"
+	SymbolWarning  = "⚠"
 	SymbolDot      = "•"
 	SymbolLine     = "─"
 	SymbolArrowUp  = "↑"
@@ -1,9 +1,10 @@
 package wizard
 
 import (
-	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,9 +13,14 @@ import (
 
 	"github.com/VexoaXYZ/inkwash/internal/convert"
 	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/network"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/resource"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
 	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/internal/ui/components"
+	"github.com/VexoaXYZ/inkwash/internal/validation"
 	"github.com/VexoaXYZ/inkwash/pkg/types"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -41,6 +47,23 @@ type ConversionItem struct {
 	Error    error
 	FileName string
 	Category string // e.g., "vehicles", "weapons", "scripts"
+
+	DownloadError error // Set when the converted file failed to download or extract
+	Installed     bool  // Downloaded and installed
+
+	RequiredGameBuild int    // Minimum sv_enforceGameBuild the content seems to need (0 = unknown)
+	RequiredDLC       string // DLC pack that set RequiredGameBuild
+
+	Resources []string        // Names of the resources the mod was installed as
+	Report    *convert.Report // What was checked and fixed before installing
+}
+
+// failure returns why the item failed to convert or install, or nil
+func (c *ConversionItem) failure() error {
+	if c.Error != nil {
+		return c.Error
+	}
+	return c.DownloadError
 }
 
 // ConvertWizardModel represents the state of the conversion wizard
@@ -48,7 +71,16 @@ type ConvertWizardModel struct {
 	step      ConvertStep
 	client    *convert.Client
 	downloader *download.Downloader
+	extractor  *download.Extractor
 	registry  *registry.Registry
+	history   *convert.History // nil if the history file couldn't be loaded
+	layout    string           // convert.Layout* value deciding where mods are installed
+	autoEnsure bool            // Add ensure lines for installed mods to a registered server's server.cfg
+	ensureFile string          // servercfg.EnsureFiles value the ensure lines go in
+
+	// Cancels in-flight convert.cfx.rs requests when the wizard exits
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// Input components
 	serverSelector *components.Selector
@@ -68,9 +100,18 @@ type ConvertWizardModel struct {
 	conversionList []string                   // Ordered UUIDs
 	downloads      []string                   // Files to download
 	error          string
+	selectError    string // Shown under the server selector (e.g. unwritable current directory)
+	ensured        []string // Resources whose ensure lines were added to ensureFile
+	ensureError    error    // Set when ensureFile couldn't be updated
+	unensured      []string // Installed resources the complete screen offers to ensure
 	quitting       bool
 	completed      bool
 
+	// Crash recovery
+	session     sessionSaver
+	resume      *ConvertSession // Session being resumed, used to preselect the target and pick up its URLs
+	retryCursor int             // Failed item selected on the complete screen
+
 	// Progress tracking
 	overallProgress float64
 	downloadProgress map[string]float64
@@ -78,17 +119,17 @@ type ConvertWizardModel struct {
 	lastUpdate      time.Time
 
 	// Queue management
-	conversionQueue []string // URLs waiting to be converted
-	activeConversions int    // Number of conversions in progress
-	maxConcurrent   int      // Maximum concurrent conversions
+	manager        *convert.Manager // Starts and polls the conversions, created by startConverting
+	managerOpts    convert.ManagerOptions
+	managerRunning bool
 
 	// UI state
 	width  int
 	height int
 }
 
-// NewConvertWizard creates a new conversion wizard
-func NewConvertWizard(reg *registry.Registry) *ConvertWizardModel {
+// NewConvertWizard creates a new conversion wizard using the given convert client
+func NewConvertWizard(reg *registry.Registry, client *convert.Client) *ConvertWizardModel {
 	tier := ui.DetectAnimationTier()
 
 	// Create URL input for adding URLs one at a time
@@ -112,10 +153,21 @@ func NewConvertWizard(reg *registry.Registry) *ConvertWizardModel {
 		return nil
 	})
 
+	// History is best-effort; a corrupt file shouldn't block conversions
+	history, _ := convert.LoadHistory(convert.GetHistoryPath())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &ConvertWizardModel{
 		step:             ConvertStepSelectServer,
-		client:           convert.NewClient(),
+		history:          history,
+		layout:           convert.DefaultLayout,
+		ensureFile:       "server.cfg",
+		ctx:              ctx,
+		cancel:           cancel,
+		client:           client,
 		downloader:       download.NewDownloader(2), // Limit concurrent downloads
+		extractor:        download.NewExtractor(),
 		registry:         reg,
 		urlInput:         urlInput,
 		customPathInput:  customPathInput,
@@ -123,13 +175,74 @@ func NewConvertWizard(reg *registry.Registry) *ConvertWizardModel {
 		spinner:          components.NewSpinner(tier),
 		conversions:      make(map[string]*ConversionItem),
 		downloadProgress: make(map[string]float64),
-		maxConcurrent:    2, // Only 2 conversions at a time to respect rate limits
+		session:          sessionSaver{kind: sessionConvert},
+	}
+}
+
+// SetRateLimits sets how many conversions run at once and how often their
+// progress is polled; zero keeps the convert.Manager defaults
+func (m *ConvertWizardModel) SetRateLimits(maxConcurrent int, pollInterval time.Duration) {
+	m.managerOpts = convert.ManagerOptions{MaxConcurrent: maxConcurrent, PollInterval: pollInterval}
+}
+
+// Resume prefills the wizard from a saved session: its URLs that aren't
+// installed yet are added and its target is preselected. URLs it already
+// converted are only downloaded. Call before running the wizard.
+func (m *ConvertWizardModel) Resume(s *ConvertSession) {
+	m.urls = s.Pending()
+	if s.CustomPath != "" {
+		m.customPathInput.Value = s.CustomPath
+	}
+	m.resume = s
+}
+
+// Continue resumes a saved session where it stopped: its target is used
+// again and converting starts right away. A session saved before a target
+// was picked is prefilled like Resume does. Call before running the wizard.
+func (m *ConvertWizardModel) Continue(s *ConvertSession) error {
+	m.Resume(s)
+
+	switch {
+	case s.Server != "":
+		srv, err := m.registry.Get(s.Server)
+		if err != nil {
+			return fmt.Errorf("can't resume the conversion: %w", err)
+		}
+		m.selectedServer = srv
+
+	case s.ExternalMode == "current":
+		currentDir, err := os.Getwd()
+		if err == nil {
+			err = validation.ValidatePathWritable(filepath.Join(currentDir, "resources"))
+		}
+		if err != nil {
+			return fmt.Errorf("can't resume the conversion into the current directory: %w", err)
+		}
+		m.externalMode = "current"
+
+	case s.ExternalMode == "custom" && s.CustomPath != "":
+		if err := validation.ValidatePathWritable(s.CustomPath); err != nil {
+			return fmt.Errorf("can't resume the conversion: %w", err)
+		}
+		m.externalMode, m.customPath = "custom", s.CustomPath
+
+	default:
+		// No target picked yet
+		return nil
 	}
+
+	m.startConverting()
+	return nil
 }
 
 // Init initializes the wizard
 func (m *ConvertWizardModel) Init() tea.Cmd {
-	return m.setupServerSelector()
+	cmd := m.setupServerSelector()
+	if m.step == ConvertStepConverting {
+		// Continued straight into converting
+		return tea.Batch(m.spinner.TickCmd(), pollTickCmd())
+	}
+	return cmd
 }
 
 // setupServerSelector creates the server selector
@@ -162,13 +275,100 @@ func (m *ConvertWizardModel) setupServerSelector() tea.Cmd {
 
 	m.serverSelector = components.NewSelector("Select Target Server", items)
 	m.serverSelector.MaxHeight = 10
+	if m.resume != nil {
+		m.serverSelector.Selected = resumedTarget(items, m.resume)
+	}
 	m.serverSelector.Focus()
 	return nil
 }
 
+// resumedTarget returns the index of the selector item a session targeted
+func resumedTarget(items []components.SelectorItem, s *ConvertSession) int {
+	for i, item := range items {
+		switch value := item.Value.(type) {
+		case string:
+			if s.Server == "" && value == "external:"+s.ExternalMode {
+				return i
+			}
+		case types.Server:
+			if s.Server != "" && value.Name == s.Server {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// saveSession keeps the session file in step with what has been entered and
+// converted, and removes it once the wizard completed without failures
+func (m *ConvertWizardModel) saveSession() {
+	if m.completed && len(m.failedURLs()) == 0 {
+		m.session.finish()
+		return
+	}
+
+	snapshot := ConvertSession{
+		URLs:         append([]string(nil), m.urls...),
+		Items:        m.sessionItems(),
+		ExternalMode: m.externalMode,
+		CustomPath:   m.customPath,
+	}
+	if m.selectedServer != nil {
+		snapshot.Server = m.selectedServer.Name
+	} else if m.externalMode == "" && m.resume != nil {
+		// Target not picked again yet, keep the resumed one
+		snapshot.Server, snapshot.ExternalMode, snapshot.CustomPath = m.resume.Server, m.resume.ExternalMode, m.resume.CustomPath
+	}
+
+	if !m.session.changed(snapshot) {
+		return
+	}
+	if len(snapshot.URLs) == 0 {
+		// Nothing worth resuming yet
+		clearSession(sessionConvert)
+		return
+	}
+	snapshot.SavedAt = time.Now()
+	m.session.write(snapshot)
+}
+
+// sessionItems records how far each URL got once converting started
+func (m *ConvertWizardModel) sessionItems() []ConvertSessionItem {
+	var items []ConvertSessionItem
+	for _, url := range m.urls {
+		item := m.conversions[url]
+		if item == nil {
+			continue
+		}
+
+		saved := ConvertSessionItem{URL: url, UUID: item.UUID, Installed: item.Installed}
+		if !errors.Is(item.DownloadError, convert.ErrFileExpired) {
+			saved.File = item.FileName
+		}
+		if err := item.failure(); err != nil {
+			saved.Error = err.Error()
+		}
+		items = append(items, saved)
+	}
+	return items
+}
+
+// failedURLs lists the items that failed to convert or install, in the order
+// they were added
+func (m *ConvertWizardModel) failedURLs() []string {
+	var failed []string
+	for _, url := range m.urls {
+		if item := m.conversions[url]; item != nil && item.failure() != nil {
+			failed = append(failed, url)
+		}
+	}
+	return failed
+}
+
 // Update handles messages
 func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
+	defer m.saveSession()
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -176,11 +376,23 @@ func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case tea.KeyMsg:
+		if m.step == ConvertStepComplete {
+			if msg.String() == "e" && len(m.unensured) > 0 {
+				return m, m.ensureCmd()
+			}
+			if cmd, handled := m.handleRetryKey(msg.String()); handled {
+				return m, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "esc":
-			if m.step == ConvertStepConverting || m.step == ConvertStepDownloading {
-				return m, nil // Don't quit during conversion/download
+			if m.step == ConvertStepDownloading {
+				return m, nil // Don't quit while files are being written
 			}
+			// Abort any in-flight conversion requests
+			m.cancel()
+			m.pollingActive = false
 			m.quitting = true
 			return m, tea.Quit
 
@@ -209,13 +421,6 @@ func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-	case conversionStartedMsg:
-		item := m.conversions[msg.uuid]
-		if item != nil {
-			item.UUID = msg.uuid
-		}
-		return m, nil
-
 	case pollTickMsg:
 		// Throttle updates to prevent excessive scrolling
 		if time.Since(m.lastUpdate) < 500*time.Millisecond {
@@ -223,65 +428,14 @@ func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.lastUpdate = time.Now()
 
-		// Check conversion progress
+		// The manager starts and polls the conversions, show where it's at
 		if m.step == ConvertStepConverting && m.pollingActive {
-			// Start new conversions from queue if under the limit
-			for len(m.conversionQueue) > 0 && m.activeConversions < m.maxConcurrent {
-				url := m.conversionQueue[0]
-				m.conversionQueue = m.conversionQueue[1:]
-				m.activeConversions++
-
-				// Start conversion in background
-				go func(u string) {
-					uuid, err := m.client.StartConversion(u)
-					if err != nil {
-						if item := m.conversions[u]; item != nil {
-							item.Error = err
-						}
-						m.activeConversions--
-						return
-					}
+			m.syncConversions()
 
-					if item := m.conversions[u]; item != nil {
-						item.UUID = uuid
-					}
-				}(url)
-
-				// Add a small delay between conversion starts
-				time.Sleep(200 * time.Millisecond)
-			}
-
-			// Poll active conversions for progress
-			allComplete := true
-			for _, item := range m.conversions {
-				if item.Error != nil {
-					// Skip failed items
-					continue
-				}
-
-				if item.UUID != "" && (item.Status == nil || item.Status.Progress < 100) {
-					status, err := m.client.QueryProgress(item.UUID)
-					if err == nil {
-						item.Status = status
-						if status.Progress >= 100 {
-							item.FileName = status.File
-							m.activeConversions--
-						}
-					}
-				}
-
-				if item.Status == nil || item.Status.Progress < 100 {
-					allComplete = false
-				}
-			}
-
-			m.updateConversionProgress()
-
-			// Check if all done (queue empty and all conversions complete)
-			if len(m.conversionQueue) == 0 && allComplete && m.activeConversions == 0 {
+			if m.manager.Idle() {
 				m.pollingActive = false
 				m.step = ConvertStepDownloading
-				return m, downloadFilesCmd(m)
+				return m, m.downloadCmd()
 			}
 			return m, pollTickCmd()
 		}
@@ -289,7 +443,7 @@ func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case conversionCompleteMsg:
 		m.step = ConvertStepDownloading
-		return m, downloadFilesCmd(m)
+		return m, m.downloadCmd()
 
 	case downloadProgressMsg:
 		m.downloadProgress[msg.file] = msg.progress
@@ -297,8 +451,38 @@ func (m *ConvertWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case downloadCompleteMsg:
+		for _, item := range msg.items {
+			if err := msg.failures[item.URL]; err != nil {
+				item.DownloadError = err
+			} else {
+				item.Installed = true
+			}
+		}
+		// Retries add to what earlier rounds ensured
+		m.ensured = append(m.ensured, msg.ensured...)
+		if msg.ensureErr != nil {
+			m.ensureError = msg.ensureErr
+		}
+		m.recordHistory(msg.resourcesPath, msg.items)
+
+		// Without auto-ensure, the complete screen offers to start them with the server
+		if !m.autoEnsure && m.externalMode == "" && m.selectedServer != nil {
+			for _, item := range msg.items {
+				if item.Installed {
+					m.unensured = append(m.unensured, item.Resources...)
+				}
+			}
+		}
 		m.step = ConvertStepComplete
 		m.completed = true
+		m.retryCursor = 0
+		return m, nil
+
+	case ensuredMsg:
+		m.ensured = append(m.ensured, msg.ensured...)
+		if msg.err != nil {
+			m.ensureError = msg.err
+		}
 		return m, nil
 
 	case wizardErrorMsg:
@@ -362,6 +546,17 @@ func (m *ConvertWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 				// Check if it's external server
 				if strVal, ok := value.(string); ok {
 					if strVal == "external:current" {
+						// Check ./resources is usable before collecting URLs
+						currentDir, err := os.Getwd()
+						if err == nil {
+							err = validation.ValidatePathWritable(filepath.Join(currentDir, "resources"))
+						}
+						if err != nil {
+							m.selectError = fmt.Sprintf("Current directory can't be used: %v", err)
+							m.serverSelector.Reset()
+							return m, nil
+						}
+						m.selectError = ""
 						m.externalMode = "current"
 						m.step = ConvertStepEnterURLs
 						m.urlInput.Focus()
@@ -382,7 +577,14 @@ func (m *ConvertWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 		if m.customPathInput.Error != "" {
 			return m, nil
 		}
-		m.customPath = filepath.Clean(m.customPathInput.Value)
+		customPath := filepath.Clean(m.customPathInput.Value)
+		if err := validation.ValidatePathWritable(customPath); err != nil {
+			// Show the problem inline so it can be fixed before converting
+			m.customPathInput.Error = err.Error()
+			m.customPathInput.Focus()
+			return m, m.customPathInput.BlinkCmd()
+		}
+		m.customPath = customPath
 		m.step = ConvertStepEnterURLs
 		m.urlInput.Focus()
 		return m, m.urlInput.BlinkCmd()
@@ -395,27 +597,14 @@ func (m *ConvertWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 			return m, nil // Stay on this step
 		}
 
-		// Initialize conversion items and queue
-		m.conversionQueue = make([]string, len(m.urls))
-		copy(m.conversionQueue, m.urls)
-
-		for _, url := range m.urls {
-			m.conversions[url] = &ConversionItem{
-				URL:      url,
-				Category: extractCategory(url),
-			}
-		}
-
-		m.step = ConvertStepConverting
-		m.pollingActive = true
-		m.activeConversions = 0
-		m.lastUpdate = time.Now()
+		m.startConverting()
 		return m, tea.Batch(
 			m.spinner.TickCmd(),
 			pollTickCmd(),
 		)
 
 	case ConvertStepComplete, ConvertStepError:
+		m.cancel()
 		m.quitting = true
 		return m, tea.Quit
 	}
@@ -423,6 +612,133 @@ func (m *ConvertWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// startConverting initializes the conversion items and queue. URLs a resumed
+// session already converted are only downloaded, and ones it was still
+// converting are polled again instead of being started over.
+func (m *ConvertWizardModel) startConverting() {
+	saved := make(map[string]ConvertSessionItem)
+	if m.resume != nil {
+		for _, item := range m.resume.Items {
+			saved[item.URL] = item
+		}
+	}
+
+	if m.manager == nil {
+		m.manager = convert.NewManager(m.client, m.managerOpts)
+	}
+	for _, url := range m.urls {
+		item := &ConversionItem{
+			URL:      url,
+			Category: convert.ModCategory(url),
+		}
+		m.conversions[url] = item
+
+		switch s := saved[url]; {
+		case s.File != "":
+			item.UUID, item.FileName = s.UUID, s.File
+			item.Status = &convert.ConversionStatus{Progress: 100, File: s.File}
+		case s.UUID != "" && s.Error == "":
+			item.UUID = s.UUID
+			m.manager.Follow(url, s.UUID)
+		default:
+			m.manager.Add(url)
+		}
+	}
+
+	m.beginConverting()
+}
+
+// beginConverting switches to the converting step and starts polling
+func (m *ConvertWizardModel) beginConverting() {
+	m.step = ConvertStepConverting
+	m.pollingActive = true
+	m.lastUpdate = time.Now()
+	if !m.managerRunning {
+		m.managerRunning = true
+		go m.manager.Run(m.ctx)
+	}
+}
+
+// syncConversions copies what the manager knows about each conversion into
+// its item
+func (m *ConvertWizardModel) syncConversions() {
+	for url, item := range m.conversions {
+		job, ok := m.manager.Job(url)
+		if !ok {
+			continue
+		}
+		item.UUID, item.Status, item.Error = job.UUID, job.Status, job.Err
+		if job.File != "" {
+			item.FileName = job.File
+		}
+	}
+	m.updateConversionProgress()
+}
+
+// handleRetryKey moves between and retries the failed items on the complete
+// screen. It reports whether the key was one of its own.
+func (m *ConvertWizardModel) handleRetryKey(key string) (tea.Cmd, bool) {
+	failed := m.failedURLs()
+	if len(failed) == 0 {
+		return nil, false
+	}
+
+	switch key {
+	case "up", "k":
+		if m.retryCursor > 0 {
+			m.retryCursor--
+		}
+	case "down", "j":
+		if m.retryCursor < len(failed)-1 {
+			m.retryCursor++
+		}
+	case "r":
+		return m.retry(failed[m.retryCursor : m.retryCursor+1]), true
+	case "a":
+		return m.retry(failed), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// retry converts the given failed items again. Items whose converted file is
+// still on convert.cfx.rs are only downloaded again.
+func (m *ConvertWizardModel) retry(urls []string) tea.Cmd {
+	for _, url := range urls {
+		item := m.conversions[url]
+		redownload := item.Error == nil && !errors.Is(item.DownloadError, convert.ErrFileExpired)
+
+		delete(m.downloadProgress, item.FileName)
+		item.Error, item.DownloadError = nil, nil
+		if !redownload {
+			item.UUID, item.FileName, item.Status = "", "", nil
+			m.manager.Add(url)
+		}
+	}
+
+	m.completed = false
+	m.retryCursor = 0
+	m.beginConverting()
+	return pollTickCmd()
+}
+
+// downloadCmd downloads the converted files that aren't installed yet and
+// haven't failed
+func (m *ConvertWizardModel) downloadCmd() tea.Cmd {
+	var items []*ConversionItem
+	m.downloads = nil
+	for _, url := range m.urls {
+		item := m.conversions[url]
+		if item == nil || item.FileName == "" || item.Installed || item.failure() != nil {
+			continue
+		}
+		items = append(items, item)
+		m.downloads = append(m.downloads, item.FileName)
+	}
+	return downloadFilesCmd(m, items)
+}
+
 // updateConversionProgress calculates overall conversion progress
 func (m *ConvertWizardModel) updateConversionProgress() {
 	if len(m.conversions) == 0 {
@@ -480,6 +796,12 @@ func (m *ConvertWizardModel) View() string {
 		if m.serverSelector != nil {
 			b.WriteString(m.serverSelector.View())
 		}
+		if m.selectError != "" {
+			errorStyle := lipgloss.NewStyle().
+				Foreground(ui.ColorError)
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(ui.SymbolCross + " " + m.selectError))
+		}
 
 	case ConvertStepCustomPath:
 		b.WriteString(m.customPathInput.View())
@@ -543,6 +865,12 @@ func (m *ConvertWizardModel) View() string {
 			Foreground(ui.ColorMediumGray).
 			Italic(true)
 		b.WriteString(helpStyle.Render("Esc: Cancel  •  Enter: Continue"))
+	} else if m.step == ConvertStepConverting {
+		b.WriteString("\n\n")
+		helpStyle := lipgloss.NewStyle().
+			Foreground(ui.ColorMediumGray).
+			Italic(true)
+		b.WriteString(helpStyle.Render("Esc: Cancel conversions"))
 	}
 
 	return b.String()
@@ -570,9 +898,16 @@ func (m *ConvertWizardModel) renderConverting() string {
 	progressStyle := lipgloss.NewStyle().
 		Foreground(ui.ColorMediumGray)
 
+	queued, active := m.manager.Counts()
 	b.WriteString(progressStyle.Render(fmt.Sprintf("Progress: %d/%d completed  •  %d queued  •  %d/%d active",
-		completedCount, len(m.conversions), len(m.conversionQueue), m.activeConversions, m.maxConcurrent)))
-	b.WriteString("\n\n")
+		completedCount, len(m.conversions), queued, active, m.manager.MaxConcurrent())))
+	b.WriteString("\n")
+	if until := m.manager.PausedUntil(); !until.IsZero() {
+		b.WriteString(progressStyle.Render(fmt.Sprintf("convert.cfx.rs is rate limiting requests, waiting %s",
+			time.Until(until).Round(time.Second))))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
 	// Individual mod statuses (ordered by URL list to maintain consistency)
 	i := 1
@@ -643,24 +978,21 @@ func (m *ConvertWizardModel) renderDownloading() string {
 		Foreground(ui.ColorPureWhite).
 		Bold(true)
 
-	b.WriteString(headerStyle.Render(fmt.Sprintf("Downloading %d Resource(s)", len(m.conversions))))
+	b.WriteString(headerStyle.Render(fmt.Sprintf("Downloading %d Resource(s)", len(m.downloads))))
 	b.WriteString("\n\n")
 
 	// Overall progress
 	completedCount := 0
-	for _, item := range m.conversions {
-		if item.FileName != "" {
-			progress, exists := m.downloadProgress[item.FileName]
-			if exists && progress >= 1.0 {
-				completedCount++
-			}
+	for _, file := range m.downloads {
+		if progress, exists := m.downloadProgress[file]; exists && progress >= 1.0 {
+			completedCount++
 		}
 	}
 
 	progressStyle := lipgloss.NewStyle().
 		Foreground(ui.ColorMediumGray)
 
-	b.WriteString(progressStyle.Render(fmt.Sprintf("Progress: %d/%d downloaded", completedCount, len(m.conversions))))
+	b.WriteString(progressStyle.Render(fmt.Sprintf("Progress: %d/%d downloaded", completedCount, len(m.downloads))))
 	b.WriteString("\n\n")
 
 	// Individual download statuses (ordered by URL list to maintain consistency)
@@ -676,10 +1008,18 @@ func (m *ConvertWizardModel) renderDownloading() string {
 		var icon, statusText string
 		var statusColor lipgloss.Color
 
-		if item.Error != nil {
+		if item.Installed {
+			icon = ui.SymbolCheck
+			statusText = "Installed"
+			statusColor = ui.ColorSuccess
+		} else if item.Error != nil {
 			icon = ui.SymbolCross
 			statusText = "Skipped (conversion failed)"
 			statusColor = ui.ColorError
+		} else if item.DownloadError != nil {
+			icon = ui.SymbolCross
+			statusText = "Skipped (download failed)"
+			statusColor = ui.ColorError
 		} else if item.FileName == "" {
 			icon = "⏳"
 			statusText = "Waiting for conversion..."
@@ -774,14 +1114,103 @@ func (m *ConvertWizardModel) renderComplete() string {
 		Foreground(ui.ColorPureWhite).
 		Bold(true)
 
-	b.WriteString(headerStyle.Render(fmt.Sprintf("Converted %d mod(s)", len(m.conversions))))
+	installed := 0
+	for _, item := range m.conversions {
+		if item.Installed {
+			installed++
+		}
+	}
+	b.WriteString(headerStyle.Render(fmt.Sprintf("Converted %d mod(s)", installed)))
 	b.WriteString("\n\n")
 
+	// Per-item conversion and download failures (e.g. truncated transfers),
+	// with the one "r" retries selected
+	errorStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorError)
+
+	failedURLs := m.failedURLs()
+	failed := len(failedURLs)
+	for i, url := range failedURLs {
+		line := fmt.Sprintf("%s %s: %v", ui.SymbolCross, extractModName(url), m.conversions[url].failure())
+		if i == m.retryCursor {
+			b.WriteString(ui.StyleSelected.Render(ui.SymbolPointer + " " + line))
+		} else {
+			b.WriteString(errorStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+	if failed > 0 {
+		b.WriteString("\n")
+	}
+
 	infoStyle := lipgloss.NewStyle().
 		Foreground(ui.ColorMediumGray).
 		Italic(true)
 
-	b.WriteString(infoStyle.Render("Resources have been extracted and are ready to use!"))
+	// Content that likely needs a newer game build than the server enforces
+	if warnings := m.gameBuildWarnings(); len(warnings) > 0 {
+		warningStyle := lipgloss.NewStyle().
+			Foreground(ui.ColorWarning)
+
+		for _, warning := range warnings {
+			b.WriteString(warningStyle.Render("  " + warning))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Fixes and problems found in the converted files, and what to /car
+	warningStyle := lipgloss.NewStyle().
+		Foreground(ui.ColorWarning)
+	var spawnNames []string
+	for _, url := range m.urls {
+		item := m.conversions[url]
+		if item == nil || !item.Installed || item.Report == nil {
+			continue
+		}
+		for _, warning := range item.Report.Warnings() {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("  %s %s: %s", ui.SymbolWarning, extractModName(url), warning)))
+			b.WriteString("\n")
+		}
+		spawnNames = append(spawnNames, item.Report.Vehicles...)
+	}
+	if len(spawnNames) > 0 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("Vehicles to spawn (%d):", len(spawnNames))))
+		b.WriteString("\n")
+		for _, name := range spawnNames {
+			b.WriteString(nameStyle.Render("  " + name))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Ensure lines added to server.cfg or resources.cfg, or offered
+	if m.ensureError != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("  %s Couldn't update %s: %v", ui.SymbolCross, m.ensureFile, m.ensureError)))
+		b.WriteString("\n\n")
+	} else if len(m.ensured) > 0 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("Added to %s (%d):", m.ensureFile, len(m.ensured))))
+		b.WriteString("\n")
+		for _, name := range m.ensured {
+			b.WriteString(nameStyle.Render("  ensure " + name))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if len(m.unensured) > 0 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("Not started with the server yet (%d): %s", len(m.unensured), strings.Join(m.unensured, ", "))))
+		b.WriteString("\n")
+		b.WriteString(nameStyle.Render(fmt.Sprintf("Press e to add their ensure lines to %s", m.ensureFile)))
+		b.WriteString("\n\n")
+	}
+
+	if failed > 0 {
+		b.WriteString(infoStyle.Render("Remaining resources have been extracted. Retry the failed items now, or later with 'inkwash convert --resume'."))
+	} else if len(m.ensured) > 0 && len(m.unensured) == 0 && m.ensureError == nil {
+		b.WriteString(infoStyle.Render("Resources have been installed and will start with the server!"))
+	} else {
+		b.WriteString(infoStyle.Render("Resources have been extracted and are ready to use!"))
+	}
 	b.WriteString("\n\n")
 
 	// Divider
@@ -793,7 +1222,18 @@ func (m *ConvertWizardModel) renderComplete() string {
 		Foreground(ui.ColorMediumGray).
 		Italic(true)
 
-	b.WriteString(helpStyle.Render("Press Enter or Esc to exit"))
+	var keys []string
+	if failed > 0 {
+		keys = append(keys, "↑/↓: Select", "r: Retry selected", "a: Retry all failed")
+	}
+	if len(m.unensured) > 0 {
+		keys = append(keys, "e: Add ensure lines")
+	}
+	if len(keys) > 0 {
+		b.WriteString(helpStyle.Render(strings.Join(append(keys, "Enter or Esc: Exit"), "  •  ")))
+	} else {
+		b.WriteString(helpStyle.Render("Press Enter or Esc to exit"))
+	}
 
 	return b.String()
 }
@@ -839,18 +1279,94 @@ func (m *ConvertWizardModel) renderError() string {
 	return b.String()
 }
 
+// SetAutoEnsure makes the wizard add ensure lines for converted mods to the
+// selected server's server.cfg. External resources folders are left alone.
+func (m *ConvertWizardModel) SetAutoEnsure(enabled bool) {
+	m.autoEnsure = enabled
+}
+
+// SetEnsureFile chooses the file ensure lines go in, one of
+// servercfg.EnsureFiles
+func (m *ConvertWizardModel) SetEnsureFile(file string) error {
+	for _, f := range servercfg.EnsureFiles {
+		if file == f {
+			m.ensureFile = file
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ensure file %q (expected %s)", file, strings.Join(servercfg.EnsureFiles, " or "))
+}
+
+// SetLayout chooses how converted mods are grouped under resources/
+func (m *ConvertWizardModel) SetLayout(layout string) error {
+	if err := convert.ValidateLayout(layout); err != nil {
+		return err
+	}
+	m.layout = layout
+	return nil
+}
+
 // Completed returns whether the wizard completed successfully
 func (m *ConvertWizardModel) Completed() bool {
 	return m.completed
 }
 
-// Messages
+// gameBuildWarnings lists converted mods that reference DLC content newer than
+// the selected server's enforced game build
+func (m *ConvertWizardModel) gameBuildWarnings() []string {
+	if m.externalMode != "" || m.selectedServer == nil {
+		return nil
+	}
 
-type conversionStartedMsg struct {
-	uuid string
-	url  string
+	enforced := m.selectedServer.GetGameBuild()
+	if enforced == types.GameBuildNone {
+		enforced = types.GameBuilds[0].Number // Base game
+	}
+
+	var warnings []string
+	highest := 0
+	for _, url := range m.urls {
+		item := m.conversions[url]
+		if item == nil || item.DownloadError != nil || item.RequiredGameBuild <= enforced {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s uses %s content (%s), which may not load on game build %d",
+			ui.SymbolWarning, extractModName(url), item.RequiredDLC, types.GameBuildLabel(item.RequiredGameBuild), enforced))
+		if item.RequiredGameBuild > highest {
+			highest = item.RequiredGameBuild
+		}
+	}
+
+	if highest > 0 {
+		warnings = append(warnings, fmt.Sprintf("  Consider setting 'set sv_enforceGameBuild %d' in server.cfg", highest))
+	}
+
+	return warnings
 }
 
+// recordHistory saves the given conversions that were installed for later
+// redownload
+func (m *ConvertWizardModel) recordHistory(resourcesPath string, items []*ConversionItem) {
+	if m.history == nil {
+		return
+	}
+
+	for _, item := range items {
+		if !item.Installed {
+			continue
+		}
+		m.history.Record(convert.HistoryEntry{
+			URL:           item.URL,
+			UUID:          item.UUID,
+			File:          item.FileName,
+			Category:      item.Category,
+			ResourcesPath: resourcesPath,
+		})
+	}
+}
+
+// Messages
+
 type pollTickMsg struct{}
 
 type conversionCompleteMsg struct{}
@@ -860,7 +1376,19 @@ type downloadProgressMsg struct {
 	progress float64
 }
 
-type downloadCompleteMsg struct{}
+type downloadCompleteMsg struct {
+	resourcesPath string
+	items         []*ConversionItem // Items that were downloaded
+	failures      map[string]error  // URL -> download/extract error
+	ensured       []string          // Resources added to server.cfg
+	ensureErr     error
+}
+
+// ensuredMsg is the outcome of adding the offered ensure lines
+type ensuredMsg struct {
+	ensured []string
+	err     error
+}
 
 type wizardErrorMsg string
 
@@ -872,8 +1400,8 @@ func pollTickCmd() tea.Cmd {
 	})
 }
 
-
-func downloadFilesCmd(m *ConvertWizardModel) tea.Cmd {
+// downloadFilesCmd downloads, extracts and installs the given items' files
+func downloadFilesCmd(m *ConvertWizardModel, items []*ConversionItem) tea.Cmd {
 	return func() tea.Msg {
 		var resourcesPath string
 
@@ -898,25 +1426,21 @@ func downloadFilesCmd(m *ConvertWizardModel) tea.Cmd {
 		}
 
 		var wg sync.WaitGroup
-		errChan := make(chan error, len(m.downloads))
-
-		for _, item := range m.conversions {
-			if item.FileName == "" {
-				continue
-			}
+		var mu sync.Mutex
+		failures := make(map[string]error)
+
+		// Record a per-item failure so one bad download doesn't abort the batch
+		fail := func(url string, err error) {
+			mu.Lock()
+			failures[url] = err
+			mu.Unlock()
+		}
 
+		for _, item := range items {
 			wg.Add(1)
 			go func(convItem *ConversionItem) {
 				defer wg.Done()
 
-				// Create category subfolder (e.g., [vehicles]/)
-				categoryFolder := fmt.Sprintf("[%s]", convItem.Category)
-				categoryPath := filepath.Join(resourcesPath, categoryFolder)
-				if err := os.MkdirAll(categoryPath, 0755); err != nil {
-					errChan <- fmt.Errorf("failed to create category folder: %w", err)
-					return
-				}
-
 				downloadURL := m.client.GetDownloadURL(convItem.FileName)
 				destPath := filepath.Join(resourcesPath, filepath.Base(convItem.FileName))
 
@@ -926,96 +1450,110 @@ func downloadFilesCmd(m *ConvertWizardModel) tea.Cmd {
 				})
 
 				if err != nil {
-					errChan <- fmt.Errorf("failed to download %s: %w", convItem.FileName, err)
+					// Don't leave a truncated archive behind
+					os.Remove(destPath)
+
+					// A file convert.cfx.rs no longer has needs converting again
+					var status *network.StatusError
+					if errors.As(err, &status) && (status.Code == http.StatusNotFound || status.Code == http.StatusGone) {
+						err = convert.ErrFileExpired
+					}
+					fail(convItem.URL, err)
 					return
 				}
 
-				// Extract zip to category subfolder
-				if err := extractZip(destPath, categoryPath); err != nil {
-					errChan <- fmt.Errorf("failed to extract %s: %w", convItem.FileName, err)
+				// Extract to a staging folder first so this mod's files can be inspected on their own
+				stagePath, err := os.MkdirTemp(resourcesPath, ".inkwash-extract-")
+				if err != nil {
+					fail(convItem.URL, fmt.Errorf("failed to create staging folder: %w", err))
 					return
 				}
+				defer os.RemoveAll(stagePath)
 
-				// Remove zip file after extraction
+				// Format detected from extension or contents
+				if err := m.extractor.Extract(destPath, stagePath); err != nil {
+					fail(convItem.URL, fmt.Errorf("failed to extract: %w", err))
+					return
+				}
+
+				// Remove archive after extraction
 				os.Remove(destPath)
+
+				// Generates a missing fxmanifest.lua, so it has to come before installing
+				report, err := convert.Inspect(stagePath)
+				if err != nil {
+					fail(convItem.URL, fmt.Errorf("failed to inspect: %w", err))
+					return
+				}
+
+				build, pack := resource.RequiredGameBuild(stagePath)
+
+				modName := convert.ModFolderName(convItem.URL)
+				_, names, err := convert.Install(stagePath, resourcesPath, m.layout, convItem.Category, modName)
+				if err != nil {
+					fail(convItem.URL, fmt.Errorf("failed to install: %w", err))
+					return
+				}
+
+				mu.Lock()
+				convItem.RequiredGameBuild, convItem.RequiredDLC = build, pack
+				convItem.Resources = names
+				convItem.Report = report
+				mu.Unlock()
 			}(item)
 		}
 
 		wg.Wait()
-		close(errChan)
 
-		// Check for errors
-		if len(errChan) > 0 {
-			return wizardErrorMsg(fmt.Sprintf("Download failed: %v", <-errChan))
+		msg := downloadCompleteMsg{resourcesPath: resourcesPath, items: items, failures: failures}
+
+		// External folders have no server.cfg we know of, so only registered servers are updated
+		if m.autoEnsure && m.externalMode == "" && m.selectedServer != nil {
+			msg.ensured, msg.ensureErr = ensureConverted(m.selectedServer, m.ensureFile, items, failures)
 		}
 
-		return downloadCompleteMsg{}
+		return msg
 	}
 }
 
-// extractZip extracts a zip file to the destination directory
-func extractZip(zipPath, destPath string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return err
+// ensureCmd adds the ensure lines the complete screen offered
+func (m *ConvertWizardModel) ensureCmd() tea.Cmd {
+	srv, file, names := m.selectedServer, m.ensureFile, m.unensured
+	m.unensured = nil
+	return func() tea.Msg {
+		ensured, err := ensureResources(srv, file, names)
+		return ensuredMsg{ensured: ensured, err: err}
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		fpath := filepath.Join(destPath, f.Name)
-
-		// Check for ZipSlip vulnerability
-		cleanDest := filepath.Clean(destPath)
-		cleanPath := filepath.Clean(fpath)
-		if !strings.HasPrefix(cleanPath, cleanDest) {
-			return fmt.Errorf("illegal file path: %s", fpath)
-		}
+}
 
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, os.ModePerm)
+// ensureConverted appends ensure lines for the successfully installed mods to
+// the server's file (server.cfg or resources.cfg) and returns the resources
+// that were added
+func ensureConverted(srv *types.Server, file string, items []*ConversionItem, failures map[string]error) ([]string, error) {
+	var names []string
+	for _, item := range items {
+		if failures[item.URL] != nil {
 			continue
 		}
-
-		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return err
-		}
-
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
-		}
-
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return err
-		}
+		names = append(names, item.Resources...)
 	}
-
-	return nil
+	return ensureResources(srv, file, names)
 }
 
-// extractCategory extracts the mod category from a gta5-mods.com URL
-// e.g., "https://www.gta5-mods.com/vehicles/..." -> "vehicles"
-func extractCategory(url string) string {
-	// Split URL by "/" and find the category after gta5-mods.com
-	parts := strings.Split(url, "/")
-	for i, part := range parts {
-		if part == "www.gta5-mods.com" || part == "gta5-mods.com" {
-			if i+1 < len(parts) {
-				return parts[i+1]
-			}
-		}
+// ensureResources appends ensure lines for the resources that aren't started
+// yet to the server's file and returns the ones that were added
+func ensureResources(srv *types.Server, file string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
 	}
-	return "misc" // Default category
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	return servercfg.AppendEnsuresIn(srv.Path, file, names)
 }
 
 // extractModName extracts a readable mod name from a gta5-mods.com URL
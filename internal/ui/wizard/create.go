@@ -2,14 +2,19 @@ package wizard
 
 import (
 	"fmt"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/database"
 	"github.com/VexoaXYZ/inkwash/internal/download"
+	"github.com/VexoaXYZ/inkwash/internal/recipe"
 	"github.com/VexoaXYZ/inkwash/internal/registry"
 	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/template"
 	"github.com/VexoaXYZ/inkwash/internal/ui"
 	"github.com/VexoaXYZ/inkwash/internal/ui/components"
 	"github.com/VexoaXYZ/inkwash/internal/validation"
@@ -26,7 +31,12 @@ const (
 	StepBuild
 	StepLicenseKey
 	StepPort
+	StepBindAddress
+	StepGameBuild
 	StepPath
+	StepFramework
+	StepDatabase
+	StepDatabasePassword
 	StepConfirm
 	StepInstalling
 	StepComplete
@@ -44,9 +54,14 @@ type CreateWizardModel struct {
 	// Input components
 	nameInput     *components.TextInput
 	portInput     *components.TextInput
+	bindInput     *components.TextInput
 	pathInput     *components.TextInput
+	dbInput       *components.TextInput
+	dbPassInput   *components.TextInput
 	buildSelector *components.Selector
 	keySelector   *components.Selector
+	gameBuildSelector *components.Selector
+	frameworkSelector *components.Selector
 
 	// Progress components
 	progressBar   *components.ProgressBar
@@ -55,9 +70,19 @@ type CreateWizardModel struct {
 	// State
 	serverName    string
 	buildNumber   int
+	buildChannel  string // Set instead of buildNumber when a channel is picked
 	licenseKey    string
+	keyID         string // Vault ID of licenseKey, "" when it was typed in
 	port          int
+	bindAddress   string
+	targetOS      string
+	gameBuild     int
 	installPath   string
+	recipe        *recipe.Recipe    // Run instead of cloning cfx-server-data
+	template      *types.Template   // Framework applied once the server is set up, nil for none
+	templateError string            // Why the chosen framework can't be installed here
+	database      *database.Options // Created during the install, nil to skip
+	warnings      []string          // Install steps to follow up on, listed once it's complete
 	builds        []types.Build
 	keys          []cache.LicenseKey
 	error         string
@@ -65,6 +90,9 @@ type CreateWizardModel struct {
 	quitting      bool
 	completed     bool
 
+	// Crash recovery
+	session       sessionSaver
+
 	// Loading states
 	loadingBuilds bool
 	loadingKeys   bool
@@ -83,8 +111,8 @@ func NewCreateWizard(installer *server.Installer, keyVault *cache.KeyVault, reg
 	// Create input components
 	nameInput := components.NewTextInput("Server Name", "My FiveM Server", 50)
 	nameInput.SetValidator(func(s string) error {
-		if s == "" {
-			return fmt.Errorf("Server name cannot be empty")
+		if err := validation.ValidateServerName(s); err != nil {
+			return err
 		}
 		if reg.Exists(s) {
 			return fmt.Errorf("Server '%s' already exists", s)
@@ -105,6 +133,15 @@ func NewCreateWizard(installer *server.Installer, keyVault *cache.KeyVault, reg
 		return nil
 	})
 
+	bindInput := components.NewTextInput("Bind Address", types.DefaultBindAddress, 45)
+	bindInput.Value = types.DefaultBindAddress
+	bindInput.SetValidator(func(s string) error {
+		if net.ParseIP(s) == nil {
+			return fmt.Errorf("Bind address must be an IP address (e.g. 0.0.0.0 or ::)")
+		}
+		return nil
+	})
+
 	// Use clean absolute path to prevent concatenation issues
 	defaultPath := filepath.Join(registry.GetDefaultConfigPath(), "servers")
 	// Ensure it's absolute and clean (prevents Windows path concatenation issues)
@@ -117,6 +154,19 @@ func NewCreateWizard(installer *server.Installer, keyVault *cache.KeyVault, reg
 	pathInput.Value = defaultPath
 	pathInput.Placeholder = defaultPath
 
+	// Opt-in: the admin account is only used to create the database and user
+	dbInput := components.NewTextInput("Database (optional): MySQL/MariaDB admin as user@host[:port], empty to skip", "root@127.0.0.1:3306", 255)
+	dbInput.SetValidator(func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		_, err := database.ParseAdmin(s)
+		return err
+	})
+
+	dbPassInput := components.NewTextInput("Database admin password (empty for none)", "", 128)
+	dbPassInput.Masked = true
+
 	return &CreateWizardModel{
 		step:           StepServerName,
 		installer:      installer,
@@ -125,11 +175,68 @@ func NewCreateWizard(installer *server.Installer, keyVault *cache.KeyVault, reg
 		registry:       reg,
 		nameInput:      nameInput,
 		portInput:      portInput,
+		bindInput:      bindInput,
 		pathInput:      pathInput,
+		dbInput:        dbInput,
+		dbPassInput:    dbPassInput,
 		progressBar:    components.NewProgressBar(60),
 		spinner:        components.NewSpinner(tier),
 		port:           30120,
+		bindAddress:    types.DefaultBindAddress,
+		gameBuild:      types.DefaultGameBuild,
+		session:        sessionSaver{kind: sessionCreate},
+	}
+}
+
+// Resume prefills the wizard from a saved session. Every step is still shown,
+// with the saved answers filled in or preselected. Call before running the wizard.
+func (m *CreateWizardModel) Resume(s *CreateSession) {
+	m.nameInput.Value = s.ServerName
+	m.nameInput.Validate() // The name may have been taken since
+	m.buildNumber, m.buildChannel = s.BuildNumber, s.BuildChannel
+	if s.Port != 0 {
+		m.port = s.Port
+		m.portInput.Value = strconv.Itoa(s.Port)
+	}
+	if s.BindAddress != "" {
+		m.bindAddress = s.BindAddress
+		m.bindInput.Value = s.BindAddress
+	}
+	if _, known := types.FindGameBuild(s.GameBuild); known || s.GameBuild == types.GameBuildNone {
+		m.gameBuild = s.GameBuild
+	}
+	if s.InstallPath != "" {
+		m.pathInput.Value = s.InstallPath
+	}
+}
+
+// saveSession keeps the session file in step with the answers given so far,
+// and removes it once the server was created
+func (m *CreateWizardModel) saveSession() {
+	if m.completed {
+		m.session.finish()
+		return
+	}
+
+	snapshot := CreateSession{
+		ServerName:   m.serverName,
+		BuildNumber:  m.buildNumber,
+		BuildChannel: m.buildChannel,
+		Port:         m.port,
+		BindAddress:  m.bindAddress,
+		GameBuild:    m.gameBuild,
+		InstallPath:  m.installPath,
 	}
+	if !m.session.changed(snapshot) {
+		return
+	}
+	if snapshot.ServerName == "" {
+		// Nothing worth resuming yet
+		clearSession(sessionCreate)
+		return
+	}
+	snapshot.SavedAt = time.Now()
+	m.session.write(snapshot)
 }
 
 // Init initializes the wizard
@@ -141,6 +248,7 @@ func (m *CreateWizardModel) Init() tea.Cmd {
 // Update handles messages
 func (m *CreateWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
+	defer m.saveSession()
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -178,6 +286,9 @@ func (m *CreateWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case installProgressMsg:
 		m.installProgress = server.InstallProgress(msg)
+		if m.installProgress.Warning {
+			m.warnings = append(m.warnings, m.installProgress.Step)
+		}
 		if m.installProgress.Progress >= 1.0 {
 			m.step = StepComplete
 			m.completed = true
@@ -208,9 +319,18 @@ func (m *CreateWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case StepPort:
 			cmd := m.portInput.Update(msg)
 			return m, cmd
+		case StepBindAddress:
+			cmd := m.bindInput.Update(msg)
+			return m, cmd
 		case StepPath:
 			cmd := m.pathInput.Update(msg)
 			return m, cmd
+		case StepDatabase:
+			cmd := m.dbInput.Update(msg)
+			return m, cmd
+		case StepDatabasePassword:
+			cmd := m.dbPassInput.Update(msg)
+			return m, cmd
 		}
 	}
 
@@ -236,9 +356,33 @@ func (m *CreateWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd := m.portInput.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case StepBindAddress:
+		cmd := m.bindInput.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case StepGameBuild:
+		if m.gameBuildSelector != nil {
+			cmd := m.gameBuildSelector.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case StepFramework:
+		if m.frameworkSelector != nil {
+			cmd := m.frameworkSelector.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
 	case StepPath:
 		cmd := m.pathInput.Update(msg)
 		cmds = append(cmds, cmd)
+
+	case StepDatabase:
+		cmd := m.dbInput.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case StepDatabasePassword:
+		cmd := m.dbPassInput.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -267,15 +411,21 @@ func (m *CreateWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 
 			// If now confirmed, advance to next step
 			if m.buildSelector.Confirmed {
-				if build, ok := m.buildSelector.SelectedValue().(types.Build); ok {
-					m.buildNumber = build.Number
-					m.step = StepLicenseKey
-					m.loadingKeys = true
-					return m, tea.Batch(
-						loadKeysCmd(m.keyVault),
-						m.spinner.TickCmd(),
-					)
+				switch value := m.buildSelector.SelectedValue().(type) {
+				case string: // Build channel
+					m.buildChannel, m.buildNumber = value, 0
+				case types.Build:
+					m.buildChannel, m.buildNumber = "", value.Number
+				default:
+					return m, nil
 				}
+
+				m.step = StepLicenseKey
+				m.loadingKeys = true
+				return m, tea.Batch(
+					loadKeysCmd(m.keyVault),
+					m.spinner.TickCmd(),
+				)
 			}
 		}
 		return m, nil
@@ -289,6 +439,12 @@ func (m *CreateWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 			if m.keySelector.Confirmed {
 				if key, ok := m.keySelector.SelectedValue().(string); ok {
 					m.licenseKey = key
+					m.keyID = ""
+					for _, stored := range m.keys {
+						if stored.Key == key {
+							m.keyID = stored.ID
+						}
+					}
 					m.step = StepPort
 					m.portInput.Focus()
 					return m, m.portInput.BlinkCmd()
@@ -304,9 +460,34 @@ func (m *CreateWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 		}
 		port, _ := strconv.Atoi(m.portInput.Value)
 		m.port = port
-		m.step = StepPath
-		m.pathInput.Focus()
-		return m, m.pathInput.BlinkCmd()
+		m.step = StepBindAddress
+		m.bindInput.Focus()
+		return m, m.bindInput.BlinkCmd()
+
+	case StepBindAddress:
+		m.bindInput.Blur()
+		if m.bindInput.Error != "" {
+			return m, nil
+		}
+		m.bindAddress = strings.TrimSpace(m.bindInput.Value)
+		m.step = StepGameBuild
+		return m.setupGameBuildSelector(), nil
+
+	case StepGameBuild:
+		if m.gameBuildSelector != nil {
+			// Pass Enter to selector to confirm selection
+			m.gameBuildSelector.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+			if m.gameBuildSelector.Confirmed {
+				if gameBuild, ok := m.gameBuildSelector.SelectedValue().(int); ok {
+					m.gameBuild = gameBuild
+					m.step = StepPath
+					m.pathInput.Focus()
+					return m, m.pathInput.BlinkCmd()
+				}
+			}
+		}
+		return m, nil
 
 	case StepPath:
 		m.pathInput.Blur()
@@ -320,6 +501,51 @@ func (m *CreateWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 			}
 		}
 		m.installPath = cleanPath
+		m.step = StepFramework
+		return m.setupFrameworkSelector(), nil
+
+	case StepFramework:
+		if m.frameworkSelector != nil {
+			// Pass Enter to selector to confirm selection
+			m.frameworkSelector.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+			if m.frameworkSelector.Confirmed {
+				t, _ := m.frameworkSelector.SelectedValue().(*types.Template)
+				if t != nil {
+					// Better to find out now than after the download
+					if err := template.CheckRequirements(t, m.port); err != nil {
+						m.templateError = err.Error()
+						m.frameworkSelector.Confirmed = false
+						return m, nil
+					}
+				}
+				m.template, m.templateError = t, ""
+				m.step = StepDatabase
+				m.dbInput.Focus()
+				return m, m.dbInput.BlinkCmd()
+			}
+		}
+		return m, nil
+
+	case StepDatabase:
+		m.dbInput.Blur()
+		if m.dbInput.Error != "" {
+			return m, nil
+		}
+		if strings.TrimSpace(m.dbInput.Value) == "" {
+			m.database = nil
+			m.step = StepConfirm
+			return m, nil
+		}
+		opts, _ := database.ParseAdmin(m.dbInput.Value)
+		m.database = &opts
+		m.step = StepDatabasePassword
+		m.dbPassInput.Focus()
+		return m, m.dbPassInput.BlinkCmd()
+
+	case StepDatabasePassword:
+		m.dbPassInput.Blur()
+		m.database.AdminPassword = m.dbPassInput.Value
 		m.step = StepConfirm
 
 	case StepConfirm:
@@ -339,7 +565,14 @@ func (m *CreateWizardModel) handleEnter() (tea.Model, tea.Cmd) {
 
 // setupBuildSelector creates the build selector with loaded builds
 func (m *CreateWizardModel) setupBuildSelector() *CreateWizardModel {
-	items := make([]components.SelectorItem, len(m.builds))
+	// Pseudo-build first: resolves to whatever is recommended when the install runs
+	items := make([]components.SelectorItem, len(m.builds)+1)
+	items[0] = components.SelectorItem{
+		Label:       "Latest Recommended",
+		Description: "Always the current recommended build, resolved at install time",
+		Value:       types.BuildChannelRecommended,
+	}
+
 	for i, build := range m.builds {
 		label := fmt.Sprintf("Build %d", build.Number)
 		desc := ""
@@ -351,7 +584,7 @@ func (m *CreateWizardModel) setupBuildSelector() *CreateWizardModel {
 			desc = "Latest features, may be unstable"
 		}
 
-		items[i] = components.SelectorItem{
+		items[i+1] = components.SelectorItem{
 			Label:       label,
 			Description: desc,
 			Value:       build,
@@ -360,19 +593,115 @@ func (m *CreateWizardModel) setupBuildSelector() *CreateWizardModel {
 
 	m.buildSelector = components.NewSelector("Select FXServer Build", items)
 	m.buildSelector.MaxHeight = 10
+	// Keep an earlier choice (e.g. from a resumed session) selected
+	for i, item := range items {
+		if build, ok := item.Value.(types.Build); ok && m.buildNumber != 0 && build.Number == m.buildNumber {
+			m.buildSelector.Selected = i
+		}
+	}
 	m.buildSelector.Focus()
 	return m
 }
 
+// setupGameBuildSelector creates the enforced game build selector, newest first
+func (m *CreateWizardModel) setupGameBuildSelector() *CreateWizardModel {
+	items := make([]components.SelectorItem, 0, len(types.GameBuilds)+1)
+	selected := 0
+
+	for i := len(types.GameBuilds) - 1; i >= 0; i-- {
+		build := types.GameBuilds[i]
+		desc := build.DLC
+		if build.Number == types.DefaultGameBuild {
+			desc += " (default)"
+		}
+		if build.Number == m.gameBuild {
+			selected = len(items)
+		}
+
+		items = append(items, components.SelectorItem{
+			Label:       fmt.Sprintf("Build %d", build.Number),
+			Description: desc,
+			Value:       build.Number,
+		})
+	}
+
+	if m.gameBuild == types.GameBuildNone {
+		selected = len(items)
+	}
+	items = append(items, components.SelectorItem{
+		Label:       "None",
+		Description: "Don't enforce a game build (base game content only)",
+		Value:       types.GameBuildNone,
+	})
+
+	m.gameBuildSelector = components.NewSelector("Select Enforced Game Build (sv_enforceGameBuild)", items)
+	m.gameBuildSelector.MaxHeight = 10
+	m.gameBuildSelector.Selected = selected
+	m.gameBuildSelector.Focus()
+	return m
+}
+
+// setupFrameworkSelector creates the framework selector from the built-in
+// templates, plus one given with --template
+func (m *CreateWizardModel) setupFrameworkSelector() *CreateWizardModel {
+	items := []components.SelectorItem{{
+		Label:       "None",
+		Description: "Only the default cfx-server-data resources",
+		Value:       (*types.Template)(nil),
+	}}
+	selected := 0
+
+	presets := template.Presets()
+	given := m.template != nil
+	for i := range presets {
+		t := &presets[i]
+		if m.template != nil && m.template.Name == t.Name {
+			t, given = m.template, false
+			selected = len(items)
+		}
+		items = append(items, components.SelectorItem{
+			Label:       t.Name,
+			Description: t.Description,
+			Value:       t,
+		})
+	}
+	if given {
+		selected = len(items)
+		items = append(items, components.SelectorItem{
+			Label:       m.template.Name,
+			Description: m.template.Description,
+			Value:       m.template,
+		})
+	}
+
+	m.frameworkSelector = components.NewSelector("Select Framework", items)
+	m.frameworkSelector.Selected = selected
+	m.frameworkSelector.Focus()
+	return m
+}
+
 // setupKeySelector creates the key selector with loaded keys
 func (m *CreateWizardModel) setupKeySelector() *CreateWizardModel {
 	items := make([]components.SelectorItem, len(m.keys)+1)
 
-	// Add existing keys
+	// Add existing keys, noting the servers already using them since only
+	// one server per key can be online
+	usedBy := make(map[string][]string)
+	if m.registry != nil {
+		for _, srv := range m.registry.List() {
+			if srv.KeyID != "" {
+				usedBy[srv.KeyID] = append(usedBy[srv.KeyID], srv.Name)
+			}
+		}
+	}
 	for i, key := range m.keys {
+		description := validation.MaskKey(key.Key)
+		if names := usedBy[key.ID]; len(names) > 0 {
+			description += " · used by " + strings.Join(names, ", ")
+		}
 		items[i] = components.SelectorItem{
 			Label:       key.Label,
-			Description: validation.MaskKey(key.Key),
+			Description: description,
 			Value:       key.Key,
 		}
 	}
@@ -414,7 +743,7 @@ func (m *CreateWizardModel) View() string {
 		Foreground(ui.ColorMediumGray)
 
 	stepNum := int(m.step) + 1
-	totalSteps := 6 // Not counting Installing, Complete, Error
+	totalSteps := 11 // Not counting Installing, Complete, Error
 	if m.step >= StepInstalling {
 		stepNum = totalSteps
 	}
@@ -446,9 +775,37 @@ func (m *CreateWizardModel) View() string {
 	case StepPort:
 		b.WriteString(m.portInput.View())
 
+	case StepBindAddress:
+		b.WriteString(m.bindInput.View())
+
+	case StepGameBuild:
+		if m.gameBuildSelector != nil {
+			b.WriteString(m.gameBuildSelector.View())
+		}
+
 	case StepPath:
 		b.WriteString(m.pathInput.View())
 
+	case StepFramework:
+		if m.frameworkSelector != nil {
+			b.WriteString(m.frameworkSelector.View())
+		}
+		if m.templateError != "" {
+			b.WriteString("\n")
+			b.WriteString(lipgloss.NewStyle().Foreground(ui.ColorError).Render(m.templateError))
+		}
+
+	case StepDatabase:
+		b.WriteString(m.dbInput.View())
+		if m.template != nil && m.template.Database != nil {
+			b.WriteString("\n\n")
+			b.WriteString(lipgloss.NewStyle().Foreground(ui.ColorMediumGray).Render(
+				fmt.Sprintf("%s needs a database; if you skip this, set it up later with 'inkwash db setup'", m.template.Name)))
+		}
+
+	case StepDatabasePassword:
+		b.WriteString(m.dbPassInput.View())
+
 	case StepConfirm:
 		b.WriteString(m.renderConfirmation())
 
@@ -496,7 +853,11 @@ func (m *CreateWizardModel) renderConfirmation() string {
 	b.WriteString("\n")
 
 	b.WriteString(labelStyle.Render("Build Number:   "))
-	b.WriteString(valueStyle.Render(fmt.Sprintf("%d", m.buildNumber)))
+	if m.buildChannel != "" {
+		b.WriteString(valueStyle.Render(fmt.Sprintf("Latest %s (resolved at install)", m.buildChannel)))
+	} else {
+		b.WriteString(valueStyle.Render(fmt.Sprintf("%d", m.buildNumber)))
+	}
 	b.WriteString("\n")
 
 	b.WriteString(labelStyle.Render("License Key:    "))
@@ -507,9 +868,40 @@ func (m *CreateWizardModel) renderConfirmation() string {
 	b.WriteString(valueStyle.Render(fmt.Sprintf("%d", m.port)))
 	b.WriteString("\n")
 
+	b.WriteString(labelStyle.Render("Bind Address:   "))
+	b.WriteString(valueStyle.Render(m.bindAddress))
+	b.WriteString("\n")
+
+	b.WriteString(labelStyle.Render("Game Build:     "))
+	b.WriteString(valueStyle.Render(types.GameBuildLabel(m.gameBuild)))
+	b.WriteString("\n")
+
 	b.WriteString(labelStyle.Render("Install Path:   "))
 	b.WriteString(valueStyle.Render(m.installPath))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+
+	if m.recipe != nil {
+		b.WriteString(labelStyle.Render("Recipe:         "))
+		b.WriteString(valueStyle.Render(m.recipe.Name))
+		b.WriteString("\n")
+	}
+
+	if m.template != nil {
+		b.WriteString(labelStyle.Render("Framework:      "))
+		b.WriteString(valueStyle.Render(m.template.Name))
+		b.WriteString("\n")
+	}
+
+	if m.database != nil {
+		port := m.database.Port
+		if port == 0 {
+			port = database.DefaultPort
+		}
+		b.WriteString(labelStyle.Render("Database:       "))
+		b.WriteString(valueStyle.Render(fmt.Sprintf("%s on %s", database.DefaultName(m.serverName), net.JoinHostPort(m.database.Host, strconv.Itoa(port)))))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
 	b.WriteString(headerStyle.Render("Press Enter to start installation"))
 
@@ -610,6 +1002,16 @@ func (m *CreateWizardModel) renderComplete() string {
 	b.WriteString(nameStyle.Render(m.serverName))
 	b.WriteString("\n\n")
 
+	if len(m.warnings) > 0 {
+		warningStyle := lipgloss.NewStyle().
+			Foreground(ui.ColorWarning)
+		for _, warning := range m.warnings {
+			b.WriteString(warningStyle.Render(ui.SymbolWarning + " " + warning))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
 	// Divider
 	dividerStyle := lipgloss.NewStyle().
 		Foreground(ui.ColorMediumGray)
@@ -708,6 +1110,25 @@ func (m *CreateWizardModel) ServerName() string {
 	return m.serverName
 }
 
+// SetTargetOS selects the platform to list and install builds for
+func (m *CreateWizardModel) SetTargetOS(targetOS string) error {
+	if err := m.artifactClient.SetTargetOS(targetOS); err != nil {
+		return err
+	}
+	m.targetOS = m.artifactClient.TargetOS()
+	return nil
+}
+
+// SetRecipe sets the server up from a txAdmin recipe, nil for cfx-server-data
+func (m *CreateWizardModel) SetRecipe(r *recipe.Recipe) {
+	m.recipe = r
+}
+
+// SetTemplate preselects the framework template to apply, nil for none
+func (m *CreateWizardModel) SetTemplate(t *types.Template) {
+	m.template = t
+}
+
 // Messages
 
 type buildsLoadedMsg struct {
@@ -753,13 +1174,32 @@ func installServerCmd(m *CreateWizardModel) tea.Cmd {
 
 		// Run installation in a goroutine
 		go func() {
+			opts := server.InstallOptions{
+				ServerName:   m.serverName,
+				InstallPath:  m.installPath,
+				BuildNumber:  m.buildNumber,
+				BuildChannel: m.buildChannel,
+				LicenseKey:   m.licenseKey,
+				KeyID:        m.keyID,
+				Port:         m.port,
+				BindAddress:  m.bindAddress,
+				TargetOS:     m.targetOS,
+				GameBuild:    m.gameBuild,
+				Recipe:       m.recipe,
+				Database:     m.database,
+
+				Template:      m.template,
+				ApplyTemplate: template.ApplyNew,
+			}
+
 			err := m.installer.Install(
-				m.serverName,
-				m.installPath,
-				m.buildNumber,
-				m.licenseKey,
-				m.port,
+				opts,
 				func(progress server.InstallProgress) {
+					if progress.Warning {
+						// Listed at the end, so these can't be dropped
+						progressChan <- progress
+						return
+					}
 					select {
 					case progressChan <- progress:
 					default:
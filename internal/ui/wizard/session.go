@@ -0,0 +1,180 @@
+package wizard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+)
+
+// Session files hold what was entered in a wizard so far, so a wizard that
+// crashed or was closed can be resumed. They're rewritten whenever the
+// answers change and removed when the wizard completes. A convert session
+// also tracks each URL's conversion and is kept while any of them failed.
+const (
+	sessionConvert = "convert"
+	sessionCreate  = "create"
+)
+
+// ConvertSession is the saved state of an unfinished convert wizard
+type ConvertSession struct {
+	URLs         []string             `json:"urls"`
+	Items        []ConvertSessionItem `json:"items,omitempty"`         // How far each URL got, once converting started
+	Server       string               `json:"server,omitempty"`        // Registered server name
+	ExternalMode string               `json:"external_mode,omitempty"` // "current" or "custom" for external servers
+	CustomPath   string               `json:"custom_path,omitempty"`
+	SavedAt      time.Time            `json:"saved_at"`
+}
+
+// ConvertSessionItem is how far one URL of a convert session got
+type ConvertSessionItem struct {
+	URL       string `json:"url"`
+	UUID      string `json:"uuid,omitempty"`      // Conversion on convert.cfx.rs, polled again on resume
+	File      string `json:"file,omitempty"`      // Converted file, downloaded on resume without converting again
+	Installed bool   `json:"installed,omitempty"` // Downloaded and installed, skipped on resume
+	Error     string `json:"error,omitempty"`     // Why it failed last time
+}
+
+// Pending returns the session's URLs that aren't installed yet
+func (s *ConvertSession) Pending() []string {
+	installed := make(map[string]bool)
+	for _, item := range s.Items {
+		installed[item.URL] = item.Installed
+	}
+
+	var pending []string
+	for _, url := range s.URLs {
+		if !installed[url] {
+			pending = append(pending, url)
+		}
+	}
+	return pending
+}
+
+// CreateSession is the saved state of an unfinished create wizard. The
+// license key is left out on purpose; it's picked again on resume.
+type CreateSession struct {
+	ServerName   string    `json:"server_name,omitempty"`
+	BuildNumber  int       `json:"build_number,omitempty"`
+	BuildChannel string    `json:"build_channel,omitempty"`
+	Port         int       `json:"port,omitempty"`
+	BindAddress  string    `json:"bind_address,omitempty"`
+	GameBuild    int       `json:"game_build,omitempty"`
+	InstallPath  string    `json:"install_path,omitempty"`
+	SavedAt      time.Time `json:"saved_at"`
+}
+
+// sessionPath returns where the session of the given wizard is saved
+func sessionPath(kind string) string {
+	return filepath.Join(registry.GetDefaultConfigPath(), "sessions", kind+".json")
+}
+
+// LoadConvertSession returns the saved convert session, or nil if there is none
+func LoadConvertSession() (*ConvertSession, error) {
+	var s ConvertSession
+	found, err := loadSession(sessionConvert, &s)
+	if !found || err != nil || len(s.Pending()) == 0 {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadCreateSession returns the saved create session, or nil if there is none
+func LoadCreateSession() (*CreateSession, error) {
+	var s CreateSession
+	found, err := loadSession(sessionCreate, &s)
+	if !found || err != nil || s.ServerName == "" {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ClearConvertSession removes the saved convert session
+func ClearConvertSession() error {
+	return clearSession(sessionConvert)
+}
+
+// ClearCreateSession removes the saved create session
+func ClearCreateSession() error {
+	return clearSession(sessionCreate)
+}
+
+// loadSession reads a session file into v. A missing file isn't an error.
+func loadSession(kind string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(sessionPath(kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read saved %s session: %w", kind, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		// A damaged session isn't worth failing over, drop it
+		clearSession(kind)
+		return false, nil
+	}
+	return true, nil
+}
+
+// clearSession removes a session file if it exists
+func clearSession(kind string) error {
+	if err := os.Remove(sessionPath(kind)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove saved %s session: %w", kind, err)
+	}
+	return nil
+}
+
+// sessionSaver writes a wizard's session whenever it changes
+type sessionSaver struct {
+	kind string
+	last interface{} // Last snapshot written, without SavedAt
+	done bool        // Set once the wizard completed; nothing is saved after that
+}
+
+// changed reports whether snapshot differs from the last one written, and
+// remembers it. Snapshots are compared without their SavedAt.
+func (s *sessionSaver) changed(snapshot interface{}) bool {
+	if s.done || reflect.DeepEqual(s.last, snapshot) {
+		return false
+	}
+	s.last = snapshot
+	return true
+}
+
+// write saves a session. Saving is best effort: a failed write must never
+// interrupt the wizard.
+func (s *sessionSaver) write(session interface{}) {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return
+	}
+
+	path := sessionPath(s.kind)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	// Write then rename, so a crash mid-write doesn't leave half a session
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// finish removes the session once the wizard completed
+func (s *sessionSaver) finish() {
+	if s.done {
+		return
+	}
+	s.done = true
+	clearSession(s.kind)
+}
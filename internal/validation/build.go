@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ParseBuild parses a build flag value: either a build number, or one of
+// "recommended", "optional" or "latest", returned as a channel to resolve at install time
+func ParseBuild(value string) (int, string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch value {
+	case types.BuildChannelRecommended, types.BuildChannelOptional, types.BuildChannelLatest:
+		return 0, value, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, "", &ValidationError{
+			Field:   "build",
+			Message: fmt.Sprintf("%q is not a build number", value),
+			Hint:    "Use a build such as 17000, or recommended, optional or latest",
+		}
+	}
+
+	return number, "", nil
+}
+
+// ParseChannel parses a build channel for a server to follow: "recommended",
+// "optional", "latest" or "pinned"
+func ParseChannel(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch value {
+	case types.BuildChannelRecommended, types.BuildChannelOptional, types.BuildChannelLatest, types.BuildChannelPinned:
+		return value, nil
+	}
+
+	return "", &ValidationError{
+		Field:   "channel",
+		Message: fmt.Sprintf("%q is not a build channel", value),
+		Hint:    "Use recommended, optional, latest or pinned",
+	}
+}
+
+// artifactBuildPattern matches the "<number>-<hash>/" folder the artifacts server puts builds in
+var artifactBuildPattern = regexp.MustCompile(`/(\d+)-([0-9a-fA-F]{40})/`)
+
+// artifactExtensions maps the archive formats FXServer builds can be installed from to their canonical extension
+var artifactExtensions = []struct{ suffix, ext string }{
+	{".7z", ".7z"},
+	{".tar.xz", ".tar.xz"},
+	{".tar.gz", ".tar.gz"},
+	{".tgz", ".tar.gz"},
+	{".zip", ".zip"},
+}
+
+// ParseArtifactURL checks a direct FXServer archive URL and returns the build
+// it points to and the archive's extension. The build number and hash come
+// from the artifacts server's "<number>-<hash>/" layout; for other URLs the
+// returned build's Number is 0.
+func ParseArtifactURL(raw string) (types.Build, string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return types.Build{}, "", &ValidationError{
+			Field:   "artifact-url",
+			Message: fmt.Sprintf("%q is not an http(s) URL", raw),
+			Hint:    "Use the full link to the archive, e.g. https://runtime.fivem.net/artifacts/.../server.7z",
+		}
+	}
+
+	ext := ""
+	lower := strings.ToLower(u.Path)
+	for _, a := range artifactExtensions {
+		if strings.HasSuffix(lower, a.suffix) {
+			ext = a.ext
+			break
+		}
+	}
+	if ext == "" {
+		return types.Build{}, "", &ValidationError{
+			Field:   "artifact-url",
+			Message: fmt.Sprintf("can't tell the archive format of %s", path.Base(u.Path)),
+			Hint:    "The URL must end in .7z, .tar.xz, .tar.gz or .zip",
+		}
+	}
+
+	var build types.Build
+	if m := artifactBuildPattern.FindStringSubmatch(u.Path); m != nil {
+		build.Number, _ = strconv.Atoi(m[1])
+		build.Hash = strings.ToLower(m[2])
+	}
+
+	return build, ext, nil
+}
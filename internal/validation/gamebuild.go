@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ParseGameBuild parses a game build flag value: a build number, "none" or "default"
+func ParseGameBuild(value string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "default":
+		return types.DefaultGameBuild, nil
+	case "none", "off":
+		return types.GameBuildNone, nil
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, &ValidationError{
+			Field:   "game_build",
+			Message: fmt.Sprintf("%q is not a build number", value),
+			Hint:    "Use a build such as 2802, or none",
+		}
+	}
+
+	return number, ValidateGameBuild(number)
+}
+
+// ValidateGameBuild checks that sv_enforceGameBuild accepts a build
+func ValidateGameBuild(number int) error {
+	if number == types.GameBuildNone {
+		return nil
+	}
+
+	if _, ok := types.FindGameBuild(number); !ok {
+		known := make([]string, len(types.GameBuilds))
+		for i, build := range types.GameBuilds {
+			known[i] = strconv.Itoa(build.Number)
+		}
+		return &ValidationError{
+			Field:   "game_build",
+			Message: fmt.Sprintf("%d is not a game build FXServer can enforce", number),
+			Hint:    "Known builds: " + strings.Join(known, ", ") + ", or none",
+		}
+	}
+
+	return nil
+}
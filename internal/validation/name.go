@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxServerNameLength keeps server folder paths well within filesystem limits
+const maxServerNameLength = 64
+
+// reservedWindowsNames are device names Windows reserves in every directory,
+// with or without an extension
+var reservedWindowsNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ValidateServerName checks that a server name is safe to use as a registry
+// key and as part of a folder path on every platform
+func ValidateServerName(name string) error {
+	invalid := func(message string) error {
+		return &ValidationError{
+			Field:   "name",
+			Message: message,
+			Hint:    "Use letters, numbers, spaces, hyphens and underscores, e.g. \"My Server\" or roleplay-1",
+		}
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return invalid("server name cannot be empty")
+	}
+	if len(name) > maxServerNameLength {
+		return invalid(fmt.Sprintf("server name is %d characters long (maximum %d)", len(name), maxServerNameLength))
+	}
+	if name != strings.TrimSpace(name) {
+		return invalid("server name cannot start or end with a space")
+	}
+
+	for _, r := range name {
+		switch {
+		case unicode.IsControl(r):
+			return invalid("server name cannot contain control characters")
+		case r == '/' || r == '\\':
+			return invalid(fmt.Sprintf("server name cannot contain path separators (%q)", r))
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return invalid(fmt.Sprintf("server name cannot contain %q", r))
+		}
+	}
+
+	if name == "." || strings.Contains(name, "..") {
+		return invalid("server name cannot contain \"..\"")
+	}
+	if strings.HasSuffix(name, ".") {
+		return invalid("server name cannot end with a dot")
+	}
+
+	// "nul.txt" is as reserved as "NUL"
+	base := strings.ToUpper(strings.SplitN(name, ".", 2)[0])
+	if reservedWindowsNames[strings.TrimSpace(base)] {
+		return invalid(fmt.Sprintf("%q is a reserved device name on Windows", name))
+	}
+
+	return nil
+}
+
+// maxTemplateNameLength matches server names, for the same reason
+const maxTemplateNameLength = 64
+
+// ValidateTemplateName checks that a template name is safe to use as a file
+// name in the template folder
+func ValidateTemplateName(name string) error {
+	invalid := func(message string) error {
+		return &ValidationError{
+			Field:   "template",
+			Message: message,
+			Hint:    "Use letters, numbers, dots, hyphens and underscores, e.g. roleplay or qbox-base",
+		}
+	}
+
+	if name == "" {
+		return invalid("template name cannot be empty")
+	}
+	if len(name) > maxTemplateNameLength {
+		return invalid(fmt.Sprintf("template name is %d characters long (maximum %d)", len(name), maxTemplateNameLength))
+	}
+	for i, r := range name {
+		isWord := r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_')
+		if !isWord && (r != '.' || i == 0) {
+			return invalid(fmt.Sprintf("template name cannot contain %q", r))
+		}
+	}
+	if strings.HasSuffix(name, ".") {
+		return invalid("template name cannot end with a dot")
+	}
+	if reservedWindowsNames[strings.ToUpper(strings.SplitN(name, ".", 2)[0])] {
+		return invalid(fmt.Sprintf("%q is a reserved device name on Windows", name))
+	}
+	return nil
+}
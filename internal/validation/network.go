@@ -0,0 +1,26 @@
+package validation
+
+import (
+	"net"
+)
+
+// ValidateBindAddress validates an IPv4 or IPv6 address to bind a server to
+func ValidateBindAddress(addr string) error {
+	if addr == "" {
+		return &ValidationError{
+			Field:   "bind_address",
+			Message: "Bind address cannot be empty",
+			Hint:    "Use 0.0.0.0 for all IPv4 interfaces or :: for all IPv6 interfaces",
+		}
+	}
+
+	if net.ParseIP(addr) == nil {
+		return &ValidationError{
+			Field:   "bind_address",
+			Message: "Bind address must be an IP address (e.g. 0.0.0.0, 192.168.1.10 or ::)",
+			Hint:    "Hostnames aren't supported by endpoint_add_tcp/udp",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ValidatePathWritable creates the directory if needed and checks that a file can be written to it
+func ValidatePathWritable(path string) error {
+	if path == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+
+	testFile := filepath.Join(path, ".inkwash-test")
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		return fmt.Errorf("path not writable: %w", err)
+	}
+	os.Remove(testFile)
+
+	return nil
+}
@@ -0,0 +1,46 @@
+// Package web serves the InkWash web panel: a small browser UI for listing,
+// starting and stopping servers, reading their logs and watching their CPU,
+// RAM and player counts, on top of the REST API in internal/api.
+package web
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/VexoaXYZ/inkwash/internal/api"
+)
+
+// DefaultListen is where the panel listens unless told otherwise; only this
+// machine can reach it
+const DefaultListen = "127.0.0.1:8484"
+
+//go:embed static
+var static embed.FS
+
+// Panel serves the web UI and the API it runs on
+type Panel struct {
+	api *api.API
+}
+
+// New returns a panel for an API
+func New(a *api.API) *Panel {
+	return &Panel{api: a}
+}
+
+// Handler returns the panel's routes. The page itself holds no data and is
+// served to anyone; the API under api.Prefix needs the token.
+func (p *Panel) Handler() http.Handler {
+	files, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle(api.Prefix+"/", p.api.Handler())
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	return mux
+}
+
+// ListenAndServe serves the panel on address until ctx is cancelled
+func (p *Panel) ListenAndServe(ctx context.Context, address string) error {
+	return p.api.ListenAndServe(ctx, address, p.Handler())
+}
@@ -7,5 +7,6 @@ import (
 var version = "dev"
 
 func main() {
+	cmd.SetVersion(version)
 	cmd.Execute()
 }
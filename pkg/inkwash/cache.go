@@ -0,0 +1,54 @@
+package inkwash
+
+import (
+	"fmt"
+	"time"
+)
+
+// CachedBuild is an FXServer build kept in the local cache
+type CachedBuild struct {
+	Number     int
+	Size       int64 // Bytes on disk
+	Downloaded time.Time
+	LastUsed   time.Time
+}
+
+// CachedBuilds lists the builds in the cache
+func (c *Client) CachedBuilds() ([]CachedBuild, error) {
+	binaryCache, err := c.binaryCache()
+	if err != nil {
+		return nil, err
+	}
+
+	var builds []CachedBuild
+	for _, b := range binaryCache.List() {
+		builds = append(builds, CachedBuild{
+			Number:     b.Number,
+			Size:       b.Size,
+			Downloaded: b.Downloaded,
+			LastUsed:   b.LastUsed,
+		})
+	}
+	return builds, nil
+}
+
+// ClearCache removes every cached build. Installed servers keep their own copy.
+func (c *Client) ClearCache() error {
+	binaryCache, err := c.binaryCache()
+	if err != nil {
+		return err
+	}
+	return binaryCache.Clear()
+}
+
+// RemoveCachedBuild deletes one build from the cache
+func (c *Client) RemoveCachedBuild(number int) error {
+	binaryCache, err := c.binaryCache()
+	if err != nil {
+		return err
+	}
+	if !binaryCache.Has(number) {
+		return fmt.Errorf("%w: %d", ErrNotCached, number)
+	}
+	return binaryCache.Remove(number)
+}
@@ -0,0 +1,255 @@
+package inkwash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/convert"
+	"github.com/VexoaXYZ/inkwash/internal/download"
+)
+
+// Mod layouts accepted by ConvertOptions.Layout
+const (
+	LayoutCategory = convert.LayoutCategory // resources/[category]/<mod>/
+	LayoutMod      = convert.LayoutMod      // resources/<mod>/
+	LayoutFlat     = convert.LayoutFlat     // resources/, as laid out in the archive
+)
+
+// ConvertReport is what was checked and fixed in a converted mod before it
+// was installed, and the spawn names of its vehicles
+type ConvertReport = convert.Report
+
+// ConvertOptions controls where and how a converted mod is installed
+type ConvertOptions struct {
+	ResourcesPath string        // Folder the mod is installed under, usually <server>/resources
+	Layout        string        // Layout*, defaults to LayoutCategory
+	Timeout       time.Duration // Per request to convert.cfx.rs, defaults to 30s
+	MaxWait       time.Duration // How long to wait for the conversion, defaults to 10 minutes
+	PollInterval  time.Duration // Time between progress checks, defaults to 2s
+	Name          string        // Folder name for the mod, defaults to one made from its URL or file name
+	Category      string        // [category] of LayoutCategory, defaults to the gta5-mods.com category
+
+	// OnStep, if set, is called as the mod moves through the Convert* steps
+	OnStep func(step string)
+
+	// OnRetry, if set, is called when a request in step failed with what
+	// looks like a transient error and is about to be tried again after wait
+	OnRetry func(step string, attempt int, err error, wait time.Duration)
+
+	// OnInspect, if set, is given what was found in the mod before it's
+	// installed: generated manifests, unreferenced data files and vehicles
+	OnInspect func(report *ConvertReport)
+}
+
+// Steps reported to ConvertOptions.OnStep
+const (
+	ConvertStepConverting  = "converting"
+	ConvertStepDownloading = "downloading"
+	ConvertStepExtracting  = "extracting"
+	ConvertStepInstalling  = "installing"
+)
+
+// Convert converts a gta5-mods.com mod with convert.cfx.rs, installs it into
+// opts.ResourcesPath and returns the folder it was installed to. A missing
+// fxmanifest.lua is generated first, see opts.OnInspect. The mod is
+// recorded in the convert history, so `inkwash convert redownload` can fetch it again.
+func (c *Client) Convert(ctx context.Context, modURL string, opts ConvertOptions) (string, error) {
+	layout := opts.Layout
+	if layout == "" {
+		layout = convert.DefaultLayout
+	}
+	if err := convert.ValidateLayout(layout); err != nil {
+		return "", err
+	}
+
+	maxWait := opts.MaxWait
+	if maxWait <= 0 {
+		maxWait = 10 * time.Minute
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = convert.DefaultPollInterval
+	}
+
+	if err := os.MkdirAll(opts.ResourcesPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create resources directory: %w", err)
+	}
+
+	step := func(name string) {
+		if opts.OnStep != nil {
+			opts.OnStep(name)
+		}
+	}
+
+	client := convert.NewClientWithTimeout(opts.Timeout)
+	if opts.OnRetry != nil {
+		client.OnRetry(func(request string, attempt int, err error, wait time.Duration) {
+			step := ConvertStepConverting
+			if request == convert.RequestDownload {
+				step = ConvertStepDownloading
+			}
+			opts.OnRetry(step, attempt, err, wait)
+		})
+	}
+
+	step(ConvertStepConverting)
+	uuid, err := client.StartConversionContext(ctx, modURL)
+	if err != nil {
+		return "", err
+	}
+
+	status, err := client.WaitForConversionContext(ctx, uuid, pollInterval, maxWait)
+	if err != nil {
+		return "", err
+	}
+
+	archivePath := filepath.Join(opts.ResourcesPath, filepath.Base(status.File))
+	defer os.Remove(archivePath)
+
+	step(ConvertStepDownloading)
+	if err := client.DownloadFileContext(ctx, client.GetDownloadURL(status.File), archivePath); err != nil {
+		return "", err
+	}
+
+	stagePath, err := os.MkdirTemp(opts.ResourcesPath, ".inkwash-extract-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging folder: %w", err)
+	}
+	defer os.RemoveAll(stagePath)
+
+	step(ConvertStepExtracting)
+	if err := download.NewExtractor().Extract(archivePath, stagePath); err != nil {
+		return "", fmt.Errorf("failed to extract: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	step(ConvertStepInstalling)
+	report, err := convert.Inspect(stagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect: %w", err)
+	}
+	if opts.OnInspect != nil {
+		opts.OnInspect(report)
+	}
+
+	category := convert.ModCategory(modURL)
+	if opts.Category != "" {
+		category = opts.Category
+	}
+	name := convert.ModFolderName(modURL)
+	if opts.Name != "" {
+		name = opts.Name
+	}
+	installPath, _, err := convert.Install(stagePath, opts.ResourcesPath, layout, category, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to install: %w", err)
+	}
+
+	// History is best-effort, like in the convert wizard
+	if history, err := convert.LoadHistory(convert.GetHistoryPath()); err == nil {
+		history.Record(convert.HistoryEntry{
+			URL:           modURL,
+			UUID:          uuid,
+			File:          status.File,
+			Category:      category,
+			ResourcesPath: opts.ResourcesPath,
+			ConvertedAt:   time.Now(),
+		})
+	}
+
+	return installPath, nil
+}
+
+// ConvertFile converts a mod that was already downloaded from gta5-mods.com
+// without convert.cfx.rs and installs it like Convert. path is the .zip,
+// .rar, .7z or .oiv archive, its extracted folder or a bare dlc.rpf. FiveM
+// resources in the mod are installed as they are; otherwise the assets in
+// its dlc.rpf are laid out as a resource (see convert.ConvertLocal).
+// Archives encrypted with the game's keys can't be read, those mods need
+// Convert. Without opts.Category, mods with vehicles go in [vehicles] and
+// others in [misc]. Local mods aren't recorded in the convert history.
+func (c *Client) ConvertFile(ctx context.Context, path string, opts ConvertOptions) (string, error) {
+	layout := opts.Layout
+	if layout == "" {
+		layout = convert.DefaultLayout
+	}
+	if err := convert.ValidateLayout(layout); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(opts.ResourcesPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create resources directory: %w", err)
+	}
+
+	step := func(name string) {
+		if opts.OnStep != nil {
+			opts.OnStep(name)
+		}
+	}
+
+	src := path
+	if !info.IsDir() && !strings.EqualFold(filepath.Ext(path), ".rpf") {
+		extractPath, err := os.MkdirTemp(opts.ResourcesPath, ".inkwash-extract-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create staging folder: %w", err)
+		}
+		defer os.RemoveAll(extractPath)
+
+		step(ConvertStepExtracting)
+		if err := download.NewExtractor().Extract(path, extractPath); err != nil {
+			return "", fmt.Errorf("failed to extract: %w", err)
+		}
+		src = extractPath
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	stagePath, err := os.MkdirTemp(opts.ResourcesPath, ".inkwash-extract-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging folder: %w", err)
+	}
+	defer os.RemoveAll(stagePath)
+
+	step(ConvertStepConverting)
+	report, err := convert.ConvertLocal(src, stagePath)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	step(ConvertStepInstalling)
+	if opts.OnInspect != nil {
+		opts.OnInspect(report)
+	}
+
+	category := opts.Category
+	if category == "" {
+		category = "misc"
+		if len(report.Vehicles) > 0 {
+			category = "vehicles"
+		}
+	}
+	name := opts.Name
+	if name == "" {
+		name = convert.LocalModName(path)
+	}
+	installPath, _, err := convert.Install(stagePath, opts.ResourcesPath, layout, category, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to install: %w", err)
+	}
+	return installPath, nil
+}
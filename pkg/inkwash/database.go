@@ -0,0 +1,46 @@
+package inkwash
+
+import (
+	"context"
+
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+)
+
+// DatabaseOptions describes the MySQL/MariaDB database SetupDatabase creates
+type DatabaseOptions = database.Options
+
+// DatabaseResult is what SetupDatabase did, including the user's password
+type DatabaseResult = database.Result
+
+// SetupDatabase creates a database and user for a server on a local or remote
+// MySQL/MariaDB, runs the given SQL files and writes the connection string to
+// the server's server.cfg as mysql_connection_string, which oxmysql reads.
+// Database and User default to a name derived from the server's. It needs the
+// mysql or mariadb client, returning ErrDatabaseClientNotFound without one.
+func (c *Client) SetupDatabase(ctx context.Context, name string, opts DatabaseOptions, onStep func(step string)) (*DatabaseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.ForServer(srv.Name)
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	result, err := database.Setup(ctx, opts, onStep)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.WriteConnectionString(server.ProfileConfigPath(srv.Path, ""), result.ConnectionString); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package inkwash
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// HealthReport is the outcome of one round of health checks; Failures lists
+// the probes that failed
+type HealthReport = server.HealthReport
+
+// CheckHealth runs a running server's health checks once, with the settings
+// in its metadata.json (defaults if there are none)
+func (c *Client) CheckHealth(ctx context.Context, name string) (*HealthReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !c.pm.IsRunning(srv) {
+		return nil, fmt.Errorf("%w: '%s'", ErrServerNotRunning, name)
+	}
+
+	metadata, err := server.NewMetadataManager().Load(srv.Path)
+	if err != nil {
+		metadata = &types.ServerMetadata{}
+	}
+	return server.CheckHealth(ctx, srv, metadata.HealthChecks()), nil
+}
+
+// CrashReport describes a crash collected into the server's crashes/ folder
+type CrashReport = server.CrashReport
+
+// Crashes returns the crashes collected for a server, newest first. Only the
+// latest server.MaxCrashReports are kept; metadata.json counts all of them.
+func (c *Client) Crashes(name string) ([]CrashReport, error) {
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return server.ListCrashes(srv.Path)
+}
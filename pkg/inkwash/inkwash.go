@@ -0,0 +1,138 @@
+// Package inkwash is the Go API for managing FiveM servers with InkWash.
+//
+// A Client works on the same registry and build cache as the inkwash CLI, so
+// servers created here show up in `inkwash list` and vice versa:
+//
+//	client, err := inkwash.New(inkwash.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	srv, err := client.Create(ctx, inkwash.CreateOptions{
+//		Name:         "my-server",
+//		Path:         "/srv/fivem",
+//		BuildChannel: "recommended",
+//	}, nil)
+//
+// Errors can be matched with errors.Is against the Err* values below.
+package inkwash
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/VexoaXYZ/inkwash/internal/cache"
+	"github.com/VexoaXYZ/inkwash/internal/database"
+	"github.com/VexoaXYZ/inkwash/internal/registry"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+)
+
+var (
+	// ErrServerNotFound is returned when no server is registered under a name
+	ErrServerNotFound = errors.New("server not found")
+
+	// ErrNameTaken is returned when creating a server under a name that's already registered
+	ErrNameTaken = errors.New("server name already in use")
+
+	// ErrServerRunning is returned when an operation needs a stopped server
+	ErrServerRunning = errors.New("server is running")
+
+	// ErrServerNotRunning is returned when stopping a server that isn't running
+	ErrServerNotRunning = errors.New("server is not running")
+
+	// ErrServerExists is returned when the install folder already holds another server
+	ErrServerExists = server.ErrServerExists
+
+	// ErrServerBusy is returned while another inkwash process is modifying a server
+	ErrServerBusy = server.ErrServerBusy
+
+	// ErrUpToDate is returned when a server already has the build an update asks for
+	ErrUpToDate = server.ErrUpToDate
+
+	// ErrPinned is returned when updating a server pinned to its build
+	// without saying which build to move it to
+	ErrPinned = server.ErrPinned
+
+	// ErrNotCached is returned when removing a build that isn't in the cache
+	ErrNotCached = errors.New("build is not cached")
+
+	// ErrStartupFailed is returned by StartAndWait when the server exits or
+	// logs a fatal problem while starting; the error is a *StartupError
+	ErrStartupFailed = server.ErrStartupFailed
+
+	// ErrStartupTimeout is returned by StartAndWait when the server is still
+	// starting once the timeout is up
+	ErrStartupTimeout = server.ErrStartupTimeout
+
+	// ErrProfileNotFound is returned when a server has no server.<profile>.cfg
+	ErrProfileNotFound = server.ErrProfileNotFound
+
+	// ErrProfileExists is returned when creating a profile that's already there
+	ErrProfileExists = server.ErrProfileExists
+
+	// ErrDatabaseClientNotFound is returned by SetupDatabase when neither the
+	// mysql nor the mariadb client is installed
+	ErrDatabaseClientNotFound = database.ErrClientNotFound
+)
+
+// DefaultMaxCachedBuilds is how many FXServer builds are cached when Options doesn't say
+const DefaultMaxCachedBuilds = 3
+
+// Options configures a Client. The zero value uses the CLI's locations.
+type Options struct {
+	RegistryPath    string // servers.json, defaults to the CLI's registry
+	CachePath       string // FXServer build cache, defaults to the CLI's cache
+	MaxCachedBuilds int    // Builds kept in the cache, defaults to DefaultMaxCachedBuilds
+	MaxCacheSize    int64  // Bytes the cache may take up before old builds are evicted, 0 for no limit
+	LongPaths       bool   // Use \\?\ paths on Windows for installs under deep folders
+	VerifyCache     bool   // Check a cached build's checksums before installing from it
+}
+
+// Client manages InkWash servers. It's safe for concurrent use.
+type Client struct {
+	opts Options
+	reg  *registry.Registry
+	pm   *server.ProcessManager
+
+	// The build cache is only opened by operations that need it
+	cacheOnce sync.Once
+	cache     *cache.BinaryCache
+	cacheErr  error
+}
+
+// New opens the server registry and returns a Client
+func New(opts Options) (*Client, error) {
+	if opts.RegistryPath == "" {
+		opts.RegistryPath = registry.GetRegistryPath()
+	}
+	if opts.CachePath == "" {
+		opts.CachePath = registry.GetDefaultCachePath()
+	}
+	if opts.MaxCachedBuilds <= 0 {
+		opts.MaxCachedBuilds = DefaultMaxCachedBuilds
+	}
+
+	reg, err := registry.NewRegistry(opts.RegistryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	return &Client{
+		opts: opts,
+		reg:  reg,
+		pm:   server.NewProcessManager(),
+	}, nil
+}
+
+// binaryCache opens the build cache on first use
+func (c *Client) binaryCache() (*cache.BinaryCache, error) {
+	c.cacheOnce.Do(func() {
+		c.cache, c.cacheErr = cache.NewBinaryCache(c.opts.CachePath, c.opts.MaxCachedBuilds)
+		if c.cacheErr != nil {
+			c.cacheErr = fmt.Errorf("failed to initialize cache: %w", c.cacheErr)
+			return
+		}
+		c.cache.SetMaxSize(c.opts.MaxCacheSize)
+	})
+	return c.cache, c.cacheErr
+}
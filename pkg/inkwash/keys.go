@@ -0,0 +1,62 @@
+package inkwash
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/servercfg"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ServersUsingKey returns the names of the servers assigned the license key
+// with vault ID keyID, see CreateOptions.KeyID and AssignKey
+func (c *Client) ServersUsingKey(keyID string) []string {
+	var names []string
+	for _, srv := range c.reg.List() {
+		if keyID != "" && srv.KeyID == keyID {
+			names = append(names, srv.Name)
+		}
+	}
+	return names
+}
+
+// AssignKey sets a server's sv_licenseKey in server.cfg to licenseKey and
+// records keyID, its vault ID, on the server. Profiles exec server.cfg, so
+// they pick it up too. A running server keeps its old key until it's
+// restarted. The same key can be assigned to several servers, but only one
+// of them can be online at a time; see ServersUsingKey.
+func (c *Client) AssignKey(ctx context.Context, name, keyID, licenseKey string) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	cfg, err := servercfg.Load(filepath.Join(srv.Path, "server.cfg"))
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Set("sv_licenseKey", licenseKey); err != nil {
+		return nil, err
+	}
+	if err := cfg.Save(); err != nil {
+		return nil, err
+	}
+
+	srv.KeyID = keyID
+	if err := c.reg.Update(*srv); err != nil {
+		return nil, fmt.Errorf("server.cfg updated but the key wasn't recorded: %w", err)
+	}
+	return srv, nil
+}
@@ -0,0 +1,92 @@
+package inkwash
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ConvarOverride is a convar a profile sets differently from server.cfg
+type ConvarOverride = server.ConvarOverride
+
+// Profiles returns the names of a server's server.<profile>.cfg profiles
+func (c *Client) Profiles(name string) ([]string, error) {
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return server.ListProfiles(srv.Path)
+}
+
+// CreateProfile writes server.<profile>.cfg for a server, derived from its
+// server.cfg with the given convars overridden, and returns its path
+func (c *Client) CreateProfile(ctx context.Context, name, profile string, overrides []ConvarOverride) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Release()
+
+	return server.NewConfigGenerator().GenerateProfile(srv, profile, overrides)
+}
+
+// SetProfile picks the profile a server is started with from now on, by
+// inkwash and by its launch script; "" or server.DefaultProfile for
+// server.cfg. A running server keeps its profile until it's restarted.
+func (c *Client) SetProfile(ctx context.Context, name, profile string) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if profile == server.DefaultProfile {
+		profile = ""
+	}
+	if err := server.CheckProfile(srv.Path, profile); err != nil {
+		return nil, err
+	}
+	if srv.Profile == profile {
+		return srv, nil
+	}
+
+	srv.Profile = profile
+	if err := c.reg.Update(*srv); err != nil {
+		return nil, fmt.Errorf("failed to save the profile: %w", err)
+	}
+	if err := server.NewConfigGenerator().GenerateLaunchScript(srv); err != nil {
+		return srv, fmt.Errorf("profile saved but the launch script wasn't updated: %w", err)
+	}
+	return srv, nil
+}
+
+// DeleteProfile removes a server's server.<profile>.cfg. The profile the
+// server is set to start with can't be deleted.
+func (c *Client) DeleteProfile(ctx context.Context, name, profile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return err
+	}
+	if srv.Profile == profile {
+		return fmt.Errorf("'%s' starts with the %s profile; switch it with 'inkwash start %s --profile %s' first",
+			name, profile, name, server.DefaultProfile)
+	}
+	return server.DeleteProfile(srv.Path, profile)
+}
@@ -0,0 +1,16 @@
+package inkwash
+
+import (
+	"context"
+
+	"github.com/VexoaXYZ/inkwash/internal/recipe"
+)
+
+// Recipe is a parsed txAdmin recipe, see CreateOptions.Recipe
+type Recipe = recipe.Recipe
+
+// LoadRecipe reads a txAdmin recipe from a file or an http(s) URL and checks
+// inkwash can run its tasks
+func LoadRecipe(ctx context.Context, source string) (*Recipe, error) {
+	return recipe.Load(ctx, source)
+}
@@ -0,0 +1,79 @@
+package inkwash
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VexoaXYZ/inkwash/internal/convert"
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// MoveResult describes a finished Move
+type MoveResult struct {
+	Server *types.Server
+	From   string // Folder the server was in
+	Copied bool   // The destination is on another drive, so files were copied rather than renamed
+}
+
+// Rename registers a stopped server under a new name. Its folder keeps its
+// name, and a generated README.md that hasn't been edited is updated to match.
+func (c *Client) Rename(ctx context.Context, name, newName string) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.pm.IsRunning(srv) {
+		return nil, fmt.Errorf("%w: '%s' (PID: %d), stop it before renaming", ErrServerRunning, name, srv.PID)
+	}
+	if _, err := c.Get(newName); err == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrNameTaken, newName)
+	}
+
+	result, err := server.Rename(c.reg, srv, newName)
+	if result == nil {
+		return nil, err
+	}
+	return result.Server, err
+}
+
+// Move moves a stopped server's folder to dest, or into dest if it's an
+// existing folder, and updates the registry, the launch script and the
+// convert history, which hold its absolute path. Moving to another drive
+// copies the folder and then removes the original.
+func (c *Client) Move(ctx context.Context, name, dest string) (*MoveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.pm.IsRunning(srv) {
+		return nil, fmt.Errorf("%w: '%s' (PID: %d), stop it before moving", ErrServerRunning, name, srv.PID)
+	}
+
+	result, err := server.Move(c.reg, srv, dest)
+	if result == nil {
+		return nil, err
+	}
+	moved := &MoveResult{Server: result.Server, From: result.From, Copied: result.Copied}
+	if err != nil {
+		return moved, err
+	}
+
+	// Mods converted into the server are redownloaded to where they now are
+	history, err := convert.LoadHistory(convert.GetHistoryPath())
+	if err == nil {
+		_, err = history.MovePaths(result.From, result.Server.Path)
+	}
+	if err != nil {
+		return moved, fmt.Errorf("server moved but the convert history couldn't be updated: %w", err)
+	}
+	return moved, nil
+}
@@ -0,0 +1,396 @@
+package inkwash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/template"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// CreateOptions describes a server to install
+type CreateOptions struct {
+	Name         string // Registry name; the folder is a slug of it
+	Path         string // Folder the server folder is created in
+	Build        int    // FXServer build number, ignored when BuildChannel is set
+	BuildChannel string // types.BuildChannel*: resolved to a build when the install runs
+	LicenseKey   string
+	KeyID        string // Vault ID of LicenseKey, recorded on the server, see ServersUsingKey
+	Port         int    // Defaults to 30120
+	BindAddress  string // Defaults to types.DefaultBindAddress
+	TargetOS     string // types.TargetWindows or types.TargetLinux, defaults to this machine
+	GameBuild    int    // sv_enforceGameBuild, 0 for types.DefaultGameBuild, types.GameBuildNone to disable
+	NoScaffold   bool   // Skip writing .gitignore and README.md
+	Force        bool   // Install over an unregistered server already in the target folder
+	ArtifactURL  string // Direct FXServer archive URL, used instead of Build and BuildChannel
+
+	ForceRedownload bool   // Download the build even if it's cached, replacing the cached copy, and update the cached cfx-server-data
+	LaunchCommand   string // Start the server with this command instead of the entrypoint detected in its build
+
+	Verify bool   // Test the downloaded archive and download it again if it's damaged
+	SHA256 string // Expected SHA256 of the downloaded archive; implies Verify
+
+	Recipe   *Recipe          // txAdmin recipe run in the new server folder instead of cloning cfx-server-data
+	Database *DatabaseOptions // MySQL/MariaDB database to create for the server, see SetupDatabase
+	Template *types.Template  // Applied once the server is set up, with its database if Database is set
+}
+
+// UpdateOptions describes the FXServer build UpdateBuild moves a server to.
+// Without Build, BuildChannel or ArtifactURL it's the build the server's
+// channel points to.
+type UpdateOptions struct {
+	Build        int    // FXServer build number, ignored when BuildChannel is set
+	BuildChannel string // types.BuildChannel*: resolved to a build when the update runs
+	ArtifactURL  string // Direct FXServer archive URL, used instead of Build and BuildChannel
+	Force        bool   // Reinstall the build even if the server already has it
+	Channel      string // Channel the server follows from now on (types.BuildChannel* or types.BuildChannelPinned), "" to keep it; a Build or ArtifactURL pins it
+
+	ForceRedownload bool   // Download the build even if it's cached, replacing the cached copy
+	Verify          bool   // Test the downloaded archive and download it again if it's damaged
+	SHA256          string // Expected SHA256 of the downloaded archive; implies Verify
+}
+
+// UpdateResult describes a finished UpdateBuild
+type UpdateResult struct {
+	From       int    // Build the server had, 0 if its metadata.json didn't say
+	To         int    // Build the server has now
+	BackupPath string // Folder holding the replaced binaries, "" if there were none
+	Channel    string // Channel the server follows now
+}
+
+// Progress reports how far a Create or UpdateBuild has got
+type Progress struct {
+	Step          string
+	Completed     int
+	Total         int
+	Percent       float64 // Overall progress from 0 to 100
+	CurrentFile   string  // What's being downloaded or copied, if anything
+	DownloadSpeed float64 // MB/s while downloading FXServer, 0 otherwise
+	DownloadETA   time.Duration
+	Warning       bool // Step is something to follow up on, e.g. a skipped recipe task
+}
+
+// ProgressFunc receives progress updates during Create and UpdateBuild
+type ProgressFunc func(Progress)
+
+// List returns every registered server. Servers whose folder has been
+// deleted are dropped from the registry.
+func (c *Client) List() []types.Server {
+	return c.reg.List()
+}
+
+// Reload rereads the registry, picking up servers that other inkwash
+// processes created, started or stopped since the Client was opened
+func (c *Client) Reload() error {
+	if err := c.reg.Reload(); err != nil {
+		return fmt.Errorf("failed to reload registry: %w", err)
+	}
+	return nil
+}
+
+// Get returns a copy of the named server
+func (c *Client) Get(name string) (*types.Server, error) {
+	srv, err := c.reg.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrServerNotFound, name)
+	}
+
+	copied := *srv
+	return &copied, nil
+}
+
+// IsRunning reports whether the server's process is alive
+func (c *Client) IsRunning(srv *types.Server) bool {
+	return c.pm.IsRunning(srv)
+}
+
+// Create installs and registers a new server. Cancelling ctx stops the install
+// and removes the server folder it created.
+func (c *Client) Create(ctx context.Context, opts CreateOptions, onProgress ProgressFunc) (*types.Server, error) {
+	if c.reg.Exists(opts.Name) {
+		return nil, fmt.Errorf("%w: '%s'", ErrNameTaken, opts.Name)
+	}
+
+	binaryCache, err := c.binaryCache()
+	if err != nil {
+		return nil, err
+	}
+
+	port := opts.Port
+	if port == 0 {
+		port = 30120
+	}
+
+	// Checked before the download rather than after it
+	if opts.Template != nil {
+		if err := template.Validate(opts.Template); err != nil {
+			return nil, err
+		}
+		if err := template.CheckRequirements(opts.Template, port); err != nil {
+			return nil, err
+		}
+	}
+
+	installer := server.NewInstaller(binaryCache, c.reg)
+	installer.SetLongPaths(c.opts.LongPaths)
+	installer.SetVerifyCache(c.opts.VerifyCache)
+	installer.SetServerDataPath(filepath.Join(c.opts.CachePath, "server-data"))
+
+	err = installer.InstallContext(ctx, server.InstallOptions{
+		ServerName:   opts.Name,
+		InstallPath:  opts.Path,
+		BuildNumber:  opts.Build,
+		BuildChannel: opts.BuildChannel,
+		LicenseKey:   opts.LicenseKey,
+		KeyID:        opts.KeyID,
+		Port:         port,
+		BindAddress:  opts.BindAddress,
+		NoScaffold:   opts.NoScaffold,
+		TargetOS:     opts.TargetOS,
+		GameBuild:    opts.GameBuild,
+		Force:        opts.Force,
+		ArtifactURL:  opts.ArtifactURL,
+
+		ForceRedownload: opts.ForceRedownload,
+		LaunchCommand:   opts.LaunchCommand,
+
+		Verify: opts.Verify,
+		SHA256: opts.SHA256,
+
+		Recipe:   opts.Recipe,
+		Database: opts.Database,
+
+		Template:      opts.Template,
+		ApplyTemplate: template.ApplyNew,
+	}, installProgress(onProgress))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Get(opts.Name)
+}
+
+// UpdateBuild moves a stopped server to another FXServer build. The old
+// binaries are kept in the folder server.BinaryBackupPath names, replacing
+// the backup of any earlier update. If the server already has the build,
+// ErrUpToDate is returned along with the result.
+func (c *Client) UpdateBuild(ctx context.Context, name string, opts UpdateOptions, onProgress ProgressFunc) (*UpdateResult, error) {
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.pm.IsRunning(srv) {
+		return nil, fmt.Errorf("%w: '%s' (PID: %d), stop it before updating", ErrServerRunning, name, srv.PID)
+	}
+
+	binaryCache, err := c.binaryCache()
+	if err != nil {
+		return nil, err
+	}
+
+	installer := server.NewInstaller(binaryCache, c.reg)
+	installer.SetLongPaths(c.opts.LongPaths)
+	installer.SetVerifyCache(c.opts.VerifyCache)
+
+	result, err := installer.UpdateContext(ctx, srv, server.UpdateOptions{
+		BuildNumber:  opts.Build,
+		BuildChannel: opts.BuildChannel,
+		ArtifactURL:  opts.ArtifactURL,
+		Force:        opts.Force,
+		Channel:      opts.Channel,
+
+		ForceRedownload: opts.ForceRedownload,
+		Verify:          opts.Verify,
+		SHA256:          opts.SHA256,
+	}, installProgress(onProgress))
+	if result == nil {
+		return nil, err
+	}
+	return &UpdateResult{From: result.From, To: result.To, BackupPath: result.BackupPath, Channel: result.Channel}, err
+}
+
+// installProgress adapts a ProgressFunc to the installer's callback
+func installProgress(onProgress ProgressFunc) server.ProgressCallback {
+	return func(p server.InstallProgress) {
+		if onProgress != nil {
+			onProgress(Progress{
+				Step:          p.Step,
+				Completed:     p.CompletedSteps,
+				Total:         p.TotalSteps,
+				Percent:       p.Progress * 100,
+				CurrentFile:   p.CurrentFile,
+				DownloadSpeed: p.DownloadSpeed,
+				DownloadETA:   p.DownloadETA,
+				Warning:       p.Warning,
+			})
+		}
+	}
+}
+
+// Remove unregisters a stopped server. Its folder is left on disk, so it can
+// be registered again with `inkwash registry scan`.
+func (c *Client) Remove(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return err
+	}
+	if c.pm.IsRunning(srv) {
+		return fmt.Errorf("%w: '%s' (PID: %d)", ErrServerRunning, name, srv.PID)
+	}
+	if server.IsLocked(srv.Path) {
+		return fmt.Errorf("%w: another inkwash command is modifying '%s'", ErrServerBusy, name)
+	}
+
+	return c.reg.Remove(name)
+}
+
+// DeleteOptions controls Delete
+type DeleteOptions struct {
+	KeepResources bool   // Zip resources/ before the folder is removed
+	ArchivePath   string // Where the zip goes, defaults to archives/ under the data directory
+}
+
+// DeleteResult describes a finished Delete
+type DeleteResult struct {
+	Stopped     bool   // The server was running and was stopped first
+	ArchivePath string // The zip of resources/, "" if it wasn't kept
+}
+
+// Delete stops a server if it's running, unregisters it and removes its
+// folder. Unlike Remove, nothing is left on disk except the resources
+// archive, if one was asked for. Folders that don't look like a server, or
+// that hold inkwash's own data or another server, are refused.
+func (c *Client) Delete(ctx context.Context, name string, opts DeleteOptions) (*DeleteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeleteResult{}
+	if c.pm.IsRunning(srv) {
+		if err := c.pm.Stop(srv); err != nil {
+			return nil, fmt.Errorf("failed to stop server: %w", err)
+		}
+		result.Stopped = true
+	}
+
+	deleted, err := server.Delete(c.reg, srv, server.DeleteOptions{
+		KeepResources: opts.KeepResources,
+		ArchivePath:   opts.ArchivePath,
+	})
+	if deleted != nil {
+		result.ArchivePath = deleted.ArchivePath
+	}
+	if err != nil && result.Stopped {
+		// Keep the registry in step with the process that was stopped
+		c.reg.Update(*srv)
+	}
+	return result, err
+}
+
+// Start launches a server in the background and records its PID. If the
+// server started but the PID couldn't be saved, both the server and an error
+// are returned.
+func (c *Client) Start(ctx context.Context, name string) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.pm.IsRunning(srv) {
+		return srv, fmt.Errorf("%w: '%s' (PID: %d)", ErrServerRunning, name, srv.PID)
+	}
+
+	if srv.PID != 0 {
+		// The process exited on its own; keep what's left of the crash before
+		// the new process writes over the log. Collecting is best effort.
+		server.RecordCrash(srv)
+		srv.PID = 0
+	}
+	if err := c.pm.Start(srv); err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	if err := c.reg.Update(*srv); err != nil {
+		return srv, fmt.Errorf("server started but the registry couldn't be updated: %w", err)
+	}
+	return srv, nil
+}
+
+// StartupError describes a server that failed while starting: why, and the
+// log lines leading up to it
+type StartupError = server.StartupError
+
+// StartAndWait starts a server like Start, then waits up to timeout for it to
+// take connections. A server that exits or logs a known fatal problem (a
+// rejected license key, a port in use) fails with a *StartupError; if it
+// exited, its PID is cleared so it isn't taken for a crash. One still
+// starting after timeout fails with ErrStartupTimeout and is left running.
+// The server is returned with either error. Cancelling ctx stops the wait,
+// not the server.
+func (c *Client) StartAndWait(ctx context.Context, name string, timeout time.Duration) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	watcher := server.NewStartupWatcher(srv.Path)
+
+	srv, err = c.Start(ctx, name)
+	if err != nil {
+		return srv, err
+	}
+
+	err = watcher.Wait(ctx, srv, timeout)
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) && startupErr.Exited {
+		srv.PID = 0
+		if c.reg.Reload() == nil {
+			c.reg.Update(*srv)
+		}
+	}
+	return srv, err
+}
+
+// Stop shuts a server down, waiting up to 30 seconds before killing it. As
+// with Start, a registry failure after a successful stop returns the server too.
+func (c *Client) Stop(ctx context.Context, name string) (*types.Server, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !c.pm.IsRunning(srv) {
+		return srv, fmt.Errorf("%w: '%s'", ErrServerNotRunning, name)
+	}
+
+	if err := c.pm.Stop(srv); err != nil {
+		return nil, fmt.Errorf("failed to stop server: %w", err)
+	}
+
+	if err := c.reg.Update(*srv); err != nil {
+		return srv, fmt.Errorf("server stopped but the registry couldn't be updated: %w", err)
+	}
+	return srv, nil
+}
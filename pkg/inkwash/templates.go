@@ -0,0 +1,131 @@
+package inkwash
+
+import (
+	"context"
+
+	"github.com/VexoaXYZ/inkwash/internal/server"
+	"github.com/VexoaXYZ/inkwash/internal/template"
+	"github.com/VexoaXYZ/inkwash/pkg/types"
+)
+
+// ErrTemplateNotFound is returned when no template is saved under a name
+var ErrTemplateNotFound = template.ErrTemplateNotFound
+
+// ErrTemplateExists is returned when saving over a template without force
+var ErrTemplateExists = template.ErrTemplateExists
+
+// RequirementsError is returned by ApplyTemplate when the host is short of
+// the RAM, CPUs or free ports a template asks for
+type RequirementsError = template.RequirementsError
+
+// DependencyError is returned by ApplyTemplate when a resource depends on one
+// that's neither on the server nor in the template
+type DependencyError = template.DependencyError
+
+// TemplateDatabaseError is returned by ApplyTemplate when everything but the
+// template's database was applied
+type TemplateDatabaseError = template.DatabaseError
+
+// TemplateOptions controls ApplyTemplate
+type TemplateOptions struct {
+	Force  bool              // Reinstall resources the server already has
+	OnStep func(step string) // Told what's happening, e.g. "Installing ox_lib"
+
+	// Database sets up the template's database, as SetupDatabase does. When
+	// nil, its SQL files are only listed in TemplateResult.SQLFiles.
+	Database *DatabaseOptions
+}
+
+// TemplateResult describes what ApplyTemplate changed
+type TemplateResult struct {
+	Installed []string        // Resources downloaded into resources/
+	Existing  []string        // Resources the server already had, left as they were
+	Convars   []string        // Convars set in server.cfg
+	Ensured   []string        // ensure lines added to server.cfg, in start order
+	SQLFiles  []string        // The template's SQL files in the server folder, in order
+	Database  *DatabaseResult // The database set up, nil without TemplateOptions.Database
+}
+
+// LoadTemplate reads a template from a JSON file and checks it
+func LoadTemplate(path string) (*types.Template, error) {
+	return template.Load(path)
+}
+
+// FindTemplate returns the template saved under name, or the built-in
+// framework template (esx, qbcore) of that name, as 'inkwash template apply'
+// does: a name ending in .json, or containing a path separator, is read as a
+// file instead
+func FindTemplate(name string) (*types.Template, error) {
+	return template.Find(name)
+}
+
+// Templates returns the saved and built-in templates sorted by name, along
+// with errors for template files that couldn't be read
+func Templates() ([]types.Template, []error) {
+	return template.List()
+}
+
+// IsBuiltinTemplate reports whether FindTemplate(name) returns a built-in
+// template rather than a saved one
+func IsBuiltinTemplate(name string) bool {
+	return template.IsPreset(name)
+}
+
+// SaveTemplate saves a template under its name where FindTemplate and the CLI
+// look for it, replacing one that's already there only if force is set.
+// Returns the file it was written to.
+func SaveTemplate(t *types.Template, force bool) (string, error) {
+	return template.Save(t, force)
+}
+
+// ExportTemplate builds a template called templateName from a server's
+// server.cfg and resources/ folder, without saving it. Secrets such as
+// sv_licenseKey and rcon_password are left out; their names are returned in
+// omitted, along with convars whose values don't validate.
+func (c *Client) ExportTemplate(ctx context.Context, name, templateName string) (t *types.Template, omitted []string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return template.Export(srv, templateName)
+}
+
+// ApplyTemplate installs a template's resources on a server and writes its
+// convars and ensure lines into server.cfg, each resource after the ones its
+// manifest depends on. The host's RAM, CPUs and ports are checked first. With
+// TemplateOptions.Database the template's database is set up last; if only
+// that fails, a *TemplateDatabaseError is returned. A running server picks
+// the changes up when it's restarted.
+func (c *Client) ApplyTemplate(ctx context.Context, name string, t *types.Template, opts TemplateOptions) (*TemplateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srv, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := server.LockServer(srv.Path, srv.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	result, err := template.Apply(ctx, srv, t, template.Options{Force: opts.Force, OnStep: opts.OnStep, Database: opts.Database})
+	if result == nil {
+		return nil, err
+	}
+	return &TemplateResult{
+		Installed: result.Installed,
+		Existing:  result.Existing,
+		Convars:   result.Convars,
+		Ensured:   result.Ensured,
+		SQLFiles:  result.SQLFiles,
+		Database:  result.Database,
+	}, err
+}
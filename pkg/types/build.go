@@ -2,6 +2,17 @@ package types
 
 import "time"
 
+// Build channels that resolve to a concrete build number at install time
+const (
+	BuildChannelRecommended = "recommended"
+	BuildChannelOptional    = "optional"
+	BuildChannelLatest      = "latest"
+)
+
+// BuildChannelPinned is the channel of a server kept on its build: update-server
+// only moves it when given a build or channel
+const BuildChannelPinned = "pinned"
+
 // Build represents a FiveM server build
 type Build struct {
 	Number      int       `json:"number"`
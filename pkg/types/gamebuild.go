@@ -0,0 +1,52 @@
+package types
+
+import "fmt"
+
+// DefaultGameBuild is the sv_enforceGameBuild new servers use unless another is chosen
+const DefaultGameBuild = 2802
+
+// GameBuildNone leaves sv_enforceGameBuild out, so the server runs the base game build
+const GameBuildNone = -1
+
+// GameBuild is a GTA V build that sv_enforceGameBuild accepts
+type GameBuild struct {
+	Number int
+	DLC    string // DLC introduced with this build
+}
+
+// GameBuilds lists the builds FXServer can enforce, oldest first
+var GameBuilds = []GameBuild{
+	{1604, "Arena War"},
+	{2060, "Los Santos Summer Special"},
+	{2189, "The Cayo Perico Heist"},
+	{2372, "Los Santos Tuners"},
+	{2545, "The Contract"},
+	{2612, "The Contract (update)"},
+	{2699, "The Criminal Enterprises"},
+	{2802, "Los Santos Drug Wars"},
+	{2944, "San Andreas Mercenaries"},
+	{3095, "The Chop Shop"},
+	{3258, "Bottom Dollar Bounties"},
+	{3407, "Agents of Sabotage"},
+}
+
+// FindGameBuild returns the known game build with the given number
+func FindGameBuild(number int) (GameBuild, bool) {
+	for _, build := range GameBuilds {
+		if build.Number == number {
+			return build, true
+		}
+	}
+	return GameBuild{}, false
+}
+
+// GameBuildLabel describes a game build number for display, e.g. "2802 (Los Santos Drug Wars)"
+func GameBuildLabel(number int) string {
+	if number == GameBuildNone {
+		return "none (base game)"
+	}
+	if build, ok := FindGameBuild(number); ok {
+		return fmt.Sprintf("%d (%s)", build.Number, build.DLC)
+	}
+	return fmt.Sprintf("%d", number)
+}
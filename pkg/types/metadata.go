@@ -6,8 +6,19 @@ import "time"
 type ServerMetadata struct {
 	Version   int               `json:"version"` // Schema version for future migrations
 	Build     BuildMetadata     `json:"build"`
+	Channel   string            `json:"channel,omitempty"` // Build channel the server follows, see BuildChannel
 	Lifecycle LifecycleMetadata `json:"lifecycle"`
 	Stats     UsageStats        `json:"stats"`
+	Resources []InstalledResource `json:"resources,omitempty"` // Resources installed by inkwash
+	Health    *HealthConfig       `json:"health,omitempty"`    // Health checks run by inkwash watch, nil for the defaults
+	Launch    *LaunchOptions      `json:"launch,omitempty"`    // Extra FXServer arguments and environment, nil for none
+}
+
+// LaunchOptions are added to however a server is started: by inkwash or by
+// its generated run.sh/run.cmd
+type LaunchOptions struct {
+	Args []string          `json:"args,omitempty"` // Appended to the launch command, e.g. "+set", "onesync", "on"
+	Env  map[string]string `json:"env,omitempty"`  // Set for FXServer on top of inkwash's own environment
 }
 
 // BuildMetadata tracks the installed FXServer build
@@ -17,6 +28,41 @@ type BuildMetadata struct {
 	InstalledAt time.Time `json:"installed_at"` // When binaries were installed
 	Recommended bool      `json:"recommended"`  // Was this a recommended build?
 	Optional    bool      `json:"optional"`     // Was this an optional build?
+
+	// Previous is the build the last update replaced; its binaries are kept
+	// next to bin/ (see server.BinaryBackupPath)
+	Previous *BuildMetadata `json:"previous,omitempty"`
+}
+
+// InstalledResource records a resource installed from a repository
+type InstalledResource struct {
+	Name        string    `json:"name"`              // Folder name under resources/
+	Repository  string    `json:"repository"`        // Repository URL
+	Ref         string    `json:"ref"`               // Branch, tag or commit ("" = default branch, or latest release)
+	Release     bool      `json:"release,omitempty"` // Installed from a GitHub release rather than the source
+	Tag         string    `json:"tag,omitempty"`     // Tag of the release installed, "" for older installs
+	Path        string    `json:"path,omitempty"`    // Folder of the resource in the repository ("" = its root)
+	Version     string    `json:"version"`           // Version from the resource manifest
+	InstalledAt time.Time `json:"installed_at"`      // When it was last installed or updated
+}
+
+// FindResource returns the installed resource with the given name, or nil
+func (m *ServerMetadata) FindResource(name string) *InstalledResource {
+	for i := range m.Resources {
+		if m.Resources[i].Name == name {
+			return &m.Resources[i]
+		}
+	}
+	return nil
+}
+
+// RecordResource adds or replaces an installed resource entry
+func (m *ServerMetadata) RecordResource(res InstalledResource) {
+	if existing := m.FindResource(res.Name); existing != nil {
+		*existing = res
+		return
+	}
+	m.Resources = append(m.Resources, res)
 }
 
 // LifecycleMetadata tracks server lifecycle events
@@ -30,6 +76,78 @@ type LifecycleMetadata struct {
 type UsageStats struct {
 	RestartCount int           `json:"restart_count"` // Number of times started
 	TotalUptime  time.Duration `json:"total_uptime"`  // Total uptime in nanoseconds
+
+	HealthRestarts int              `json:"health_restarts,omitempty"` // Restarts after failed health checks
+	Incidents      []HealthIncident `json:"incidents,omitempty"`       // The latest MaxIncidents, oldest first
+
+	CrashCount   int        `json:"crash_count,omitempty"`    // Times the process exited without being stopped
+	LastCrash    *time.Time `json:"last_crash,omitempty"`     // When the last crash was noticed
+	LastCrashPID int        `json:"last_crash_pid,omitempty"` // So a crash noticed twice is only counted once
+}
+
+// MaxIncidents is how many health incidents metadata.json keeps
+const MaxIncidents = 20
+
+// HealthIncident records a server failing its health checks too many times in a row
+type HealthIncident struct {
+	Time      time.Time `json:"time"`
+	Failures  []string  `json:"failures"`        // What the last check found wrong
+	Restarted bool      `json:"restarted"`       // The server was restarted
+	Error     string    `json:"error,omitempty"` // Why the restart failed or was skipped
+}
+
+// RecordIncident adds a health incident, dropping the oldest past MaxIncidents
+func (m *ServerMetadata) RecordIncident(incident HealthIncident) {
+	m.Stats.Incidents = append(m.Stats.Incidents, incident)
+	if len(m.Stats.Incidents) > MaxIncidents {
+		m.Stats.Incidents = m.Stats.Incidents[len(m.Stats.Incidents)-MaxIncidents:]
+	}
+	if incident.Restarted {
+		m.Stats.HealthRestarts++
+	}
+}
+
+// HealthConfig configures a server's health checks. Fields left at zero use
+// the defaults, so metadata.json only needs the ones that differ.
+type HealthConfig struct {
+	NoTCP        bool `json:"no_tcp,omitempty"`        // Skip connecting to the game port
+	NoPlayers    bool `json:"no_players,omitempty"`    // Skip fetching players.json
+	LogTimeout   int  `json:"log_timeout,omitempty"`   // Seconds the log may go without output, 0 to not check it
+	Interval     int  `json:"interval,omitempty"`      // Seconds between checks (default 30)
+	Timeout      int  `json:"timeout,omitempty"`       // Seconds each probe may take (default 5)
+	Failures     int  `json:"failures,omitempty"`      // Failed checks in a row before a restart (default 3)
+	StartupGrace int  `json:"startup_grace,omitempty"` // Seconds after a start before failures count (default 120)
+}
+
+// HealthChecks returns the server's health check settings with defaults filled in
+func (m *ServerMetadata) HealthChecks() HealthConfig {
+	var cfg HealthConfig
+	if m.Health != nil {
+		cfg = *m.Health
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5
+	}
+	if cfg.Failures <= 0 {
+		cfg.Failures = 3
+	}
+	if cfg.StartupGrace <= 0 {
+		cfg.StartupGrace = 120
+	}
+	return cfg
+}
+
+// BuildChannel returns the channel update-server moves the server along, one
+// of the BuildChannel* constants. Servers that never chose one follow the
+// recommended channel.
+func (m *ServerMetadata) BuildChannel() string {
+	if m.Channel == "" {
+		return BuildChannelRecommended
+	}
+	return m.Channel
 }
 
 // NewServerMetadata creates metadata for a freshly created server
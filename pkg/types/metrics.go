@@ -10,7 +10,24 @@ type ServerMetrics struct {
 	NetworkTX   uint64    // Bytes transmitted per second
 	NetworkRX   uint64    // Bytes received per second
 	PlayerCount int
+	MaxPlayers  int // sv_maxClients as the server reports it, 0 if unknown
 	LastUpdate  time.Time
+
+	// PlayersKnown is set when the last players query was answered; a server
+	// that's still starting up doesn't serve players.json yet
+	PlayersKnown bool
+
+	// StatsUnavailable is set when the process is running but the OS denied
+	// access to some of its statistics; the affected samples stay at their last value
+	StatsUnavailable bool
+
+	// Paused is set while the server is stopped; the samples are its last known values
+	Paused bool
+
+	// Previous cumulative I/O counters, used to turn totals into rates
+	lastReadBytes  uint64
+	lastWriteBytes uint64
+	lastIOSample   time.Time
 }
 
 // NewServerMetrics creates a new ServerMetrics instance
@@ -23,6 +40,22 @@ func NewServerMetrics(pid int) *ServerMetrics {
 	}
 }
 
+// Resume points the metrics at a restarted process, keeping the sample history.
+// The I/O baseline is dropped since the new process's counters start over.
+func (m *ServerMetrics) Resume(pid int) {
+	m.PID = pid
+	m.Paused = false
+	m.StatsUnavailable = false
+	m.PlayerCount = 0
+	m.MaxPlayers = 0
+	m.PlayersKnown = false
+	m.NetworkRX = 0
+	m.NetworkTX = 0
+	m.lastReadBytes = 0
+	m.lastWriteBytes = 0
+	m.lastIOSample = time.Time{}
+}
+
 // AddRAMSample adds a RAM usage sample (sliding window)
 func (m *ServerMetrics) AddRAMSample(ramGB float64) {
 	m.RAM = append(m.RAM[1:], ramGB)
@@ -48,3 +81,24 @@ func (m *ServerMetrics) CurrentCPU() float64 {
 	}
 	return m.CPU[len(m.CPU)-1]
 }
+
+// UpdateNetworkIO records cumulative read/write counters taken at the given time
+// and sets NetworkRX/NetworkTX to the per-second rate since the previous call.
+// The first call (and any call after a counter reset, e.g. a process restart)
+// only establishes a baseline and reports a rate of 0.
+func (m *ServerMetrics) UpdateNetworkIO(readBytes, writeBytes uint64, at time.Time) {
+	elapsed := at.Sub(m.lastIOSample).Seconds()
+	reset := readBytes < m.lastReadBytes || writeBytes < m.lastWriteBytes
+
+	if m.lastIOSample.IsZero() || reset || elapsed <= 0 {
+		m.NetworkRX = 0
+		m.NetworkTX = 0
+	} else {
+		m.NetworkRX = uint64(float64(readBytes-m.lastReadBytes) / elapsed)
+		m.NetworkTX = uint64(float64(writeBytes-m.lastWriteBytes) / elapsed)
+	}
+
+	m.lastReadBytes = readBytes
+	m.lastWriteBytes = writeBytes
+	m.lastIOSample = at
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateNetworkIO(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	type sample struct {
+		read, write uint64
+		after       time.Duration // Since start
+		wantRX      uint64
+		wantTX      uint64
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "first sample is a baseline",
+			samples: []sample{
+				{read: 5000, write: 9000, after: 0, wantRX: 0, wantTX: 0},
+			},
+		},
+		{
+			name: "increasing counters",
+			samples: []sample{
+				{read: 1000, write: 2000, after: 0},
+				{read: 3000, write: 2500, after: 2 * time.Second, wantRX: 1000, wantTX: 250},
+				{read: 3000, write: 4500, after: 4 * time.Second, wantRX: 0, wantTX: 1000},
+			},
+		},
+		{
+			name: "counter reset",
+			samples: []sample{
+				{read: 10000, write: 10000, after: 0},
+				{read: 500, write: 12000, after: time.Second, wantRX: 0, wantTX: 0},
+				{read: 1500, write: 14000, after: 2 * time.Second, wantRX: 1000, wantTX: 2000},
+			},
+		},
+		{
+			name: "counter wrap",
+			samples: []sample{
+				{read: ^uint64(0) - 100, write: 0, after: 0},
+				{read: 50, write: 100, after: time.Second, wantRX: 0, wantTX: 0},
+				{read: 150, write: 200, after: 2 * time.Second, wantRX: 100, wantTX: 100},
+			},
+		},
+		{
+			name: "same timestamp",
+			samples: []sample{
+				{read: 1000, write: 1000, after: 0},
+				{read: 2000, write: 2000, after: 0, wantRX: 0, wantTX: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ServerMetrics{}
+			for i, s := range tt.samples {
+				m.UpdateNetworkIO(s.read, s.write, start.Add(s.after))
+				if m.NetworkRX != s.wantRX || m.NetworkTX != s.wantTX {
+					t.Errorf("sample %d: rx/tx = %d/%d, want %d/%d", i, m.NetworkRX, m.NetworkTX, s.wantRX, s.wantTX)
+				}
+			}
+		})
+	}
+}
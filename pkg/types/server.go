@@ -1,10 +1,30 @@
 package types
 
 import (
+	"net"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"time"
 )
 
+// DefaultBindAddress is the address servers listen on when none is configured
+const DefaultBindAddress = "0.0.0.0"
+
+// Platforms FXServer artifacts are published for
+const (
+	TargetWindows = "windows"
+	TargetLinux   = "linux"
+)
+
+// HostTargetOS returns the artifact platform that runs on this machine
+func HostTargetOS() string {
+	if runtime.GOOS == "windows" {
+		return TargetWindows
+	}
+	return TargetLinux
+}
+
 // Server represents a FiveM server instance
 type Server struct {
 	Name        string    `json:"name"`
@@ -14,10 +34,21 @@ type Server struct {
 	// BuildHash removed - now in metadata.json
 	KeyID       string    `json:"key_id"`
 	Port        int       `json:"port"`
+	BindAddress string    `json:"bind_address,omitempty"`
+	TargetOS    string    `json:"target_os,omitempty"`
+	GameBuild   int       `json:"game_build,omitempty"` // sv_enforceGameBuild, GameBuildNone to disable
 	Created     time.Time `json:"created"`
 	LastStarted time.Time `json:"last_started"`
 	PID         int       `json:"pid"`
 	AutoStart   bool      `json:"auto_start"`
+
+	// LaunchCommand replaces the detected FXServer entrypoint, e.g. for proot
+	// wrappers. It's split on spaces and run from the server folder.
+	LaunchCommand string `json:"launch_command,omitempty"`
+
+	// Profile picks the server.<profile>.cfg FXServer execs instead of
+	// server.cfg; "" for server.cfg itself
+	Profile string `json:"profile,omitempty"`
 }
 
 // GetBinaryPath returns the path to the server's bin directory
@@ -30,6 +61,40 @@ func (s *Server) GetBinaryExecutable() string {
 	return filepath.Join(s.GetBinaryPath(), "FXServer.exe")
 }
 
+// GetBindAddress returns the address the server listens on
+func (s *Server) GetBindAddress() string {
+	if s.BindAddress == "" {
+		return DefaultBindAddress
+	}
+	return s.BindAddress
+}
+
+// GetGameBuild returns the enforced game build (GameBuildNone if disabled)
+func (s *Server) GetGameBuild() int {
+	if s.GameBuild == 0 {
+		return DefaultGameBuild
+	}
+	return s.GameBuild
+}
+
+// GetTargetOS returns the platform the server's binaries were installed for
+func (s *Server) GetTargetOS() string {
+	if s.TargetOS == "" {
+		return HostTargetOS()
+	}
+	return s.TargetOS
+}
+
+// CanRunOnHost reports whether the server's binaries can run on this machine
+func (s *Server) CanRunOnHost() bool {
+	return s.GetTargetOS() == HostTargetOS()
+}
+
+// Endpoint returns the host:port the server listens on (IPv6 addresses are bracketed)
+func (s *Server) Endpoint() string {
+	return net.JoinHostPort(s.GetBindAddress(), strconv.Itoa(s.Port))
+}
+
 // IsRunning returns true if the server is currently running
 func (s *Server) IsRunning() bool {
 	return s.PID > 0
@@ -0,0 +1,45 @@
+package types
+
+// Template describes a reusable server setup: the resources it runs, the
+// convars it sets, the database it needs and what the host needs to run it
+type Template struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description,omitempty"`
+	Resources    []TemplateResource   `json:"resources,omitempty"` // Ensured in dependency order
+	Convars      []TemplateConvar     `json:"convars,omitempty"`   // Set in server.cfg, in order
+	Database     *TemplateDatabase    `json:"database,omitempty"`  // nil if the resources need no database
+	Requirements TemplateRequirements `json:"requirements"`        // Checked against the host before anything changes
+}
+
+// TemplateResource is a resource a template installs and starts
+type TemplateResource struct {
+	Name       string `json:"name"`                 // Folder name under resources/, and what's ensured
+	Category   string `json:"category,omitempty"`   // Installed under resources/[category]/ when set
+	Repository string `json:"repository,omitempty"` // GitHub URL; "" if the server must already have it
+	Ref        string `json:"ref,omitempty"`        // Branch, tag or commit ("" = default branch, or latest release)
+	Release    bool   `json:"release,omitempty"`    // Install the GitHub release rather than the source
+	Path       string `json:"path,omitempty"`       // Folder of the resource in the repository ("" = its root)
+	NoEnsure   bool   `json:"no_ensure,omitempty"`  // Install only; something else starts it
+}
+
+// TemplateConvar is a convar a template sets in server.cfg
+type TemplateConvar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TemplateDatabase is the MySQL/MariaDB database a template's resources use
+// through oxmysql
+type TemplateDatabase struct {
+	// SQL is run in order against the database once it's set up: paths in the
+	// server folder, or http(s) URLs downloaded into its sql/ folder
+	SQL []string `json:"sql,omitempty"`
+}
+
+// TemplateRequirements is what a template needs from the host. Zero values
+// aren't checked.
+type TemplateRequirements struct {
+	MinRAMGB float64 `json:"min_ram_gb,omitempty"` // Total memory
+	MinCPUs  int     `json:"min_cpus,omitempty"`   // Logical CPUs
+	Ports    []int   `json:"ports,omitempty"`      // TCP ports that must be free, e.g. for a web panel
+}